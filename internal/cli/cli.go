@@ -0,0 +1,2782 @@
+// Package cli implements the chartscan command line. It is run by
+// cmd/chartscan and, with the renderers of a wrapper registered, by
+// pkg/chartscan.Main.
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Jaydee94/chartscan/internal/configfile"
+	"github.com/Jaydee94/chartscan/internal/doctor"
+	"github.com/Jaydee94/chartscan/internal/finder"
+	"github.com/Jaydee94/chartscan/internal/gate"
+	"github.com/Jaydee94/chartscan/internal/models"
+	"github.com/Jaydee94/chartscan/internal/progress"
+	"github.com/Jaydee94/chartscan/internal/release"
+	"github.com/Jaydee94/chartscan/internal/renderer"
+	"github.com/Jaydee94/chartscan/internal/rules"
+	"github.com/Jaydee94/chartscan/internal/ruletest"
+	"github.com/Jaydee94/chartscan/internal/scaffold"
+	"github.com/Jaydee94/chartscan/internal/schema"
+	"github.com/Jaydee94/chartscan/internal/strvals"
+	"github.com/Jaydee94/chartscan/internal/tracing"
+	"github.com/Jaydee94/chartscan/internal/validation"
+	"github.com/Jaydee94/chartscan/internal/valuesfmt"
+	"github.com/Jaydee94/chartscan/internal/valuessource"
+	"github.com/Jaydee94/chartscan/internal/watch"
+	"github.com/briandowns/spinner"
+	"github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/tw"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+)
+
+// version, commit and buildDate describe the running build. They are set by
+// Main.
+var (
+	version   = "dev"
+	commit    = ""
+	buildDate = ""
+)
+
+// helmBinary is the helm executable set with --helm-binary. It takes
+// precedence over the helmBinary of the config file.
+var helmBinary string
+
+// Main runs the chartscan command line with os.Args and exits the process
+// with its exit code. An empty buildVersion is reported as "dev"; an empty
+// buildCommit or date is taken from the version control information Go
+// embedded in the binary, if any.
+func Main(buildVersion, buildCommit, date string) {
+	if buildVersion != "" {
+		version = buildVersion
+	}
+	commit, buildDate = buildCommit, date
+
+	var configFile string
+	var listEnvironments bool
+
+	rootCmd := &cobra.Command{
+		Use:   "chartscan",
+		Short: "ChartScan is a tool to scan Helm charts",
+		PreRun: func(cmd *cobra.Command, args []string) {
+			if configFile == "" {
+				var err error
+				configFile, err = discoverConfigFile()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error finding config file: %v\n", err)
+					os.Exit(1)
+				}
+			}
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			if listEnvironments {
+				if err := listConfiguredEnvironments(configFile); err != nil {
+					fmt.Fprintf(os.Stderr, "Error listing environments: %v\n", err)
+					os.Exit(1)
+				}
+				os.Exit(0)
+			}
+			if len(args) == 0 {
+				cmd.Help() //nolint:errcheck
+				os.Exit(0)
+			}
+		},
+	}
+
+	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "", "Path to configuration file")
+	rootCmd.PersistentFlags().StringVar(&helmBinary, "helm-binary", "", "Path or name of the helm executable to run (default: helm from PATH)")
+	rootCmd.PersistentFlags().BoolVarP(&listEnvironments, "list-environments", "l", false, "List all configured environments if a chartscan.yaml is found or explicitly passed")
+
+	rootCmd.AddCommand(buildScanCmd())
+	rootCmd.AddCommand(buildWatchCmd())
+	rootCmd.AddCommand(buildTemplateCmd())
+	rootCmd.AddCommand(buildDiffCmd())
+	rootCmd.AddCommand(buildFuzzCmd())
+	rootCmd.AddCommand(buildSchemaCmd())
+	rootCmd.AddCommand(buildNewCmd())
+	rootCmd.AddCommand(buildFixCmd())
+	rootCmd.AddCommand(buildValuesCmd())
+	rootCmd.AddCommand(buildGraphCmd())
+	rootCmd.AddCommand(buildDepsCmd())
+	rootCmd.AddCommand(buildRulesCmd())
+	rootCmd.AddCommand(buildAssetsCmd())
+	rootCmd.AddCommand(buildGateCmd())
+	rootCmd.AddCommand(buildDoctorCmd())
+	rootCmd.AddCommand(buildVersionCmd())
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// buildScanCmd constructs and returns the `scan` subcommand.
+func buildScanCmd() *cobra.Command {
+	var (
+		configFile       string
+		valuesFiles      []string
+		format           string
+		environment      string
+		failOnError      bool
+		failOn           []string
+		setValues        strvals.Overrides
+		registryOpts     renderer.RegistryOptions
+		passwordStdin    bool
+		kubeVersion      string
+		crdSchemas       string
+		policyDirs       []string
+		threshold        string
+		includeDeps      bool
+		blame            bool
+		onlyNew          bool
+		baseRef          string
+		debug            bool
+		debugCharts      []string
+		cacheDir         string
+		depsFlags        models.DependenciesConfig
+		changedSince     string
+		timeout          time.Duration
+		noProgress       bool
+		outputFile       string
+		environments     []string
+		allEnvironments  bool
+		chartFilter      finder.ChartFilter
+		releaseName      string
+		namespace        string
+		postRenderer     string
+		postRendererArgs []string
+		experiments      []string
+		determinism      bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "scan [chart-path | chart.tgz | oci://registry/repo/chart:version | repo.git//path?ref=ref]...",
+		Short: "Scan Helm charts for potential issues",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if configFile == "" {
+				var err error
+				configFile, err = discoverConfigFile()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error finding config file: %v\n", err)
+					os.Exit(1)
+				}
+			}
+
+			if err := chartFilter.Validate(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitFatal)
+			}
+			if err := setValues.Validate(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitFatal)
+			}
+			if (allEnvironments || len(environments) > 0) && (environment != "" || len(valuesFiles) > 0) {
+				fmt.Fprintln(os.Stderr, "Error: --environments and --all-environments cannot be combined with --environment or --values")
+				os.Exit(exitFatal)
+			}
+
+			// scanConfig loads the configuration for environment, or for
+			// none if it is empty, and applies the flags to it.
+			scanConfig := func(environment string) *models.Config {
+				config, err := loadConfig(configFile, valuesFiles, format, args, environment)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+					os.Exit(1)
+				}
+				applyReleaseFlags(config, releaseName, namespace)
+				applyPostRendererFlags(config, postRenderer, postRendererArgs)
+				applyDependencyFlags(config, depsFlags)
+				if err := applyValidationFlags(config, kubeVersion, crdSchemas); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(exitFatal)
+				}
+				config.Policies.Dirs = append(config.Policies.Dirs, policyDirs...)
+				if determinism {
+					config.Determinism.Enabled = true
+				}
+				if len(failOn) > 0 {
+					config.FailOn = failOn
+				}
+				if err := validateFailOn(config.FailOn); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(exitFatal)
+				}
+				if err := applySeverityThreshold(config, threshold); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(exitFatal)
+				}
+				if err := applyExperiments(config, experiments); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(exitFatal)
+				}
+				return config
+			}
+			config := scanConfig(environment)
+			if err := requireHelm(config); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitFatal)
+			}
+			registryOpts.RegistryConfig = config.Dependencies.RegistryConfig
+			if err := readRegistryPassword(&registryOpts, passwordStdin, os.Stdin); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitFatal)
+			}
+
+			// Each chart is scanned once per run: once, or once per
+			// environment of the matrix.
+			runs := []scanRun{{config: config}}
+			if allEnvironments {
+				environments = slices.Sorted(maps.Keys(config.Environments))
+				if len(environments) == 0 {
+					fmt.Fprintln(os.Stderr, "Error: --all-environments requires environments in chartscan.yaml")
+					os.Exit(exitFatal)
+				}
+			}
+			if len(environments) > 0 {
+				runs = nil
+				for _, name := range environments {
+					runs = append(runs, scanRun{environment: name, config: scanConfig(name)})
+				}
+			}
+
+			startTime := time.Now()
+			var chartDirs, tempDirs []string
+			// chartSources maps pulled and extracted chart dirs to the
+			// reference or archive they came from.
+			chartSources := make(map[string]string)
+			// chartInfos holds the Chart.yaml metadata of discovered charts.
+			chartInfos := make(map[string]finder.ChartInfo)
+			removeTempDirs := func() {
+				for _, dir := range tempDirs {
+					os.RemoveAll(dir)
+				}
+				valuessource.Cleanup()
+			}
+			if config.Dependencies.Offline {
+				for _, chartPath := range args {
+					if finder.IsGitReference(chartPath) || renderer.IsOCIReference(chartPath) {
+						fmt.Fprintf(os.Stderr, "Error: cannot fetch %s in offline mode, scan a local copy instead\n", chartPath)
+						os.Exit(exitFatal)
+					}
+				}
+			}
+			// Git references are fetched together, so references into the
+			// same repository and ref share one sparse checkout.
+			var gitTargets []string
+			for _, chartPath := range args {
+				if finder.IsGitReference(chartPath) {
+					gitTargets = append(gitTargets, chartPath)
+				}
+			}
+			gitDirs := make(map[string]string)
+			if len(gitTargets) > 0 {
+				dirs, gitTempDirs, err := finder.CloneGitReferences(gitTargets)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error fetching %s: %v\n", strings.Join(gitTargets, ", "), err)
+					os.Exit(1)
+				}
+				tempDirs = append(tempDirs, gitTempDirs...)
+				for i, target := range gitTargets {
+					gitDirs[target] = dirs[i]
+				}
+			}
+			for _, chartPath := range args {
+				if renderer.IsOCIReference(chartPath) {
+					chartDir, tempDir, err := renderer.PullChart(chartPath, registryOpts)
+					if err != nil {
+						removeTempDirs()
+						fmt.Fprintf(os.Stderr, "Error pulling chart %s: %v\n", chartPath, err)
+						os.Exit(1)
+					}
+					tempDirs = append(tempDirs, tempDir)
+					chartSources[chartDir] = chartPath
+					chartDirs = append(chartDirs, chartDir)
+					continue
+				}
+
+				// Charts of a git reference are found in its checkout and
+				// reported by reference.
+				root := chartPath
+				source := func(path string) string { return path }
+				if dir, ok := gitDirs[chartPath]; ok {
+					gitRef, _ := finder.ParseGitReference(chartPath)
+					root = dir
+					source = func(path string) string {
+						rel, _ := filepath.Rel(dir, path)
+						return gitRef.Join(rel).String()
+					}
+				}
+
+				charts, err := finder.FindHelmCharts(root)
+				if err != nil {
+					removeTempDirs()
+					fmt.Fprintf(os.Stderr, "Error finding Helm charts in %s: %v\n", chartPath, err)
+					os.Exit(1)
+				}
+				for _, chart := range charts {
+					if src := source(chart.Path); src != chart.Path {
+						chartSources[chart.Path] = src
+					}
+					chartInfos[chart.Path] = chart
+					chartDirs = append(chartDirs, chart.Path)
+				}
+
+				archives, err := finder.FindHelmChartArchives(root)
+				if err != nil {
+					removeTempDirs()
+					fmt.Fprintf(os.Stderr, "Error finding chart archives in %s: %v\n", chartPath, err)
+					os.Exit(1)
+				}
+				for _, archive := range archives {
+					chartDir, tempDir, err := finder.ExtractChartArchive(archive)
+					if err != nil {
+						removeTempDirs()
+						fmt.Fprintf(os.Stderr, "Error extracting chart archive: %v\n", err)
+						os.Exit(1)
+					}
+					tempDirs = append(tempDirs, tempDir)
+					chartSources[chartDir] = source(archive)
+					chartDirs = append(chartDirs, chartDir)
+				}
+			}
+
+			if !chartFilter.Empty() {
+				var kept []string
+				for _, chartDir := range chartDirs {
+					info, ok := chartInfos[chartDir]
+					if !ok {
+						info = finder.ReadChartInfo(chartDir)
+						chartInfos[chartDir] = info
+					}
+					if chartFilter.Match(info) {
+						kept = append(kept, chartDir)
+					}
+				}
+				if len(kept) == 0 {
+					fmt.Fprintln(os.Stderr, "Warning: no charts match --only, --skip and --type")
+				}
+				chartDirs = kept
+			}
+
+			if changedSince != "" {
+				// Pulled, extracted and cloned charts have no local history
+				// and are always scanned.
+				var localDirs, remoteDirs []string
+				for _, chartDir := range chartDirs {
+					if _, ok := chartSources[chartDir]; ok {
+						remoteDirs = append(remoteDirs, chartDir)
+					} else {
+						localDirs = append(localDirs, chartDir)
+					}
+				}
+				// Chart-relative values files change with their chart.
+				var sharedFiles []string
+				if config.ValuesFilesRelativeTo != models.ValuesFilesRelativeToChart {
+					sharedFiles = slices.Clone(config.ValuesFiles)
+				}
+				if configFile != "" {
+					sharedFiles = append(sharedFiles, configFile)
+				}
+				changedDirs, err := renderer.ChangedCharts(localDirs, changedSince, sharedFiles)
+				if err != nil {
+					removeTempDirs()
+					fmt.Fprintf(os.Stderr, "Error finding charts changed since %s: %v\n", changedSince, err)
+					os.Exit(1)
+				}
+				chartDirs = append(changedDirs, remoteDirs...)
+			}
+
+			scanOpts := renderer.ScanOptions{
+				ValuesFiles:         config.ValuesFiles,
+				SetValues:           setValues,
+				Config:              *config,
+				IncludeDependencies: includeDeps,
+				Blame:               blame,
+				Debug:               debug,
+				DebugCharts:         debugCharts,
+				CacheDir:            cacheDir,
+				Charts:              chartInfos,
+			}
+			if onlyNew {
+				scanOpts.OnlyNewSince = baseRef
+			}
+			tracer, err := tracing.FromEnv(version)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: tracing disabled: %v\n", err)
+			}
+			scanOpts.Trace = tracer.Start("chartscan scan", startTime, tracing.Int("chartscan.charts", len(chartDirs)))
+			// Interrupts and timeouts still report the charts scanned so far.
+			scanCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				scanCtx, cancel = context.WithDeadline(scanCtx, startTime.Add(timeout))
+				defer cancel()
+			}
+			var results []models.Result
+			var runFindings []models.Finding
+			invalidCharts, incomplete := 0, 0
+			// Values files are only known to be unused when no chart was
+			// left out of the scan.
+			allCharts := chartFilter.Empty() && changedSince == ""
+			for _, run := range runs {
+				runOpts := scanOpts
+				runOpts.ValuesFiles, runOpts.Config = run.config.ValuesFiles, *run.config
+				runResults, runInvalid, pending := processCharts(scanCtx, chartDirs, runOpts, !noProgress)
+				if len(pending) == 0 && allCharts {
+					// Only a complete run shows that no chart uses a values file.
+					runFindings = append(runFindings, renderer.CheckUnusedValuesFiles(runResults, runOpts.ValuesFiles, &runOpts.Config)...)
+				}
+				if len(pending) > 0 {
+					reason := "the scan was interrupted"
+					if errors.Is(scanCtx.Err(), context.DeadlineExceeded) {
+						reason = fmt.Sprintf("the scan timed out after %v", timeout)
+					}
+					fmt.Fprintf(os.Stderr, "Reporting %d of %d charts%s: %s\n", len(runResults), len(chartDirs), run.label(), reason)
+					for _, chartDir := range pending {
+						runResults = append(runResults, renderer.IncompleteResult(chartDir, reason))
+					}
+				}
+				for i := range runResults {
+					setEnvironment(&runResults[i], run.environment)
+				}
+				results = append(results, runResults...)
+				invalidCharts += runInvalid
+				incomplete += len(pending)
+			}
+			stopSignals()
+			scanOpts.Trace.SetAttributes(tracing.Int("chartscan.invalid_charts", invalidCharts), tracing.Int("chartscan.incomplete_charts", incomplete))
+			scanOpts.Trace.End()
+			if err := tracer.Shutdown(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+			removeTempDirs()
+			for i := range results {
+				// Report pulled and extracted charts by their reference or
+				// archive, not the temp dir.
+				if source, ok := chartSources[results[i].ChartPath]; ok {
+					replaceChartPath(&results[i], results[i].ChartPath, source)
+				}
+			}
+			duration := time.Since(startTime)
+			results = renderer.ApplySeverityThreshold(results, config.SeverityThreshold)
+
+			runFindings = renderer.FindingsAtThreshold(runFindings, config.SeverityThreshold)
+
+			if err := writeResults(outputFile, results, runFindings, duration, config); err != nil {
+				fmt.Fprintf(os.Stderr, "Error processing results: %v\n", err)
+				os.Exit(1)
+			}
+			// json and yaml carry the debug logs in the results.
+			if config.Format != "json" && config.Format != "yaml" {
+				printDebugLogs(results)
+			}
+
+			if incomplete > 0 || (failOnError && invalidCharts > 0) {
+				os.Exit(exitFatal)
+			}
+			if code := failOnExitCode(results, runFindings, config.FailOn); code != exitOK {
+				os.Exit(code)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&configFile, "config", "c", "", "Path to configuration file")
+	cmd.Flags().StringSliceVarP(&valuesFiles, "values", "f", []string{}, "Specify values files for rendering (optional)")
+	cmd.Flags().StringVarP(&format, "output-format", "o", "pretty", "Output format ("+strings.Join(renderer.FormatNames(), ", ")+")")
+	cmd.Flags().StringVarP(&environment, "environment", "e", "", "(Optional) Specify the environment to use (e.g., test, staging, production).")
+	cmd.Flags().StringSliceVar(&environments, "environments", nil, "Scan every chart once for each of these environments and report a matrix of the results")
+	cmd.Flags().BoolVar(&allEnvironments, "all-environments", false, "Scan every chart once for each environment of the config file and report a matrix of the results")
+	cmd.Flags().BoolVar(&failOnError, "fail-on-error", false, "Exit with error code 1 if there are invalid charts")
+	cmd.Flags().MarkDeprecated("fail-on-error", "use --fail-on=error instead, which exits with code 2 rather than 1")
+	cmd.Flags().StringSliceVar(&failOn, "fail-on", nil, "Classes of problems that cause a non-zero exit: error, warning, undefined-values or none")
+	cmd.Flags().StringVar(&threshold, "severity-threshold", "", "Only report and fail on findings of this severity or higher: info, warning or error")
+	cmd.Flags().StringSliceVar(&experiments, "enable-experimental", nil, "Also run these experimental checks, in addition to the experimental section of the config (available: "+experimentNames()+")")
+	addSetFlags(cmd, &setValues)
+	cmd.Flags().StringVar(&releaseName, "release-name", "", "Release name to render charts with (default: helm's release-name)")
+	cmd.Flags().StringVarP(&namespace, "namespace", "n", "", "Namespace to render and lint charts in")
+	cmd.Flags().StringVar(&postRenderer, "post-renderer", "", "Pipe the rendered manifests through this executable, or build them with the kustomize overlay of kustomize:<dir>, before they are checked")
+	cmd.Flags().StringArrayVar(&postRendererArgs, "post-renderer-args", nil, "Argument passed to the post-renderer executable (repeatable)")
+	cmd.Flags().BoolVar(&includeDeps, "include-dependencies", false, "Also check the templates of each chart's subcharts and report them under the chart")
+	cmd.Flags().BoolVar(&blame, "blame", false, "Name the commit and author that last changed the source of each undefined value and finding (git blame)")
+	cmd.Flags().BoolVar(&onlyNew, "only-new", false, "Only report undefined values and findings on lines changed since the merge base with --base-ref")
+	cmd.Flags().StringVar(&baseRef, "base-ref", "origin/HEAD", "Git ref the current branch is compared against with --only-new")
+	cmd.Flags().StringSliceVar(&chartFilter.Only, "only", nil, "Only scan charts whose name matches this shell pattern (repeatable)")
+	cmd.Flags().StringSliceVar(&chartFilter.Skip, "skip", nil, "Do not scan charts whose name matches this shell pattern (repeatable)")
+	cmd.Flags().StringVar(&chartFilter.Type, "type", "", "Only scan charts of this type: application or library")
+	cmd.Flags().StringVar(&changedSince, "changed-since", "", "Only scan charts with files changed since the merge base of this git ref and HEAD")
+	cmd.Flags().StringVar(&kubeVersion, "kube-version", "", "Validate rendered manifests against the Kubernetes schemas of this version (e.g. 1.29)")
+	cmd.Flags().StringVar(&crdSchemas, "crd-schemas", "", "Validate custom resources against the CRDs in this file or directory, or read from this kubeconfig context (requires --kube-version)")
+	cmd.Flags().StringSliceVar(&policyDirs, "policy-dir", nil, "Evaluate the Rego policies in this directory against the rendered manifests (repeatable)")
+	cmd.Flags().BoolVar(&determinism, "check-determinism", false, "Render every chart twice and report resources that differ between the renderings")
+	cmd.Flags().BoolVar(&debug, "debug", false, "Print the stack trace of internal errors to stderr")
+	cmd.Flags().StringSliceVar(&debugCharts, "debug-chart", nil, "Record the scan stages and helm output of charts matching this path, glob or directory name (repeatable)")
+	cmd.Flags().StringVar(&cacheDir, "cache-dir", renderer.DefaultCacheDir(), "Directory caching downloaded chart dependencies between runs (empty disables caching)")
+	cmd.Flags().StringVar(&depsFlags.RepositoryConfig, "repository-config", "", "Path to the helm repository config used to update dependencies (overrides dependencies.repositoryConfig)")
+	cmd.Flags().StringVar(&depsFlags.RepositoryCache, "repository-cache", "", "Path to the helm repository cache used to update dependencies (default: below --cache-dir)")
+	cmd.Flags().BoolVar(&depsFlags.SkipRefresh, "skip-refresh", false, "Update dependencies with the repository indexes in the repository cache instead of downloading them")
+	cmd.Flags().BoolVar(&depsFlags.Offline, "offline", false, "Never download chart dependencies: use only those vendored in charts/ or cached")
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "Stop scanning after this long (e.g. 10m) and report the charts finished so far (0 disables)")
+	cmd.Flags().BoolVar(&noProgress, "no-progress", false, "Do not show the progress bar or the status line of each finished chart")
+	cmd.Flags().StringVar(&outputFile, "output-file", "", "Write the report to this file instead of stdout")
+	addRegistryCredentialFlags(cmd, &registryOpts, &passwordStdin, "oci:// charts")
+	cmd.Flags().StringVar(&depsFlags.RegistryConfig, "registry-config", "", "Path to the helm registry config file for pulling oci:// charts and dependencies (overrides dependencies.registryConfig)")
+
+	return cmd
+}
+
+// printResults writes results to w in the output format of config, built in
+// or registered through pkg/chartscan. json and yaml wrap the results in a
+// report with the totals of the scan and config.
+func printResults(w io.Writer, results []models.Result, runFindings []models.Finding, duration time.Duration, config *models.Config) error {
+	var output []byte
+	var err error
+	switch format := config.Format; format {
+	case "pretty":
+		renderer.PrintResultsPretty(w, results, duration)
+	case "json":
+		output, err = json.MarshalIndent(renderer.NewReport(results, runFindings, duration, version, *config), "", "  ")
+	case "yaml":
+		output, err = yaml.Marshal(renderer.NewReport(results, runFindings, duration, version, *config))
+	case "junit":
+		err = renderer.PrintResultsJUnit(w, results, duration, renderer.JUnitOptions{
+			Started:     time.Now().Add(-duration),
+			HelmVersion: renderer.HelmVersion(),
+			KubeVersion: config.Validation.KubeVersion,
+			Environment: config.Environment,
+			Experiments: config.Experimental,
+		})
+	case "markdown":
+		renderer.PrintResultsMarkdown(w, results, duration)
+	case "github":
+		renderer.PrintResultsGitHub(w, results, duration)
+	case "teamcity":
+		renderer.PrintResultsTeamCity(w, results, duration)
+	case "azuredevops":
+		renderer.PrintResultsAzureDevOps(w, results, duration)
+	default:
+		custom, ok := renderer.LookupFormat(format)
+		if !ok {
+			return fmt.Errorf("unknown output format: %s (expected %s)", format, strings.Join(renderer.FormatNames(), ", "))
+		}
+		err = custom(w, results, duration)
+	}
+
+	if err != nil {
+		return err
+	}
+	if output != nil {
+		fmt.Fprintln(w, string(output))
+	} else {
+		// The other formats have no place for findings of the whole run.
+		for _, finding := range runFindings {
+			fmt.Fprintf(os.Stderr, "%s\n", finding)
+		}
+	}
+	return nil
+}
+
+// writeResults writes results in the output format of config to outputFile,
+// or to stdout when outputFile is empty.
+func writeResults(outputFile string, results []models.Result, runFindings []models.Finding, duration time.Duration, config *models.Config) error {
+	if outputFile == "" {
+		return printResults(os.Stdout, results, runFindings, duration, config)
+	}
+	file, err := os.Create(outputFile)
+	if err != nil {
+		return fmt.Errorf("error creating output file: %v", err)
+	}
+	if err := printResults(file, results, runFindings, duration, config); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// printDebugLogs prints the debug logs of results to stderr.
+func printDebugLogs(results []models.Result) {
+	for _, result := range models.FlattenResults(results) {
+		if len(result.DebugLog) == 0 {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "\n--- Debug log of %s ---\n%s\n", result.ChartPath, strings.Join(result.DebugLog, "\n"))
+	}
+}
+
+// scanRun is one pass of the scan command over all charts, for an
+// environment of a matrix scan or, with an empty environment, for the
+// configuration as loaded.
+type scanRun struct {
+	environment string
+	config      *models.Config
+}
+
+// label names the environment of the run in messages.
+func (r scanRun) label() string {
+	if r.environment == "" {
+		return ""
+	}
+	return " for " + r.environment
+}
+
+// setEnvironment records environment in result and the results of its
+// dependencies.
+func setEnvironment(result *models.Result, environment string) {
+	result.Environment = environment
+	for i := range result.Dependencies {
+		setEnvironment(&result.Dependencies[i], environment)
+	}
+}
+
+// replaceChartPath replaces the chart path prefix from with to in result and
+// the results of its dependencies.
+func replaceChartPath(result *models.Result, from, to string) {
+	if rest, ok := strings.CutPrefix(result.ChartPath, from); ok {
+		result.ChartPath = to + rest
+	}
+	for i := range result.Dependencies {
+		replaceChartPath(&result.Dependencies[i], from, to)
+	}
+}
+
+// Exit codes of the scan command. Each --fail-on class has its own code so
+// pipelines can tell them apart.
+const (
+	exitOK              = 0
+	exitFatal           = 1
+	exitChartErrors     = 2
+	exitWarnings        = 3
+	exitUndefinedValues = 4
+)
+
+// failOnClasses are the accepted --fail-on values.
+var failOnClasses = []string{"error", "warning", "undefined-values", "none"}
+
+// addSetFlags adds the --set, --set-string and --set-file flags, collected
+// into overrides, to cmd.
+func addSetFlags(cmd *cobra.Command, overrides *strvals.Overrides) {
+	cmd.Flags().StringArrayVar(&overrides.Values, "set", nil, "Set values on the command line (key1=val1,key2=val2)")
+	cmd.Flags().StringArrayVar(&overrides.StringValues, "set-string", nil, "Set STRING values on the command line (key1=val1,key2=val2)")
+	cmd.Flags().StringArrayVar(&overrides.FileValues, "set-file", nil, "Set values from the contents of files (key1=path1,key2=path2)")
+}
+
+// registryPasswordEnv holds the password for pulling oci:// charts when no
+// password flag is set, so it stays out of the process arguments.
+const registryPasswordEnv = "CHARTSCAN_REGISTRY_PASSWORD"
+
+// addRegistryCredentialFlags adds the --registry-username,
+// --registry-password and --registry-password-stdin flags for pulling what
+// to cmd.
+func addRegistryCredentialFlags(cmd *cobra.Command, opts *renderer.RegistryOptions, passwordStdin *bool, what string) {
+	cmd.Flags().StringVar(&opts.Username, "registry-username", "", "Username for pulling "+what)
+	cmd.Flags().StringVar(&opts.Password, "registry-password", "", "Password for pulling "+what+" (visible to other processes; prefer --registry-password-stdin or $"+registryPasswordEnv+")")
+	cmd.Flags().BoolVar(passwordStdin, "registry-password-stdin", false, "Read the password for pulling "+what+" from stdin")
+	cmd.MarkFlagsMutuallyExclusive("registry-password", "registry-password-stdin")
+}
+
+// readRegistryPassword sets the password of opts from stdin with fromStdin,
+// stripping the trailing newline as `helm registry login` does, or else from
+// $CHARTSCAN_REGISTRY_PASSWORD unless --registry-password was set.
+func readRegistryPassword(opts *renderer.RegistryOptions, fromStdin bool, stdin io.Reader) error {
+	if !fromStdin {
+		if opts.Password == "" {
+			opts.Password = os.Getenv(registryPasswordEnv)
+		}
+		return nil
+	}
+	data, err := io.ReadAll(stdin)
+	if err != nil {
+		return fmt.Errorf("error reading the registry password from stdin: %v", err)
+	}
+	opts.Password = strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r")
+	if opts.Password == "" {
+		return fmt.Errorf("--registry-password-stdin read an empty password")
+	}
+	return nil
+}
+
+// applyReleaseFlags overrides the release name and namespace of config with
+// the --release-name and --namespace flags.
+func applyReleaseFlags(config *models.Config, releaseName, namespace string) {
+	if releaseName != "" {
+		config.ReleaseName = releaseName
+	}
+	if namespace != "" {
+		config.Namespace = namespace
+	}
+}
+
+// kustomizePrefix marks a --post-renderer value as a kustomize overlay
+// directory rather than an executable.
+const kustomizePrefix = "kustomize:"
+
+// applyPostRendererFlags replaces the post-renderer of config with the one
+// given by --post-renderer, and its arguments with --post-renderer-args.
+func applyPostRendererFlags(config *models.Config, postRenderer string, args []string) {
+	if dir, ok := strings.CutPrefix(postRenderer, kustomizePrefix); ok {
+		config.PostRenderer = models.PostRendererConfig{Kustomize: dir}
+		return
+	}
+	if postRenderer != "" {
+		config.PostRenderer = models.PostRendererConfig{Command: postRenderer}
+	}
+	if len(args) > 0 {
+		config.PostRenderer.Args = args
+	}
+}
+
+// applyDependencyFlags overrides the dependencies section of config with the
+// --repository-config, --repository-cache, --registry-config and
+// --skip-refresh and --offline flags in flags.
+func applyDependencyFlags(config *models.Config, flags models.DependenciesConfig) {
+	if flags.RepositoryConfig != "" {
+		config.Dependencies.RepositoryConfig = flags.RepositoryConfig
+	}
+	if flags.RepositoryCache != "" {
+		config.Dependencies.RepositoryCache = flags.RepositoryCache
+	}
+	if flags.RegistryConfig != "" {
+		config.Dependencies.RegistryConfig = flags.RegistryConfig
+	}
+	if flags.SkipRefresh {
+		config.Dependencies.SkipRefresh = true
+	}
+	if flags.Offline {
+		config.Dependencies.Offline = true
+	}
+}
+
+// applyValidationFlags overrides the validation settings of config with the
+// --kube-version and --crd-schemas flags.
+func applyValidationFlags(config *models.Config, kubeVersion, crdSchemas string) error {
+	if kubeVersion != "" {
+		config.Validation.KubeVersion = kubeVersion
+	}
+	if crdSchemas != "" {
+		config.Validation.CRDSchemas = crdSchemas
+	}
+	if config.Validation.CRDSchemas != "" && config.Validation.KubeVersion == "" {
+		return fmt.Errorf("CRD schemas are only used to validate manifests; set --kube-version too")
+	}
+	return nil
+}
+
+// applySeverityThreshold overrides the severity threshold of config with the
+// --severity-threshold flag and rejects unknown severities.
+func applySeverityThreshold(config *models.Config, threshold string) error {
+	if threshold != "" {
+		config.SeverityThreshold = threshold
+	}
+	if config.SeverityThreshold != "" && models.SeverityRank(config.SeverityThreshold) < 0 {
+		return fmt.Errorf("invalid severity threshold %q (expected one of %s)", config.SeverityThreshold, strings.Join(models.Severities, ", "))
+	}
+	return nil
+}
+
+// applyExperiments adds the experiments of the --enable-experimental flag to
+// those config opts into and rejects unknown experiments.
+func applyExperiments(config *models.Config, experiments []string) error {
+	for _, name := range experiments {
+		if !slices.Contains(config.Experimental, name) {
+			config.Experimental = append(config.Experimental, name)
+		}
+	}
+	return rules.ValidateExperiments(config.Experimental)
+}
+
+// experimentNames lists the registered experiments for flag help.
+func experimentNames() string {
+	var names []string
+	for _, experiment := range rules.Experiments() {
+		names = append(names, experiment.Name)
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+// validateFailOn rejects unknown --fail-on classes.
+func validateFailOn(classes []string) error {
+	for _, class := range classes {
+		if !slices.Contains(failOnClasses, class) {
+			return fmt.Errorf("invalid --fail-on value %q (expected one of %s)", class, strings.Join(failOnClasses, ", "))
+		}
+	}
+	return nil
+}
+
+// failOnExitCode returns the exit code for results and the findings of the
+// whole run under the selected --fail-on classes. Chart errors take
+// precedence over undefined values, which take precedence over warnings. Tool
+// errors always exit with exitFatal.
+func failOnExitCode(results []models.Result, runFindings []models.Finding, classes []string) int {
+	var chartErrors, undefinedValues, warnings bool
+	for _, finding := range runFindings {
+		chartErrors = chartErrors || finding.Severity == models.SeverityError
+		warnings = warnings || finding.Severity == models.SeverityWarning
+	}
+	for _, result := range models.FlattenResults(results) {
+		if result.ToolError != "" {
+			return exitFatal
+		}
+		chartErrors = chartErrors || !result.Success
+		for _, finding := range result.Findings {
+			undefinedValues = undefinedValues || finding.RuleID == renderer.UndefinedValueID
+			warnings = warnings || finding.Severity == models.SeverityWarning
+		}
+	}
+
+	switch {
+	case slices.Contains(classes, "error") && chartErrors:
+		return exitChartErrors
+	case slices.Contains(classes, "undefined-values") && undefinedValues:
+		return exitUndefinedValues
+	case slices.Contains(classes, "warning") && warnings:
+		return exitWarnings
+	default:
+		return exitOK
+	}
+}
+
+// buildWatchCmd constructs and returns the `watch` subcommand.
+func buildWatchCmd() *cobra.Command {
+	var (
+		configFile  string
+		valuesFiles []string
+		format      string
+		environment string
+		setValues   strvals.Overrides
+		kubeVersion string
+		crdSchemas  string
+		policyDirs  []string
+		threshold   string
+		includeDeps bool
+		interval    time.Duration
+		poll        bool
+		cacheDir    string
+		depsFlags   models.DependenciesConfig
+		noProgress  bool
+		experiments []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "watch [chart-path]...",
+		Short: "Rescan charts whenever their templates or values change",
+		Long: `Scan charts once, then keep watching them and rescan every chart whose
+files change. Changes to values files or the config file outside of the
+charts rescan all charts. Press Ctrl+C to stop.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				args = []string{"."}
+			}
+			if configFile == "" {
+				var err error
+				configFile, err = discoverConfigFile()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error finding config file: %v\n", err)
+					os.Exit(1)
+				}
+			}
+
+			var config *models.Config
+			var scanOpts renderer.ScanOptions
+			reloadConfig := func() error {
+				loaded, err := loadConfig(configFile, valuesFiles, format, args, environment)
+				if err != nil {
+					return err
+				}
+				if err := applyValidationFlags(loaded, kubeVersion, crdSchemas); err != nil {
+					return err
+				}
+				loaded.Policies.Dirs = append(loaded.Policies.Dirs, policyDirs...)
+				applyDependencyFlags(loaded, depsFlags)
+				if err := applySeverityThreshold(loaded, threshold); err != nil {
+					return err
+				}
+				if err := applyExperiments(loaded, experiments); err != nil {
+					return err
+				}
+				config = loaded
+				scanOpts = renderer.ScanOptions{
+					ValuesFiles:         config.ValuesFiles,
+					SetValues:           setValues,
+					Config:              *config,
+					IncludeDependencies: includeDeps,
+					CacheDir:            cacheDir,
+				}
+				return nil
+			}
+			if err := reloadConfig(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+				os.Exit(1)
+			}
+			if err := requireHelm(config); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitFatal)
+			}
+
+			findCharts := func() []string {
+				var chartDirs []string
+				for _, chartPath := range args {
+					dirs, err := finder.FindHelmChartDirs(chartPath)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "Error finding Helm charts in %s: %v\n", chartPath, err)
+						continue
+					}
+					chartDirs = append(chartDirs, dirs...)
+				}
+				return chartDirs
+			}
+			scan := func(chartDirs []string) {
+				startTime := time.Now()
+				rules.ResetPolicies()
+				results, _, _ := processCharts(context.Background(), chartDirs, scanOpts, !noProgress)
+				results = renderer.ApplySeverityThreshold(results, config.SeverityThreshold)
+				if err := printResults(os.Stdout, results, nil, time.Since(startTime), config); err != nil {
+					fmt.Fprintf(os.Stderr, "Error processing results: %v\n", err)
+				}
+			}
+
+			chartDirs := findCharts()
+			scan(chartDirs)
+
+			// Watched paths keep the form they were given in, so changed files
+			// compare directly to the chart dirs found under the same args.
+			watched := append([]string{}, args...)
+			if config.ValuesFilesRelativeTo != models.ValuesFilesRelativeToChart {
+				for _, file := range config.ValuesFiles {
+					if !valuessource.IsURI(file) {
+						watched = append(watched, file)
+					}
+				}
+			}
+			if configFile != "" {
+				watched = append(watched, configFile)
+			}
+			var watcher *watch.Watcher
+			if poll {
+				watcher = watch.NewPolling(watched...)
+			} else {
+				var err error
+				if watcher, err = watch.New(watched...); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v (use --poll to check files by polling instead)\n", err)
+					os.Exit(exitFatal)
+				}
+			}
+
+			stop := make(chan struct{})
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+			go func() {
+				<-signals
+				close(stop)
+			}()
+
+			fmt.Fprintf(os.Stderr, "Watching %s for changes. Press Ctrl+C to stop.\n", strings.Join(args, ", "))
+			watcher.Run(interval, stop, func(changed []string) {
+				fmt.Fprintf(os.Stderr, "\n[%s] Changed: %s\n", time.Now().Format(time.TimeOnly), strings.Join(changed, ", "))
+
+				rescanAll := false
+				if configFile != "" && slices.Contains(changed, configFile) {
+					// Remote values files are fetched again with the config.
+					valuessource.Cleanup()
+					if err := reloadConfig(); err != nil {
+						fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+						return
+					}
+					rescanAll = true
+				}
+
+				known := chartDirs
+				chartDirs = findCharts()
+				for _, file := range changed {
+					// A changed file outside every chart is a shared values file.
+					if !slices.ContainsFunc(chartDirs, func(chartDir string) bool { return watch.Under(file, chartDir) }) {
+						rescanAll = true
+					}
+				}
+
+				var affected []string
+				for _, chartDir := range chartDirs {
+					if rescanAll || !slices.Contains(known, chartDir) ||
+						slices.ContainsFunc(changed, func(file string) bool { return watch.Under(file, chartDir) }) {
+						affected = append(affected, chartDir)
+					}
+				}
+				if len(affected) == 0 {
+					return
+				}
+				scan(affected)
+			})
+			valuessource.Cleanup()
+		},
+	}
+
+	cmd.Flags().StringVarP(&configFile, "config", "c", "", "Path to configuration file")
+	cmd.Flags().StringSliceVarP(&valuesFiles, "values", "f", []string{}, "Specify values files for rendering (optional)")
+	cmd.Flags().StringVarP(&format, "output-format", "o", "pretty", "Output format ("+strings.Join(renderer.FormatNames(), ", ")+")")
+	cmd.Flags().StringVarP(&environment, "environment", "e", "", "(Optional) Specify the environment to use (e.g., test, staging, production).")
+	addSetFlags(cmd, &setValues)
+	cmd.Flags().BoolVar(&includeDeps, "include-dependencies", false, "Also check the templates of each chart's subcharts and report them under the chart")
+	cmd.Flags().StringVar(&kubeVersion, "kube-version", "", "Validate rendered manifests against the Kubernetes schemas of this version (e.g. 1.29)")
+	cmd.Flags().StringVar(&crdSchemas, "crd-schemas", "", "Validate custom resources against the CRDs in this file or directory, or read from this kubeconfig context (requires --kube-version)")
+	cmd.Flags().StringSliceVar(&policyDirs, "policy-dir", nil, "Evaluate the Rego policies in this directory against the rendered manifests (repeatable)")
+	cmd.Flags().StringVar(&threshold, "severity-threshold", "", "Only report findings of this severity or higher: info, warning or error")
+	cmd.Flags().StringSliceVar(&experiments, "enable-experimental", nil, "Also run these experimental checks, in addition to the experimental section of the config (available: "+experimentNames()+")")
+	cmd.Flags().DurationVar(&interval, "interval", 500*time.Millisecond, "How long to wait for further changes before rescanning, and with --poll how often to check files")
+	cmd.Flags().BoolVar(&poll, "poll", false, "Check files for changes by polling their modification times, e.g. on network filesystems that do not report changes")
+	cmd.Flags().BoolVar(&noProgress, "no-progress", false, "Do not show the progress bar or the status line of each finished chart")
+	cmd.Flags().StringVar(&cacheDir, "cache-dir", renderer.DefaultCacheDir(), "Directory caching downloaded chart dependencies between runs (empty disables caching)")
+	cmd.Flags().StringVar(&depsFlags.RepositoryConfig, "repository-config", "", "Path to the helm repository config used to update dependencies (overrides dependencies.repositoryConfig)")
+	cmd.Flags().StringVar(&depsFlags.RepositoryCache, "repository-cache", "", "Path to the helm repository cache used to update dependencies (default: below --cache-dir)")
+	cmd.Flags().BoolVar(&depsFlags.SkipRefresh, "skip-refresh", false, "Update dependencies with the repository indexes in the repository cache instead of downloading them")
+	cmd.Flags().BoolVar(&depsFlags.Offline, "offline", false, "Never download chart dependencies: use only those vendored in charts/ or cached")
+
+	return cmd
+}
+
+// buildTemplateCmd constructs and returns the `template` subcommand.
+func buildTemplateCmd() *cobra.Command {
+	var (
+		configFile       string
+		valuesFiles      []string
+		outputFile       string
+		environment      string
+		setValues        strvals.Overrides
+		cacheDir         string
+		depsFlags        models.DependenciesConfig
+		releaseName      string
+		namespace        string
+		postRenderer     string
+		postRendererArgs []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "template [chart-path | chart.tgz]...",
+		Short: "Render Helm charts using helm template",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if configFile == "" {
+				var err error
+				configFile, err = discoverConfigFile()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error finding config file: %v\n", err)
+					os.Exit(1)
+				}
+			}
+
+			config, err := loadConfig(configFile, valuesFiles, "", args, environment)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+				os.Exit(1)
+			}
+			if err := requireHelm(config); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitFatal)
+			}
+			applyReleaseFlags(config, releaseName, namespace)
+			applyDependencyFlags(config, depsFlags)
+			applyPostRendererFlags(config, postRenderer, postRendererArgs)
+
+			s := spinner.New(spinner.CharSets[4], 100*time.Millisecond, spinner.WithWriter(os.Stderr))
+			s.Start()
+			defer s.Stop()
+
+			for _, chartPath := range args {
+				s.Suffix = fmt.Sprintf(" Templating: %s", chartPath)
+				if err := templateChart(chartPath, config, setValues, outputFile, cacheDir); err != nil {
+					fmt.Fprintf(os.Stderr, "Error rendering chart %s: %v\n", chartPath, err)
+					s.Stop()
+					valuessource.Cleanup()
+					os.Exit(1)
+				}
+			}
+			valuessource.Cleanup()
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&valuesFiles, "values", "f", nil, "Specify values files for rendering")
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file to write the rendered chart (optional)")
+	cmd.Flags().StringVarP(&configFile, "config", "c", "", "Path to configuration file")
+	cmd.Flags().StringVarP(&environment, "environment", "e", "", "(Optional) Specify the environment to use.")
+	addSetFlags(cmd, &setValues)
+	cmd.Flags().StringVar(&cacheDir, "cache-dir", renderer.DefaultCacheDir(), "Directory caching downloaded chart dependencies between runs (empty disables caching)")
+	cmd.Flags().StringVar(&depsFlags.RepositoryConfig, "repository-config", "", "Path to the helm repository config used to update dependencies (overrides dependencies.repositoryConfig)")
+	cmd.Flags().StringVar(&depsFlags.RepositoryCache, "repository-cache", "", "Path to the helm repository cache used to update dependencies (default: below --cache-dir)")
+	cmd.Flags().BoolVar(&depsFlags.SkipRefresh, "skip-refresh", false, "Update dependencies with the repository indexes in the repository cache instead of downloading them")
+	cmd.Flags().BoolVar(&depsFlags.Offline, "offline", false, "Never download chart dependencies: use only those vendored in charts/ or cached")
+	cmd.Flags().StringVar(&releaseName, "release-name", "", "Release name to render with (default: the chart directory name)")
+	cmd.Flags().StringVarP(&namespace, "namespace", "n", "", "Namespace to render the release in")
+	cmd.Flags().StringVar(&postRenderer, "post-renderer", "", "Pipe the rendered manifests through this executable, or build them with the kustomize overlay of kustomize:<dir>")
+	cmd.Flags().StringArrayVar(&postRendererArgs, "post-renderer-args", nil, "Argument passed to the post-renderer executable (repeatable)")
+
+	return cmd
+}
+
+// templateChart renders a chart directory or packaged chart archive with the
+// values files, release and post-renderer of config.
+func templateChart(chartPath string, config *models.Config, setValues strvals.Overrides, outputFile, cacheDir string) error {
+	if finder.IsChartArchive(chartPath) {
+		chartDir, tempDir, err := finder.ExtractChartArchive(chartPath)
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tempDir)
+		chartPath = chartDir
+	}
+	valuesFiles := renderer.ChartValuesFiles(chartPath, config.ValuesFiles, config.ValuesFilesRelativeTo)
+	return renderer.TemplateHelmChart(chartPath, config.ReleaseName, config.Namespace, valuesFiles, setValues, outputFile, cacheDir, config.Dependencies, config.PostRenderer)
+}
+
+// buildDiffCmd constructs and returns the `diff` subcommand.
+func buildDiffCmd() *cobra.Command {
+	var (
+		configFile   string
+		environments []string
+		fromValues   []string
+		toValues     []string
+		fromRef      string
+		toRef        string
+		setValues    strvals.Overrides
+		cacheDir     string
+		depsFlags    models.DependenciesConfig
+		exitCode     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "diff <chart-path | chart.tgz>",
+		Short: "Compare a chart's rendered manifests across environments, values files or git revisions",
+		Long: `Render a chart twice and print a unified diff of the manifests, matched by
+kind, namespace and name.
+
+Compare two environments of the config file:
+
+  chartscan diff ./charts/web --environment staging --environment production
+
+two sets of values files:
+
+  chartscan diff ./charts/web --from-values values-staging.yaml --to-values values-production.yaml
+
+or two git revisions of the chart, e.g. to review a pull request:
+
+  chartscan diff ./charts/web --from origin/main --to HEAD --environment production
+
+Without --to, the revision is compared with the working tree. Values files
+inside the chart are taken from each revision.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if configFile == "" {
+				var err error
+				configFile, err = discoverConfigFile()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error finding config file: %v\n", err)
+					os.Exit(1)
+				}
+			}
+			if toRef != "" && fromRef == "" {
+				fmt.Fprintln(os.Stderr, "Error: --to requires --from")
+				os.Exit(1)
+			}
+			compareRefs := fromRef != ""
+			config, err := loadConfig(configFile, nil, "", args, "")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+				os.Exit(1)
+			}
+			if err := requireHelm(config); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitFatal)
+			}
+			applyDependencyFlags(config, depsFlags)
+
+			type side struct {
+				label       string
+				ref         string
+				valuesFiles []string
+			}
+			sides := [2]side{{ref: fromRef}, {ref: toRef}}
+			environmentValues := func(environment string) []string {
+				config, err := loadConfig(configFile, nil, "", args, environment)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+					os.Exit(1)
+				}
+				return renderer.ChartValuesFiles(args[0], config.ValuesFiles, config.ValuesFilesRelativeTo)
+			}
+			switch {
+			case len(environments) == 2 && len(fromValues) == 0 && len(toValues) == 0:
+				for i, environment := range environments {
+					sides[i].label, sides[i].valuesFiles = environment, environmentValues(environment)
+				}
+			case len(environments) == 1 && compareRefs && len(fromValues) == 0 && len(toValues) == 0:
+				valuesFiles := environmentValues(environments[0])
+				for i := range sides {
+					sides[i].label, sides[i].valuesFiles = environments[0], valuesFiles
+				}
+			case len(environments) == 0 && (len(fromValues) > 0 || len(toValues) > 0 || compareRefs):
+				sides[0].label, sides[0].valuesFiles = valuesLabel(fromValues), fromValues
+				sides[1].label, sides[1].valuesFiles = valuesLabel(toValues), toValues
+			default:
+				fmt.Fprintln(os.Stderr, "Error: pass --environment twice, --from-values and --to-values, or --from with a git revision")
+				os.Exit(1)
+			}
+			if compareRefs {
+				sameValues := sides[0].label == sides[1].label
+				for i := range sides {
+					revision := sides[i].ref
+					if revision == "" {
+						revision = "working tree"
+					}
+					if sameValues {
+						sides[i].label = revision
+					} else {
+						sides[i].label = revision + " (" + sides[i].label + ")"
+					}
+				}
+			}
+
+			chartPath := args[0]
+			if finder.IsChartArchive(chartPath) {
+				if compareRefs {
+					fmt.Fprintln(os.Stderr, "Error: --from and --to need a chart directory in a git repository")
+					os.Exit(1)
+				}
+				chartDir, tempDir, err := finder.ExtractChartArchive(chartPath)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error extracting %s: %v\n", chartPath, err)
+					os.Exit(1)
+				}
+				defer os.RemoveAll(tempDir)
+				chartPath = chartDir
+			}
+
+			var rendered [2]string
+			for i, side := range sides {
+				sideChart, valuesFiles := chartPath, side.valuesFiles
+				if side.ref != "" {
+					chartDir, tempDir, err := finder.CheckoutChartRevision(chartPath, side.ref)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "Error checking out %s at %s: %v\n", chartPath, side.ref, err)
+						os.Exit(1)
+					}
+					defer os.RemoveAll(tempDir)
+					sideChart, valuesFiles = chartDir, revisionValuesFiles(chartPath, chartDir, valuesFiles)
+				}
+				var err error
+				if rendered[i], err = renderer.RenderHelmChart(sideChart, "", "", valuesFiles, setValues, cacheDir, config.Dependencies, models.PostRendererConfig{}); err != nil {
+					fmt.Fprintf(os.Stderr, "Error rendering chart %s for %s: %v\n", args[0], side.label, err)
+					valuessource.Cleanup()
+					os.Exit(1)
+				}
+				// The diff may end up in CI logs, so secrets are redacted.
+				rendered[i] = valuessource.Redact(rendered[i])
+			}
+			valuessource.Cleanup()
+
+			if renderer.WriteManifestDiff(os.Stdout, sides[0].label, rendered[0], sides[1].label, rendered[1]) && exitCode {
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&configFile, "config", "c", "", "Path to configuration file")
+	cmd.Flags().StringSliceVarP(&environments, "environment", "e", nil, "Environment of the config file to compare; pass twice, or once with --from to use it for both revisions")
+	cmd.Flags().StringSliceVar(&fromValues, "from-values", nil, "Values files of the first rendering (repeatable)")
+	cmd.Flags().StringSliceVar(&toValues, "to-values", nil, "Values files of the second rendering (repeatable)")
+	cmd.Flags().StringVar(&fromRef, "from", "", "Git revision (branch, tag or commit) of the chart for the first rendering")
+	cmd.Flags().StringVar(&toRef, "to", "", "Git revision of the chart for the second rendering (default: the working tree)")
+	addSetFlags(cmd, &setValues)
+	cmd.Flags().StringVar(&cacheDir, "cache-dir", renderer.DefaultCacheDir(), "Directory caching downloaded chart dependencies between runs (empty disables caching)")
+	cmd.Flags().StringVar(&depsFlags.RepositoryConfig, "repository-config", "", "Path to the helm repository config used to update dependencies (overrides dependencies.repositoryConfig)")
+	cmd.Flags().StringVar(&depsFlags.RepositoryCache, "repository-cache", "", "Path to the helm repository cache used to update dependencies (default: below --cache-dir)")
+	cmd.Flags().BoolVar(&depsFlags.SkipRefresh, "skip-refresh", false, "Update dependencies with the repository indexes in the repository cache instead of downloading them")
+	cmd.Flags().BoolVar(&depsFlags.Offline, "offline", false, "Never download chart dependencies: use only those vendored in charts/ or cached")
+	cmd.Flags().BoolVar(&exitCode, "exit-code", false, "Exit with status 1 when the renderings differ")
+
+	return cmd
+}
+
+// buildFuzzCmd constructs and returns the experimental `fuzz` subcommand.
+func buildFuzzCmd() *cobra.Command {
+	var (
+		configFile  string
+		valuesFiles []string
+		environment string
+		setValues   strvals.Overrides
+		cacheDir    string
+		depsFlags   models.DependenciesConfig
+		limit       int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "fuzz <chart-path | chart.tgz>",
+		Short: "Render a chart with mutated values to find template crashes (experimental)",
+		Long: `Render a chart once per mutation of its values and report the templates
+that crash, e.g. with "nil pointer evaluating", instead of handling the values
+gracefully.
+
+Every value the templates use is dropped, emptied (strings and lists) and
+replaced with a value of another type, one mutation at a time, on top of the
+chart's values files. Mutations that the chart's values.schema.json rules out
+are skipped, and templates that reject a mutation with required or fail are
+considered to handle it. This command is experimental.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if configFile == "" {
+				var err error
+				configFile, err = discoverConfigFile()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error finding config file: %v\n", err)
+					os.Exit(exitFatal)
+				}
+			}
+
+			config, err := loadConfig(configFile, valuesFiles, "", args, environment)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+				os.Exit(exitFatal)
+			}
+			if err := requireHelm(config); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitFatal)
+			}
+			applyDependencyFlags(config, depsFlags)
+
+			chartPath := args[0]
+			if finder.IsChartArchive(chartPath) {
+				chartDir, tempDir, err := finder.ExtractChartArchive(chartPath)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error extracting %s: %v\n", chartPath, err)
+					os.Exit(exitFatal)
+				}
+				defer os.RemoveAll(tempDir)
+				chartPath = chartDir
+			}
+
+			s := spinner.New(spinner.CharSets[4], 100*time.Millisecond, spinner.WithWriter(os.Stderr))
+			s.Suffix = fmt.Sprintf(" Fuzzing: %s", args[0])
+			s.Start()
+			result, err := renderer.Fuzz(chartPath, renderer.FuzzOptions{
+				ValuesFiles:  renderer.ChartValuesFiles(chartPath, config.ValuesFiles, config.ValuesFilesRelativeTo),
+				SetValues:    setValues,
+				CacheDir:     cacheDir,
+				Dependencies: config.Dependencies,
+				Limit:        limit,
+			})
+			s.Stop()
+			valuessource.Cleanup()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error fuzzing chart %s: %v\n", args[0], err)
+				os.Exit(exitFatal)
+			}
+
+			for _, crash := range result.Crashes {
+				fmt.Println(crash.String())
+			}
+			summary := fmt.Sprintf("Mutations rendered: %d, template crashes: %d", result.Mutations, len(result.Crashes))
+			if result.Total > result.Mutations {
+				summary += fmt.Sprintf(" (%d mutations left out by --limit)", result.Total-result.Mutations)
+			}
+			fmt.Println(summary)
+			if len(result.Crashes) > 0 {
+				os.Exit(exitChartErrors)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&configFile, "config", "c", "", "Path to configuration file")
+	cmd.Flags().StringSliceVarP(&valuesFiles, "values", "f", nil, "Values files the mutations are applied on top of")
+	cmd.Flags().StringVarP(&environment, "environment", "e", "", "Environment of the config file whose values files to use")
+	addSetFlags(cmd, &setValues)
+	cmd.Flags().StringVar(&cacheDir, "cache-dir", renderer.DefaultCacheDir(), "Directory caching downloaded chart dependencies between runs (empty disables caching)")
+	cmd.Flags().StringVar(&depsFlags.RepositoryConfig, "repository-config", "", "Path to the helm repository config used to update dependencies (overrides dependencies.repositoryConfig)")
+	cmd.Flags().StringVar(&depsFlags.RepositoryCache, "repository-cache", "", "Path to the helm repository cache used to update dependencies (default: below --cache-dir)")
+	cmd.Flags().BoolVar(&depsFlags.SkipRefresh, "skip-refresh", false, "Update dependencies with the repository indexes in the repository cache instead of downloading them")
+	cmd.Flags().BoolVar(&depsFlags.Offline, "offline", false, "Never download chart dependencies: use only those vendored in charts/ or cached")
+	cmd.Flags().IntVar(&limit, "limit", 0, "Maximum number of mutations to render (default 200)")
+
+	return cmd
+}
+
+// revisionValuesFiles maps the values files inside chartPath to the same
+// files in a checkout of the chart at another revision, checkoutDir. Values
+// files outside the chart and remote values files are used as they are.
+func revisionValuesFiles(chartPath, checkoutDir string, valuesFiles []string) []string {
+	chartAbs, err := filepath.Abs(chartPath)
+	if err != nil {
+		return valuesFiles
+	}
+	mapped := make([]string, len(valuesFiles))
+	for i, file := range valuesFiles {
+		mapped[i] = file
+		fileAbs, err := filepath.Abs(file)
+		if err != nil || valuessource.IsURI(file) {
+			continue
+		}
+		if rel, err := filepath.Rel(chartAbs, fileAbs); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			mapped[i] = filepath.Join(checkoutDir, rel)
+		}
+	}
+	return mapped
+}
+
+// valuesLabel names a rendering by its values files in diff headers.
+func valuesLabel(valuesFiles []string) string {
+	if len(valuesFiles) == 0 {
+		return "chart defaults"
+	}
+	return strings.Join(valuesFiles, ",")
+}
+
+// buildSchemaCmd constructs and returns the `schema` subcommand.
+func buildSchemaCmd() *cobra.Command {
+	var (
+		write bool
+		force bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "schema [chart-path]...",
+		Short: "Generate a values.schema.json skeleton from templates and default values",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var chartDirs []string
+			for _, chartPath := range args {
+				dirs, err := finder.FindHelmChartDirs(chartPath)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error finding Helm charts in %s: %v\n", chartPath, err)
+					os.Exit(1)
+				}
+				chartDirs = append(chartDirs, dirs...)
+			}
+			if len(chartDirs) > 1 && !write {
+				fmt.Fprintf(os.Stderr, "Found %d charts; use --write to write a schema into each chart\n", len(chartDirs))
+				os.Exit(1)
+			}
+
+			for _, chartDir := range chartDirs {
+				output, err := generateValuesSchema(chartDir)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error generating schema for %s: %v\n", chartDir, err)
+					os.Exit(1)
+				}
+
+				if !write {
+					fmt.Println(string(output))
+					continue
+				}
+				schemaFile := filepath.Join(chartDir, "values.schema.json")
+				if _, err := os.Stat(schemaFile); err == nil && !force {
+					fmt.Fprintf(os.Stderr, "%s already exists; use --force to overwrite it\n", schemaFile)
+					os.Exit(1)
+				}
+				if err := os.WriteFile(schemaFile, append(output, '\n'), 0644); err != nil {
+					fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", schemaFile, err)
+					os.Exit(1)
+				}
+				fmt.Printf("Wrote %s\n", schemaFile)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVarP(&write, "write", "w", false, "Write values.schema.json into each chart instead of printing it")
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite existing values.schema.json files")
+
+	return cmd
+}
+
+// buildNewCmd constructs and returns the `new` subcommand.
+func buildNewCmd() *cobra.Command {
+	var (
+		directory     string
+		starter       string
+		registryOpts  renderer.RegistryOptions
+		passwordStdin bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "new <name>",
+		Short: "Create a new chart that passes chartscan's rules",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := readRegistryPassword(&registryOpts, passwordStdin, os.Stdin); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			starterDir, tempDir, err := resolveStarter(starter, registryOpts)
+			if tempDir != "" {
+				defer os.RemoveAll(tempDir)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error fetching starter %s: %v\n", starter, err)
+				os.Exit(1)
+			}
+
+			chartDir, err := scaffold.Create(args[0], directory, starterDir)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error creating chart: %v\n", err)
+				os.Exit(1)
+			}
+
+			schemaFile := filepath.Join(chartDir, "values.schema.json")
+			if _, err := os.Stat(schemaFile); os.IsNotExist(err) {
+				output, err := generateValuesSchema(chartDir)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error generating schema for %s: %v\n", chartDir, err)
+					os.Exit(1)
+				}
+				if err := os.WriteFile(schemaFile, append(output, '\n'), 0644); err != nil {
+					fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", schemaFile, err)
+					os.Exit(1)
+				}
+			}
+
+			fmt.Printf("Created %s\n", chartDir)
+		},
+	}
+
+	cmd.Flags().StringVarP(&directory, "directory", "d", ".", "Directory to create the chart in")
+	cmd.Flags().StringVar(&starter, "starter", "", "Starter chart to copy instead of the built-in one: a directory, a git URL (url.git[#ref]) or an oci:// reference")
+	addRegistryCredentialFlags(cmd, &registryOpts, &passwordStdin, "an oci:// starter")
+	cmd.Flags().StringVar(&registryOpts.RegistryConfig, "registry-config", "", "Path to the helm registry config file for pulling an oci:// starter")
+
+	return cmd
+}
+
+// resolveStarter returns the directory of a starter chart, fetching git and
+// oci:// starters into a temporary directory that the caller must remove. An
+// empty starter selects the built-in one.
+func resolveStarter(starter string, registryOpts renderer.RegistryOptions) (string, string, error) {
+	switch {
+	case starter == "":
+		return "", "", nil
+	case renderer.IsOCIReference(starter):
+		if err := requireHelm(&models.Config{}); err != nil {
+			return "", "", err
+		}
+		return renderer.PullChart(starter, registryOpts)
+	case strings.HasPrefix(starter, "git@") || strings.HasSuffix(strings.SplitN(starter, "#", 2)[0], ".git"):
+		tempDir, err := os.MkdirTemp("", "chartscan-starter")
+		if err != nil {
+			return "", "", fmt.Errorf("error creating temp dir: %v", err)
+		}
+		url, ref, _ := strings.Cut(starter, "#")
+		cloneArgs := []string{"clone", "--quiet", "--depth", "1"}
+		if ref != "" {
+			cloneArgs = append(cloneArgs, "--branch", ref)
+		}
+		cloneCmd := exec.Command("git", append(cloneArgs, url, tempDir)...)
+		if output, err := cloneCmd.CombinedOutput(); err != nil {
+			return "", tempDir, fmt.Errorf("error cloning %s: %v\n%s", url, err, output)
+		}
+		return tempDir, tempDir, nil
+	default:
+		return starter, "", nil
+	}
+}
+
+// generateValuesSchema returns the indented schema skeleton for a chart.
+func generateValuesSchema(chartDir string) ([]byte, error) {
+	refs, values, err := loadValueUsage(chartDir)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(schema.Generate(refs, values), "", "  ")
+}
+
+// loadValueUsage returns the value references of a chart's templates and its
+// default values.
+func loadValueUsage(chartDir string) ([]models.ValueReference, map[string]interface{}, error) {
+	refs, errs := renderer.ParseTemplates(chartDir)
+	if len(errs) > 0 {
+		return nil, nil, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+
+	values := map[string]interface{}{}
+	valuesFile := filepath.Join(chartDir, "values.yaml")
+	if _, err := os.Stat(valuesFile); err == nil {
+		loaded, err := renderer.ValuesLoader(valuesFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error loading values.yaml: %v", err)
+		}
+		if loaded != nil {
+			values = loaded
+		}
+	}
+
+	return refs, values, nil
+}
+
+// buildFixCmd constructs and returns the `fix` subcommand.
+func buildFixCmd() *cobra.Command {
+	var (
+		configFile string
+		dryRun     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "fix [chart-path]...",
+		Short: "Apply automatic fixes to charts",
+		Long: `Apply automatic fixes to charts.
+
+fix keeps values.schema.json in step with the templates: keys referenced by
+templates but missing from the schema are added as typed stubs, inferred from
+values.yaml defaults or from how the templates use them. With valuesFormat
+enabled in the config file, it also sorts and re-indents each chart's
+values.yaml.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if configFile == "" {
+				var err error
+				configFile, err = discoverConfigFile()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error finding config file: %v\n", err)
+					os.Exit(1)
+				}
+			}
+			config, err := loadConfig(configFile, nil, "", args, "")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+				os.Exit(1)
+			}
+
+			var chartDirs []string
+			for _, chartPath := range args {
+				dirs, err := finder.FindHelmChartDirs(chartPath)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error finding Helm charts in %s: %v\n", chartPath, err)
+					os.Exit(1)
+				}
+				chartDirs = append(chartDirs, dirs...)
+			}
+
+			for _, chartDir := range chartDirs {
+				if err := fixValuesSchema(chartDir, dryRun); err != nil {
+					fmt.Fprintf(os.Stderr, "Error fixing %s: %v\n", chartDir, err)
+					os.Exit(1)
+				}
+				if !config.ValuesFormat.Enabled {
+					continue
+				}
+				if err := fixValuesFormat(chartDir, config.ValuesFormat, dryRun); err != nil {
+					fmt.Fprintf(os.Stderr, "Error fixing %s: %v\n", chartDir, err)
+					os.Exit(1)
+				}
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&configFile, "config", "c", "", "Path to configuration file")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the changes without writing them")
+
+	return cmd
+}
+
+// fixValuesFormat sorts and re-indents the chart's values.yaml as configured
+// by config. Charts without a values.yaml are skipped.
+func fixValuesFormat(chartDir string, config models.ValuesFormatConfig, dryRun bool) error {
+	valuesFile := filepath.Join(chartDir, "values.yaml")
+	data, err := os.ReadFile(valuesFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	opts, err := valuesfmt.ChartOptions(chartDir, config)
+	if err != nil {
+		return err
+	}
+	problems, err := valuesfmt.Check(data, opts)
+	if err != nil {
+		return fmt.Errorf("error parsing %s: %v", valuesFile, err)
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	if dryRun {
+		fmt.Printf("Would reformat %s:\n", valuesFile)
+		for _, problem := range problems {
+			fmt.Printf("  line %d: %s\n", problem.Line, problem.Message)
+		}
+		return nil
+	}
+
+	formatted, err := valuesfmt.Format(data, opts)
+	if err != nil {
+		return fmt.Errorf("error formatting %s: %v", valuesFile, err)
+	}
+	if err := os.WriteFile(valuesFile, formatted, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Reformatted %s\n", valuesFile)
+	return nil
+}
+
+// fixValuesSchema adds stubs for undeclared value keys to the chart's
+// values.schema.json. Charts without a schema are skipped.
+func fixValuesSchema(chartDir string, dryRun bool) error {
+	schemaFile := filepath.Join(chartDir, "values.schema.json")
+	data, err := os.ReadFile(schemaFile)
+	if os.IsNotExist(err) {
+		fmt.Printf("Skipping %s: no values.schema.json (create one with `chartscan schema --write`)\n", chartDir)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("error parsing %s: %v", schemaFile, err)
+	}
+
+	refs, values, err := loadValueUsage(chartDir)
+	if err != nil {
+		return err
+	}
+
+	added := schema.Update(doc, refs, values)
+	if len(added) == 0 {
+		return nil
+	}
+	if dryRun {
+		fmt.Printf("Would add to %s: %s\n", schemaFile, strings.Join(added, ", "))
+		return nil
+	}
+
+	output, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(schemaFile, append(output, '\n'), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Added to %s: %s\n", schemaFile, strings.Join(added, ", "))
+	return nil
+}
+
+// buildValuesCmd constructs and returns the `values` command group.
+func buildValuesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "values",
+		Short: "Inspect chart values",
+	}
+	cmd.AddCommand(buildValuesAuditCmd())
+	cmd.AddCommand(buildValuesOrphansCmd())
+	return cmd
+}
+
+// buildValuesAuditCmd constructs and returns the `values audit` subcommand.
+func buildValuesAuditCmd() *cobra.Command {
+	var valuesFiles []string
+
+	cmd := &cobra.Command{
+		Use:   "audit [chart-path]...",
+		Short: "Report values that no template uses",
+		Long: `Report values that no template uses.
+
+Every key set in a chart's values.yaml, and in the values files passed with
+--values, is compared with the values accessed by the chart's templates and
+helpers. Keys that are never accessed are reported, as are the keys of a
+values file that drifted from the chart. Global values and values passed to
+subcharts are not reported.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var chartDirs []string
+			for _, chartPath := range args {
+				dirs, err := finder.FindHelmChartDirs(chartPath)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error finding Helm charts in %s: %v\n", chartPath, err)
+					os.Exit(exitFatal)
+				}
+				chartDirs = append(chartDirs, dirs...)
+			}
+
+			found := 0
+			for _, chartDir := range chartDirs {
+				files := valuesFiles
+				defaults := filepath.Join(chartDir, "values.yaml")
+				if _, err := os.Stat(defaults); err == nil {
+					files = append([]string{defaults}, valuesFiles...)
+				}
+				for _, file := range files {
+					values, err := renderer.ValuesLoader(file)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "Error loading %s: %v\n", file, err)
+						os.Exit(exitFatal)
+					}
+					unused, errs := renderer.UnusedValues(chartDir, values)
+					if len(errs) > 0 {
+						fmt.Fprintf(os.Stderr, "Error auditing %s: %s\n", chartDir, strings.Join(errs, "; "))
+						os.Exit(exitFatal)
+					}
+					for _, key := range unused {
+						if file == defaults {
+							fmt.Printf("%s: %s is not used by any template\n", file, key)
+						} else {
+							fmt.Printf("%s: %s is not used by any template of %s\n", file, key, chartDir)
+						}
+					}
+					found += len(unused)
+				}
+			}
+
+			if found > 0 {
+				fmt.Printf("\n%d unused values\n", found)
+				os.Exit(exitWarnings)
+			}
+			fmt.Println("No unused values")
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&valuesFiles, "values", "f", nil, "Also audit the keys of these values files (repeatable)")
+
+	return cmd
+}
+
+// buildValuesOrphansCmd constructs and returns the `values orphans`
+// subcommand.
+func buildValuesOrphansCmd() *cobra.Command {
+	var valuesFiles []string
+
+	cmd := &cobra.Command{
+		Use:   "orphans [chart-path]...",
+		Short: "List undefined values across charts, grouped by key",
+		Long: `List undefined values across charts, grouped by key.
+
+The templates of every chart found under the given paths are checked against
+the chart's values.yaml and the values files passed with --values, like scan
+checks them but without running helm. Every undefined key is listed once with
+the templates referencing it, keys referenced by the most charts first, so
+template bugs copied between charts can be fixed together.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var chartDirs []string
+			for _, chartPath := range args {
+				dirs, err := finder.FindHelmChartDirs(chartPath)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error finding Helm charts in %s: %v\n", chartPath, err)
+					os.Exit(exitFatal)
+				}
+				chartDirs = append(chartDirs, dirs...)
+			}
+
+			var results []models.Result
+			for _, chartDir := range chartDirs {
+				findings, errs := renderer.UndefinedValues(chartDir, valuesFiles)
+				if len(errs) > 0 {
+					fmt.Fprintf(os.Stderr, "Error checking %s: %s\n", chartDir, strings.Join(errs, "; "))
+					os.Exit(exitFatal)
+				}
+				results = append(results, models.Result{ChartPath: chartDir, Findings: findings})
+			}
+
+			index := renderer.IndexUndefinedValues(results)
+			if len(index) == 0 {
+				fmt.Println("No undefined values")
+				return
+			}
+			renderer.PrintUndefinedValueIndex(os.Stdout, index)
+			os.Exit(exitUndefinedValues)
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&valuesFiles, "values", "f", nil, "Values files applied to every chart (repeatable)")
+
+	return cmd
+}
+
+// buildGraphCmd constructs and returns the `graph` command group.
+func buildGraphCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Show how the parts of a chart depend on each other",
+	}
+	cmd.AddCommand(buildGraphValuesCmd())
+	return cmd
+}
+
+// buildGraphValuesCmd constructs and returns the `graph values` subcommand.
+func buildGraphValuesCmd() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "values <chart-path | chart.tgz>",
+		Short: "Map which templates use which values, and through which helpers",
+		Long: `Map which templates use which values keys, and through which named
+templates (helpers), to help restructure a chart's values.
+
+The map is printed as a markdown table, as JSON, or as a Graphviz graph:
+
+  chartscan graph values ./charts/web -o dot | dot -Tsvg > values.svg`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			chartPath := args[0]
+			if finder.IsChartArchive(chartPath) {
+				chartDir, tempDir, err := finder.ExtractChartArchive(chartPath)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error extracting %s: %v\n", chartPath, err)
+					os.Exit(exitFatal)
+				}
+				defer os.RemoveAll(tempDir)
+				chartPath = chartDir
+			}
+			if _, err := os.Stat(filepath.Join(chartPath, "Chart.yaml")); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s is not a chart: %v\n", args[0], err)
+				os.Exit(exitFatal)
+			}
+
+			usages, errs := renderer.ValueUsages(chartPath)
+			if len(errs) > 0 {
+				fmt.Fprintf(os.Stderr, "Error parsing templates of %s: %s\n", args[0], strings.Join(errs, "; "))
+				os.Exit(exitFatal)
+			}
+
+			chartName := filepath.Base(filepath.Clean(chartPath))
+			switch format {
+			case "markdown":
+				renderer.WriteValueUsagesMarkdown(os.Stdout, chartName, usages)
+			case "dot":
+				renderer.WriteValueUsagesDOT(os.Stdout, chartName, usages)
+			case "json":
+				if usages == nil {
+					usages = []models.ValueUsage{}
+				}
+				output, err := json.MarshalIndent(usages, "", "  ")
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error encoding JSON: %v\n", err)
+					os.Exit(exitFatal)
+				}
+				fmt.Println(string(output))
+			default:
+				fmt.Fprintf(os.Stderr, "Unknown output format %q (expected markdown, json or dot)\n", format)
+				os.Exit(exitFatal)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "output-format", "o", "markdown", "Output format (markdown, json, dot)")
+
+	return cmd
+}
+
+// buildDepsCmd constructs and returns the `deps` subcommand.
+func buildDepsCmd() *cobra.Command {
+	var (
+		format    string
+		localOnly bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "deps [path]...",
+		Short: "Print the dependency graph of the charts found in the paths",
+		Long: `Print the dependencies of every chart found in the paths (the current
+directory by default), including file:// dependencies between the charts
+of a monorepo. Charts vendored in charts/ directories are left out.
+
+The graph is printed as a list, as JSON, or as a Graphviz or Mermaid graph:
+
+  chartscan deps charts/ -o dot | dot -Tsvg > deps.svg
+
+Dependency cycles between local charts are printed to stderr and make the
+command exit with status 2.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				args = []string{"."}
+			}
+			var charts []finder.ChartInfo
+			for _, path := range args {
+				found, err := finder.FindHelmCharts(path)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error finding Helm charts in %s: %v\n", path, err)
+					os.Exit(exitFatal)
+				}
+				charts = append(charts, found...)
+			}
+
+			graph := renderer.DependencyGraph(charts, localOnly)
+			switch format {
+			case "text":
+				renderer.WriteDependencyGraphText(os.Stdout, graph)
+			case "dot":
+				renderer.WriteDependencyGraphDOT(os.Stdout, graph)
+			case "mermaid":
+				renderer.WriteDependencyGraphMermaid(os.Stdout, graph)
+			case "json":
+				if graph.Charts == nil {
+					graph.Charts = []models.ChartNode{}
+				}
+				output, err := json.MarshalIndent(graph, "", "  ")
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error encoding JSON: %v\n", err)
+					os.Exit(exitFatal)
+				}
+				fmt.Println(string(output))
+			default:
+				fmt.Fprintf(os.Stderr, "Unknown output format %q (expected text, json, dot or mermaid)\n", format)
+				os.Exit(exitFatal)
+			}
+
+			for _, cycle := range graph.Cycles {
+				fmt.Fprintf(os.Stderr, "✘ Dependency cycle: %s\n", strings.Join(cycle, " → "))
+			}
+			if len(graph.Cycles) > 0 {
+				os.Exit(exitChartErrors)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "output-format", "o", "text", "Output format (text, json, dot, mermaid)")
+	cmd.Flags().BoolVar(&localOnly, "local-only", false, "Only show file:// dependencies between local charts")
+
+	return cmd
+}
+
+// buildRulesCmd constructs and returns the `rules` command group.
+func buildRulesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rules",
+		Short: "Develop and test rules",
+	}
+	cmd.AddCommand(buildRulesTestCmd())
+	return cmd
+}
+
+// buildRulesTestCmd constructs and returns the `rules test` subcommand.
+func buildRulesTestCmd() *cobra.Command {
+	var (
+		ruleIDs []string
+		update  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "test <fixtures-dir>",
+		Short: "Run rules against fixture charts and compare their findings",
+		Long: `Run rules against fixture charts and compare their findings with the
+expected ones.
+
+Every subdirectory of <fixtures-dir> containing a Chart.yaml is a fixture. It
+is scanned with the chartscan.yaml next to its Chart.yaml, if any, and its
+findings are compared with ` + ruletest.ExpectedFile + `. A fixture without
+that file is expected to produce no findings.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			known := rules.IDs()
+			for _, id := range ruleIDs {
+				if !slices.Contains(known, id) {
+					fmt.Fprintf(os.Stderr, "Error: unknown rule %q (registered rules: %s)\n", id, strings.Join(known, ", "))
+					os.Exit(exitFatal)
+				}
+			}
+
+			fixtures, err := ruletest.FindFixtures(args[0])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error finding fixtures in %s: %v\n", args[0], err)
+				os.Exit(exitFatal)
+			}
+			if len(fixtures) == 0 {
+				fmt.Fprintf(os.Stderr, "Error: no fixture charts found in %s\n", args[0])
+				os.Exit(exitFatal)
+			}
+			if err := requireHelm(&models.Config{}); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitFatal)
+			}
+
+			failed := 0
+			for _, fixture := range fixtures {
+				if !runRuleFixture(fixture, ruleIDs, update) {
+					failed++
+				}
+			}
+
+			fmt.Printf("\n%d fixtures, %d failed\n", len(fixtures), failed)
+			if failed > 0 {
+				os.Exit(exitFatal)
+			}
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&ruleIDs, "rule", nil, "Only compare the findings of these rules (repeatable)")
+	cmd.Flags().BoolVar(&update, "update", false, "Record the current findings as the expected findings of each fixture")
+
+	return cmd
+}
+
+// buildAssetsCmd constructs and returns the `assets` command group.
+func buildAssetsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "assets",
+		Short: "Manage the remote assets pinned in the configuration file",
+	}
+	cmd.AddCommand(buildAssetsUpdateCmd())
+	return cmd
+}
+
+// buildAssetsUpdateCmd constructs and returns the `assets update` subcommand.
+func buildAssetsUpdateCmd() *cobra.Command {
+	var configFile string
+
+	cmd := &cobra.Command{
+		Use:   "update",
+		Short: "Pin the remote assets of the configuration file at their current versions",
+		Long: `Pin the remote assets of the configuration file at their current versions.
+
+The extended base configuration is pinned to the digest of its current
+content, and the default Kubernetes schema repository, if the file has a
+validation section, to its latest commit. The configuration file is rewritten
+in place; commit it to use the same policy in every run.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if configFile == "" {
+				var err error
+				configFile, err = discoverConfigFile()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error finding config file: %v\n", err)
+					os.Exit(exitFatal)
+				}
+				if configFile == "" {
+					fmt.Fprintln(os.Stderr, "Error: no configuration file found; pass one with --config")
+					os.Exit(exitFatal)
+				}
+			}
+
+			updates, err := configfile.NewLoader().UpdatePins(configFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error updating pins in %s: %v\n", configFile, err)
+				os.Exit(exitFatal)
+			}
+			if len(updates) == 0 {
+				fmt.Println("All assets are pinned at their current versions.")
+				return
+			}
+			for _, update := range updates {
+				old := update.Old
+				if old == "" {
+					old = "unpinned"
+				}
+				fmt.Printf("%s: %s -> %s\n", update.Key, old, update.New)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&configFile, "config", "c", "", "Path to configuration file")
+
+	return cmd
+}
+
+// runRuleFixture scans a fixture chart, prints whether its findings match the
+// expected ones and reports success. With update, the findings are recorded
+// instead.
+func runRuleFixture(fixture string, ruleIDs []string, update bool) bool {
+	configFile := filepath.Join(fixture, ruletest.ConfigFile)
+	if _, err := os.Stat(configFile); err != nil {
+		configFile = ""
+	}
+	config, err := loadConfig(configFile, nil, "", nil, "")
+	if err != nil {
+		fmt.Printf("FAIL %s: error loading config: %v\n", fixture, err)
+		return false
+	}
+
+	result := renderer.ScanHelmChart(fixture, renderer.ScanOptions{ValuesFiles: config.ValuesFiles, Config: *config})
+	// Errors of the scan stages mean the chart was not checked.
+	var scanErrors []string
+	for _, finding := range result.Findings {
+		if finding.Severity == models.SeverityError && finding.RuleID != renderer.UndefinedValueID && !slices.Contains(rules.IDs(), finding.RuleID) {
+			scanErrors = append(scanErrors, finding.String())
+		}
+	}
+	if len(scanErrors) > 0 {
+		fmt.Printf("FAIL %s: chart could not be checked\n", fixture)
+		for _, e := range scanErrors {
+			fmt.Printf("    %s\n", e)
+		}
+		return false
+	}
+	findings := ruletest.FilterRules(result.Findings, ruleIDs)
+
+	if update {
+		if err := ruletest.WriteExpectations(fixture, findings); err != nil {
+			fmt.Printf("FAIL %s: error writing %s: %v\n", fixture, ruletest.ExpectedFile, err)
+			return false
+		}
+		fmt.Printf("ok   %s: recorded %d findings\n", fixture, len(findings))
+		return true
+	}
+
+	expected, err := ruletest.LoadExpectations(fixture)
+	if err != nil {
+		fmt.Printf("FAIL %s: %v\n", fixture, err)
+		return false
+	}
+	missing, unexpected := ruletest.Compare(expected, findings)
+	if len(missing) == 0 && len(unexpected) == 0 {
+		fmt.Printf("ok   %s: %d findings\n", fixture, len(findings))
+		return true
+	}
+
+	fmt.Printf("FAIL %s\n", fixture)
+	for _, expectation := range missing {
+		fmt.Printf("    missing:    %s\n", expectation)
+	}
+	for _, finding := range unexpected {
+		fmt.Printf("    unexpected: %s\n", finding)
+	}
+	return false
+}
+
+// buildGateCmd constructs and returns the `gate` subcommand.
+func buildGateCmd() *cobra.Command {
+	var (
+		reportFile string
+		policyFile string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "gate --report <results.json> --policy <gate.yaml>",
+		Short: "Check a scan report against a gate policy",
+		Long: `Check a report written by scan -o json or -o yaml against a gate policy and
+exit non-zero when the policy is not met.
+
+A policy caps the severity of findings (maxSeverity), requires rules to pass
+in every chart (requiredRules) and sets budgets for the number of findings by
+severity and rule and for failed and incomplete charts. Since gating is
+separate from scanning, one report can be checked against several policies,
+e.g. a strict one for releases and a lenient one for previews.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			policy, err := gate.LoadPolicy(policyFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading policy: %v\n", err)
+				os.Exit(exitFatal)
+			}
+			report, err := gate.LoadReport(reportFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading report: %v\n", err)
+				os.Exit(exitFatal)
+			}
+
+			violations := gate.Evaluate(report, policy)
+			for _, violation := range violations {
+				fmt.Printf("✘ %s: %s\n", violation.Check, violation.Message)
+			}
+			if len(violations) > 0 {
+				noun := "violations"
+				if len(violations) == 1 {
+					noun = "violation"
+				}
+				fmt.Printf("Gate %s failed: %d %s\n", policyFile, len(violations), noun)
+				os.Exit(exitChartErrors)
+			}
+			fmt.Printf("✔ Gate %s passed\n", policyFile)
+		},
+	}
+
+	cmd.Flags().StringVar(&reportFile, "report", "", "Report written by scan -o json or -o yaml")
+	cmd.Flags().StringVar(&policyFile, "policy", "", "Gate policy to check the report against")
+	cmd.MarkFlagRequired("report") //nolint:errcheck
+	cmd.MarkFlagRequired("policy") //nolint:errcheck
+
+	return cmd
+}
+
+// buildDoctorCmd constructs and returns the `doctor` subcommand.
+func buildDoctorCmd() *cobra.Command {
+	var (
+		configFile  string
+		kubeVersion string
+		policyDirs  []string
+		cacheDir    string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "doctor [chart-path...]",
+		Short: "Check that the environment has what a scan needs",
+		Long: `Check that the environment has what a scan needs and print how to fix
+what is missing.
+
+Helm must be version 3.8 or newer. Git is needed to
+discover the configuration file and for the git features of scan. Configured
+policies must compile, the repositories of the charts'
+dependencies are contacted, the schemas of the Kubernetes version manifests
+are validated for are loaded, and the cache directory must be writable.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if configFile == "" {
+				// A missing git is reported by the checks below.
+				configFile, _ = discoverConfigFile()
+			}
+			config, err := loadConfig(configFile, nil, "", args, "")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+				os.Exit(exitFatal)
+			}
+			if kubeVersion != "" {
+				config.Validation.KubeVersion = kubeVersion
+			}
+			config.Policies.Dirs = append(config.Policies.Dirs, policyDirs...)
+
+			chartPaths := args
+			if len(chartPaths) == 0 {
+				chartPaths = []string{config.ChartPath}
+				if config.ChartPath == "" {
+					chartPaths = []string{"."}
+				}
+			}
+
+			checks := doctor.Run(doctor.Options{Config: *config, ChartPaths: chartPaths, CacheDir: cacheDir, HelmBinary: helmBinaryFor(config)})
+			for _, check := range checks {
+				mark := "✔"
+				switch check.Status {
+				case doctor.Warning:
+					mark = "!"
+				case doctor.Failed:
+					mark = "✘"
+				}
+				fmt.Printf("%s %s: %s\n", mark, check.Name, check.Detail)
+				if check.Hint != "" {
+					fmt.Printf("    hint: %s\n", check.Hint)
+				}
+			}
+			if doctor.AnyFailed(checks) {
+				os.Exit(exitFatal)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&configFile, "config", "c", "", "Path to configuration file")
+	cmd.Flags().StringVar(&kubeVersion, "kube-version", "", "Check the schemas of this Kubernetes version (overrides validation.kubeVersion)")
+	cmd.Flags().StringSliceVar(&policyDirs, "policy-dir", nil, "Check that the Rego policies in this directory compile (repeatable)")
+	cmd.Flags().StringVar(&cacheDir, "cache-dir", renderer.DefaultCacheDir(), "Cache directory to check (empty skips the check)")
+
+	return cmd
+}
+
+// buildVersionCmd constructs and returns the `version` subcommand.
+func buildVersionCmd() *cobra.Command {
+	var (
+		format      string
+		checkLatest bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Print the version of ChartScan",
+		Long: `Print the version, commit and build date of ChartScan, the Go and helm
+versions it runs with and the Kubernetes versions whose schemas are embedded.
+
+With --check-latest, the latest release is looked up on GitHub. Nothing is
+fetched without it, so the command works offline.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if format != "text" && format != "json" {
+				fmt.Fprintf(os.Stderr, "Error: unknown output format %s (expected text or json)\n", format)
+				os.Exit(exitFatal)
+			}
+
+			renderer.SetHelmBinary(helmBinary)
+			info := release.BuildInfo(version, commit, buildDate)
+			info.HelmVersion = renderer.HelmVersion()
+			info.Schemas = validation.BundledVersions()
+			if checkLatest {
+				latest, err := release.CheckLatest(release.LatestURL, version)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(exitFatal)
+				}
+				info.Latest = latest
+			}
+
+			if format == "json" {
+				output, err := json.MarshalIndent(info, "", "  ")
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(exitFatal)
+				}
+				fmt.Println(string(output))
+				return
+			}
+
+			fmt.Printf("ChartScan version %s\n", info.Version)
+			if info.Commit != "" {
+				fmt.Printf("Commit: %s\n", info.Commit)
+			}
+			if info.BuildDate != "" {
+				fmt.Printf("Build date: %s\n", info.BuildDate)
+			}
+			fmt.Printf("Go version: %s\n", info.GoVersion)
+			helmVersion := info.HelmVersion
+			if helmVersion == "" {
+				helmVersion = "not found"
+			}
+			fmt.Printf("Helm version: %s\n", helmVersion)
+			embedded := "none"
+			if len(info.Schemas) > 0 {
+				embedded = strings.Join(info.Schemas, ", ")
+			}
+			fmt.Printf("Embedded Kubernetes schemas: %s\n", embedded)
+			if latest := info.Latest; latest != nil {
+				if latest.UpdateAvailable {
+					fmt.Printf("A newer release is available: %s (%s)\n", latest.Version, latest.URL)
+				} else {
+					fmt.Printf("Latest release: %s\n", latest.Version)
+				}
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "output", "o", "text", "Output format (text, json)")
+	// --output-format is accepted too, as on the other commands, but hidden
+	// so that the help lists one flag per setting.
+	cmd.Flags().StringVar(&format, "output-format", "text", "Output format (text, json)")
+	cmd.Flags().MarkDeprecated("output-format", "use --output instead")
+	cmd.Flags().BoolVar(&checkLatest, "check-latest", false, "Look up the latest release on GitHub and report whether it is newer")
+
+	return cmd
+}
+
+// discoverConfigFile returns the config file found by configfile.Find from
+// the current directory, or an empty string if there is none.
+func discoverConfigFile() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	configFile, err := configfile.Find(dir)
+	if err != nil {
+		return "", err
+	}
+	if configFile != "" {
+		fmt.Fprintf(os.Stderr, "Using config file %s\n", configFile)
+	}
+	return configFile, nil
+}
+
+// listConfiguredEnvironments prints all environments defined in the config file
+// as a formatted table.
+func listConfiguredEnvironments(configFile string) error {
+	config, err := loadConfigFromFile(configFile)
+	if err != nil {
+		return err
+	}
+
+	if config.Environments == nil {
+		fmt.Println("No environments configured.")
+		return nil
+	}
+
+	table := tablewriter.NewTable(os.Stdout,
+		tablewriter.WithHeader([]string{"Environment", "Values Files"}),
+		tablewriter.WithRowAlignment(tw.AlignLeft),
+	)
+
+	for env, envConfig := range config.Environments {
+		valuesFiles := ""
+		if len(envConfig.ValuesFiles) > 0 {
+			valuesFiles = "• " + strings.Join(envConfig.ValuesFiles, "\n• ")
+		}
+		table.Append([]string{env, valuesFiles}) //nolint:errcheck
+	}
+
+	table.Render() //nolint:errcheck
+	return nil
+}
+
+// loadConfigFromFile reads and unmarshals the configuration file. If
+// configFile is empty, it attempts to discover it from the current directory.
+func loadConfigFromFile(configFile string) (*models.Config, error) {
+	config := &models.Config{}
+
+	if configFile == "" {
+		var err error
+		configFile, err = discoverConfigFile()
+		if err != nil {
+			return config, err
+		}
+	}
+
+	if configFile == "" {
+		return config, nil
+	}
+
+	data, err := configfile.Read(configFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// loadConfig builds a Config from the config file and CLI overrides.
+func loadConfig(configFile string, valuesFiles []string, format string, args []string, environment string) (*models.Config, error) {
+	config := &models.Config{}
+
+	if configFile != "" {
+		configDir := filepath.Dir(configFile)
+		data, err := configfile.Read(configFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(data, config); err != nil {
+			return nil, err
+		}
+
+		config.ChartPath, err = resolveRelativePath(configDir, config.ChartPath)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving chartPath: %v", err)
+		}
+
+		for i, location := range config.Validation.SchemaLocations {
+			if location == "default" || strings.Contains(location, "://") || filepath.IsAbs(location) {
+				continue
+			}
+			config.Validation.SchemaLocations[i] = filepath.Join(configDir, location)
+		}
+
+		if crdSchemas := config.Validation.CRDSchemas; crdSchemas != "" && !filepath.IsAbs(crdSchemas) {
+			if _, err := os.Stat(filepath.Join(configDir, crdSchemas)); err == nil {
+				config.Validation.CRDSchemas = filepath.Join(configDir, crdSchemas)
+			}
+		}
+
+		for i, dir := range config.Policies.Dirs {
+			if filepath.IsAbs(dir) {
+				continue
+			}
+			config.Policies.Dirs[i], err = resolveRelativePath(configDir, dir)
+			if err != nil {
+				return nil, fmt.Errorf("error resolving policy directory %s: %v", dir, err)
+			}
+		}
+
+		for i, pattern := range config.ReferencePatterns {
+			for j, file := range pattern.Files {
+				resolved, err := resolveRelativePath(configDir, file)
+				if err != nil {
+					return nil, fmt.Errorf("error resolving reference pattern file %s: %v", file, err)
+				}
+				config.ReferencePatterns[i].Files[j] = resolved
+			}
+		}
+	}
+
+	if environment != "" {
+		envConfig, exists := config.Environments[environment]
+		if !exists {
+			return nil, fmt.Errorf("environment %s not found in chartscan.yaml", environment)
+		}
+		config.Environment = environment
+		if len(envConfig.ValuesFiles) > 0 {
+			config.ValuesFiles = envConfig.ValuesFiles
+		} else {
+			config.ValuesFiles = nil
+		}
+		if envConfig.QoS != nil {
+			config.Scheduling.QoS = envConfig.QoS
+		}
+		if envConfig.PodSecurityLevel != "" {
+			config.PodSecurity.Level = envConfig.PodSecurityLevel
+		}
+		if envConfig.Production {
+			config.Rollouts.Production = true
+		}
+		if envConfig.PostRenderer != nil {
+			config.PostRenderer = *envConfig.PostRenderer
+		}
+	}
+
+	if len(valuesFiles) > 0 {
+		config.ValuesFiles = valuesFiles
+	}
+	if format != "" {
+		config.Format = format
+	}
+
+	if err := renderer.ValidateReferencePatterns(config.ReferencePatterns); err != nil {
+		return nil, err
+	}
+
+	switch config.ValuesFilesRelativeTo {
+	case "", models.ValuesFilesRelativeToConfig, models.ValuesFilesRelativeToChart:
+	default:
+		return nil, fmt.Errorf("invalid valuesFilesRelativeTo %q: must be %s or %s", config.ValuesFilesRelativeTo, models.ValuesFilesRelativeToConfig, models.ValuesFilesRelativeToChart)
+	}
+
+	if configFile != "" {
+		configDir := filepath.Dir(configFile)
+		// Chart-relative values files are resolved for each chart by the
+		// renderer.
+		if config.ValuesFilesRelativeTo != models.ValuesFilesRelativeToChart {
+			for i, vf := range config.ValuesFiles {
+				if valuessource.IsURI(vf) {
+					continue
+				}
+				resolved, err := resolveRelativePath(configDir, vf)
+				if err != nil {
+					return config, fmt.Errorf("error resolving valuesFile %s: %v", vf, err)
+				}
+				config.ValuesFiles[i] = resolved
+			}
+		}
+
+		// Commands without a directory are looked up in PATH.
+		postRenderer := &config.PostRenderer
+		if postRenderer.Kustomize != "" && !filepath.IsAbs(postRenderer.Kustomize) {
+			resolved, err := resolveRelativePath(configDir, postRenderer.Kustomize)
+			if err != nil {
+				return config, fmt.Errorf("error resolving kustomize overlay %s: %v", postRenderer.Kustomize, err)
+			}
+			postRenderer.Kustomize = resolved
+		}
+		if strings.Contains(postRenderer.Command, "/") && !filepath.IsAbs(postRenderer.Command) {
+			resolved, err := resolveRelativePath(configDir, postRenderer.Command)
+			if err != nil {
+				return config, fmt.Errorf("error resolving post-renderer %s: %v", postRenderer.Command, err)
+			}
+			postRenderer.Command = resolved
+		}
+		for _, path := range []*string{&config.Dependencies.RepositoryConfig, &config.Dependencies.RepositoryCache, &config.Dependencies.RegistryConfig} {
+			if *path == "" || filepath.IsAbs(*path) {
+				continue
+			}
+			resolved, err := resolveRelativePath(configDir, *path)
+			if err != nil {
+				return config, fmt.Errorf("error resolving %s: %v", *path, err)
+			}
+			*path = resolved
+		}
+		if strings.Contains(config.HelmBinary, "/") && !filepath.IsAbs(config.HelmBinary) {
+			resolved, err := resolveRelativePath(configDir, config.HelmBinary)
+			if err != nil {
+				return config, fmt.Errorf("error resolving helmBinary %s: %v", config.HelmBinary, err)
+			}
+			config.HelmBinary = resolved
+		}
+	}
+
+	return config, nil
+}
+
+// helmBinaryFor returns the helm executable of --helm-binary, or else of
+// config; empty means helm from PATH.
+func helmBinaryFor(config *models.Config) string {
+	if helmBinary != "" {
+		return helmBinary
+	}
+	return config.HelmBinary
+}
+
+// requireHelm makes scans run the helm binary of --helm-binary or config and
+// checks that it is a supported version.
+func requireHelm(config *models.Config) error {
+	renderer.SetHelmBinary(helmBinaryFor(config))
+	_, err := renderer.CheckHelmVersion()
+	return err
+}
+
+// resolveRelativePath joins relativePath with baseDir and returns the absolute path.
+func resolveRelativePath(baseDir, relativePath string) (string, error) {
+	return filepath.Abs(filepath.Join(baseDir, relativePath))
+}
+
+// processCharts scans chart directories concurrently and returns results with
+// the total count of invalid charts. When ctx is done before every chart is
+// scanned, it returns the results finished so far and the charts still
+// pending; scans in progress are abandoned. With showProgress, a progress bar
+// is drawn on stderr if it is a terminal and, in the pretty format, a status
+// line is printed on stderr as each chart finishes, so long scans show their
+// results before the table.
+func processCharts(ctx context.Context, chartDirs []string, opts renderer.ScanOptions, showProgress bool) ([]models.Result, int, []string) {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	results := make([]models.Result, 0, len(chartDirs))
+	invalidCharts := 0
+	finished := make(map[string]bool, len(chartDirs))
+
+	var bar *progress.Bar
+	if fd := int(os.Stderr.Fd()); showProgress && term.IsTerminal(fd) {
+		columns, _, _ := term.GetSize(fd)
+		bar = progress.New(os.Stderr, len(chartDirs), columns)
+	}
+	bar.Start()
+	defer bar.Stop()
+	printStatus := func(result models.Result) {
+		if !showProgress || opts.Config.Format != "pretty" {
+			return
+		}
+		if bar == nil {
+			fmt.Fprintln(os.Stderr, renderer.StatusLine(result, opts.Config.SeverityThreshold))
+			return
+		}
+		bar.Println(renderer.StatusLine(result, opts.Config.SeverityThreshold))
+	}
+
+	wg.Add(len(chartDirs))
+	for _, chartDir := range chartDirs {
+		go func(chartDir string) {
+			defer wg.Done()
+
+			bar.Begin(chartDir)
+			result := renderer.ScanHelmChart(chartDir, opts)
+			bar.Finish(chartDir)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			// An interrupt also reaches the helm processes of scans in
+			// progress, so their failures say nothing about the chart.
+			if ctx.Err() != nil {
+				return
+			}
+			if !result.Success {
+				invalidCharts++
+			}
+
+			results = append(results, result)
+			finished[chartDir] = true
+			printStatus(result)
+		}(chartDir)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	var pending []string
+	for _, chartDir := range chartDirs {
+		if !finished[chartDir] {
+			pending = append(pending, chartDir)
+		}
+	}
+	return slices.Clone(results), invalidCharts, pending
+}
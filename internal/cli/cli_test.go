@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+	"github.com/Jaydee94/chartscan/internal/renderer"
+)
+
+func TestFailOnExitCode(t *testing.T) {
+	invalid := models.Result{ChartPath: "charts/web", Findings: []models.Finding{{RuleID: "helm-lint", Severity: models.SeverityError}}}
+	undefined := models.Result{ChartPath: "charts/api", Findings: []models.Finding{{RuleID: renderer.UndefinedValueID, Severity: models.SeverityError}}}
+	warning := models.Result{ChartPath: "charts/db", Success: true, Findings: []models.Finding{{RuleID: "image-pinning", Severity: models.SeverityWarning}}}
+	passed := models.Result{ChartPath: "charts/ok", Success: true}
+	toolError := models.Result{ChartPath: "charts/crash", ToolError: "panic in rule"}
+	nested := models.Result{ChartPath: "charts/app", Success: true, Dependencies: []models.Result{warning}}
+
+	tests := []struct {
+		name        string
+		results     []models.Result
+		runFindings []models.Finding
+		classes     []string
+		want        int
+	}{
+		{"no classes", []models.Result{invalid, undefined, warning}, nil, nil, exitOK},
+		{"none", []models.Result{invalid, undefined, warning}, nil, []string{"none"}, exitOK},
+		{"passed charts", []models.Result{passed}, nil, []string{"error", "warning", "undefined-values"}, exitOK},
+		{"error", []models.Result{invalid}, nil, []string{"error"}, exitChartErrors},
+		{"warning", []models.Result{warning}, nil, []string{"warning"}, exitWarnings},
+		{"undefined values", []models.Result{undefined}, nil, []string{"undefined-values"}, exitUndefinedValues},
+		{"errors before undefined values", []models.Result{undefined, warning}, nil, []string{"warning", "undefined-values", "error"}, exitChartErrors},
+		{"undefined values before warnings", []models.Result{warning, {ChartPath: "charts/api", Success: true, Findings: undefined.Findings}}, nil, []string{"warning", "undefined-values"}, exitUndefinedValues},
+		{"unselected classes", []models.Result{invalid, undefined}, nil, []string{"warning"}, exitOK},
+		{"dependencies", []models.Result{nested}, nil, []string{"warning"}, exitWarnings},
+		{"tool errors", []models.Result{invalid, toolError}, nil, []string{"error"}, exitFatal},
+		{"tool errors with none", []models.Result{toolError}, nil, []string{"none"}, exitFatal},
+		{"run warnings", []models.Result{passed}, []models.Finding{{RuleID: "unused-values-file", Severity: models.SeverityWarning}}, []string{"warning"}, exitWarnings},
+		{"run errors", []models.Result{passed}, []models.Finding{{RuleID: "unused-values-file", Severity: models.SeverityError}}, []string{"error"}, exitChartErrors},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := failOnExitCode(tt.results, tt.runFindings, tt.classes); got != tt.want {
+				t.Errorf("Expected exit code %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestValidateFailOn(t *testing.T) {
+	tests := []struct {
+		classes []string
+		valid   bool
+	}{
+		{nil, true},
+		{[]string{"none"}, true},
+		{[]string{"error", "warning", "undefined-values"}, true},
+		{[]string{"errors"}, false},
+		{[]string{"error", ""}, false},
+	}
+	for _, tt := range tests {
+		if err := validateFailOn(tt.classes); (err == nil) != tt.valid {
+			t.Errorf("Expected %v to be valid: %v, got %v", tt.classes, tt.valid, err)
+		}
+	}
+}
+
+func TestLoadConfigRejectsInvalidReferencePatterns(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "chartscan.yaml")
+	config := "referencePatterns:\n  - name: envsubst\n    pattern: '\\$\\{[A-Z_]+\\}'\n    source: env\n"
+	if err := os.WriteFile(configFile, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	_, err := loadConfig(configFile, nil, "", nil, "")
+	if err == nil || !strings.Contains(err.Error(), "reference pattern envsubst must contain exactly one capture group") {
+		t.Errorf("Expected an error for the pattern without capture group, got %v", err)
+	}
+}
+
+func TestReadRegistryPassword(t *testing.T) {
+	t.Setenv(registryPasswordEnv, "from-env")
+
+	opts := renderer.RegistryOptions{}
+	if err := readRegistryPassword(&opts, false, strings.NewReader("")); err != nil || opts.Password != "from-env" {
+		t.Errorf("Expected the password from %s, got '%s', %v", registryPasswordEnv, opts.Password, err)
+	}
+
+	opts = renderer.RegistryOptions{Password: "from-flag"}
+	if err := readRegistryPassword(&opts, false, strings.NewReader("")); err != nil || opts.Password != "from-flag" {
+		t.Errorf("Expected --registry-password to take precedence, got '%s', %v", opts.Password, err)
+	}
+
+	opts = renderer.RegistryOptions{}
+	if err := readRegistryPassword(&opts, true, strings.NewReader("s3cret\r\n")); err != nil || opts.Password != "s3cret" {
+		t.Errorf("Expected the password from stdin without its newline, got '%s', %v", opts.Password, err)
+	}
+	if err := readRegistryPassword(&opts, true, strings.NewReader("\n")); err == nil {
+		t.Errorf("Expected an error for an empty password on stdin")
+	}
+}
@@ -0,0 +1,350 @@
+// Package configfile finds and reads chartscan.yaml files, resolving the
+// shared configurations they extend. Config files may also be written in
+// JSON or TOML.
+package configfile
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/Jaydee94/chartscan/internal/finder"
+	"github.com/Jaydee94/chartscan/internal/registry"
+	"gopkg.in/yaml.v3"
+)
+
+// FileNames are the names Find looks for, in order of preference.
+var FileNames = []string{
+	"chartscan.yaml",
+	"chartscan.yml",
+	".chartscan.yaml",
+	".chartscan.yml",
+	"chartscan.json",
+	".chartscan.json",
+	"chartscan.toml",
+	".chartscan.toml",
+}
+
+// Extends is the base configuration a config file extends. In YAML it is
+// either a plain source or a mapping with a source and a digest.
+type Extends struct {
+	// URL is an https:// URL, an oci:// artifact reference, a
+	// git::<repository>//<path>?ref=<ref> reference or a local path relative
+	// to the extending file.
+	URL string `yaml:"url"`
+	// Digest pins the content of the base configuration, e.g. sha256:….
+	Digest string `yaml:"digest"`
+	// AllowHTTP permits an unencrypted http:// URL.
+	AllowHTTP bool `yaml:"allowHTTP"`
+}
+
+// checkScheme rejects a plain http:// source unless it is explicitly allowed.
+func (e Extends) checkScheme() error {
+	if strings.HasPrefix(strings.TrimPrefix(e.URL, "git::"), "http://") && !e.AllowHTTP {
+		return fmt.Errorf("%s is fetched over unencrypted HTTP: use https:// or set allowHTTP: true", e.URL)
+	}
+	return nil
+}
+
+// UnmarshalYAML accepts both the plain and the mapping form.
+func (e *Extends) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&e.URL)
+	}
+	type plain Extends
+	return node.Decode((*plain)(e))
+}
+
+// Loader reads config files. Remote base configurations are cached in
+// CacheDir so pinned ones are only downloaded once and unpinned ones remain
+// usable when their source is unreachable.
+type Loader struct {
+	HTTPClient *http.Client
+	Registry   *registry.Client
+	CacheDir   string
+	// Warnings receives a warning when an unpinned base configuration is
+	// read from the cache because its source is unreachable. Nil discards
+	// them.
+	Warnings io.Writer
+}
+
+// NewLoader returns a Loader caching in the user cache directory.
+func NewLoader() *Loader {
+	cacheDir := ""
+	if userCacheDir, err := os.UserCacheDir(); err == nil {
+		cacheDir = filepath.Join(userCacheDir, "chartscan", "config")
+	}
+	return &Loader{
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		Registry:   registry.NewClient(),
+		CacheDir:   cacheDir,
+		Warnings:   os.Stderr,
+	}
+}
+
+// Find returns the config file in dir or in the closest of its parents that
+// has one, or an empty string if there is none. The search stops at the root
+// of the git repository containing dir, so a config file outside the
+// repository is never used; outside a repository it goes up to the root of
+// the file system.
+func Find(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		for _, name := range FileNames {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path, nil
+			}
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return "", nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// isTOML reports whether source names a TOML file. Other config files are
+// parsed as YAML, of which JSON is a subset.
+func isTOML(source string) bool {
+	source, _, _ = strings.Cut(source, "?")
+	return strings.EqualFold(filepath.Ext(source), ".toml")
+}
+
+// Read returns the content of the config file at path merged over the
+// configurations it extends, as YAML.
+func Read(path string) ([]byte, error) {
+	return NewLoader().Read(path)
+}
+
+// Read returns the content of the config file at path merged over the
+// configurations it extends, as YAML. Mappings are merged key by key,
+// everything else in the extending file replaces the base.
+func (l *Loader) Read(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := l.resolve(data, absPath, true, map[string]bool{absPath: true})
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return data, nil
+	}
+	return yaml.Marshal(config)
+}
+
+// resolve parses data, read from source, and merges it over its base
+// configuration. seen holds the sources of the chain to detect cycles.
+func (l *Loader) resolve(data []byte, source string, local bool, seen map[string]bool) (map[string]interface{}, error) {
+	var config map[string]interface{}
+	if isTOML(source) {
+		if err := toml.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("error parsing %s: %v", source, err)
+		}
+		var err error
+		if data, err = yaml.Marshal(config); err != nil {
+			return nil, fmt.Errorf("error converting %s to YAML: %v", source, err)
+		}
+	} else if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", source, err)
+	}
+
+	var header struct {
+		Extends *Extends `yaml:"extends"`
+	}
+	if err := yaml.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("error parsing extends of %s: %v", source, err)
+	}
+	if header.Extends == nil || header.Extends.URL == "" {
+		return config, nil
+	}
+	delete(config, "extends")
+
+	base := header.Extends.URL
+	if err := header.Extends.checkScheme(); err != nil {
+		return nil, fmt.Errorf("error loading %s extended by %s: %v", base, source, err)
+	}
+	baseLocal := isLocal(base)
+	if baseLocal {
+		if !local {
+			return nil, fmt.Errorf("%s cannot extend local file %s", source, base)
+		}
+		if !filepath.IsAbs(base) {
+			base = filepath.Join(filepath.Dir(source), base)
+		}
+	}
+	if seen[base] {
+		return nil, fmt.Errorf("%s extends itself through %s", source, base)
+	}
+	seen[base] = true
+
+	baseData, err := l.fetch(base, header.Extends.Digest, baseLocal)
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s extended by %s: %v", base, source, err)
+	}
+	baseConfig, err := l.resolve(baseData, base, baseLocal, seen)
+	if err != nil {
+		return nil, err
+	}
+	return mergeConfig(baseConfig, config), nil
+}
+
+// fetch returns the content of source, verified against digest if set.
+// Remote content is cached.
+func (l *Loader) fetch(source, digest string, local bool) ([]byte, error) {
+	if local {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return nil, err
+		}
+		return data, verifyDigest(data, digest)
+	}
+
+	cacheFile := l.cacheFile(source)
+	if digest != "" && cacheFile != "" {
+		if data, err := os.ReadFile(cacheFile); err == nil && verifyDigest(data, digest) == nil {
+			return data, nil
+		}
+	}
+
+	data, err := l.download(source)
+	if err != nil {
+		if digest == "" && cacheFile != "" {
+			if cached, cacheErr := os.ReadFile(cacheFile); cacheErr == nil {
+				if l.Warnings != nil {
+					var fetched string
+					if info, statErr := os.Stat(cacheFile); statErr == nil {
+						fetched = " fetched " + info.ModTime().Format(time.DateTime)
+					}
+					fmt.Fprintf(l.Warnings, "Warning: cannot download %s (%v); using the cached copy%s, which may be stale. Pin it with a digest to make runs reproducible.\n", source, err, fetched)
+				}
+				return cached, nil
+			}
+		}
+		return nil, err
+	}
+	if err := verifyDigest(data, digest); err != nil {
+		return nil, err
+	}
+
+	if cacheFile != "" {
+		if err := os.MkdirAll(filepath.Dir(cacheFile), 0755); err == nil {
+			os.WriteFile(cacheFile, data, 0644) //nolint:errcheck
+		}
+	}
+	return data, nil
+}
+
+// download fetches a remote source.
+func (l *Loader) download(source string) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(source, "oci://"):
+		return l.Registry.Artifact(strings.TrimPrefix(source, "oci://"))
+	case strings.HasPrefix(source, "git::"):
+		return readFromGit(strings.TrimPrefix(source, "git::"))
+	default:
+		resp, err := l.HTTPClient.Get(source)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s returned %s", source, resp.Status)
+		}
+		return io.ReadAll(resp.Body)
+	}
+}
+
+// readFromGit reads a file from a git repository, referenced as
+// <repository>//<path>?ref=<ref>. The ref may be a branch, tag or commit, and
+// only the directory holding the file is checked out.
+func readFromGit(reference string) ([]byte, error) {
+	parsed, err := finder.ParseGitReference(reference)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Subdir == "" {
+		return nil, fmt.Errorf("git reference %s has no //<path> to the config file", reference)
+	}
+	dir, name := path.Split(parsed.Subdir)
+	parsed.Subdir = strings.TrimSuffix(dir, "/")
+
+	checkout, tempDir, err := finder.CloneGitReference(parsed.String())
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tempDir)
+	return os.ReadFile(filepath.Join(checkout, name))
+}
+
+// cacheFile returns the cache location of source, or an empty string if there
+// is no cache directory.
+func (l *Loader) cacheFile(source string) string {
+	if l.CacheDir == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(source))
+	return filepath.Join(l.CacheDir, hex.EncodeToString(sum[:])+".yaml")
+}
+
+// verifyDigest checks data against a sha256:<hex> digest. An empty digest
+// matches anything.
+func verifyDigest(data []byte, digest string) error {
+	if digest == "" {
+		return nil
+	}
+	expected, found := strings.CutPrefix(digest, "sha256:")
+	if !found {
+		return fmt.Errorf("unsupported digest %q: use sha256:<hex>", digest)
+	}
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); actual != strings.ToLower(expected) {
+		return fmt.Errorf("digest mismatch: expected sha256:%s, got sha256:%s", expected, actual)
+	}
+	return nil
+}
+
+// isLocal reports whether source is a file path rather than a remote source.
+func isLocal(source string) bool {
+	return !strings.HasPrefix(source, "git::") && !strings.Contains(source, "://")
+}
+
+// mergeConfig merges override over base. Nested mappings are merged, other
+// values in override replace those in base.
+func mergeConfig(base, override map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(override))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range override {
+		baseMap, baseIsMap := merged[key].(map[string]interface{})
+		overrideMap, overrideIsMap := value.(map[string]interface{})
+		if baseIsMap && overrideIsMap {
+			merged[key] = mergeConfig(baseMap, overrideMap)
+		} else {
+			merged[key] = value
+		}
+	}
+	return merged
+}
@@ -0,0 +1,324 @@
+package configfile
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const orgConfig = `format: json
+failOn:
+  - error
+  - warning
+images:
+  allowedRegistries:
+    - registry.example.com
+  requireDigest: true
+`
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func readConfig(t *testing.T, loader *Loader, path string) map[string]interface{} {
+	t.Helper()
+	data, err := loader.Read(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var config map[string]interface{}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		t.Fatalf("Failed to parse merged config: %v", err)
+	}
+	return config
+}
+
+func digestOf(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func TestReadWithoutExtends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chartscan.yaml")
+	writeFile(t, path, "format: yaml\n")
+
+	data, err := (&Loader{}).Read(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(data) != "format: yaml\n" {
+		t.Errorf("Expected the file content, got %q", data)
+	}
+}
+
+func TestReadMergesOverRemoteBase(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, orgConfig)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "chartscan.yaml")
+	writeFile(t, path, fmt.Sprintf(`extends: %s/org-chartscan.yaml
+failOn:
+  - error
+images:
+  requireDigest: false
+`, server.URL))
+
+	loader := NewLoader()
+	loader.HTTPClient = server.Client()
+	loader.CacheDir = t.TempDir()
+	config := readConfig(t, loader, path)
+
+	if _, found := config["extends"]; found {
+		t.Errorf("Expected extends to be removed from the merged config")
+	}
+	if config["format"] != "json" {
+		t.Errorf("Expected format json from the base, got %v", config["format"])
+	}
+	if failOn := config["failOn"].([]interface{}); len(failOn) != 1 {
+		t.Errorf("Expected the local failOn list to replace the base, got %v", failOn)
+	}
+	images := config["images"].(map[string]interface{})
+	if images["requireDigest"] != false {
+		t.Errorf("Expected the local requireDigest to win, got %v", images["requireDigest"])
+	}
+	if registries := images["allowedRegistries"].([]interface{}); len(registries) != 1 {
+		t.Errorf("Expected allowedRegistries from the base, got %v", registries)
+	}
+}
+
+func TestReadLocalChain(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "org.yaml"), orgConfig)
+	writeFile(t, filepath.Join(dir, "team.yaml"), "extends: org.yaml\nformat: junit\n")
+	path := filepath.Join(dir, "chartscan.yaml")
+	writeFile(t, path, "extends: ./team.yaml\nchartPath: charts\n")
+
+	config := readConfig(t, &Loader{}, path)
+	if config["format"] != "junit" || config["chartPath"] != "charts" || config["failOn"] == nil {
+		t.Errorf("Expected settings from all three files, got %v", config)
+	}
+}
+
+func TestReadOtherFormats(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "org.yaml"), orgConfig)
+	writeFile(t, filepath.Join(dir, "chartscan.toml"), `extends = "org.json"
+chartPath = "charts"
+
+[environments.production]
+valuesFiles = ["values-production.yaml"]
+production = true
+`)
+	writeFile(t, filepath.Join(dir, "org.json"), `{"extends": "org.yaml", "format": "junit", "images": {"requireDigest": false}}`)
+
+	config := readConfig(t, &Loader{}, filepath.Join(dir, "chartscan.toml"))
+	if config["chartPath"] != "charts" || config["format"] != "junit" || config["failOn"] == nil {
+		t.Errorf("Expected settings from all three files, got %v", config)
+	}
+	images, _ := config["images"].(map[string]interface{})
+	if images["requireDigest"] != false || images["allowedRegistries"] == nil {
+		t.Errorf("Expected images merged over the base, got %v", images)
+	}
+	environments, _ := config["environments"].(map[string]interface{})
+	production, _ := environments["production"].(map[string]interface{})
+	if production["production"] != true {
+		t.Errorf("Expected the production environment from TOML, got %v", environments)
+	}
+
+	writeFile(t, filepath.Join(dir, "broken.toml"), "chartPath = \n")
+	if _, err := (&Loader{}).Read(filepath.Join(dir, "broken.toml")); err == nil {
+		t.Errorf("Expected an error for invalid TOML")
+	}
+}
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	nested := filepath.Join(repo, "services", "shop", "charts")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatalf("Failed to create .git: %v", err)
+	}
+
+	// A config file above the repository is never used.
+	writeFile(t, filepath.Join(root, "chartscan.yaml"), "format: json\n")
+	if found, err := Find(nested); err != nil || found != "" {
+		t.Errorf("Expected no config file inside the repository, got %q, %v", found, err)
+	}
+
+	writeFile(t, filepath.Join(repo, ".chartscan.toml"), "format = \"json\"\n")
+	if found, _ := Find(nested); found != filepath.Join(repo, ".chartscan.toml") {
+		t.Errorf("Expected the config file of the repository root, got %q", found)
+	}
+
+	writeFile(t, filepath.Join(repo, "services", "shop", "chartscan.yml"), "format: json\n")
+	writeFile(t, filepath.Join(repo, "services", "shop", "chartscan.json"), "{}")
+	if found, _ := Find(nested); found != filepath.Join(repo, "services", "shop", "chartscan.yml") {
+		t.Errorf("Expected the closest config file, preferring YAML, got %q", found)
+	}
+
+	// Outside a repository the search goes up to the root.
+	outside := filepath.Join(root, "workspace", "charts")
+	if err := os.MkdirAll(outside, 0755); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+	if found, _ := Find(outside); found != filepath.Join(root, "chartscan.yaml") {
+		t.Errorf("Expected the config file above the workspace, got %q", found)
+	}
+}
+
+func TestReadDetectsCycles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.yaml"), "extends: b.yaml\n")
+	writeFile(t, filepath.Join(dir, "b.yaml"), "extends: a.yaml\n")
+
+	_, err := (&Loader{}).Read(filepath.Join(dir, "a.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "extends itself") {
+		t.Errorf("Expected a cycle error, got %v", err)
+	}
+}
+
+func TestReadVerifiesDigest(t *testing.T) {
+	requests := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, orgConfig)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	loader := NewLoader()
+	loader.HTTPClient = server.Client()
+	loader.CacheDir = t.TempDir()
+
+	pinned := filepath.Join(dir, "pinned.yaml")
+	writeFile(t, pinned, fmt.Sprintf("extends:\n  url: %s/org.yaml\n  digest: %s\n", server.URL, digestOf(orgConfig)))
+	readConfig(t, loader, pinned)
+	readConfig(t, loader, pinned)
+	if requests != 1 {
+		t.Errorf("Expected the pinned config to be downloaded once, got %d requests", requests)
+	}
+
+	mismatch := filepath.Join(dir, "mismatch.yaml")
+	writeFile(t, mismatch, fmt.Sprintf("extends:\n  url: %s/other.yaml\n  digest: %s\n", server.URL, digestOf("format: yaml\n")))
+	if _, err := loader.Read(mismatch); err == nil || !strings.Contains(err.Error(), "digest mismatch") {
+		t.Errorf("Expected a digest mismatch, got %v", err)
+	}
+}
+
+func TestReadFallsBackToCache(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, orgConfig)
+	}))
+
+	path := filepath.Join(t.TempDir(), "chartscan.yaml")
+	writeFile(t, path, fmt.Sprintf("extends: %s/org.yaml\n", server.URL))
+
+	loader := NewLoader()
+	loader.HTTPClient = server.Client()
+	loader.CacheDir = t.TempDir()
+	var warnings bytes.Buffer
+	loader.Warnings = &warnings
+	readConfig(t, loader, path)
+	server.Close()
+
+	config := readConfig(t, loader, path)
+	if config["format"] != "json" {
+		t.Errorf("Expected the cached base config, got %v", config)
+	}
+	if !strings.Contains(warnings.String(), "using the cached copy") {
+		t.Errorf("Expected a warning about the cached copy, got '%s'", warnings.String())
+	}
+}
+
+func TestReadRejectsHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, orgConfig)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "plain.yaml")
+	writeFile(t, plain, fmt.Sprintf("extends: %s/org.yaml\n", server.URL))
+	if _, err := (&Loader{HTTPClient: server.Client()}).Read(plain); err == nil || !strings.Contains(err.Error(), "unencrypted HTTP") {
+		t.Errorf("Expected an http:// base to be rejected, got %v", err)
+	}
+
+	allowed := filepath.Join(dir, "allowed.yaml")
+	writeFile(t, allowed, fmt.Sprintf("extends:\n  url: %s/org.yaml\n  allowHTTP: true\n", server.URL))
+	if config := readConfig(t, &Loader{HTTPClient: server.Client()}, allowed); config["format"] != "json" {
+		t.Errorf("Expected the explicitly allowed http:// base, got %v", config)
+	}
+}
+
+func TestReadRejectsLocalFileFromRemoteBase(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "extends: /etc/passwd\n")
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "chartscan.yaml")
+	writeFile(t, path, fmt.Sprintf("extends: %s/org.yaml\n", server.URL))
+
+	if _, err := (&Loader{HTTPClient: server.Client()}).Read(path); err == nil {
+		t.Errorf("Expected an error for a remote config extending a local file")
+	}
+}
+
+func TestReadFromGit(t *testing.T) {
+	repo := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repo, "policies"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	writeFile(t, filepath.Join(repo, "policies", "chartscan.yaml"), orgConfig)
+	for _, args := range [][]string{
+		{"init", "--quiet", "--initial-branch", "main"},
+		{"add", "."},
+		{"-c", "user.name=Platform", "-c", "user.email=platform@example.com", "commit", "--quiet", "-m", "Add policy"},
+	} {
+		if output, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+
+	data, err := readFromGit("file://" + repo + "//policies/chartscan.yaml?ref=main")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(data) != orgConfig {
+		t.Errorf("Expected the policy file, got %q", data)
+	}
+
+	commit, err := exec.Command("git", "-C", repo, "rev-parse", "HEAD").Output()
+	if err != nil {
+		t.Fatalf("git rev-parse failed: %v", err)
+	}
+	data, err = readFromGit("file://" + repo + "//policies/chartscan.yaml?ref=" + strings.TrimSpace(string(commit)))
+	if err != nil {
+		t.Fatalf("Unexpected error for a commit ref: %v", err)
+	}
+	if string(data) != orgConfig {
+		t.Errorf("Expected the policy file at the commit, got %q", data)
+	}
+
+	if _, err := readFromGit("file://" + repo); err == nil {
+		t.Errorf("Expected an error for a reference without a path")
+	}
+}
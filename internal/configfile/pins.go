@@ -0,0 +1,147 @@
+package configfile
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/Jaydee94/chartscan/internal/validation"
+	"gopkg.in/yaml.v3"
+)
+
+// PinUpdate is a pin of a remote asset changed by UpdatePins. Old is empty
+// for an asset that was not pinned before.
+type PinUpdate struct {
+	Key string
+	Old string
+	New string
+}
+
+// latestCommit returns the commit at HEAD of a git repository. It is a
+// variable so tests can avoid the network.
+var latestCommit = func(repository string) (string, error) {
+	output, err := exec.Command("git", "ls-remote", repository, "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("error running git ls-remote %s: %v", repository, err)
+	}
+	commit, _, _ := strings.Cut(string(output), "\t")
+	if commit = strings.TrimSpace(commit); commit == "" {
+		return "", fmt.Errorf("%s has no HEAD", repository)
+	}
+	return commit, nil
+}
+
+// UpdatePins pins the remote assets the config file at path refers to at
+// their current version and rewrites the file, keeping its comments:
+// extends gets the digest of the base configuration's current content, and
+// validation.schemaVersion, if the file has a validation section, the latest
+// commit of the default schema repository. It returns the changed pins.
+func (l *Loader) UpdatePins(path string) ([]PinUpdate, error) {
+	if ext := strings.ToLower(filepath.Ext(path)); ext != ".yaml" && ext != ".yml" {
+		return nil, fmt.Errorf("pins can only be updated in YAML config files, not %s", filepath.Base(path))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", path, err)
+	}
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	root := document.Content[0]
+
+	var updates []PinUpdate
+	if node := mappingValue(root, "extends"); node != nil {
+		var extends Extends
+		if err := node.Decode(&extends); err != nil {
+			return nil, fmt.Errorf("error parsing extends of %s: %v", path, err)
+		}
+		if extends.URL != "" && !isLocal(extends.URL) {
+			if err := extends.checkScheme(); err != nil {
+				return nil, err
+			}
+			content, err := l.download(extends.URL)
+			if err != nil {
+				return nil, fmt.Errorf("error downloading %s: %v", extends.URL, err)
+			}
+			sum := sha256.Sum256(content)
+			digest := "sha256:" + hex.EncodeToString(sum[:])
+			if digest != extends.Digest {
+				updates = append(updates, PinUpdate{Key: "extends.digest", Old: extends.Digest, New: digest})
+				if node.Kind == yaml.MappingNode {
+					setMappingValue(node, "digest", scalarNode(digest))
+				} else {
+					setMappingValue(root, "extends", &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+						scalarNode("url"), scalarNode(extends.URL),
+						scalarNode("digest"), scalarNode(digest),
+					}})
+				}
+			}
+		}
+	}
+
+	if node := mappingValue(root, "validation"); node != nil && node.Kind == yaml.MappingNode {
+		commit, err := latestCommit(validation.DefaultSchemaRepository)
+		if err != nil {
+			return nil, err
+		}
+		var current string
+		if version := mappingValue(node, "schemaVersion"); version != nil {
+			current = version.Value
+		}
+		if commit != current {
+			updates = append(updates, PinUpdate{Key: "validation.schemaVersion", Old: current, New: commit})
+			setMappingValue(node, "schemaVersion", scalarNode(commit))
+		}
+	}
+
+	if len(updates) == 0 {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&document); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
+// mappingValue returns the value of key in a mapping node, or nil.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue replaces the value of key in a mapping node, keeping the
+// comments attached to the old value, or appends key if it is missing.
+func setMappingValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			old := mapping.Content[i+1]
+			value.HeadComment, value.LineComment, value.FootComment = old.HeadComment, old.LineComment, old.FootComment
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content, scalarNode(key), value)
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+}
@@ -0,0 +1,93 @@
+package configfile
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/validation"
+)
+
+func TestUpdatePins(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, orgConfig)
+	}))
+	defer server.Close()
+
+	defer func(original func(string) (string, error)) { latestCommit = original }(latestCommit)
+	latestCommit = func(repository string) (string, error) {
+		if repository != validation.DefaultSchemaRepository {
+			t.Errorf("Expected the default schema repository, got %s", repository)
+		}
+		return "0123abcd", nil
+	}
+
+	path := filepath.Join(t.TempDir(), "chartscan.yaml")
+	writeFile(t, path, fmt.Sprintf(`# Organization policy.
+extends: %s/org.yaml
+format: pretty
+validation:
+  # Keep in sync with the clusters.
+  kubeVersion: "1.29"
+`, server.URL))
+
+	loader := NewLoader()
+	loader.HTTPClient = server.Client()
+	loader.CacheDir = ""
+	updates, err := loader.UpdatePins(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(updates) != 2 {
+		t.Fatalf("Expected 2 updated pins, got %v", updates)
+	}
+	if updates[0].Key != "extends.digest" || updates[0].Old != "" || updates[0].New != digestOf(orgConfig) {
+		t.Errorf("Expected extends to be pinned to the base's digest, got %+v", updates[0])
+	}
+	if updates[1].Key != "validation.schemaVersion" || updates[1].New != "0123abcd" {
+		t.Errorf("Expected the schema version to be pinned, got %+v", updates[1])
+	}
+
+	data, _ := os.ReadFile(path)
+	for _, expected := range []string{
+		"# Organization policy.",
+		"  url: " + server.URL + "/org.yaml\n  digest: " + digestOf(orgConfig),
+		"  # Keep in sync with the clusters.\n  kubeVersion: \"1.29\"\n  schemaVersion: 0123abcd\n",
+	} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("Expected the rewritten config to contain %q, got:\n%s", expected, data)
+		}
+	}
+	readConfig(t, loader, path)
+
+	if updates, err := loader.UpdatePins(path); err != nil || len(updates) != 0 {
+		t.Errorf("Expected current pins to be left alone, got %v (%v)", updates, err)
+	}
+}
+
+func TestUpdatePinsSkipsLocalBase(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "org.yaml"), orgConfig)
+	path := filepath.Join(dir, "chartscan.yaml")
+	writeFile(t, path, "extends: org.yaml\n")
+
+	updates, err := (&Loader{}).UpdatePins(path)
+	if err != nil || len(updates) != 0 {
+		t.Errorf("Expected no pins for a local base, got %v (%v)", updates, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "extends: org.yaml\n" {
+		t.Errorf("Expected the file to be unchanged, got %q", data)
+	}
+}
+
+func TestUpdatePinsRejectsOtherFormats(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chartscan.toml")
+	writeFile(t, path, "format = \"json\"\n")
+	if _, err := NewLoader().UpdatePins(path); err == nil || !strings.Contains(err.Error(), "YAML") {
+		t.Errorf("Expected an error for a TOML config file, got %v", err)
+	}
+}
@@ -0,0 +1,321 @@
+// Package doctor checks that the environment chartscan runs in has what a
+// scan needs: a compatible helm, git, policies that compile, the chart
+// repositories and schemas the configuration refers to and a writable cache.
+// Each check comes with a hint on how to fix it, since in CI containers the
+// errors a scan runs into otherwise are hard to trace back to their cause.
+package doctor
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+	"github.com/Jaydee94/chartscan/internal/rules"
+	"github.com/Jaydee94/chartscan/internal/validation"
+	"gopkg.in/yaml.v3"
+)
+
+// Status is the outcome of a check.
+type Status int
+
+const (
+	// OK means the check passed.
+	OK Status = iota
+	// Warning means scans work, but some features will not.
+	Warning
+	// Failed means scans will fail.
+	Failed
+)
+
+// Check is the result of one check of the environment.
+type Check struct {
+	Name   string
+	Status Status
+	// Detail says what was found, e.g. the helm version.
+	Detail string
+	// Hint says how to fix a failed check.
+	Hint string
+}
+
+// Options are the inputs of Run.
+type Options struct {
+	// Config is the loaded configuration; its policies and validation
+	// sections decide whether policies and schemas are checked.
+	Config models.Config
+	// ChartPaths are the directories searched for charts whose dependency
+	// repositories are checked.
+	ChartPaths []string
+	// CacheDir is the directory scans cache downloads in, "" if disabled.
+	CacheDir string
+	// HelmBinary is the helm executable scans run, "" for helm from PATH.
+	HelmBinary string
+}
+
+// minHelmMinor is the oldest helm 3 minor version scans run with, the first
+// with OCI registry support enabled by default (see
+// renderer.MinHelmVersion).
+const minHelmMinor = 8
+
+// runCommand runs a command and returns its combined output. It is a
+// variable so tests can fake the tools on PATH.
+var runCommand = func(name string, args ...string) (string, error) {
+	if _, err := exec.LookPath(name); err != nil {
+		return "", err
+	}
+	output, err := exec.Command(name, args...).CombinedOutput()
+	return string(output), err
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Run checks the environment and returns the results in a fixed order.
+func Run(opts Options) []Check {
+	checks := []Check{checkHelm(opts.HelmBinary), checkGit()}
+	if len(opts.Config.Policies.Dirs) > 0 {
+		checks = append(checks, checkPolicies(opts.Config.Policies.Dirs))
+	}
+	checks = append(checks, checkRepositories(opts.ChartPaths)...)
+	if opts.Config.Validation.KubeVersion != "" {
+		checks = append(checks, checkSchemas(opts.Config.Validation))
+	}
+	if opts.CacheDir != "" {
+		checks = append(checks, checkCacheDir(opts.CacheDir))
+	}
+	return checks
+}
+
+// AnyFailed reports whether any check failed.
+func AnyFailed(checks []Check) bool {
+	for _, check := range checks {
+		if check.Status == Failed {
+			return true
+		}
+	}
+	return false
+}
+
+var helmVersion = regexp.MustCompile(`v(\d+)\.(\d+)\.(\d+)`)
+
+// checkHelm requires binary, or helm if it is empty, to be helm 3.8 or
+// newer.
+func checkHelm(binary string) Check {
+	check := Check{Name: "helm"}
+	if binary == "" {
+		binary = "helm"
+	}
+	output, err := runCommand(binary, "version", "--short")
+	if errors.Is(err, exec.ErrNotFound) {
+		check.Status = Failed
+		check.Detail = fmt.Sprintf("%s is not on PATH", binary)
+		check.Hint = "install Helm 3 (https://helm.sh/docs/intro/install/), use an image that bundles it or point --helm-binary at it"
+		return check
+	}
+	if err != nil {
+		check.Status = Failed
+		check.Detail = fmt.Sprintf("%s version failed: %v", binary, commandError(output, err))
+		check.Hint = fmt.Sprintf("run `%s version` to see why helm does not start", binary)
+		return check
+	}
+
+	match := helmVersion.FindStringSubmatch(output)
+	if match == nil {
+		check.Status = Warning
+		check.Detail = fmt.Sprintf("cannot parse the helm version %q", strings.TrimSpace(output))
+		check.Hint = "make sure `helm` is Helm 3 and not a wrapper script"
+		return check
+	}
+	check.Detail = match[0]
+	major, _ := strconv.Atoi(match[1])
+	minor, _ := strconv.Atoi(match[2])
+	switch {
+	case major != 3:
+		check.Status = Failed
+		check.Hint = "chartscan needs Helm 3; upgrade helm"
+	case minor < minHelmMinor:
+		check.Status = Failed
+		check.Hint = fmt.Sprintf("chartscan needs Helm 3.%d or newer; upgrade helm", minHelmMinor)
+	}
+	return check
+}
+
+// checkGit warns when git is missing, since scans work without it but git
+// features fail.
+func checkGit() Check {
+	check := Check{Name: "git"}
+	output, err := runCommand("git", "--version")
+	if err != nil {
+		check.Status = Warning
+		check.Detail = "git is not available"
+		if !errors.Is(err, exec.ErrNotFound) {
+			check.Detail = fmt.Sprintf("git --version failed: %v", commandError(output, err))
+		}
+		check.Hint = "install git to use --changed-since, --only-new, --blame and git chart references"
+		return check
+	}
+	check.Detail = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(output), "git version"))
+	return check
+}
+
+// checkPolicies requires the policies below dirs to load and compile.
+func checkPolicies(dirs []string) Check {
+	check := Check{Name: "policies"}
+	if err := rules.CheckPolicies(dirs); err != nil {
+		check.Status = Failed
+		check.Detail = err.Error()
+		check.Hint = "fix the Rego errors in " + strings.Join(dirs, ", ") + " or remove policies.dirs"
+		return check
+	}
+	check.Detail = "compiled from " + strings.Join(dirs, ", ")
+	return check
+}
+
+// checkRepositories checks that the http(s) and oci:// repositories of the
+// dependencies of the charts below paths can be reached. Local and aliased
+// repositories are not checked.
+func checkRepositories(paths []string) []Check {
+	var checks []Check
+	for _, repository := range dependencyRepositories(paths) {
+		check := Check{Name: "repository " + repository}
+		target := strings.TrimSuffix(repository, "/") + "/index.yaml"
+		if host, ok := strings.CutPrefix(repository, "oci://"); ok {
+			// A registry answers /v2/ with 200 or 401; either means it is
+			// reachable.
+			target = "https://" + strings.SplitN(host, "/", 2)[0] + "/v2/"
+		}
+
+		resp, err := httpClient.Get(target)
+		if err != nil {
+			check.Status = Failed
+			check.Detail = fmt.Sprintf("cannot reach %s: %v", target, unwrapURLError(err))
+			check.Hint = "allow outbound HTTPS to the repository, set HTTPS_PROXY and NO_PROXY if a proxy is required, or vendor the dependencies in charts/ with a Chart.lock"
+			checks = append(checks, check)
+			continue
+		}
+		resp.Body.Close()
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized && strings.HasPrefix(repository, "oci://"):
+			check.Detail = "reachable"
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			check.Status = Warning
+			check.Detail = fmt.Sprintf("%s answered %s", target, resp.Status)
+			check.Hint = "add the repository with credentials (helm repo add --username) before scanning"
+		case resp.StatusCode >= 400:
+			check.Status = Failed
+			check.Detail = fmt.Sprintf("%s answered %s", target, resp.Status)
+			check.Hint = "check the repository URL in Chart.yaml"
+		default:
+			check.Detail = "reachable"
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// dependencyRepositories returns the remote repositories of the dependencies
+// of the charts below paths, sorted and without duplicates.
+func dependencyRepositories(paths []string) []string {
+	seen := make(map[string]bool)
+	for _, root := range paths {
+		filepath.Walk(root, func(path string, info os.FileInfo, err error) error { //nolint:errcheck
+			if err != nil || info.IsDir() || info.Name() != "Chart.yaml" {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			var chart struct {
+				Dependencies []struct {
+					Repository string `yaml:"repository"`
+				} `yaml:"dependencies"`
+			}
+			if yaml.Unmarshal(data, &chart) != nil {
+				return nil
+			}
+			for _, dependency := range chart.Dependencies {
+				repository := dependency.Repository
+				if strings.HasPrefix(repository, "http://") || strings.HasPrefix(repository, "https://") || strings.HasPrefix(repository, "oci://") {
+					seen[repository] = true
+				}
+			}
+			return nil
+		})
+	}
+	repositories := make([]string, 0, len(seen))
+	for repository := range seen {
+		repositories = append(repositories, repository)
+	}
+	sort.Strings(repositories)
+	return repositories
+}
+
+// checkSchemas loads the schema of a Deployment for the configured Kubernetes
+// version, from the embedded bundles, the disk cache or the network, the way
+// a scan does.
+func checkSchemas(config models.ValidationConfig) Check {
+	check := Check{Name: "schemas"}
+	config.CRDSchemas = ""
+	validator, err := validation.New(config)
+	if err == nil {
+		_, err = validator.Validate(map[string]interface{}{"apiVersion": "apps/v1", "kind": "Deployment"})
+	}
+	if err != nil {
+		check.Status = Failed
+		check.Detail = fmt.Sprintf("cannot load the schemas of Kubernetes %s: %v", config.KubeVersion, err)
+		embedded := "none"
+		if versions := validation.BundledVersions(); len(versions) > 0 {
+			embedded = strings.Join(versions, ", ")
+		}
+		check.Hint = fmt.Sprintf("run once with network access to fill the cache, point validation.schemaLocations at a local copy, or validate a Kubernetes version whose schemas are embedded (%s)", embedded)
+		return check
+	}
+	check.Detail = "Kubernetes " + config.KubeVersion + " available"
+	return check
+}
+
+// checkCacheDir warns when the cache directory cannot be written, since
+// dependencies and schemas are then downloaded on every run.
+func checkCacheDir(dir string) Check {
+	check := Check{Name: "cache", Detail: dir}
+	err := os.MkdirAll(dir, 0755)
+	if err == nil {
+		var file *os.File
+		if file, err = os.CreateTemp(dir, ".doctor-"); err == nil {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}
+	if err != nil {
+		check.Status = Warning
+		check.Detail = fmt.Sprintf("%s is not writable: %v", dir, err)
+		check.Hint = "pass a writable --cache-dir, or set XDG_CACHE_HOME, to reuse downloads between runs"
+	}
+	return check
+}
+
+// commandError returns the first line of a failed command's output, or err.
+func commandError(output string, err error) string {
+	if line, _, _ := strings.Cut(strings.TrimSpace(output), "\n"); line != "" {
+		return line
+	}
+	return err.Error()
+}
+
+// unwrapURLError drops the method and URL net/http prefixes errors with.
+func unwrapURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
@@ -0,0 +1,158 @@
+package doctor
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// fakeCommands replaces runCommand with canned outputs by command name; a
+// missing name is not on PATH.
+func fakeCommands(t *testing.T, outputs map[string]string) {
+	t.Helper()
+	original := runCommand
+	t.Cleanup(func() { runCommand = original })
+	runCommand = func(name string, args ...string) (string, error) {
+		output, ok := outputs[name]
+		if !ok {
+			return "", &exec.Error{Name: name, Err: exec.ErrNotFound}
+		}
+		return output, nil
+	}
+}
+
+func TestCheckHelm(t *testing.T) {
+	tests := []struct {
+		output   string
+		status   Status
+		expected string
+	}{
+		{"v3.14.2+gc309b6f\n", OK, "v3.14.2"},
+		{"v3.7.1+g1d11fcb\n", Failed, "v3.7.1"},
+		{"Client: v2.17.0+ga690bad\n", Failed, "v2.17.0"},
+	}
+	for _, test := range tests {
+		fakeCommands(t, map[string]string{"helm": test.output})
+		check := checkHelm("")
+		if check.Status != test.status || check.Detail != test.expected {
+			t.Errorf("Expected %d %s for %q, got %d %s", test.status, test.expected, test.output, check.Status, check.Detail)
+		}
+	}
+
+	fakeCommands(t, map[string]string{"/opt/helm3/helm": "v3.14.2+gc309b6f\n"})
+	if check := checkHelm("/opt/helm3/helm"); check.Status != OK {
+		t.Errorf("Expected the configured helm binary to be checked, got %+v", check)
+	}
+
+	fakeCommands(t, nil)
+	if check := checkHelm(""); check.Status != Failed || check.Hint == "" {
+		t.Errorf("Expected a failed check with a hint without helm, got %+v", check)
+	}
+}
+
+func TestRun(t *testing.T) {
+	fakeCommands(t, map[string]string{"helm": "v3.14.2+gc309b6f\n", "git": "git version 2.43.0\n"})
+
+	var config models.Config
+	config.Policies.Dirs = []string{"policies"}
+	checks := Run(Options{Config: config, CacheDir: filepath.Join(t.TempDir(), "cache")})
+
+	expected := []struct {
+		name   string
+		status Status
+	}{{"helm", OK}, {"git", OK}, {"policies", Failed}, {"cache", OK}}
+	if len(checks) != len(expected) {
+		t.Fatalf("Expected %d checks, got %+v", len(expected), checks)
+	}
+	for i, check := range checks {
+		if check.Name != expected[i].name || check.Status != expected[i].status {
+			t.Errorf("Expected %s with status %d, got %+v", expected[i].name, expected[i].status, check)
+		}
+	}
+	if checks[1].Detail != "2.43.0" {
+		t.Errorf("Expected git version 2.43.0, got %q", checks[1].Detail)
+	}
+	if !AnyFailed(checks) {
+		t.Errorf("Expected the missing policies to fail the checks")
+	}
+}
+
+func TestCheckRepositories(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/stable/index.yaml":
+			fmt.Fprintln(w, "apiVersion: v1")
+		case "/private/index.yaml":
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	root := t.TempDir()
+	chart := fmt.Sprintf(`apiVersion: v2
+name: web
+version: 1.0.0
+dependencies:
+  - name: redis
+    repository: %[1]s/stable
+  - name: auth
+    repository: %[1]s/private
+  - name: gone
+    repository: %[1]s/gone/
+  - name: common
+    repository: file://../common
+  - name: postgres
+    repository: "@bitnami"
+`, server.URL)
+	if err := os.MkdirAll(filepath.Join(root, "web"), 0755); err != nil {
+		t.Fatalf("Failed to create chart: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "web", "Chart.yaml"), []byte(chart), 0644); err != nil {
+		t.Fatalf("Failed to write Chart.yaml: %v", err)
+	}
+
+	checks := checkRepositories([]string{root})
+	expected := map[string]Status{
+		"repository " + server.URL + "/gone/":   Failed,
+		"repository " + server.URL + "/private": Warning,
+		"repository " + server.URL + "/stable":  OK,
+	}
+	if len(checks) != len(expected) {
+		t.Fatalf("Expected %d checks, got %+v", len(expected), checks)
+	}
+	for _, check := range checks {
+		status, ok := expected[check.Name]
+		if !ok || check.Status != status {
+			t.Errorf("Expected status %d for %s, got %+v", status, check.Name, check)
+		}
+	}
+}
+
+func TestCheckSchemas(t *testing.T) {
+	dir := t.TempDir()
+	config := models.ValidationConfig{
+		KubeVersion:     "1.29",
+		SchemaLocations: []string{filepath.Join(dir, "{{ .ResourceKind }}{{ .KindSuffix }}.json")},
+	}
+	if check := checkSchemas(config); check.Status != Failed {
+		t.Errorf("Expected a failed check without schemas, got %+v", check)
+	}
+
+	// Misses are cached for the whole process, so the schema goes elsewhere.
+	dir = t.TempDir()
+	config.SchemaLocations = []string{filepath.Join(dir, "{{ .ResourceKind }}{{ .KindSuffix }}.json")}
+	if err := os.WriteFile(filepath.Join(dir, "deployment-apps-v1.json"), []byte(`{"type": "object"}`), 0644); err != nil {
+		t.Fatalf("Failed to write schema: %v", err)
+	}
+	if check := checkSchemas(config); check.Status != OK {
+		t.Errorf("Expected a passed check, got %+v", check)
+	}
+}
@@ -0,0 +1,169 @@
+package finder
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IsChartArchive reports whether path is a packaged chart, i.e. a .tgz or
+// .tar.gz file whose top-level directory contains a Chart.yaml file.
+func IsChartArchive(path string) bool {
+	if !hasArchiveSuffix(path) {
+		return false
+	}
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	found, err := archiveHasChartYaml(path)
+	return err == nil && found
+}
+
+// FindHelmChartArchives finds all packaged charts in the file tree rooted at
+// root. Archives in the charts/ directory of a chart are dependencies of that
+// chart and are not returned.
+func FindHelmChartArchives(root string) ([]string, error) {
+	var archives []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if info.IsDir() || !hasArchiveSuffix(path) || isDependencyArchive(path) {
+			return nil
+		}
+		if IsChartArchive(path) {
+			archives = append(archives, path)
+		}
+		return nil
+	})
+	return archives, err
+}
+
+// ExtractChartArchive unpacks a packaged chart into a new temporary directory.
+// It returns the chart directory and the temporary directory, which the caller
+// must remove.
+func ExtractChartArchive(archive string) (string, string, error) {
+	tempDir, err := os.MkdirTemp("", "chartscan-archive")
+	if err != nil {
+		return "", "", fmt.Errorf("error creating temp dir: %v", err)
+	}
+
+	if err := extractTarGz(archive, tempDir); err != nil {
+		os.RemoveAll(tempDir)
+		return "", "", fmt.Errorf("error extracting %s: %v", archive, err)
+	}
+
+	entries, err := os.ReadDir(tempDir)
+	if err != nil || len(entries) != 1 || !entries[0].IsDir() {
+		os.RemoveAll(tempDir)
+		return "", "", fmt.Errorf("unexpected contents after extracting %s", archive)
+	}
+
+	return filepath.Join(tempDir, entries[0].Name()), tempDir, nil
+}
+
+// hasArchiveSuffix reports whether path has a gzipped tarball extension.
+func hasArchiveSuffix(path string) bool {
+	return strings.HasSuffix(path, ".tgz") || strings.HasSuffix(path, ".tar.gz")
+}
+
+// isDependencyArchive reports whether path lies in the charts/ directory of a
+// chart.
+func isDependencyArchive(path string) bool {
+	dir := filepath.Dir(path)
+	if filepath.Base(dir) != "charts" {
+		return false
+	}
+	stat, err := os.Stat(filepath.Join(filepath.Dir(dir), "Chart.yaml"))
+	return err == nil && stat.Mode().IsRegular()
+}
+
+// archiveHasChartYaml reports whether the tarball contains <dir>/Chart.yaml
+// for its top-level directory.
+func archiveHasChartYaml(archive string) (bool, error) {
+	found := false
+	err := walkTarGz(archive, func(header *tar.Header, _ io.Reader) error {
+		name := path.Clean(header.Name)
+		if header.Typeflag == tar.TypeReg && path.Base(name) == "Chart.yaml" && path.Dir(path.Dir(name)) == "." {
+			found = true
+			return errStopWalk
+		}
+		return nil
+	})
+	return found, err
+}
+
+// extractTarGz unpacks the regular files and directories of a tarball into
+// dest. Entries escaping dest are rejected.
+func extractTarGz(archive, dest string) error {
+	return walkTarGz(archive, func(header *tar.Header, content io.Reader) error {
+		name := path.Clean(header.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("invalid path %q in archive", header.Name)
+		}
+		target := filepath.Join(dest, filepath.FromSlash(name))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			return os.MkdirAll(target, 0755)
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(file, content); err != nil {
+				file.Close()
+				return err
+			}
+			return file.Close()
+		default:
+			// Charts packaged by helm contain only files and directories.
+			return nil
+		}
+	})
+}
+
+// errStopWalk ends walkTarGz early without reporting an error.
+var errStopWalk = errors.New("stop walking archive")
+
+// walkTarGz calls fn for every entry of a gzipped tarball.
+func walkTarGz(archive string, fn func(header *tar.Header, content io.Reader) error) error {
+	file, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(header, tarReader); err != nil {
+			if errors.Is(err, errStopWalk) {
+				return nil
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package finder
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeArchive writes a gzipped tarball containing the given files.
+func writeArchive(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer file.Close()
+
+	gzipWriter := gzip.NewWriter(file)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for name, content := range files {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := tarWriter.Write([]byte(content)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestIsChartArchive(t *testing.T) {
+	tempDir := t.TempDir()
+	chart := filepath.Join(tempDir, "mychart-1.0.0.tgz")
+	writeArchive(t, chart, map[string]string{
+		"mychart/Chart.yaml":             "apiVersion: v2\nname: mychart\n",
+		"mychart/templates/service.yaml": "kind: Service\n",
+	})
+	other := filepath.Join(tempDir, "backup.tar.gz")
+	writeArchive(t, other, map[string]string{"data/file.txt": "hello"})
+
+	if !IsChartArchive(chart) {
+		t.Errorf("Expected %s to be a chart archive", chart)
+	}
+	if IsChartArchive(other) {
+		t.Errorf("Expected %s not to be a chart archive", other)
+	}
+	if IsChartArchive(tempDir) {
+		t.Errorf("Expected directory not to be a chart archive")
+	}
+}
+
+func TestFindHelmChartArchives(t *testing.T) {
+	tempDir := t.TempDir()
+	chartDir := filepath.Join(tempDir, "app")
+	os.MkdirAll(filepath.Join(chartDir, "charts"), 0755)
+	os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte("apiVersion: v2"), 0644)
+
+	packaged := filepath.Join(tempDir, "mychart-1.0.0.tgz")
+	writeArchive(t, packaged, map[string]string{"mychart/Chart.yaml": "apiVersion: v2\n"})
+	// Dependencies of app are scanned as part of app, not on their own.
+	writeArchive(t, filepath.Join(chartDir, "charts", "redis-1.0.0.tgz"), map[string]string{"redis/Chart.yaml": "apiVersion: v2\n"})
+
+	archives, err := FindHelmChartArchives(tempDir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(archives) != 1 || archives[0] != packaged {
+		t.Fatalf("Expected [%s], got %v", packaged, archives)
+	}
+}
+
+func TestExtractChartArchive(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "mychart-1.0.0.tgz")
+	writeArchive(t, archive, map[string]string{
+		"mychart/Chart.yaml":             "apiVersion: v2\nname: mychart\n",
+		"mychart/templates/service.yaml": "kind: Service\n",
+	})
+
+	chartDir, tempDir, err := ExtractChartArchive(archive)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	if chartDir != filepath.Join(tempDir, "mychart") {
+		t.Errorf("Expected chart dir %s, got %s", filepath.Join(tempDir, "mychart"), chartDir)
+	}
+	content, err := os.ReadFile(filepath.Join(chartDir, "templates", "service.yaml"))
+	if err != nil || string(content) != "kind: Service\n" {
+		t.Errorf("Expected extracted template, got %q (%v)", content, err)
+	}
+}
+
+func TestExtractChartArchive_RejectsTraversal(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "evil.tgz")
+	writeArchive(t, archive, map[string]string{"../evil.txt": "x"})
+
+	if _, _, err := ExtractChartArchive(archive); err == nil {
+		t.Fatalf("Expected error for archive escaping the target directory, got nil")
+	}
+}
@@ -1,10 +1,74 @@
 package finder
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Chart types declared by the type field of Chart.yaml.
+const (
+	ChartTypeApplication = "application"
+	ChartTypeLibrary     = "library"
 )
 
+// ChartInfo describes a chart directory by the metadata of its Chart.yaml,
+// parsed once at discovery.
+type ChartInfo struct {
+	Path       string
+	Name       string
+	Version    string
+	APIVersion string
+	// Type is application or library. Charts that declare no type are
+	// applications.
+	Type string
+	// HasDependencies reports whether Chart.yaml declares dependencies.
+	HasDependencies bool
+	// Err is set when Chart.yaml cannot be read or parsed. Such charts are
+	// still found, so that scanning them reports the problem.
+	Err error
+}
+
+// ReadChartInfo parses the Chart.yaml of the chart directory dir.
+func ReadChartInfo(dir string) ChartInfo {
+	info := ChartInfo{Path: dir, Type: ChartTypeApplication}
+	data, err := os.ReadFile(filepath.Join(dir, "Chart.yaml"))
+	if err != nil {
+		info.Err = fmt.Errorf("error reading Chart.yaml: %v", err)
+		return info
+	}
+	var chart struct {
+		APIVersion   string        `yaml:"apiVersion"`
+		Name         string        `yaml:"name"`
+		Version      string        `yaml:"version"`
+		Type         string        `yaml:"type"`
+		Dependencies []interface{} `yaml:"dependencies"`
+	}
+	if err := yaml.Unmarshal(data, &chart); err != nil {
+		info.Err = fmt.Errorf("error parsing Chart.yaml: %v", err)
+		return info
+	}
+	info.Name, info.Version, info.APIVersion = chart.Name, chart.Version, chart.APIVersion
+	if chart.Type != "" {
+		info.Type = chart.Type
+	}
+	info.HasDependencies = len(chart.Dependencies) > 0
+	return info
+}
+
+// FindHelmCharts finds all chart directories in the file tree rooted at root,
+// like FindHelmChartDirs, and reads their Chart.yaml.
+func FindHelmCharts(root string) ([]ChartInfo, error) {
+	dirs, err := FindHelmChartDirs(root)
+	charts := make([]ChartInfo, 0, len(dirs))
+	for _, dir := range dirs {
+		charts = append(charts, ReadChartInfo(dir))
+	}
+	return charts, err
+}
+
 // FindHelmChartDirs finds all directories in the file tree rooted at root that contain a Chart.yaml file.
 // It returns a slice of strings that stores the paths to the Helm chart directories and an error if an error occurs while walking the tree.
 // If the root is empty, it returns an empty slice and a nil error.
@@ -35,3 +99,54 @@ func FindHelmChartDirs(root string) ([]string, error) {
 	// Return the chartDirs slice and the error from the filepath.Walk call.
 	return chartDirs, err
 }
+
+// ChartFilter selects charts by name and type. Only and Skip hold shell
+// patterns matched against the chart name, or the directory name of charts
+// without one; a chart is kept if it matches any Only pattern, or there are
+// none, and no Skip pattern. Type, if set, keeps only charts of that type.
+type ChartFilter struct {
+	Only []string
+	Skip []string
+	Type string
+}
+
+// Validate rejects malformed patterns and unknown chart types.
+func (f ChartFilter) Validate() error {
+	for _, pattern := range append(append([]string{}, f.Only...), f.Skip...) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid chart name pattern %q: %v", pattern, err)
+		}
+	}
+	if f.Type != "" && f.Type != ChartTypeApplication && f.Type != ChartTypeLibrary {
+		return fmt.Errorf("invalid chart type %q (expected %s or %s)", f.Type, ChartTypeApplication, ChartTypeLibrary)
+	}
+	return nil
+}
+
+// Empty reports whether the filter keeps every chart.
+func (f ChartFilter) Empty() bool {
+	return len(f.Only) == 0 && len(f.Skip) == 0 && f.Type == ""
+}
+
+// Match reports whether the filter keeps chart.
+func (f ChartFilter) Match(chart ChartInfo) bool {
+	name := chart.Name
+	if name == "" {
+		name = filepath.Base(chart.Path)
+	}
+	matches := func(patterns []string) bool {
+		for _, pattern := range patterns {
+			if ok, _ := filepath.Match(pattern, name); ok {
+				return true
+			}
+		}
+		return false
+	}
+	if len(f.Only) > 0 && !matches(f.Only) {
+		return false
+	}
+	if matches(f.Skip) {
+		return false
+	}
+	return f.Type == "" || chart.Type == f.Type
+}
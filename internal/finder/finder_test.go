@@ -3,6 +3,7 @@ package finder
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -42,3 +43,72 @@ func TestFindHelmChartDirs_NonExistentDir(t *testing.T) {
 		t.Fatalf("Expected error for non-existent directory, got nil")
 	}
 }
+
+func TestFindHelmCharts(t *testing.T) {
+	tempDir := t.TempDir()
+	charts := map[string]string{
+		"web":    "apiVersion: v2\nname: web\nversion: 1.2.0\ndependencies:\n  - name: common\n    version: 2.x\n",
+		"common": "apiVersion: v2\nname: common\nversion: 2.0.0\ntype: library\n",
+		"broken": "name: [",
+	}
+	for dir, chartYaml := range charts {
+		os.Mkdir(filepath.Join(tempDir, dir), 0755)
+		os.WriteFile(filepath.Join(tempDir, dir, "Chart.yaml"), []byte(chartYaml), 0644)
+	}
+
+	found, err := FindHelmCharts(tempDir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(found) != 3 {
+		t.Fatalf("Expected 3 charts, got %v", found)
+	}
+	broken, common, web := found[0], found[1], found[2]
+	if broken.Err == nil || broken.Path != filepath.Join(tempDir, "broken") {
+		t.Errorf("Expected the broken chart with a parse error, got %+v", broken)
+	}
+	if common.Type != ChartTypeLibrary || common.HasDependencies {
+		t.Errorf("Expected a library chart without dependencies, got %+v", common)
+	}
+	expected := ChartInfo{Path: filepath.Join(tempDir, "web"), Name: "web", Version: "1.2.0", APIVersion: "v2", Type: ChartTypeApplication, HasDependencies: true}
+	if web != expected {
+		t.Errorf("Expected %+v, got %+v", expected, web)
+	}
+}
+
+func TestChartFilter(t *testing.T) {
+	charts := []ChartInfo{
+		{Path: "charts/web", Name: "web", Type: ChartTypeApplication},
+		{Path: "charts/web-admin", Name: "web-admin", Type: ChartTypeApplication},
+		{Path: "charts/common", Name: "common", Type: ChartTypeLibrary},
+		{Path: "charts/broken", Type: ChartTypeApplication},
+	}
+	tests := []struct {
+		filter   ChartFilter
+		expected []string
+	}{
+		{ChartFilter{}, []string{"web", "web-admin", "common", "broken"}},
+		{ChartFilter{Only: []string{"web*"}}, []string{"web", "web-admin"}},
+		{ChartFilter{Only: []string{"web*"}, Skip: []string{"*-admin"}}, []string{"web"}},
+		{ChartFilter{Skip: []string{"broken"}, Type: ChartTypeApplication}, []string{"web", "web-admin"}},
+		{ChartFilter{Type: ChartTypeLibrary}, []string{"common"}},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, chart := range charts {
+			if test.filter.Match(chart) {
+				got = append(got, filepath.Base(chart.Path))
+			}
+		}
+		if strings.Join(got, ",") != strings.Join(test.expected, ",") {
+			t.Errorf("Expected %v for %+v, got %v", test.expected, test.filter, got)
+		}
+	}
+
+	if err := (ChartFilter{Only: []string{"["}}).Validate(); err == nil {
+		t.Errorf("Expected an error for a malformed pattern")
+	}
+	if err := (ChartFilter{Type: "service"}).Validate(); err == nil {
+		t.Errorf("Expected an error for an unknown chart type")
+	}
+}
@@ -0,0 +1,245 @@
+package finder
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// GitReference addresses a directory in a git repository, written in
+// go-getter style as [git::]<repository>[//<subdir>][?ref=<ref>], e.g.
+// https://github.com/org/repo.git//charts/foo?ref=v1.2.3.
+type GitReference struct {
+	Repository string
+	Subdir     string
+	Ref        string
+}
+
+// IsGitReference reports whether target addresses a git repository rather
+// than a local path: it has a git:: prefix, or is a URL or scp-style address
+// of a repository ending in .git.
+func IsGitReference(target string) bool {
+	if strings.HasPrefix(target, "git::") {
+		return true
+	}
+	if strings.HasPrefix(target, "oci://") {
+		return false
+	}
+	if !strings.Contains(target, "://") && !strings.HasPrefix(target, "git@") {
+		return false
+	}
+	reference, err := ParseGitReference(target)
+	return err == nil && strings.HasSuffix(reference.Repository, ".git")
+}
+
+// ParseGitReference splits a go-getter style git address into the
+// repository, the subdirectory and the ref.
+func ParseGitReference(target string) (GitReference, error) {
+	address, query, _ := strings.Cut(strings.TrimPrefix(target, "git::"), "?")
+	var reference GitReference
+	for _, param := range strings.Split(query, "&") {
+		if ref, ok := strings.CutPrefix(param, "ref="); ok {
+			reference.Ref = ref
+		} else if param != "" {
+			return GitReference{}, fmt.Errorf("unsupported parameter %q in git reference %s", param, target)
+		}
+	}
+
+	start := 0
+	if i := strings.Index(address, "://"); i >= 0 {
+		start = i + len("://")
+	}
+	reference.Repository = address
+	if i := strings.Index(address[start:], "//"); i >= 0 {
+		reference.Repository = address[:start+i]
+		reference.Subdir = strings.Trim(path.Clean(address[start+i+len("//"):]), "/")
+		if reference.Subdir == "." {
+			reference.Subdir = ""
+		}
+		if reference.Subdir == ".." || strings.HasPrefix(reference.Subdir, "../") {
+			return GitReference{}, fmt.Errorf("subdirectory of git reference %s leaves the repository", target)
+		}
+	}
+	if reference.Repository == "" {
+		return GitReference{}, fmt.Errorf("git reference %s has no repository", target)
+	}
+	return reference, nil
+}
+
+// String returns the reference in go-getter style, with a git:: prefix if the
+// repository does not end in .git.
+func (r GitReference) String() string {
+	s := r.Repository
+	if !strings.HasSuffix(s, ".git") {
+		s = "git::" + s
+	}
+	if r.Subdir != "" {
+		s += "//" + r.Subdir
+	}
+	if r.Ref != "" {
+		s += "?ref=" + r.Ref
+	}
+	return s
+}
+
+// Join returns the reference to a path below the reference's subdirectory.
+func (r GitReference) Join(rel string) GitReference {
+	r.Subdir = strings.Trim(path.Join(r.Subdir, filepath.ToSlash(rel)), "/")
+	if r.Subdir == "." {
+		r.Subdir = ""
+	}
+	return r
+}
+
+// CloneGitReference checks out the subdirectory of a git reference into a new
+// temporary directory. It returns the checked out subdirectory and the
+// temporary directory, which the caller must remove.
+func CloneGitReference(target string) (string, string, error) {
+	dirs, tempDirs, err := CloneGitReferences([]string{target})
+	if err != nil {
+		return "", "", err
+	}
+	return dirs[0], tempDirs[0], nil
+}
+
+// CloneGitReferences checks out the subdirectories of git references into new
+// temporary directories, one per repository and ref. It returns the checked
+// out subdirectory of each target and the temporary directories, which the
+// caller must remove. Only the ref's commit is fetched, file contents are
+// fetched lazily (a partial clone, where the server supports it), and a
+// sparse checkout limits them to the referenced subdirectories and the
+// file:// dependencies of the charts below them, so unrelated content of
+// large repositories is never downloaded. The ref may be a branch,
+// tag or commit.
+func CloneGitReferences(targets []string) ([]string, []string, error) {
+	type checkout struct {
+		repository, ref string
+	}
+	references := make([]GitReference, len(targets))
+	subdirs := make(map[checkout][]string)
+	var order []checkout
+	for i, target := range targets {
+		reference, err := ParseGitReference(target)
+		if err != nil {
+			return nil, nil, err
+		}
+		references[i] = reference
+		key := checkout{reference.Repository, reference.Ref}
+		if _, ok := subdirs[key]; !ok {
+			order = append(order, key)
+		}
+		subdirs[key] = append(subdirs[key], reference.Subdir)
+	}
+
+	var tempDirs []string
+	removeTempDirs := func() {
+		for _, dir := range tempDirs {
+			os.RemoveAll(dir)
+		}
+	}
+	checkouts := make(map[checkout]string)
+	for _, key := range order {
+		tempDir, err := sparseCheckout(key.repository, key.ref, subdirs[key])
+		if err != nil {
+			removeTempDirs()
+			return nil, nil, err
+		}
+		tempDirs = append(tempDirs, tempDir)
+		checkouts[key] = tempDir
+	}
+
+	dirs := make([]string, len(targets))
+	for i, reference := range references {
+		dir := filepath.Join(checkouts[checkout{reference.Repository, reference.Ref}], filepath.FromSlash(reference.Subdir))
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			removeTempDirs()
+			return nil, nil, fmt.Errorf("%s not found in %s", reference.Subdir, reference)
+		}
+		dirs[i] = dir
+	}
+	return dirs, tempDirs, nil
+}
+
+// sparseCheckout fetches the commit at ref of repository into a new temporary
+// directory and checks out the given subdirectories and the file://
+// dependencies of their charts, or everything if one of them is the
+// repository root.
+func sparseCheckout(repository, ref string, subdirs []string) (string, error) {
+	tempDir, err := os.MkdirTemp("", "chartscan-git")
+	if err != nil {
+		return "", fmt.Errorf("error creating temp dir: %v", err)
+	}
+
+	if ref == "" {
+		ref = "HEAD"
+	}
+	run := func(steps ...[]string) error {
+		for _, args := range steps {
+			gitCmd := exec.Command("git", append([]string{"-C", tempDir}, args...)...)
+			if output, err := gitCmd.CombinedOutput(); err != nil {
+				return fmt.Errorf("error running git %s: %v\n%s", strings.Join(args, " "), err, output)
+			}
+		}
+		return nil
+	}
+	err = run(
+		[]string{"init", "--quiet"},
+		[]string{"remote", "add", "--", "origin", repository},
+		[]string{"fetch", "--quiet", "--no-tags", "--depth", "1", "--filter=blob:none", "--", "origin", ref},
+	)
+	if err == nil && !slices.Contains(subdirs, "") {
+		// Dependencies that cannot be resolved are left to helm, with
+		// everything checked out.
+		if dirs, depErr := sparseDirs(tempDir, subdirs); depErr == nil {
+			// Non-cone patterns match only the directories, not the files
+			// along their parents as cone mode does.
+			patterns := []string{"sparse-checkout", "set", "--no-cone", "--"}
+			for _, dir := range dirs {
+				patterns = append(patterns, "/"+sparsePatternEscaper.Replace(dir)+"/")
+			}
+			err = run(patterns)
+		}
+	}
+	if err == nil {
+		err = run([]string{"checkout", "--quiet", "FETCH_HEAD"})
+	}
+	if err != nil {
+		os.RemoveAll(tempDir)
+		return "", err
+	}
+	return tempDir, nil
+}
+
+// sparseDirs returns subdirs and the paths of the file:// dependencies the
+// charts below them declare at FETCH_HEAD of the repository in dir. Only
+// trees are read to find the charts; their Chart.yaml files are fetched on
+// demand.
+func sparseDirs(dir string, subdirs []string) ([]string, error) {
+	dirs := slices.Clone(subdirs)
+	for _, subdir := range subdirs {
+		files, err := gitOutput(dir, "--literal-pathspecs", "ls-tree", "-r", "-z", "--name-only", "FETCH_HEAD", "--", subdir)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range strings.Split(files, "\x00") {
+			if path.Base(file) != "Chart.yaml" {
+				continue
+			}
+			paths, err := localDependencyPaths(dir, "FETCH_HEAD", path.Dir(file))
+			if err != nil {
+				return nil, err
+			}
+			dirs = append(dirs, paths[1:]...)
+		}
+	}
+	slices.Sort(dirs)
+	return slices.Compact(dirs), nil
+}
+
+// sparsePatternEscaper escapes the characters with a meaning in gitignore
+// style sparse-checkout patterns.
+var sparsePatternEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "!", `\!`, "#", `\#`)
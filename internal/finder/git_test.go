@@ -0,0 +1,178 @@
+package finder
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsGitReference(t *testing.T) {
+	tests := map[string]bool{
+		"https://github.com/org/repo.git//charts/foo?ref=v1.2.3": true,
+		"https://github.com/org/repo.git":                        true,
+		"git@github.com:org/repo.git//charts/foo":                true,
+		"git::https://example.com/charts//foo":                   true,
+		"oci://registry.example.com/charts/foo":                  false,
+		"https://example.com/charts/foo-1.0.0.tgz":               false,
+		"charts/foo.git": false,
+		"./charts":       false,
+	}
+	for target, expected := range tests {
+		if got := IsGitReference(target); got != expected {
+			t.Errorf("Expected IsGitReference(%q) to be %v, got %v", target, expected, got)
+		}
+	}
+}
+
+func TestParseGitReference(t *testing.T) {
+	tests := map[string]GitReference{
+		"https://github.com/org/repo.git//charts/foo?ref=v1.2.3": {"https://github.com/org/repo.git", "charts/foo", "v1.2.3"},
+		"https://github.com/org/repo.git":                        {"https://github.com/org/repo.git", "", ""},
+		"git@github.com:org/repo.git//charts/?ref=main":          {"git@github.com:org/repo.git", "charts", "main"},
+		"git::ssh://git@example.com/repo//foo":                   {"ssh://git@example.com/repo", "foo", ""},
+	}
+	for target, expected := range tests {
+		reference, err := ParseGitReference(target)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %q: %v", target, err)
+			continue
+		}
+		if reference != expected {
+			t.Errorf("Expected %q to parse as %+v, got %+v", target, expected, reference)
+		}
+	}
+
+	for _, target := range []string{"https://github.com/org/repo.git//../etc", "https://github.com/org/repo.git?depth=1"} {
+		if _, err := ParseGitReference(target); err == nil {
+			t.Errorf("Expected an error parsing %q", target)
+		}
+	}
+
+	reference := GitReference{"https://github.com/org/repo.git", "charts", "v1"}
+	if got := reference.Join("foo").String(); got != "https://github.com/org/repo.git//charts/foo?ref=v1" {
+		t.Errorf("Expected the joined reference, got '%s'", got)
+	}
+	if got := (GitReference{Repository: "ssh://git@example.com/repo"}).String(); got != "git::ssh://git@example.com/repo" {
+		t.Errorf("Expected the git:: prefix to be kept, got '%s'", got)
+	}
+}
+
+// initGitRepo creates a repository with two charts, unrelated content and a
+// v1.0.0 tag.
+func initGitRepo(t *testing.T) string {
+	t.Helper()
+	repo := t.TempDir()
+	os.MkdirAll(filepath.Join(repo, "charts", "foo"), 0755)
+	os.MkdirAll(filepath.Join(repo, "charts", "bar"), 0755)
+	os.MkdirAll(filepath.Join(repo, "other"), 0755)
+	os.WriteFile(filepath.Join(repo, "charts", "foo", "Chart.yaml"), []byte("name: foo\n"), 0644)
+	os.WriteFile(filepath.Join(repo, "charts", "bar", "Chart.yaml"), []byte("name: bar\n"), 0644)
+	os.WriteFile(filepath.Join(repo, "charts", "index.yaml"), []byte("entries: {}\n"), 0644)
+	os.WriteFile(filepath.Join(repo, "README.md"), []byte("# monorepo\n"), 0644)
+	os.WriteFile(filepath.Join(repo, "other", "big.txt"), []byte("unrelated\n"), 0644)
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "initial"},
+		{"tag", "v1.0.0"},
+	} {
+		if output, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput(); err != nil {
+			t.Skipf("git unavailable: %v\n%s", err, output)
+		}
+	}
+	return repo
+}
+
+func TestCloneGitReference(t *testing.T) {
+	repo := initGitRepo(t)
+
+	if _, _, err := CloneGitReference("file://" + repo + ".git//charts/foo?ref=v1.0.0"); err == nil {
+		t.Fatalf("Expected an error cloning a missing repository")
+	}
+
+	dir, tempDir, err := CloneGitReference("git::file://" + repo + "//charts/foo?ref=v1.0.0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	if _, err := os.Stat(filepath.Join(dir, "Chart.yaml")); err != nil {
+		t.Errorf("Expected the chart to be checked out, got %v", err)
+	}
+	for _, unrelated := range []string{"other", "README.md", "charts/index.yaml", "charts/bar"} {
+		if _, err := os.Stat(filepath.Join(tempDir, unrelated)); !os.IsNotExist(err) {
+			t.Errorf("Expected only the subdirectory to be checked out, found %s", unrelated)
+		}
+	}
+
+	if _, _, err := CloneGitReference("git::file://" + repo + "//missing"); err == nil {
+		t.Errorf("Expected an error for a missing subdirectory")
+	}
+}
+
+func TestCloneGitReferenceLocalDependencies(t *testing.T) {
+	repo := initGitRepo(t)
+	os.MkdirAll(filepath.Join(repo, "charts", "foo", "nested"), 0755)
+	os.MkdirAll(filepath.Join(repo, "libs", "common"), 0755)
+	os.MkdirAll(filepath.Join(repo, "libs", "base"), 0755)
+	os.WriteFile(filepath.Join(repo, "charts", "foo", "nested", "Chart.yaml"), []byte("name: nested\ndependencies:\n  - name: common\n    repository: file://../../../libs/common\n"), 0644)
+	os.WriteFile(filepath.Join(repo, "libs", "common", "Chart.yaml"), []byte("name: common\ndependencies:\n  - name: base\n    repository: file://../base\n"), 0644)
+	os.WriteFile(filepath.Join(repo, "libs", "base", "Chart.yaml"), []byte("name: base\n"), 0644)
+	for _, args := range [][]string{
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "libraries"},
+	} {
+		if output, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+
+	_, tempDir, err := CloneGitReference("git::file://" + repo + "//charts/foo")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for _, chart := range []string{"charts/foo/nested", "libs/common", "libs/base"} {
+		if _, err := os.Stat(filepath.Join(tempDir, chart, "Chart.yaml")); err != nil {
+			t.Errorf("Expected %s to be checked out, got %v", chart, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "charts", "bar")); !os.IsNotExist(err) {
+		t.Errorf("Expected unrelated charts not to be checked out")
+	}
+}
+
+func TestCloneGitReferencesSharesCheckouts(t *testing.T) {
+	repo := initGitRepo(t)
+
+	dirs, tempDirs, err := CloneGitReferences([]string{
+		"git::file://" + repo + "//charts/foo?ref=v1.0.0",
+		"git::file://" + repo + "//charts/bar?ref=v1.0.0",
+		"git::file://" + repo + "//charts/foo",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer func() {
+		for _, dir := range tempDirs {
+			os.RemoveAll(dir)
+		}
+	}()
+
+	if len(tempDirs) != 2 {
+		t.Errorf("Expected one checkout per ref, got %v", tempDirs)
+	}
+	if len(dirs) != 3 || filepath.Dir(dirs[0]) != filepath.Dir(dirs[1]) {
+		t.Fatalf("Expected foo and bar at v1.0.0 to share a checkout, got %v", dirs)
+	}
+	for _, dir := range dirs {
+		if _, err := os.Stat(filepath.Join(dir, "Chart.yaml")); err != nil {
+			t.Errorf("Expected a chart in %s, got %v", dir, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dirs[0]), "..", "other")); !os.IsNotExist(err) {
+		t.Errorf("Expected unrelated content not to be checked out")
+	}
+}
@@ -0,0 +1,110 @@
+package finder
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CheckoutChartRevision extracts the chart in dir, a directory of a git
+// working tree, as of ref into a new temporary directory. The file://
+// dependencies the chart declares at that revision are extracted along with
+// it, keeping their paths relative to the chart. It returns the chart's
+// directory in the checkout and the temporary directory, which the caller
+// must remove. The working tree, its index and its worktrees are left
+// untouched.
+func CheckoutChartRevision(dir, ref string) (string, string, error) {
+	root, err := gitOutput(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", "", fmt.Errorf("%s is not in a git repository: %v", dir, err)
+	}
+	prefix, err := gitOutput(dir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return "", "", err
+	}
+	commit, err := gitOutput(dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return "", "", fmt.Errorf("unknown git revision %q", ref)
+	}
+
+	chart := path.Clean("./" + prefix)
+	if _, err := gitOutput(root, "cat-file", "-e", commit+":"+path.Join(chart, "Chart.yaml")); err != nil {
+		return "", "", fmt.Errorf("%s has no Chart.yaml at %s", dir, ref)
+	}
+	paths, err := localDependencyPaths(root, commit, chart)
+	if err != nil {
+		return "", "", err
+	}
+
+	tempDir, err := os.MkdirTemp("", "chartscan-revision")
+	if err != nil {
+		return "", "", fmt.Errorf("error creating temp dir: %v", err)
+	}
+	archive := filepath.Join(tempDir, "revision.tar.gz")
+	args := append([]string{"archive", "--format=tar.gz", "--output", archive, commit, "--"}, paths...)
+	if output, err := exec.Command("git", append([]string{"-C", root}, args...)...).CombinedOutput(); err != nil {
+		os.RemoveAll(tempDir)
+		return "", "", fmt.Errorf("error running git archive: %v\n%s", err, output)
+	}
+	checkout := filepath.Join(tempDir, "checkout")
+	if err := extractTarGz(archive, checkout); err != nil {
+		os.RemoveAll(tempDir)
+		return "", "", fmt.Errorf("error extracting %s at %s: %v", dir, ref, err)
+	}
+	return filepath.Join(checkout, filepath.FromSlash(chart)), tempDir, nil
+}
+
+// localDependencyPaths returns chart, a path relative to the repository root,
+// and the paths of the file:// dependencies it and its dependencies declare
+// at commit. Dependencies outside the repository are rejected.
+func localDependencyPaths(root, commit, chart string) ([]string, error) {
+	paths := []string{chart}
+	seen := map[string]bool{chart: true}
+	for i := 0; i < len(paths); i++ {
+		content, err := gitOutput(root, "show", commit+":"+path.Join(paths[i], "Chart.yaml"))
+		if err != nil {
+			// A missing dependency is reported by helm when rendering.
+			continue
+		}
+		var metadata struct {
+			Dependencies []struct {
+				Repository string `yaml:"repository"`
+			} `yaml:"dependencies"`
+		}
+		if err := yaml.Unmarshal([]byte(content), &metadata); err != nil {
+			return nil, fmt.Errorf("error parsing %s/Chart.yaml at %s: %v", paths[i], commit, err)
+		}
+		for _, dependency := range metadata.Dependencies {
+			if !strings.HasPrefix(dependency.Repository, "file://") {
+				continue
+			}
+			relative := strings.TrimPrefix(dependency.Repository, "file://")
+			dependencyPath := path.Join(paths[i], relative)
+			if path.IsAbs(relative) || dependencyPath == ".." || strings.HasPrefix(dependencyPath, "../") {
+				return nil, fmt.Errorf("dependency %s of %s is outside the git repository", dependency.Repository, paths[i])
+			}
+			if !seen[dependencyPath] {
+				seen[dependencyPath] = true
+				paths = append(paths, dependencyPath)
+			}
+		}
+	}
+	return paths, nil
+}
+
+// gitOutput runs git in dir and returns its trimmed standard output.
+func gitOutput(dir string, args ...string) (string, error) {
+	output, err := exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("%v: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,64 @@
+package finder
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckoutChartRevision(t *testing.T) {
+	repo := initGitRepo(t)
+	os.MkdirAll(filepath.Join(repo, "charts", "common"), 0755)
+	os.WriteFile(filepath.Join(repo, "charts", "common", "Chart.yaml"), []byte("name: common\n"), 0644)
+	os.WriteFile(filepath.Join(repo, "charts", "foo", "Chart.yaml"), []byte("name: foo\ndependencies:\n  - name: common\n    repository: file://../common\n"), 0644)
+	for _, args := range [][]string{
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "use common"},
+	} {
+		if output, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("Unexpected error running git %v: %v\n%s", args, err, output)
+		}
+	}
+	// Uncommitted changes must not show up in a revision.
+	os.WriteFile(filepath.Join(repo, "charts", "foo", "values.yaml"), []byte("replicas: 2\n"), 0644)
+
+	chartDir := filepath.Join(repo, "charts", "foo")
+	dir, tempDir, err := CheckoutChartRevision(chartDir, "v1.0.0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	content, err := os.ReadFile(filepath.Join(dir, "Chart.yaml"))
+	if err != nil || string(content) != "name: foo\n" {
+		t.Errorf("Expected the Chart.yaml of v1.0.0, got '%s' (%v)", content, err)
+	}
+	for _, unrelated := range []string{"../bar", "../common", "../../other", "values.yaml"} {
+		if _, err := os.Stat(filepath.Join(dir, unrelated)); !os.IsNotExist(err) {
+			t.Errorf("Expected only the chart to be extracted, found %s", unrelated)
+		}
+	}
+
+	dir, tempDir, err = CheckoutChartRevision(chartDir, "HEAD")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	if _, err := os.Stat(filepath.Join(dir, "..", "common", "Chart.yaml")); err != nil {
+		t.Errorf("Expected the file:// dependency to be extracted, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "values.yaml")); !os.IsNotExist(err) {
+		t.Errorf("Expected uncommitted files not to be extracted")
+	}
+
+	if _, _, err := CheckoutChartRevision(chartDir, "v9.9.9"); err == nil {
+		t.Errorf("Expected an error for an unknown revision")
+	}
+	os.MkdirAll(filepath.Join(repo, "charts", "new"), 0755)
+	if _, _, err := CheckoutChartRevision(filepath.Join(repo, "charts", "new"), "HEAD"); err == nil {
+		t.Errorf("Expected an error for a chart missing at the revision")
+	}
+	if _, _, err := CheckoutChartRevision(t.TempDir(), "HEAD"); err == nil {
+		t.Errorf("Expected an error outside a git repository")
+	}
+}
@@ -0,0 +1,205 @@
+// Package gate decides whether a scan report passes a gate policy, for
+// `chartscan gate`. Scanning once and gating separately lets one report feed
+// several pipeline decisions, e.g. a strict gate for production releases and
+// a lenient one for previews.
+package gate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Policy is what a report must meet to pass the gate. Unset fields do not
+// restrict the report.
+type Policy struct {
+	// MaxSeverity is the highest severity findings may have: info, warning
+	// or error.
+	MaxSeverity string `yaml:"maxSeverity"`
+	// RequiredRules are rules, or scan stages such as undefined-value, that
+	// must pass: the scan ran them and no chart has an error finding of them.
+	RequiredRules []string `yaml:"requiredRules"`
+	Budgets       Budgets  `yaml:"budgets"`
+}
+
+// Budgets cap the number of findings and charts that did not pass, across
+// all charts and subcharts of the report.
+type Budgets struct {
+	// Severities caps the findings of each severity.
+	Severities map[string]int `yaml:"severities"`
+	// Rules caps the findings of each rule, by ID.
+	Rules            map[string]int `yaml:"rules"`
+	FailedCharts     *int           `yaml:"failedCharts"`
+	IncompleteCharts *int           `yaml:"incompleteCharts"`
+}
+
+// Violation is a requirement of the policy the report does not meet.
+type Violation struct {
+	// Check is the part of the policy violated: max-severity,
+	// required-rule or budget.
+	Check   string
+	Message string
+}
+
+// LoadPolicy reads and validates the policy at path. Unknown keys are
+// rejected, so a misspelled budget does not silently allow everything.
+func LoadPolicy(path string) (Policy, error) {
+	var policy Policy
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return policy, err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&policy); err != nil && !errors.Is(err, io.EOF) {
+		return policy, fmt.Errorf("error parsing %s: %v", path, err)
+	}
+	if err := policy.validate(); err != nil {
+		return policy, fmt.Errorf("invalid policy %s: %v", path, err)
+	}
+	return policy, nil
+}
+
+// validate rejects unknown severities and negative budgets.
+func (p Policy) validate() error {
+	if p.MaxSeverity != "" && models.SeverityRank(p.MaxSeverity) < 0 {
+		return fmt.Errorf("unknown maxSeverity %q (expected one of %s)", p.MaxSeverity, strings.Join(models.Severities, ", "))
+	}
+	for severity, budget := range p.Budgets.Severities {
+		if models.SeverityRank(severity) < 0 {
+			return fmt.Errorf("unknown severity %q in budgets (expected one of %s)", severity, strings.Join(models.Severities, ", "))
+		}
+		if budget < 0 {
+			return fmt.Errorf("negative budget for %s findings", severity)
+		}
+	}
+	for ruleID, budget := range p.Budgets.Rules {
+		if budget < 0 {
+			return fmt.Errorf("negative budget for %s findings", ruleID)
+		}
+	}
+	if budget := p.Budgets.FailedCharts; budget != nil && *budget < 0 {
+		return fmt.Errorf("negative budget for failed charts")
+	}
+	if budget := p.Budgets.IncompleteCharts; budget != nil && *budget < 0 {
+		return fmt.Errorf("negative budget for incomplete charts")
+	}
+	return nil
+}
+
+// LoadReport reads a report written by `chartscan scan -o json`, or by
+// -o yaml when path ends in .yaml or .yml.
+func LoadReport(path string) (models.Report, error) {
+	var report models.Report
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return report, err
+	}
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &report)
+	default:
+		err = json.Unmarshal(data, &report)
+	}
+	if err != nil {
+		return report, fmt.Errorf("error parsing report %s: %v", path, err)
+	}
+	return report, nil
+}
+
+// Evaluate returns the violations of policy by report, in the order of the
+// policy: the maximum severity, the required rules, then the budgets.
+func Evaluate(report models.Report, policy Policy) []Violation {
+	results := models.FlattenResults(report.Results)
+	bySeverity := make(map[string]int)
+	byRule := make(map[string]int)
+	failedRules := make(map[string][]string)
+	failed, incomplete := 0, 0
+	for _, result := range results {
+		if !result.Success {
+			failed++
+		}
+		if result.Incomplete {
+			incomplete++
+		}
+		for _, finding := range result.Findings {
+			bySeverity[finding.Severity]++
+			byRule[finding.RuleID]++
+			chart := result.ChartPath
+			if result.Environment != "" {
+				chart += " (" + result.Environment + ")"
+			}
+			if finding.Severity == models.SeverityError && !slices.Contains(failedRules[finding.RuleID], chart) {
+				failedRules[finding.RuleID] = append(failedRules[finding.RuleID], chart)
+			}
+		}
+	}
+	// Findings of the whole run count once, not once per chart.
+	for _, finding := range report.Findings {
+		bySeverity[finding.Severity]++
+		byRule[finding.RuleID]++
+		if finding.Severity == models.SeverityError && !slices.Contains(failedRules[finding.RuleID], "the run") {
+			failedRules[finding.RuleID] = append(failedRules[finding.RuleID], "the run")
+		}
+	}
+
+	var violations []Violation
+	if policy.MaxSeverity != "" {
+		maxRank := models.SeverityRank(policy.MaxSeverity)
+		for _, severity := range models.Severities {
+			if models.SeverityRank(severity) > maxRank && bySeverity[severity] > 0 {
+				violations = append(violations, Violation{
+					Check:   "max-severity",
+					Message: fmt.Sprintf("%d %s findings, but the highest severity allowed is %s", bySeverity[severity], severity, policy.MaxSeverity),
+				})
+			}
+		}
+	}
+
+	for _, ruleID := range policy.RequiredRules {
+		if !slices.Contains(report.Rules, ruleID) {
+			// A rule without findings only passed if it ran.
+			violations = append(violations, Violation{
+				Check:   "required-rule",
+				Message: fmt.Sprintf("required rule %s did not run: the report has no record of it", ruleID),
+			})
+		} else if charts := failedRules[ruleID]; len(charts) > 0 {
+			violations = append(violations, Violation{
+				Check:   "required-rule",
+				Message: fmt.Sprintf("required rule %s failed in %s", ruleID, strings.Join(charts, ", ")),
+			})
+		}
+	}
+
+	for _, severity := range slices.Sorted(maps.Keys(policy.Budgets.Severities)) {
+		if count, budget := bySeverity[severity], policy.Budgets.Severities[severity]; count > budget {
+			violations = append(violations, budgetViolation(fmt.Sprintf("%s findings", severity), count, budget))
+		}
+	}
+	for _, ruleID := range slices.Sorted(maps.Keys(policy.Budgets.Rules)) {
+		if count, budget := byRule[ruleID], policy.Budgets.Rules[ruleID]; count > budget {
+			violations = append(violations, budgetViolation(fmt.Sprintf("%s findings", ruleID), count, budget))
+		}
+	}
+	if budget := policy.Budgets.FailedCharts; budget != nil && failed > *budget {
+		violations = append(violations, budgetViolation("failed charts", failed, *budget))
+	}
+	if budget := policy.Budgets.IncompleteCharts; budget != nil && incomplete > *budget {
+		violations = append(violations, budgetViolation("incomplete charts", incomplete, *budget))
+	}
+	return violations
+}
+
+func budgetViolation(what string, count, budget int) Violation {
+	return Violation{Check: "budget", Message: fmt.Sprintf("%d %s, but the budget is %d", count, what, budget)}
+}
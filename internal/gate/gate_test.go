@@ -0,0 +1,146 @@
+package gate
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+func testReport() models.Report {
+	return models.Report{Rules: []string{"helm-lint", "image-pinning", "undefined-value"}, Results: []models.Result{
+		{ChartPath: "charts/web", Success: true, Findings: []models.Finding{
+			{RuleID: "image-pinning", Severity: models.SeverityWarning},
+			{RuleID: "image-pinning", Severity: models.SeverityWarning},
+		}},
+		{ChartPath: "charts/api", Findings: []models.Finding{
+			{RuleID: "undefined-value", Severity: models.SeverityError},
+		}, Dependencies: []models.Result{
+			{ChartPath: "charts/api/charts/redis", Findings: []models.Finding{
+				{RuleID: "undefined-value", Severity: models.SeverityError},
+			}},
+		}},
+	}}
+}
+
+func TestEvaluate(t *testing.T) {
+	one, zero := 1, 0
+	policy := Policy{
+		MaxSeverity:   models.SeverityWarning,
+		RequiredRules: []string{"undefined-value", "schema-validation", "helm-lint"},
+		Budgets: Budgets{
+			Severities:       map[string]int{models.SeverityWarning: 1, models.SeverityError: 5},
+			Rules:            map[string]int{"image-pinning": 2},
+			FailedCharts:     &one,
+			IncompleteCharts: &zero,
+		},
+	}
+
+	var checks, messages []string
+	for _, violation := range Evaluate(testReport(), policy) {
+		checks = append(checks, violation.Check)
+		messages = append(messages, violation.Message)
+	}
+	if expected := []string{"max-severity", "required-rule", "required-rule", "budget", "budget"}; !reflect.DeepEqual(checks, expected) {
+		t.Fatalf("Expected violations %v, got %v", expected, messages)
+	}
+	expected := []string{
+		"2 error findings, but the highest severity allowed is warning",
+		"required rule undefined-value failed in charts/api, charts/api/charts/redis",
+		"required rule schema-validation did not run: the report has no record of it",
+		"2 warning findings, but the budget is 1",
+		"2 failed charts, but the budget is 1",
+	}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Errorf("Expected %q, got %q", expected, messages)
+	}
+
+	if violations := Evaluate(testReport(), Policy{}); len(violations) != 0 {
+		t.Errorf("Expected an empty policy to pass, got %v", violations)
+	}
+}
+
+func TestEvaluateRunFindings(t *testing.T) {
+	report := testReport()
+	report.Findings = []models.Finding{{RuleID: "unused-values-file", Severity: models.SeverityWarning, File: "values-prod.yaml"}}
+	policy := Policy{Budgets: Budgets{Rules: map[string]int{"unused-values-file": 0}}}
+
+	violations := Evaluate(report, policy)
+	if len(violations) != 1 || violations[0].Message != "1 unused-values-file findings, but the budget is 0" {
+		t.Errorf("Expected the run finding to count once, got %v", violations)
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	policy, err := LoadPolicy(write("gate.yaml", "maxSeverity: warning\nrequiredRules: [helm-lint]\nbudgets:\n  severities:\n    warning: 10\n  failedCharts: 0\n"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if policy.MaxSeverity != "warning" || policy.Budgets.Severities["warning"] != 10 || policy.Budgets.FailedCharts == nil || *policy.Budgets.FailedCharts != 0 {
+		t.Errorf("Expected the policy of the file, got %+v", policy)
+	}
+
+	if _, err := LoadPolicy(write("empty.yaml", "")); err != nil {
+		t.Errorf("Expected an empty policy to be valid, got %v", err)
+	}
+
+	for content, expected := range map[string]string{
+		"maxSeverity: critical\n":                 "unknown maxSeverity",
+		"budgets:\n  severities:\n    fatal: 1\n": "unknown severity",
+		"budgets:\n  rules:\n    helm-lint: -1\n": "negative budget",
+		"budgets:\n  warnings: 10\n":              "field warnings not found",
+	} {
+		if _, err := LoadPolicy(write("invalid.yaml", content)); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected an error containing %q for %q, got %v", expected, content, err)
+		}
+	}
+}
+
+func TestLoadReport(t *testing.T) {
+	dir := t.TempDir()
+	report := testReport()
+	jsonData, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("Failed to encode the report: %v", err)
+	}
+	yamlData, err := yaml.Marshal(report)
+	if err != nil {
+		t.Fatalf("Failed to encode the report: %v", err)
+	}
+
+	for name, data := range map[string][]byte{"results.json": jsonData, "results.yaml": yamlData} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		loaded, err := LoadReport(path)
+		if err != nil {
+			t.Fatalf("Expected no error for %s, got %v", name, err)
+		}
+		if len(loaded.Results) != 2 || len(loaded.Results[1].Dependencies) != 1 || loaded.Results[0].Findings[0].RuleID != "image-pinning" {
+			t.Errorf("Expected the results of %s, got %+v", name, loaded.Results)
+		}
+	}
+
+	path := filepath.Join(dir, "legacy.json")
+	if err := os.WriteFile(path, []byte("[]"), 0644); err != nil {
+		t.Fatalf("Failed to write legacy.json: %v", err)
+	}
+	if _, err := LoadReport(path); err == nil {
+		t.Errorf("Expected an error for a file that is not a report")
+	}
+}
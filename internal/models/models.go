@@ -1,31 +1,660 @@
 package models
 
-import "encoding/xml"
+import (
+	"encoding/xml"
+	"fmt"
+)
 
 type Result struct {
-	ChartPath       string                 `json:"ChartPath"`
-	Success         bool                   `json:"Success"`
-	Errors          []string               `json:"Errors,omitempty"`
-	UndefinedValues []string               `json:"UndefinedValues,omitempty"`
+	ChartPath string `json:"ChartPath"`
+	// Environment is the environment of chartscan.yaml the chart was scanned
+	// for when a scan covers several environments.
+	Environment string `json:"Environment,omitempty"`
+	Success     bool   `json:"Success"`
+	// Findings holds every problem of the chart: lint, render and validation
+	// errors, undefined values and rule findings.
+	Findings []Finding `json:"Findings,omitempty"`
+	// SuppressedFindings were acknowledged by chartscan:ignore comments in
+	// templates. They do not fail the chart.
+	SuppressedFindings []Finding `json:"SuppressedFindings,omitempty"`
+	// SkippedFindings were reported for resources that opt out of their rule
+	// with the chartscan.io/skip annotation. They do not fail the chart.
+	SkippedFindings []Finding              `json:"SkippedFindings,omitempty"`
 	Values          map[string]interface{} `json:"Values,omitempty"`
+	// ToolError is set when chartscan itself failed on the chart, e.g. by a
+	// panic, rather than the chart having problems.
+	ToolError string `json:"ToolError,omitempty"`
+	// DebugLog holds the scan stages and helm output of charts selected with
+	// --debug-chart.
+	DebugLog []string `json:"DebugLog,omitempty"`
+	// DurationSeconds is how long the chart took to scan. Subcharts are
+	// scanned as part of their parent and have no duration of their own.
+	DurationSeconds float64 `json:"DurationSeconds,omitempty"`
+	// Dependencies holds the results of the chart's subcharts when they are
+	// scanned too. Findings in subchart templates are reported there.
+	Dependencies []Result `json:"Dependencies,omitempty"`
+	// Incomplete marks a chart whose scan had not finished when the run was
+	// interrupted or timed out. Its only finding says so.
+	Incomplete bool `json:"Incomplete,omitempty"`
+}
+
+// FlattenResults returns results with the results of their dependencies
+// following each chart, depth first. The returned results have no
+// Dependencies themselves.
+func FlattenResults(results []Result) []Result {
+	var flat []Result
+	for _, result := range results {
+		dependencies := result.Dependencies
+		result.Dependencies = nil
+		flat = append(flat, result)
+		flat = append(flat, FlattenResults(dependencies)...)
+	}
+	return flat
+}
+
+// Severity levels of a Finding. Only error findings mark a chart as failed.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// Severities lists the severity levels from least to most severe.
+var Severities = []string{SeverityInfo, SeverityWarning, SeverityError}
+
+// SeverityRank orders severities from info (0) to error (2). Unknown
+// severities rank as -1.
+func SeverityRank(severity string) int {
+	for i, s := range Severities {
+		if s == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+// Finding is a single problem of a chart, reported by a rule or by a scan
+// stage such as helm lint, and located in a template file and rendered
+// resource where known.
+type Finding struct {
+	RuleID   string `json:"RuleID"`
+	Severity string `json:"Severity"`
+	Message  string `json:"Message"`
+	Resource string `json:"Resource,omitempty"`
+	File     string `json:"File,omitempty"`
+	Line     int    `json:"Line,omitempty"`
+	Blame    *Blame `json:"Blame,omitempty"`
+}
+
+// Blame identifies the commit that last changed the source of a problem.
+type Blame struct {
+	Author string `json:"Author"`
+	Commit string `json:"Commit"`
+	Date   string `json:"Date"`
+}
+
+// String formats the blame as "<author> in <short commit> on <date>".
+func (b Blame) String() string {
+	commit := b.Commit
+	if len(commit) > 8 {
+		commit = commit[:8]
+	}
+	return fmt.Sprintf("%s in %s on %s", b.Author, commit, b.Date)
+}
+
+// String formats the finding for human-readable output. Findings on a
+// resource name the template it was rendered from.
+func (f Finding) String() string {
+	location := f.Resource
+	if location != "" && f.File != "" {
+		location += " (" + f.File + ")"
+	}
+	if location == "" {
+		location = f.File
+		if location != "" && f.Line > 0 {
+			location = fmt.Sprintf("%s:%d", f.File, f.Line)
+		}
+	}
+	message := f.Message
+	if f.Blame != nil {
+		message += " (last changed by " + f.Blame.String() + ")"
+	}
+	if location == "" {
+		return fmt.Sprintf("[%s] %s: %s", f.Severity, f.RuleID, message)
+	}
+	return fmt.Sprintf("[%s] %s: %s: %s", f.Severity, f.RuleID, location, message)
+}
+
+// Report is the json and yaml output of a scan: the results with the totals
+// of the run, the ChartScan version and the configuration the charts were
+// scanned with.
+type Report struct {
+	Version         string        `json:"Version"`
+	DurationSeconds float64       `json:"DurationSeconds"`
+	Summary         ReportSummary `json:"Summary"`
+	// Experiments are the experimental checks the scan ran with.
+	Experiments []string `json:"Experiments,omitempty"`
+	// Rules are the IDs of the rules and scan stages the scan ran, so that a
+	// rule without findings can be told from one that did not run.
+	Rules   []string `json:"Rules,omitempty"`
+	Config  Config   `json:"Config"`
+	Results []Result `json:"Results"`
+	// Findings are about the run as a whole rather than a chart, such as
+	// values files no chart uses.
+	Findings []Finding `json:"Findings,omitempty"`
+}
+
+// ReportSummary holds the totals of a scan. Charts counts subcharts scanned
+// with their parent as well; Incomplete charts are counted as failed.
+type ReportSummary struct {
+	Charts             int            `json:"Charts"`
+	Passed             int            `json:"Passed"`
+	Failed             int            `json:"Failed"`
+	Incomplete         int            `json:"Incomplete,omitempty"`
+	FindingsBySeverity map[string]int `json:"FindingsBySeverity"`
+}
+
+// Statistics summarizes the findings of a scan so teams can see where to
+// focus cleanup. FindingsByRule and ChartsByFindings are ordered by count,
+// highest first.
+type Statistics struct {
+	FindingsBySeverity map[string]int `json:"FindingsBySeverity,omitempty"`
+	FindingsByRule     []RuleCount    `json:"FindingsByRule,omitempty"`
+	ChartsByFindings   []ChartCount   `json:"ChartsByFindings,omitempty"`
+	// Skipped is the number of findings skipped by chartscan.io/skip
+	// annotations.
+	Skipped int `json:"Skipped,omitempty"`
+	// UndefinedValues groups the undefined values of all charts by key,
+	// ordered by the number of charts referencing them, highest first.
+	UndefinedValues []UndefinedValue `json:"UndefinedValues,omitempty"`
+}
+
+// RuleCount is the number of findings reported by a rule.
+type RuleCount struct {
+	RuleID string `json:"RuleID"`
+	Count  int    `json:"Count"`
+}
+
+// ChartCount is the number of findings reported for a chart.
+type ChartCount struct {
+	ChartPath string `json:"ChartPath"`
+	Count     int    `json:"Count"`
+}
+
+// UndefinedValue is a value key that templates reference without any values
+// file defining it, with every template line referencing it. Keys copied
+// across many charts usually point to a template bug copied with them.
+type UndefinedValue struct {
+	Key string `json:"Key"`
+	// Charts are the paths of the charts referencing the key, sorted.
+	Charts     []string         `json:"Charts"`
+	References []ValueReference `json:"References"`
 }
 
 type ValueReference struct {
-	Name     string `json:"Name"`
-	File     string `json:"File"`
+	Name     string   `json:"Name"`
+	Path     []string `json:"Path,omitempty"`
+	File     string   `json:"File"`
+	Line     int      `json:"Line"`
+	FullText string   `json:"FullText"`
+	// Optional marks references that carry their own fallback (dig, pluck)
+	// and therefore never render as missing.
+	Optional bool `json:"Optional,omitempty"`
+	// Guards lists the value paths checked with hasKey by enclosing if blocks.
+	// The reference is only evaluated when every guard path exists.
+	Guards [][]string `json:"Guards,omitempty"`
+	// IncludedFrom is the file:line of the include or template call through
+	// which a reference in a named template is rendered.
+	IncludedFrom string `json:"IncludedFrom,omitempty"`
+	// Expects is the kind of value the template needs, inferred from its use:
+	// ExpectsList for values ranged over and ExpectsBool for if conditions.
+	Expects string `json:"Expects,omitempty"`
+}
+
+// Kinds of values a ValueReference expects. Accessing keys below a value
+// expects a map, which its Path already tells.
+const (
+	ExpectsList = "list"
+	ExpectsBool = "bool"
+)
+
+// ValueUsage is a use of a values key by one of a chart's templates, as
+// reported by `chartscan graph values`.
+type ValueUsage struct {
+	// Key is the dotted path of the value, e.g. image.tag.
+	Key string `json:"Key"`
+	// Template is the template file using the value, relative to the chart,
+	// and Line the line of the use or of the call leading to it.
+	Template string `json:"Template"`
 	Line     int    `json:"Line"`
-	FullText string `json:"FullText"`
+	// Helpers are the named templates through which the template uses the
+	// value, outermost first. It is empty for direct uses.
+	Helpers []string `json:"Helpers,omitempty"`
+}
+
+// DependencyGraph is the graph of the dependencies of charts, as reported by
+// `chartscan deps`.
+type DependencyGraph struct {
+	Charts []ChartNode `json:"Charts"`
+	// Cycles are the dependency cycles between local charts, each listed by
+	// chart path from its first chart back to that chart.
+	Cycles [][]string `json:"Cycles,omitempty"`
+}
+
+// ChartNode is a chart of a DependencyGraph with the dependencies its
+// Chart.yaml declares.
+type ChartNode struct {
+	Path         string            `json:"Path"`
+	Name         string            `json:"Name"`
+	Version      string            `json:"Version,omitempty"`
+	Dependencies []ChartDependency `json:"Dependencies,omitempty"`
+	// Error is set when the chart's Chart.yaml cannot be read.
+	Error string `json:"Error,omitempty"`
+}
+
+// ChartDependency is a dependency declared in Chart.yaml.
+type ChartDependency struct {
+	Name       string `json:"Name"`
+	Version    string `json:"Version,omitempty"`
+	Repository string `json:"Repository,omitempty"`
+	Alias      string `json:"Alias,omitempty"`
+	// Path is the chart directory a file:// dependency resolves to, in the
+	// form of the Path of its ChartNode when it is one of the graph's charts.
+	// It is empty for dependencies from repositories and registries.
+	Path string `json:"Path,omitempty"`
 }
 
 type EnvironmentConfig struct {
 	ValuesFiles []string `yaml:"valuesFiles"`
+	// QoS overrides SchedulingConfig.QoS when the environment is selected.
+	QoS map[string]string `yaml:"qos"`
+	// PodSecurityLevel overrides PodSecurityConfig.Level when the environment
+	// is selected.
+	PodSecurityLevel string `yaml:"podSecurityLevel"`
+	// Production marks the environment as production for the rollout rules.
+	Production bool `yaml:"production"`
+	// PostRenderer replaces Config.PostRenderer when the environment is
+	// selected, e.g. to apply the environment's kustomize overlay.
+	PostRenderer *PostRendererConfig `yaml:"postRenderer"`
+}
+
+// ReferencePattern declares an additional placeholder syntax used in
+// templates (e.g. ${VAR} for envsubst passes). Pattern must contain exactly
+// one capture group that yields the referenced name, which is looked up in
+// Source: "values" (merged chart values), "env" (process environment) or
+// "file" (the YAML or KEY=VALUE files listed in Files).
+type ReferencePattern struct {
+	Name    string   `yaml:"name"`
+	Pattern string   `yaml:"pattern"`
+	Source  string   `yaml:"source"`
+	Files   []string `yaml:"files"`
+}
+
+// GitOpsConfig enables the analyzer for rendered fields that cause perpetual
+// diffs in GitOps tools. Tool selects the suggestion format: "argocd" or "flux".
+type GitOpsConfig struct {
+	Tool string `yaml:"tool"`
+}
+
+// ResourceReferencesConfig enables the check that Secrets and ConfigMaps
+// referenced by pod specs are rendered by the chart. Names listed as external
+// (shell patterns allowed) are managed outside the chart and always accepted.
+type ResourceReferencesConfig struct {
+	Enabled            bool     `yaml:"enabled"`
+	ExternalSecrets    []string `yaml:"externalSecrets"`
+	ExternalConfigMaps []string `yaml:"externalConfigMaps"`
+}
+
+// MonitoringConfig enables the monitoring rule pack for Prometheus operator
+// resources. RequiredLabels must be present on every ServiceMonitor, PodMonitor
+// and PrometheusRule so the cluster's Prometheus selects them; an empty value
+// accepts any value for the label.
+type MonitoringConfig struct {
+	Enabled        bool              `yaml:"enabled"`
+	RequiredLabels map[string]string `yaml:"requiredLabels"`
+}
+
+// ServiceMeshConfig enables the Istio and Gateway API rule pack. ExternalHosts
+// lists route destinations that are not rendered by the chart and
+// ExternalSecrets lists TLS secrets managed outside of it; both accept shell
+// patterns.
+type ServiceMeshConfig struct {
+	Enabled         bool     `yaml:"enabled"`
+	ExternalHosts   []string `yaml:"externalHosts"`
+	ExternalSecrets []string `yaml:"externalSecrets"`
+}
+
+// DNSConfig enables the external-dns and cert-manager annotation checks.
+// AllowedDomains restricts published and certified hosts to these domains and
+// their subdomains; AllowedIssuers restricts the cert-manager issuers charts
+// may reference. Empty lists allow everything.
+type DNSConfig struct {
+	Enabled        bool     `yaml:"enabled"`
+	AllowedDomains []string `yaml:"allowedDomains"`
+	AllowedIssuers []string `yaml:"allowedIssuers"`
+}
+
+// SchedulingConfig enables the priority class and QoS rules.
+// PriorityClasses lists the priorityClassName values workloads must choose
+// from. QoS maps a tier, read from the TierLabel label of the pod template
+// ("tier" by default), to the required QoS class (Guaranteed, Burstable or
+// BestEffort); the tier "*" applies to workloads without a more specific
+// entry.
+type SchedulingConfig struct {
+	Enabled         bool              `yaml:"enabled"`
+	PriorityClasses []string          `yaml:"priorityClasses"`
+	TierLabel       string            `yaml:"tierLabel"`
+	QoS             map[string]string `yaml:"qos"`
+}
+
+// PodSecurityConfig enables evaluating workloads against the Kubernetes Pod
+// Security Standards. Level is the profile every workload must satisfy:
+// privileged, baseline (the default) or restricted.
+type PodSecurityConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Level   string `yaml:"level"`
+}
+
+// ImagesConfig configures checks of the container images referenced by
+// workloads. Architectures lists the platforms every image must be published
+// for, as architecture (arm64), os/architecture (linux/arm64) or with variant
+// (arm/v7); it is verified against the registry's manifest list. Pinning
+// selects how strictly image references must be pinned: "digest", "semver"
+// (full x.y.z tags) or "no-latest". Images from registries matching
+// PinningExceptions are exempt.
+type ImagesConfig struct {
+	Architectures     []string `yaml:"architectures"`
+	Pinning           string   `yaml:"pinning"`
+	PinningExceptions []string `yaml:"pinningExceptions"`
+}
+
+// ValidationConfig enables validating rendered manifests against Kubernetes
+// JSON Schemas for KubeVersion (e.g. 1.29). SchemaLocations are kubeconform
+// style templates for URLs or file paths; "default" is the upstream
+// Kubernetes schema repository at SchemaVersion, a commit, branch or tag that
+// defaults to master. CRDSchemas is a file or directory of CRD manifests, or a
+// kubeconfig context, whose CRDs validate the custom resources they define.
+// Kinds without a schema are errors unless IgnoreMissingSchemas is set, and
+// kinds listed in SkipKinds are not validated.
+type ValidationConfig struct {
+	KubeVersion          string   `yaml:"kubeVersion"`
+	SchemaLocations      []string `yaml:"schemaLocations"`
+	SchemaVersion        string   `yaml:"schemaVersion"`
+	CRDSchemas           string   `yaml:"crdSchemas"`
+	IgnoreMissingSchemas bool     `yaml:"ignoreMissingSchemas"`
+	SkipKinds            []string `yaml:"skipKinds"`
+}
+
+// ValuesSchemaConfig enables auditing charts' values.schema.json files.
+// Referenced keys must always be declared; ForbidAdditionalProperties and
+// RequireTypes add the corresponding strictness checks.
+type ValuesSchemaConfig struct {
+	Enabled                    bool `yaml:"enabled"`
+	ForbidAdditionalProperties bool `yaml:"forbidAdditionalProperties"`
+	RequireTypes               bool `yaml:"requireTypes"`
+}
+
+// ValuesFormatConfig enables the values-format rule, which keeps the keys of
+// each chart's own values.yaml in order and consistently indented, and lets
+// `chartscan fix` rewrite the file. Order is alphabetical (default) or schema,
+// the order of the properties in values.schema.json; Indent defaults to 2.
+type ValuesFormatConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Order   string `yaml:"order"`
+	Indent  int    `yaml:"indent"`
+}
+
+// DuplicateValuesConfig enables the duplicate-values rule, which keeps the
+// values files of the environments minimal: it flags keys repeating the chart
+// default or an earlier file, and keys every environment sets to the same
+// value.
+type DuplicateValuesConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// LintConfig controls the helm lint stage of a scan. Strict, true unless set
+// to false, passes --strict to helm lint, which renders templates strictly and
+// fails the lint on warnings. Messages change the severity of matching lint
+// messages or drop them; the first matching entry applies.
+type LintConfig struct {
+	Strict   *bool         `yaml:"strict"`
+	Messages []LintMessage `yaml:"messages"`
+}
+
+// LintMessage matches helm lint messages, without their level and file, by
+// the regular expression Pattern. Severity is info, warning or error, or
+// ignore to drop the messages.
+type LintMessage struct {
+	Pattern  string `yaml:"pattern"`
+	Severity string `yaml:"severity"`
+}
+
+// ChartMetadataConfig enables the rules for a chart's Chart.yaml.
+// RequireKubeVersion also flags charts without a kubeVersion constraint, and
+// CheckIcon fetches icon URLs to flag those that cannot be reached.
+type ChartMetadataConfig struct {
+	Enabled            bool `yaml:"enabled"`
+	RequireKubeVersion bool `yaml:"requireKubeVersion"`
+	CheckIcon          bool `yaml:"checkIcon"`
+}
+
+// SecretsConfig enables the ExternalSecret and SealedSecret consistency rules.
+// RequireExternal forbids Secrets that render their data inline, except those
+// whose names match AllowedSecrets (shell patterns).
+type SecretsConfig struct {
+	Enabled         bool     `yaml:"enabled"`
+	RequireExternal bool     `yaml:"requireExternal"`
+	AllowedSecrets  []string `yaml:"allowedSecrets"`
+}
+
+// SecretScanningConfig enables the hard-coded credential rules, which flag
+// likely credentials in the chart's values.yaml, the configured values files
+// and the data templates write into Secrets. Known token formats are errors;
+// values of password-like keys and random-looking strings whose Shannon
+// entropy reaches MinEntropy bits per character (4.5 by default) are
+// warnings. Allow lists regular expressions matched against the dotted key
+// and the value; matching values are never reported.
+type SecretScanningConfig struct {
+	Enabled    bool     `yaml:"enabled"`
+	MinEntropy float64  `yaml:"minEntropy"`
+	Allow      []string `yaml:"allow"`
+}
+
+// ContainersConfig enables the init and sidecar container rules: init
+// containers must meet the security and resource settings all main containers
+// share, charts must not ship their own service mesh proxies, and native
+// sidecars require a Kubernetes version (validation.kubeVersion) that supports
+// them.
+type ContainersConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// PoliciesConfig evaluates Rego policies against the rendered manifests with
+// the embedded OPA. Dirs are loaded as with opa's --data flag; every manifest
+// is the input of the deny and warn rules of package Namespace ("main" by
+// default), as with conftest.
+type PoliciesConfig struct {
+	Dirs      []string `yaml:"dirs"`
+	Namespace string   `yaml:"namespace"`
+}
+
+// BestPracticesConfig enables the built-in best-practice rules: resource
+// limits, latest image tags, privileged containers, liveness probes and
+// deprecated API versions.
+type BestPracticesConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// RuleConfig overrides a single rule or scan stage, such as helm-lint or
+// undefined-value, keyed by its ID in Config.Rules. Enabled false turns any
+// rule off; true turns on a best-practice rule without the rest of the set.
+// Severity, info, warning or error, replaces the severity of the rule's
+// findings. Options are passed to the rule as is.
+type RuleConfig struct {
+	Enabled  *bool                  `yaml:"enabled"`
+	Severity string                 `yaml:"severity"`
+	Options  map[string]interface{} `yaml:"options"`
+}
+
+// JobsConfig enables the Job and CronJob rules. MinScheduleInterval is the
+// shortest time between CronJob runs accepted, as a Go duration such as 15m;
+// it defaults to 5m.
+type JobsConfig struct {
+	Enabled             bool   `yaml:"enabled"`
+	MinScheduleInterval string `yaml:"minScheduleInterval"`
+}
+
+// RolloutsConfig enables the rollout strategy rules. OnDeleteAnnotation is
+// the annotation that must justify StatefulSets using the OnDelete strategy
+// ("chartscan.io/on-delete-reason" by default). Production, usually set by
+// selecting an environment marked as production, additionally forbids
+// single-replica Deployments with the Recreate strategy.
+type RolloutsConfig struct {
+	Enabled            bool   `yaml:"enabled"`
+	OnDeleteAnnotation string `yaml:"onDeleteAnnotation"`
+	Production         bool   `yaml:"production"`
+}
+
+// PermutationsConfig renders charts once per combination of alternative
+// values, so bugs behind rarely used toggles are caught. Each entry of Charts
+// applies to the charts matching Chart, a path, glob or directory name. Limit
+// bounds the combinations rendered per chart and defaults to 32.
+type PermutationsConfig struct {
+	Limit  int                 `yaml:"limit"`
+	Charts []ChartPermutations `yaml:"charts"`
+}
+
+// ChartPermutations lists the alternatives of each value, by dotted key, to
+// render a chart with, e.g. ingress.enabled: [true, false].
+type ChartPermutations struct {
+	Chart  string                   `yaml:"chart"`
+	Values map[string][]interface{} `yaml:"values"`
+}
+
+// DeterminismConfig enables rendering every chart a second time and
+// reporting the resources whose manifests differ between the renderings,
+// which GitOps tools would show as out of sync after every sync.
+type DeterminismConfig struct {
+	Enabled bool `yaml:"enabled"`
 }
 
 type Config struct {
-	ChartPath    string                       `yaml:"chartPath"`
-	ValuesFiles  []string                     `yaml:"valuesFiles"`
-	Format       string                       `yaml:"format"`
-	Environments map[string]EnvironmentConfig `yaml:"environments"`
+	ChartPath          string                       `yaml:"chartPath"`
+	ValuesFiles        []string                     `yaml:"valuesFiles"`
+	Format             string                       `yaml:"format"`
+	Environments       map[string]EnvironmentConfig `yaml:"environments"`
+	ReferencePatterns  []ReferencePattern           `yaml:"referencePatterns"`
+	GitOps             GitOpsConfig                 `yaml:"gitops"`
+	ResourceReferences ResourceReferencesConfig     `yaml:"resourceReferences"`
+	Monitoring         MonitoringConfig             `yaml:"monitoring"`
+	ServiceMesh        ServiceMeshConfig            `yaml:"serviceMesh"`
+	Secrets            SecretsConfig                `yaml:"secrets"`
+	SecretScanning     SecretScanningConfig         `yaml:"secretScanning"`
+	DNS                DNSConfig                    `yaml:"dns"`
+	Scheduling         SchedulingConfig             `yaml:"scheduling"`
+	PodSecurity        PodSecurityConfig            `yaml:"podSecurity"`
+	Containers         ContainersConfig             `yaml:"containers"`
+	Jobs               JobsConfig                   `yaml:"jobs"`
+	Rollouts           RolloutsConfig               `yaml:"rollouts"`
+	Images             ImagesConfig                 `yaml:"images"`
+	Validation         ValidationConfig             `yaml:"validation"`
+	ValuesSchema       ValuesSchemaConfig           `yaml:"valuesSchema"`
+	ValuesFormat       ValuesFormatConfig           `yaml:"valuesFormat"`
+	DuplicateValues    DuplicateValuesConfig        `yaml:"duplicateValues"`
+	ChartMetadata      ChartMetadataConfig          `yaml:"chartMetadata"`
+	Policies           PoliciesConfig               `yaml:"policies"`
+	Lint               LintConfig                   `yaml:"lint"`
+	Permutations       PermutationsConfig           `yaml:"permutations"`
+	Determinism        DeterminismConfig            `yaml:"determinism"`
+	BestPractices      BestPracticesConfig          `yaml:"bestPractices"`
+	Rules              map[string]RuleConfig        `yaml:"rules"`
+	// FailOn lists the classes of problems that make scan exit non-zero:
+	// error, warning, undefined-values or none.
+	FailOn []string `yaml:"failOn"`
+	// SeverityThreshold hides findings below this severity (info, warning or
+	// error) from the output and the exit status.
+	SeverityThreshold string `yaml:"severityThreshold"`
+	// ReleaseName and Namespace are the release name and namespace charts
+	// are rendered with, as .Release.Name and .Release.Namespace. Empty
+	// values leave helm's defaults.
+	ReleaseName string `yaml:"releaseName"`
+	Namespace   string `yaml:"namespace"`
+	// PostRenderer transforms the rendered manifests before they are
+	// validated and checked by rules.
+	PostRenderer PostRendererConfig `yaml:"postRenderer"`
+	// Dependencies configures how helm downloads chart dependencies.
+	Dependencies DependenciesConfig `yaml:"dependencies"`
+	// ValuesFilesRelativeTo is what relative values files are resolved
+	// against: ValuesFilesRelativeToConfig, the default, or
+	// ValuesFilesRelativeToChart for per-chart files such as
+	// ci/test-values.yaml.
+	ValuesFilesRelativeTo string `yaml:"valuesFilesRelativeTo"`
+	// HelmBinary is the helm executable to run, a path or a name looked up
+	// in PATH. It defaults to helm.
+	HelmBinary string `yaml:"helmBinary"`
+	// Experimental lists the experimental checks to run, by name. They are
+	// off by default until they are stable.
+	Experimental []string `yaml:"experimental"`
+	// Environment is the environment selected with --environment, whose
+	// settings have been applied. It is not read from the config file.
+	Environment string `yaml:"-"`
+}
+
+// Values of Config.ValuesFilesRelativeTo. Charts without a chart-relative
+// values file are rendered without it.
+const (
+	ValuesFilesRelativeToConfig = "config"
+	ValuesFilesRelativeToChart  = "chart"
+)
+
+// PostRendererConfig pipes rendered manifests through a post-renderer, like
+// helm's --post-renderer, so charts are checked as they are deployed. Command
+// is an executable that reads the manifests on stdin and writes the result to
+// stdout, called with Args. Kustomize is an overlay directory instead, whose
+// kustomization.yaml lists the rendered manifests as the resource all.yaml;
+// it is built with kustomize, or kubectl kustomize when kustomize is not
+// installed.
+type PostRendererConfig struct {
+	Command   string   `yaml:"command"`
+	Args      []string `yaml:"args"`
+	Kustomize string   `yaml:"kustomize"`
+}
+
+// Enabled reports whether a post-renderer is configured.
+func (c PostRendererConfig) Enabled() bool {
+	return c.Command != "" || c.Kustomize != ""
+}
+
+// DependenciesConfig configures `helm dependency update`, e.g. for private
+// repositories in CI. RepositoryConfig, RepositoryCache and RegistryConfig
+// are passed to helm as --repository-config, --repository-cache and
+// --registry-config; the repository cache defaults to one in the chartscan
+// cache directory. SkipRefresh passes --skip-refresh, so the repository
+// indexes already in the cache are used instead of being downloaded again;
+// it is implied when no dependency comes from a chart repository.
+// Repositories adds the credentials of chart repositories to the repository
+// config helm runs with. Offline never runs `helm dependency update`, for
+// network-isolated builds: only dependencies vendored in charts/ or cached
+// are used.
+type DependenciesConfig struct {
+	RepositoryConfig string                  `yaml:"repositoryConfig"`
+	RepositoryCache  string                  `yaml:"repositoryCache"`
+	RegistryConfig   string                  `yaml:"registryConfig"`
+	SkipRefresh      bool                    `yaml:"skipRefresh"`
+	Repositories     []RepositoryCredentials `yaml:"repositories"`
+	Offline          bool                    `yaml:"offline"`
+}
+
+// RepositoryCredentials authenticate helm against the chart repository at
+// URL, which is named Name in the repository config (by default after its
+// host). Username and Password may reference environment variables as $VAR
+// or ${VAR}, so secrets stay out of the config file; they are expanded only
+// in the temporary repository config passed to helm.
+type RepositoryCredentials struct {
+	Name     string `yaml:"name"`
+	URL      string `yaml:"url"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
 }
 
 // TestSuite represents a JUnit-style test suite for test reports
@@ -34,20 +663,40 @@ type TestSuite struct {
 	Name       string     `xml:"name,attr"`
 	Tests      int        `xml:"tests,attr"`
 	Failures   int        `xml:"failures,attr"`
+	Skipped    int        `xml:"skipped,attr,omitempty"`
 	Time       string     `xml:"time,attr"`
-	TestCases  []TestCase `xml:"testcase"`
+	Timestamp  string     `xml:"timestamp,attr,omitempty"`
 	Properties []Property `xml:"properties>property,omitempty"`
+	TestCases  []TestCase `xml:"testcase"`
+}
+
+// TestSuites is the root of a JUnit-style test report with several suites.
+type TestSuites struct {
+	XMLName   xml.Name    `xml:"testsuites"`
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Skipped   int         `xml:"skipped,attr,omitempty"`
+	Time      string      `xml:"time,attr"`
+	Timestamp string      `xml:"timestamp,attr,omitempty"`
+	Suites    []TestSuite `xml:"testsuite"`
 }
 
 // TestCase represents a single test case in a JUnit-style test report
 type TestCase struct {
 	Name      string     `xml:"name,attr"`
 	ClassName string     `xml:"classname,attr"`
-	Time      string     `xml:"time,attr"`
+	Time      string     `xml:"time,attr,omitempty"`
 	Failure   *Failure   `xml:"failure,omitempty"`
+	Skipped   *Skipped   `xml:"skipped,omitempty"`
 	SystemOut *SystemOut `xml:"system-out,omitempty"`
 }
 
+// Skipped marks a test case that did not run
+type Skipped struct {
+	Message string `xml:"message,attr"`
+}
+
 // Failure represents a failure in a test case
 type Failure struct {
 	Message string `xml:"message,attr"`
@@ -0,0 +1,178 @@
+// Package progress draws a single-line progress bar for concurrent chart
+// scans: how many charts are done, which are being scanned and an estimate
+// of the time left.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+)
+
+const (
+	// barWidth is the number of cells in the bar itself.
+	barWidth = 20
+	// interval is how often the line is redrawn.
+	interval = 100 * time.Millisecond
+	// clearLine returns the cursor to the start of the line and erases it.
+	clearLine = "\r\033[K"
+)
+
+// Bar is a progress bar for a fixed number of tasks. A nil *Bar is valid and
+// draws nothing, so callers need not check whether progress is shown.
+type Bar struct {
+	w       io.Writer
+	total   int
+	columns int
+	now     func() time.Time
+
+	// out serializes writes to w between redraws and Println. drawing is
+	// set while the bar is shown.
+	out     sync.Mutex
+	drawing bool
+
+	mu      sync.Mutex
+	started time.Time
+	done    int
+	active  []string
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// New returns a Bar for total tasks writing to w, which should be a terminal.
+// Lines are cut to columns terminal cells unless columns is 0.
+func New(w io.Writer, total, columns int) *Bar {
+	return &Bar{w: w, total: total, columns: columns, now: time.Now}
+}
+
+// Start starts redrawing the bar until Stop is called.
+func (b *Bar) Start() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	b.started = b.now()
+	b.mu.Unlock()
+	b.stop = make(chan struct{})
+	b.stopped = make(chan struct{})
+	b.out.Lock()
+	b.drawing = true
+	b.out.Unlock()
+
+	go func() {
+		defer close(b.stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			b.draw()
+			select {
+			case <-b.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Begin marks the task name as in progress.
+func (b *Bar) Begin(name string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.active = append(b.active, name)
+}
+
+// Finish marks the task name as done.
+func (b *Bar) Finish(name string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, active := range b.active {
+		if active == name {
+			b.active = append(b.active[:i], b.active[i+1:]...)
+			break
+		}
+	}
+	b.done++
+}
+
+// Stop stops redrawing and erases the bar, leaving the line free for output.
+func (b *Bar) Stop() {
+	if b == nil || b.stop == nil {
+		return
+	}
+	close(b.stop)
+	<-b.stopped
+	b.stop = nil
+	b.out.Lock()
+	defer b.out.Unlock()
+	b.drawing = false
+	fmt.Fprint(b.w, clearLine)
+}
+
+// Println writes line above the bar, which is redrawn below it right away.
+// Before Start and after Stop, line is written on its own.
+func (b *Bar) Println(line string) {
+	if b == nil {
+		return
+	}
+	b.out.Lock()
+	defer b.out.Unlock()
+	if !b.drawing {
+		fmt.Fprintln(b.w, line)
+		return
+	}
+	fmt.Fprint(b.w, clearLine+line+"\n"+b.line())
+}
+
+// draw writes the current line over the previous one.
+func (b *Bar) draw() {
+	b.out.Lock()
+	defer b.out.Unlock()
+	fmt.Fprint(b.w, clearLine+b.line())
+}
+
+// line returns the bar, for example
+//
+//	[======>             ] 3/10  ETA 14s  scanning charts/web, charts/api
+//
+// The estimate is left out until the first task is done.
+func (b *Bar) line() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	filled := 0
+	if b.total > 0 {
+		filled = b.done * barWidth / b.total
+	}
+	bar := strings.Repeat("=", filled)
+	if filled < barWidth {
+		bar += ">" + strings.Repeat(" ", barWidth-filled-1)
+	}
+
+	line := fmt.Sprintf("[%s] %d/%d", bar, b.done, b.total)
+	if b.done > 0 && b.done < b.total {
+		elapsed := b.now().Sub(b.started)
+		remaining := elapsed / time.Duration(b.done) * time.Duration(b.total-b.done)
+		line += fmt.Sprintf("  ETA %v", remaining.Round(time.Second))
+	}
+	if len(b.active) > 0 {
+		line += "  scanning " + strings.Join(b.active, ", ")
+	}
+
+	if b.columns > 0 && runewidth.StringWidth(line) >= b.columns {
+		// Writing up to the last column wraps the cursor on some terminals.
+		// Chart names may have wide and multi-byte characters.
+		line = runewidth.Truncate(line, b.columns-1, "")
+	}
+	return line
+}
@@ -0,0 +1,98 @@
+package progress
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+)
+
+func TestLine(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := New(&bytes.Buffer{}, 4, 0)
+	b.now = func() time.Time { return now }
+	b.started = now
+
+	if expected := "[>                   ] 0/4"; b.line() != expected {
+		t.Errorf("Expected %q, got %q", expected, b.line())
+	}
+
+	b.Begin("charts/web")
+	b.Begin("charts/api")
+	b.Begin("charts/db")
+	b.Finish("charts/api")
+	now = now.Add(10 * time.Second)
+	expected := "[=====>              ] 1/4  ETA 30s  scanning charts/web, charts/db"
+	if b.line() != expected {
+		t.Errorf("Expected %q, got %q", expected, b.line())
+	}
+
+	b.columns = 30
+	if line := b.line(); line != expected[:29] {
+		t.Errorf("Expected the line cut to 29 characters, got %q", line)
+	}
+
+	// Wide characters are not split: cutting into 本 drops it.
+	wide := New(&bytes.Buffer{}, 1, 0)
+	wide.now = func() time.Time { return now }
+	wide.Begin("charts/日本語")
+	width := runewidth.StringWidth(wide.line())
+	wide.columns = width - 2
+	if line := wide.line(); !utf8.ValidString(line) || !strings.HasSuffix(line, "charts/日") || runewidth.StringWidth(line) > width-3 {
+		t.Errorf("Expected the line cut before 本, got %q", line)
+	}
+
+	for _, name := range []string{"charts/web", "charts/db", "charts/cache"} {
+		b.Finish(name)
+	}
+	if expected := "[====================] 4/4"; !strings.HasPrefix(b.line(), expected) {
+		t.Errorf("Expected %q, got %q", expected, b.line())
+	}
+}
+
+func TestStop(t *testing.T) {
+	var out bytes.Buffer
+	b := New(&out, 1, 0)
+	b.Start()
+	b.Finish("charts/web")
+	b.Stop()
+	b.Stop()
+
+	if !strings.HasSuffix(out.String(), clearLine) {
+		t.Errorf("Expected the bar to be erased, got %q", out.String())
+	}
+
+	var bar *Bar
+	bar.Start()
+	bar.Begin("charts/web")
+	bar.Finish("charts/web")
+	bar.Stop()
+}
+
+func TestPrintln(t *testing.T) {
+	var out bytes.Buffer
+	b := New(&out, 2, 0)
+	b.Println("before")
+	b.Start()
+	b.Finish("charts/web")
+	b.Println("✔ web")
+	b.Stop()
+	b.Println("after")
+
+	s := out.String()
+	if !strings.HasPrefix(s, "before\n") {
+		t.Errorf("Expected a line before Start to be written on its own, got %q", s)
+	}
+	if expected := clearLine + "✔ web\n[==========>         ] 1/2"; !strings.Contains(s, expected) {
+		t.Errorf("Expected %q with the bar redrawn below it, got %q", expected, s)
+	}
+	if !strings.HasSuffix(s, clearLine+"after\n") {
+		t.Errorf("Expected a line after Stop to be written on its own, got %q", s)
+	}
+
+	var bar *Bar
+	bar.Println("ignored")
+}
@@ -0,0 +1,317 @@
+// Package registry reads image metadata from OCI and Docker registries using
+// the distribution HTTP API.
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// manifestMediaTypes are the manifest formats accepted from registries, image
+// indexes first.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Reference is a parsed image reference.
+type Reference struct {
+	// Registry is the registry host, e.g. registry-1.docker.io or ghcr.io.
+	Registry string
+	// Repository is the repository path, e.g. library/nginx.
+	Repository string
+	// Reference is the tag or digest, e.g. 1.25 or sha256:….
+	Reference string
+}
+
+// ParseReference parses an image reference the way container runtimes do:
+// images without a registry host resolve to Docker Hub, official images to
+// its library/ namespace, and a missing tag to latest.
+func ParseReference(image string) (Reference, error) {
+	if image == "" || strings.ContainsAny(image, " \t\n") {
+		return Reference{}, fmt.Errorf("invalid image reference %q", image)
+	}
+
+	ref := Reference{Registry: "registry-1.docker.io"}
+	name := image
+	if i := strings.Index(name, "/"); i >= 0 {
+		if host := name[:i]; strings.ContainsAny(host, ".:") || host == "localhost" {
+			ref.Registry = host
+			if host == "docker.io" || host == "index.docker.io" {
+				ref.Registry = "registry-1.docker.io"
+			}
+			name = name[i+1:]
+		}
+	}
+
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, ref.Reference = name[:i], name[i+1:]
+	} else if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.Reference = name[:i], name[i+1:]
+	}
+	if ref.Reference == "" {
+		ref.Reference = "latest"
+	}
+	if ref.Registry == "registry-1.docker.io" && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	if name == "" {
+		return Reference{}, fmt.Errorf("invalid image reference %q", image)
+	}
+	ref.Repository = name
+	return ref, nil
+}
+
+// Client queries registries anonymously. Results are cached per image, so a
+// Client can be shared by concurrent chart scans.
+type Client struct {
+	HTTPClient *http.Client
+
+	mu        sync.Mutex
+	platforms map[string][]string
+	errors    map[string]error
+}
+
+// NewClient returns a Client with a bounded request timeout.
+func NewClient() *Client {
+	return &Client{HTTPClient: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Platforms returns the platforms ("os/arch" or "os/arch/variant") an image
+// is published for. Single-platform images report the platform of their
+// image config.
+func (c *Client) Platforms(image string) ([]string, error) {
+	c.mu.Lock()
+	if platforms, ok := c.platforms[image]; ok {
+		c.mu.Unlock()
+		return platforms, nil
+	}
+	if err, ok := c.errors[image]; ok {
+		c.mu.Unlock()
+		return nil, err
+	}
+	c.mu.Unlock()
+
+	platforms, err := c.fetchPlatforms(image)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.platforms == nil {
+		c.platforms = make(map[string][]string)
+		c.errors = make(map[string]error)
+	}
+	if err != nil {
+		c.errors[image] = err
+	} else {
+		c.platforms[image] = platforms
+	}
+	return platforms, err
+}
+
+// manifest holds the fields of image indexes and image manifests needed to
+// determine platforms.
+type manifest struct {
+	MediaType string `json:"mediaType"`
+	Manifests []struct {
+		Platform struct {
+			OS           string `json:"os"`
+			Architecture string `json:"architecture"`
+			Variant      string `json:"variant"`
+		} `json:"platform"`
+	} `json:"manifests"`
+	Config struct {
+		Digest string `json:"digest"`
+	} `json:"config"`
+}
+
+func (c *Client) fetchPlatforms(image string) ([]string, error) {
+	ref, err := ParseReference(image)
+	if err != nil {
+		return nil, err
+	}
+
+	var m manifest
+	if err := c.getJSON(ref, "manifests/"+ref.Reference, strings.Join(manifestMediaTypes, ", "), &m); err != nil {
+		return nil, fmt.Errorf("error fetching manifest of %s: %v", image, err)
+	}
+
+	if len(m.Manifests) > 0 {
+		var platforms []string
+		for _, entry := range m.Manifests {
+			p := entry.Platform
+			// Attestation manifests are listed with an unknown platform.
+			if p.Architecture == "" || p.Architecture == "unknown" {
+				continue
+			}
+			platforms = append(platforms, formatPlatform(p.OS, p.Architecture, p.Variant))
+		}
+		return platforms, nil
+	}
+
+	if m.Config.Digest == "" {
+		return nil, fmt.Errorf("manifest of %s lists neither platforms nor a config", image)
+	}
+	var config struct {
+		OS           string `json:"os"`
+		Architecture string `json:"architecture"`
+		Variant      string `json:"variant"`
+	}
+	if err := c.getJSON(ref, "blobs/"+m.Config.Digest, "*/*", &config); err != nil {
+		return nil, fmt.Errorf("error fetching image config of %s: %v", image, err)
+	}
+	return []string{formatPlatform(config.OS, config.Architecture, config.Variant)}, nil
+}
+
+// Artifact returns the content of the single-layer OCI artifact at
+// reference, such as a file pushed with `oras push`.
+func (c *Client) Artifact(reference string) ([]byte, error) {
+	ref, err := ParseReference(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	var m struct {
+		Layers []struct {
+			Digest string `json:"digest"`
+		} `json:"layers"`
+	}
+	if err := c.getJSON(ref, "manifests/"+ref.Reference, "application/vnd.oci.image.manifest.v1+json", &m); err != nil {
+		return nil, fmt.Errorf("error fetching manifest of %s: %v", reference, err)
+	}
+	if len(m.Layers) != 1 {
+		return nil, fmt.Errorf("artifact %s has %d layers, expected 1", reference, len(m.Layers))
+	}
+
+	content, err := c.fetch(ref, "blobs/"+m.Layers[0].Digest, "*/*")
+	if err != nil {
+		return nil, fmt.Errorf("error fetching layer of %s: %v", reference, err)
+	}
+	return content, nil
+}
+
+// getJSON fetches /v2/<repository>/<path> and decodes the JSON response.
+func (c *Client) getJSON(ref Reference, path, accept string, target interface{}) error {
+	body, err := c.fetch(ref, path, accept)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, target)
+}
+
+// fetch returns the body of /v2/<repository>/<path>, obtaining an anonymous
+// bearer token when the registry asks for one.
+func (c *Client) fetch(ref Reference, path, accept string) ([]byte, error) {
+	endpoint := fmt.Sprintf("%s://%s/v2/%s/%s", scheme(ref.Registry), ref.Registry, ref.Repository, path)
+
+	resp, err := c.get(endpoint, accept, "")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		token, err := c.token(challenge, ref.Repository)
+		if err != nil {
+			return nil, err
+		}
+		if resp, err = c.get(endpoint, accept, token); err != nil {
+			return nil, err
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", endpoint, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (c *Client) get(endpoint, accept, token string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return c.HTTPClient.Do(req)
+}
+
+// token requests an anonymous pull token as described by a Bearer
+// WWW-Authenticate challenge.
+func (c *Client) token(challenge, repository string) (string, error) {
+	params, found := strings.CutPrefix(challenge, "Bearer ")
+	if !found {
+		return "", fmt.Errorf("registry requires unsupported authentication %q", challenge)
+	}
+
+	values := make(map[string]string)
+	for _, param := range strings.Split(params, ",") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(param), "="); ok {
+			values[key] = strings.Trim(value, `"`)
+		}
+	}
+	if values["realm"] == "" {
+		return "", fmt.Errorf("registry challenge %q has no realm", challenge)
+	}
+
+	query := url.Values{}
+	if values["service"] != "" {
+		query.Set("service", values["service"])
+	}
+	scope := values["scope"]
+	if scope == "" {
+		scope = "repository:" + repository + ":pull"
+	}
+	query.Set("scope", scope)
+
+	resp, err := c.get(values["realm"]+"?"+query.Encode(), "application/json", "")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request to %s returned %s", values["realm"], resp.Status)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("error decoding token response: %v", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
+
+// scheme returns http for local registries and https otherwise.
+func scheme(host string) string {
+	hostname := host
+	if i := strings.LastIndex(host, ":"); i >= 0 {
+		hostname = host[:i]
+	}
+	if hostname == "localhost" || hostname == "127.0.0.1" || hostname == "[::1]" {
+		return "http"
+	}
+	return "https"
+}
+
+func formatPlatform(os, architecture, variant string) string {
+	platform := os + "/" + architecture
+	if variant != "" {
+		platform += "/" + variant
+	}
+	return platform
+}
@@ -0,0 +1,112 @@
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := map[string]Reference{
+		"nginx":                          {"registry-1.docker.io", "library/nginx", "latest"},
+		"nginx:1.25":                     {"registry-1.docker.io", "library/nginx", "1.25"},
+		"docker.io/bitnami/redis:7":      {"registry-1.docker.io", "bitnami/redis", "7"},
+		"ghcr.io/org/app@sha256:abc":     {"ghcr.io", "org/app", "sha256:abc"},
+		"localhost:5000/team/app:v1.2.3": {"localhost:5000", "team/app", "v1.2.3"},
+	}
+	for image, expected := range tests {
+		ref, err := ParseReference(image)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v", image, err)
+			continue
+		}
+		if ref != expected {
+			t.Errorf("Expected %s to parse as %+v, got %+v", image, expected, ref)
+		}
+	}
+
+	if _, err := ParseReference("not an image"); err == nil {
+		t.Errorf("Expected an error for an invalid reference")
+	}
+}
+
+func TestPlatforms(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			fmt.Fprint(w, `{"token": "secret"}`)
+		case r.Header.Get("Authorization") != "Bearer secret":
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/v2/multi/manifests/1.0":
+			fmt.Fprint(w, `{"manifests": [
+				{"platform": {"os": "linux", "architecture": "amd64"}},
+				{"platform": {"os": "linux", "architecture": "arm64", "variant": "v8"}},
+				{"platform": {"os": "unknown", "architecture": "unknown"}}]}`)
+		case r.URL.Path == "/v2/single/manifests/1.0":
+			fmt.Fprint(w, `{"config": {"digest": "sha256:cfg"}}`)
+		case r.URL.Path == "/v2/single/blobs/sha256:cfg":
+			fmt.Fprint(w, `{"os": "linux", "architecture": "amd64"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	client := NewClient()
+
+	platforms, err := client.Platforms(host + "/multi:1.0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Join(platforms, ",") != "linux/amd64,linux/arm64/v8" {
+		t.Errorf("Expected amd64 and arm64 platforms, got %v", platforms)
+	}
+
+	platforms, err = client.Platforms(host + "/single:1.0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(platforms) != 1 || platforms[0] != "linux/amd64" {
+		t.Errorf("Expected linux/amd64, got %v", platforms)
+	}
+
+	if _, err := client.Platforms(host + "/missing:1.0"); err == nil {
+		t.Errorf("Expected an error for a missing image")
+	}
+}
+
+func TestArtifact(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/org/config/manifests/v1":
+			fmt.Fprint(w, `{"layers": [{"digest": "sha256:layer"}]}`)
+		case "/v2/org/config/blobs/sha256:layer":
+			fmt.Fprint(w, "format: json\n")
+		case "/v2/org/empty/manifests/v1":
+			fmt.Fprint(w, `{"layers": []}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	client := NewClient()
+
+	content, err := client.Artifact(host + "/org/config:v1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(content) != "format: json\n" {
+		t.Errorf("Expected the layer content, got %q", content)
+	}
+
+	if _, err := client.Artifact(host + "/org/empty:v1"); err == nil {
+		t.Errorf("Expected an error for an artifact without layers")
+	}
+}
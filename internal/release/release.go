@@ -0,0 +1,117 @@
+// Package release describes the running build of ChartScan and looks up the
+// latest published release.
+package release
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"time"
+)
+
+// LatestURL is the GitHub API endpoint of the latest ChartScan release.
+const LatestURL = "https://api.github.com/repos/Jaydee94/chartscan/releases/latest"
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Info describes a ChartScan build and the tools it runs with.
+type Info struct {
+	Version   string `json:"Version"`
+	Commit    string `json:"Commit,omitempty"`
+	BuildDate string `json:"BuildDate,omitempty"`
+	GoVersion string `json:"GoVersion"`
+	// HelmVersion is the version of the helm binary on PATH, empty when
+	// helm cannot be run.
+	HelmVersion string `json:"HelmVersion,omitempty"`
+	// Schemas are the Kubernetes versions whose schemas are embedded.
+	Schemas []string `json:"Schemas,omitempty"`
+	// Latest is set when the latest release was looked up.
+	Latest *Latest `json:"Latest,omitempty"`
+}
+
+// Latest is the newest published release.
+type Latest struct {
+	Version string `json:"Version"`
+	URL     string `json:"URL"`
+	// UpdateAvailable is set when the release is newer than the running
+	// version. Builds without a release version, such as dev, are never
+	// considered outdated.
+	UpdateAvailable bool `json:"UpdateAvailable"`
+}
+
+// BuildInfo returns the Info of the running binary for version, commit and
+// date as injected with -ldflags. An empty commit or date falls back to the
+// VCS information the Go toolchain embeds in builds from a checkout.
+func BuildInfo(version, commit, date string) Info {
+	info := Info{Version: version, Commit: commit, BuildDate: date, GoVersion: runtime.Version()}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = setting.Value
+			}
+		}
+	}
+	return info
+}
+
+// CheckLatest fetches the latest release from url, a GitHub API endpoint
+// like LatestURL, and compares it with the current version.
+func CheckLatest(url, current string) (*Latest, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch the latest release: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot fetch the latest release: %s answered %s", url, resp.Status)
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("cannot decode the latest release: %v", err)
+	}
+	if release.TagName == "" {
+		return nil, fmt.Errorf("cannot decode the latest release: no tag name")
+	}
+	return &Latest{
+		Version:         release.TagName,
+		URL:             release.HTMLURL,
+		UpdateAvailable: newer(release.TagName, current),
+	}, nil
+}
+
+// versionRegex matches the release number at the start of a version such as
+// v1.4.0 or the git describe output v1.4.0-3-g1a2b3c4.
+var versionRegex = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)`)
+
+// newer reports whether the release number of latest is higher than that of
+// current. It is false when either has none.
+func newer(latest, current string) bool {
+	a, b := versionRegex.FindStringSubmatch(latest), versionRegex.FindStringSubmatch(current)
+	if a == nil || b == nil {
+		return false
+	}
+	for i := 1; i <= 3; i++ {
+		x, _ := strconv.Atoi(a[i])
+		y, _ := strconv.Atoi(b[i])
+		if x != y {
+			return x > y
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package release
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+func TestBuildInfo(t *testing.T) {
+	info := BuildInfo("v1.4.0", "1a2b3c4", "2026-03-04T12:30:00Z")
+	if info.Version != "v1.4.0" || info.Commit != "1a2b3c4" || info.BuildDate != "2026-03-04T12:30:00Z" {
+		t.Errorf("Expected the injected build details, got %+v", info)
+	}
+	if info.GoVersion != runtime.Version() {
+		t.Errorf("Expected Go version %s, got %s", runtime.Version(), info.GoVersion)
+	}
+}
+
+func TestCheckLatest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/releases/latest" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintln(w, `{"tag_name": "v1.5.0", "html_url": "https://github.com/Jaydee94/chartscan/releases/tag/v1.5.0"}`)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		current string
+		update  bool
+	}{
+		{"v1.4.0", true},
+		{"v1.4.0-3-g1a2b3c4", true},
+		{"1.5.0", false},
+		{"v1.10.0", false},
+		{"dev", false},
+	}
+	for _, tt := range tests {
+		latest, err := CheckLatest(server.URL+"/releases/latest", tt.current)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if latest.Version != "v1.5.0" || latest.UpdateAvailable != tt.update {
+			t.Errorf("Expected v1.5.0 with update available %v for %s, got %+v", tt.update, tt.current, latest)
+		}
+	}
+
+	if _, err := CheckLatest(server.URL+"/missing", "v1.4.0"); err == nil {
+		t.Errorf("Expected an error for a missing release")
+	}
+}
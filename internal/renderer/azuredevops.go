@@ -0,0 +1,57 @@
+package renderer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// PrintResultsAzureDevOps writes the findings to w as Azure Pipelines
+// logging commands, so errors and warnings show up as issues of the pipeline
+// run: one task.logissue command per error or warning. Azure Pipelines has
+// no issue type for info findings, so they are written as plain log lines. A
+// summary line follows.
+func PrintResultsAzureDevOps(w io.Writer, results []models.Result, duration time.Duration) {
+	var validCharts, invalidCharts int
+	for _, result := range models.FlattenResults(results) {
+		if result.Success {
+			validCharts++
+		} else {
+			invalidCharts++
+		}
+		for _, finding := range result.Findings {
+			message := annotationMessage(result, finding)
+			if finding.Severity == models.SeverityInfo {
+				fmt.Fprintf(w, "chartscan %s: %s\n", finding.RuleID, message)
+				continue
+			}
+
+			properties := []string{"type=error"}
+			if finding.Severity == models.SeverityWarning {
+				properties[0] = "type=warning"
+			}
+			if file := annotationFile(result.ChartPath, finding.File); file != "" {
+				properties = append(properties, "sourcepath="+escapeAzureDevOpsProperty(file))
+				if finding.Line > 0 {
+					properties = append(properties, fmt.Sprintf("linenumber=%d", finding.Line))
+				}
+			}
+			properties = append(properties, "code="+escapeAzureDevOpsProperty(finding.RuleID))
+			fmt.Fprintf(w, "##vso[task.logissue %s;]%s\n", strings.Join(properties, ";"), escapeAzureDevOpsData(message))
+		}
+	}
+	fmt.Fprintf(w, "chartscan: %d valid charts, %d invalid charts scanned in %v\n", validCharts, invalidCharts, duration.Round(time.Millisecond))
+}
+
+// escapeAzureDevOpsData escapes the message of a logging command.
+func escapeAzureDevOpsData(s string) string {
+	return strings.NewReplacer("%", "%AZP25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeAzureDevOpsProperty escapes a property value of a logging command.
+func escapeAzureDevOpsProperty(s string) string {
+	return strings.NewReplacer("%", "%AZP25", "\r", "%0D", "\n", "%0A", ";", "%3B", "]", "%5D").Replace(s)
+}
@@ -0,0 +1,36 @@
+package renderer
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestWriteResultsAzureDevOps(t *testing.T) {
+	results := []models.Result{
+		{ChartPath: "charts/web", Findings: []models.Finding{
+			{RuleID: UndefinedValueID, Severity: models.SeverityError, File: "charts/web/templates/deployment.yaml", Line: 3, Message: "Undefined value: 'a'\n100% broken"},
+			{RuleID: "latest-image-tag", Severity: models.SeverityWarning, Resource: "Deployment/web", Message: "uses latest"},
+			{RuleID: "duplicate-values", Severity: models.SeverityInfo, Message: "repeats a default"},
+		}},
+		{ChartPath: "charts/api", Success: true},
+	}
+
+	var output bytes.Buffer
+	PrintResultsAzureDevOps(&output, results, 1500*time.Millisecond)
+	expected := "##vso[task.logissue type=error;sourcepath=charts/web/templates/deployment.yaml;linenumber=3;code=undefined-value;]charts/web: Undefined value: 'a'%0A100%AZP25 broken\n" +
+		"##vso[task.logissue type=warning;code=latest-image-tag;]charts/web: Deployment/web: uses latest\n" +
+		"chartscan duplicate-values: charts/web: repeats a default\n" +
+		"chartscan: 1 valid charts, 1 invalid charts scanned in 1.5s\n"
+	if output.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, output.String())
+	}
+}
+
+func TestEscapeAzureDevOpsProperty(t *testing.T) {
+	if got := escapeAzureDevOpsProperty("a;b]c%"); got != "a%3Bb%5Dc%AZP25" {
+		t.Errorf("Expected 'a%%3Bb%%5Dc%%AZP25', got '%s'", got)
+	}
+}
@@ -0,0 +1,156 @@
+package renderer
+
+import (
+	"bufio"
+	"bytes"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// uncommittedHash is the commit git blame reports for lines that are not
+// committed yet.
+const uncommittedHash = "0000000000000000000000000000000000000000"
+
+// blamer attributes template lines to the commits that last changed them,
+// running git once per file.
+type blamer struct {
+	lines map[string][]*models.Blame
+	files map[string]*models.Blame
+}
+
+func newBlamer() *blamer {
+	return &blamer{lines: map[string][]*models.Blame{}, files: map[string]*models.Blame{}}
+}
+
+// line returns the blame of a 1-based line of file, or nil if the file is not
+// tracked by git.
+func (b *blamer) line(file string, line int) *models.Blame {
+	if b == nil {
+		return nil
+	}
+	lines, ok := b.lines[file]
+	if !ok {
+		lines = blameFile(file)
+		b.lines[file] = lines
+	}
+	if line < 1 || line > len(lines) {
+		return nil
+	}
+	return lines[line-1]
+}
+
+// file returns the last commit that changed file, or nil if the file is not
+// tracked by git.
+func (b *blamer) file(file string) *models.Blame {
+	if b == nil {
+		return nil
+	}
+	blame, ok := b.files[file]
+	if !ok {
+		blame = lastCommit(file)
+		b.files[file] = blame
+	}
+	return blame
+}
+
+// annotateFindings sets the blame of findings located in the chart at
+// chartPath, whose templates appear under sourcePrefix in rendered output.
+// Findings with a line are attributed to the commit that last changed that
+// line, others to the last commit that changed their file.
+func (b *blamer) annotateFindings(chartPath, sourcePrefix string, findings []models.Finding) {
+	if b == nil {
+		return
+	}
+	for i := range findings {
+		file := findingFile(chartPath, sourcePrefix, findings[i].File)
+		if file == "" {
+			continue
+		}
+		if findings[i].Line > 0 {
+			findings[i].Blame = b.line(file, findings[i].Line)
+		} else {
+			findings[i].Blame = b.file(file)
+		}
+	}
+}
+
+// findingFile resolves the File of a finding, which is relative to the
+// directory containing the chart ("mychart/templates/x.yaml"), to a path.
+func findingFile(chartPath, sourcePrefix, file string) string {
+	file = filepath.ToSlash(file)
+	rest, found := strings.CutPrefix(file, sourcePrefix)
+	if !found {
+		_, rest, found = strings.Cut(file, "/")
+	}
+	if !found || rest == "" {
+		return ""
+	}
+	return filepath.Join(chartPath, filepath.FromSlash(rest))
+}
+
+// blameFile runs git blame on file and returns the blame of each line.
+func blameFile(file string) []*models.Blame {
+	cmd := exec.Command("git", "-C", filepath.Dir(file), "blame", "--line-porcelain", "--", filepath.Base(file))
+	output, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+
+	var lines []*models.Blame
+	var current *models.Blame
+	var authorTime int64
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "\t"):
+			if current != nil {
+				current.Date = time.Unix(authorTime, 0).UTC().Format(time.DateOnly)
+				lines = append(lines, current)
+			}
+			current = nil
+		case current == nil:
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			current = &models.Blame{Commit: fields[0]}
+		case strings.HasPrefix(line, "author "):
+			current.Author = strings.TrimPrefix(line, "author ")
+		case strings.HasPrefix(line, "author-time "):
+			authorTime, _ = strconv.ParseInt(strings.TrimPrefix(line, "author-time "), 10, 64)
+		}
+	}
+
+	for i, blame := range lines {
+		if blame.Commit == uncommittedHash {
+			lines[i] = nil
+		}
+	}
+	return lines
+}
+
+// lastCommit returns the last commit that changed file.
+func lastCommit(file string) *models.Blame {
+	cmd := exec.Command("git", "-C", filepath.Dir(file), "log", "-1", "--format=%H%x00%an%x00%at", "--", filepath.Base(file))
+	output, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+	fields := strings.Split(strings.TrimSpace(string(output)), "\x00")
+	if len(fields) != 3 {
+		return nil
+	}
+	authorTime, _ := strconv.ParseInt(fields[2], 10, 64)
+	return &models.Blame{
+		Commit: fields[0],
+		Author: fields[1],
+		Date:   time.Unix(authorTime, 0).UTC().Format(time.DateOnly),
+	}
+}
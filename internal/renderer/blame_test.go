@@ -0,0 +1,88 @@
+package renderer
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// gitCommit commits all files in dir as the given author.
+func gitCommit(t *testing.T, dir, author string) {
+	t.Helper()
+	for _, args := range [][]string{
+		{"add", "-A"},
+		{"-c", "user.name=" + author, "-c", "user.email=" + author + "@example.com", "commit", "-q", "-m", "change"},
+	} {
+		if output, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+}
+
+func TestBlamer(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	if output, err := exec.Command("git", "init", "-q", repo).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, output)
+	}
+
+	chartDir := filepath.Join(repo, "app")
+	templates := filepath.Join(chartDir, "templates")
+	os.MkdirAll(templates, 0755)
+	template := filepath.Join(templates, "service.yaml")
+	os.WriteFile(template, []byte("kind: Service\n"), 0644)
+	gitCommit(t, repo, "alice")
+	os.WriteFile(template, []byte("kind: Service\nport: {{ .Values.port }}\n"), 0644)
+	gitCommit(t, repo, "bob")
+	os.WriteFile(template, []byte("kind: Service\nport: {{ .Values.port }}\nhost: {{ .Values.host }}\n"), 0644)
+
+	b := newBlamer()
+	if blame := b.line(template, 1); blame == nil || blame.Author != "alice" {
+		t.Errorf("Expected line 1 by alice, got %v", blame)
+	}
+	if blame := b.line(template, 2); blame == nil || blame.Author != "bob" || len(blame.Commit) != 40 {
+		t.Errorf("Expected line 2 by bob, got %v", blame)
+	}
+	if blame := b.line(template, 3); blame != nil {
+		t.Errorf("Expected no blame for an uncommitted line, got %v", blame)
+	}
+	if blame := b.file(template); blame == nil || blame.Author != "bob" {
+		t.Errorf("Expected file last changed by bob, got %v", blame)
+	}
+
+	refs, err := TemplateParser(template)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	undefined := checkValueReferences(refs, map[string]interface{}{}, b)
+	if len(undefined) != 2 || undefined[0].Blame == nil || undefined[0].Blame.Author != "bob" || undefined[1].Blame != nil {
+		t.Errorf("Expected only the committed reference to be blamed, got %v", undefined)
+	}
+
+	findings := []models.Finding{{RuleID: "pod-security", File: "app/templates/service.yaml"}}
+	b.annotateFindings(chartDir, "app/", findings)
+	if findings[0].Blame == nil || findings[0].Blame.Author != "bob" {
+		t.Errorf("Expected finding blamed on bob, got %v", findings[0].Blame)
+	}
+}
+
+func TestFindingFile(t *testing.T) {
+	tests := []struct {
+		prefix, file, expected string
+	}{
+		{"app/", "app/templates/a.yaml", filepath.Join("charts", "app", "templates", "a.yaml")},
+		{"app/charts/web/", "app/charts/web/templates/a.yaml", filepath.Join("charts", "app", "templates", "a.yaml")},
+		{"app/", "app-dir/values.schema.json", filepath.Join("charts", "app", "values.schema.json")},
+		{"app/", "", ""},
+	}
+	for _, test := range tests {
+		if got := findingFile(filepath.Join("charts", "app"), test.prefix, test.file); got != test.expected {
+			t.Errorf("Expected %q for %q, got %q", test.expected, test.file, got)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package renderer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// changeSet holds the lines changed on the current branch, keyed by absolute
+// file path. A nil line set marks a file that is new as a whole. A nil
+// changeSet reports everything as changed.
+type changeSet struct {
+	files map[string]map[int]bool
+}
+
+// loadChangeSet collects the lines under dir that changed since the merge
+// base of baseRef and HEAD, including uncommitted and untracked files.
+func loadChangeSet(dir, baseRef string) (*changeSet, error) {
+	root := gitRepoRoot(dir)
+	if root == "" {
+		return nil, fmt.Errorf("%s is not inside a git repository", dir)
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	mergeBase, err := exec.Command("git", "-C", root, "merge-base", baseRef, "HEAD").Output()
+	if err != nil {
+		return nil, fmt.Errorf("error finding merge base with %s: %v", baseRef, err)
+	}
+
+	// Paths with other than printable ASCII are quoted only when they
+	// contain quotes, backslashes or control characters.
+	diff, err := exec.Command("git", "-C", root, "-c", "core.quotePath=false", "diff", "-U0", "--no-color", "--no-ext-diff", strings.TrimSpace(string(mergeBase)), "--", absDir).Output()
+	if err != nil {
+		return nil, fmt.Errorf("error running git diff: %v", err)
+	}
+	changes := &changeSet{files: parseDiffLines(root, diff)}
+
+	untracked, err := exec.Command("git", "-C", root, "ls-files", "--others", "--exclude-standard", "-z", "--", absDir).Output()
+	if err != nil {
+		return nil, fmt.Errorf("error listing untracked files: %v", err)
+	}
+	for _, file := range strings.Split(string(untracked), "\x00") {
+		if file != "" {
+			changes.files[filepath.Join(root, filepath.FromSlash(file))] = nil
+		}
+	}
+
+	return changes, nil
+}
+
+// ChangedCharts returns the charts of chartDirs with files added, modified or
+// deleted since the merge base of baseRef and HEAD, including uncommitted and
+// untracked files. A chart counts as changed when one of its subcharts or of
+// the charts its file:// dependencies point at changed. If any of sharedFiles, such as values files passed to every chart,
+// changed, all charts are returned.
+func ChangedCharts(chartDirs []string, baseRef string, sharedFiles []string) ([]string, error) {
+	// changedFiles caches the changed files of each repository.
+	changedFiles := make(map[string][]string)
+	changedSince := func(path string) ([]string, error) {
+		root := gitRepoRoot(path)
+		if root == "" {
+			return nil, fmt.Errorf("%s is not inside a git repository", path)
+		}
+		if files, ok := changedFiles[root]; ok {
+			return files, nil
+		}
+		files, err := listChangedFiles(root, baseRef)
+		if err != nil {
+			return nil, err
+		}
+		changedFiles[root] = files
+		return files, nil
+	}
+
+	for _, file := range sharedFiles {
+		files, err := changedSince(filepath.Dir(file))
+		if err != nil {
+			continue
+		}
+		if absFile := resolvedPath(file); slices.Contains(files, absFile) {
+			return chartDirs, nil
+		}
+	}
+
+	var changed []string
+	for _, chartDir := range chartDirs {
+		files, err := changedSince(chartDir)
+		if err != nil {
+			return nil, err
+		}
+		dirs := localDependencyDirs(chartDir)
+		if slices.ContainsFunc(files, func(file string) bool {
+			return slices.ContainsFunc(dirs, func(dir string) bool {
+				return strings.HasPrefix(file, dir+string(filepath.Separator))
+			})
+		}) {
+			changed = append(changed, chartDir)
+		}
+	}
+	return changed, nil
+}
+
+// localDependencyDirs returns the resolved path of chartDir and of the
+// directories of the file:// dependencies it and its dependencies declare.
+func localDependencyDirs(chartDir string) []string {
+	dirs := []string{resolvedPath(chartDir)}
+	for i := 0; i < len(dirs); i++ {
+		// Broken dependencies are reported when the chart is scanned.
+		dependencies, _ := chartDependencies(filepath.Join(dirs[i], "Chart.yaml")) //nolint:errcheck
+		for _, dependency := range dependencies {
+			dir, ok := strings.CutPrefix(dependency.Repository, "file://")
+			if !ok {
+				continue
+			}
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(dirs[i], dir)
+			}
+			if dir = resolvedPath(dir); !slices.Contains(dirs, dir) {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	return dirs
+}
+
+// listChangedFiles returns the absolute paths of the files in the repository
+// at root that were added, modified or deleted since the merge base of baseRef
+// and HEAD, or are untracked.
+func listChangedFiles(root, baseRef string) ([]string, error) {
+	mergeBase, err := exec.Command("git", "-C", root, "merge-base", baseRef, "HEAD").Output()
+	if err != nil {
+		return nil, fmt.Errorf("error finding merge base with %s: %v", baseRef, err)
+	}
+	diff, err := exec.Command("git", "-C", root, "diff", "--name-only", "--no-renames", "-z", strings.TrimSpace(string(mergeBase))).Output()
+	if err != nil {
+		return nil, fmt.Errorf("error running git diff: %v", err)
+	}
+	untracked, err := exec.Command("git", "-C", root, "ls-files", "--others", "--exclude-standard", "-z").Output()
+	if err != nil {
+		return nil, fmt.Errorf("error listing untracked files: %v", err)
+	}
+
+	var files []string
+	for _, file := range strings.Split(string(diff)+string(untracked), "\x00") {
+		if file != "" {
+			files = append(files, filepath.Join(root, filepath.FromSlash(file)))
+		}
+	}
+	return files, nil
+}
+
+// resolvedPath returns the absolute path of path with symlinks resolved, as
+// git reports paths.
+func resolvedPath(path string) string {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if realPath, err := filepath.EvalSymlinks(absPath); err == nil {
+		return realPath
+	}
+	return absPath
+}
+
+// parseDiffLines returns the added and modified lines of a unified diff with
+// zero context, keyed by absolute path.
+func parseDiffLines(root string, diff []byte) map[string]map[int]bool {
+	files := make(map[string]map[int]bool)
+	var current map[int]bool
+
+	scanner := bufio.NewScanner(bytes.NewReader(diff))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			current = nil
+			// Paths with spaces end in a tab.
+			path := strings.TrimSuffix(strings.TrimPrefix(line, "+++ "), "\t")
+			if strings.HasPrefix(path, `"`) {
+				// git quotes unusual paths with C escapes.
+				unquoted, err := strconv.Unquote(path)
+				if err != nil {
+					continue
+				}
+				path = unquoted
+			}
+			if path, found := strings.CutPrefix(path, "b/"); found {
+				current = make(map[int]bool)
+				files[filepath.Join(root, filepath.FromSlash(path))] = current
+			}
+		case strings.HasPrefix(line, "@@ ") && current != nil:
+			// @@ -a,b +c,d @@: lines c to c+d-1 are new; d defaults to 1.
+			fields := strings.Fields(line)
+			if len(fields) < 3 {
+				continue
+			}
+			start, count, _ := strings.Cut(strings.TrimPrefix(fields[2], "+"), ",")
+			first, err := strconv.Atoi(start)
+			if err != nil {
+				continue
+			}
+			n := 1
+			if count != "" {
+				if n, err = strconv.Atoi(count); err != nil {
+					continue
+				}
+			}
+			for i := first; i < first+n; i++ {
+				current[i] = true
+			}
+		}
+	}
+
+	return files
+}
+
+// lineChanged reports whether line of file changed.
+func (c *changeSet) lineChanged(file string, line int) bool {
+	if c == nil {
+		return true
+	}
+	lines, found := c.lookup(file)
+	return found && (lines == nil || lines[line])
+}
+
+// fileChanged reports whether any line of file changed.
+func (c *changeSet) fileChanged(file string) bool {
+	if c == nil {
+		return true
+	}
+	lines, found := c.lookup(file)
+	return found && (lines == nil || len(lines) > 0)
+}
+
+func (c *changeSet) lookup(file string) (map[int]bool, bool) {
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		return nil, false
+	}
+	if lines, found := c.files[absFile]; found {
+		return lines, true
+	}
+	// git reports paths with symlinks resolved.
+	if realFile, err := filepath.EvalSymlinks(absFile); err == nil {
+		lines, found := c.files[realFile]
+		return lines, found
+	}
+	return nil, false
+}
+
+// filterReferences returns the value references on changed lines.
+func (c *changeSet) filterReferences(refs []models.ValueReference) []models.ValueReference {
+	if c == nil {
+		return refs
+	}
+	var changed []models.ValueReference
+	for _, ref := range refs {
+		if c.lineChanged(ref.File, ref.Line) {
+			changed = append(changed, ref)
+		}
+	}
+	return changed
+}
+
+// filterTemplateFindings returns the findings on changed lines of findings
+// located by template file path, like value references.
+func (c *changeSet) filterTemplateFindings(findings []models.Finding) []models.Finding {
+	if c == nil {
+		return findings
+	}
+	var changed []models.Finding
+	for _, finding := range findings {
+		if c.lineChanged(finding.File, finding.Line) {
+			changed = append(changed, finding)
+		}
+	}
+	return changed
+}
+
+// filterFindings returns the findings of the chart at chartPath located on
+// changed lines, or in changed files for findings without a line.
+func (c *changeSet) filterFindings(chartPath, sourcePrefix string, findings []models.Finding) []models.Finding {
+	if c == nil {
+		return findings
+	}
+	var changed []models.Finding
+	for _, finding := range findings {
+		file := findingFile(chartPath, sourcePrefix, finding.File)
+		if file == "" {
+			continue
+		}
+		if (finding.Line > 0 && c.lineChanged(file, finding.Line)) || (finding.Line == 0 && c.fileChanged(file)) {
+			changed = append(changed, finding)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,181 @@
+package renderer
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestParseDiffLines(t *testing.T) {
+	diff := []byte(`diff --git a/app/templates/a.yaml b/app/templates/a.yaml
+--- a/app/templates/a.yaml
++++ b/app/templates/a.yaml
+@@ -2 +2 @@
+-old
++new
+@@ -10,0 +11,2 @@
++added
++added
+diff --git a/app/templates/b.yaml b/app/templates/b.yaml
+--- a/app/templates/b.yaml
++++ /dev/null
+@@ -1 +0,0 @@
+-gone
+diff --git a/app/templates/my file.yaml b/app/templates/my file.yaml
+--- a/app/templates/my file.yaml	
++++ b/app/templates/my file.yaml	
+@@ -0,0 +1 @@
++spaced
+diff --git "a/app/templates/\"quoted\"\tü.yaml" "b/app/templates/\"quoted\"\tü.yaml"
+--- "a/app/templates/\"quoted\"\tü.yaml"
++++ "b/app/templates/\"quoted\"\tü.yaml"
+@@ -0,0 +3 @@
++quoted
+`)
+	files := parseDiffLines("/repo", diff)
+
+	lines := files[filepath.Join("/repo", "app", "templates", "a.yaml")]
+	for _, line := range []int{2, 11, 12} {
+		if !lines[line] {
+			t.Errorf("Expected line %d to be changed, got %v", line, lines)
+		}
+	}
+	if len(lines) != 3 {
+		t.Errorf("Expected 3 changed lines, got %v", lines)
+	}
+	if _, found := files[filepath.Join("/repo", "app", "templates", "b.yaml")]; found {
+		t.Errorf("Expected deleted file to be skipped, got %v", files)
+	}
+	if !files[filepath.Join("/repo", "app", "templates", "my file.yaml")][1] {
+		t.Errorf("Expected line 1 of the file with a space to be changed, got %v", files)
+	}
+	if !files[filepath.Join("/repo", "app", "templates", "\"quoted\"\tü.yaml")][3] {
+		t.Errorf("Expected line 3 of the quoted path to be changed, got %v", files)
+	}
+}
+
+func TestLoadChangeSet(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	if output, err := exec.Command("git", "init", "-q", "-b", "main", repo).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, output)
+	}
+
+	chartDir := filepath.Join(repo, "app")
+	templates := filepath.Join(chartDir, "templates")
+	os.MkdirAll(templates, 0755)
+	service := filepath.Join(templates, "service.yaml")
+	os.WriteFile(service, []byte("kind: Service\nport: {{ .Values.port }}\n"), 0644)
+	deployment := filepath.Join(templates, "deployment.yaml")
+	os.WriteFile(deployment, []byte("kind: Deployment\n"), 0644)
+	gitCommit(t, repo, "alice")
+
+	exec.Command("git", "-C", repo, "checkout", "-q", "-b", "feature").Run()
+	os.WriteFile(service, []byte("kind: Service\nport: {{ .Values.port }}\nhost: {{ .Values.host }}\n"), 0644)
+	gitCommit(t, repo, "bob")
+	ingress := filepath.Join(templates, "ingress.yaml")
+	os.WriteFile(ingress, []byte("kind: Ingress\n"), 0644)
+
+	changes, err := loadChangeSet(chartDir, "main")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	refs, err := TemplateParser(service)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	changed := changes.filterReferences(refs)
+	if len(changed) != 1 || changed[0].Name != "host" {
+		t.Errorf("Expected only the host reference to be new, got %v", changed)
+	}
+
+	findings := []models.Finding{
+		{RuleID: "a", File: "app/templates/service.yaml"},
+		{RuleID: "b", File: "app/templates/deployment.yaml"},
+		{RuleID: "c", File: "app/templates/ingress.yaml"},
+		{RuleID: "d", File: "app/templates/service.yaml", Line: 1},
+	}
+	kept := changes.filterFindings(chartDir, "app/", findings)
+	if len(kept) != 2 || kept[0].RuleID != "a" || kept[1].RuleID != "c" {
+		t.Errorf("Expected findings a and c, got %v", kept)
+	}
+
+	undefined := []models.Finding{
+		{RuleID: "port", File: service, Line: 2},
+		{RuleID: "host", File: service, Line: 3},
+		{RuleID: "ingress", File: ingress, Line: 1},
+	}
+	kept = changes.filterTemplateFindings(undefined)
+	if len(kept) != 2 || kept[0].RuleID != "host" || kept[1].RuleID != "ingress" {
+		t.Errorf("Expected the host and ingress findings, got %v", kept)
+	}
+
+	if _, err := loadChangeSet(chartDir, "no-such-ref"); err == nil {
+		t.Errorf("Expected error for unknown base ref, got nil")
+	}
+}
+
+func TestChangedCharts(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	if output, err := exec.Command("git", "init", "-q", "-b", "main", repo).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, output)
+	}
+
+	app := filepath.Join(repo, "charts", "app")
+	sub := filepath.Join(app, "charts", "sub")
+	api := filepath.Join(repo, "charts", "api")
+	web := filepath.Join(repo, "charts", "web")
+	worker := filepath.Join(repo, "charts", "worker")
+	common := filepath.Join(repo, "libs", "common")
+	base := filepath.Join(repo, "libs", "base")
+	for _, dir := range []string{sub, api, web, worker, common, base} {
+		os.MkdirAll(filepath.Join(dir, "templates"), 0755)
+		os.WriteFile(filepath.Join(dir, "Chart.yaml"), []byte("name: "+filepath.Base(dir)+"\n"), 0644)
+		os.WriteFile(filepath.Join(dir, "templates", "service.yaml"), []byte("kind: Service\n"), 0644)
+	}
+	os.WriteFile(filepath.Join(app, "Chart.yaml"), []byte("name: app\n"), 0644)
+	// worker uses the common library, which in turn uses base.
+	os.WriteFile(filepath.Join(worker, "Chart.yaml"), []byte("name: worker\ndependencies:\n  - name: common\n    repository: file://../../libs/common\n"), 0644)
+	os.WriteFile(filepath.Join(common, "Chart.yaml"), []byte("name: common\ndependencies:\n  - name: base\n    repository: file://../base\n"), 0644)
+	values := filepath.Join(repo, "values.yaml")
+	os.WriteFile(values, []byte("port: 80\n"), 0644)
+	gitCommit(t, repo, "alice")
+
+	exec.Command("git", "-C", repo, "checkout", "-q", "-b", "feature").Run()
+	os.WriteFile(filepath.Join(sub, "templates", "service.yaml"), []byte("kind: Service\nport: 80\n"), 0644)
+	gitCommit(t, repo, "bob")
+	os.Remove(filepath.Join(api, "templates", "service.yaml"))
+	os.WriteFile(filepath.Join(base, "templates", "service.yaml"), []byte("kind: Service\nport: 80\n"), 0644)
+
+	chartDirs := []string{app, sub, api, web, worker}
+	changed, err := ChangedCharts(chartDirs, "main", []string{values})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Join(changed, ",") != strings.Join([]string{app, sub, api, worker}, ",") {
+		t.Errorf("Expected app, its subchart, api and worker, through its library, to have changed, got %v", changed)
+	}
+
+	os.WriteFile(values, []byte("port: 8080\n"), 0644)
+	changed, err = ChangedCharts(chartDirs, "main", []string{values})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(changed) != len(chartDirs) {
+		t.Errorf("Expected a changed shared values file to select all charts, got %v", changed)
+	}
+
+	if _, err := ChangedCharts(chartDirs, "no-such-ref", nil); err == nil {
+		t.Errorf("Expected error for unknown base ref, got nil")
+	}
+}
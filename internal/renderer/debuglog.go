@@ -0,0 +1,122 @@
+package renderer
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Jaydee94/chartscan/internal/tracing"
+)
+
+// scanLog records the stages of a single chart's scan and the helm commands
+// it runs: as the debug log of charts selected with --debug-chart, and as
+// trace spans below the chart's span when tracing is enabled. A nil scanLog
+// records nothing.
+type scanLog struct {
+	start time.Time
+	lines []string
+	// debug records lines; a log that only traces has it unset.
+	debug bool
+	// span is the trace span of the chart's scan and stageSpan that of the
+	// current stage.
+	span      *tracing.Span
+	stageSpan *tracing.Span
+}
+
+func newScanLog(debug bool, span *tracing.Span) *scanLog {
+	return &scanLog{start: time.Now(), debug: debug, span: span}
+}
+
+// printf records a stage message, prefixed with the time since the scan
+// started.
+func (l *scanLog) printf(format string, args ...interface{}) {
+	if l == nil || !l.debug {
+		return
+	}
+	elapsed := time.Since(l.start).Round(time.Millisecond)
+	l.lines = append(l.lines, fmt.Sprintf("[%s] %s", elapsed, fmt.Sprintf(format, args...)))
+}
+
+// stage begins the named stage of the scan, ending the previous one.
+func (l *scanLog) stage(name string) {
+	if l == nil {
+		return
+	}
+	l.stageSpan.End()
+	l.stageSpan = l.span.Start(name)
+}
+
+// end ends the current stage.
+func (l *scanLog) end() {
+	if l == nil {
+		return
+	}
+	l.stageSpan.End()
+	l.stageSpan = nil
+}
+
+// command records a command started at start and finished with its exit
+// status and output.
+func (l *scanLog) command(cmd *exec.Cmd, start time.Time, stdout, stderr string, err error) {
+	if l == nil {
+		return
+	}
+	parent := l.stageSpan
+	if parent == nil {
+		parent = l.span
+	}
+	span := parent.StartAt(strings.Join(cmd.Args[:min(2, len(cmd.Args))], " "), start,
+		tracing.String("process.command_line", strings.Join(cmd.Args, " ")))
+	if cmd.ProcessState != nil {
+		span.SetAttributes(tracing.Int("process.exit_code", cmd.ProcessState.ExitCode()))
+	}
+	if err != nil {
+		span.SetError(err.Error())
+	}
+	span.End()
+
+	if !l.debug {
+		return
+	}
+	status := "ok"
+	if err != nil {
+		status = err.Error()
+	}
+	l.printf("$ %s (%s)", strings.Join(cmd.Args, " "), status)
+	for _, stream := range []struct{ name, output string }{{"stdout", stdout}, {"stderr", stderr}} {
+		if output := strings.TrimRight(stream.output, "\n"); output != "" {
+			l.lines = append(l.lines, "  "+stream.name+":\n    "+strings.ReplaceAll(output, "\n", "\n    "))
+		}
+	}
+}
+
+// entries returns the recorded lines.
+func (l *scanLog) entries() []string {
+	if l == nil {
+		return nil
+	}
+	return l.lines
+}
+
+// matchesChart reports whether chartPath is selected by one of the patterns,
+// as given to --debug-chart or permutations.charts: the chart's path, a glob
+// matching it, or the name of its directory.
+func matchesChart(chartPath string, patterns []string) bool {
+	cleanPath := filepath.Clean(chartPath)
+	absPath, _ := filepath.Abs(chartPath)
+	for _, pattern := range patterns {
+		cleanPattern := filepath.Clean(pattern)
+		if cleanPattern == cleanPath || cleanPattern == filepath.Base(cleanPath) {
+			return true
+		}
+		if absPattern, err := filepath.Abs(pattern); err == nil && absPattern == absPath {
+			return true
+		}
+		if matched, _ := filepath.Match(cleanPattern, cleanPath); matched {
+			return true
+		}
+	}
+	return false
+}
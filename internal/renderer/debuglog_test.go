@@ -0,0 +1,66 @@
+package renderer
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScanLog(t *testing.T) {
+	var disabled *scanLog
+	disabled.printf("ignored")
+	disabled.stage("lint")
+	disabled.command(exec.Command("helm", "lint"), time.Now(), "out", "err", nil)
+	if entries := disabled.entries(); entries != nil {
+		t.Errorf("Expected a nil log to record nothing, got %v", entries)
+	}
+
+	tracingOnly := newScanLog(false, nil)
+	tracingOnly.stage("lint")
+	tracingOnly.printf("linting %s", "charts/app")
+	tracingOnly.command(exec.Command("helm", "lint"), time.Now(), "out", "err", nil)
+	tracingOnly.end()
+	if entries := tracingOnly.entries(); entries != nil {
+		t.Errorf("Expected a log without debug to record no lines, got %v", entries)
+	}
+
+	log := newScanLog(true, nil)
+	log.printf("linting %s", "charts/app")
+	log.command(exec.Command("helm", "lint", "--strict", "charts/app"), time.Now(), "==> Linting charts/app\n", "", errors.New("exit status 1"))
+
+	entries := log.entries()
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %v", entries)
+	}
+	if !strings.HasSuffix(entries[0], "] linting charts/app") {
+		t.Errorf("Expected a timestamped stage, got '%s'", entries[0])
+	}
+	if !strings.HasSuffix(entries[1], "$ helm lint --strict charts/app (exit status 1)") {
+		t.Errorf("Expected the command with its status, got '%s'", entries[1])
+	}
+	if entries[2] != "  stdout:\n    ==> Linting charts/app" {
+		t.Errorf("Expected the indented stdout, got '%s'", entries[2])
+	}
+}
+
+func TestMatchesDebugChart(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		expected bool
+	}{
+		{[]string{"charts/app"}, true},
+		{[]string{"./charts/app/"}, true},
+		{[]string{"app"}, true},
+		{[]string{"charts/*"}, true},
+		{[]string{"other", "charts/a*"}, true},
+		{[]string{"charts/api"}, false},
+		{nil, false},
+	}
+	for _, test := range tests {
+		if got := matchesChart("charts/app", test.patterns); got != test.expected {
+			t.Errorf("Expected %v to match charts/app: %v, got %v", test.patterns, test.expected, got)
+		}
+	}
+}
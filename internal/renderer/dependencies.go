@@ -0,0 +1,463 @@
+package renderer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Jaydee94/chartscan/internal/finder"
+	"github.com/Jaydee94/chartscan/internal/models"
+	"github.com/Jaydee94/chartscan/internal/rules"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultCacheDir returns the directory chartscan caches downloads in,
+// chartscan/ under the user cache directory ($XDG_CACHE_HOME or ~/.cache on
+// Linux), or "" if there is none.
+func DefaultCacheDir() string {
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(userCacheDir, "chartscan")
+}
+
+// handleDependencies makes the dependencies declared in the Chart.yaml of the
+// chart at chartPath available in its charts/ directory. Dependencies already
+// vendored as pinned by Chart.lock are used as they are. Otherwise they are
+// restored from cacheDir, or downloaded with `helm dependency update` and
+// cached there; an empty cacheDir disables caching. config sets up helm for
+// private repositories; in offline mode, dependencies neither vendored nor
+// cached are reported as missing instead of downloaded. The returned cleanup
+// function removes everything that was added to the chart.
+func handleDependencies(chartPath, cacheDir string, config models.DependenciesConfig, log *scanLog) (bool, []string, func()) {
+	noop := func() {}
+
+	dependencies, err := chartDependencies(filepath.Join(chartPath, "Chart.yaml"))
+	if err != nil {
+		return false, []string{fmt.Sprintf("Error reading Chart.yaml: %v", err)}, noop
+	}
+	if len(dependencies) == 0 {
+		return true, nil, noop
+	}
+
+	cleanup := trackAddedDependencies(chartPath)
+
+	if lockSatisfied(chartPath, dependencies) {
+		log.printf("dependencies vendored as pinned by Chart.lock, skipping helm dependency update")
+		return true, nil, cleanup
+	}
+
+	var entryDir string
+	if key, ok := dependencyKey(chartPath, dependencies); ok && cacheDir != "" {
+		entryDir = filepath.Join(cacheDir, "dependencies", key)
+		if err := restoreDependencies(entryDir, chartPath); err == nil {
+			log.printf("dependencies restored from cache %s", entryDir)
+			return true, nil, cleanup
+		}
+	}
+
+	if config.Offline {
+		if missing := missingDependencies(chartPath, dependencies); len(missing) > 0 {
+			cleanup()
+			return false, []string{fmt.Sprintf("Dependencies not vendored in charts/ or cached, not downloaded in offline mode: %s", strings.Join(missing, ", "))}, noop
+		}
+		log.printf("dependencies found in charts/, skipping helm dependency update in offline mode")
+		return true, nil, cleanup
+	}
+
+	repositoryCache := config.RepositoryCache
+	switch {
+	case repositoryCache != "":
+	case cacheDir != "":
+		repositoryCache = filepath.Join(cacheDir, "repository")
+	default:
+		if repositoryCache, err = os.MkdirTemp("", "chartscan"); err != nil {
+			return false, []string{fmt.Sprintf("Error creating temp cache dir: %v", err)}, noop
+		}
+		defer os.RemoveAll(repositoryCache)
+	}
+
+	args := []string{"dependency", "update", "--repository-cache", repositoryCache}
+	if config.SkipRefresh || !needsRepositoryIndex(dependencies) {
+		args = append(args, "--skip-refresh")
+	}
+	if config.RegistryConfig != "" {
+		args = append(args, "--registry-config", config.RegistryConfig)
+	}
+	repositoryConfig, removeRepositoryConfig, err := repositoryConfigFile(config)
+	if err != nil {
+		return false, []string{fmt.Sprintf("Error writing repository config: %v", err)}, noop
+	}
+	defer removeRepositoryConfig()
+	if repositoryConfig != "" {
+		args = append(args, "--repository-config", repositoryConfig)
+	}
+
+	dependencyCmd := helmCommand(append(args, chartPath)...)
+	var dependencyStdout, dependencyStderr bytes.Buffer
+	dependencyCmd.Stdout = &dependencyStdout
+	dependencyCmd.Stderr = &dependencyStderr
+	started := time.Now()
+	err = dependencyCmd.Run()
+	log.command(dependencyCmd, started, dependencyStdout.String(), dependencyStderr.String(), err)
+	if err != nil {
+		cleanup()
+		return false, []string{fmt.Sprintf("Error updating dependencies: %v", err)}, noop
+	}
+
+	if entryDir != "" {
+		if err := storeDependencies(chartPath, entryDir); err != nil {
+			log.printf("error caching dependencies: %v", err)
+		}
+	}
+	return true, nil, cleanup
+}
+
+// needsRepositoryIndex reports whether any dependency comes from a chart
+// repository, whose index helm refreshes before updating dependencies,
+// rather than from an OCI registry or a local directory.
+func needsRepositoryIndex(dependencies []chartDependency) bool {
+	for _, dependency := range dependencies {
+		repository := dependency.Repository
+		if repository != "" && !strings.HasPrefix(repository, "oci://") && !strings.HasPrefix(repository, "file://") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkLocalDependencies checks the file:// dependencies declared in the
+// Chart.yaml of the chart at chartPath, which helm dependency update only
+// reports vaguely: each must point at a chart directory holding the chart of
+// the dependency's name, at a version satisfying its version constraint. It
+// returns one message per problem; a Chart.yaml that cannot be read is left
+// to handleDependencies.
+func checkLocalDependencies(chartPath string) []string {
+	dependencies, err := chartDependencies(filepath.Join(chartPath, "Chart.yaml"))
+	if err != nil {
+		return nil
+	}
+	var problems []string
+	for _, dependency := range dependencies {
+		dir, ok := strings.CutPrefix(dependency.Repository, "file://")
+		if !ok {
+			continue
+		}
+		local := dir
+		if !filepath.IsAbs(local) {
+			local = filepath.Join(chartPath, local)
+		}
+		info := finder.ReadChartInfo(local)
+		switch {
+		case info.Err != nil:
+			problems = append(problems, fmt.Sprintf("Dependency %s points at %s, which is not a chart: %v", dependency.Name, dependency.Repository, info.Err))
+		case info.Name != dependency.Name:
+			problems = append(problems, fmt.Sprintf("Dependency %s points at %s, which is the chart %s", dependency.Name, dependency.Repository, info.Name))
+		case dependency.Version != "":
+			if _, err := rules.VersionSatisfies(dependency.Version, ""); err != nil {
+				problems = append(problems, fmt.Sprintf("Dependency %s has an invalid version constraint %q: %v", dependency.Name, dependency.Version, err))
+			} else if satisfied, err := rules.VersionSatisfies(dependency.Version, info.Version); err != nil {
+				problems = append(problems, fmt.Sprintf("Dependency %s points at %s, whose version %q is invalid", dependency.Name, dependency.Repository, info.Version))
+			} else if !satisfied {
+				problems = append(problems, fmt.Sprintf("Dependency %s requires version %s, but the chart at %s is version %s", dependency.Name, dependency.Version, dependency.Repository, info.Version))
+			}
+		}
+	}
+	return problems
+}
+
+// repositoryConfigFile returns the repository config helm runs with: the
+// configured one, or, when credentials are configured, a temporary copy of
+// it, or of helm's default, with the repositories of the credentials added
+// or replaced. The returned function removes the temporary copy.
+func repositoryConfigFile(config models.DependenciesConfig) (string, func(), error) {
+	noop := func() {}
+	if len(config.Repositories) == 0 {
+		return config.RepositoryConfig, noop, nil
+	}
+
+	base := config.RepositoryConfig
+	if base == "" {
+		if output, err := helmCommand("env", "HELM_REPOSITORY_CONFIG").Output(); err == nil {
+			base = strings.TrimSpace(string(output))
+		}
+	}
+	repositoryFile := make(map[string]interface{})
+	if base != "" {
+		data, err := os.ReadFile(base)
+		if err != nil && !os.IsNotExist(err) {
+			return "", noop, err
+		}
+		if err := yaml.Unmarshal(data, &repositoryFile); err != nil {
+			return "", noop, fmt.Errorf("error parsing %s: %v", base, err)
+		}
+	}
+
+	var repositories []interface{}
+	if existing, ok := repositoryFile["repositories"].([]interface{}); ok {
+		repositories = existing
+	}
+	for _, credentials := range config.Repositories {
+		if credentials.URL == "" {
+			return "", noop, fmt.Errorf("repository without url")
+		}
+		name := credentials.Name
+		if name == "" {
+			name = repositoryName(credentials.URL)
+		}
+		entry := map[string]interface{}{
+			"name":     name,
+			"url":      credentials.URL,
+			"username": os.ExpandEnv(credentials.Username),
+			"password": os.ExpandEnv(credentials.Password),
+		}
+		replaced := false
+		for i, repository := range repositories {
+			if existing, ok := repository.(map[string]interface{}); ok && (existing["url"] == credentials.URL || existing["name"] == name) {
+				for key, value := range entry {
+					// An entry matched by url keeps its name unless the
+					// credentials name the repository.
+					if key == "name" && credentials.Name == "" && existing["url"] == credentials.URL {
+						continue
+					}
+					existing[key] = value
+				}
+				repositories[i], replaced = existing, true
+				break
+			}
+		}
+		if !replaced {
+			repositories = append(repositories, entry)
+		}
+	}
+	repositoryFile["repositories"] = repositories
+
+	data, err := yaml.Marshal(repositoryFile)
+	if err != nil {
+		return "", noop, err
+	}
+	// CreateTemp creates the file readable only by its owner, which keeps the
+	// expanded credentials private.
+	file, err := os.CreateTemp("", "chartscan-repositories-*.yaml")
+	if err != nil {
+		return "", noop, err
+	}
+	remove := func() { os.Remove(file.Name()) }
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		remove()
+		return "", noop, err
+	}
+	if err := file.Close(); err != nil {
+		remove()
+		return "", noop, err
+	}
+	return file.Name(), remove, nil
+}
+
+// repositoryNameRegex matches the characters not allowed in the repository
+// names derived from URLs.
+var repositoryNameRegex = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+// repositoryName names the repository at url after its host and path, e.g.
+// charts-example-com-stable for https://charts.example.com/stable.
+func repositoryName(url string) string {
+	_, rest, found := strings.Cut(url, "://")
+	if !found {
+		rest = url
+	}
+	return strings.Trim(repositoryNameRegex.ReplaceAllString(rest, "-"), "-")
+}
+
+// trackAddedDependencies records the contents of the chart's charts/
+// directory and whether it has a Chart.lock, and returns a function removing
+// whatever was added since.
+func trackAddedDependencies(chartPath string) func() {
+	chartsDir := filepath.Join(chartPath, "charts")
+	lockFile := filepath.Join(chartPath, "Chart.lock")
+
+	existing := make(map[string]bool)
+	entries, chartsErr := os.ReadDir(chartsDir)
+	for _, entry := range entries {
+		existing[entry.Name()] = true
+	}
+	_, lockErr := os.Stat(lockFile)
+
+	return func() {
+		if os.IsNotExist(chartsErr) {
+			os.RemoveAll(chartsDir)
+		} else {
+			entries, _ := os.ReadDir(chartsDir)
+			for _, entry := range entries {
+				if !existing[entry.Name()] {
+					os.RemoveAll(filepath.Join(chartsDir, entry.Name()))
+				}
+			}
+		}
+		if os.IsNotExist(lockErr) {
+			os.Remove(lockFile)
+		}
+	}
+}
+
+// lockedDependency is a dependency pinned by Chart.lock.
+type lockedDependency struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+}
+
+// lockSatisfied reports whether the chart has a Chart.lock covering every
+// dependency of Chart.yaml and each locked version is present in charts/.
+func lockSatisfied(chartPath string, dependencies []chartDependency) bool {
+	data, err := os.ReadFile(filepath.Join(chartPath, "Chart.lock"))
+	if err != nil {
+		return false
+	}
+	var lock struct {
+		Dependencies []lockedDependency `yaml:"dependencies"`
+	}
+	if err := yaml.Unmarshal(data, &lock); err != nil || len(lock.Dependencies) != len(dependencies) {
+		return false
+	}
+
+	locked := make(map[string]bool)
+	for _, dependency := range lock.Dependencies {
+		locked[dependency.Name] = true
+		archive := filepath.Join(chartPath, "charts", dependency.Name+"-"+dependency.Version+".tgz")
+		unpacked := filepath.Join(chartPath, "charts", dependency.Name, "Chart.yaml")
+		if !fileExists(archive) && !fileExists(unpacked) {
+			return false
+		}
+	}
+	for _, dependency := range dependencies {
+		if !locked[dependency.Name] {
+			return false
+		}
+	}
+	return true
+}
+
+// missingDependencies returns the names of the dependencies with neither a
+// directory nor an archive of any version in the chart's charts/ directory.
+func missingDependencies(chartPath string, dependencies []chartDependency) []string {
+	var missing []string
+	for _, dependency := range dependencies {
+		chartsDir := filepath.Join(chartPath, "charts")
+		archives, _ := filepath.Glob(filepath.Join(chartsDir, dependency.Name+"-[0-9]*.tgz"))
+		if len(archives) == 0 && !fileExists(filepath.Join(chartsDir, dependency.Name, "Chart.yaml")) {
+			missing = append(missing, dependency.Name)
+		}
+	}
+	return missing
+}
+
+// dependencyKey identifies the downloaded dependencies of a chart by its
+// declared dependencies and Chart.lock. Charts with local dependencies are not
+// cacheable since those change without Chart.yaml changing.
+func dependencyKey(chartPath string, dependencies []chartDependency) (string, bool) {
+	hash := sha256.New()
+	for _, dependency := range dependencies {
+		if dependency.Repository == "" || strings.HasPrefix(dependency.Repository, "file://") {
+			return "", false
+		}
+		fmt.Fprintf(hash, "%s\x00%s\x00%s\n", dependency.Name, dependency.Version, dependency.Repository)
+	}
+	if lock, err := os.ReadFile(filepath.Join(chartPath, "Chart.lock")); err == nil {
+		hash.Write(lock)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), true
+}
+
+// restoreDependencies copies cached archives into the chart's charts/
+// directory, and the cached Chart.lock if the chart has none.
+func restoreDependencies(entryDir, chartPath string) error {
+	entries, err := os.ReadDir(entryDir)
+	if err != nil {
+		return err
+	}
+	chartsDir := filepath.Join(chartPath, "charts")
+	if err := os.MkdirAll(chartsDir, 0755); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		switch {
+		case entry.Name() == "Chart.lock":
+			if !fileExists(filepath.Join(chartPath, "Chart.lock")) {
+				if err := copyFile(filepath.Join(entryDir, entry.Name()), filepath.Join(chartPath, "Chart.lock")); err != nil {
+					return err
+				}
+			}
+		case strings.HasSuffix(entry.Name(), ".tgz"):
+			if err := copyFile(filepath.Join(entryDir, entry.Name()), filepath.Join(chartsDir, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// storeDependencies caches the archives in the chart's charts/ directory and
+// its Chart.lock under entryDir. The entry is written to a temporary
+// directory first, so concurrent scans never see a partial entry.
+func storeDependencies(chartPath, entryDir string) error {
+	if err := os.MkdirAll(filepath.Dir(entryDir), 0755); err != nil {
+		return err
+	}
+	tempDir, err := os.MkdirTemp(filepath.Dir(entryDir), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+
+	chartsDir := filepath.Join(chartPath, "charts")
+	entries, err := os.ReadDir(chartsDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".tgz") {
+			if err := copyFile(filepath.Join(chartsDir, entry.Name()), filepath.Join(tempDir, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	if err := copyFile(filepath.Join(chartPath, "Chart.lock"), filepath.Join(tempDir, "Chart.lock")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := os.Rename(tempDir, entryDir); err != nil && !fileExists(entryDir) {
+		return err
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,382 @@
+package renderer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+const dependencyChart = `apiVersion: v2
+name: app
+version: 0.1.0
+dependencies:
+  - name: redis
+    version: 18.1.0
+    repository: https://charts.bitnami.com/bitnami
+`
+
+const dependencyLock = `dependencies:
+- name: redis
+  repository: https://charts.bitnami.com/bitnami
+  version: 18.1.0
+digest: sha256:0000
+generated: "2024-01-01T00:00:00Z"
+`
+
+func TestLockSatisfied(t *testing.T) {
+	chartDir := t.TempDir()
+	writeChart(t, chartDir, dependencyChart, "")
+	dependencies, err := chartDependencies(filepath.Join(chartDir, "Chart.yaml"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if lockSatisfied(chartDir, dependencies) {
+		t.Errorf("Expected a chart without Chart.lock not to be satisfied")
+	}
+
+	os.WriteFile(filepath.Join(chartDir, "Chart.lock"), []byte(dependencyLock), 0644)
+	if lockSatisfied(chartDir, dependencies) {
+		t.Errorf("Expected a chart missing the locked archive not to be satisfied")
+	}
+
+	os.MkdirAll(filepath.Join(chartDir, "charts"), 0755)
+	os.WriteFile(filepath.Join(chartDir, "charts", "redis-18.0.0.tgz"), []byte("old"), 0644)
+	if lockSatisfied(chartDir, dependencies) {
+		t.Errorf("Expected a chart vendoring another version not to be satisfied")
+	}
+
+	os.WriteFile(filepath.Join(chartDir, "charts", "redis-18.1.0.tgz"), []byte("archive"), 0644)
+	if !lockSatisfied(chartDir, dependencies) {
+		t.Errorf("Expected a chart vendoring the locked version to be satisfied")
+	}
+}
+
+func TestDependencyKey(t *testing.T) {
+	chartDir := t.TempDir()
+	dependencies := []chartDependency{{Name: "redis", Version: "18.1.0", Repository: "https://charts.bitnami.com/bitnami"}}
+
+	key, ok := dependencyKey(chartDir, dependencies)
+	if !ok || key == "" {
+		t.Fatalf("Expected remote dependencies to be cacheable")
+	}
+
+	os.WriteFile(filepath.Join(chartDir, "Chart.lock"), []byte(dependencyLock), 0644)
+	if locked, _ := dependencyKey(chartDir, dependencies); locked == key {
+		t.Errorf("Expected Chart.lock to change the key")
+	}
+
+	dependencies[0].Version = "18.2.0"
+	if changed, _ := dependencyKey(chartDir, dependencies); changed == key {
+		t.Errorf("Expected the version to change the key")
+	}
+
+	local := append(dependencies, chartDependency{Name: "common", Repository: "file://../common"})
+	if _, ok := dependencyKey(chartDir, local); ok {
+		t.Errorf("Expected file:// dependencies not to be cacheable")
+	}
+}
+
+func TestStoreAndRestoreDependencies(t *testing.T) {
+	chartDir := t.TempDir()
+	os.MkdirAll(filepath.Join(chartDir, "charts"), 0755)
+	os.WriteFile(filepath.Join(chartDir, "charts", "redis-18.1.0.tgz"), []byte("archive"), 0644)
+	os.WriteFile(filepath.Join(chartDir, "Chart.lock"), []byte(dependencyLock), 0644)
+
+	entryDir := filepath.Join(t.TempDir(), "dependencies", "key")
+	if err := storeDependencies(chartDir, entryDir); err != nil {
+		t.Fatalf("Unexpected error storing dependencies: %v", err)
+	}
+
+	otherDir := t.TempDir()
+	if err := restoreDependencies(entryDir, otherDir); err != nil {
+		t.Fatalf("Unexpected error restoring dependencies: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(otherDir, "charts", "redis-18.1.0.tgz")); err != nil || string(data) != "archive" {
+		t.Errorf("Expected the archive to be restored, got '%s' (%v)", data, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(otherDir, "Chart.lock")); err != nil || string(data) != dependencyLock {
+		t.Errorf("Expected Chart.lock to be restored, got '%s' (%v)", data, err)
+	}
+
+	if err := restoreDependencies(filepath.Join(t.TempDir(), "missing"), otherDir); err == nil {
+		t.Errorf("Expected an error restoring a missing cache entry")
+	}
+}
+
+func TestHandleDependenciesUsesCacheAndCleansUp(t *testing.T) {
+	chartDir := t.TempDir()
+	writeChart(t, chartDir, dependencyChart, "")
+	os.MkdirAll(filepath.Join(chartDir, "charts", "vendored"), 0755)
+
+	dependencies, _ := chartDependencies(filepath.Join(chartDir, "Chart.yaml"))
+	key, _ := dependencyKey(chartDir, dependencies)
+	cacheDir := t.TempDir()
+	entryDir := filepath.Join(cacheDir, "dependencies", key)
+	os.MkdirAll(entryDir, 0755)
+	os.WriteFile(filepath.Join(entryDir, "redis-18.1.0.tgz"), []byte("archive"), 0644)
+	os.WriteFile(filepath.Join(entryDir, "Chart.lock"), []byte(dependencyLock), 0644)
+
+	success, errors, cleanup := handleDependencies(chartDir, cacheDir, models.DependenciesConfig{}, nil)
+	if !success {
+		t.Fatalf("Expected the cached dependencies to be used, got %v", errors)
+	}
+	if !fileExists(filepath.Join(chartDir, "charts", "redis-18.1.0.tgz")) {
+		t.Errorf("Expected the cached archive in charts/")
+	}
+
+	cleanup()
+	if fileExists(filepath.Join(chartDir, "charts", "redis-18.1.0.tgz")) || fileExists(filepath.Join(chartDir, "Chart.lock")) {
+		t.Errorf("Expected the restored dependencies to be removed")
+	}
+	if !fileExists(filepath.Join(chartDir, "charts", "vendored")) {
+		t.Errorf("Expected the chart's own charts/ entries to be kept")
+	}
+}
+
+func TestNeedsRepositoryIndex(t *testing.T) {
+	tests := []struct {
+		repository string
+		expected   bool
+	}{
+		{"https://charts.bitnami.com/bitnami", true},
+		{"@bitnami", true},
+		{"oci://registry.example.com/charts", false},
+		{"file://../common", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := needsRepositoryIndex([]chartDependency{{Name: "dep", Repository: tt.repository}}); got != tt.expected {
+			t.Errorf("Expected %v for %q, got %v", tt.expected, tt.repository, got)
+		}
+	}
+}
+
+func TestRepositoryConfigFile(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "repositories.yaml")
+	os.WriteFile(base, []byte(`apiVersion: ""
+repositories:
+  - name: bitnami
+    url: https://charts.bitnami.com/bitnami
+  - name: internal
+    url: https://charts.example.com/internal
+`), 0644)
+	t.Setenv("CHARTS_PASSWORD", "s3cret")
+
+	path, remove, err := repositoryConfigFile(models.DependenciesConfig{RepositoryConfig: base})
+	if err != nil || path != base {
+		t.Errorf("Expected the configured repository config without credentials, got %s (%v)", path, err)
+	}
+	remove()
+
+	path, remove, err = repositoryConfigFile(models.DependenciesConfig{
+		RepositoryConfig: base,
+		Repositories: []models.RepositoryCredentials{
+			{URL: "https://charts.example.com/internal", Username: "ci", Password: "${CHARTS_PASSWORD}"},
+			{URL: "https://charts.example.com/private", Username: "ci", Password: "$CHARTS_PASSWORD"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected a repository config only its owner can read, got %v (%v)", info, err)
+	}
+	data, _ := os.ReadFile(path)
+	var written struct {
+		Repositories []map[string]string `yaml:"repositories"`
+	}
+	if err := yaml.Unmarshal(data, &written); err != nil {
+		t.Fatalf("Expected a valid repository config, got %v", err)
+	}
+	if len(written.Repositories) != 3 {
+		t.Fatalf("Expected the credentials to replace one repository and add another, got %v", written.Repositories)
+	}
+	internal, private := written.Repositories[1], written.Repositories[2]
+	if internal["name"] != "internal" || internal["username"] != "ci" || internal["password"] != "s3cret" {
+		t.Errorf("Expected the credentials on the internal repository, got %v", internal)
+	}
+	if private["name"] != "charts-example-com-private" || private["password"] != "s3cret" {
+		t.Errorf("Expected a repository named after its URL, got %v", private)
+	}
+
+	remove()
+	if fileExists(path) {
+		t.Errorf("Expected the temporary repository config to be removed")
+	}
+}
+
+func TestHandleDependenciesHelmArgs(t *testing.T) {
+	bin := t.TempDir()
+	argsFile := filepath.Join(t.TempDir(), "args")
+	script := "#!/bin/sh\necho \"$@\" > " + argsFile + "\n"
+	if err := os.WriteFile(filepath.Join(bin, "helm"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write helm: %v", err)
+	}
+	t.Setenv("PATH", bin)
+
+	chartDir := t.TempDir()
+	writeChart(t, chartDir, strings.Replace(dependencyChart, "https://charts.bitnami.com/bitnami", "oci://registry.example.com/charts", 1), "")
+	config := models.DependenciesConfig{RepositoryCache: "/ci/helm-cache", RegistryConfig: "/ci/registry.json"}
+	if success, errors, cleanup := handleDependencies(chartDir, "", config, nil); !success {
+		t.Fatalf("Expected the dependencies to be updated, got %v", errors)
+	} else {
+		cleanup()
+	}
+
+	data, _ := os.ReadFile(argsFile)
+	args := strings.Fields(string(data))
+	expected := []string{"dependency", "update", "--repository-cache", "/ci/helm-cache", "--skip-refresh", "--registry-config", "/ci/registry.json", chartDir}
+	if strings.Join(args, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected helm %v, got %v", expected, args)
+	}
+}
+
+func TestHandleDependenciesOffline(t *testing.T) {
+	bin := t.TempDir()
+	ranFile := filepath.Join(t.TempDir(), "ran")
+	if err := os.WriteFile(filepath.Join(bin, "helm"), []byte("#!/bin/sh\ntouch "+ranFile+"\n"), 0755); err != nil {
+		t.Fatalf("Failed to write helm: %v", err)
+	}
+	t.Setenv("PATH", bin)
+
+	chartDir := t.TempDir()
+	writeChart(t, chartDir, dependencyChart, "")
+	config := models.DependenciesConfig{Offline: true}
+	success, errors, _ := handleDependencies(chartDir, "", config, nil)
+	if success || len(errors) != 1 || !strings.Contains(errors[0], "redis") {
+		t.Errorf("Expected redis to be reported missing, got %v %v", success, errors)
+	}
+
+	os.MkdirAll(filepath.Join(chartDir, "charts"), 0755)
+	os.WriteFile(filepath.Join(chartDir, "charts", "redis-17.0.0.tgz"), []byte("archive"), 0644)
+	if success, errors, cleanup := handleDependencies(chartDir, "", config, nil); !success {
+		t.Errorf("Expected the vendored archive to be used, got %v", errors)
+	} else {
+		cleanup()
+	}
+	if !fileExists(filepath.Join(chartDir, "charts", "redis-17.0.0.tgz")) {
+		t.Errorf("Expected the vendored archive to be kept")
+	}
+	if fileExists(ranFile) {
+		t.Errorf("Expected helm not to run in offline mode")
+	}
+}
+
+func TestScanHelmChartOfflineMissingDependencies(t *testing.T) {
+	bin := t.TempDir()
+	ranFile := filepath.Join(t.TempDir(), "ran")
+	if err := os.WriteFile(filepath.Join(bin, "helm"), []byte("#!/bin/sh\ntouch "+ranFile+"\nexit 1\n"), 0755); err != nil {
+		t.Fatalf("Failed to write helm: %v", err)
+	}
+	t.Setenv("PATH", bin)
+
+	chartDir := t.TempDir()
+	writeChart(t, chartDir, dependencyChart, "replicas: 1\n")
+	os.MkdirAll(filepath.Join(chartDir, "templates"), 0755)
+	os.WriteFile(filepath.Join(chartDir, "templates", "deployment.yaml"), []byte("replicas: {{ .Values.replicas }}\nimage: {{ .Values.image }}\n"), 0644)
+
+	result := ScanHelmChart(chartDir, ScanOptions{Config: models.Config{Dependencies: models.DependenciesConfig{Offline: true}}})
+	var warned, undefined bool
+	for _, finding := range result.Findings {
+		switch {
+		case finding.RuleID == dependenciesRuleID && finding.Severity == models.SeverityWarning:
+			warned = true
+		case finding.RuleID == dependenciesRuleID:
+			t.Errorf("Expected dependency findings to be warnings, got %v", finding)
+		case strings.Contains(finding.Message, "image"):
+			undefined = true
+		}
+	}
+	if !warned {
+		t.Errorf("Expected a warning about the missing dependencies, got %v", result.Findings)
+	}
+	if !undefined {
+		t.Errorf("Expected undefined values to be checked without the dependencies, got %v", result.Findings)
+	}
+	if fileExists(ranFile) {
+		t.Errorf("Expected helm not to run for a chart missing dependencies in offline mode")
+	}
+}
+
+func TestCheckLocalDependencies(t *testing.T) {
+	root := t.TempDir()
+	writeChart(t, filepath.Join(root, "common"), "apiVersion: v2\nname: common\nversion: 1.4.0\n", "")
+	writeChart(t, filepath.Join(root, "broken"), "name: [\n", "")
+	chartDir := filepath.Join(root, "web")
+	writeChart(t, chartDir, `apiVersion: v2
+name: web
+version: 0.1.0
+dependencies:
+  - name: common
+    version: ~1.4.0
+    repository: file://../common
+  - name: common
+    alias: legacy
+    version: ^2.0.0
+    repository: file://../common
+  - name: common
+    alias: any
+    repository: file://../common
+  - name: common
+    alias: invalid
+    version: ">=a.b"
+    repository: file://../common
+  - name: shared
+    version: 1.x
+    repository: file://../common
+  - name: missing
+    version: 1.x
+    repository: file://../missing
+  - name: broken
+    version: 1.x
+    repository: file://../broken
+  - name: redis
+    version: ^99.0.0
+    repository: https://charts.example.com
+`, "")
+
+	problems := checkLocalDependencies(chartDir)
+	expected := []string{
+		"Dependency common requires version ^2.0.0, but the chart at file://../common is version 1.4.0",
+		`Dependency common has an invalid version constraint ">=a.b"`,
+		"Dependency shared points at file://../common, which is the chart common",
+		"Dependency missing points at file://../missing, which is not a chart: error reading Chart.yaml",
+		"Dependency broken points at file://../broken, which is not a chart: error parsing Chart.yaml",
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected %d problems, got %v", len(expected), problems)
+	}
+	for i, prefix := range expected {
+		if !strings.HasPrefix(problems[i], prefix) {
+			t.Errorf("Expected problem %d to start with %q, got %q", i, prefix, problems[i])
+		}
+	}
+}
+
+func TestScanHelmChartLocalDependencyMismatch(t *testing.T) {
+	bin := t.TempDir()
+	ranFile := filepath.Join(t.TempDir(), "ran")
+	if err := os.WriteFile(filepath.Join(bin, "helm"), []byte("#!/bin/sh\ntouch "+ranFile+"\n"), 0755); err != nil {
+		t.Fatalf("Failed to write helm: %v", err)
+	}
+	t.Setenv("PATH", bin)
+
+	root := t.TempDir()
+	writeChart(t, filepath.Join(root, "common"), "apiVersion: v2\nname: common\nversion: 1.4.0\n", "")
+	chartDir := filepath.Join(root, "web")
+	writeChart(t, chartDir, "apiVersion: v2\nname: web\nversion: 0.1.0\ndependencies:\n  - name: common\n    version: 2.0.0\n    repository: file://../common\n", "")
+
+	result := ScanHelmChart(chartDir, ScanOptions{Config: models.Config{Dependencies: models.DependenciesConfig{Offline: true}}})
+	if len(result.Findings) != 1 || result.Findings[0].RuleID != dependenciesRuleID || result.Findings[0].Severity != models.SeverityError {
+		t.Fatalf("Expected one dependencies error, got %v", result.Findings)
+	}
+	if fileExists(ranFile) {
+		t.Errorf("Expected helm not to run for a chart with a mismatched local dependency")
+	}
+}
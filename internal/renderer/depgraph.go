@@ -0,0 +1,268 @@
+package renderer
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/Jaydee94/chartscan/internal/finder"
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// DependencyGraph returns the graph of the dependencies of charts, ordered
+// by path, and the cycles between them. file:// dependencies are resolved to
+// the chart directory they point at, which links charts of a monorepo.
+// Charts vendored in the charts/ directory of another chart are left out.
+// With localOnly, dependencies from repositories and registries are left out
+// too.
+func DependencyGraph(charts []finder.ChartInfo, localOnly bool) models.DependencyGraph {
+	byDir := make(map[string]string)
+	for _, chart := range charts {
+		byDir[absPath(chart.Path)] = chart.Path
+	}
+
+	var graph models.DependencyGraph
+	for _, chart := range charts {
+		parent := filepath.Dir(absPath(chart.Path))
+		if _, ok := byDir[filepath.Dir(parent)]; ok && filepath.Base(parent) == "charts" {
+			continue
+		}
+		node := models.ChartNode{Path: chart.Path, Name: chart.Name, Version: chart.Version}
+		dependencies, err := chartDependencies(filepath.Join(chart.Path, "Chart.yaml"))
+		if err != nil {
+			node.Error = err.Error()
+		}
+		for _, dependency := range dependencies {
+			edge := models.ChartDependency{Name: dependency.Name, Version: dependency.Version, Repository: dependency.Repository, Alias: dependency.Alias}
+			if dir, ok := strings.CutPrefix(dependency.Repository, "file://"); ok {
+				if !filepath.IsAbs(dir) {
+					dir = filepath.Join(chart.Path, dir)
+				}
+				edge.Path = filepath.Clean(dir)
+				if path, ok := byDir[absPath(dir)]; ok {
+					edge.Path = path
+				}
+			} else if localOnly {
+				continue
+			}
+			node.Dependencies = append(node.Dependencies, edge)
+		}
+		graph.Charts = append(graph.Charts, node)
+	}
+	slices.SortFunc(graph.Charts, func(a, b models.ChartNode) int { return strings.Compare(a.Path, b.Path) })
+	graph.Cycles = dependencyCycles(graph.Charts)
+	return graph
+}
+
+// absPath returns the absolute form of path, or path itself if there is none.
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// dependencyCycles returns the cycles of the local dependencies between
+// charts, each starting at its chart with the lowest path.
+func dependencyCycles(charts []models.ChartNode) [][]string {
+	nodes := make([]string, 0, len(charts))
+	edges := make(map[string][]string)
+	for _, chart := range charts {
+		nodes = append(nodes, chart.Path)
+		for _, dependency := range chart.Dependencies {
+			if dependency.Path != "" {
+				edges[chart.Path] = append(edges[chart.Path], dependency.Path)
+			}
+		}
+	}
+	return findCycles(nodes, edges)
+}
+
+// findCycles returns the cycles of the directed graph of edges, searched from
+// nodes in order. Each cycle starts and ends at its lowest node.
+func findCycles(nodes []string, edges map[string][]string) [][]string {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int)
+	seen := make(map[string]bool)
+	var cycles [][]string
+	var stack []string
+	var visit func(node string)
+	visit = func(node string) {
+		state[node] = visiting
+		stack = append(stack, node)
+		for _, next := range edges[node] {
+			switch state[next] {
+			case visiting:
+				cycle := slices.Clone(stack[slices.Index(stack, next):])
+				start := slices.Index(cycle, slices.Min(cycle))
+				cycle = append(cycle[start:], cycle[:start]...)
+				cycle = append(cycle, cycle[0])
+				if key := strings.Join(cycle, "\x00"); !seen[key] {
+					seen[key] = true
+					cycles = append(cycles, cycle)
+				}
+			case 0:
+				visit(next)
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[node] = done
+	}
+	for _, node := range nodes {
+		if state[node] == 0 {
+			visit(node)
+		}
+	}
+	return cycles
+}
+
+// inCycle reports whether the dependency of from on to is part of a cycle.
+func inCycle(cycles [][]string, from, to string) bool {
+	for _, cycle := range cycles {
+		for i := 0; i+1 < len(cycle); i++ {
+			if cycle[i] == from && cycle[i+1] == to {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// dependencyLabel names a dependency by its name and version, and its alias
+// when it has one.
+func dependencyLabel(dependency models.ChartDependency) string {
+	label := strings.TrimSpace(dependency.Name + " " + dependency.Version)
+	if dependency.Alias != "" {
+		label += " as " + dependency.Alias
+	}
+	return label
+}
+
+// WriteDependencyGraphText writes the graph as a list of charts, each
+// followed by its dependencies and where they come from.
+func WriteDependencyGraphText(w io.Writer, graph models.DependencyGraph) {
+	for _, chart := range graph.Charts {
+		fmt.Fprintf(w, "%s (%s)\n", chart.Path, strings.TrimSpace(chart.Name+" "+chart.Version))
+		if chart.Error != "" {
+			fmt.Fprintf(w, "  error: %s\n", chart.Error)
+		}
+		for _, dependency := range chart.Dependencies {
+			source := dependency.Repository
+			if dependency.Path != "" {
+				source = dependency.Path
+			}
+			if source == "" {
+				source = "charts/"
+			}
+			fmt.Fprintf(w, "  - %s → %s\n", dependencyLabel(dependency), source)
+		}
+	}
+}
+
+// remoteNode identifies the node of a dependency from a repository or
+// registry, shared by the charts depending on the same chart version.
+func remoteNode(dependency models.ChartDependency) string {
+	return dependency.Repository + "/" + dependency.Name + "@" + dependency.Version
+}
+
+// WriteDependencyGraphDOT writes the graph as a Graphviz graph: charts
+// (boxes) point at the local charts and the remote charts (ellipses) they
+// depend on. Dependencies that are part of a cycle are red.
+func WriteDependencyGraphDOT(w io.Writer, graph models.DependencyGraph) {
+	nodes := make(map[string]string)
+	var edges []string
+	for _, chart := range graph.Charts {
+		nodes[dotQuote(chart.Path)] = fmt.Sprintf("label=%s, shape=box", dotQuote(chart.Path))
+	}
+	for _, chart := range graph.Charts {
+		for _, dependency := range chart.Dependencies {
+			to := dotQuote(dependency.Path)
+			if dependency.Path == "" {
+				to = dotQuote(remoteNode(dependency))
+				nodes[to] = fmt.Sprintf("label=%s, shape=ellipse", dotQuote(strings.TrimSpace(dependency.Name+" "+dependency.Version)))
+			} else if _, ok := nodes[to]; !ok {
+				nodes[to] = fmt.Sprintf("label=%s, shape=box, style=dashed", dotQuote(dependency.Path))
+			}
+			var attributes []string
+			if dependency.Alias != "" {
+				attributes = append(attributes, "label="+dotQuote(dependency.Alias))
+			}
+			if inCycle(graph.Cycles, chart.Path, dependency.Path) {
+				attributes = append(attributes, "color=red")
+			}
+			edge := dotQuote(chart.Path) + " -> " + to
+			if len(attributes) > 0 {
+				edge += " [" + strings.Join(attributes, ", ") + "]"
+			}
+			edges = append(edges, edge)
+		}
+	}
+
+	fmt.Fprintln(w, "digraph \"chart dependencies\" {")
+	fmt.Fprintln(w, "  rankdir=LR;")
+	for _, id := range sortedKeys(nodes) {
+		fmt.Fprintf(w, "  %s [%s];\n", id, nodes[id])
+	}
+	for _, edge := range edges {
+		fmt.Fprintf(w, "  %s;\n", edge)
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// WriteDependencyGraphMermaid writes the graph as a Mermaid flowchart, which
+// GitHub and GitLab render in markdown. Charts are boxes and remote charts
+// rounded; dependencies that are part of a cycle are red.
+func WriteDependencyGraphMermaid(w io.Writer, graph models.DependencyGraph) {
+	ids := make(map[string]string)
+	var nodes []string
+	node := func(key, label, shape string) string {
+		if id, ok := ids[key]; ok {
+			return id
+		}
+		id := fmt.Sprintf("n%d", len(ids))
+		ids[key] = id
+		nodes = append(nodes, id+fmt.Sprintf(shape, mermaidQuote(label)))
+		return id
+	}
+	for _, chart := range graph.Charts {
+		node(chart.Path, chart.Path, "[%s]")
+	}
+
+	var edges, cycleEdges []string
+	for _, chart := range graph.Charts {
+		for _, dependency := range chart.Dependencies {
+			var to string
+			if dependency.Path == "" {
+				to = node(remoteNode(dependency), strings.TrimSpace(dependency.Name+" "+dependency.Version), "(%s)")
+			} else {
+				to = node(dependency.Path, dependency.Path, "[%s]")
+			}
+			arrow := " --> "
+			if dependency.Alias != "" {
+				arrow = " -->|" + mermaidQuote(dependency.Alias) + "| "
+			}
+			if inCycle(graph.Cycles, chart.Path, dependency.Path) {
+				cycleEdges = append(cycleEdges, fmt.Sprint(len(edges)))
+			}
+			edges = append(edges, ids[chart.Path]+arrow+to)
+		}
+	}
+
+	fmt.Fprintln(w, "flowchart LR")
+	for _, line := range append(nodes, edges...) {
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+	if len(cycleEdges) > 0 {
+		fmt.Fprintf(w, "  linkStyle %s stroke:red\n", strings.Join(cycleEdges, ","))
+	}
+}
+
+// mermaidQuote quotes a label for Mermaid.
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
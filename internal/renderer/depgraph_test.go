@@ -0,0 +1,155 @@
+package renderer
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/finder"
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// writeDependencyCharts writes a monorepo whose charts common and lib depend
+// on each other, and returns the discovered charts.
+func writeDependencyCharts(t *testing.T) (string, []finder.ChartInfo) {
+	root := t.TempDir()
+	charts := map[string]string{
+		"web": `dependencies:
+  - name: common
+    version: 1.0.0
+    repository: file://../common
+  - name: redis
+    version: 18.1.0
+    repository: https://charts.bitnami.com/bitnami
+    alias: cache
+`,
+		"common": `dependencies:
+  - name: lib
+    version: 1.0.0
+    repository: file://../lib
+`,
+		"lib": `dependencies:
+  - name: common
+    version: 1.0.0
+    repository: file://../common
+`,
+		"web/charts/vendored": "",
+	}
+	for path, dependencies := range charts {
+		name := filepath.Base(path)
+		writeChart(t, filepath.Join(root, path), "apiVersion: v2\nname: "+name+"\nversion: 1.0.0\n"+dependencies, "")
+	}
+	found, err := finder.FindHelmCharts(root)
+	if err != nil {
+		t.Fatalf("Failed to find charts: %v", err)
+	}
+	return root, found
+}
+
+func TestDependencyGraph(t *testing.T) {
+	root, charts := writeDependencyCharts(t)
+	path := func(name string) string { return filepath.Join(root, name) }
+
+	graph := DependencyGraph(charts, false)
+	var paths []string
+	for _, chart := range graph.Charts {
+		paths = append(paths, chart.Path)
+	}
+	expected := []string{path("common"), path("lib"), path("web")}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Expected the charts %v without vendored ones, got %v", expected, paths)
+	}
+
+	web := graph.Charts[2].Dependencies
+	if len(web) != 2 || web[0].Path != path("common") || web[1].Path != "" || web[1].Alias != "cache" {
+		t.Errorf("Expected web to depend on the local common chart and on redis, got %+v", web)
+	}
+
+	expectedCycles := [][]string{{path("common"), path("lib"), path("common")}}
+	if !reflect.DeepEqual(graph.Cycles, expectedCycles) {
+		t.Errorf("Expected cycles %v, got %v", expectedCycles, graph.Cycles)
+	}
+
+	if local := DependencyGraph(charts, true); len(local.Charts[2].Dependencies) != 1 {
+		t.Errorf("Expected only local dependencies, got %+v", local.Charts[2].Dependencies)
+	}
+}
+
+func TestDependencyCycles(t *testing.T) {
+	node := func(path string, dependencies ...string) models.ChartNode {
+		chart := models.ChartNode{Path: path}
+		for _, dependency := range dependencies {
+			chart.Dependencies = append(chart.Dependencies, models.ChartDependency{Name: dependency, Path: dependency})
+		}
+		return chart
+	}
+	tests := []struct {
+		name     string
+		charts   []models.ChartNode
+		expected [][]string
+	}{
+		{"acyclic", []models.ChartNode{node("a", "b", "c"), node("b", "c"), node("c")}, nil},
+		{"self", []models.ChartNode{node("a", "a")}, [][]string{{"a", "a"}}},
+		{"rotated", []models.ChartNode{node("c", "a"), node("a", "b"), node("b", "c")}, [][]string{{"a", "b", "c", "a"}}},
+		{"two", []models.ChartNode{node("a", "b"), node("b", "a", "c"), node("c", "d"), node("d", "c")}, [][]string{{"a", "b", "a"}, {"c", "d", "c"}}},
+	}
+	for _, tt := range tests {
+		if got := dependencyCycles(tt.charts); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%s: Expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestWriteDependencyGraph(t *testing.T) {
+	graph := models.DependencyGraph{
+		Charts: []models.ChartNode{
+			{Path: "charts/common", Name: "common", Version: "1.0.0", Dependencies: []models.ChartDependency{{Name: "lib", Version: "1.0.0", Repository: "file://../lib", Path: "charts/lib"}}},
+			{Path: "charts/lib", Name: "lib", Version: "1.0.0", Dependencies: []models.ChartDependency{{Name: "common", Version: "1.0.0", Repository: "file://../common", Path: "charts/common"}}},
+			{Path: "charts/web", Name: "web", Version: "2.0.0", Dependencies: []models.ChartDependency{
+				{Name: "common", Version: "1.0.0", Repository: "file://../common", Path: "charts/common"},
+				{Name: "redis", Version: "18.1.0", Repository: "https://charts.bitnami.com/bitnami", Alias: "cache"},
+			}},
+		},
+		Cycles: [][]string{{"charts/common", "charts/lib", "charts/common"}},
+	}
+
+	var text bytes.Buffer
+	WriteDependencyGraphText(&text, graph)
+	for _, line := range []string{"charts/web (web 2.0.0)", "  - common 1.0.0 → charts/common", "  - redis 18.1.0 as cache → https://charts.bitnami.com/bitnami"} {
+		if !strings.Contains(text.String(), line+"\n") {
+			t.Errorf("Expected the line %q, got:\n%s", line, text.String())
+		}
+	}
+
+	var dot bytes.Buffer
+	WriteDependencyGraphDOT(&dot, graph)
+	for _, line := range []string{
+		`"charts/common" -> "charts/lib" [color=red];`,
+		`"charts/web" -> "charts/common";`,
+		`"https://charts.bitnami.com/bitnami/redis@18.1.0" [label="redis 18.1.0", shape=ellipse];`,
+		`"charts/web" -> "https://charts.bitnami.com/bitnami/redis@18.1.0" [label="cache"];`,
+	} {
+		if !strings.Contains(dot.String(), line) {
+			t.Errorf("Expected %q in the DOT graph, got:\n%s", line, dot.String())
+		}
+	}
+
+	var mermaid bytes.Buffer
+	WriteDependencyGraphMermaid(&mermaid, graph)
+	expected := `flowchart LR
+  n0["charts/common"]
+  n1["charts/lib"]
+  n2["charts/web"]
+  n3("redis 18.1.0")
+  n0 --> n1
+  n1 --> n0
+  n2 --> n0
+  n2 -->|"cache"| n3
+  linkStyle 0,1 stroke:red
+`
+	if mermaid.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, mermaid.String())
+	}
+}
@@ -0,0 +1,139 @@
+package renderer
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+	"github.com/Jaydee94/chartscan/internal/rules"
+	"github.com/Jaydee94/chartscan/internal/strvals"
+)
+
+// determinismRuleID is the ID of the findings of resources whose rendering
+// differs between runs of helm template.
+const determinismRuleID = "determinism"
+
+// checkDeterminism renders the chart twice with the same values and reports
+// every resource whose manifest differs between the renderings, e.g. because
+// of randAlphaNum, uuidv4 or now. GitOps tools render the chart on every
+// sync, so such resources never stop drifting. Manifests are compared as
+// objects, so differences in key order do not count. Rendering failures are
+// left to helm lint and the manifest checks.
+func checkDeterminism(chartPath string, valuesFiles []string, setValues strvals.Overrides, config models.Config, log *scanLog) []models.Finding {
+	var renderings [2]map[string]rules.Manifest
+	for i := range renderings {
+		rendered, err := renderChart(config.ReleaseName, config.Namespace, chartPath, valuesFiles, setValues, config.Validation.KubeVersion, log)
+		if err == nil {
+			rendered, err = postRender(rendered, config.PostRenderer, log)
+		}
+		var manifests []rules.Manifest
+		if err == nil {
+			manifests, err = rules.ParseManifests(rendered)
+		}
+		if err != nil {
+			log.printf("rendering %d failed, skipping the determinism check: %v", i+1, err)
+			return nil
+		}
+		renderings[i] = manifestsByResource(manifests)
+	}
+
+	keys := make(map[string]bool)
+	for _, rendering := range renderings {
+		for key := range rendering {
+			keys[key] = true
+		}
+	}
+	var findings []models.Finding
+	for _, key := range sortedKeys(keys) {
+		first, inFirst := renderings[0][key]
+		second, inSecond := renderings[1][key]
+		manifest := first
+		var message string
+		switch {
+		case !inFirst:
+			manifest, message = second, "is only rendered by some runs of helm template"
+		case !inSecond:
+			message = "is only rendered by some runs of helm template"
+		default:
+			path := firstDifference(first.Object, second.Object, "")
+			if path == "" {
+				continue
+			}
+			message = fmt.Sprintf("renders differently on every run at %s", path)
+		}
+		findings = append(findings, models.Finding{
+			RuleID:   determinismRuleID,
+			Severity: models.SeverityWarning,
+			Message:  message + "; GitOps tools will report it out of sync after every render",
+			Resource: manifest.Resource(),
+			File:     manifest.Source,
+		})
+	}
+	return findings
+}
+
+// manifestsByResource indexes manifests by namespace, kind and name. Manifests
+// sharing them are told apart by their position.
+func manifestsByResource(manifests []rules.Manifest) map[string]rules.Manifest {
+	byResource := make(map[string]rules.Manifest)
+	for _, m := range manifests {
+		key := m.Namespace + "/" + m.Resource()
+		for i := 2; ; i++ {
+			if _, ok := byResource[key]; !ok {
+				break
+			}
+			key = fmt.Sprintf("%s/%s#%d", m.Namespace, m.Resource(), i)
+		}
+		byResource[key] = m
+	}
+	return byResource
+}
+
+// firstDifference returns the dotted path of the first field, in key order,
+// whose value differs between a and b, or "" if they are equal. path is the
+// path of a and b themselves.
+func firstDifference(a, b interface{}, path string) string {
+	switch a := a.(type) {
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(a)+len(b))
+		for key := range a {
+			keys = append(keys, key)
+		}
+		for key := range b {
+			if _, ok := a[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if diff := firstDifference(a[key], b[key], strings.TrimPrefix(path+"."+key, ".")); diff != "" {
+				return diff
+			}
+		}
+		return ""
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			break
+		}
+		for i := range a {
+			if diff := firstDifference(a[i], b[i], fmt.Sprintf("%s[%d]", path, i)); diff != "" {
+				return diff
+			}
+		}
+		return ""
+	}
+	if reflect.DeepEqual(a, b) {
+		return ""
+	}
+	if path == "" {
+		return "."
+	}
+	return path
+}
@@ -0,0 +1,93 @@
+package renderer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+	"github.com/Jaydee94/chartscan/internal/strvals"
+)
+
+func TestCheckDeterminism(t *testing.T) {
+	bin := t.TempDir()
+	counter := filepath.Join(t.TempDir(), "renders")
+	// The fake helm renders a Secret with a new password every time, a
+	// ConfigMap whose keys come in a different order every time and, on the
+	// second render only, a Job.
+	script := `#!/bin/sh
+echo x >> ` + counter + `
+n=$(wc -l < ` + counter + ` | tr -d ' ')
+cat <<EOF
+---
+# Source: web/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: web
+data:
+  password: secret-$n
+---
+# Source: web/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web
+EOF
+if [ "$n" = 1 ]; then
+  printf 'data:\n  a: "1"\n  b: "2"\n'
+else
+  printf 'data:\n  b: "2"\n  a: "1"\n---\napiVersion: batch/v1\nkind: Job\nmetadata:\n  name: migrate\n'
+fi
+`
+	if err := os.WriteFile(filepath.Join(bin, "helm"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write helm: %v", err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	findings := checkDeterminism(t.TempDir(), nil, strvals.Overrides{}, models.Config{}, nil)
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %v", findings)
+	}
+	if findings[0].Resource != "Job/migrate" || findings[0].Message != "is only rendered by some runs of helm template; GitOps tools will report it out of sync after every render" {
+		t.Errorf("Expected the Job rendered once to be reported, got %v", findings[0])
+	}
+	secret := findings[1]
+	if secret.Resource != "Secret/web" || secret.File != "web/templates/secret.yaml" || secret.Severity != models.SeverityWarning {
+		t.Errorf("Expected a warning on Secret/web from its template, got %v", secret)
+	}
+	if secret.Message != "renders differently on every run at data.password; GitOps tools will report it out of sync after every render" {
+		t.Errorf("Expected the differing field to be named, got %q", secret.Message)
+	}
+}
+
+func TestCheckDeterminismRenderError(t *testing.T) {
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "helm"), []byte("#!/bin/sh\necho 'Error: parse error' >&2\nexit 1\n"), 0755); err != nil {
+		t.Fatalf("Failed to write helm: %v", err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	if findings := checkDeterminism(t.TempDir(), nil, strvals.Overrides{}, models.Config{}, nil); len(findings) != 0 {
+		t.Errorf("Expected rendering errors to be left to the other stages, got %v", findings)
+	}
+}
+
+func TestFirstDifference(t *testing.T) {
+	tests := []struct {
+		a, b     interface{}
+		expected string
+	}{
+		{map[string]interface{}{"a": 1, "b": []interface{}{"x"}}, map[string]interface{}{"b": []interface{}{"x"}, "a": 1}, ""},
+		{map[string]interface{}{"spec": map[string]interface{}{"a": 1, "b": 2}}, map[string]interface{}{"spec": map[string]interface{}{"a": 1, "b": 3}}, "spec.b"},
+		{map[string]interface{}{"a": 1}, map[string]interface{}{"a": 1, "z": 2}, "z"},
+		{map[string]interface{}{"args": []interface{}{"x", "y"}}, map[string]interface{}{"args": []interface{}{"x", "z"}}, "args[1]"},
+		{map[string]interface{}{"args": []interface{}{"x"}}, map[string]interface{}{"args": []interface{}{"x", "y"}}, "args"},
+		{"a", "b", "."},
+	}
+	for _, test := range tests {
+		if got := firstDifference(test.a, test.b, ""); got != test.expected {
+			t.Errorf("Expected %q for %v and %v, got %q", test.expected, test.a, test.b, got)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package renderer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+	"gopkg.in/yaml.v3"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// renderedResource is one document of a chart's rendered output.
+type renderedResource struct {
+	id    string
+	lines []string
+}
+
+// splitRendered splits the output of helm template into its documents,
+// identified by kind, namespace and name. Documents without an object, such
+// as empty templates, are dropped.
+func splitRendered(rendered string) []renderedResource {
+	var resources []renderedResource
+	var current []string
+	flush := func() {
+		var meta struct {
+			Kind     string `yaml:"kind"`
+			Metadata struct {
+				Name      string `yaml:"name"`
+				Namespace string `yaml:"namespace"`
+			} `yaml:"metadata"`
+		}
+		text := strings.Join(current, "\n")
+		current = nil
+		if err := yaml.Unmarshal([]byte(text), &meta); err != nil || meta.Kind == "" {
+			return
+		}
+		id := meta.Kind + "/" + meta.Metadata.Name
+		if meta.Metadata.Namespace != "" {
+			id += " in namespace " + meta.Metadata.Namespace
+		}
+		resources = append(resources, renderedResource{id: id, lines: strings.Split(strings.TrimRight(text, "\n"), "\n")})
+	}
+	for _, line := range strings.Split(rendered, "\n") {
+		if strings.TrimRight(line, " ") == "---" {
+			flush()
+			continue
+		}
+		current = append(current, line)
+	}
+	flush()
+	return resources
+}
+
+// WriteManifestDiff writes a unified diff of two renderings of a chart to w,
+// one section per added, removed or changed resource, and reports whether
+// they differ. Resources are matched by kind, namespace and name, so
+// reordered templates show no changes. fromLabel and toLabel name the two
+// renderings in the headers. The output is colored unless color output is
+// disabled, e.g. because stdout is not a terminal.
+func WriteManifestDiff(w io.Writer, fromLabel, from, toLabel, to string) bool {
+	fromResources, toResources := splitRendered(from), splitRendered(to)
+	toByID := make(map[string]renderedResource, len(toResources))
+	for _, r := range toResources {
+		toByID[r.id] = r
+	}
+
+	var ids []string
+	fromByID := make(map[string]renderedResource, len(fromResources))
+	for _, r := range fromResources {
+		fromByID[r.id] = r
+		ids = append(ids, r.id)
+	}
+	for _, r := range toResources {
+		if _, ok := fromByID[r.id]; !ok {
+			ids = append(ids, r.id)
+		}
+	}
+
+	bold := color.New(color.Bold).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+
+	changed := false
+	for _, id := range ids {
+		hunks := diffHunks(fromByID[id].lines, toByID[id].lines, diffContext)
+		if len(hunks) == 0 {
+			continue
+		}
+		changed = true
+		fromHeader, toHeader := fromLabel+": "+id, toLabel+": "+id
+		if _, ok := fromByID[id]; !ok {
+			fromHeader = "/dev/null"
+		}
+		if _, ok := toByID[id]; !ok {
+			toHeader = "/dev/null"
+		}
+		fmt.Fprintln(w, bold("--- "+fromHeader))
+		fmt.Fprintln(w, bold("+++ "+toHeader))
+		for _, hunk := range hunks {
+			fmt.Fprintln(w, cyan(hunk.header()))
+			for _, line := range hunk.lines {
+				switch line[0] {
+				case '-':
+					fmt.Fprintln(w, red(line))
+				case '+':
+					fmt.Fprintln(w, green(line))
+				default:
+					fmt.Fprintln(w, line)
+				}
+			}
+		}
+	}
+	return changed
+}
+
+// diffHunk is a group of changes with their surrounding context. Lines are
+// prefixed with " ", "-" or "+".
+type diffHunk struct {
+	fromStart, fromCount int
+	toStart, toCount     int
+	lines                []string
+}
+
+// header formats the hunk's "@@ -l,s +l,s @@" line.
+func (h diffHunk) header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.fromStart, h.fromCount), hunkRange(h.toStart, h.toCount))
+}
+
+// hunkRange formats a hunk range the way diff -u does: an empty range names
+// the line before it.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// diffOp is one line of an edit script: kept (' '), removed ('-') or added
+// ('+').
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// diffLines returns the shortest edit script turning a into b, computed from
+// their longest common subsequence.
+func diffLines(a, b []string) []diffOp {
+	// lcs[i][j] is the length of the LCS of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	return ops
+}
+
+// diffHunks groups the changes between a and b into hunks with up to context
+// unchanged lines around them. Changes closer than twice the context share a
+// hunk.
+func diffHunks(a, b []string, context int) []diffHunk {
+	ops := diffLines(a, b)
+
+	var changes []int
+	for k, op := range ops {
+		if op.kind != ' ' {
+			changes = append(changes, k)
+		}
+	}
+
+	var hunks []diffHunk
+	for len(changes) > 0 {
+		last := 0
+		for last+1 < len(changes) && changes[last+1]-changes[last] <= 2*context+1 {
+			last++
+		}
+		begin := max(changes[0]-context, 0)
+		end := min(changes[last]+context+1, len(ops))
+
+		hunk := diffHunk{fromStart: 1, toStart: 1}
+		for _, op := range ops[:begin] {
+			if op.kind != '+' {
+				hunk.fromStart++
+			}
+			if op.kind != '-' {
+				hunk.toStart++
+			}
+		}
+		for _, op := range ops[begin:end] {
+			hunk.add(op)
+		}
+		hunks = append(hunks, hunk)
+		changes = changes[last+1:]
+	}
+	return hunks
+}
+
+// add appends op to the hunk and counts it.
+func (h *diffHunk) add(op diffOp) {
+	h.lines = append(h.lines, string(op.kind)+op.line)
+	if op.kind != '+' {
+		h.fromCount++
+	}
+	if op.kind != '-' {
+		h.toCount++
+	}
+}
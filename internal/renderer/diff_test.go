@@ -0,0 +1,132 @@
+package renderer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+const stagingManifests = `---
+# Source: web/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+    - port: 80
+---
+# Source: web/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+        - name: web
+          image: nginx:1.25
+---
+# Source: web/templates/debug.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: debug
+`
+
+const productionManifests = `---
+# Source: web/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+        - name: web
+          image: nginx:1.25
+---
+# Source: web/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+    - port: 80
+---
+# Source: web/templates/pdb.yaml
+apiVersion: policy/v1
+kind: PodDisruptionBudget
+metadata:
+  name: web
+  namespace: prod
+`
+
+func TestWriteManifestDiff(t *testing.T) {
+	color.NoColor = true
+
+	var output bytes.Buffer
+	if !WriteManifestDiff(&output, "staging", stagingManifests, "production", productionManifests) {
+		t.Fatalf("Expected the renderings to differ")
+	}
+	expected := `--- staging: Deployment/web
++++ production: Deployment/web
+@@ -4,7 +4,7 @@
+ metadata:
+   name: web
+ spec:
+-  replicas: 1
++  replicas: 3
+   template:
+     spec:
+       containers:
+--- staging: ConfigMap/debug
++++ /dev/null
+@@ -1,5 +0,0 @@
+-# Source: web/templates/debug.yaml
+-apiVersion: v1
+-kind: ConfigMap
+-metadata:
+-  name: debug
+--- /dev/null
++++ production: PodDisruptionBudget/web in namespace prod
+@@ -0,0 +1,6 @@
++# Source: web/templates/pdb.yaml
++apiVersion: policy/v1
++kind: PodDisruptionBudget
++metadata:
++  name: web
++  namespace: prod
+`
+	if output.String() != expected {
+		t.Errorf("Expected diff:\n%s\ngot:\n%s", expected, output.String())
+	}
+
+	output.Reset()
+	if WriteManifestDiff(&output, "a", stagingManifests, "b", stagingManifests) || output.Len() != 0 {
+		t.Errorf("Expected no diff for identical renderings, got:\n%s", output.String())
+	}
+}
+
+func TestDiffHunks(t *testing.T) {
+	a := strings.Split("1 2 3 4 5 6 7 8 9 10 11 12 13 14 15 16 17", " ")
+	b := strings.Split("1 2 x 4 5 6 7 8 9 y 11 12 13 14 15 16 17 z", " ")
+
+	hunks := diffHunks(a, b, 3)
+	if len(hunks) != 2 {
+		t.Fatalf("Expected 2 hunks, got %d: %v", len(hunks), hunks)
+	}
+	if hunks[0].header() != "@@ -1,13 +1,13 @@" {
+		t.Errorf("Expected the changes at lines 3 and 10 to share a hunk, got %s", hunks[0].header())
+	}
+	if hunks[1].header() != "@@ -15,3 +15,4 @@" || hunks[1].lines[3] != "+z" {
+		t.Errorf("Expected a hunk appending z, got %s %v", hunks[1].header(), hunks[1].lines)
+	}
+}
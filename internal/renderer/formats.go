@@ -0,0 +1,51 @@
+package renderer
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// BuiltinFormats are the output formats of scan and watch implemented by
+// chartscan itself.
+var BuiltinFormats = []string{"pretty", "json", "yaml", "junit", "markdown", "github", "teamcity", "azuredevops"}
+
+// FormatFunc writes the results of a scan that took duration to w.
+type FormatFunc func(w io.Writer, results []models.Result, duration time.Duration) error
+
+var customFormats = make(map[string]FormatFunc)
+
+// RegisterFormat adds an output format to scan and watch under name. It is
+// meant to be called from init functions. Names must be non-empty and must
+// not repeat a built-in or registered format.
+func RegisterFormat(name string, format FormatFunc) error {
+	if name == "" || format == nil {
+		return fmt.Errorf("output format needs a name and a function")
+	}
+	if _, ok := customFormats[name]; ok || slices.Contains(BuiltinFormats, name) {
+		return fmt.Errorf("output format %q is already registered", name)
+	}
+	customFormats[name] = format
+	return nil
+}
+
+// LookupFormat returns the registered output format name.
+func LookupFormat(name string) (FormatFunc, bool) {
+	format, ok := customFormats[name]
+	return format, ok
+}
+
+// FormatNames returns the built-in output formats followed by the registered
+// ones in alphabetical order.
+func FormatNames() []string {
+	names := make([]string, 0, len(customFormats))
+	for name := range customFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append(slices.Clone(BuiltinFormats), names...)
+}
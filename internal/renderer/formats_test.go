@@ -0,0 +1,38 @@
+package renderer
+
+import (
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestRegisterFormat(t *testing.T) {
+	defer delete(customFormats, "csv")
+	csv := func(w io.Writer, results []models.Result, duration time.Duration) error { return nil }
+
+	if err := RegisterFormat("csv", csv); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := LookupFormat("csv"); !ok {
+		t.Errorf("Expected csv to be registered")
+	}
+	if _, ok := LookupFormat("json"); ok {
+		t.Errorf("Expected built-in formats not to be looked up")
+	}
+	expected := []string{"pretty", "json", "yaml", "junit", "markdown", "github", "teamcity", "azuredevops", "csv"}
+	if names := FormatNames(); !slices.Equal(names, expected) {
+		t.Errorf("Expected %v, got %v", expected, names)
+	}
+
+	for _, name := range []string{"csv", "json", ""} {
+		if err := RegisterFormat(name, csv); err == nil {
+			t.Errorf("Expected an error registering %q", name)
+		}
+	}
+	if err := RegisterFormat("tsv", nil); err == nil {
+		t.Errorf("Expected an error registering a nil format")
+	}
+}
@@ -0,0 +1,292 @@
+package renderer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+	"github.com/Jaydee94/chartscan/internal/rules"
+	"github.com/Jaydee94/chartscan/internal/schema"
+	"github.com/Jaydee94/chartscan/internal/strvals"
+	"github.com/Jaydee94/chartscan/internal/valuessource"
+	"gopkg.in/yaml.v3"
+)
+
+// fuzzRuleID is the ID of the template crashes found by Fuzz.
+const fuzzRuleID = "fuzz"
+
+// defaultFuzzLimit bounds the mutations rendered per chart when
+// FuzzOptions.Limit is not set.
+const defaultFuzzLimit = 200
+
+// FuzzOptions configures Fuzz.
+type FuzzOptions struct {
+	ValuesFiles []string
+	SetValues   strvals.Overrides
+	CacheDir    string
+	// Dependencies configures how the chart's dependencies are downloaded.
+	Dependencies models.DependenciesConfig
+	// Limit is the maximum number of mutations rendered; it defaults to 200.
+	Limit int
+}
+
+// FuzzResult is the outcome of fuzzing a chart's values.
+type FuzzResult struct {
+	// Mutations is the number of mutations rendered and Total the number
+	// generated.
+	Mutations int
+	Total     int
+	// Crashes are the distinct template failures, each named after the first
+	// mutation that caused it.
+	Crashes []models.Finding
+}
+
+// mutation replaces the value at path, or removes it when drop is set.
+type mutation struct {
+	path  []string
+	value interface{}
+	drop  bool
+}
+
+// String describes the mutation the way crashes name it.
+func (m mutation) String() string {
+	key := strings.Join(m.path, ".")
+	if m.drop {
+		return key + " removed"
+	}
+	value, _ := json.Marshal(m.value)
+	return key + "=" + string(value)
+}
+
+// values returns the mutation as a values overlay. A null removes the key
+// from the chart's values when helm merges it.
+func (m mutation) values() map[string]interface{} {
+	var value interface{}
+	if !m.drop {
+		value = m.value
+	}
+	values := make(map[string]interface{})
+	current := values
+	for _, key := range m.path[:len(m.path)-1] {
+		next := make(map[string]interface{})
+		current[key] = next
+		current = next
+	}
+	current[m.path[len(m.path)-1]] = value
+	return values
+}
+
+// Fuzz renders the chart at chartPath once per mutation of its values and
+// reports the mutations that crash a template, e.g. with "nil pointer
+// evaluating". Values used by the templates are dropped, emptied and given
+// another type, unless the chart's values.schema.json rules the mutation out.
+// Templates that reject a mutation with required or fail handle it
+// gracefully and are not reported. It fails when the chart does not render
+// with its unmutated values.
+func Fuzz(chartPath string, opts FuzzOptions) (FuzzResult, error) {
+	var result FuzzResult
+
+	valuesFiles, err := valuessource.Resolve(opts.ValuesFiles)
+	if err != nil {
+		return result, err
+	}
+	opts.ValuesFiles = valuesFiles
+
+	success, errs, cleanup := handleDependencies(chartPath, opts.CacheDir, opts.Dependencies, nil)
+	if !success {
+		return result, fmt.Errorf("error building dependencies: %s", strings.Join(errs, "; "))
+	}
+	defer cleanup()
+
+	if _, err := renderChart("", "", chartPath, opts.ValuesFiles, opts.SetValues, "", nil); err != nil {
+		return result, fmt.Errorf("chart does not render with the given values: %v", err)
+	}
+
+	values, loadErrors := loadAndMergeValues(chartPath, opts.ValuesFiles)
+	if len(loadErrors) > 0 {
+		return result, errors.New(strings.Join(loadErrors, "; "))
+	}
+	if err := opts.SetValues.MergeInto(values); err != nil {
+		return result, err
+	}
+	used, _ := UsedValuePaths(chartPath)
+	doc, err := loadValuesSchema(chartPath)
+	if err != nil {
+		return result, err
+	}
+
+	mutations := fuzzMutations(values, used, doc)
+	result.Total = len(mutations)
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultFuzzLimit
+	}
+	if len(mutations) > limit {
+		mutations = mutations[:limit]
+	}
+	result.Mutations = len(mutations)
+
+	dir, err := os.MkdirTemp("", "chartscan-fuzz")
+	if err != nil {
+		return result, fmt.Errorf("error creating temp dir for mutations: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	seen := make(map[string]bool)
+	for i, m := range mutations {
+		data, err := yaml.Marshal(m.values())
+		if err != nil {
+			return result, fmt.Errorf("error encoding %s: %v", m, err)
+		}
+		// The values may hold secrets fetched from values sources.
+		file := filepath.Join(dir, fmt.Sprintf("mutation-%d.yaml", i))
+		if err := os.WriteFile(file, data, 0600); err != nil {
+			return result, fmt.Errorf("error writing mutation values: %v", err)
+		}
+
+		valuesFiles := append(append([]string{}, opts.ValuesFiles...), file)
+		for _, crash := range renderMutation(chartPath, valuesFiles, opts.SetValues) {
+			key := crash.File + "\x00" + crash.Message
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			crash.Message = valuessource.Redact(fmt.Sprintf("with %s: %s", m, crash.Message))
+			result.Crashes = append(result.Crashes, crash)
+		}
+	}
+	return result, nil
+}
+
+// renderMutation renders the chart with valuesFiles and returns the template
+// crashes, leaving out deliberate failures of required and fail and values
+// rejected by the schema.
+func renderMutation(chartPath string, valuesFiles []string, setValues strvals.Overrides) []models.Finding {
+	rendered, err := renderChart("", "", chartPath, valuesFiles, setValues, "", nil)
+	if err != nil {
+		var helmErr *helmError
+		if !errors.As(err, &helmErr) {
+			return errorFindings(fuzzRuleID, []string{fmt.Sprintf("Error rendering chart: %v", err)})
+		}
+		findings := parseHelmOutput(fuzzRuleID, helmErr.stderr)
+		if len(findings) == 0 {
+			return errorFindings(fuzzRuleID, []string{fmt.Sprintf("Error rendering chart: %v", err)})
+		}
+		var crashes []models.Finding
+		for _, finding := range findings {
+			if !gracefulFailure(finding.Message) {
+				crashes = append(crashes, finding)
+			}
+		}
+		return crashes
+	}
+
+	if _, err := rules.ParseManifests(rendered); err != nil {
+		return errorFindings(fuzzRuleID, []string{err.Error()})
+	}
+	return nil
+}
+
+// gracefulFailure reports whether a helm error is a chart rejecting its
+// values on purpose rather than a template crash.
+func gracefulFailure(message string) bool {
+	return strings.Contains(message, "execution error at (") ||
+		strings.Contains(message, helmSchemaError)
+}
+
+// loadValuesSchema reads the chart's values.schema.json, or returns nil when
+// there is none.
+func loadValuesSchema(chartPath string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(filepath.Join(chartPath, "values.schema.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading values.schema.json: %v", err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("values.schema.json is not valid JSON: %v", err)
+	}
+	return doc, nil
+}
+
+// fuzzMutations returns the mutations of the values at the used paths and
+// their parents that doc, if set, allows, ordered by path: dropping the value,
+// emptying strings and lists, and replacing scalars and lists with a value of
+// another type. Maps keep their type, since helm does not let a values file
+// replace a map of the chart's defaults with a scalar.
+func fuzzMutations(values map[string]interface{}, used [][]string, doc map[string]interface{}) []mutation {
+	paths := make(map[string][]string)
+	for _, path := range used {
+		for i := 1; i <= len(path); i++ {
+			paths[strings.Join(path[:i], "\x00")] = path[:i]
+		}
+	}
+	keys := make([]string, 0, len(paths))
+	for key := range paths {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var mutations []mutation
+	for _, key := range keys {
+		path := paths[key]
+		value := lookupValue(path, values)
+		if value == nil {
+			continue
+		}
+		var property map[string]interface{}
+		required := false
+		if doc != nil {
+			property, required = schema.Property(doc, path)
+		}
+		typed := property != nil && schema.ConstrainsType(property)
+
+		if !required {
+			mutations = append(mutations, mutation{path: path, drop: true})
+		}
+		switch v := value.(type) {
+		case string:
+			if v != "" && !constrainsString(property) {
+				mutations = append(mutations, mutation{path: path, value: ""})
+			}
+			if !typed {
+				mutations = append(mutations, mutation{path: path, value: 1})
+			}
+		case []interface{}:
+			if len(v) > 0 && minimum(property, "minItems") < 1 {
+				mutations = append(mutations, mutation{path: path, value: []interface{}{}})
+			}
+			if !typed {
+				mutations = append(mutations, mutation{path: path, value: "chartscan"})
+			}
+		case bool, int, int64, float64:
+			if !typed {
+				mutations = append(mutations, mutation{path: path, value: "chartscan"})
+			}
+		}
+	}
+	return mutations
+}
+
+// constrainsString reports whether property rules out an empty string.
+func constrainsString(property map[string]interface{}) bool {
+	for _, keyword := range []string{"enum", "const", "pattern", "format"} {
+		if _, ok := property[keyword]; ok {
+			return true
+		}
+	}
+	return minimum(property, "minLength") >= 1
+}
+
+// minimum returns the numeric keyword of property, or 0.
+func minimum(property map[string]interface{}, keyword string) float64 {
+	n, _ := property[keyword].(float64)
+	return n
+}
@@ -0,0 +1,78 @@
+package renderer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFuzzMutations(t *testing.T) {
+	values := map[string]interface{}{
+		"image": map[string]interface{}{
+			"repository": "nginx",
+			"tag":        "1.27",
+		},
+		"hosts":    []interface{}{"example.org"},
+		"replicas": 2,
+		"unused":   "x",
+	}
+	used := [][]string{{"image", "repository"}, {"image", "tag"}, {"hosts"}, {"replicas"}, {"missing", "key"}}
+	doc := map[string]interface{}{
+		"properties": map[string]interface{}{
+			"image": map[string]interface{}{
+				"required": []interface{}{"repository"},
+				"properties": map[string]interface{}{
+					"repository": map[string]interface{}{"type": "string", "minLength": 1.0},
+				},
+			},
+			"replicas": map[string]interface{}{"type": "integer"},
+		},
+	}
+
+	var got []string
+	for _, m := range fuzzMutations(values, used, doc) {
+		got = append(got, m.String())
+	}
+	expected := []string{
+		"hosts removed",
+		`hosts=[]`,
+		`hosts="chartscan"`,
+		"image removed",
+		"image.tag removed",
+		`image.tag=""`,
+		"image.tag=1",
+		"replicas removed",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected mutations %v, got %v", expected, got)
+	}
+
+	got = nil
+	for _, m := range fuzzMutations(values, used, nil) {
+		got = append(got, m.String())
+	}
+	if len(got) != 12 {
+		t.Errorf("Expected 12 mutations without a schema, got %v", got)
+	}
+}
+
+func TestMutationValues(t *testing.T) {
+	m := mutation{path: []string{"image", "tag"}, drop: true}
+	expected := map[string]interface{}{"image": map[string]interface{}{"tag": nil}}
+	if got := m.values(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected values %v, got %v", expected, got)
+	}
+}
+
+func TestGracefulFailure(t *testing.T) {
+	tests := map[string]bool{
+		`execution error at (web/templates/deployment.yaml:12:20): image.repository is required`:                                                                  true,
+		`values don't meet the specifications of the schema(s) in the following chart(s)`:                                                                         true,
+		`template: web/templates/deployment.yaml:8:28: executing "web/templates/deployment.yaml" at <.Values.image.tag>: nil pointer evaluating interface {}.tag`: false,
+		`YAML parse error on web/templates/service.yaml: error converting YAML to JSON`:                                                                           false,
+	}
+	for message, expected := range tests {
+		if got := gracefulFailure(message); got != expected {
+			t.Errorf("Expected %v for %q, got %v", expected, message, got)
+		}
+	}
+}
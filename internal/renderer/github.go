@@ -0,0 +1,103 @@
+package renderer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// githubCommands maps finding severities to GitHub Actions workflow commands.
+var githubCommands = map[string]string{
+	models.SeverityError:   "error",
+	models.SeverityWarning: "warning",
+	models.SeverityInfo:    "notice",
+}
+
+// PrintResultsGitHub writes the findings to w as GitHub Actions workflow
+// commands, so they show up as annotations on the lines of the pull request
+// diff: one ::error, ::warning or ::notice command per finding, followed by
+// a summary line.
+func PrintResultsGitHub(w io.Writer, results []models.Result, duration time.Duration) {
+	var validCharts, invalidCharts int
+	for _, result := range models.FlattenResults(results) {
+		if result.Success {
+			validCharts++
+		} else {
+			invalidCharts++
+		}
+		for _, finding := range result.Findings {
+			var properties []string
+			if file := annotationFile(result.ChartPath, finding.File); file != "" {
+				properties = append(properties, "file="+escapeGitHubProperty(file))
+				if finding.Line > 0 {
+					properties = append(properties, fmt.Sprintf("line=%d", finding.Line))
+				}
+			}
+			properties = append(properties, "title="+escapeGitHubProperty("chartscan "+finding.RuleID))
+
+			command, ok := githubCommands[finding.Severity]
+			if !ok {
+				command = "error"
+			}
+			fmt.Fprintf(w, "::%s %s::%s\n", command, strings.Join(properties, ","), escapeGitHubData(annotationMessage(result, finding)))
+		}
+	}
+	fmt.Fprintf(w, "chartscan: %d valid charts, %d invalid charts scanned in %v\n", validCharts, invalidCharts, duration.Round(time.Millisecond))
+}
+
+// annotationMessage returns the message of a finding prefixed with its chart
+// and resource, for CI systems that show findings out of the report's context.
+func annotationMessage(result models.Result, finding models.Finding) string {
+	message := finding.Message
+	if finding.Resource != "" {
+		message = finding.Resource + ": " + message
+	}
+	message = result.ChartPath + ": " + message
+	if finding.Blame != nil {
+		message += " (last changed by " + finding.Blame.String() + ")"
+	}
+	return message
+}
+
+// annotationFile returns the path of a finding's file relative to the working
+// directory, which CI systems resolve against the repository root. Findings name
+// files relative to the working directory or, for rendered manifests, to the
+// directory containing the chart. Files that exist in neither place are
+// returned unchanged.
+func annotationFile(chartPath, file string) string {
+	if file == "" {
+		return ""
+	}
+	if filepath.IsAbs(file) {
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, file); err == nil && !strings.HasPrefix(rel, "..") {
+				return filepath.ToSlash(rel)
+			}
+		}
+		return filepath.ToSlash(file)
+	}
+	if _, err := os.Stat(file); err == nil {
+		return filepath.ToSlash(filepath.Clean(file))
+	}
+	if candidate := filepath.Join(filepath.Dir(chartPath), file); candidate != file {
+		if _, err := os.Stat(candidate); err == nil {
+			return filepath.ToSlash(candidate)
+		}
+	}
+	return filepath.ToSlash(file)
+}
+
+// escapeGitHubData escapes the message of a workflow command.
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeGitHubProperty escapes a property value of a workflow command.
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,47 @@
+package renderer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestWriteResultsGitHub(t *testing.T) {
+	dir := t.TempDir()
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	os.MkdirAll(filepath.Join("charts", "web", "templates"), 0755)
+	os.WriteFile(filepath.Join("charts", "web", "templates", "deployment.yaml"), []byte("kind: Deployment\n"), 0644)
+
+	results := []models.Result{
+		{ChartPath: "charts/web", Findings: []models.Finding{
+			{RuleID: UndefinedValueID, Severity: models.SeverityError, File: "charts/web/templates/deployment.yaml", Line: 3, Message: "Undefined value: 'a'\n100% broken"},
+			{RuleID: "latest-image-tag", Severity: models.SeverityWarning, Resource: "Deployment/web", File: "web/templates/deployment.yaml", Message: "uses latest"},
+			{RuleID: "duplicate-values", Severity: models.SeverityInfo, File: "envs/prod.yaml", Line: 2, Message: "repeats a default"},
+		}},
+		{ChartPath: "charts/api", Success: true},
+	}
+
+	var output bytes.Buffer
+	PrintResultsGitHub(&output, results, 1500*time.Millisecond)
+	expected := "::error file=charts/web/templates/deployment.yaml,line=3,title=chartscan undefined-value::charts/web: Undefined value: 'a'%0A100%25 broken\n" +
+		"::warning file=charts/web/templates/deployment.yaml,title=chartscan latest-image-tag::charts/web: Deployment/web: uses latest\n" +
+		"::notice file=envs/prod.yaml,line=2,title=chartscan duplicate-values::charts/web: repeats a default\n" +
+		"chartscan: 1 valid charts, 1 invalid charts scanned in 1.5s\n"
+	if output.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, output.String())
+	}
+}
+
+func TestEscapeGitHubProperty(t *testing.T) {
+	if got := escapeGitHubProperty("a:b,c%"); got != "a%3Ab%2Cc%25" {
+		t.Errorf("Expected 'a%%3Ab%%2Cc%%25', got '%s'", got)
+	}
+}
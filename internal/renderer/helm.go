@@ -0,0 +1,77 @@
+package renderer
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MinHelmVersion is the oldest helm release scans support, the first with
+// OCI registry support enabled by default.
+const MinHelmVersion = "3.8"
+
+// helmBinary is the helm executable scans run.
+var helmBinary = "helm"
+
+// SetHelmBinary makes scans run the helm executable at path, or the one
+// named path in PATH. An empty path restores the default, helm.
+func SetHelmBinary(path string) {
+	if path == "" {
+		path = "helm"
+	}
+	helmBinary = path
+}
+
+// helmCommand returns the command running helm with args.
+func helmCommand(args ...string) *exec.Cmd {
+	return exec.Command(helmBinary, args...)
+}
+
+// HelmVersion returns the short version of the helm binary, e.g.
+// v3.15.2+g1a500d5, or an empty string when helm cannot be run.
+func HelmVersion() string {
+	output, err := helmCommand("version", "--short").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+var helmVersionRegex = regexp.MustCompile(`v?(\d+)\.(\d+)\.(\d+)`)
+
+// CheckHelmVersion returns the version of the helm binary, and an error when
+// it cannot be run or is older than MinHelmVersion, so scans fail with a
+// clear message rather than with the errors helm commands give.
+func CheckHelmVersion() (string, error) {
+	if _, err := exec.LookPath(helmBinary); err != nil {
+		return "", fmt.Errorf("helm >= %s required, but %s was not found: install Helm or set --helm-binary", MinHelmVersion, helmBinary)
+	}
+	version := HelmVersion()
+	if version == "" {
+		return "", fmt.Errorf("helm >= %s required, but `%s version` failed", MinHelmVersion, helmBinary)
+	}
+	match := helmVersionRegex.FindStringSubmatch(version)
+	if match == nil {
+		return version, fmt.Errorf("helm >= %s required, but cannot parse the version %q of %s", MinHelmVersion, version, helmBinary)
+	}
+	if !helmVersionAtLeast(match, MinHelmVersion) {
+		return version, fmt.Errorf("helm >= %s required, found %s.%s", MinHelmVersion, match[1], match[2])
+	}
+	return version, nil
+}
+
+// helmVersionAtLeast reports whether the major and minor version of match,
+// a helmVersionRegex match, are at least those of minimum.
+func helmVersionAtLeast(match []string, minimum string) bool {
+	minMajor, minMinor, _ := strings.Cut(minimum, ".")
+	for _, pair := range [][2]string{{match[1], minMajor}, {match[2], minMinor}} {
+		got, _ := strconv.Atoi(pair[0])
+		want, _ := strconv.Atoi(pair[1])
+		if got != want {
+			return got > want
+		}
+	}
+	return true
+}
@@ -0,0 +1,69 @@
+package renderer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFakeHelm writes a helm script printing version to dir. It appends the
+// arguments of every run to the file args in dir.
+func writeFakeHelm(t *testing.T, dir, version string) string {
+	t.Helper()
+	path := filepath.Join(dir, "helm")
+	script := "#!/bin/sh\necho \"$@\" >> '" + filepath.Join(dir, "args") + "'\necho '" + version + "'\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write helm: %v", err)
+	}
+	return path
+}
+
+func TestHelmVersion(t *testing.T) {
+	bin := t.TempDir()
+	writeFakeHelm(t, bin, "v3.15.2+g1a500d5")
+	t.Setenv("PATH", bin)
+
+	if version := HelmVersion(); version != "v3.15.2+g1a500d5" {
+		t.Errorf("Expected v3.15.2+g1a500d5, got %q", version)
+	}
+
+	t.Setenv("PATH", t.TempDir())
+	if version := HelmVersion(); version != "" {
+		t.Errorf("Expected no version without helm, got %q", version)
+	}
+}
+
+func TestCheckHelmVersion(t *testing.T) {
+	t.Cleanup(func() { SetHelmBinary("") })
+	t.Setenv("PATH", t.TempDir())
+
+	tests := []struct {
+		version string
+		err     string
+	}{
+		{"v3.15.2+g1a500d5", ""},
+		{"v3.8.0+gd14138", ""},
+		{"v4.0.1+g7f5a9b2", ""},
+		{"v3.2.4+g0ad800e", "helm >= 3.8 required, found 3.2"},
+		{"unknown", "cannot parse the version"},
+	}
+	for _, tt := range tests {
+		SetHelmBinary(writeFakeHelm(t, t.TempDir(), tt.version))
+		version, err := CheckHelmVersion()
+		if tt.err == "" {
+			if err != nil || version != tt.version {
+				t.Errorf("Expected %s to be supported, got %q and %v", tt.version, version, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Expected an error containing %q for %s, got %v", tt.err, tt.version, err)
+		}
+	}
+
+	SetHelmBinary("")
+	if _, err := CheckHelmVersion(); err == nil || !strings.Contains(err.Error(), "helm was not found") {
+		t.Errorf("Expected an error for a missing helm, got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package renderer
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// helmError is the failure of a helm command, keeping its stderr so the
+// messages in it can be reported as findings.
+type helmError struct {
+	command string
+	err     error
+	stderr  string
+}
+
+func (e *helmError) Error() string {
+	return fmt.Sprintf("error running helm %s: %v\nstderr: %s", e.command, e.err, e.stderr)
+}
+
+// lintMessage matches a message of helm lint, such as
+// "[ERROR] templates/: parse error at (web/templates/a.yaml:3): ...".
+var lintMessage = regexp.MustCompile(`^\[(ERROR|WARNING|INFO)\]\s+(?:([^\s:]+):\s+)?(.*)$`)
+
+// lintSummary matches the final line helm lint prints for failed charts,
+// which repeats what the messages already say.
+var lintSummary = regexp.MustCompile(`^\d+ chart\(s\) linted, \d+ chart\(s\) failed$`)
+
+// helmLocation matches the template location helm names in error messages:
+// "template: web/templates/a.yaml:12:20: executing ...", "parse error at
+// (web/templates/_helpers.tpl:3): ..." or "YAML parse error on
+// web/templates/a.yaml: ...". The line of YAML parse errors refers to the
+// rendered output and is not captured.
+var helmLocation = regexp.MustCompile(`(?:template: |error at \(|YAML parse error on )([^\s:()"]+\.[A-Za-z]+)(?::(\d+))?`)
+
+// parseHelmOutput returns a finding under ruleID for every message in the
+// output of helm: "[LEVEL] file: message" lines of helm lint, with their
+// level, and "Error: message" lines as errors. Lines following a message up
+// to the next blank line continue it, so multi-line errors are kept whole.
+// The template file and line named in a message are used as its location
+// when helm gives none.
+func parseHelmOutput(ruleID, output string) []models.Finding {
+	var findings []models.Finding
+	continued := false
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if match := lintMessage.FindStringSubmatch(trimmed); match != nil {
+			findings = append(findings, models.Finding{
+				RuleID:   ruleID,
+				Severity: strings.ToLower(match[1]),
+				Message:  match[3],
+				File:     match[2],
+			})
+			continued = true
+			continue
+		}
+		if message, found := strings.CutPrefix(trimmed, "Error: "); found {
+			continued = !lintSummary.MatchString(message)
+			if continued {
+				findings = append(findings, models.Finding{RuleID: ruleID, Severity: models.SeverityError, Message: message})
+			}
+			continue
+		}
+		switch {
+		case trimmed == "", strings.HasPrefix(trimmed, "==> "):
+			continued = false
+		case strings.HasPrefix(trimmed, "Use --debug flag"):
+			// helm's hint on YAML parse errors.
+		case continued:
+			findings[len(findings)-1].Message += "\n" + trimmed
+		}
+	}
+
+	for i := range findings {
+		if findings[i].Line > 0 || (findings[i].File != "" && !strings.HasSuffix(findings[i].File, "/")) {
+			continue
+		}
+		if match := helmLocation.FindStringSubmatch(findings[i].Message); match != nil {
+			findings[i].File = match[1]
+			findings[i].Line, _ = strconv.Atoi(match[2])
+		}
+	}
+	return findings
+}
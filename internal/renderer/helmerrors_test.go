@@ -0,0 +1,75 @@
+package renderer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestParseHelmOutput_Template(t *testing.T) {
+	stderr := `walk.go:74: found symbolic link in path: /charts/web/shared resolves to /charts/shared
+Error: template: web/templates/deployment.yaml:12:20: executing "web/templates/deployment.yaml" at <.Values.image.tag>: nil pointer evaluating interface {}.tag
+`
+	findings := parseHelmOutput(renderRuleID, stderr)
+	expected := []models.Finding{{
+		RuleID:   renderRuleID,
+		Severity: models.SeverityError,
+		File:     "web/templates/deployment.yaml",
+		Line:     12,
+		Message:  `template: web/templates/deployment.yaml:12:20: executing "web/templates/deployment.yaml" at <.Values.image.tag>: nil pointer evaluating interface {}.tag`,
+	}}
+	if len(findings) != len(expected) || findings[0] != expected[0] {
+		t.Errorf("Expected %v, got %v", expected, findings)
+	}
+}
+
+func TestParseHelmOutput_MultiLine(t *testing.T) {
+	stderr := `Error: YAML parse error on web/templates/configmap.yaml: error converting YAML to JSON: yaml: line 5: did not find expected key
+Use --debug flag to render out invalid YAML
+`
+	findings := parseHelmOutput(renderRuleID, stderr)
+	if len(findings) != 1 {
+		t.Fatalf("Expected 1 finding, got %v", findings)
+	}
+	if findings[0].File != "web/templates/configmap.yaml" || findings[0].Line != 0 {
+		t.Errorf("Expected web/templates/configmap.yaml without a line, got %s:%d", findings[0].File, findings[0].Line)
+	}
+	if strings.Contains(findings[0].Message, "--debug") {
+		t.Errorf("Expected the --debug hint to be dropped, got '%s'", findings[0].Message)
+	}
+
+	output := `==> Linting charts/web
+[ERROR] values.yaml: - replicas: Invalid type. Expected: integer, given: string
+- image: tag is required
+[ERROR] templates/: execution error at (web/templates/deployment.yaml:5:4): image.tag is required
+
+Error: 1 chart(s) linted, 1 chart(s) failed
+`
+	var got []string
+	for _, finding := range parseHelmOutput(lintRuleID, output) {
+		got = append(got, finding.String())
+	}
+	expectedLint := []string{
+		"[error] helm-lint: values.yaml: - replicas: Invalid type. Expected: integer, given: string\n- image: tag is required",
+		"[error] helm-lint: web/templates/deployment.yaml:5: execution error at (web/templates/deployment.yaml:5:4): image.tag is required",
+	}
+	if strings.Join(got, "\n") != strings.Join(expectedLint, "\n") {
+		t.Errorf("Expected findings:\n%s\ngot:\n%s", strings.Join(expectedLint, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestParseHelmOutput_LoadError(t *testing.T) {
+	findings := parseHelmOutput(lintRuleID, "==> Linting charts/web\nError: unable to load chart: Chart.yaml file is missing\n")
+	if len(findings) != 1 || findings[0].Message != "unable to load chart: Chart.yaml file is missing" {
+		t.Errorf("Expected the load error to be reported, got %v", findings)
+	}
+}
+
+func TestHelmError(t *testing.T) {
+	err := &helmError{command: "template", err: errors.New("exit status 1"), stderr: "Error: boom\n"}
+	if err.Error() != "error running helm template: exit status 1\nstderr: Error: boom\n" {
+		t.Errorf("Unexpected error message: %q", err.Error())
+	}
+}
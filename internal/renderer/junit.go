@@ -0,0 +1,151 @@
+package renderer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// junitChecks are the test cases of every chart, which pass unless a finding
+// of their rule ID fails them. Other rules get a test case once they report
+// findings.
+var junitChecks = []string{lintRuleID, UndefinedValueID, validationRuleID}
+
+// junitTimestamp is the timestamp format of JUnit reports, ISO 8601 without
+// a time zone.
+const junitTimestamp = "2006-01-02T15:04:05"
+
+// JUnitOptions describe the scan a JUnit report is written for. Each chart's
+// suite carries the helm version, the target Kubernetes version, the
+// environment and the experiments as properties; the chart's own environment
+// takes precedence.
+type JUnitOptions struct {
+	Started     time.Time
+	HelmVersion string
+	KubeVersion string
+	Environment string
+	Experiments []string
+}
+
+// PrintResultsJUnit writes a JUnit XML report of results to w, with one test
+// suite per chart and subchart and one test case per check. duration is the
+// time of the whole scan. Charts whose scan did not finish have a single
+// skipped test case, and their suite is marked as incomplete.
+func PrintResultsJUnit(w io.Writer, results []models.Result, duration time.Duration, opts JUnitOptions) error {
+	report := models.TestSuites{
+		Name:      "ChartScan",
+		Time:      fmt.Sprintf("%.3f", duration.Seconds()),
+		Timestamp: opts.Started.Format(junitTimestamp),
+	}
+	for _, result := range models.FlattenResults(results) {
+		suite := junitSuite(result, opts)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Skipped += suite.Skipped
+		report.Suites = append(report.Suites, suite)
+	}
+
+	output, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, xml.Header+string(output))
+	return nil
+}
+
+// junitSuite returns the test suite of a single chart.
+func junitSuite(result models.Result, opts JUnitOptions) models.TestSuite {
+	name := result.ChartPath
+	environment := opts.Environment
+	if result.Environment != "" {
+		name += " (" + result.Environment + ")"
+		environment = result.Environment
+	}
+	suite := models.TestSuite{
+		Name:      name,
+		Time:      fmt.Sprintf("%.3f", result.DurationSeconds),
+		Timestamp: opts.Started.Format(junitTimestamp),
+	}
+	for _, property := range []models.Property{
+		{Name: "helm.version", Value: opts.HelmVersion},
+		{Name: "kube.version", Value: opts.KubeVersion},
+		{Name: "environment", Value: environment},
+		{Name: "experiments", Value: strings.Join(opts.Experiments, ",")},
+	} {
+		if property.Value != "" {
+			suite.Properties = append(suite.Properties, property)
+		}
+	}
+
+	if result.Incomplete {
+		var messages []string
+		for _, finding := range result.Findings {
+			messages = append(messages, finding.Message)
+		}
+		suite.TestCases = []models.TestCase{{
+			Name:      scanRuleID,
+			ClassName: result.ChartPath,
+			Skipped:   &models.Skipped{Message: strings.Join(messages, "\n")},
+		}}
+		suite.Tests, suite.Skipped = 1, 1
+		suite.Properties = append(suite.Properties, models.Property{Name: "incomplete", Value: "true"})
+		return suite
+	}
+
+	byRule := make(map[string][]models.Finding)
+	for _, finding := range result.Findings {
+		byRule[finding.RuleID] = append(byRule[finding.RuleID], finding)
+	}
+	var others []string
+	for ruleID := range byRule {
+		if !slices.Contains(junitChecks, ruleID) {
+			others = append(others, ruleID)
+		}
+	}
+	sort.Strings(others)
+
+	for _, ruleID := range append(append([]string{}, junitChecks...), others...) {
+		testCase := junitTestCase(result.ChartPath, ruleID, byRule[ruleID])
+		if testCase.Failure != nil {
+			suite.Failures++
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+	suite.Tests = len(suite.TestCases)
+	return suite
+}
+
+// junitTestCase returns the test case of ruleID for the chart at chartPath,
+// which fails when findings include errors. Findings of lower severity are
+// kept as its output.
+func junitTestCase(chartPath, ruleID string, findings []models.Finding) models.TestCase {
+	testCase := models.TestCase{Name: ruleID, ClassName: chartPath}
+	if len(findings) == 0 {
+		return testCase
+	}
+
+	var lines []string
+	var firstError *models.Finding
+	for i, finding := range findings {
+		lines = append(lines, finding.String())
+		if finding.Severity == models.SeverityError && firstError == nil {
+			firstError = &findings[i]
+		}
+	}
+	if firstError == nil {
+		testCase.SystemOut = &models.SystemOut{Content: strings.Join(lines, "\n")}
+		return testCase
+	}
+	testCase.Failure = &models.Failure{
+		Message: firstError.Message,
+		Type:    ruleID,
+		Content: strings.Join(lines, "\n"),
+	}
+	return testCase
+}
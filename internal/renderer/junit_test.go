@@ -0,0 +1,77 @@
+package renderer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestPrintResultsJUnit(t *testing.T) {
+	results := []models.Result{
+		{ChartPath: "charts/web", Success: true, DurationSeconds: 1.25, Findings: []models.Finding{
+			{RuleID: "image-pinning", Severity: models.SeverityWarning, Message: "image nginx is not pinned"},
+		}},
+		{ChartPath: "charts/api", Environment: "production", Findings: []models.Finding{
+			{RuleID: UndefinedValueID, Severity: models.SeverityError, Message: "Undefined value: 'image.tag'"},
+			{RuleID: UndefinedValueID, Severity: models.SeverityError, Message: "Undefined value: 'port'"},
+		}},
+		IncompleteResult("charts/db", "the scan timed out after 10m0s"),
+	}
+	opts := JUnitOptions{
+		Started:     time.Date(2026, 3, 4, 12, 30, 0, 0, time.UTC),
+		HelmVersion: "v3.15.2+g1a500d5",
+		KubeVersion: "1.29",
+		Environment: "staging",
+		Experiments: []string{"schema-v2", "ast-parser"},
+	}
+
+	var out bytes.Buffer
+	if err := PrintResultsJUnit(&out, results, 3*time.Second, opts); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var report models.TestSuites
+	if err := xml.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("Expected a valid report, got %v:\n%s", err, out.String())
+	}
+
+	if report.Tests != 8 || report.Failures != 1 || report.Skipped != 1 || report.Time != "3.000" || report.Timestamp != "2026-03-04T12:30:00" {
+		t.Errorf("Expected 8 tests, 1 failure and 1 skipped in 3.000s at 2026-03-04T12:30:00, got %+v", report)
+	}
+	if len(report.Suites) != 3 {
+		t.Fatalf("Expected a suite per chart, got %d", len(report.Suites))
+	}
+
+	web := report.Suites[0]
+	var names []string
+	for _, testCase := range web.TestCases {
+		names = append(names, testCase.Name)
+	}
+	if expected := "helm-lint,undefined-value,schema-validation,image-pinning"; strings.Join(names, ",") != expected {
+		t.Errorf("Expected test cases %s, got %s", expected, strings.Join(names, ","))
+	}
+	if web.Time != "1.250" || web.Failures != 0 || web.TestCases[3].SystemOut == nil {
+		t.Errorf("Expected warnings to pass as output, got %+v", web)
+	}
+	if expected := []models.Property{{Name: "helm.version", Value: "v3.15.2+g1a500d5"}, {Name: "kube.version", Value: "1.29"}, {Name: "environment", Value: "staging"}, {Name: "experiments", Value: "schema-v2,ast-parser"}}; !reflect.DeepEqual(web.Properties, expected) {
+		t.Errorf("Expected properties %v, got %v", expected, web.Properties)
+	}
+
+	api := report.Suites[1]
+	if api.Name != "charts/api (production)" || api.Properties[2].Value != "production" {
+		t.Errorf("Expected the suite of the chart's environment, got %s with %v", api.Name, api.Properties)
+	}
+	failure := api.TestCases[1].Failure
+	if failure == nil || failure.Message != "Undefined value: 'image.tag'" || !strings.Contains(failure.Content, "'port'") {
+		t.Errorf("Expected undefined-value to fail with both findings, got %+v", failure)
+	}
+
+	db := report.Suites[2]
+	if len(db.TestCases) != 1 || db.TestCases[0].Skipped == nil || db.Properties[len(db.Properties)-1].Name != "incomplete" {
+		t.Errorf("Expected an incomplete suite with a skipped test case, got %+v", db)
+	}
+}
@@ -0,0 +1,107 @@
+package renderer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+	"github.com/Jaydee94/chartscan/internal/rules"
+)
+
+// PrintResultsMarkdown writes the results to w as GitHub-flavored markdown,
+// ready to be posted as a pull request comment: a results table followed by
+// the summary and the findings breakdown.
+func PrintResultsMarkdown(w io.Writer, results []models.Result, duration time.Duration) {
+	var validCharts, invalidCharts int
+
+	fmt.Fprintln(w, "## ChartScan results")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "| Chart | Status | Details |")
+	fmt.Fprintln(w, "|-------|--------|---------|")
+	for _, result := range models.FlattenResults(results) {
+		chartName, err := getChartName(result.ChartPath)
+		if err != nil {
+			chartName = result.ChartPath
+		}
+
+		status := "✅"
+		if result.Success {
+			validCharts++
+		} else {
+			status = "❌"
+			invalidCharts++
+		}
+
+		var details []string
+		for _, finding := range result.Findings {
+			details = append(details, finding.String())
+		}
+		if suppressed := len(result.SuppressedFindings); suppressed > 0 {
+			details = append(details, fmt.Sprintf("%d suppressed by chartscan:ignore", suppressed))
+		}
+		if skipped := len(result.SkippedFindings); skipped > 0 {
+			details = append(details, fmt.Sprintf("%d skipped by %s", skipped, rules.SkipAnnotation))
+		}
+		var cells []string
+		for _, detail := range details {
+			cells = append(cells, "• "+escapeMarkdownCell(detail))
+		}
+
+		fmt.Fprintf(w, "| %s | %s | %s |\n", escapeMarkdownCell(chartName), status, strings.Join(cells, "<br>"))
+	}
+
+	fmt.Fprintf(w, "\n**Summary:** %d valid charts, %d invalid charts scanned in %v\n", validCharts, invalidCharts, duration.Round(time.Millisecond))
+
+	stats := ComputeStatistics(results)
+	if stats.Skipped > 0 {
+		fmt.Fprintf(w, "\n**Skipped by `%s`:** %d\n", rules.SkipAnnotation, stats.Skipped)
+	}
+	if len(stats.FindingsByRule) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "| Severity | Findings |")
+	fmt.Fprintln(w, "|----------|----------|")
+	for _, severity := range []string{models.SeverityError, models.SeverityWarning, models.SeverityInfo} {
+		if count := stats.FindingsBySeverity[severity]; count > 0 {
+			fmt.Fprintf(w, "| %s | %d |\n", severity, count)
+		}
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "| Rule | Findings |")
+	fmt.Fprintln(w, "|------|----------|")
+	for _, rule := range stats.FindingsByRule[:min(summaryTopN, len(stats.FindingsByRule))] {
+		fmt.Fprintf(w, "| `%s` | %d |\n", rule.RuleID, rule.Count)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "| Chart | Findings |")
+	fmt.Fprintln(w, "|-------|----------|")
+	for _, chart := range stats.ChartsByFindings[:min(summaryTopN, len(stats.ChartsByFindings))] {
+		chartName, err := getChartName(chart.ChartPath)
+		if err != nil {
+			chartName = chart.ChartPath
+		}
+		fmt.Fprintf(w, "| %s | %d |\n", escapeMarkdownCell(chartName), chart.Count)
+	}
+
+	if len(stats.UndefinedValues) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "| Undefined value | Charts | References |")
+		fmt.Fprintln(w, "|-----------------|--------|------------|")
+		for _, value := range stats.UndefinedValues[:min(summaryTopN, len(stats.UndefinedValues))] {
+			fmt.Fprintf(w, "| `%s` | %d | %d |\n", escapeMarkdownCell(value.Key), len(value.Charts), len(value.References))
+		}
+	}
+}
+
+// escapeMarkdownCell makes s safe to use inside a markdown table cell.
+func escapeMarkdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	s = strings.ReplaceAll(s, "\\n", "\n")
+	return strings.ReplaceAll(strings.TrimSpace(s), "\n", "<br>")
+}
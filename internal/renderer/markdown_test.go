@@ -0,0 +1,47 @@
+package renderer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestWriteResultsMarkdown(t *testing.T) {
+	results := []models.Result{
+		{ChartPath: "charts/web", Success: true},
+		{ChartPath: "charts/api", Findings: []models.Finding{
+			{RuleID: UndefinedValueID, Severity: models.SeverityError, File: "x.yaml", Line: 1, Message: "Undefined value: 'a|b'\nsecond line"},
+			{RuleID: "pod-security", Severity: models.SeverityError, Resource: "Deployment/api", Message: "runs as root"},
+		}},
+	}
+
+	var output bytes.Buffer
+	PrintResultsMarkdown(&output, results, 1500*time.Millisecond)
+	markdown := output.String()
+
+	for _, expected := range []string{
+		"| Chart | Status | Details |",
+		"| charts/web | ✅ |  |",
+		"| charts/api | ❌ | • [error] undefined-value: x.yaml:1: Undefined value: 'a\\|b'<br>second line<br>• [error] pod-security: Deployment/api: runs as root |",
+		"**Summary:** 1 valid charts, 1 invalid charts scanned in 1.5s",
+		"| error | 2 |",
+		"| `pod-security` | 1 |",
+		"| charts/api | 2 |",
+	} {
+		if !strings.Contains(markdown, expected) {
+			t.Errorf("Expected markdown to contain %q, got:\n%s", expected, markdown)
+		}
+	}
+}
+
+func TestWriteResultsMarkdown_NoFindings(t *testing.T) {
+	var output bytes.Buffer
+	PrintResultsMarkdown(&output, []models.Result{{ChartPath: "charts/web", Success: true}}, time.Second)
+
+	if strings.Contains(output.String(), "| Rule | Findings |") {
+		t.Errorf("Expected no findings breakdown without findings, got:\n%s", output.String())
+	}
+}
@@ -0,0 +1,87 @@
+package renderer
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/tw"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// PrintEnvironmentMatrix prints a table of the charts of results by the
+// environment they were scanned for, with the status and number of errors
+// and warnings of each chart, subcharts included, per environment. It prints
+// nothing unless results cover several environments.
+func PrintEnvironmentMatrix(w io.Writer, results []models.Result) {
+	var environments, charts []string
+	cells := make(map[string]map[string]string)
+	for _, result := range results {
+		if result.Environment == "" {
+			continue
+		}
+		if !slices.Contains(environments, result.Environment) {
+			environments = append(environments, result.Environment)
+		}
+		if cells[result.ChartPath] == nil {
+			charts = append(charts, result.ChartPath)
+			cells[result.ChartPath] = make(map[string]string)
+		}
+		cells[result.ChartPath][result.Environment] = matrixCell(result)
+	}
+	if len(environments) < 2 {
+		return
+	}
+
+	fmt.Fprintln(w, "\nResults by environment:")
+	table := tablewriter.NewTable(w,
+		tablewriter.WithHeader(append([]string{"Chart"}, environments...)),
+		tablewriter.WithRowAlignment(tw.AlignLeft),
+	)
+	for _, chart := range charts {
+		row := []string{chart}
+		for _, environment := range environments {
+			cell, ok := cells[chart][environment]
+			if !ok {
+				cell = "-"
+			}
+			row = append(row, cell)
+		}
+		table.Append(row) //nolint:errcheck
+	}
+	table.Render() //nolint:errcheck
+}
+
+// matrixCell summarizes the result of a chart in one environment.
+func matrixCell(result models.Result) string {
+	var errors, warnings int
+	for _, r := range models.FlattenResults([]models.Result{result}) {
+		for _, finding := range r.Findings {
+			switch finding.Severity {
+			case models.SeverityError:
+				errors++
+			case models.SeverityWarning:
+				warnings++
+			}
+		}
+	}
+
+	var counts []string
+	if errors > 0 {
+		counts = append(counts, fmt.Sprintf("%d errors", errors))
+	}
+	if warnings > 0 {
+		counts = append(counts, fmt.Sprintf("%d warnings", warnings))
+	}
+	symbol := "✔"
+	if !result.Success {
+		symbol = "✘"
+	}
+	if len(counts) == 0 {
+		return colorSymbol(symbol, result.Success)
+	}
+	return colorSymbol(symbol, result.Success) + " " + strings.Join(counts, ", ")
+}
@@ -0,0 +1,43 @@
+package renderer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestPrintEnvironmentMatrix(t *testing.T) {
+	results := []models.Result{
+		{ChartPath: "charts/web", Environment: "staging", Success: true},
+		{ChartPath: "charts/api", Environment: "staging", Success: true, Findings: []models.Finding{
+			{RuleID: "liveness-probe", Severity: models.SeverityWarning},
+		}},
+		{ChartPath: "charts/web", Environment: "production", Success: false, Findings: []models.Finding{
+			{RuleID: UndefinedValueID, Severity: models.SeverityError},
+		}, Dependencies: []models.Result{{ChartPath: "charts/web/charts/db", Findings: []models.Finding{
+			{RuleID: UndefinedValueID, Severity: models.SeverityError},
+		}}}},
+	}
+
+	var out bytes.Buffer
+	PrintEnvironmentMatrix(&out, results)
+	output := out.String()
+	for _, expected := range []string{"STAGING", "PRODUCTION", "✘ 2 errors", "✔ 1 warnings"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected the matrix to contain %q, got:\n%s", expected, output)
+		}
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if strings.Contains(line, "charts/api") && !strings.Contains(line, " - ") {
+			t.Errorf("Expected a dash for charts/api in production, got %q", line)
+		}
+	}
+
+	out.Reset()
+	PrintEnvironmentMatrix(&out, results[:1])
+	if out.Len() != 0 {
+		t.Errorf("Expected no matrix for a single environment, got:\n%s", out.String())
+	}
+}
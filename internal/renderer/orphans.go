@@ -0,0 +1,102 @@
+package renderer
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// undefinedValuePrefix starts the message of undefined value findings, which
+// quotes the key after it.
+const undefinedValuePrefix = "Undefined value: '"
+
+// UndefinedValues returns the undefined value findings of the chart at
+// chartPath with the chart's values.yaml and valuesFiles applied, like scan
+// reports them but without running helm. Subcharts in the chart's charts
+// directory see the values their parent passes down.
+func UndefinedValues(chartPath string, valuesFiles []string) ([]models.Finding, []string) {
+	valueReferences, errors := ParseTemplates(chartPath)
+
+	values, loadErrors := loadAndMergeValues(chartPath, valuesFiles)
+	errors = append(errors, loadErrors...)
+	if inherited := parentScopedValues(chartPath); inherited != nil {
+		mergeMaps(inherited, values)
+		values = inherited
+	}
+	errors = append(errors, coalesceSubchartValues(chartPath, values)...)
+
+	checked, _ := newSuppressions().filterReferences(valueReferences)
+	return checkValueReferences(checked, values, nil), errors
+}
+
+// undefinedValueKey returns the key of an undefined value finding.
+func undefinedValueKey(finding models.Finding) (string, bool) {
+	if finding.RuleID != UndefinedValueID {
+		return "", false
+	}
+	rest, ok := strings.CutPrefix(finding.Message, undefinedValuePrefix)
+	if !ok {
+		return "", false
+	}
+	key, _, ok := strings.Cut(rest, "'")
+	return key, ok
+}
+
+// IndexUndefinedValues groups the undefined value findings of results and
+// their dependencies by key, ordered by the number of charts referencing a
+// key, highest first, then by key. Undefined reference pattern names are
+// not included.
+func IndexUndefinedValues(results []models.Result) []models.UndefinedValue {
+	byKey := make(map[string]*models.UndefinedValue)
+	for _, result := range models.FlattenResults(results) {
+		for _, finding := range result.Findings {
+			key, ok := undefinedValueKey(finding)
+			if !ok {
+				continue
+			}
+			entry := byKey[key]
+			if entry == nil {
+				entry = &models.UndefinedValue{Key: key}
+				byKey[key] = entry
+			}
+			if !slices.Contains(entry.Charts, result.ChartPath) {
+				entry.Charts = append(entry.Charts, result.ChartPath)
+			}
+			entry.References = append(entry.References, models.ValueReference{Name: key, File: finding.File, Line: finding.Line})
+		}
+	}
+
+	index := make([]models.UndefinedValue, 0, len(byKey))
+	for _, entry := range byKey {
+		sort.Strings(entry.Charts)
+		index = append(index, *entry)
+	}
+	sort.Slice(index, func(i, j int) bool {
+		a, b := index[i], index[j]
+		if len(a.Charts) != len(b.Charts) {
+			return len(a.Charts) > len(b.Charts)
+		}
+		return a.Key < b.Key
+	})
+	return index
+}
+
+// PrintUndefinedValueIndex prints each undefined value of index with the
+// template lines referencing it, followed by the number of keys and charts.
+func PrintUndefinedValueIndex(w io.Writer, index []models.UndefinedValue) {
+	charts := make(map[string]bool)
+	for _, value := range index {
+		fmt.Fprintf(w, "%s (%s)\n", value.Key, undefinedValueCounts(value))
+		for _, ref := range value.References {
+			fmt.Fprintf(w, "  %s:%d\n", ref.File, ref.Line)
+		}
+		for _, chart := range value.Charts {
+			charts[chart] = true
+		}
+	}
+	fmt.Fprintf(w, "\n%s in %s\n", plural(len(index), "undefined value"), plural(len(charts), "chart"))
+}
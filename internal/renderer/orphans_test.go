@@ -0,0 +1,90 @@
+package renderer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestUndefinedValues(t *testing.T) {
+	chartDir := t.TempDir()
+	files := map[string]string{
+		"Chart.yaml":                "apiVersion: v2\nname: web\nversion: 0.1.0\n",
+		"values.yaml":               "image:\n  repository: nginx\n",
+		"templates/deployment.yaml": "image: {{ .Values.image.repository }}:{{ .Values.image.tag }}\nport: {{ .Values.port }}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(chartDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	override := filepath.Join(t.TempDir(), "override.yaml")
+	if err := os.WriteFile(override, []byte("port: 8080\n"), 0644); err != nil {
+		t.Fatalf("Failed to write values file: %v", err)
+	}
+
+	findings, errs := UndefinedValues(chartDir, []string{override})
+	if len(errs) > 0 {
+		t.Fatalf("Expected no errors, got %v", errs)
+	}
+	if len(findings) != 1 {
+		t.Fatalf("Expected 1 undefined value, got %v", findings)
+	}
+	if key, _ := undefinedValueKey(findings[0]); key != "image.tag" {
+		t.Errorf("Expected image.tag to be undefined, got %s", key)
+	}
+}
+
+func TestIndexUndefinedValues(t *testing.T) {
+	undefined := func(key, file string, line int) models.Finding {
+		return models.Finding{RuleID: UndefinedValueID, Severity: models.SeverityError, Message: "Undefined value: '" + key + "' (included from _helpers.tpl:3)", File: file, Line: line}
+	}
+	results := []models.Result{
+		{ChartPath: "charts/web", Findings: []models.Finding{
+			undefined("image.tag", "charts/web/templates/deployment.yaml", 12),
+			undefined("port", "charts/web/templates/service.yaml", 4),
+			{RuleID: UndefinedValueID, Message: "Undefined secret reference: 'db'"},
+			{RuleID: "image-pinning", Message: "Undefined value: 'x'"},
+		}},
+		{ChartPath: "charts/api", Findings: []models.Finding{
+			undefined("image.tag", "charts/api/templates/deployment.yaml", 12),
+			undefined("image.tag", "charts/api/templates/job.yaml", 9),
+		}},
+	}
+
+	index := IndexUndefinedValues(results)
+	if len(index) != 2 {
+		t.Fatalf("Expected 2 undefined values, got %v", index)
+	}
+	if index[0].Key != "image.tag" || index[1].Key != "port" {
+		t.Errorf("Expected image.tag before port, got %s and %s", index[0].Key, index[1].Key)
+	}
+	if expected := []string{"charts/api", "charts/web"}; !reflect.DeepEqual(index[0].Charts, expected) {
+		t.Errorf("Expected charts %v, got %v", expected, index[0].Charts)
+	}
+	if len(index[0].References) != 3 {
+		t.Errorf("Expected 3 references to image.tag, got %v", index[0].References)
+	}
+
+	var out bytes.Buffer
+	PrintUndefinedValueIndex(&out, index)
+	for _, expected := range []string{
+		"image.tag (2 charts, 3 references)",
+		"  charts/api/templates/job.yaml:9",
+		"port (1 chart, 1 reference)",
+		"2 undefined values in 2 charts",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, out.String())
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package renderer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+	"github.com/Jaydee94/chartscan/internal/rules"
+	"github.com/Jaydee94/chartscan/internal/strvals"
+	"gopkg.in/yaml.v3"
+)
+
+// permutationRuleID is the ID of the findings of value permutations that
+// fail to render.
+const permutationRuleID = "permutation"
+
+// defaultPermutationLimit bounds the permutations rendered per chart when
+// permutations.limit is not set.
+const defaultPermutationLimit = 32
+
+// permutation is one combination of alternative values, ordered by key.
+type permutation []permutationValue
+
+type permutationValue struct {
+	key   string
+	value interface{}
+}
+
+// String formats the permutation as key=value pairs, the way findings name
+// it.
+func (p permutation) String() string {
+	pairs := make([]string, len(p))
+	for i, v := range p {
+		pairs[i] = fmt.Sprintf("%s=%v", v.key, v.value)
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// values returns the permutation as nested values, splitting keys at dots.
+func (p permutation) values() map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, v := range p {
+		keys := strings.Split(v.key, ".")
+		current := values
+		for _, key := range keys[:len(keys)-1] {
+			next, ok := current[key].(map[string]interface{})
+			if !ok {
+				next = make(map[string]interface{})
+				current[key] = next
+			}
+			current = next
+		}
+		current[keys[len(keys)-1]] = v.value
+	}
+	return values
+}
+
+// chartPermutations returns the value permutations configured for the chart
+// at chartPath, at most config.Limit of them, and how many there are in
+// total. The alternatives of all matching entries are combined, later entries
+// replacing the alternatives of a key. Permutations vary the last key fastest.
+func chartPermutations(chartPath string, config models.PermutationsConfig) ([]permutation, int) {
+	alternatives := make(map[string][]interface{})
+	for _, entry := range config.Charts {
+		if !matchesChart(chartPath, []string{entry.Chart}) {
+			continue
+		}
+		for key, values := range entry.Values {
+			if len(values) > 0 {
+				alternatives[key] = values
+			}
+		}
+	}
+	if len(alternatives) == 0 {
+		return nil, 0
+	}
+
+	keys := make([]string, 0, len(alternatives))
+	total := 1
+	for key, values := range alternatives {
+		keys = append(keys, key)
+		total *= len(values)
+	}
+	sort.Strings(keys)
+
+	limit := config.Limit
+	if limit <= 0 {
+		limit = defaultPermutationLimit
+	}
+
+	var permutations []permutation
+	indices := make([]int, len(keys))
+	for len(permutations) < limit {
+		p := make(permutation, len(keys))
+		for i, key := range keys {
+			p[i] = permutationValue{key: key, value: alternatives[key][indices[i]]}
+		}
+		permutations = append(permutations, p)
+
+		i := len(keys) - 1
+		for ; i >= 0; i-- {
+			indices[i]++
+			if indices[i] < len(alternatives[keys[i]]) {
+				break
+			}
+			indices[i] = 0
+		}
+		if i < 0 {
+			break
+		}
+	}
+	return permutations, total
+}
+
+// checkPermutations renders the chart once per permutation, each on top of
+// the chart's values files, and reports the permutations that fail to render,
+// produce invalid YAML or, with a kube version, invalid manifests. A warning
+// notes permutations left out by the limit.
+func checkPermutations(chartPath string, valuesFiles []string, setValues strvals.Overrides, permutations []permutation, total int, config models.Config, log *scanLog) []models.Finding {
+	var findings []models.Finding
+	if total > len(permutations) {
+		findings = append(findings, models.Finding{
+			RuleID:   permutationRuleID,
+			Severity: models.SeverityWarning,
+			Message:  fmt.Sprintf("%d value permutations configured, only the first %d are rendered (permutations.limit)", total, len(permutations)),
+		})
+	}
+
+	dir, err := os.MkdirTemp("", "chartscan-permutations")
+	if err != nil {
+		return append(findings, errorFindings(scanRuleID, []string{fmt.Sprintf("Error creating temp dir for permutations: %v", err)})...)
+	}
+	defer os.RemoveAll(dir)
+
+	for i, p := range permutations {
+		data, err := yaml.Marshal(p.values())
+		if err != nil {
+			findings = append(findings, errorFindings(permutationRuleID, []string{fmt.Sprintf("with %s: error encoding values: %v", p, err)})...)
+			continue
+		}
+		file := filepath.Join(dir, fmt.Sprintf("permutation-%d.yaml", i))
+		if err := os.WriteFile(file, data, 0644); err != nil {
+			findings = append(findings, errorFindings(scanRuleID, []string{fmt.Sprintf("Error writing permutation values: %v", err)})...)
+			continue
+		}
+
+		log.printf("rendering permutation %s", p)
+		permutationFiles := append(append([]string{}, valuesFiles...), file)
+		failures := renderPermutation(chartPath, permutationFiles, setValues, config, log)
+		for _, failure := range failures {
+			failure.Message = fmt.Sprintf("with %s: %s", p, failure.Message)
+			findings = append(findings, failure)
+		}
+	}
+	return findings
+}
+
+// renderPermutation renders the chart with valuesFiles and returns findings
+// of permutationRuleID for whatever fails.
+func renderPermutation(chartPath string, valuesFiles []string, setValues strvals.Overrides, config models.Config, log *scanLog) []models.Finding {
+	rendered, err := renderChart(config.ReleaseName, config.Namespace, chartPath, valuesFiles, setValues, config.Validation.KubeVersion, log)
+	if err != nil {
+		var helmErr *helmError
+		if errors.As(err, &helmErr) {
+			if findings := parseHelmOutput(permutationRuleID, helmErr.stderr); len(findings) > 0 {
+				return findings
+			}
+		}
+		return errorFindings(permutationRuleID, []string{fmt.Sprintf("Error rendering chart: %v", err)})
+	}
+	if rendered, err = postRender(rendered, config.PostRenderer, log); err != nil {
+		return errorFindings(permutationRuleID, []string{err.Error()})
+	}
+
+	manifests, err := rules.ParseManifests(rendered)
+	if err != nil {
+		return errorFindings(permutationRuleID, []string{err.Error()})
+	}
+	if config.Validation.KubeVersion != "" {
+		return errorFindings(permutationRuleID, validateManifests(manifests, config.Validation))
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package renderer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestChartPermutations(t *testing.T) {
+	config := models.PermutationsConfig{Charts: []models.ChartPermutations{
+		{Chart: "charts/*", Values: map[string][]interface{}{
+			"persistence.enabled": {true, false},
+			"replicas":            {1, 3},
+		}},
+		{Chart: "web", Values: map[string][]interface{}{
+			"ingress.enabled": {true, false},
+			"replicas":        {2},
+		}},
+		{Chart: "api", Values: map[string][]interface{}{"debug": {true}}},
+	}}
+
+	permutations, total := chartPermutations("charts/web", config)
+	if total != 4 {
+		t.Errorf("Expected 4 permutations in total, got %d", total)
+	}
+	var got []string
+	for _, p := range permutations {
+		got = append(got, p.String())
+	}
+	expected := []string{
+		"ingress.enabled=true, persistence.enabled=true, replicas=2",
+		"ingress.enabled=true, persistence.enabled=false, replicas=2",
+		"ingress.enabled=false, persistence.enabled=true, replicas=2",
+		"ingress.enabled=false, persistence.enabled=false, replicas=2",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected permutations %v, got %v", expected, got)
+	}
+
+	config.Limit = 3
+	if permutations, total := chartPermutations("charts/web", config); len(permutations) != 3 || total != 4 {
+		t.Errorf("Expected 3 of 4 permutations with a limit of 3, got %d of %d", len(permutations), total)
+	}
+
+	if permutations, _ := chartPermutations("other/db", config); permutations != nil {
+		t.Errorf("Expected no permutations for an unmatched chart, got %v", permutations)
+	}
+}
+
+func TestPermutationValues(t *testing.T) {
+	p := permutation{
+		{key: "ingress.enabled", value: true},
+		{key: "ingress.tls.enabled", value: false},
+		{key: "replicas", value: 2},
+	}
+	expected := map[string]interface{}{
+		"ingress": map[string]interface{}{
+			"enabled": true,
+			"tls":     map[string]interface{}{"enabled": false},
+		},
+		"replicas": 2,
+	}
+	if got := p.values(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected values %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,96 @@
+package renderer
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// kustomizeResource is the file the rendered manifests are written to in a
+// kustomize overlay.
+const kustomizeResource = "all.yaml"
+
+// postRender pipes rendered through the post-renderer of config and returns
+// its output, or returns rendered unchanged when none is configured.
+func postRender(rendered string, config models.PostRendererConfig, log *scanLog) (string, error) {
+	switch {
+	case config.Kustomize != "":
+		return kustomizeBuild(rendered, config.Kustomize, log)
+	case config.Command != "":
+		cmd := exec.Command(config.Command, config.Args...)
+		cmd.Stdin = strings.NewReader(rendered)
+		return runPostRenderer(cmd, log)
+	}
+	return rendered, nil
+}
+
+// kustomizeBuild builds the kustomize overlay in dir with rendered as its
+// all.yaml resource. The overlay is copied next to dir first, so that
+// relative references such as ../base resolve as they do from dir and
+// concurrent builds do not share files.
+func kustomizeBuild(rendered, dir string, log *scanLog) (string, error) {
+	if _, err := os.Stat(filepath.Join(dir, "kustomization.yaml")); err != nil {
+		return "", fmt.Errorf("%s is not a kustomize overlay: %v", dir, err)
+	}
+	overlay, err := os.MkdirTemp(filepath.Dir(filepath.Clean(dir)), ".chartscan-overlay-")
+	if err != nil {
+		return "", fmt.Errorf("error creating temp dir for kustomize overlay: %v", err)
+	}
+	defer os.RemoveAll(overlay)
+
+	if err := copyDir(dir, overlay); err != nil {
+		return "", fmt.Errorf("error copying kustomize overlay %s: %v", dir, err)
+	}
+	if err := os.WriteFile(filepath.Join(overlay, kustomizeResource), []byte(rendered), 0644); err != nil {
+		return "", fmt.Errorf("error writing rendered manifests: %v", err)
+	}
+
+	cmd := exec.Command("kubectl", "kustomize", overlay)
+	if _, err := exec.LookPath("kustomize"); err == nil {
+		cmd = exec.Command("kustomize", "build", overlay)
+	}
+	return runPostRenderer(cmd, log)
+}
+
+// runPostRenderer runs cmd and returns its standard output.
+func runPostRenderer(cmd *exec.Cmd, log *scanLog) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	started := time.Now()
+	err := cmd.Run()
+	log.command(cmd, started, stdout.String(), stderr.String(), err)
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("post-renderer %s failed: %v: %s", strings.Join(cmd.Args, " "), err, message)
+		}
+		return "", fmt.Errorf("post-renderer %s failed: %v", strings.Join(cmd.Args, " "), err)
+	}
+	return stdout.String(), nil
+}
+
+// copyDir copies the files below src to dst, which must exist.
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		return copyFile(path, target)
+	})
+}
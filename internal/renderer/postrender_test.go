@@ -0,0 +1,94 @@
+package renderer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+const postRenderInput = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web
+`
+
+func TestPostRenderCommand(t *testing.T) {
+	bin := t.TempDir()
+	script := "#!/bin/sh\nsed \"s/name: web/name: $1-web/\"\n"
+	hook := filepath.Join(bin, "hook")
+	if err := os.WriteFile(hook, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write hook: %v", err)
+	}
+
+	output, err := postRender(postRenderInput, models.PostRendererConfig{Command: hook, Args: []string{"prod"}}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(output, "name: prod-web") {
+		t.Errorf("Expected post-rendered manifests, got:\n%s", output)
+	}
+
+	if output, _ := postRender(postRenderInput, models.PostRendererConfig{}, nil); output != postRenderInput {
+		t.Errorf("Expected manifests unchanged without a post-renderer, got:\n%s", output)
+	}
+
+	failing := filepath.Join(bin, "failing")
+	if err := os.WriteFile(failing, []byte("#!/bin/sh\necho 'patch does not apply' >&2\nexit 1\n"), 0755); err != nil {
+		t.Fatalf("Failed to write hook: %v", err)
+	}
+	_, err = postRender(postRenderInput, models.PostRendererConfig{Command: failing}, nil)
+	if err == nil || !strings.Contains(err.Error(), "patch does not apply") {
+		t.Errorf("Expected the post-renderer's error output, got %v", err)
+	}
+}
+
+func TestPostRenderKustomize(t *testing.T) {
+	bin := t.TempDir()
+	// The fake kustomize prints the overlay's base, to show that relative
+	// references resolve, and the manifests written to the overlay.
+	script := "#!/bin/sh\ncat \"$2/../base/kustomization.yaml\" \"$2/all.yaml\"\n"
+	if err := os.WriteFile(filepath.Join(bin, "kustomize"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write kustomize: %v", err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	root := t.TempDir()
+	overlay := filepath.Join(root, "prod")
+	for path, content := range map[string]string{
+		filepath.Join(root, "base", "kustomization.yaml"): "# base\n",
+		filepath.Join(overlay, "kustomization.yaml"):      "resources:\n- all.yaml\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+
+	output, err := postRender(postRenderInput, models.PostRendererConfig{Kustomize: overlay}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if expected := "# base\n" + postRenderInput; output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", root, err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected the copy of the overlay to be removed, got %v", entries)
+	}
+	if _, err := os.Stat(filepath.Join(overlay, kustomizeResource)); !os.IsNotExist(err) {
+		t.Errorf("Expected the overlay to be left unchanged, got %v", err)
+	}
+
+	if _, err := postRender(postRenderInput, models.PostRendererConfig{Kustomize: root}, nil); err == nil {
+		t.Errorf("Expected an error for a directory without kustomization.yaml")
+	}
+}
@@ -0,0 +1,134 @@
+package renderer
+
+import (
+	"fmt"
+	"strings"
+	"text/template/parse"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// rangeField is a field that a range block over values reads from every
+// element, like .host in {{ range .Values.hosts }}{{ .host }}{{ end }}.
+type rangeField struct {
+	list  []string
+	field []string
+	file  string
+	line  int
+}
+
+// checkRangeElements reports the elements of ranged values that lack a field
+// the range block reads from each of them, e.g. the second of two hosts
+// without a host. Only fields read directly in the block are checked: those
+// in nested if, with and range blocks and those passed to fallback functions
+// may be missing. Ranges over values that are not set are reported as
+// undefined values already.
+func checkRangeElements(chartPath string, values map[string]interface{}) []models.Finding {
+	sources, _, _, _ := loadTemplateSources(chartPath)
+	var fields []rangeField
+	for _, source := range sources {
+		fields = collectRangeFields(source.tree.Root, valueScope{root: true}, source, fields)
+	}
+
+	var findings []models.Finding
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		for _, element := range rangeElements(field.list, lookupValue(field.list, values)) {
+			name := element.name + "." + strings.Join(field.field, ".")
+			if seen[name] || checkNestedValueExists(field.field, element.value) {
+				continue
+			}
+			seen[name] = true
+			findings = append(findings, models.Finding{
+				RuleID:   UndefinedValueID,
+				Severity: models.SeverityError,
+				Message:  fmt.Sprintf("%s%s' (read from each element of %s)", undefinedValuePrefix, name, strings.Join(field.list, ".")),
+				File:     field.file,
+				Line:     field.line,
+			})
+		}
+	}
+	return findings
+}
+
+// rangeElement is an element of a ranged value and its name, e.g. hosts[1].
+type rangeElement struct {
+	name  string
+	value interface{}
+}
+
+// rangeElements returns the map elements of the list or map at path, in the
+// order range visits them.
+func rangeElements(path []string, value interface{}) []rangeElement {
+	prefix := strings.Join(path, ".")
+	var elements []rangeElement
+	switch v := value.(type) {
+	case []interface{}:
+		for i, element := range v {
+			if _, ok := element.(map[string]interface{}); ok {
+				elements = append(elements, rangeElement{name: fmt.Sprintf("%s[%d]", prefix, i), value: element})
+			}
+		}
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			if _, ok := v[key].(map[string]interface{}); ok {
+				elements = append(elements, rangeElement{name: prefix + "." + key, value: v[key]})
+			}
+		}
+	}
+	return elements
+}
+
+// collectRangeFields appends the fields read from the elements of ranged
+// values below list, in the template file source, to fields. dot is what dot
+// refers to in list.
+func collectRangeFields(list *parse.ListNode, dot valueScope, source *templateSource, fields []rangeField) []rangeField {
+	if list == nil {
+		return fields
+	}
+	for _, node := range list.Nodes {
+		switch n := node.(type) {
+		case *parse.IfNode:
+			fields = collectRangeFields(n.List, dot, source, fields)
+			fields = collectRangeFields(n.ElseList, dot, source, fields)
+		case *parse.WithNode:
+			fields = collectRangeFields(n.List, pipeScope(n.Pipe, dot, nil), source, fields)
+			fields = collectRangeFields(n.ElseList, dot, source, fields)
+		case *parse.RangeNode:
+			if scope := pipeScope(n.Pipe, dot, nil); scope.values && len(scope.path) > 0 {
+				fields = append(fields, elementFields(n.List, scope.path, source)...)
+			}
+			fields = collectRangeFields(n.List, unknownScope, source, fields)
+			fields = collectRangeFields(n.ElseList, dot, source, fields)
+		}
+	}
+	return fields
+}
+
+// elementFields returns the fields that the actions directly in list, the
+// block of a range over the value at path, read from dot.
+func elementFields(list *parse.ListNode, path []string, source *templateSource) []rangeField {
+	var fields []rangeField
+	for _, node := range list.Nodes {
+		action, ok := node.(*parse.ActionNode)
+		if !ok || len(action.Pipe.Decl) > 0 {
+			continue
+		}
+		for _, cmd := range action.Pipe.Cmds {
+			if identifier, ok := cmd.Args[0].(*parse.IdentifierNode); ok && fallbackFuncs[identifier.Ident] {
+				continue
+			}
+			for _, arg := range cmd.Args {
+				if field, ok := arg.(*parse.FieldNode); ok {
+					fields = append(fields, rangeField{
+						list:  path,
+						field: field.Ident,
+						file:  source.file,
+						line:  strings.Count(source.content[:field.Position()], "\n") + 1,
+					})
+				}
+			}
+		}
+	}
+	return fields
+}
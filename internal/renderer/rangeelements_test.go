@@ -0,0 +1,62 @@
+package renderer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckRangeElements(t *testing.T) {
+	chartDir := t.TempDir()
+	templatesDir := filepath.Join(chartDir, "templates")
+	if err := os.MkdirAll(templatesDir, 0755); err != nil {
+		t.Fatalf("Failed to create templates dir: %v", err)
+	}
+	template := `rules:
+{{- range .Values.hosts }}
+  - host: {{ .host | quote }}
+    port: {{ default 80 .port }}
+    {{- if .tls }}
+    secret: {{ .tls.secret }}
+    {{- end }}
+{{- end }}
+{{- range $name, $server := .Values.servers }}
+  - {{ $name }}: {{ .url }}
+{{- end }}
+{{- range .Values.missing }}
+  - {{ .name }}
+{{- end }}
+`
+	if err := os.WriteFile(filepath.Join(templatesDir, "ingress.yaml"), []byte(template), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	values := map[string]interface{}{
+		"hosts": []interface{}{
+			map[string]interface{}{"host": "a.example.com"},
+			map[string]interface{}{"port": 8080},
+			"c.example.com",
+		},
+		"servers": map[string]interface{}{
+			"api": map[string]interface{}{"url": "https://api"},
+			"web": map[string]interface{}{},
+		},
+	}
+
+	findings := checkRangeElements(chartDir, values)
+	expected := []struct {
+		message string
+		line    int
+	}{
+		{"Undefined value: 'hosts[1].host' (read from each element of hosts)", 3},
+		{"Undefined value: 'servers.web.url' (read from each element of servers)", 10},
+	}
+	if len(findings) != len(expected) {
+		t.Fatalf("Expected %d findings, got %v", len(expected), findings)
+	}
+	for i, want := range expected {
+		if findings[i].RuleID != UndefinedValueID || findings[i].Message != want.message || findings[i].Line != want.line {
+			t.Errorf("Expected %q at line %d, got %+v", want.message, want.line, findings[i])
+		}
+	}
+}
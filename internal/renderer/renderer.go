@@ -2,12 +2,16 @@ package renderer
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"runtime/debug"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -18,7 +22,13 @@ import (
 	"github.com/olekukonko/tablewriter/tw"
 	"gopkg.in/yaml.v3"
 
+	"github.com/Jaydee94/chartscan/internal/finder"
 	"github.com/Jaydee94/chartscan/internal/models"
+	"github.com/Jaydee94/chartscan/internal/rules"
+	"github.com/Jaydee94/chartscan/internal/strvals"
+	"github.com/Jaydee94/chartscan/internal/tracing"
+	"github.com/Jaydee94/chartscan/internal/validation"
+	"github.com/Jaydee94/chartscan/internal/valuessource"
 )
 
 var (
@@ -37,31 +47,361 @@ func TemplateParser(templateFile string) ([]models.ValueReference, error) {
 	}
 
 	templateString := string(templateBytes)
+	if refs, ok := parseTemplateTree(templateFile, templateString); ok {
+		return refs, nil
+	}
+	return parseTemplateText(templateFile, templateString)
+}
+
+// parseTemplateText extracts the value references of a template that does not
+// parse, e.g. because it indexes lists as in .Values.hosts[0].host, by
+// matching plain value and index actions line by line.
+func parseTemplateText(templateFile, templateString string) ([]models.ValueReference, error) {
 	var valueReferences []models.ValueReference
 
-	// Regex to capture dot notation values like .Values.service.port
-	re := regexp.MustCompile(`{{\s*\.Values\.([a-zA-Z0-9_.\[\]-]+)\s*}}`)
+	actions := findTemplateActions(templateString)
+	scopes := findGuardScopes(actions, len(templateString))
 	lines := strings.Split(templateString, "\n")
+	offset := 0
 
 	for i, line := range lines {
-		matches := re.FindAllStringSubmatchIndex(line, -1)
-		for _, match := range matches {
+		for _, match := range dotValueRegex.FindAllStringSubmatchIndex(line, -1) {
 			reference := line[match[2]:match[3]]
 			if reference == "" {
 				return nil, fmt.Errorf("empty value reference: %s", line[match[0]:match[1]])
 			}
+			path, err := parseValuePath(reference)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value reference %s: %v", line[match[0]:match[1]], err)
+			}
 			valueReferences = append(valueReferences, models.ValueReference{
 				Name:     reference,
+				Path:     path,
+				File:     templateFile,
+				Line:     i + 1,
+				FullText: line[match[0]:match[1]],
+				Guards:   scopes.guardsAt(offset + match[0]),
+			})
+		}
+
+		for _, match := range indexValueRegex.FindAllStringSubmatchIndex(line, -1) {
+			name, path, err := parseIndexArgs(line[match[2]:match[3]], line[match[4]:match[5]])
+			if err != nil {
+				return nil, fmt.Errorf("invalid value reference %s: %v", line[match[0]:match[1]], err)
+			}
+			valueReferences = append(valueReferences, models.ValueReference{
+				Name:     name,
+				Path:     path,
 				File:     templateFile,
 				Line:     i + 1,
 				FullText: line[match[0]:match[1]],
+				Guards:   scopes.guardsAt(offset + match[0]),
 			})
 		}
+
+		offset += len(line) + 1
+	}
+
+	for _, action := range actions {
+		for _, ref := range parseFallbackReferences(action) {
+			ref.File = templateFile
+			valueReferences = append(valueReferences, ref)
+		}
 	}
 
 	return valueReferences, nil
 }
 
+var (
+	// dotValueRegex captures dot notation values like .Values.service.port or
+	// .Values.ingress.hosts[0].host.
+	dotValueRegex = regexp.MustCompile(`{{\s*\.Values\.([a-zA-Z0-9_.\[\]-]+)\s*}}`)
+
+	// indexValueRegex captures index calls on values like
+	// index .Values.annotations "example.com/key" 0. The first group is the
+	// optional dotted prefix, the second group the list of index arguments.
+	indexValueRegex = regexp.MustCompile(`{{\s*index\s+\.Values((?:\.[a-zA-Z0-9_-]+)*)((?:\s+(?:"[^"]*"|[0-9]+))+)\s*}}`)
+
+	// indexArgRegex splits the arguments of an index call into quoted keys and
+	// integer list indices.
+	indexArgRegex = regexp.MustCompile(`"([^"]*)"|([0-9]+)`)
+)
+
+// templateAction is a single {{ ... }} action of a template together with its
+// byte offsets and the line it starts on.
+type templateAction struct {
+	Body  string
+	Start int
+	End   int
+	Line  int
+}
+
+// guardScope is a byte range of a template that is only rendered when the
+// value at guard exists.
+type guardScope struct {
+	start, end int
+	guard      []string
+}
+
+type guardScopes []guardScope
+
+// guardsAt returns the guard paths of every scope enclosing offset.
+func (g guardScopes) guardsAt(offset int) [][]string {
+	var guards [][]string
+	for _, scope := range g {
+		if offset >= scope.start && offset < scope.end {
+			guards = append(guards, scope.guard)
+		}
+	}
+	return guards
+}
+
+var (
+	// actionRegex matches template actions including trim markers.
+	actionRegex = regexp.MustCompile(`(?s){{-?\s*(.*?)\s*-?}}`)
+
+	// hasKeyRegex captures hasKey calls on values like hasKey .Values.ingress "tls".
+	hasKeyRegex = regexp.MustCompile(`hasKey\s+\.Values((?:\.[a-zA-Z0-9_-]+)*)\s+"([^"]*)"`)
+
+	// digRegex captures dig calls on values. The first group holds the keys and
+	// the default, the second group the dotted path of the dictionary.
+	digRegex = regexp.MustCompile(`\bdig((?:\s+(?:"[^"]*"|\([^)]*\)|[^\s"()]+))+?)\s+\.Values((?:\.[a-zA-Z0-9_-]+)*)\b`)
+
+	// pluckRegex captures pluck calls on values like pluck "port" .Values.a .Values.b.
+	pluckRegex = regexp.MustCompile(`\bpluck\s+"([^"]*)"((?:\s+\.Values(?:\.[a-zA-Z0-9_-]+)*)+)`)
+
+	// digArgRegex splits dig arguments into quoted strings, parenthesized
+	// expressions and bare words.
+	digArgRegex = regexp.MustCompile(`"[^"]*"|\([^)]*\)|[^\s"()]+`)
+)
+
+// findTemplateActions returns all actions of a template in order of
+// appearance. Comments are skipped.
+func findTemplateActions(content string) []templateAction {
+	var actions []templateAction
+	for _, match := range actionRegex.FindAllStringSubmatchIndex(content, -1) {
+		body := content[match[2]:match[3]]
+		if strings.HasPrefix(body, "/*") {
+			continue
+		}
+		actions = append(actions, templateAction{
+			Body:  body,
+			Start: match[0],
+			End:   match[1],
+			Line:  strings.Count(content[:match[0]], "\n") + 1,
+		})
+	}
+	return actions
+}
+
+// findGuardScopes tracks block structure across actions and returns the
+// ranges guarded by `if hasKey` conditions. Else branches are not guarded
+// since they run exactly when the key is missing.
+func findGuardScopes(actions []templateAction, contentLen int) guardScopes {
+	type frame struct {
+		start  int
+		guards [][]string
+	}
+
+	var scopes guardScopes
+	var stack []frame
+
+	closeFrame := func(f frame, end int) {
+		for _, guard := range f.guards {
+			scopes = append(scopes, guardScope{start: f.start, end: end, guard: guard})
+		}
+	}
+
+	for _, action := range actions {
+		fields := strings.Fields(action.Body)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "if":
+			stack = append(stack, frame{start: action.End, guards: hasKeyGuards(strings.TrimPrefix(action.Body, "if"))})
+		case "with", "range", "define", "block":
+			stack = append(stack, frame{start: action.End})
+		case "else":
+			if len(stack) == 0 {
+				continue
+			}
+			top := &stack[len(stack)-1]
+			closeFrame(*top, action.Start)
+			top.start = action.End
+			top.guards = nil
+			if rest := strings.TrimSpace(strings.TrimPrefix(action.Body, "else")); strings.HasPrefix(rest, "if ") {
+				top.guards = hasKeyGuards(strings.TrimPrefix(rest, "if"))
+			}
+		case "end":
+			if len(stack) == 0 {
+				continue
+			}
+			closeFrame(stack[len(stack)-1], action.Start)
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	for _, f := range stack {
+		closeFrame(f, contentLen)
+	}
+
+	return scopes
+}
+
+// hasKeyGuards returns the value paths asserted by hasKey calls in an if
+// condition. Conditions using or/not do not guarantee presence and yield no
+// guards.
+func hasKeyGuards(condition string) [][]string {
+	fields := strings.Fields(strings.NewReplacer("(", " ", ")", " ").Replace(condition))
+	for _, field := range fields {
+		if field == "or" || field == "not" {
+			return nil
+		}
+	}
+
+	var guards [][]string
+	for _, match := range hasKeyRegex.FindAllStringSubmatch(condition, -1) {
+		var guard []string
+		if prefix := strings.TrimPrefix(match[1], "."); prefix != "" {
+			guard = strings.Split(prefix, ".")
+		}
+		guards = append(guards, append(guard, match[2]))
+	}
+	return guards
+}
+
+// parseFallbackReferences extracts value references accessed through dig and
+// pluck within a single action. Both functions fall back gracefully when a
+// key is missing, so the references are marked optional.
+func parseFallbackReferences(action templateAction) []models.ValueReference {
+	var refs []models.ValueReference
+
+	for _, match := range digRegex.FindAllStringSubmatch(action.Body, -1) {
+		args := digArgRegex.FindAllString(match[1], -1)
+		if len(args) < 2 {
+			continue
+		}
+		var path []string
+		if prefix := strings.TrimPrefix(match[2], "."); prefix != "" {
+			path = strings.Split(prefix, ".")
+		}
+		valid := true
+		for _, arg := range args[:len(args)-1] {
+			if !strings.HasPrefix(arg, `"`) {
+				valid = false
+				break
+			}
+			path = append(path, strings.Trim(arg, `"`))
+		}
+		if !valid || len(path) == 0 {
+			continue
+		}
+		refs = append(refs, models.ValueReference{
+			Name:     strings.Join(path, "."),
+			Path:     path,
+			Line:     action.Line,
+			FullText: match[0],
+			Optional: true,
+		})
+	}
+
+	for _, match := range pluckRegex.FindAllStringSubmatch(action.Body, -1) {
+		for _, dict := range strings.Fields(match[2]) {
+			var path []string
+			if prefix := strings.TrimPrefix(strings.TrimPrefix(dict, ".Values"), "."); prefix != "" {
+				path = strings.Split(prefix, ".")
+			}
+			path = append(path, match[1])
+			refs = append(refs, models.ValueReference{
+				Name:     strings.Join(path, "."),
+				Path:     path,
+				Line:     action.Line,
+				FullText: match[0],
+				Optional: true,
+			})
+		}
+	}
+
+	return refs
+}
+
+// parseValuePath splits a dot notation reference such as
+// ingress.hosts[0].host into its path segments. List indices are returned as
+// decimal strings.
+func parseValuePath(reference string) ([]string, error) {
+	var path []string
+	for _, part := range strings.Split(reference, ".") {
+		key := part
+		var indices []string
+		if open := strings.Index(part, "["); open >= 0 {
+			key = part[:open]
+			rest := part[open:]
+			for rest != "" {
+				end := strings.Index(rest, "]")
+				if !strings.HasPrefix(rest, "[") || end < 0 {
+					return nil, fmt.Errorf("malformed index in %q", part)
+				}
+				index := rest[1:end]
+				if _, err := strconv.Atoi(index); err != nil {
+					return nil, fmt.Errorf("non-numeric index %q in %q", index, part)
+				}
+				indices = append(indices, index)
+				rest = rest[end+1:]
+			}
+		}
+		if key == "" {
+			return nil, fmt.Errorf("empty key in %q", reference)
+		}
+		path = append(path, key)
+		path = append(path, indices...)
+	}
+	return path, nil
+}
+
+// parseIndexArgs builds the display name and path segments for an index call
+// on .Values. prefix holds the dotted path before the call arguments (e.g.
+// ".podAnnotations") and args the raw arguments (e.g. ` "example.com/key" 0`).
+func parseIndexArgs(prefix, args string) (string, []string, error) {
+	var path []string
+	name := strings.TrimPrefix(prefix, ".")
+	if name != "" {
+		path = strings.Split(name, ".")
+	}
+
+	for _, arg := range indexArgRegex.FindAllStringSubmatch(args, -1) {
+		if arg[2] != "" {
+			path = append(path, arg[2])
+			name = appendName(name, arg[2], true)
+			continue
+		}
+		key := arg[1]
+		if key == "" {
+			return "", nil, fmt.Errorf("empty key")
+		}
+		path = append(path, key)
+		name = appendName(name, key, false)
+	}
+
+	return name, path, nil
+}
+
+// appendName appends a path segment to the display name of a reference: list
+// indices and keys containing dots or brackets in brackets, other keys in dot
+// notation.
+func appendName(name, segment string, index bool) string {
+	switch {
+	case index:
+		return name + "[" + segment + "]"
+	case strings.ContainsAny(segment, ".[]"):
+		return name + fmt.Sprintf("[%q]", segment)
+	case name == "":
+		return segment
+	default:
+		return name + "." + segment
+	}
+}
+
 // ValuesLoader loads values from a YAML file and returns them as a map.
 func ValuesLoader(valuesFile string) (map[string]interface{}, error) {
 	valuesBytes, err := os.ReadFile(valuesFile)
@@ -78,47 +418,92 @@ func ValuesLoader(valuesFile string) (map[string]interface{}, error) {
 }
 
 // CheckValueReferences checks a slice of ValueReferences against a values map
-// and returns a list of undefined value error strings.
-func CheckValueReferences(valueReferences []models.ValueReference, values map[string]interface{}) []string {
-	undefinedValues := make([]string, 0, len(valueReferences))
+// and returns an undefined-value finding for each reference without a value.
+func CheckValueReferences(valueReferences []models.ValueReference, values map[string]interface{}) []models.Finding {
+	return checkValueReferences(valueReferences, values, nil)
+}
+
+// checkValueReferences is CheckValueReferences, naming the commit that
+// introduced each undefined reference when b is set.
+func checkValueReferences(valueReferences []models.ValueReference, values map[string]interface{}, b *blamer) []models.Finding {
+	undefinedValues := make([]models.Finding, 0, len(valueReferences))
 
 	for _, ref := range valueReferences {
-		keys := strings.Split(ref.Name, ".")
+		if ref.Optional || !guardsSatisfied(ref.Guards, values) {
+			continue
+		}
+		keys := ref.Path
+		if len(keys) == 0 {
+			keys = strings.Split(ref.Name, ".")
+		}
 		if !checkNestedValueExists(keys, values) {
-			undefinedValues = append(undefinedValues,
-				fmt.Sprintf("Undefined value: '%s' referenced in %s at line %d", ref.Name, ref.File, ref.Line),
-			)
+			message := undefinedValuePrefix + ref.Name + "'"
+			if ref.IncludedFrom != "" {
+				message += " (included from " + ref.IncludedFrom + ")"
+			}
+			undefinedValues = append(undefinedValues, models.Finding{
+				RuleID:   UndefinedValueID,
+				Severity: models.SeverityError,
+				Message:  message,
+				File:     ref.File,
+				Line:     ref.Line,
+				Blame:    b.line(ref.File, ref.Line),
+			})
 		}
 	}
 
 	return undefinedValues
 }
 
+// guardsSatisfied reports whether every guard path exists in values, i.e.
+// whether the guarded template block would be rendered at all.
+func guardsSatisfied(guards [][]string, values map[string]interface{}) bool {
+	for _, guard := range guards {
+		if !checkNestedValueExists(guard, values) {
+			return false
+		}
+	}
+	return true
+}
+
 // checkNestedValueExists recursively checks whether the nested key path
-// described by keys exists within currentMap.
-func checkNestedValueExists(keys []string, currentMap interface{}) bool {
-	if len(keys) == 0 || currentMap == nil {
+// described by keys exists within current. Segments index into maps by key
+// and into lists by their decimal position.
+func checkNestedValueExists(keys []string, current interface{}) bool {
+	if len(keys) == 0 || current == nil {
 		return false
 	}
 
-	m, ok := currentMap.(map[string]interface{})
-	if !ok {
+	next, exists := lookupPathSegment(current, keys[0])
+	if !exists {
 		return false
 	}
 
 	if len(keys) == 1 {
-		_, exists := m[keys[0]]
-		return exists
-	}
-
-	next, exists := m[keys[0]].(map[string]interface{})
-	if !exists {
-		return false
+		return true
 	}
 
 	return checkNestedValueExists(keys[1:], next)
 }
 
+// lookupPathSegment returns the element addressed by key within a map or a
+// list, and whether it exists.
+func lookupPathSegment(current interface{}, key string) (interface{}, bool) {
+	switch c := current.(type) {
+	case map[string]interface{}:
+		value, exists := c[key]
+		return value, exists
+	case []interface{}:
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 || index >= len(c) {
+			return nil, false
+		}
+		return c[index], true
+	default:
+		return nil, false
+	}
+}
+
 // mergeMaps merges source into target, combining nested maps recursively.
 // Values in source overwrite values in target at non-map keys.
 func mergeMaps(target, source map[string]interface{}) {
@@ -133,21 +518,120 @@ func mergeMaps(target, source map[string]interface{}) {
 	}
 }
 
-// ScanHelmChart renders a Helm chart and checks for undefined values.
-// Returns: success, errors, merged values map, and a list of undefined values.
-func ScanHelmChart(chartPath string, valuesFiles []string, setValues []string) (bool, []string, map[string]interface{}, []string) {
+// ScanOptions controls how ScanHelmChart renders and checks a chart.
+type ScanOptions struct {
+	ValuesFiles []string
+	SetValues   strvals.Overrides
+	// Config provides reference patterns and rule settings from chartscan.yaml.
+	Config models.Config
+	// IncludeDependencies also checks the templates of the chart's subcharts
+	// and reports them in Result.Dependencies.
+	IncludeDependencies bool
+	// Blame names the commit that last changed the source of each undefined
+	// value and finding, using git blame.
+	Blame bool
+	// OnlyNewSince, if set, limits undefined values and findings to lines
+	// changed since the merge base of this git ref and HEAD.
+	OnlyNewSince string
+	// Debug prints the stack trace of internal errors to stderr.
+	Debug bool
+	// DebugCharts selects charts, by path, glob or directory name, whose
+	// stage log and helm output are recorded in Result.DebugLog.
+	DebugCharts []string
+	// CacheDir caches downloaded chart dependencies between charts and runs.
+	// Caching is disabled if it is empty.
+	CacheDir string
+	// Trace is the span the scan of each chart is traced below. Tracing is
+	// disabled if it is nil.
+	Trace *tracing.Span
+	// Charts holds the Chart.yaml metadata read at discovery, by chart path.
+	// The Chart.yaml of charts missing from it is read when they are scanned.
+	Charts map[string]finder.ChartInfo
+}
+
+// ScanHelmChart lints and renders a Helm chart, checks for undefined values
+// and evaluates the enabled manifest rules against the rendered output. A
+// panic while scanning is reported as a tool error of the chart.
+func ScanHelmChart(chartPath string, opts ScanOptions) (result models.Result) {
+	start := time.Now()
+	defer func() { result.DurationSeconds = time.Since(start).Seconds() }()
+	span := opts.Trace.StartAt("scan chart", start, tracing.String("chartscan.chart.path", chartPath))
+	defer func() {
+		span.SetAttributes(tracing.Bool("chartscan.chart.success", result.Success), tracing.Int("chartscan.chart.findings", len(result.Findings)))
+		if result.ToolError != "" {
+			span.SetError(result.ToolError)
+		}
+		span.End()
+	}()
+	defer recoverScan(chartPath, opts.Debug, &result)
+	defer applyRuleOverrides(&result, &opts.Config)
+	defer redactResult(&result)
+
+	var log *scanLog
+	if debug := matchesChart(chartPath, opts.DebugCharts); debug || span != nil {
+		log = newScanLog(debug, span)
+		defer func() {
+			log.end()
+			result.DebugLog = log.entries()
+		}()
+	}
+
+	valuesFiles, setValues := ChartValuesFiles(chartPath, opts.ValuesFiles, opts.Config.ValuesFilesRelativeTo), opts.SetValues
+	result = models.Result{ChartPath: chartPath}
+
 	if chartPath == "" {
-		return false, []string{"Chart path is empty"}, nil, nil
+		result.Findings = errorFindings(scanRuleID, []string{"Chart path is empty"})
+		return result
 	}
 
-	success, errors := handleDependencies(chartPath)
-	if !success {
-		return false, errors, nil, nil
+	valuesFiles, err := valuessource.Resolve(valuesFiles)
+	if err != nil {
+		result.Findings = errorFindings(valuesRuleID, []string{err.Error()})
+		return result
+	}
+
+	info, ok := opts.Charts[chartPath]
+	if !ok {
+		info = finder.ReadChartInfo(chartPath)
+	}
+
+	log.stage("dependencies")
+	// Charts whose Chart.yaml cannot be read fail here. In offline mode,
+	// charts missing dependencies are checked without them: the checks
+	// rendering the chart are skipped, with a warning instead of an error.
+	var dependenciesMissing bool
+	var dependencyFindings []models.Finding
+	if info.HasDependencies || info.Err != nil {
+		// Broken file:// dependencies fail even offline, with clearer
+		// messages than helm's.
+		if problems := checkLocalDependencies(chartPath); len(problems) > 0 {
+			result.Findings = errorFindings(dependenciesRuleID, problems)
+			return result
+		}
+		log.printf("updating dependencies")
+		success, errors, cleanup := handleDependencies(chartPath, opts.CacheDir, opts.Config.Dependencies, log)
+		if !success && (!opts.Config.Dependencies.Offline || info.Err != nil) {
+			result.Findings = errorFindings(dependenciesRuleID, errors)
+			return result
+		}
+		if !success {
+			log.printf("dependencies missing in offline mode, skipping lint and manifest checks")
+			dependenciesMissing = true
+			for _, message := range errors {
+				dependencyFindings = append(dependencyFindings, models.Finding{
+					RuleID:   dependenciesRuleID,
+					Severity: models.SeverityWarning,
+					Message:  message + "; lint and manifest checks skipped",
+				})
+			}
+		}
+		defer cleanup()
 	}
 
 	if len(valuesFiles) > 0 {
 		if missingErrors := checkValuesFilesExistence(valuesFiles); len(missingErrors) > 0 {
-			return false, missingErrors, nil, nil
+			result.Findings = errorFindings(valuesRuleID, missingErrors)
+			return result
 		}
 	}
 
@@ -155,77 +639,457 @@ func ScanHelmChart(chartPath string, valuesFiles []string, setValues []string) (
 		valuesFiles = []string{}
 	}
 
-	lintErrors := lintChart(chartPath, valuesFiles, setValues)
+	scanFindings := dependencyFindings
+	if !dependenciesMissing {
+		log.stage("lint")
+		log.printf("linting with values files %v and set values %v", valuesFiles, setValues.Args())
+		scanFindings = append(scanFindings, lintChart(chartPath, opts.Config.Namespace, valuesFiles, setValues, opts.Config.Lint, log)...)
+	}
 
-	valueReferences, templateErrors := parseTemplates(chartPath)
-	lintErrors = append(lintErrors, templateErrors...)
+	log.stage("templates")
+	valueReferences, templateErrors := ParseTemplates(chartPath)
+	scanFindings = append(scanFindings, errorFindings(templateRuleID, templateErrors)...)
+	log.printf("parsed templates: %d value references, %d errors", len(valueReferences), len(templateErrors))
+	scanFindings = append(scanFindings, checkTemplateCycles(chartPath)...)
 
+	log.stage("values")
 	values, loadErrors := loadAndMergeValues(chartPath, valuesFiles)
-	lintErrors = append(lintErrors, loadErrors...)
+	scanFindings = append(scanFindings, errorFindings(valuesRuleID, loadErrors)...)
+	log.printf("loaded values: %d top-level keys, %d errors", len(values), len(loadErrors))
 
 	if values == nil {
 		values = make(map[string]interface{})
 	}
 
-	if len(setValues) > 0 {
-		mergeSetValues(values, setValues)
+	if err := setValues.MergeInto(values); err != nil {
+		scanFindings = append(scanFindings, errorFindings(valuesRuleID, []string{err.Error()})...)
+	}
+
+	// Subcharts see the values their parent passes down, including globals.
+	if inherited := parentScopedValues(chartPath); inherited != nil {
+		mergeMaps(inherited, values)
+		values = inherited
+	}
+	scanFindings = append(scanFindings, errorFindings(valuesRuleID, coalesceSubchartValues(chartPath, values))...)
+
+	// Violations of values.schema.json replace helm's summary of them.
+	schemaFindings := checkValuesSchema(chartPath, values)
+	if rules.ExperimentEnabled(&opts.Config, schemaV2Experiment) {
+		schemaFindings = append(schemaFindings, checkSubchartValuesSchemas(chartPath, values, "")...)
+	}
+	if len(schemaFindings) > 0 {
+		scanFindings = append(withoutHelmSchemaErrors(scanFindings), schemaFindings...)
+	}
+
+	log.stage("value references")
+	var b *blamer
+	if opts.Blame {
+		b = newBlamer()
+	}
+
+	var changes *changeSet
+	if opts.OnlyNewSince != "" {
+		var err error
+		if changes, err = loadChangeSet(chartPath, opts.OnlyNewSince); err != nil {
+			scanFindings = append(scanFindings, errorFindings(scanRuleID, []string{fmt.Sprintf("Error determining changes since %s: %v", opts.OnlyNewSince, err)})...)
+		}
+	}
+
+	s := newSuppressions()
+	checkedReferences, suppressedReferences := s.filterReferences(changes.filterReferences(valueReferences))
+	undefinedValues := checkValueReferences(checkedReferences, values, b)
+	suppressedValues := checkValueReferences(suppressedReferences, values, nil)
+	log.printf("checked value references: %d undefined, %d suppressed", len(undefinedValues), len(suppressedValues))
+	typeFindings := checkValueTypes(checkedReferences, values)
+	scanFindings = append(scanFindings, typeFindings...)
+	log.printf("checked value types: %d mismatches", len(typeFindings))
+
+	if rules.ExperimentEnabled(&opts.Config, astParserExperiment) {
+		rangeFindings := changes.filterTemplateFindings(checkRangeElements(chartPath, values))
+		undefinedValues = append(undefinedValues, rangeFindings...)
+		log.printf("checked ranged values: %d undefined element fields", len(rangeFindings))
+	}
+
+	if len(opts.Config.ReferencePatterns) > 0 {
+		undefinedPatterns, patternErrors := checkReferencePatterns(chartPath, opts.Config.ReferencePatterns, values)
+		scanFindings = append(scanFindings, errorFindings(scanRuleID, patternErrors)...)
+		undefinedValues = append(undefinedValues, changes.filterTemplateFindings(undefinedPatterns)...)
 	}
 
-	undefinedValues := CheckValueReferences(valueReferences, values)
-	allErrors := append(lintErrors, undefinedValues...)
-	success = len(allErrors) == 0
+	if !dependenciesMissing && (rules.AnyEnabled(&opts.Config) || opts.Config.Validation.KubeVersion != "" || opts.Config.PostRenderer.Enabled()) {
+		log.stage("manifests")
+		log.printf("rendering manifests for rule checks")
+		findings, skipped, validationErrors, renderFindings := checkManifests(chartPath, valuesFiles, setValues, valueReferences, opts.Config, log)
+		log.printf("rules reported %d findings, %d skipped, %d validation errors", len(findings), len(skipped), len(validationErrors))
+		if !hasErrorFindings(scanFindings) {
+			scanFindings = append(scanFindings, renderFindings...)
+		}
+		scanFindings = append(scanFindings, errorFindings(validationRuleID, validationErrors)...)
+		result.Findings = findings
+		result.SkippedFindings = skipped
+	}
+
+	if permutations, total := chartPermutations(chartPath, opts.Config.Permutations); len(permutations) > 0 && !dependenciesMissing {
+		log.stage("permutations")
+		permutationFindings := checkPermutations(chartPath, valuesFiles, setValues, permutations, total, opts.Config, log)
+		log.printf("rendered %d of %d value permutations, %d findings", len(permutations), total, len(permutationFindings))
+		scanFindings = append(scanFindings, permutationFindings...)
+	}
+
+	if opts.Config.Determinism.Enabled && !dependenciesMissing {
+		log.stage("determinism")
+		determinismFindings := checkDeterminism(chartPath, valuesFiles, setValues, opts.Config, log)
+		log.printf("rendered the chart twice, %d resources differ", len(determinismFindings))
+		scanFindings = append(scanFindings, determinismFindings...)
+	}
+
+	chartName := info.Name
+	if opts.IncludeDependencies {
+		log.stage("subcharts")
+		result.Dependencies, result.Findings = scanDependencies(chartPath, chartName+"/", values, result.Findings, b, changes, s)
+	}
+	result.Findings, result.SuppressedFindings = s.filterFindings(chartPath, chartName+"/", changes.filterFindings(chartPath, chartName+"/", result.Findings))
+	b.annotateFindings(chartPath, chartName+"/", result.Findings)
 
-	defer cleanupDependencies(chartPath)
+	// Undefined values were filtered by their references and template lines
+	// already.
+	result.Findings = append(append(scanFindings, undefinedValues...), result.Findings...)
+	result.SuppressedFindings = append(suppressedValues, result.SuppressedFindings...)
+	result.Values = values
+	result.Success = !hasErrorFindings(result.Findings) && dependenciesSucceeded(result.Dependencies)
 
-	return success, allErrors, values, undefinedValues
+	return result
 }
 
-// TemplateHelmChart renders a Helm chart using `helm template` and writes
-// the output to stdout or the specified outputFile.
-func TemplateHelmChart(chartPath string, valuesFiles []string, setValues []string, outputFile string) error {
-	if chartPath == "" {
-		return fmt.Errorf("chart path is empty")
+// redactResult redacts the values fetched from sensitive values sources, and
+// the local files they were fetched into, from result and the results of its
+// dependencies.
+func redactResult(result *models.Result) {
+	for _, findings := range [][]models.Finding{result.Findings, result.SuppressedFindings, result.SkippedFindings} {
+		for i := range findings {
+			findings[i].Message = valuessource.Redact(findings[i].Message)
+			findings[i].File = valuessource.Redact(findings[i].File)
+		}
+	}
+	for i, entry := range result.DebugLog {
+		result.DebugLog[i] = valuessource.Redact(entry)
 	}
+	result.ToolError = valuessource.Redact(result.ToolError)
+	valuessource.RedactValues(result.Values)
+	for i := range result.Dependencies {
+		redactResult(&result.Dependencies[i])
+	}
+}
 
-	chartPath = filepath.Clean(chartPath)
-	_, releaseName := filepath.Split(chartPath)
+// recoverScan turns a panic of the scan of the chart at chartPath into a
+// failed result with a tool error, so the remaining charts are still scanned.
+// It must be deferred directly.
+func recoverScan(chartPath string, printStack bool, result *models.Result) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if printStack {
+		fmt.Fprintf(os.Stderr, "panic while scanning %s: %v\n%s\n", chartPath, r, debug.Stack())
+	}
+	toolError := fmt.Sprintf("Internal error while scanning chart: %v (this is a chartscan bug, please report it)", r)
+	*result = models.Result{
+		ChartPath: chartPath,
+		Findings:  errorFindings(scanRuleID, []string{toolError}),
+		ToolError: toolError,
+		DebugLog:  result.DebugLog,
+	}
+}
 
-	if releaseName == "." {
-		currentDir, err := os.Getwd()
+// IncompleteResult is the result of a chart whose scan had not finished when
+// the run was interrupted or timed out, for the given reason.
+func IncompleteResult(chartPath, reason string) models.Result {
+	return models.Result{
+		ChartPath:  chartPath,
+		Findings:   errorFindings(scanRuleID, []string{"Chart was not checked: " + reason}),
+		Incomplete: true,
+	}
+}
+
+// checkManifests renders the chart, passes the output through the configured
+// post-renderer, validates it against Kubernetes schemas when a kube version
+// is configured and evaluates the enabled manifest rules. It returns the findings, the findings skipped by resource
+// annotations, the validation errors and findings for any rendering failures.
+func checkManifests(chartPath string, valuesFiles []string, setValues strvals.Overrides, valueReferences []models.ValueReference, config models.Config, log *scanLog) ([]models.Finding, []models.Finding, []string, []models.Finding) {
+	rendered, err := renderChart(config.ReleaseName, config.Namespace, chartPath, valuesFiles, setValues, config.Validation.KubeVersion, log)
+	if err != nil {
+		var helmErr *helmError
+		if errors.As(err, &helmErr) {
+			if findings := parseHelmOutput(renderRuleID, helmErr.stderr); len(findings) > 0 {
+				return nil, nil, nil, findings
+			}
+		}
+		return nil, nil, nil, errorFindings(renderRuleID, []string{fmt.Sprintf("Error rendering chart for manifest checks: %v", err)})
+	}
+	if rendered, err = postRender(rendered, config.PostRenderer, log); err != nil {
+		return nil, nil, nil, errorFindings(renderRuleID, []string{err.Error()})
+	}
+
+	manifests, err := rules.ParseManifests(rendered)
+	if err != nil {
+		return nil, nil, nil, errorFindings(renderRuleID, []string{err.Error()})
+	}
+
+	var validationErrors []string
+	if config.Validation.KubeVersion != "" {
+		validationErrors = validateManifests(manifests, config.Validation)
+	}
+
+	findings, skipped := rules.Run(&rules.Context{
+		ChartPath:       chartPath,
+		RepoRoot:        gitRepoRoot(chartPath),
+		Manifests:       manifests,
+		ValueReferences: valueReferences,
+		Config:          config,
+	})
+	return findings, skipped, validationErrors, nil
+}
+
+// validateManifests checks each manifest against the schema of its kind and
+// returns one error message per violation.
+func validateManifests(manifests []rules.Manifest, config models.ValidationConfig) []string {
+	validator, err := validation.New(config)
+	if err != nil {
+		return []string{fmt.Sprintf("Error setting up manifest validation: %v", err)}
+	}
+
+	var messages []string
+	for _, m := range manifests {
+		if slices.Contains(config.SkipKinds, m.Kind) {
+			continue
+		}
+
+		location := m.Resource()
+		if m.Source != "" {
+			location += " (" + m.Source + ")"
+		}
+
+		problems, err := validator.Validate(m.Object)
 		if err != nil {
-			return fmt.Errorf("error getting current directory: %v", err)
+			if !config.IgnoreMissingSchemas || !errors.Is(err, validation.ErrSchemaNotFound) {
+				messages = append(messages, fmt.Sprintf("Error validating %s: %v", location, err))
+			}
+			continue
+		}
+		for _, problem := range problems {
+			messages = append(messages, fmt.Sprintf("Invalid manifest %s: %s", location, problem))
 		}
-		_, releaseName = filepath.Split(currentDir)
 	}
+	return messages
+}
 
-	releaseName = strings.TrimSpace(releaseName)
-	if !isValidReleaseName(releaseName) {
-		return fmt.Errorf("invalid release name: %s", releaseName)
+// gitRepoRoot returns the top-level directory of the Git repository that
+// contains path, or an empty string if path is not inside a repository.
+func gitRepoRoot(path string) string {
+	output, err := exec.Command("git", "-C", path, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return ""
 	}
+	return strings.TrimSpace(string(output))
+}
 
-	success, errors := handleDependencies(chartPath)
-	if !success {
-		return fmt.Errorf("error building dependencies: %s", errors)
+// IDs of the findings reported by the scan stages rather than by rules.
+const (
+	lintRuleID         = "helm-lint"
+	templateRuleID     = "template"
+	valuesRuleID       = "values"
+	dependenciesRuleID = "dependencies"
+	renderRuleID       = "render"
+	validationRuleID   = "schema-validation"
+	scanRuleID         = "chartscan"
+)
+
+// Experiments of the scan stages; see rules.RegisterExperiment.
+const (
+	astParserExperiment = "ast-parser"
+	schemaV2Experiment  = "schema-v2"
+)
+
+func init() {
+	rules.RegisterExperiment(astParserExperiment, "Check the fields range blocks read from every element of ranged values")
+	rules.RegisterExperiment(schemaV2Experiment, "Validate the values passed to subcharts against their values.schema.json")
+	// Internal errors of chartscan itself cannot be turned off.
+	rules.RegisterStage(lintRuleID, templateRuleID, templateCycleRuleID, valuesRuleID, valuesSchemaViolationID, dependenciesRuleID, renderRuleID, validationRuleID, UndefinedValueID, valueTypeMismatchID, permutationRuleID, determinismRuleID, unusedValuesFileID)
+}
+
+// errorFindings turns the error messages of a scan stage into error findings
+// of ruleID.
+func errorFindings(ruleID string, messages []string) []models.Finding {
+	var findings []models.Finding
+	for _, message := range messages {
+		findings = append(findings, models.Finding{RuleID: ruleID, Severity: models.SeverityError, Message: message})
 	}
+	return findings
+}
 
-	templateCmd := exec.Command("helm", "template", releaseName, chartPath)
-	for _, vf := range valuesFiles {
-		templateCmd.Args = append(templateCmd.Args, "--values", vf)
+// hasErrorFindings reports whether any finding has error severity.
+func hasErrorFindings(findings []models.Finding) bool {
+	for _, f := range findings {
+		if f.Severity == models.SeverityError {
+			return true
+		}
 	}
-	for _, sv := range setValues {
-		templateCmd.Args = append(templateCmd.Args, "--set", sv)
+	return false
+}
+
+// checkReferencePatterns scans the chart templates for every configured
+// reference pattern and checks each captured name against the pattern's
+// source. It returns the undefined references and any errors reading the
+// templates or the sources of the patterns.
+func checkReferencePatterns(chartPath string, patterns []models.ReferencePattern, values map[string]interface{}) ([]models.Finding, []string) {
+	var undefined []models.Finding
+	templateFiles, errors := findTemplateFiles(chartPath)
+
+	for _, pattern := range patterns {
+		name := referencePatternName(pattern)
+		// Invalid patterns are rejected when the config is loaded.
+		re, err := compileReferencePattern(pattern)
+		if err != nil {
+			continue
+		}
+
+		lookup, err := referenceSourceLookup(pattern, values)
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("Error loading source for reference pattern %s: %v", name, err))
+			continue
+		}
+
+		for _, templateFile := range templateFiles {
+			data, err := os.ReadFile(templateFile)
+			if err != nil {
+				errors = append(errors, fmt.Sprintf("Error reading template file %s: %v", templateFile, err))
+				continue
+			}
+			for i, line := range strings.Split(string(data), "\n") {
+				for _, match := range re.FindAllStringSubmatch(line, -1) {
+					if !lookup(match[1]) {
+						undefined = append(undefined, models.Finding{
+							RuleID:   UndefinedValueID,
+							Severity: models.SeverityError,
+							Message:  fmt.Sprintf("Undefined %s reference: '%s'", name, match[1]),
+							File:     templateFile,
+							Line:     i + 1,
+						})
+					}
+				}
+			}
+		}
 	}
 
-	var templateStdout, templateStderr bytes.Buffer
-	templateCmd.Stdout = &templateStdout
-	templateCmd.Stderr = &templateStderr
+	return undefined, errors
+}
+
+// ValidateReferencePatterns returns an error for the first of patterns that
+// is not a regular expression with exactly one capture group or names an
+// unknown source.
+func ValidateReferencePatterns(patterns []models.ReferencePattern) error {
+	for _, pattern := range patterns {
+		if _, err := compileReferencePattern(pattern); err != nil {
+			return err
+		}
+		switch pattern.Source {
+		case "", "values", "env", "file":
+		default:
+			return fmt.Errorf("reference pattern %s has unknown source %q (expected values, env or file)", referencePatternName(pattern), pattern.Source)
+		}
+	}
+	return nil
+}
+
+// compileReferencePattern compiles the regular expression of pattern, which
+// must contain exactly one capture group.
+func compileReferencePattern(pattern models.ReferencePattern) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid reference pattern %s: %v", referencePatternName(pattern), err)
+	}
+	if re.NumSubexp() != 1 {
+		return nil, fmt.Errorf("reference pattern %s must contain exactly one capture group", referencePatternName(pattern))
+	}
+	return re, nil
+}
+
+// referencePatternName returns the name of pattern, or the pattern itself if
+// it has none.
+func referencePatternName(pattern models.ReferencePattern) string {
+	if pattern.Name == "" {
+		return pattern.Pattern
+	}
+	return pattern.Name
+}
+
+// referenceSourceLookup returns a function reporting whether a name captured
+// by pattern is defined in the pattern's source.
+func referenceSourceLookup(pattern models.ReferencePattern, values map[string]interface{}) (func(string) bool, error) {
+	switch pattern.Source {
+	case "", "values":
+		return func(name string) bool {
+			return checkNestedValueExists(strings.Split(name, "."), values)
+		}, nil
+	case "env":
+		return func(name string) bool {
+			_, exists := os.LookupEnv(name)
+			return exists
+		}, nil
+	case "file":
+		fileValues := make(map[string]interface{})
+		for _, file := range pattern.Files {
+			loaded, err := loadReferenceFile(file)
+			if err != nil {
+				return nil, err
+			}
+			mergeMaps(fileValues, loaded)
+		}
+		return func(name string) bool {
+			if _, exists := fileValues[name]; exists {
+				return true
+			}
+			return checkNestedValueExists(strings.Split(name, "."), fileValues)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown source %q (expected values, env or file)", pattern.Source)
+	}
+}
+
+// loadReferenceFile loads a source file for a reference pattern. Files ending
+// in .env are read as KEY=VALUE lines, everything else as YAML.
+func loadReferenceFile(file string) (map[string]interface{}, error) {
+	if !strings.HasSuffix(file, ".env") {
+		return ValuesLoader(file)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]interface{})
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, _ := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return values, nil
+}
 
-	if err := templateCmd.Run(); err != nil {
-		return fmt.Errorf("error running helm template: %v\nstderr: %s", err, templateStderr.String())
+// TemplateHelmChart renders a Helm chart using `helm template` and
+// postRenderer and writes the output to stdout or the specified outputFile.
+func TemplateHelmChart(chartPath, releaseName, namespace string, valuesFiles []string, setValues strvals.Overrides, outputFile, cacheDir string, dependencies models.DependenciesConfig, postRenderer models.PostRendererConfig) error {
+	rendered, err := RenderHelmChart(chartPath, releaseName, namespace, valuesFiles, setValues, cacheDir, dependencies, postRenderer)
+	if err != nil {
+		return err
 	}
 
 	if outputFile == "" {
-		fmt.Println(templateStdout.String())
+		fmt.Println(rendered)
 	} else {
 		file, err := os.OpenFile(outputFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 		if err != nil {
@@ -233,7 +1097,7 @@ func TemplateHelmChart(chartPath string, valuesFiles []string, setValues []strin
 		}
 		defer file.Close()
 
-		if _, err := file.Write(templateStdout.Bytes()); err != nil {
+		if _, err := file.WriteString(rendered); err != nil {
 			return fmt.Errorf("error writing to output file %s: %v", outputFile, err)
 		}
 		if _, err := file.Write([]byte("\n")); err != nil {
@@ -241,52 +1105,183 @@ func TemplateHelmChart(chartPath string, valuesFiles []string, setValues []strin
 		}
 	}
 
-	defer cleanupDependencies(chartPath)
 	return nil
 }
 
-// isValidReleaseName returns true if name matches Helm's release name regex.
-func isValidReleaseName(name string) bool {
-	const releaseNamePattern = `^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
-	return regexp.MustCompile(releaseNamePattern).MatchString(name)
-}
+// RenderHelmChart renders a Helm chart with `helm template` as release
+// releaseName, by default named after its directory, in namespace, and
+// returns the rendered manifests, passed through postRenderer when one is
+// configured. Dependencies are updated first, as configured by dependencies.
+func RenderHelmChart(chartPath, releaseName, namespace string, valuesFiles []string, setValues strvals.Overrides, cacheDir string, dependencies models.DependenciesConfig, postRenderer models.PostRendererConfig) (string, error) {
+	if chartPath == "" {
+		return "", fmt.Errorf("chart path is empty")
+	}
 
-// handleDependencies checks for and runs `helm dependency update` if the chart
-// has declared dependencies. Returns success and any error messages.
-func handleDependencies(chartPath string) (bool, []string) {
-	chartYamlPath := filepath.Join(chartPath, "Chart.yaml")
-	hasDependencies, err := checkForDependencies(chartYamlPath)
+	chartPath = filepath.Clean(chartPath)
+	if releaseName == "" {
+		_, releaseName = filepath.Split(chartPath)
+	}
+
+	if releaseName == "." {
+		currentDir, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("error getting current directory: %v", err)
+		}
+		_, releaseName = filepath.Split(currentDir)
+	}
+
+	releaseName = strings.TrimSpace(releaseName)
+	if !isValidReleaseName(releaseName) {
+		return "", fmt.Errorf("invalid release name: %s", releaseName)
+	}
+
+	valuesFiles, err := valuessource.Resolve(valuesFiles)
 	if err != nil {
-		return false, []string{fmt.Sprintf("Error reading Chart.yaml: %v", err)}
+		return "", err
 	}
 
-	if !hasDependencies {
-		return true, nil
+	success, errors, cleanup := handleDependencies(chartPath, cacheDir, dependencies, nil)
+	if !success {
+		return "", fmt.Errorf("error building dependencies: %s", errors)
 	}
+	defer cleanup()
 
-	cacheDir, err := os.MkdirTemp("", "chartscan")
+	rendered, err := renderChart(releaseName, namespace, chartPath, valuesFiles, setValues, "", nil)
 	if err != nil {
-		return false, []string{fmt.Sprintf("Error creating temp cache dir: %v", err)}
+		return "", err
 	}
-	defer os.RemoveAll(cacheDir)
+	return postRender(rendered, postRenderer, nil)
+}
 
-	dependencyCmd := exec.Command("helm", "dependency", "update", "--repository-cache", cacheDir, chartPath)
-	if err := dependencyCmd.Run(); err != nil {
-		return false, []string{fmt.Sprintf("Error updating dependencies: %v", err)}
+// renderChart runs `helm template` on the chart and returns the rendered
+// manifests. An empty releaseName lets helm pick its default name and an
+// empty namespace its default namespace. A kubeVersion is passed on as
+// .Capabilities.KubeVersion, so charts choosing their API versions by it
+// render what the target cluster would get.
+func renderChart(releaseName, namespace, chartPath string, valuesFiles []string, setValues strvals.Overrides, kubeVersion string, log *scanLog) (string, error) {
+	templateCmd := helmCommand("template")
+	if releaseName != "" {
+		templateCmd.Args = append(templateCmd.Args, releaseName)
+	}
+	templateCmd.Args = append(templateCmd.Args, chartPath)
+	if namespace != "" {
+		templateCmd.Args = append(templateCmd.Args, "--namespace", namespace)
+	}
+	for _, vf := range valuesFiles {
+		templateCmd.Args = append(templateCmd.Args, "--values", vf)
+	}
+	templateCmd.Args = append(templateCmd.Args, setValues.Args()...)
+	if kubeVersion != "" {
+		templateCmd.Args = append(templateCmd.Args, "--kube-version", kubeVersion)
 	}
 
-	return true, nil
+	var templateStdout, templateStderr bytes.Buffer
+	templateCmd.Stdout = &templateStdout
+	templateCmd.Stderr = &templateStderr
+
+	started := time.Now()
+	err := templateCmd.Run()
+	log.command(templateCmd, started, templateStdout.String(), templateStderr.String(), err)
+	if err != nil {
+		return "", &helmError{command: "template", err: err, stderr: templateStderr.String()}
+	}
+
+	return templateStdout.String(), nil
 }
 
-// cleanupDependencies removes the `charts/` directory and `Chart.lock` produced
-// by a previous `helm dependency update` call.
-func cleanupDependencies(chartPath string) {
-	chartsDir := filepath.Join(chartPath, "charts")
-	chartLockFile := filepath.Join(chartPath, "Chart.lock")
-	defer func() {
-		os.RemoveAll(chartsDir)
-		os.Remove(chartLockFile)
-	}()
+// RegistryOptions holds the credentials for pulling charts from OCI
+// registries. Empty fields fall back to helm's own registry configuration,
+// e.g. logins made with `helm registry login`.
+type RegistryOptions struct {
+	Username       string
+	Password       string
+	RegistryConfig string
+}
+
+// IsOCIReference reports whether target is an oci:// chart reference rather
+// than a local path.
+func IsOCIReference(target string) bool {
+	return strings.HasPrefix(target, "oci://")
+}
+
+// PullChart runs `helm pull` for an oci:// chart reference and unpacks the
+// chart into a new temporary directory. It returns the chart directory and the
+// temporary directory, which the caller must remove.
+func PullChart(ref string, opts RegistryOptions) (string, string, error) {
+	tempDir, err := os.MkdirTemp("", "chartscan-oci")
+	if err != nil {
+		return "", "", fmt.Errorf("error creating temp dir: %v", err)
+	}
+
+	chartRef, version := splitOCIReference(ref)
+	pullCmd := helmCommand("pull", chartRef, "--untar", "--untardir", tempDir)
+	if version != "" {
+		pullCmd.Args = append(pullCmd.Args, "--version", version)
+	}
+	if opts.Username != "" {
+		pullCmd.Args = append(pullCmd.Args, "--username", opts.Username)
+	}
+	if opts.Password != "" {
+		pullCmd.Args = append(pullCmd.Args, "--password", opts.Password)
+	}
+	if opts.RegistryConfig != "" {
+		pullCmd.Args = append(pullCmd.Args, "--registry-config", opts.RegistryConfig)
+	}
+
+	var pullStderr bytes.Buffer
+	pullCmd.Stderr = &pullStderr
+	if err := pullCmd.Run(); err != nil {
+		os.RemoveAll(tempDir)
+		return "", "", fmt.Errorf("error running helm pull for %s: %v\nstderr: %s", ref, err, pullStderr.String())
+	}
+
+	entries, err := os.ReadDir(tempDir)
+	if err != nil || len(entries) != 1 || !entries[0].IsDir() {
+		os.RemoveAll(tempDir)
+		return "", "", fmt.Errorf("unexpected contents after pulling %s", ref)
+	}
+
+	return filepath.Join(tempDir, entries[0].Name()), tempDir, nil
+}
+
+// splitOCIReference splits a tag off an oci:// reference, since helm pull
+// expects the chart version as a separate flag. Digest references are kept.
+func splitOCIReference(ref string) (string, string) {
+	lastSlash := strings.LastIndex(ref, "/")
+	if strings.Contains(ref[lastSlash+1:], "@") {
+		return ref, ""
+	}
+	if i := strings.LastIndex(ref, ":"); i > lastSlash {
+		return ref[:i], ref[i+1:]
+	}
+	return ref, ""
+}
+
+// isValidReleaseName returns true if name matches Helm's release name regex.
+func isValidReleaseName(name string) bool {
+	const releaseNamePattern = `^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	return regexp.MustCompile(releaseNamePattern).MatchString(name)
+}
+
+// ChartValuesFiles returns the values files to render the chart at chartPath
+// with. With relativeTo set to models.ValuesFilesRelativeToChart, relative
+// paths are joined with chartPath and files the chart does not have are left
+// out; otherwise valuesFiles are returned as they are.
+func ChartValuesFiles(chartPath string, valuesFiles []string, relativeTo string) []string {
+	if relativeTo != models.ValuesFilesRelativeToChart {
+		return valuesFiles
+	}
+	var files []string
+	for _, file := range valuesFiles {
+		if !filepath.IsAbs(file) && !valuessource.IsURI(file) {
+			file = filepath.Join(chartPath, file)
+			if _, err := os.Stat(file); os.IsNotExist(err) {
+				continue
+			}
+		}
+		files = append(files, file)
+	}
+	return files
 }
 
 // checkValuesFilesExistence returns error messages for any values file that
@@ -301,45 +1296,136 @@ func checkValuesFilesExistence(valuesFiles []string) []string {
 	return errors
 }
 
-// lintChart runs `helm lint --strict` on the chart and returns any error messages.
-func lintChart(chartPath string, valuesFiles []string, setValues []string) []string {
-	lintCmd := exec.Command("helm", "lint", "--strict", chartPath)
+// lintChart runs `helm lint` on the chart, with --strict unless config turns
+// it off, and returns its messages as findings filtered by config.
+func lintChart(chartPath, namespace string, valuesFiles []string, setValues strvals.Overrides, config models.LintConfig, log *scanLog) []models.Finding {
+	lintCmd := helmCommand("lint", chartPath)
+	if namespace != "" {
+		lintCmd.Args = append(lintCmd.Args, "--namespace", namespace)
+	}
+	if config.Strict == nil || *config.Strict {
+		lintCmd.Args = append(lintCmd.Args, "--strict")
+	}
 	for _, vf := range valuesFiles {
 		lintCmd.Args = append(lintCmd.Args, "--values", vf)
 	}
-	for _, sv := range setValues {
-		lintCmd.Args = append(lintCmd.Args, "--set", sv)
-	}
+	lintCmd.Args = append(lintCmd.Args, setValues.Args()...)
 
 	var lintStdout, lintStderr bytes.Buffer
 	lintCmd.Stdout = &lintStdout
 	lintCmd.Stderr = &lintStderr
 
-	if err := lintCmd.Run(); err != nil {
-		return parseErrorLogs(lintStdout.String() + lintStderr.String())
+	started := time.Now()
+	err := lintCmd.Run()
+	log.command(lintCmd, started, lintStdout.String(), lintStderr.String(), err)
+
+	return filterLintFindings(parseLintOutput(lintStdout.String()+lintStderr.String()), config.Messages)
+}
+
+// lintIgnore is the severity of lint messages that are dropped.
+const lintIgnore = "ignore"
+
+// filterLintFindings applies the severity of the first matching entry of
+// messages to each lint finding and drops the ignored ones. Invalid entries
+// are reported as errors.
+func filterLintFindings(findings []models.Finding, messages []models.LintMessage) []models.Finding {
+	var errors []string
+	var patterns []*regexp.Regexp
+	var severities []string
+	for _, message := range messages {
+		pattern, err := regexp.Compile(message.Pattern)
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("Invalid lint message pattern %q: %v", message.Pattern, err))
+			continue
+		}
+		if message.Severity != lintIgnore && models.SeverityRank(message.Severity) < 0 {
+			errors = append(errors, fmt.Sprintf("Invalid severity %q for lint message pattern %q (expected %s or %s)", message.Severity, message.Pattern, strings.Join(models.Severities, ", "), lintIgnore))
+			continue
+		}
+		patterns = append(patterns, pattern)
+		severities = append(severities, message.Severity)
 	}
 
-	return nil
+	filtered := errorFindings(lintRuleID, errors)
+	for _, finding := range findings {
+		severity := finding.Severity
+		for i, pattern := range patterns {
+			if pattern.MatchString(finding.Message) {
+				severity = severities[i]
+				break
+			}
+		}
+		if severity == lintIgnore {
+			continue
+		}
+		finding.Severity = severity
+		filtered = append(filtered, finding)
+	}
+	return filtered
 }
 
-// parseTemplates walks the chart's templates/ directory, parses YAML files,
-// and returns all extracted value references together with any error messages.
-func parseTemplates(chartPath string) ([]models.ValueReference, []string) {
+// templateExtensions are the extensions of the template files Helm renders or
+// loads named templates from that are parsed for value references.
+var templateExtensions = []string{".yaml", ".yml", ".tpl", ".txt"}
+
+// ParseTemplates walks the chart's templates/ directory, parses its template
+// files, and returns all extracted value references together with any error
+// messages. Named templates are analyzed across files, so the references in
+// helpers are attributed to the templates that include them.
+func ParseTemplates(chartPath string) ([]models.ValueReference, []string) {
+	sources, defines, valueReferences, errors := loadTemplateSources(chartPath)
+	valueReferences = append(valueReferences, walkTemplateSources(sources, defines)...)
+	return valueReferences, errors
+}
+
+// loadTemplateSources parses the template files of the chart's templates/
+// directory. Files that do not parse as templates are scanned for value
+// references as text instead, which are returned along with any error
+// messages.
+func loadTemplateSources(chartPath string) ([]*templateSource, map[string]*namedTemplate, []models.ValueReference, []string) {
 	var valueReferences []models.ValueReference
+	var sources []*templateSource
+	defines := make(map[string]*namedTemplate)
+
+	templateFiles, errors := findTemplateFiles(chartPath)
+	for _, path := range templateFiles {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("Error parsing template file %s: %v", path, err))
+			continue
+		}
+		if source, ok := parseTemplateSource(path, string(content), defines); ok {
+			sources = append(sources, source)
+			continue
+		}
+		refs, err := parseTemplateText(path, string(content))
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("Error parsing template file %s: %v", path, err))
+		} else {
+			valueReferences = append(valueReferences, refs...)
+		}
+	}
+	return sources, defines, valueReferences, errors
+}
+
+// findTemplateFiles returns the files below the chart's templates/ directory
+// with one of the templateExtensions, along with any error messages.
+func findTemplateFiles(chartPath string) ([]string, []string) {
+	var templateFiles []string
 	var errors []string
 
 	templatesDir := filepath.Join(chartPath, "templates")
 	info, err := os.Stat(templatesDir)
 	if os.IsNotExist(err) {
-		return valueReferences, errors
+		return templateFiles, errors
 	}
 	if err != nil {
 		errors = append(errors, fmt.Sprintf("Error accessing templates directory: %v", err))
-		return valueReferences, errors
+		return templateFiles, errors
 	}
 	if !info.IsDir() {
 		errors = append(errors, fmt.Sprintf("Expected templates to be a directory but found a file: %s", templatesDir))
-		return valueReferences, errors
+		return templateFiles, errors
 	}
 
 	err = filepath.Walk(templatesDir, func(path string, info os.FileInfo, walkErr error) error {
@@ -347,22 +1433,15 @@ func parseTemplates(chartPath string) ([]models.ValueReference, []string) {
 			errors = append(errors, fmt.Sprintf("Error accessing file %s: %v", path, walkErr))
 			return nil
 		}
-		if !info.IsDir() && strings.HasSuffix(info.Name(), ".yaml") {
-			refs, err := TemplateParser(path)
-			if err != nil {
-				errors = append(errors, fmt.Sprintf("Error parsing template file %s: %v", path, err))
-			} else {
-				valueReferences = append(valueReferences, refs...)
-			}
+		if !info.IsDir() && slices.Contains(templateExtensions, filepath.Ext(info.Name())) {
+			templateFiles = append(templateFiles, path)
 		}
 		return nil
 	})
-
 	if err != nil {
 		errors = append(errors, fmt.Sprintf("Error walking templates directory: %v", err))
 	}
-
-	return valueReferences, errors
+	return templateFiles, errors
 }
 
 // loadAndMergeValues loads the chart's values.yaml and any additional values
@@ -397,37 +1476,10 @@ func loadAndMergeValues(chartPath string, valuesFiles []string) (map[string]inte
 	return values, errors
 }
 
-// checkForDependencies reads Chart.yaml and returns true if the chart has a
-// non-empty dependencies list.
-func checkForDependencies(chartYamlPath string) (bool, error) {
-	data, err := os.ReadFile(chartYamlPath)
-	if err != nil {
-		return false, err
-	}
-
-	var chartData map[string]interface{}
-	if err = yaml.Unmarshal(data, &chartData); err != nil {
-		return false, err
-	}
-
-	dependencies, ok := chartData["dependencies"]
-	if !ok {
-		return false, nil
-	}
-
-	depsList, ok := dependencies.([]interface{})
-	return ok && len(depsList) > 0, nil
-}
-
-// parseErrorLogs scans Helm command output and returns lines containing "[ERROR]".
-func parseErrorLogs(output string) []string {
-	var errorMessages []string
-	for _, line := range strings.Split(output, "\n") {
-		if strings.Contains(line, "[ERROR]") {
-			errorMessages = append(errorMessages, line)
-		}
-	}
-	return errorMessages
+// parseLintOutput returns a finding for every message in the output of a
+// helm lint run.
+func parseLintOutput(output string) []models.Finding {
+	return parseHelmOutput(lintRuleID, output)
 }
 
 // colorSymbol returns a green or red colored symbol based on success.
@@ -451,21 +1503,24 @@ func colorize(s string, c string) string {
 	}
 }
 
-// PrintResultsPretty prints the scan results as a formatted table, followed
-// by a summary line with counts and elapsed time.
-func PrintResultsPretty(results []models.Result, duration time.Duration) {
-	table := tablewriter.NewTable(os.Stdout,
+// PrintResultsPretty writes the scan results to w as a formatted table,
+// followed by a summary line with counts and elapsed time.
+func PrintResultsPretty(w io.Writer, results []models.Result, duration time.Duration) {
+	table := tablewriter.NewTable(w,
 		tablewriter.WithHeader([]string{"Chart Name", "Success", "Details"}),
 		tablewriter.WithRowAlignment(tw.AlignLeft),
 	)
 
 	var validCharts, invalidCharts int
 
-	for _, result := range results {
+	for _, result := range models.FlattenResults(results) {
 		chartName, err := getChartName(result.ChartPath)
 		if err != nil {
 			chartName = result.ChartPath
 		}
+		if result.Environment != "" {
+			chartName += " (" + result.Environment + ")"
+		}
 
 		successStr := colorSymbol("✔", result.Success)
 		if result.Success {
@@ -475,8 +1530,19 @@ func PrintResultsPretty(results []models.Result, duration time.Duration) {
 			invalidCharts++
 		}
 
+		var details []string
+		for _, finding := range result.Findings {
+			details = append(details, finding.String())
+		}
+		if suppressed := len(result.SuppressedFindings); suppressed > 0 {
+			details = append(details, fmt.Sprintf("%d suppressed by chartscan:ignore", suppressed))
+		}
+		if skipped := len(result.SkippedFindings); skipped > 0 {
+			details = append(details, fmt.Sprintf("%d skipped by %s", skipped, rules.SkipAnnotation))
+		}
+
 		errorDetails := ""
-		if sanitized := sanitizeErrors(result.Errors); len(sanitized) > 0 {
+		if sanitized := sanitizeErrors(details); len(sanitized) > 0 {
 			errorDetails = "• " + strings.Join(sanitized, "\n• ")
 		}
 
@@ -485,7 +1551,39 @@ func PrintResultsPretty(results []models.Result, duration time.Duration) {
 
 	table.Render() //nolint:errcheck
 
-	fmt.Printf("\nSummary: %d valid charts, %d invalid charts scanned in %v\n", validCharts, invalidCharts, duration)
+	fmt.Fprintf(w, "\nSummary: %d valid charts, %d invalid charts scanned in %v\n", validCharts, invalidCharts, duration)
+	printStatistics(w, ComputeStatistics(results))
+	PrintEnvironmentMatrix(w, results)
+}
+
+// StatusLine returns the line printed for result as soon as its chart is
+// scanned, before the table, e.g. "✘ charts/api: 2 errors, 1 warning (3.4s)".
+// Findings of subcharts are counted with their parent, and findings below
+// threshold are left out, as they are from the report.
+func StatusLine(result models.Result, threshold string) string {
+	counts := make(map[string]int)
+	for _, r := range models.FlattenResults(ApplySeverityThreshold([]models.Result{result}, threshold)) {
+		for _, finding := range r.Findings {
+			counts[finding.Severity]++
+		}
+	}
+	var parts []string
+	for _, severity := range []string{models.SeverityError, models.SeverityWarning, models.SeverityInfo} {
+		if counts[severity] > 0 {
+			parts = append(parts, plural(counts[severity], severity))
+		}
+	}
+
+	symbol := "✔"
+	if !result.Success {
+		symbol = "✘"
+	}
+	line := colorSymbol(symbol, result.Success) + " " + result.ChartPath
+	if len(parts) > 0 {
+		line += ": " + strings.Join(parts, ", ")
+	}
+	duration := time.Duration(result.DurationSeconds * float64(time.Second))
+	return fmt.Sprintf("%s (%v)", line, duration.Round(100*time.Millisecond))
 }
 
 // sanitizeErrors replaces problematic characters in error messages and wraps
@@ -595,39 +1693,3 @@ func getChartName(chartPath string) (string, error) {
 
 	return name, nil
 }
-
-// mergeSetValues parses "key=value" strings and sets the resulting values in
-// the values map, creating nested maps for dot-separated key paths.
-// Boolean and integer values are parsed automatically.
-func mergeSetValues(values map[string]interface{}, setValues []string) {
-	for _, sv := range setValues {
-		parts := strings.SplitN(sv, "=", 2)
-		if len(parts) != 2 {
-			continue
-		}
-
-		keyPath, valueStr := parts[0], parts[1]
-
-		var value interface{} = valueStr
-		if b, err := strconv.ParseBool(valueStr); err == nil {
-			value = b
-		} else if i, err := strconv.Atoi(valueStr); err == nil {
-			value = i
-		} else if f, err := strconv.ParseFloat(valueStr, 64); err == nil {
-			value = f
-		}
-
-		keys := strings.Split(keyPath, ".")
-		currentMap := values
-		for i, key := range keys {
-			if i == len(keys)-1 {
-				currentMap[key] = value
-				break
-			}
-			if _, ok := currentMap[key].(map[string]interface{}); !ok {
-				currentMap[key] = make(map[string]interface{})
-			}
-			currentMap = currentMap[key].(map[string]interface{})
-		}
-	}
-}
@@ -3,9 +3,12 @@ package renderer
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/Jaydee94/chartscan/internal/models"
+	"github.com/Jaydee94/chartscan/internal/strvals"
+	"github.com/fatih/color"
 )
 
 func TestValuesLoader(t *testing.T) {
@@ -116,43 +119,414 @@ func TestSanitizeErrors(t *testing.T) {
 	}
 }
 
-func TestMergeSetValues(t *testing.T) {
+func TestTemplateParser_IndexExpressions(t *testing.T) {
+	tempDir := t.TempDir()
+	templateFile := filepath.Join(tempDir, "ingress.yaml")
+	templateContent := []byte(`
+host: {{ .Values.ingress.hosts[0].host }}
+annotation: {{ index .Values.podAnnotations "example.com/scrape.port" }}
+path: {{ index .Values "ingress" "paths" 1 }}
+`)
+	if err := os.WriteFile(templateFile, templateContent, 0644); err != nil {
+		t.Fatalf("Failed to create test template file: %v", err)
+	}
+
+	refs, err := TemplateParser(templateFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []struct {
+		name string
+		path []string
+	}{
+		{"ingress.hosts[0].host", []string{"ingress", "hosts", "0", "host"}},
+		{`podAnnotations["example.com/scrape.port"]`, []string{"podAnnotations", "example.com/scrape.port"}},
+		{"ingress.paths[1]", []string{"ingress", "paths", "1"}},
+	}
+
+	if len(refs) != len(expected) {
+		t.Fatalf("Expected %d value references, got %d", len(expected), len(refs))
+	}
+
+	for i, want := range expected {
+		if refs[i].Name != want.name {
+			t.Errorf("Expected name '%s', got '%s'", want.name, refs[i].Name)
+		}
+		if strings.Join(refs[i].Path, "|") != strings.Join(want.path, "|") {
+			t.Errorf("Expected path %v, got %v", want.path, refs[i].Path)
+		}
+	}
+}
+
+func TestTemplateParser_EmptyActions(t *testing.T) {
+	tempDir := t.TempDir()
+	templateFile := filepath.Join(tempDir, "configmap.yaml")
+	templateContent := []byte(`
+{{ }}
+host: {{ .Values.hosts[0].host }}
+{{- -}}
+`)
+	if err := os.WriteFile(templateFile, templateContent, 0644); err != nil {
+		t.Fatalf("Failed to create test template file: %v", err)
+	}
+
+	refs, err := TemplateParser(templateFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(refs) != 1 || refs[0].Name != "hosts[0].host" {
+		t.Errorf("Expected the hosts[0].host reference, got %v", refs)
+	}
+}
+
+func TestCheckValueReferences_ListIndices(t *testing.T) {
+	refs := []models.ValueReference{
+		{Name: "ingress.hosts[0].host", Path: []string{"ingress", "hosts", "0", "host"}, File: "test.yaml", Line: 1},
+		{Name: "ingress.hosts[2].host", Path: []string{"ingress", "hosts", "2", "host"}, File: "test.yaml", Line: 2},
+		{Name: `podAnnotations["example.com/scrape"]`, Path: []string{"podAnnotations", "example.com/scrape"}, File: "test.yaml", Line: 3},
+	}
+
 	values := map[string]interface{}{
-		"existing": "value",
+		"ingress": map[string]interface{}{
+			"hosts": []interface{}{
+				map[string]interface{}{"host": "a.example.com"},
+			},
+		},
+		"podAnnotations": map[string]interface{}{
+			"example.com/scrape": "true",
+		},
 	}
 
-	setValues := []string{
-		"newStr=strvalue",
-		"newInt=123",
-		"newFloat=1.23",
-		"newBool=true",
-		"nested.key=val",
+	undefined := CheckValueReferences(refs, values)
+
+	if len(undefined) != 1 {
+		t.Fatalf("Expected 1 undefined reference, got %d: %v", len(undefined), undefined)
+	}
+	if !strings.Contains(undefined[0].Message, "ingress.hosts[2].host") {
+		t.Errorf("Expected out-of-range index to be reported, got %s", undefined[0].Message)
 	}
+}
 
-	mergeSetValues(values, setValues)
+func TestTemplateParser_GuardedReferences(t *testing.T) {
+	tempDir := t.TempDir()
+	templateFile := filepath.Join(tempDir, "service.yaml")
+	templateContent := []byte(`
+{{- if hasKey .Values "metrics" }}
+port: {{ .Values.metrics.port }}
+{{- else }}
+port: {{ .Values.port }}
+{{- end }}
+timeout: {{ dig "probe" "timeout" 5 .Values }}
+ports: {{ pluck "port" .Values.primary .Values.secondary | first }}
+`)
+	if err := os.WriteFile(templateFile, templateContent, 0644); err != nil {
+		t.Fatalf("Failed to create test template file: %v", err)
+	}
 
-	if values["existing"] != "value" {
-		t.Errorf("Expected existing=value, got %v", values["existing"])
+	refs, err := TemplateParser(templateFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
 	}
-	if values["newStr"] != "strvalue" {
-		t.Errorf("Expected newStr=strvalue, got %v", values["newStr"])
+
+	byName := map[string]models.ValueReference{}
+	for _, ref := range refs {
+		byName[ref.Name] = ref
 	}
-	if values["newInt"] != 123 {
-		t.Errorf("Expected newInt=123, got %v", values["newInt"])
+
+	if guards := byName["metrics.port"].Guards; len(guards) != 1 || strings.Join(guards[0], ".") != "metrics" {
+		t.Errorf("Expected metrics.port to be guarded by metrics, got %v", guards)
 	}
-	if values["newFloat"] != 1.23 {
-		t.Errorf("Expected newFloat=1.23, got %v", values["newFloat"])
+	if guards := byName["port"].Guards; len(guards) != 0 {
+		t.Errorf("Expected else branch to be unguarded, got %v", guards)
 	}
-	if values["newBool"] != true {
-		t.Errorf("Expected newBool=true, got %v", values["newBool"])
+	for _, name := range []string{"probe.timeout", "primary.port", "secondary.port"} {
+		if ref, ok := byName[name]; !ok || !ref.Optional {
+			t.Errorf("Expected optional reference %s, got %+v", name, ref)
+		}
 	}
 
-	nested, ok := values["nested"].(map[string]interface{})
-	if !ok {
-		t.Fatalf("Expected nested to be map[string]interface{}, got %T", values["nested"])
+	undefined := CheckValueReferences(refs, map[string]interface{}{})
+	if len(undefined) != 1 || !strings.Contains(undefined[0].Message, "'port'") {
+		t.Fatalf("Expected only the unguarded port reference to be undefined, got %v", undefined)
+	}
+
+	undefined = CheckValueReferences(refs, map[string]interface{}{
+		"port":    8080,
+		"metrics": map[string]interface{}{},
+	})
+	if len(undefined) != 1 || !strings.Contains(undefined[0].Message, "metrics.port") {
+		t.Fatalf("Expected metrics.port to be undefined once its guard holds, got %v", undefined)
+	}
+}
+
+func TestCheckReferencePatterns(t *testing.T) {
+	chartDir := t.TempDir()
+	templatesDir := filepath.Join(chartDir, "templates")
+	if err := os.Mkdir(templatesDir, 0755); err != nil {
+		t.Fatalf("Failed to create templates dir: %v", err)
+	}
+	templateContent := []byte(`
+image: ${REGISTRY}/app:${TAG}
+domain: ${DOMAIN}
+`)
+	if err := os.WriteFile(filepath.Join(templatesDir, "deployment.yaml"), templateContent, 0644); err != nil {
+		t.Fatalf("Failed to create test template file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(templatesDir, "_helpers.tpl"), []byte("{{- define \"app.region\" }}${REGION}{{ end }}\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test helper file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(templatesDir, "NOTES.txt"), []byte("Open https://${DOMAIN}\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test notes file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(templatesDir, "service.yml"), []byte("port: ${PORT}\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test template file: %v", err)
+	}
+	envFile := filepath.Join(chartDir, "vars.env")
+	if err := os.WriteFile(envFile, []byte("# shared\nexport DOMAIN=example.com\n"), 0644); err != nil {
+		t.Fatalf("Failed to create env file: %v", err)
+	}
+
+	t.Setenv("REGISTRY", "registry.example.com")
+
+	patterns := []models.ReferencePattern{
+		{Name: "envsubst", Pattern: `\$\{([A-Z_]+)\}`, Source: "env"},
+		{Name: "vars", Pattern: `\$\{(DOMAIN)\}`, Source: "file", Files: []string{envFile}},
+	}
+
+	undefined, errs := checkReferencePatterns(chartDir, patterns, nil)
+	if len(errs) != 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+
+	// REGION, TAG, DOMAIN and PORT are missing from the environment; DOMAIN
+	// is defined in vars.env.
+	var got []string
+	for _, finding := range undefined {
+		got = append(got, filepath.Base(finding.File)+": "+finding.Message)
+	}
+	expected := []string{
+		"NOTES.txt: Undefined envsubst reference: 'DOMAIN'",
+		"_helpers.tpl: Undefined envsubst reference: 'REGION'",
+		"deployment.yaml: Undefined envsubst reference: 'TAG'",
+		"deployment.yaml: Undefined envsubst reference: 'DOMAIN'",
+		"service.yml: Undefined envsubst reference: 'PORT'",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected undefined references %q, got %q", expected, got)
+	}
+
+}
+
+func TestValidateReferencePatterns(t *testing.T) {
+	tests := []struct {
+		pattern models.ReferencePattern
+		err     string
+	}{
+		{models.ReferencePattern{Name: "envsubst", Pattern: `\$\{([A-Z_]+)\}`, Source: "env"}, ""},
+		{models.ReferencePattern{Name: "bad", Pattern: `\$\{[A-Z]+\}`}, "reference pattern bad must contain exactly one capture group"},
+		{models.ReferencePattern{Pattern: `\$\{(`}, "invalid reference pattern \\$\\{(: "},
+		{models.ReferencePattern{Name: "vault", Pattern: `vault:(\w+)`, Source: "vault"}, `reference pattern vault has unknown source "vault"`},
+	}
+	for _, tt := range tests {
+		err := ValidateReferencePatterns([]models.ReferencePattern{tt.pattern})
+		if tt.err == "" && err != nil {
+			t.Errorf("Expected %v to be valid, got %v", tt.pattern, err)
+		}
+		if tt.err != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.err)) {
+			t.Errorf("Expected an error starting with %q for %v, got %v", tt.err, tt.pattern, err)
+		}
+	}
+}
+
+func TestSplitOCIReference(t *testing.T) {
+	tests := []struct {
+		ref, chartRef, version string
+	}{
+		{"oci://registry.example.com/myrepo/mychart:1.2.3", "oci://registry.example.com/myrepo/mychart", "1.2.3"},
+		{"oci://registry.example.com:5000/mychart", "oci://registry.example.com:5000/mychart", ""},
+		{"oci://registry.example.com/mychart@sha256:abc", "oci://registry.example.com/mychart@sha256:abc", ""},
+	}
+	for _, tt := range tests {
+		chartRef, version := splitOCIReference(tt.ref)
+		if chartRef != tt.chartRef || version != tt.version {
+			t.Errorf("Expected %s to split into %s and %q, got %s and %q", tt.ref, tt.chartRef, tt.version, chartRef, version)
+		}
+	}
+}
+
+func TestChartValuesFiles(t *testing.T) {
+	chart := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(chart, "ci"), 0755); err != nil {
+		t.Fatalf("Failed to create ci dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(chart, "ci", "test-values.yaml"), []byte("replicas: 1\n"), 0644); err != nil {
+		t.Fatalf("Failed to write values file: %v", err)
+	}
+	valuesFiles := []string{"ci/test-values.yaml", "ci/lint-values.yaml", "/etc/chartscan/shared.yaml", "vault://secret/data/shop"}
+
+	files := ChartValuesFiles(chart, valuesFiles, models.ValuesFilesRelativeToChart)
+	expected := []string{filepath.Join(chart, "ci", "test-values.yaml"), "/etc/chartscan/shared.yaml", "vault://secret/data/shop"}
+	if strings.Join(files, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, files)
+	}
+
+	if files := ChartValuesFiles(chart, valuesFiles, models.ValuesFilesRelativeToConfig); len(files) != len(valuesFiles) {
+		t.Errorf("Expected values files unchanged, got %v", files)
 	}
+}
+
+func TestRecoverScan(t *testing.T) {
+	scan := func() (result models.Result) {
+		defer recoverScan("charts/broken", false, &result)
+		result = models.Result{ChartPath: "charts/broken", Success: true}
+		var values map[string]interface{}
+		values["boom"] = true
+		return result
+	}
+
+	result := scan()
+	if result.Success {
+		t.Errorf("Expected a panicking scan to fail")
+	}
+	if result.ChartPath != "charts/broken" {
+		t.Errorf("Expected chart path charts/broken, got %s", result.ChartPath)
+	}
+	if !strings.Contains(result.ToolError, "assignment to entry in nil map") {
+		t.Errorf("Expected the panic in the tool error, got '%s'", result.ToolError)
+	}
+	if len(result.Findings) != 1 || result.Findings[0].Message != result.ToolError || result.Findings[0].Severity != models.SeverityError {
+		t.Errorf("Expected the tool error as an error finding, got %v", result.Findings)
+	}
+}
+
+func TestIncompleteResult(t *testing.T) {
+	result := IncompleteResult("charts/slow", "the scan timed out after 1m0s")
+	if !result.Incomplete || result.Success || result.ChartPath != "charts/slow" {
+		t.Errorf("Expected an incomplete, failed result for charts/slow, got %+v", result)
+	}
+	expected := "[error] chartscan: Chart was not checked: the scan timed out after 1m0s"
+	if len(result.Findings) != 1 || result.Findings[0].String() != expected {
+		t.Errorf("Expected the finding '%s', got %v", expected, result.Findings)
+	}
+}
 
-	if nested["key"] != "val" {
-		t.Errorf("Expected nested.key=val, got %v", nested["key"])
+func TestScanHelmChartDuration(t *testing.T) {
+	result := ScanHelmChart("", ScanOptions{})
+	if len(result.Findings) != 1 || result.Success {
+		t.Fatalf("Expected an error for an empty chart path, got %v", result.Findings)
+	}
+	if result.DurationSeconds <= 0 {
+		t.Errorf("Expected the scan time to be recorded, got %v", result.DurationSeconds)
+	}
+}
+
+func TestParseLintOutput(t *testing.T) {
+	output := `==> Linting charts/web
+[INFO] Chart.yaml: icon is recommended
+[WARNING] templates/: directory not found
+[ERROR] templates/deployment.yaml: unable to parse YAML: error converting YAML to JSON
+
+Error: 1 chart(s) linted, 1 chart(s) failed
+`
+	findings := parseLintOutput(output)
+	if len(findings) != 3 {
+		t.Fatalf("Expected 3 findings, got %d: %v", len(findings), findings)
+	}
+	expected := []models.Finding{
+		{RuleID: "helm-lint", Severity: models.SeverityInfo, File: "Chart.yaml", Message: "icon is recommended"},
+		{RuleID: "helm-lint", Severity: models.SeverityWarning, File: "templates/", Message: "directory not found"},
+		{RuleID: "helm-lint", Severity: models.SeverityError, File: "templates/deployment.yaml", Message: "unable to parse YAML: error converting YAML to JSON"},
+	}
+	for i, finding := range findings {
+		if finding != expected[i] {
+			t.Errorf("Expected %v, got %v", expected[i], finding)
+		}
+	}
+}
+
+func TestFilterLintFindings(t *testing.T) {
+	findings := []models.Finding{
+		{RuleID: "helm-lint", Severity: models.SeverityInfo, File: "Chart.yaml", Message: "icon is recommended"},
+		{RuleID: "helm-lint", Severity: models.SeverityWarning, File: "templates/", Message: "directory not found"},
+		{RuleID: "helm-lint", Severity: models.SeverityError, File: "values.yaml", Message: "unable to parse YAML"},
+	}
+	messages := []models.LintMessage{
+		{Pattern: "^icon is recommended$", Severity: "ignore"},
+		{Pattern: "directory", Severity: models.SeverityError},
+		{Pattern: "not found", Severity: models.SeverityInfo},
+		{Pattern: "(", Severity: models.SeverityInfo},
+		{Pattern: "YAML", Severity: "fatal"},
+	}
+
+	var got []string
+	for _, finding := range filterLintFindings(findings, messages) {
+		got = append(got, finding.String())
+	}
+	expected := []string{
+		`[error] helm-lint: Invalid lint message pattern "(": error parsing regexp: missing closing ): ` + "`(`",
+		`[error] helm-lint: Invalid severity "fatal" for lint message pattern "YAML" (expected info, warning, error or ignore)`,
+		"[error] helm-lint: templates/: directory not found",
+		"[error] helm-lint: values.yaml: unable to parse YAML",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected findings:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestStatusLine(t *testing.T) {
+	color.NoColor = true
+
+	result := models.Result{ChartPath: "charts/web", Success: true, DurationSeconds: 1.23}
+	if line, expected := StatusLine(result, ""), "✔ charts/web (1.2s)"; line != expected {
+		t.Errorf("Expected %q, got %q", expected, line)
+	}
+
+	result = models.Result{
+		ChartPath: "charts/api",
+		Findings: []models.Finding{
+			{Severity: models.SeverityError},
+			{Severity: models.SeverityWarning},
+		},
+		Dependencies: []models.Result{{
+			ChartPath: "charts/api/charts/db",
+			Findings:  []models.Finding{{Severity: models.SeverityError}},
+		}},
+		DurationSeconds: 3.4,
+	}
+	if line, expected := StatusLine(result, ""), "✘ charts/api: 2 errors, 1 warning (3.4s)"; line != expected {
+		t.Errorf("Expected %q, got %q", expected, line)
+	}
+	if line, expected := StatusLine(result, models.SeverityError), "✘ charts/api: 2 errors (3.4s)"; line != expected {
+		t.Errorf("Expected %q, got %q", expected, line)
+	}
+}
+
+func TestHelmReleaseNameAndNamespace(t *testing.T) {
+	bin := t.TempDir()
+	writeFakeHelm(t, bin, "v3.15.2")
+	t.Setenv("PATH", bin)
+	chartDir := filepath.Join(t.TempDir(), "web")
+	writeChart(t, chartDir, "apiVersion: v2\nname: web\nversion: 0.1.0\n", "")
+
+	if _, err := renderChart("shop", "payments", chartDir, nil, strvals.Overrides{}, "", nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	lintChart(chartDir, "payments", nil, strvals.Overrides{}, models.LintConfig{}, nil)
+	if _, err := RenderHelmChart(chartDir, "", "", nil, strvals.Overrides{}, "", models.DependenciesConfig{}, models.PostRendererConfig{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(bin, "args"))
+	if err != nil {
+		t.Fatalf("Failed to read helm arguments: %v", err)
+	}
+	expected := []string{
+		"template shop " + chartDir + " --namespace payments",
+		"lint " + chartDir + " --namespace payments --strict",
+		"template web " + chartDir,
+	}
+	if got := strings.Split(strings.TrimSpace(string(data)), "\n"); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected helm runs %q, got %q", expected, got)
 	}
 }
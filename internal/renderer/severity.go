@@ -0,0 +1,54 @@
+package renderer
+
+import (
+	"github.com/Jaydee94/chartscan/internal/models"
+	"github.com/Jaydee94/chartscan/internal/rules"
+)
+
+// applyRuleOverrides applies the rules section of config to the findings of
+// the scan stages in result and its dependencies, which rules.Run does not
+// see, and updates their success accordingly.
+func applyRuleOverrides(result *models.Result, config *models.Config) {
+	if len(config.Rules) == 0 || result.ToolError != "" {
+		return
+	}
+	result.Findings = rules.ApplyOverrides(config, result.Findings)
+	for i := range result.Dependencies {
+		applyRuleOverrides(&result.Dependencies[i], config)
+	}
+	result.Success = !hasErrorFindings(result.Findings) && dependenciesSucceeded(result.Dependencies)
+}
+
+// ApplySeverityThreshold removes the findings below threshold from results and
+// their dependencies, so they are neither printed nor considered for the exit
+// status. An empty threshold keeps every finding. Success is unaffected, since
+// only error findings fail a chart.
+func ApplySeverityThreshold(results []models.Result, threshold string) []models.Result {
+	if threshold == "" {
+		return results
+	}
+
+	filtered := make([]models.Result, 0, len(results))
+	for _, result := range results {
+		result.Findings = FindingsAtThreshold(result.Findings, threshold)
+		result.Dependencies = ApplySeverityThreshold(result.Dependencies, threshold)
+		filtered = append(filtered, result)
+	}
+	return filtered
+}
+
+// FindingsAtThreshold returns the findings of threshold or higher severity,
+// such as those of the whole run. An empty threshold keeps every finding.
+func FindingsAtThreshold(findings []models.Finding, threshold string) []models.Finding {
+	if threshold == "" {
+		return findings
+	}
+	minimum := models.SeverityRank(threshold)
+	var kept []models.Finding
+	for _, finding := range findings {
+		if models.SeverityRank(finding.Severity) >= minimum {
+			kept = append(kept, finding)
+		}
+	}
+	return kept
+}
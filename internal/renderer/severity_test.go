@@ -0,0 +1,75 @@
+package renderer
+
+import (
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestApplySeverityThreshold(t *testing.T) {
+	results := []models.Result{{
+		ChartPath: "charts/web",
+		Findings: []models.Finding{
+			{RuleID: "helm-lint", Severity: models.SeverityInfo, Message: "icon is recommended"},
+			{RuleID: "liveness-probe", Severity: models.SeverityWarning, Message: "container web has no livenessProbe"},
+			{RuleID: UndefinedValueID, Severity: models.SeverityError, Message: "Undefined value: 'image.tag'"},
+		},
+		Dependencies: []models.Result{{
+			ChartPath: "charts/web/charts/common",
+			Findings:  []models.Finding{{RuleID: "resource-limits", Severity: models.SeverityWarning}},
+		}},
+	}}
+
+	if filtered := ApplySeverityThreshold(results, ""); len(filtered[0].Findings) != 3 {
+		t.Errorf("Expected no threshold to keep all 3 findings, got %v", filtered[0].Findings)
+	}
+
+	filtered := ApplySeverityThreshold(results, models.SeverityWarning)
+	if len(filtered[0].Findings) != 2 || filtered[0].Findings[0].Severity != models.SeverityWarning {
+		t.Errorf("Expected the warning and the error, got %v", filtered[0].Findings)
+	}
+	if len(filtered[0].Dependencies[0].Findings) != 1 {
+		t.Errorf("Expected the dependency warning to be kept, got %v", filtered[0].Dependencies[0].Findings)
+	}
+
+	filtered = ApplySeverityThreshold(results, models.SeverityError)
+	if len(filtered[0].Findings) != 1 || filtered[0].Findings[0].RuleID != UndefinedValueID {
+		t.Errorf("Expected only the undefined value, got %v", filtered[0].Findings)
+	}
+	if len(filtered[0].Dependencies[0].Findings) != 0 {
+		t.Errorf("Expected the dependency warning to be removed, got %v", filtered[0].Dependencies[0].Findings)
+	}
+	if len(results[0].Findings) != 3 {
+		t.Errorf("Expected the original results to be unchanged, got %v", results[0].Findings)
+	}
+}
+
+func TestApplyRuleOverrides(t *testing.T) {
+	disabled := false
+	config := models.Config{Rules: map[string]models.RuleConfig{
+		lintRuleID:       {Severity: models.SeverityWarning},
+		UndefinedValueID: {Enabled: &disabled},
+	}}
+	result := models.Result{
+		ChartPath: "charts/web",
+		Findings: []models.Finding{
+			{RuleID: lintRuleID, Severity: models.SeverityError, Message: "templates/: parse error"},
+			{RuleID: scanRuleID, Severity: models.SeverityWarning, Message: "Error determining changes"},
+		},
+		Dependencies: []models.Result{{
+			ChartPath: "charts/web/charts/common",
+			Findings:  []models.Finding{{RuleID: UndefinedValueID, Severity: models.SeverityError, Message: "Undefined value: 'a'"}},
+		}},
+	}
+
+	applyRuleOverrides(&result, &config)
+	if !result.Success || !result.Dependencies[0].Success {
+		t.Errorf("Expected the chart and its dependency to succeed without error findings, got %v", result)
+	}
+	if len(result.Findings) != 2 || result.Findings[0].Severity != models.SeverityWarning {
+		t.Errorf("Expected the lint error to become a warning, got %v", result.Findings)
+	}
+	if len(result.Dependencies[0].Findings) != 0 {
+		t.Errorf("Expected the undefined value of the dependency to be dropped, got %v", result.Dependencies[0].Findings)
+	}
+}
@@ -0,0 +1,328 @@
+package renderer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Jaydee94/chartscan/internal/finder"
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// subchart is a chart in the charts/ directory of a parent chart. Key is the
+// parent values key the subchart is scoped to: its alias, or its name. Path is
+// its directory or archive in charts/, and Dir the directory holding its
+// files, which differs from Path for extracted archives.
+type subchart struct {
+	Key       string
+	Path      string
+	Dir       string
+	Condition string
+	Tags      []string
+}
+
+// chartDependency is a dependency declared in Chart.yaml.
+type chartDependency struct {
+	Name       string   `yaml:"name"`
+	Version    string   `yaml:"version"`
+	Repository string   `yaml:"repository"`
+	Alias      string   `yaml:"alias"`
+	Condition  string   `yaml:"condition"`
+	Tags       []string `yaml:"tags"`
+}
+
+// coalesceSubchartValues applies Helm's value scoping to values, the merged
+// values of the chart at chartPath. For every subchart, its default values
+// are merged below values[key], and the parent's globals are copied into the
+// subchart's global values, taking precedence over the subchart's own. Nested
+// subcharts are handled recursively. values is modified in place.
+func coalesceSubchartValues(chartPath string, values map[string]interface{}) []string {
+	subcharts, cleanup, errors := findSubcharts(chartPath)
+	defer cleanup()
+
+	for _, sub := range subcharts {
+		scoped, err := scopeSubchartValues(sub, values)
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("Error loading values of subchart %s: %v", sub.Key, err))
+			continue
+		}
+		if scoped == nil {
+			continue
+		}
+		values[sub.Key] = scoped
+		errors = append(errors, coalesceSubchartValues(sub.Dir, scoped)...)
+	}
+
+	return errors
+}
+
+// scopeSubchartValues returns the values seen by sub: its defaults overridden
+// by the parent's values at sub.Key, with the parent's globals. It returns nil
+// if the parent sets sub.Key to something other than a map.
+func scopeSubchartValues(sub subchart, parentValues map[string]interface{}) (map[string]interface{}, error) {
+	parentScoped, ok := parentValues[sub.Key].(map[string]interface{})
+	if !ok && parentValues[sub.Key] != nil {
+		return nil, nil
+	}
+
+	scoped := make(map[string]interface{})
+	defaultsFile := filepath.Join(sub.Dir, "values.yaml")
+	if _, err := os.Stat(defaultsFile); err == nil {
+		defaults, err := ValuesLoader(defaultsFile)
+		if err != nil {
+			return nil, err
+		}
+		if defaults != nil {
+			scoped = defaults
+		}
+	}
+	mergeMaps(scoped, parentScoped)
+
+	globals, _ := scoped["global"].(map[string]interface{})
+	if globals == nil {
+		globals = make(map[string]interface{})
+	}
+	if parentGlobals, ok := parentValues["global"].(map[string]interface{}); ok {
+		mergeMaps(globals, parentGlobals)
+	}
+	if len(globals) > 0 {
+		scoped["global"] = globals
+	}
+
+	return scoped, nil
+}
+
+// parentScopedValues returns the values Helm passes to the chart at chartPath
+// when it is a subchart in the charts/ directory of another chart, computed
+// from the parent's default values. It returns nil if chartPath is not a
+// subchart. Errors in the parent are ignored here; they are reported when the
+// parent itself is scanned.
+func parentScopedValues(chartPath string) map[string]interface{} {
+	chartsDir := filepath.Dir(filepath.Clean(chartPath))
+	parentPath := filepath.Dir(chartsDir)
+	if filepath.Base(chartsDir) != "charts" {
+		return nil
+	}
+	if stat, err := os.Stat(filepath.Join(parentPath, "Chart.yaml")); err != nil || !stat.Mode().IsRegular() {
+		return nil
+	}
+
+	// A parent that is a subchart itself sees its defaults overridden by its
+	// own parent.
+	parentValues := parentScopedValues(parentPath)
+	if parentValues == nil {
+		parentValues, _ = loadAndMergeValues(parentPath, nil)
+	}
+
+	subcharts, cleanup, _ := findSubcharts(parentPath)
+	defer cleanup()
+
+	for _, sub := range subcharts {
+		if filepath.Clean(sub.Dir) == filepath.Clean(chartPath) {
+			scoped, _ := scopeSubchartValues(sub, parentValues)
+			return scoped
+		}
+	}
+	return nil
+}
+
+// findSubcharts returns the subcharts of the chart at chartPath: chart
+// directories and packaged charts in its charts/ directory. A subchart declared
+// several times under different aliases is returned once per alias. Packaged
+// charts are extracted to temporary directories removed by cleanup.
+func findSubcharts(chartPath string) ([]subchart, func(), []string) {
+	var tempDirs []string
+	cleanup := func() {
+		for _, dir := range tempDirs {
+			os.RemoveAll(dir)
+		}
+	}
+
+	chartsDir := filepath.Join(chartPath, "charts")
+	entries, err := os.ReadDir(chartsDir)
+	if os.IsNotExist(err) {
+		return nil, cleanup, nil
+	}
+	if err != nil {
+		return nil, cleanup, []string{fmt.Sprintf("Error reading charts directory: %v", err)}
+	}
+
+	dependencies, err := chartDependencies(filepath.Join(chartPath, "Chart.yaml"))
+	if err != nil {
+		return nil, cleanup, []string{fmt.Sprintf("Error reading Chart.yaml: %v", err)}
+	}
+
+	var subcharts []subchart
+	var errors []string
+	for _, entry := range entries {
+		path := filepath.Join(chartsDir, entry.Name())
+		dir := path
+		if !entry.IsDir() {
+			if !finder.IsChartArchive(path) {
+				continue
+			}
+			chartDir, tempDir, err := finder.ExtractChartArchive(path)
+			if err != nil {
+				errors = append(errors, fmt.Sprintf("Error extracting subchart: %v", err))
+				continue
+			}
+			tempDirs = append(tempDirs, tempDir)
+			dir = chartDir
+		}
+
+		name, err := getChartName(dir)
+		if err != nil {
+			continue
+		}
+		declared := false
+		for _, dependency := range dependencies {
+			if dependency.Name != name {
+				continue
+			}
+			declared = true
+			key := dependency.Alias
+			if key == "" {
+				key = name
+			}
+			subcharts = append(subcharts, subchart{Key: key, Path: path, Dir: dir, Condition: dependency.Condition, Tags: dependency.Tags})
+		}
+		if !declared {
+			subcharts = append(subcharts, subchart{Key: name, Path: path, Dir: dir})
+		}
+	}
+
+	return subcharts, cleanup, errors
+}
+
+// chartDependencies returns the dependencies declared in Chart.yaml.
+func chartDependencies(chartYamlPath string) ([]chartDependency, error) {
+	data, err := os.ReadFile(chartYamlPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var chartData struct {
+		Dependencies []chartDependency `yaml:"dependencies"`
+	}
+	if err := yaml.Unmarshal(data, &chartData); err != nil {
+		return nil, err
+	}
+	return chartData.Dependencies, nil
+}
+
+// subchartEnabled evaluates the condition and tags of sub against the parent's
+// values the way Helm does: the first condition path holding a boolean
+// decides; otherwise a subchart whose tags are all set to false is disabled.
+func subchartEnabled(sub subchart, parentValues map[string]interface{}) bool {
+	if sub.Condition != "" {
+		for _, condition := range strings.Split(sub.Condition, ",") {
+			value := lookupValue(strings.Split(strings.TrimSpace(condition), "."), parentValues)
+			if enabled, ok := value.(bool); ok {
+				return enabled
+			}
+		}
+	}
+
+	var hasTrue, hasFalse bool
+	for _, tag := range sub.Tags {
+		switch lookupValue([]string{"tags", tag}, parentValues) {
+		case true:
+			hasTrue = true
+		case false:
+			hasFalse = true
+		}
+	}
+	return hasTrue || !hasFalse
+}
+
+// lookupValue returns the value at keys within values, or nil.
+func lookupValue(keys []string, values map[string]interface{}) interface{} {
+	var current interface{} = values
+	for _, key := range keys {
+		next, exists := lookupPathSegment(current, key)
+		if !exists {
+			return nil
+		}
+		current = next
+	}
+	return current
+}
+
+// scanDependencies checks the templates of the enabled subcharts of the chart
+// at chartPath against their scoped values, recursively. values are the
+// chart's coalesced values and sourcePrefix is the path of the chart's
+// templates in rendered output (e.g. "app/"). Findings located in a subchart's
+// templates are moved from findings into its result; the remaining findings
+// are returned. b, if set, annotates the problems of subcharts with git blame,
+// changes, if set, limits them to changed lines and s separates those
+// acknowledged by ignore directives.
+func scanDependencies(chartPath, sourcePrefix string, values map[string]interface{}, findings []models.Finding, b *blamer, changes *changeSet, s *suppressions) ([]models.Result, []models.Finding) {
+	subcharts, cleanup, _ := findSubcharts(chartPath)
+	defer cleanup()
+
+	var results []models.Result
+	for _, sub := range subcharts {
+		if !subchartEnabled(sub, values) {
+			continue
+		}
+
+		// Report extracted archives by their location in charts/.
+		displayPath := func(s string) string { return strings.ReplaceAll(s, sub.Dir, sub.Path) }
+
+		valueReferences, templateErrors := ParseTemplates(sub.Dir)
+		// Directives are read before extracted archives are renamed.
+		valueReferences, suppressedReferences := s.filterReferences(valueReferences)
+		for i := range valueReferences {
+			valueReferences[i].File = displayPath(valueReferences[i].File)
+		}
+		for i := range suppressedReferences {
+			suppressedReferences[i].File = displayPath(suppressedReferences[i].File)
+		}
+		for i := range templateErrors {
+			templateErrors[i] = displayPath(templateErrors[i])
+		}
+
+		scoped, _ := values[sub.Key].(map[string]interface{})
+		if scoped == nil {
+			scoped = make(map[string]interface{})
+		}
+		undefinedValues := checkValueReferences(changes.filterReferences(valueReferences), scoped, b)
+		suppressedValues := checkValueReferences(changes.filterReferences(suppressedReferences), scoped, nil)
+
+		subPrefix := sourcePrefix + "charts/" + sub.Key + "/"
+		var own []models.Finding
+		var rest []models.Finding
+		for _, finding := range findings {
+			if strings.HasPrefix(finding.File, subPrefix) {
+				own = append(own, finding)
+			} else {
+				rest = append(rest, finding)
+			}
+		}
+		findings = rest
+
+		result := models.Result{ChartPath: sub.Path}
+		result.Dependencies, result.Findings = scanDependencies(sub.Dir, subPrefix, scoped, own, b, changes, s)
+		result.Findings, result.SuppressedFindings = s.filterFindings(sub.Dir, subPrefix, changes.filterFindings(sub.Dir, subPrefix, result.Findings))
+		b.annotateFindings(sub.Dir, subPrefix, result.Findings)
+		result.Findings = append(append(errorFindings(templateRuleID, templateErrors), undefinedValues...), result.Findings...)
+		result.SuppressedFindings = append(suppressedValues, result.SuppressedFindings...)
+		result.Success = !hasErrorFindings(result.Findings) && dependenciesSucceeded(result.Dependencies)
+		results = append(results, result)
+	}
+
+	return results, findings
+}
+
+// dependenciesSucceeded reports whether every dependency result succeeded.
+func dependenciesSucceeded(results []models.Result) bool {
+	for _, result := range results {
+		if !result.Success {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,156 @@
+package renderer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// writeChart creates a chart directory with the given Chart.yaml and
+// values.yaml contents.
+func writeChart(t *testing.T, dir, chartYaml, valuesYaml string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create chart dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Chart.yaml"), []byte(chartYaml), 0644); err != nil {
+		t.Fatalf("Failed to write Chart.yaml: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "values.yaml"), []byte(valuesYaml), 0644); err != nil {
+		t.Fatalf("Failed to write values.yaml: %v", err)
+	}
+}
+
+func TestCoalesceSubchartValues(t *testing.T) {
+	parent := t.TempDir()
+	writeChart(t, parent, `
+apiVersion: v2
+name: app
+dependencies:
+  - name: redis
+    alias: cache
+  - name: redis
+    alias: queue
+`, "")
+	writeChart(t, filepath.Join(parent, "charts", "redis"), "apiVersion: v2\nname: redis\n", `
+port: 6379
+auth:
+  enabled: true
+global:
+  registry: docker.io
+`)
+
+	values := map[string]interface{}{
+		"global": map[string]interface{}{"registry": "registry.example.com", "env": "prod"},
+		"cache":  map[string]interface{}{"port": 6380},
+	}
+	if errors := coalesceSubchartValues(parent, values); len(errors) != 0 {
+		t.Fatalf("Unexpected errors: %v", errors)
+	}
+
+	for _, key := range []string{"cache", "queue"} {
+		if !checkNestedValueExists([]string{key, "auth", "enabled"}, values) {
+			t.Errorf("Expected %s.auth.enabled from subchart defaults, got %v", key, values[key])
+		}
+		if !checkNestedValueExists([]string{key, "global", "env"}, values) {
+			t.Errorf("Expected parent globals in %s, got %v", key, values[key])
+		}
+	}
+	cache := values["cache"].(map[string]interface{})
+	if cache["port"] != 6380 {
+		t.Errorf("Expected parent value 6380 to override default port, got %v", cache["port"])
+	}
+	if registry := cache["global"].(map[string]interface{})["registry"]; registry != "registry.example.com" {
+		t.Errorf("Expected parent global registry to take precedence, got %v", registry)
+	}
+	if checkNestedValueExists([]string{"redis"}, values) {
+		t.Errorf("Expected aliased subchart not to be scoped under its name, got %v", values["redis"])
+	}
+}
+
+func TestParentScopedValues(t *testing.T) {
+	parent := t.TempDir()
+	writeChart(t, parent, "apiVersion: v2\nname: app\n", `
+global:
+  domain: example.com
+common:
+  labels:
+    team: platform
+`)
+	sub := filepath.Join(parent, "charts", "common")
+	writeChart(t, sub, "apiVersion: v2\nname: common\n", "replicas: 1\n")
+
+	values := parentScopedValues(sub)
+	if !checkNestedValueExists([]string{"global", "domain"}, values) {
+		t.Errorf("Expected global.domain from parent, got %v", values)
+	}
+	if !checkNestedValueExists([]string{"labels", "team"}, values) {
+		t.Errorf("Expected labels.team from parent, got %v", values)
+	}
+	if !checkNestedValueExists([]string{"replicas"}, values) {
+		t.Errorf("Expected replicas from subchart defaults, got %v", values)
+	}
+
+	if values := parentScopedValues(parent); values != nil {
+		t.Errorf("Expected nil for a top-level chart, got %v", values)
+	}
+}
+
+func TestScanDependencies(t *testing.T) {
+	parent := t.TempDir()
+	writeChart(t, parent, `
+apiVersion: v2
+name: app
+dependencies:
+  - name: common
+    alias: web
+  - name: metrics
+    condition: metrics.enabled
+`, "")
+	common := filepath.Join(parent, "charts", "common")
+	writeChart(t, common, "apiVersion: v2\nname: common\n", "port: 80\n")
+	os.MkdirAll(filepath.Join(common, "templates"), 0755)
+	os.WriteFile(filepath.Join(common, "templates", "service.yaml"), []byte("port: {{ .Values.port }}\nhost: {{ .Values.host }}\nenv: {{ .Values.global.env }}\n"), 0644)
+	metrics := filepath.Join(parent, "charts", "metrics")
+	writeChart(t, metrics, "apiVersion: v2\nname: metrics\n", "")
+	os.MkdirAll(filepath.Join(metrics, "templates"), 0755)
+	os.WriteFile(filepath.Join(metrics, "templates", "monitor.yaml"), []byte("interval: {{ .Values.interval }}\n"), 0644)
+
+	values := map[string]interface{}{
+		"global":  map[string]interface{}{"env": "prod"},
+		"metrics": map[string]interface{}{"enabled": false},
+	}
+	coalesceSubchartValues(parent, values)
+
+	findings := []models.Finding{
+		{RuleID: "pod-security", Severity: models.SeverityError, File: "app/charts/web/templates/deployment.yaml"},
+		{RuleID: "pod-security", Severity: models.SeverityError, File: "app/templates/deployment.yaml"},
+	}
+	results, remaining := scanDependencies(parent, "app/", values, findings, nil, nil, nil)
+
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 dependency result (metrics disabled), got %d", len(results))
+	}
+	result := results[0]
+	if result.ChartPath != common {
+		t.Errorf("Expected chart path %s, got %s", common, result.ChartPath)
+	}
+	if len(result.Findings) != 2 {
+		t.Fatalf("Expected an undefined value and the subchart finding, got %v", result.Findings)
+	}
+	if result.Findings[0].RuleID != UndefinedValueID || !strings.Contains(result.Findings[0].Message, "'host'") {
+		t.Errorf("Expected only host to be undefined, got %v", result.Findings[0])
+	}
+	if result.Findings[1].File != "app/charts/web/templates/deployment.yaml" {
+		t.Errorf("Expected the subchart finding to be attributed to the dependency, got %v", result.Findings[1])
+	}
+	if len(remaining) != 1 || remaining[0].File != "app/templates/deployment.yaml" {
+		t.Errorf("Expected the parent finding to remain, got %v", remaining)
+	}
+	if result.Success {
+		t.Errorf("Expected dependency with undefined values to fail")
+	}
+}
@@ -0,0 +1,202 @@
+package renderer
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+	"github.com/Jaydee94/chartscan/internal/rules"
+)
+
+// summaryTopN is the number of rules and charts listed in the pretty summary.
+const summaryTopN = 5
+
+// ComputeStatistics counts the findings of results and their dependencies by
+// severity, rule and chart, and groups their undefined values by key.
+func ComputeStatistics(results []models.Result) models.Statistics {
+	stats := models.Statistics{FindingsBySeverity: map[string]int{}}
+	byRule := map[string]int{}
+
+	for _, result := range models.FlattenResults(results) {
+		for _, finding := range result.Findings {
+			stats.FindingsBySeverity[finding.Severity]++
+			byRule[finding.RuleID]++
+		}
+		stats.Skipped += len(result.SkippedFindings)
+		if len(result.Findings) > 0 {
+			stats.ChartsByFindings = append(stats.ChartsByFindings, models.ChartCount{ChartPath: result.ChartPath, Count: len(result.Findings)})
+		}
+	}
+
+	for ruleID, count := range byRule {
+		stats.FindingsByRule = append(stats.FindingsByRule, models.RuleCount{RuleID: ruleID, Count: count})
+	}
+	sort.Slice(stats.FindingsByRule, func(i, j int) bool {
+		a, b := stats.FindingsByRule[i], stats.FindingsByRule[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.RuleID < b.RuleID
+	})
+	sort.SliceStable(stats.ChartsByFindings, func(i, j int) bool {
+		return stats.ChartsByFindings[i].Count > stats.ChartsByFindings[j].Count
+	})
+	if index := IndexUndefinedValues(results); len(index) > 0 {
+		stats.UndefinedValues = index
+	}
+
+	return stats
+}
+
+// NewReport wraps results and the findings of the whole run in a report with
+// the totals of the scan, which took duration and was run by ChartScan
+// version with config. The credentials
+// of dependency repositories are redacted from the config, since reports are
+// often kept as CI artifacts.
+func NewReport(results []models.Result, findings []models.Finding, duration time.Duration, version string, config models.Config) models.Report {
+	summary := models.ReportSummary{FindingsBySeverity: map[string]int{}}
+	for _, result := range models.FlattenResults(results) {
+		summary.Charts++
+		if result.Success {
+			summary.Passed++
+		} else {
+			summary.Failed++
+		}
+		if result.Incomplete {
+			summary.Incomplete++
+		}
+		for _, finding := range result.Findings {
+			summary.FindingsBySeverity[finding.Severity]++
+		}
+	}
+	for _, finding := range findings {
+		summary.FindingsBySeverity[finding.Severity]++
+	}
+	if results == nil {
+		results = []models.Result{}
+	}
+	return models.Report{
+		Version:         version,
+		DurationSeconds: duration.Seconds(),
+		Summary:         summary,
+		Experiments:     config.Experimental,
+		Rules:           RulesRun(config),
+		Config:          redactConfig(config),
+		Results:         results,
+		Findings:        findings,
+	}
+}
+
+// RulesRun returns the IDs of the rules and scan stages that a scan under
+// config runs, sorted. Stages that depend on the configuration, such as
+// schema validation, are only included when it turns them on.
+func RulesRun(config models.Config) []string {
+	ids := []string{dependenciesRuleID, lintRuleID, templateRuleID, templateCycleRuleID, valuesRuleID, valuesSchemaViolationID, UndefinedValueID, valueTypeMismatchID}
+	manifestRules := rules.EnabledIDs(&config)
+	if len(manifestRules) > 0 || config.Validation.KubeVersion != "" || config.PostRenderer.Enabled() {
+		ids = append(ids, renderRuleID)
+	}
+	if config.Validation.KubeVersion != "" {
+		ids = append(ids, validationRuleID)
+	}
+	if len(config.Permutations.Charts) > 0 {
+		ids = append(ids, permutationRuleID)
+	}
+	if config.Determinism.Enabled {
+		ids = append(ids, determinismRuleID)
+	}
+	if len(config.ValuesFiles) > 0 {
+		ids = append(ids, unusedValuesFileID)
+	}
+	ids = append(ids, manifestRules...)
+	// Stages turned off in the rules section report nothing.
+	ids = slices.DeleteFunc(ids, func(id string) bool {
+		enabled := config.Rules[id].Enabled
+		return enabled != nil && !*enabled
+	})
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
+
+// redactedCredential replaces the username and password of dependency
+// repositories in reports.
+const redactedCredential = "[redacted]"
+
+// redactConfig returns a copy of config whose dependency repository
+// credentials are replaced by redactedCredential.
+func redactConfig(config models.Config) models.Config {
+	repositories := config.Dependencies.Repositories
+	if len(repositories) == 0 {
+		return config
+	}
+	config.Dependencies.Repositories = make([]models.RepositoryCredentials, len(repositories))
+	for i, credentials := range repositories {
+		if credentials.Username != "" {
+			credentials.Username = redactedCredential
+		}
+		if credentials.Password != "" {
+			credentials.Password = redactedCredential
+		}
+		config.Dependencies.Repositories[i] = credentials
+	}
+	return config
+}
+
+// printStatistics writes the findings breakdown below the pretty summary to
+// w. Nothing but the number of skipped findings is written when there are no
+// findings.
+func printStatistics(w io.Writer, stats models.Statistics) {
+	if stats.Skipped > 0 {
+		fmt.Fprintf(w, "Skipped by %s: %d\n", rules.SkipAnnotation, stats.Skipped)
+	}
+	if len(stats.FindingsByRule) == 0 {
+		return
+	}
+
+	var severities []string
+	for _, severity := range []string{models.SeverityError, models.SeverityWarning, models.SeverityInfo} {
+		if count := stats.FindingsBySeverity[severity]; count > 0 {
+			severities = append(severities, fmt.Sprintf("%d %s", count, severity))
+		}
+	}
+	fmt.Fprintf(w, "Findings: %s\n", strings.Join(severities, ", "))
+
+	fmt.Fprintln(w, "\nTop rules:")
+	for _, rule := range stats.FindingsByRule[:min(summaryTopN, len(stats.FindingsByRule))] {
+		fmt.Fprintf(w, "  %-40s %d\n", rule.RuleID, rule.Count)
+	}
+
+	fmt.Fprintln(w, "\nCharts with most findings:")
+	for _, chart := range stats.ChartsByFindings[:min(summaryTopN, len(stats.ChartsByFindings))] {
+		chartName, err := getChartName(chart.ChartPath)
+		if err != nil {
+			chartName = chart.ChartPath
+		}
+		fmt.Fprintf(w, "  %-40s %d\n", chartName, chart.Count)
+	}
+
+	if len(stats.UndefinedValues) > 0 {
+		fmt.Fprintln(w, "\nUndefined values by key:")
+		for _, value := range stats.UndefinedValues[:min(summaryTopN, len(stats.UndefinedValues))] {
+			fmt.Fprintf(w, "  %-40s %s\n", value.Key, undefinedValueCounts(value))
+		}
+	}
+}
+
+// undefinedValueCounts describes how widely an undefined value is referenced,
+// e.g. "3 charts, 4 references".
+func undefinedValueCounts(value models.UndefinedValue) string {
+	return fmt.Sprintf("%s, %s", plural(len(value.Charts), "chart"), plural(len(value.References), "reference"))
+}
+
+// plural formats n with noun, adding an s unless n is 1.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
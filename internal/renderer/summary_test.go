@@ -0,0 +1,166 @@
+package renderer
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+func TestComputeStatistics(t *testing.T) {
+	results := []models.Result{
+		{ChartPath: "charts/a", Findings: []models.Finding{
+			{RuleID: "image-pinning", Severity: models.SeverityError},
+		}},
+		{ChartPath: "charts/b", Findings: []models.Finding{
+			{RuleID: "image-pinning", Severity: models.SeverityError},
+			{RuleID: "pod-security", Severity: models.SeverityError},
+			{RuleID: "mesh-tls", Severity: models.SeverityWarning},
+		}, SkippedFindings: []models.Finding{
+			{RuleID: "pod-security", Severity: models.SeverityError},
+		}},
+		{ChartPath: "charts/c", Findings: []models.Finding{
+			{RuleID: UndefinedValueID, Severity: models.SeverityError, Message: "Undefined value: 'image.tag'"},
+		}},
+	}
+
+	stats := ComputeStatistics(results)
+
+	if stats.FindingsBySeverity[models.SeverityError] != 4 || stats.FindingsBySeverity[models.SeverityWarning] != 1 {
+		t.Errorf("Expected 4 errors and 1 warning, got %v", stats.FindingsBySeverity)
+	}
+	if stats.Skipped != 1 {
+		t.Errorf("Expected 1 skipped finding, got %d", stats.Skipped)
+	}
+
+	expectedRules := []models.RuleCount{{RuleID: "image-pinning", Count: 2}, {RuleID: "mesh-tls", Count: 1}, {RuleID: "pod-security", Count: 1}, {RuleID: UndefinedValueID, Count: 1}}
+	if len(stats.FindingsByRule) != len(expectedRules) {
+		t.Fatalf("Expected %v, got %v", expectedRules, stats.FindingsByRule)
+	}
+	for i, rule := range expectedRules {
+		if stats.FindingsByRule[i] != rule {
+			t.Errorf("Expected rule %d to be %v, got %v", i, rule, stats.FindingsByRule[i])
+		}
+	}
+
+	expectedCharts := []models.ChartCount{{ChartPath: "charts/b", Count: 3}, {ChartPath: "charts/a", Count: 1}, {ChartPath: "charts/c", Count: 1}}
+	if len(stats.ChartsByFindings) != len(expectedCharts) {
+		t.Fatalf("Expected %v, got %v", expectedCharts, stats.ChartsByFindings)
+	}
+	for i, chart := range expectedCharts {
+		if stats.ChartsByFindings[i] != chart {
+			t.Errorf("Expected chart %d to be %v, got %v", i, chart, stats.ChartsByFindings[i])
+		}
+	}
+	if len(stats.UndefinedValues) != 1 || stats.UndefinedValues[0].Key != "image.tag" {
+		t.Errorf("Expected image.tag to be indexed as undefined, got %v", stats.UndefinedValues)
+	}
+}
+
+func TestPrintStatistics(t *testing.T) {
+	var out bytes.Buffer
+	printStatistics(&out, models.Statistics{
+		FindingsBySeverity: map[string]int{models.SeverityError: 2, models.SeverityInfo: 1},
+		FindingsByRule:     []models.RuleCount{{RuleID: "image-pinning", Count: 3}},
+		ChartsByFindings:   []models.ChartCount{{ChartPath: "charts/web", Count: 3}},
+	})
+	for _, expected := range []string{"Findings: 2 error, 1 info\n", "Top rules:\n  image-pinning", "Charts with most findings:\n  charts/web"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, out.String())
+		}
+	}
+
+	out.Reset()
+	printStatistics(&out, models.Statistics{})
+	if out.Len() != 0 {
+		t.Errorf("Expected no output without findings, got %q", out.String())
+	}
+}
+
+func TestNewReport(t *testing.T) {
+	results := []models.Result{
+		{ChartPath: "charts/web", Success: true, Findings: []models.Finding{{RuleID: "image-pinning", Severity: models.SeverityWarning}}},
+		{ChartPath: "charts/api", Findings: []models.Finding{{RuleID: UndefinedValueID, Severity: models.SeverityError}}, Dependencies: []models.Result{
+			{ChartPath: "charts/api/charts/redis", Success: true},
+		}},
+		{ChartPath: "charts/db", Incomplete: true, Findings: []models.Finding{{RuleID: scanRuleID, Severity: models.SeverityError}}},
+	}
+
+	runFindings := []models.Finding{{RuleID: unusedValuesFileID, Severity: models.SeverityWarning, File: "values-prod.yaml"}}
+
+	report := NewReport(results, runFindings, 1500*time.Millisecond, "1.2.3", models.Config{Namespace: "payments", Experimental: []string{"schema-v2"}})
+	expected := models.ReportSummary{Charts: 4, Passed: 2, Failed: 2, Incomplete: 1, FindingsBySeverity: map[string]int{models.SeverityError: 2, models.SeverityWarning: 2}}
+	if !reflect.DeepEqual(report.Summary, expected) {
+		t.Errorf("Expected summary %v, got %v", expected, report.Summary)
+	}
+	if report.Version != "1.2.3" || report.DurationSeconds != 1.5 || report.Config.Namespace != "payments" || len(report.Results) != 3 {
+		t.Errorf("Expected the version, duration, config and results of the scan, got %v", report)
+	}
+	if !reflect.DeepEqual(report.Findings, runFindings) {
+		t.Errorf("Expected the findings of the run, got %v", report.Findings)
+	}
+	if !reflect.DeepEqual(report.Experiments, []string{"schema-v2"}) {
+		t.Errorf("Expected the experiments of the scan, got %v", report.Experiments)
+	}
+
+	if report := NewReport(nil, nil, 0, "dev", models.Config{}); report.Results == nil {
+		t.Errorf("Expected an empty list of results, got nil")
+	}
+}
+
+func TestRulesRun(t *testing.T) {
+	disabled := false
+	config := models.Config{
+		Validation: models.ValidationConfig{KubeVersion: "1.29"},
+		Rules:      map[string]models.RuleConfig{templateCycleRuleID: {Enabled: &disabled}},
+	}
+	ids := RulesRun(config)
+	for _, id := range []string{lintRuleID, UndefinedValueID, renderRuleID, validationRuleID} {
+		if !slices.Contains(ids, id) {
+			t.Errorf("Expected %s to run, got %v", id, ids)
+		}
+	}
+	for _, id := range []string{templateCycleRuleID, determinismRuleID, permutationRuleID, unusedValuesFileID} {
+		if slices.Contains(ids, id) {
+			t.Errorf("Expected %s not to run, got %v", id, ids)
+		}
+	}
+	if !slices.IsSorted(ids) {
+		t.Errorf("Expected sorted rule IDs, got %v", ids)
+	}
+}
+
+func TestNewReportRedactsCredentials(t *testing.T) {
+	config := models.Config{Dependencies: models.DependenciesConfig{Repositories: []models.RepositoryCredentials{
+		{Name: "internal", URL: "https://charts.example.com", Username: "ci-bot", Password: "s3cr3t-p4ssw0rd"},
+	}}}
+	report := NewReport(nil, nil, 0, "dev", config)
+
+	jsonOutput, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("Failed to marshal the report as JSON: %v", err)
+	}
+	yamlOutput, err := yaml.Marshal(report)
+	if err != nil {
+		t.Fatalf("Failed to marshal the report as YAML: %v", err)
+	}
+	for format, output := range map[string][]byte{"JSON": jsonOutput, "YAML": yamlOutput} {
+		for _, secret := range []string{"ci-bot", "s3cr3t-p4ssw0rd"} {
+			if strings.Contains(string(output), secret) {
+				t.Errorf("Expected %q to be redacted from the %s report, got %s", secret, format, output)
+			}
+		}
+		if !strings.Contains(string(output), "https://charts.example.com") {
+			t.Errorf("Expected the repository URL in the %s report, got %s", format, output)
+		}
+	}
+	if config.Dependencies.Repositories[0].Password != "s3cr3t-p4ssw0rd" {
+		t.Errorf("Expected the config of the scan to keep its credentials, got %v", config.Dependencies.Repositories[0])
+	}
+}
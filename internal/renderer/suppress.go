@@ -0,0 +1,172 @@
+package renderer
+
+import (
+	"bufio"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// ignoreDirective in a template comment acknowledges the problems on its
+// line, and on the next line when the comment stands alone:
+//
+//	# chartscan:ignore undefined-value,image-pinning optional reason
+//	{{- /* chartscan:ignore */ -}}
+//
+// Without IDs every problem on those lines is suppressed. Findings without a
+// line are suppressed by any directive in their template naming their rule.
+const ignoreDirective = "chartscan:ignore"
+
+// UndefinedValueID is the rule ID of undefined value findings, also used to
+// suppress them in ignore directives.
+const UndefinedValueID = "undefined-value"
+
+// suppressions reads the ignore directives of templates, parsing each file
+// once.
+type suppressions struct {
+	files map[string]*fileSuppressions
+}
+
+// fileSuppressions holds the directives of one template.
+type fileSuppressions struct {
+	// lines maps line numbers to the suppressed IDs; an empty list
+	// suppresses everything.
+	lines map[int][]string
+	// ruleIDs are all IDs named anywhere in the file.
+	ruleIDs map[string]bool
+}
+
+func newSuppressions() *suppressions {
+	return &suppressions{files: map[string]*fileSuppressions{}}
+}
+
+// suppressed reports whether the problem id at line of file is ignored. Line
+// 0 stands for a problem without a line.
+func (s *suppressions) suppressed(file string, line int, id string) bool {
+	if s == nil {
+		return false
+	}
+	fs, ok := s.files[file]
+	if !ok {
+		fs = parseSuppressions(file)
+		s.files[file] = fs
+	}
+	if line == 0 {
+		return fs.ruleIDs[id]
+	}
+	ids, ok := fs.lines[line]
+	return ok && (len(ids) == 0 || slices.Contains(ids, id))
+}
+
+// filterReferences splits refs into those checked and those suppressed as
+// undefined values.
+func (s *suppressions) filterReferences(refs []models.ValueReference) ([]models.ValueReference, []models.ValueReference) {
+	var kept, suppressed []models.ValueReference
+	for _, ref := range refs {
+		if s.suppressed(ref.File, ref.Line, UndefinedValueID) {
+			suppressed = append(suppressed, ref)
+		} else {
+			kept = append(kept, ref)
+		}
+	}
+	return kept, suppressed
+}
+
+// filterFindings splits the findings of the chart at chartPath into those
+// reported and those suppressed by directives in their templates.
+func (s *suppressions) filterFindings(chartPath, sourcePrefix string, findings []models.Finding) ([]models.Finding, []models.Finding) {
+	var kept, suppressed []models.Finding
+	for _, finding := range findings {
+		file := findingFile(chartPath, sourcePrefix, finding.File)
+		if file != "" && s.suppressed(file, finding.Line, finding.RuleID) {
+			suppressed = append(suppressed, finding)
+		} else {
+			kept = append(kept, finding)
+		}
+	}
+	return kept, suppressed
+}
+
+// parseSuppressions reads the ignore directives of file. Unreadable files
+// have none.
+func parseSuppressions(file string) *fileSuppressions {
+	fs := &fileSuppressions{lines: map[int][]string{}, ruleIDs: map[string]bool{}}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return fs
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		ids, found := parseIgnoreDirective(line)
+		if !found {
+			continue
+		}
+		for _, id := range ids {
+			fs.ruleIDs[id] = true
+		}
+		fs.add(lineNumber, ids)
+		if isCommentLine(line) {
+			fs.add(lineNumber+1, ids)
+		}
+	}
+
+	return fs
+}
+
+// add suppresses ids on line, keeping an existing suppression of everything.
+func (fs *fileSuppressions) add(line int, ids []string) {
+	existing, ok := fs.lines[line]
+	switch {
+	case !ok:
+		fs.lines[line] = ids
+	case len(existing) > 0 && len(ids) > 0:
+		fs.lines[line] = slices.Concat(existing, ids)
+	default:
+		fs.lines[line] = []string{}
+	}
+}
+
+// parseIgnoreDirective returns the comma-separated IDs following the ignore
+// directive in line, and whether line has a directive. Text after the IDs is
+// a free-form reason.
+func parseIgnoreDirective(line string) ([]string, bool) {
+	_, rest, found := strings.Cut(line, ignoreDirective)
+	if !found || (rest != "" && rest[0] != ' ' && rest[0] != '\t' && !strings.HasPrefix(rest, "*/")) {
+		return nil, false
+	}
+	rest, _, _ = strings.Cut(rest, "*/")
+	rest, _, _ = strings.Cut(rest, "}}")
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return []string{}, true
+	}
+	var ids []string
+	for _, id := range strings.Split(fields[0], ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids, true
+}
+
+// isCommentLine reports whether line holds nothing but a YAML or template
+// comment.
+func isCommentLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "#") {
+		return true
+	}
+	inner, found := strings.CutPrefix(trimmed, "{{")
+	if !found || !strings.HasSuffix(trimmed, "}}") {
+		return false
+	}
+	return strings.HasPrefix(strings.TrimLeft(inner, "- "), "/*")
+}
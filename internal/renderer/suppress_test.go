@@ -0,0 +1,94 @@
+package renderer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestParseIgnoreDirective(t *testing.T) {
+	tests := map[string][]string{
+		"# chartscan:ignore":                                      {},
+		"# chartscan:ignore undefined-value":                      {"undefined-value"},
+		"# chartscan:ignore undefined-value,image-pinning legacy": {"undefined-value", "image-pinning"},
+		"{{- /* chartscan:ignore */ -}}":                          {},
+		"{{/* chartscan:ignore pod-security */}}":                 {"pod-security"},
+		"image: {{ .Values.image }} # chartscan:ignore":           {},
+	}
+	for line, expected := range tests {
+		ids, found := parseIgnoreDirective(line)
+		if !found {
+			t.Errorf("Expected a directive in %q", line)
+			continue
+		}
+		if strings.Join(ids, ",") != strings.Join(expected, ",") {
+			t.Errorf("Expected IDs %v in %q, got %v", expected, line, ids)
+		}
+	}
+
+	for _, line := range []string{"kind: Service", "# chartscan:ignored-by-nobody"} {
+		if _, found := parseIgnoreDirective(line); found {
+			t.Errorf("Expected no directive in %q", line)
+		}
+	}
+}
+
+func TestSuppressionsFilterReferences(t *testing.T) {
+	chartDir := t.TempDir()
+	writeChart(t, chartDir, "apiVersion: v2\nname: app\nversion: 0.1.0\n", "")
+	template := `kind: Deployment
+replicas: {{ .Values.replicas }} # chartscan:ignore undefined-value
+# chartscan:ignore
+image: {{ .Values.image }}
+tag: {{ .Values.tag }}
+{{- /* chartscan:ignore image-pinning */}}
+port: {{ .Values.port }}
+`
+	os.MkdirAll(filepath.Join(chartDir, "templates"), 0755)
+	if err := os.WriteFile(filepath.Join(chartDir, "templates", "deployment.yaml"), []byte(template), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	refs, errs := ParseTemplates(chartDir)
+	if len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+
+	kept, suppressed := newSuppressions().filterReferences(refs)
+	var keptNames, suppressedNames []string
+	for _, ref := range kept {
+		keptNames = append(keptNames, ref.Name)
+	}
+	for _, ref := range suppressed {
+		suppressedNames = append(suppressedNames, ref.Name)
+	}
+	if strings.Join(suppressedNames, ",") != "replicas,image" {
+		t.Errorf("Expected replicas and image to be suppressed, got %v", suppressedNames)
+	}
+	if strings.Join(keptNames, ",") != "tag,port" {
+		t.Errorf("Expected tag and port to be checked, got %v", keptNames)
+	}
+}
+
+func TestSuppressionsFilterFindings(t *testing.T) {
+	chartDir := t.TempDir()
+	os.MkdirAll(filepath.Join(chartDir, "templates"), 0755)
+	os.WriteFile(filepath.Join(chartDir, "templates", "deployment.yaml"), []byte("# chartscan:ignore image-pinning pinned by the release pipeline\nkind: Deployment\n"), 0644)
+	os.WriteFile(filepath.Join(chartDir, "templates", "job.yaml"), []byte("kind: Job\n"), 0644)
+
+	findings := []models.Finding{
+		{RuleID: "image-pinning", File: "app/templates/deployment.yaml"},
+		{RuleID: "pod-security", File: "app/templates/deployment.yaml"},
+		{RuleID: "image-pinning", File: "app/templates/job.yaml"},
+	}
+	kept, suppressed := newSuppressions().filterFindings(chartDir, "app/", findings)
+	if len(suppressed) != 1 || suppressed[0].RuleID != "image-pinning" || suppressed[0].File != "app/templates/deployment.yaml" {
+		t.Errorf("Expected the image-pinning finding of deployment.yaml to be suppressed, got %v", suppressed)
+	}
+	if len(kept) != 2 {
+		t.Errorf("Expected 2 findings to be kept, got %v", kept)
+	}
+}
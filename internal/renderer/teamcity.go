@@ -0,0 +1,62 @@
+package renderer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// teamcitySeverities maps finding severities to TeamCity inspection
+// severities.
+var teamcitySeverities = map[string]string{
+	models.SeverityError:   "ERROR",
+	models.SeverityWarning: "WARNING",
+	models.SeverityInfo:    "INFO",
+}
+
+// PrintResultsTeamCity writes the findings to w as TeamCity service
+// messages, so they show up on the Inspections tab of the build: an
+// inspectionType message for every rule that reported findings and an
+// inspection message per finding, followed by the number of invalid charts
+// as a build statistic and a summary line.
+func PrintResultsTeamCity(w io.Writer, results []models.Result, duration time.Duration) {
+	var validCharts, invalidCharts int
+	declared := make(map[string]bool)
+	for _, result := range models.FlattenResults(results) {
+		if result.Success {
+			validCharts++
+		} else {
+			invalidCharts++
+		}
+		for _, finding := range result.Findings {
+			if !declared[finding.RuleID] {
+				declared[finding.RuleID] = true
+				fmt.Fprintf(w, "##teamcity[inspectionType id='%s' name='%s' category='chartscan' description='chartscan rule %s']\n",
+					escapeTeamCity(finding.RuleID), escapeTeamCity(finding.RuleID), escapeTeamCity(finding.RuleID))
+			}
+
+			severity, ok := teamcitySeverities[finding.Severity]
+			if !ok {
+				severity = "ERROR"
+			}
+			attributes := fmt.Sprintf("typeId='%s' message='%s'", escapeTeamCity(finding.RuleID), escapeTeamCity(annotationMessage(result, finding)))
+			if file := annotationFile(result.ChartPath, finding.File); file != "" {
+				attributes += fmt.Sprintf(" file='%s'", escapeTeamCity(file))
+				if finding.Line > 0 {
+					attributes += fmt.Sprintf(" line='%d'", finding.Line)
+				}
+			}
+			fmt.Fprintf(w, "##teamcity[inspection %s SEVERITY='%s']\n", attributes, severity)
+		}
+	}
+	fmt.Fprintf(w, "##teamcity[buildStatisticValue key='chartscan.invalidCharts' value='%d']\n", invalidCharts)
+	fmt.Fprintf(w, "chartscan: %d valid charts, %d invalid charts scanned in %v\n", validCharts, invalidCharts, duration.Round(time.Millisecond))
+}
+
+// escapeTeamCity escapes an attribute value of a service message.
+func escapeTeamCity(s string) string {
+	return strings.NewReplacer("|", "||", "'", "|'", "\n", "|n", "\r", "|r", "[", "|[", "]", "|]", "\u0085", "|x", "\u2028", "|l", "\u2029", "|p").Replace(s)
+}
@@ -0,0 +1,39 @@
+package renderer
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestWriteResultsTeamCity(t *testing.T) {
+	results := []models.Result{
+		{ChartPath: "charts/web", Findings: []models.Finding{
+			{RuleID: UndefinedValueID, Severity: models.SeverityError, File: "charts/web/templates/deployment.yaml", Line: 3, Message: "Undefined value: 'a'"},
+			{RuleID: UndefinedValueID, Severity: models.SeverityError, File: "charts/web/templates/service.yaml", Line: 7, Message: "Undefined value: 'b'"},
+			{RuleID: "duplicate-values", Severity: models.SeverityInfo, Message: "repeats [a default]"},
+		}},
+		{ChartPath: "charts/api", Success: true},
+	}
+
+	var output bytes.Buffer
+	PrintResultsTeamCity(&output, results, 1500*time.Millisecond)
+	expected := "##teamcity[inspectionType id='undefined-value' name='undefined-value' category='chartscan' description='chartscan rule undefined-value']\n" +
+		"##teamcity[inspection typeId='undefined-value' message='charts/web: Undefined value: |'a|'' file='charts/web/templates/deployment.yaml' line='3' SEVERITY='ERROR']\n" +
+		"##teamcity[inspection typeId='undefined-value' message='charts/web: Undefined value: |'b|'' file='charts/web/templates/service.yaml' line='7' SEVERITY='ERROR']\n" +
+		"##teamcity[inspectionType id='duplicate-values' name='duplicate-values' category='chartscan' description='chartscan rule duplicate-values']\n" +
+		"##teamcity[inspection typeId='duplicate-values' message='charts/web: repeats |[a default|]' SEVERITY='INFO']\n" +
+		"##teamcity[buildStatisticValue key='chartscan.invalidCharts' value='1']\n" +
+		"chartscan: 1 valid charts, 1 invalid charts scanned in 1.5s\n"
+	if output.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, output.String())
+	}
+}
+
+func TestEscapeTeamCity(t *testing.T) {
+	if got := escapeTeamCity("a|b\nc"); got != "a||b|nc" {
+		t.Errorf("Expected 'a||b|nc', got '%s'", got)
+	}
+}
@@ -0,0 +1,153 @@
+package renderer
+
+import (
+	"fmt"
+	"strings"
+	"text/template/parse"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// templateCycleRuleID is the ID of the findings of named templates that call
+// each other in a cycle.
+const templateCycleRuleID = "template-cycle"
+
+// templateCall is a call of a named template with include or template.
+type templateCall struct {
+	name string
+	file string
+	line int
+	// conditional is set for calls inside if, with and range blocks, which
+	// may end the recursion.
+	conditional bool
+}
+
+// checkTemplateCycles reports the named templates of the chart at chartPath
+// that include each other, directly or through other templates, in a cycle.
+// Helm renders such templates until it hits its recursion limit. A cycle is
+// an error when every call in it is unconditional, and a warning when a
+// condition may end the recursion. Templates that do not parse are reported
+// by the templates stage.
+func checkTemplateCycles(chartPath string) []models.Finding {
+	_, defines, _, _ := loadTemplateSources(chartPath)
+	names := sortedKeys(defines)
+	calls := make(map[string][]templateCall)
+	edges := make(map[string][]string)
+	for _, name := range names {
+		defined := defines[name]
+		calls[name] = collectTemplateCalls(defined.tree.Root, defined.source, false, nil)
+		for _, call := range calls[name] {
+			if _, ok := defines[call.name]; ok {
+				edges[name] = append(edges[name], call.name)
+			}
+		}
+	}
+
+	var findings []models.Finding
+	for _, cycle := range findCycles(names, edges) {
+		conditional := false
+		var steps []string
+		var first templateCall
+		for i := 0; i+1 < len(cycle); i++ {
+			call := cycleCall(calls[cycle[i]], cycle[i+1])
+			if i == 0 {
+				first = call
+			}
+			conditional = conditional || call.conditional
+			steps = append(steps, fmt.Sprintf("%s includes %s at %s:%d", cycle[i], cycle[i+1], call.file, call.line))
+		}
+		finding := models.Finding{
+			RuleID:   templateCycleRuleID,
+			Severity: models.SeverityError,
+			Message:  fmt.Sprintf("Named templates include each other in a cycle: %s (%s)", strings.Join(cycle, " → "), strings.Join(steps, ", ")),
+			File:     first.file,
+			Line:     first.line,
+		}
+		if conditional {
+			finding.Severity = models.SeverityWarning
+			finding.Message += "; it only ends if a condition stops the recursion"
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+// cycleCall returns the call of name among calls, preferring unconditional
+// calls.
+func cycleCall(calls []templateCall, name string) templateCall {
+	var found templateCall
+	for _, call := range calls {
+		if call.name != name {
+			continue
+		}
+		if !call.conditional {
+			return call
+		}
+		if found.name == "" {
+			found = call
+		}
+	}
+	return found
+}
+
+// collectTemplateCalls appends the calls of named templates below node, in
+// the template file source, to calls. conditional is whether node only runs
+// under a condition.
+func collectTemplateCalls(node parse.Node, source *templateSource, conditional bool, calls []templateCall) []templateCall {
+	add := func(name string, pos parse.Pos) {
+		calls = append(calls, templateCall{
+			name:        name,
+			file:        source.file,
+			line:        strings.Count(source.content[:pos], "\n") + 1,
+			conditional: conditional,
+		})
+	}
+
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			break
+		}
+		for _, child := range n.Nodes {
+			calls = collectTemplateCalls(child, source, conditional, calls)
+		}
+	case *parse.ActionNode:
+		calls = collectTemplateCalls(n.Pipe, source, conditional, calls)
+	case *parse.PipeNode:
+		if n == nil {
+			break
+		}
+		for _, cmd := range n.Cmds {
+			calls = collectTemplateCalls(cmd, source, conditional, calls)
+		}
+	case *parse.CommandNode:
+		if identifier, ok := n.Args[0].(*parse.IdentifierNode); ok && identifier.Ident == "include" && len(n.Args) > 1 {
+			if name, ok := n.Args[1].(*parse.StringNode); ok {
+				add(name.Text, name.Position())
+			}
+		}
+		for _, arg := range n.Args {
+			calls = collectTemplateCalls(arg, source, conditional, calls)
+		}
+	case *parse.ChainNode:
+		calls = collectTemplateCalls(n.Node, source, conditional, calls)
+	case *parse.TemplateNode:
+		add(n.Name, n.Position())
+		calls = collectTemplateCalls(n.Pipe, source, conditional, calls)
+	case *parse.IfNode:
+		calls = collectBranchCalls(&n.BranchNode, source, conditional, calls)
+	case *parse.WithNode:
+		calls = collectBranchCalls(&n.BranchNode, source, conditional, calls)
+	case *parse.RangeNode:
+		calls = collectBranchCalls(&n.BranchNode, source, conditional, calls)
+	}
+	return calls
+}
+
+// collectBranchCalls appends the calls of an if, with or range block. Its
+// pipeline always runs, its lists only under the condition.
+func collectBranchCalls(branch *parse.BranchNode, source *templateSource, conditional bool, calls []templateCall) []templateCall {
+	calls = collectTemplateCalls(branch.Pipe, source, conditional, calls)
+	calls = collectTemplateCalls(branch.List, source, true, calls)
+	return collectTemplateCalls(branch.ElseList, source, true, calls)
+}
@@ -0,0 +1,67 @@
+package renderer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestCheckTemplateCycles(t *testing.T) {
+	chartDir := t.TempDir()
+	templatesDir := filepath.Join(chartDir, "templates")
+	if err := os.MkdirAll(templatesDir, 0755); err != nil {
+		t.Fatalf("Failed to create templates dir: %v", err)
+	}
+	helpers := `{{- define "web.labels" -}}
+app: web
+{{ include "web.selector" . }}
+{{- end }}
+{{- define "web.selector" -}}
+{{ template "web.labels" . }}
+{{- end }}
+{{- define "web.tree" -}}
+{{- range .children }}
+{{ include "web.tree" . }}
+{{- end }}
+{{- end }}
+{{- define "web.name" -}}
+{{ default "web" .Values.name }}
+{{- end }}
+`
+	files := map[string]string{
+		"_helpers.tpl":    helpers,
+		"deployment.yaml": "metadata:\n  labels: {{ include \"web.labels\" . | nindent 4 }}\n  name: {{ include \"web.name\" . }}\n",
+		"broken.yaml":     "{{ if }}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(templatesDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	findings := checkTemplateCycles(chartDir)
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %v", findings)
+	}
+
+	helpersFile := filepath.Join(templatesDir, "_helpers.tpl")
+	cycle := findings[0]
+	if cycle.Severity != models.SeverityError || cycle.RuleID != templateCycleRuleID || cycle.File != helpersFile || cycle.Line != 3 {
+		t.Errorf("Expected an error at %s:3, got %v", helpersFile, cycle)
+	}
+	expected := "Named templates include each other in a cycle: web.labels → web.selector → web.labels (web.labels includes web.selector at " + helpersFile + ":3, web.selector includes web.labels at " + helpersFile + ":6)"
+	if cycle.Message != expected {
+		t.Errorf("Expected message %q, got %q", expected, cycle.Message)
+	}
+
+	recursion := findings[1]
+	if recursion.Severity != models.SeverityWarning || recursion.Line != 10 {
+		t.Errorf("Expected a warning at line 10 for the guarded recursion, got %v", recursion)
+	}
+	if !strings.HasPrefix(recursion.Message, "Named templates include each other in a cycle: web.tree → web.tree") {
+		t.Errorf("Expected the recursion of web.tree to be reported, got %q", recursion.Message)
+	}
+}
@@ -0,0 +1,553 @@
+package renderer
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// fallbackFuncs are the functions whose arguments may be missing without
+// rendering anything wrong: they supply a default, test for emptiness or
+// combine conditions.
+var fallbackFuncs = map[string]bool{
+	"default":  true,
+	"coalesce": true,
+	"empty":    true,
+	"ternary":  true,
+	"and":      true,
+	"or":       true,
+	"not":      true,
+	"kindIs":   true,
+	"typeIs":   true,
+}
+
+// valueScope is what dot or a variable refers to while walking a template:
+// the root context, the value at path below .Values, or something unknown,
+// such as a range element or a dict passed to a named template.
+type valueScope struct {
+	root   bool
+	values bool
+	path   []string
+}
+
+var unknownScope = valueScope{}
+
+// templateSource is a parsed template file.
+type templateSource struct {
+	file    string
+	content string
+	tree    *parse.Tree
+	actions []templateAction
+}
+
+// namedTemplate is a template defined with define or block, and the file
+// defining it.
+type namedTemplate struct {
+	source *templateSource
+	tree   *parse.Tree
+}
+
+// parseTemplateSource parses a template file and adds its named templates to
+// defines, replacing earlier definitions of the same name. ok is false if the
+// file does not parse.
+func parseTemplateSource(file, content string, defines map[string]*namedTemplate) (*templateSource, bool) {
+	tree := parse.New(file)
+	tree.Mode = parse.SkipFuncCheck
+	treeSet := make(map[string]*parse.Tree)
+	if _, err := tree.Parse(content, "", "", treeSet); err != nil {
+		return nil, false
+	}
+
+	source := &templateSource{file: file, content: content, tree: tree, actions: findTemplateActions(content)}
+	for name, defined := range treeSet {
+		if defined != tree && defined.Root != nil {
+			defines[name] = &namedTemplate{source: source, tree: defined}
+		}
+	}
+	return source, true
+}
+
+// parseTemplateTree returns the value references of a single template by
+// walking its parse tree; see walkTemplateSources. ok is false if the
+// template does not parse.
+func parseTemplateTree(file, content string) ([]models.ValueReference, bool) {
+	defines := make(map[string]*namedTemplate)
+	source, ok := parseTemplateSource(file, content, defines)
+	if !ok {
+		return nil, false
+	}
+	return walkTemplateSources([]*templateSource{source}, defines), true
+}
+
+// walkTemplateSources returns the value references of templates by walking
+// their parse trees, following dot through with blocks, variables and calls
+// of named templates, so references in conditions, pipelines and function
+// arguments are found too. Arguments of fallback functions, range and with
+// pipelines and conditions are optional, and the references in an if or with
+// block are guarded by the values its condition tests.
+//
+// Named templates are walked where they are called with include or template,
+// with dot set to the argument; their references name the outermost call
+// site. Named templates that are never called are walked with dot set to the
+// root context, the way helpers are usually called.
+func walkTemplateSources(sources []*templateSource, defines map[string]*namedTemplate) []models.ValueReference {
+	return walkTemplateReferences(sources, defines).refs
+}
+
+// walkTemplateReferences walks templates like walkTemplateSources and returns
+// every reference found, along with each use through a distinct chain of
+// named templates.
+func walkTemplateReferences(sources []*templateSource, defines map[string]*namedTemplate) *referenceSet {
+	refs := &referenceSet{seen: make(map[string]bool)}
+	called := make(map[string]bool)
+	root := valueScope{root: true}
+
+	for _, source := range sources {
+		w := &treeWalker{source: source, defines: defines, called: called, refs: refs}
+		if source.tree.Root != nil {
+			w.walkList(source.tree.Root, root, map[string]valueScope{}, nil)
+		}
+	}
+
+	names := make([]string, 0, len(defines))
+	for name := range defines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if called[name] {
+			continue
+		}
+		defined := defines[name]
+		w := &treeWalker{source: defined.source, defines: defines, called: called, refs: refs, stack: []string{name}}
+		w.walkList(defined.tree.Root, root, map[string]valueScope{}, nil)
+	}
+	return refs
+}
+
+// referenceSet collects references, dropping those found again at the same
+// place through another call of a named template. uses keeps them all.
+type referenceSet struct {
+	refs []models.ValueReference
+	seen map[string]bool
+	uses []valueUse
+}
+
+// valueUse is a reference reached from a template file, at line, through the
+// named templates in helpers, outermost first.
+type valueUse struct {
+	ref     models.ValueReference
+	file    string
+	line    int
+	helpers []string
+}
+
+// treeWalker collects the value references of a template tree.
+type treeWalker struct {
+	source  *templateSource
+	defines map[string]*namedTemplate
+	called  map[string]bool
+	refs    *referenceSet
+	// stack holds the named templates being walked, callSite the outermost
+	// call that led to them, at callLine of callFile.
+	stack    []string
+	callSite string
+	callFile string
+	callLine int
+}
+
+func (w *treeWalker) walkList(list *parse.ListNode, dot valueScope, vars map[string]valueScope, guards [][]string) {
+	if list == nil {
+		return
+	}
+	for _, node := range list.Nodes {
+		switch n := node.(type) {
+		case *parse.ActionNode:
+			w.walkPipe(n.Pipe, dot, vars, guards, false)
+			w.declare(n.Pipe, dot, vars)
+		case *parse.IfNode:
+			w.walkCondition(n.Pipe, dot, vars, guards, models.ExpectsBool)
+			w.walkList(n.List, dot, maps.Clone(vars), append(conditionGuards(n.Pipe, dot, vars), guards...))
+			w.walkList(n.ElseList, dot, maps.Clone(vars), guards)
+		case *parse.WithNode:
+			w.walkPipe(n.Pipe, dot, vars, guards, true)
+			inner := maps.Clone(vars)
+			w.declare(n.Pipe, dot, inner)
+			scope := pipeScope(n.Pipe, dot, vars)
+			innerGuards := guards
+			if scope.values && len(scope.path) > 0 {
+				innerGuards = append([][]string{scope.path}, guards...)
+			}
+			w.walkList(n.List, scope, inner, innerGuards)
+			w.walkList(n.ElseList, dot, maps.Clone(vars), guards)
+		case *parse.RangeNode:
+			w.walkCondition(n.Pipe, dot, vars, guards, models.ExpectsList)
+			inner := maps.Clone(vars)
+			for _, variable := range n.Pipe.Decl {
+				inner[variable.Ident[0]] = unknownScope
+			}
+			w.walkList(n.List, unknownScope, inner, guards)
+			w.walkList(n.ElseList, dot, maps.Clone(vars), guards)
+		case *parse.TemplateNode:
+			w.walkPipe(n.Pipe, dot, vars, guards, false)
+			w.walkNamed(n.Name, pipeScope(n.Pipe, dot, vars), guards, n)
+		case *parse.ListNode:
+			w.walkList(n, dot, vars, guards)
+		}
+	}
+}
+
+// declare records the scope of the variables a pipeline declares.
+func (w *treeWalker) declare(pipe *parse.PipeNode, dot valueScope, vars map[string]valueScope) {
+	if pipe == nil {
+		return
+	}
+	scope := pipeScope(pipe, dot, vars)
+	for _, variable := range pipe.Decl {
+		vars[variable.Ident[0]] = scope
+	}
+}
+
+func (w *treeWalker) walkPipe(pipe *parse.PipeNode, dot valueScope, vars map[string]valueScope, guards [][]string, optional bool) {
+	if pipe == nil {
+		return
+	}
+	if len(pipe.Decl) > 0 {
+		optional = true
+	}
+	for _, cmd := range pipe.Cmds {
+		if identifier, ok := cmd.Args[0].(*parse.IdentifierNode); ok && fallbackFuncs[identifier.Ident] {
+			optional = true
+		}
+	}
+	for _, cmd := range pipe.Cmds {
+		w.walkCommand(cmd, dot, vars, guards, optional)
+	}
+}
+
+// walkCondition walks the pipeline of an if or range block. A pipeline that
+// is a single value records that the block expects it to be of kind expects.
+func (w *treeWalker) walkCondition(pipe *parse.PipeNode, dot valueScope, vars map[string]valueScope, guards [][]string, expects string) {
+	if pipe != nil && len(pipe.Cmds) == 1 && len(pipe.Cmds[0].Args) == 1 {
+		arg := pipe.Cmds[0].Args[0]
+		if path, ok := resolveValue(arg, dot, vars); ok {
+			name, path, _ := literalKeys(path, nil, false)
+			w.addExpecting(name, path, arg, guards, true, expects)
+			return
+		}
+	}
+	w.walkPipe(pipe, dot, vars, guards, true)
+}
+
+func (w *treeWalker) walkCommand(cmd *parse.CommandNode, dot valueScope, vars map[string]valueScope, guards [][]string, optional bool) {
+	args := cmd.Args
+	if identifier, ok := args[0].(*parse.IdentifierNode); ok {
+		switch identifier.Ident {
+		case "index":
+			if len(args) > 2 {
+				if path, ok := resolveValue(args[1], dot, vars); ok {
+					if name, path, ok := literalKeys(path, args[2:], true); ok {
+						w.add(name, path, args[1], guards, optional)
+						return
+					}
+				}
+			}
+		case "get":
+			if len(args) == 3 {
+				if path, ok := resolveValue(args[1], dot, vars); ok {
+					if name, path, ok := literalKeys(path, args[2:], false); ok {
+						w.add(name, path, args[1], guards, true)
+						return
+					}
+				}
+			}
+		case "hasKey":
+			if len(args) == 3 {
+				if _, ok := resolveValue(args[1], dot, vars); ok {
+					w.walkArgs(args[2:], dot, vars, guards, optional)
+					return
+				}
+			}
+		case "dig":
+			if len(args) > 3 {
+				if path, ok := resolveValue(args[len(args)-1], dot, vars); ok {
+					if name, path, ok := literalKeys(path, args[1:len(args)-2], false); ok {
+						w.add(name, path, args[len(args)-1], guards, true)
+						w.walkArgs(args[len(args)-2:len(args)-1], dot, vars, guards, optional)
+						return
+					}
+				}
+			}
+		case "include":
+			if len(args) == 3 {
+				if name, ok := args[1].(*parse.StringNode); ok {
+					w.walkArgs(args[2:], dot, vars, guards, optional)
+					w.walkNamed(name.Text, argScope(args[2], dot, vars), guards, name)
+					return
+				}
+			}
+		case "pluck":
+			if len(args) < 3 {
+				break
+			}
+			if key, ok := args[1].(*parse.StringNode); ok {
+				for _, arg := range args[2:] {
+					if path, ok := resolveValue(arg, dot, vars); ok {
+						name, path, _ := literalKeys(path, []parse.Node{key}, false)
+						w.add(name, path, arg, guards, true)
+					} else {
+						w.walkArgs([]parse.Node{arg}, dot, vars, guards, optional)
+					}
+				}
+				return
+			}
+		}
+	}
+	w.walkArgs(args, dot, vars, guards, optional)
+}
+
+func (w *treeWalker) walkArgs(args []parse.Node, dot valueScope, vars map[string]valueScope, guards [][]string, optional bool) {
+	for _, arg := range args {
+		switch a := arg.(type) {
+		case *parse.PipeNode:
+			w.walkPipe(a, dot, vars, guards, optional)
+		case *parse.ChainNode:
+			if pipe, ok := a.Node.(*parse.PipeNode); ok {
+				w.walkPipe(pipe, dot, vars, guards, optional)
+			}
+		default:
+			if path, ok := resolveValue(arg, dot, vars); ok {
+				name, path, _ := literalKeys(path, nil, false)
+				w.add(name, path, arg, guards, optional)
+			}
+		}
+	}
+}
+
+// walkNamed walks the named template called at node with dot set to scope.
+// Templates called with an unknown dot are not walked, nor are recursive
+// calls.
+func (w *treeWalker) walkNamed(name string, scope valueScope, guards [][]string, node parse.Node) {
+	w.called[name] = true
+	defined, ok := w.defines[name]
+	if !ok || (!scope.root && !scope.values) || slices.Contains(w.stack, name) {
+		return
+	}
+
+	callSite, callFile, callLine := w.callSite, w.callFile, w.callLine
+	if callSite == "" {
+		callFile, callLine = w.source.file, w.line(int(node.Position()))
+		callSite = fmt.Sprintf("%s:%d", callFile, callLine)
+	}
+	inner := &treeWalker{
+		source:   defined.source,
+		defines:  w.defines,
+		called:   w.called,
+		refs:     w.refs,
+		stack:    append(slices.Clone(w.stack), name),
+		callSite: callSite,
+		callFile: callFile,
+		callLine: callLine,
+	}
+	inner.walkList(defined.tree.Root, scope, map[string]valueScope{}, guards)
+}
+
+// add records a reference to path found at node. References to .Values as a
+// whole are not recorded.
+func (w *treeWalker) add(name string, path []string, node parse.Node, guards [][]string, optional bool) {
+	w.addExpecting(name, path, node, guards, optional, "")
+}
+
+// addExpecting records a reference like add, expecting its value to be of
+// kind expects unless expects is empty.
+func (w *treeWalker) addExpecting(name string, path []string, node parse.Node, guards [][]string, optional bool, expects string) {
+	if len(path) == 0 {
+		return
+	}
+	pos := int(node.Position())
+	ref := models.ValueReference{
+		Name:         name,
+		Path:         path,
+		File:         w.source.file,
+		Line:         w.line(pos),
+		Optional:     optional,
+		Guards:       guards,
+		IncludedFrom: w.callSite,
+		Expects:      expects,
+	}
+	if action, ok := w.enclosingAction(pos); ok {
+		ref.FullText = w.source.content[action.Start:action.End]
+	}
+
+	use := valueUse{ref: ref, file: ref.File, line: ref.Line, helpers: w.stack}
+	if w.callSite != "" {
+		use.file, use.line = w.callFile, w.callLine
+	}
+	w.refs.uses = append(w.refs.uses, use)
+
+	key := fmt.Sprintf("%s:%d:%s:%v:%s", ref.File, ref.Line, ref.Name, ref.Optional, ref.Expects)
+	if w.refs.seen[key] {
+		return
+	}
+	w.refs.seen[key] = true
+	w.refs.refs = append(w.refs.refs, ref)
+}
+
+// line returns the line of the action containing the byte offset pos.
+func (w *treeWalker) line(pos int) int {
+	if action, ok := w.enclosingAction(pos); ok {
+		return action.Line
+	}
+	return strings.Count(w.source.content[:pos], "\n") + 1
+}
+
+// enclosingAction returns the action containing the byte offset pos.
+func (w *treeWalker) enclosingAction(pos int) (templateAction, bool) {
+	actions := w.source.actions
+	i := sort.Search(len(actions), func(i int) bool { return actions[i].Start > pos })
+	if i == 0 || pos >= actions[i-1].End {
+		return templateAction{}, false
+	}
+	return actions[i-1], true
+}
+
+// resolveValue returns the path below .Values that a field, variable or dot
+// node refers to. An empty path is .Values itself.
+func resolveValue(node parse.Node, dot valueScope, vars map[string]valueScope) ([]string, bool) {
+	switch n := node.(type) {
+	case *parse.FieldNode:
+		return scopedPath(dot, n.Ident)
+	case *parse.VariableNode:
+		scope, ok := vars[n.Ident[0]]
+		if n.Ident[0] == "$" {
+			scope, ok = valueScope{root: true}, true
+		}
+		if !ok {
+			return nil, false
+		}
+		return scopedPath(scope, n.Ident[1:])
+	case *parse.DotNode:
+		return scopedPath(dot, nil)
+	}
+	return nil, false
+}
+
+// scopedPath returns the value path of the fields idents accessed on scope.
+func scopedPath(scope valueScope, idents []string) ([]string, bool) {
+	switch {
+	case scope.values:
+		return append(append([]string{}, scope.path...), idents...), true
+	case scope.root && len(idents) > 0 && idents[0] == "Values":
+		return append([]string{}, idents[1:]...), true
+	}
+	return nil, false
+}
+
+// pipeScope returns what a pipeline evaluates to when it is a single value.
+func pipeScope(pipe *parse.PipeNode, dot valueScope, vars map[string]valueScope) valueScope {
+	if pipe == nil || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return unknownScope
+	}
+	return argScope(pipe.Cmds[0].Args[0], dot, vars)
+}
+
+// argScope returns what an argument evaluates to: dot, the root context
+// through a variable, or a value.
+func argScope(arg parse.Node, dot valueScope, vars map[string]valueScope) valueScope {
+	switch a := arg.(type) {
+	case *parse.DotNode:
+		return dot
+	case *parse.PipeNode:
+		return pipeScope(a, dot, vars)
+	case *parse.VariableNode:
+		if len(a.Ident) == 1 {
+			if a.Ident[0] == "$" {
+				return valueScope{root: true}
+			}
+			return vars[a.Ident[0]]
+		}
+	}
+	if path, ok := resolveValue(arg, dot, vars); ok {
+		return valueScope{values: true, path: path}
+	}
+	return unknownScope
+}
+
+// conditionGuards returns the value paths an if condition guarantees to
+// exist: the value it tests, the key of a hasKey call, or those of every
+// operand of and. Other conditions guarantee nothing.
+func conditionGuards(pipe *parse.PipeNode, dot valueScope, vars map[string]valueScope) [][]string {
+	if pipe == nil || len(pipe.Cmds) != 1 {
+		return nil
+	}
+	args := pipe.Cmds[0].Args
+	if len(args) == 1 {
+		if nested, ok := args[0].(*parse.PipeNode); ok {
+			return conditionGuards(nested, dot, vars)
+		}
+		if path, ok := resolveValue(args[0], dot, vars); ok && len(path) > 0 {
+			return [][]string{path}
+		}
+		return nil
+	}
+
+	identifier, ok := args[0].(*parse.IdentifierNode)
+	if !ok {
+		return nil
+	}
+	switch identifier.Ident {
+	case "hasKey":
+		if len(args) == 3 {
+			if path, ok := resolveValue(args[1], dot, vars); ok {
+				if _, path, ok := literalKeys(path, args[2:], false); ok {
+					return [][]string{path}
+				}
+			}
+		}
+	case "and":
+		var guards [][]string
+		for _, arg := range args[1:] {
+			operand := &parse.PipeNode{Cmds: []*parse.CommandNode{{Args: []parse.Node{arg}}}}
+			guards = append(guards, conditionGuards(operand, dot, vars)...)
+		}
+		return guards
+	}
+	return nil
+}
+
+// literalKeys appends the literal keys to path and returns the display name
+// of the result. Integer keys are list indices if indices is set. ok is false
+// if a key is not a literal.
+func literalKeys(path []string, keys []parse.Node, indices bool) (string, []string, bool) {
+	var name string
+	for _, segment := range path {
+		name = appendName(name, segment, false)
+	}
+	path = append([]string{}, path...)
+	for _, key := range keys {
+		switch k := key.(type) {
+		case *parse.StringNode:
+			if k.Text == "" {
+				return "", nil, false
+			}
+			path = append(path, k.Text)
+			name = appendName(name, k.Text, false)
+		case *parse.NumberNode:
+			if !indices || !k.IsInt {
+				return "", nil, false
+			}
+			index := strconv.FormatInt(k.Int64, 10)
+			path = append(path, index)
+			name = appendName(name, index, true)
+		default:
+			return "", nil, false
+		}
+	}
+	return name, path, true
+}
@@ -0,0 +1,151 @@
+package renderer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestParseTemplateTree(t *testing.T) {
+	content := `{{- $root := . -}}
+{{- $image := .Values.image -}}
+image: {{ printf "%s:%s" $image.repository (.Values.image.tag | default .Chart.AppVersion) | quote }}
+{{- with .Values.resources }}
+resources:
+  {{- toYaml .limits | nindent 2 }}
+{{- end }}
+{{- if and .Values.metrics.enabled (hasKey .Values.metrics "port") }}
+port: {{ .Values.metrics.port }}
+path: {{ $root.Values.metrics.path }}
+{{- else }}
+port: {{ $.Values.port }}
+{{- end }}
+{{- range .Values.hosts }}
+host: {{ .name }}
+{{- end }}
+annotation: {{ index .Values.podAnnotations "example.com/port" }}
+{{- define "app.name" -}}
+{{ .Values.nameOverride | trunc 63 }}
+{{- end }}
+`
+	refs, ok := parseTemplateTree("deployment.yaml", content)
+	if !ok {
+		t.Fatalf("Expected the template to parse")
+	}
+
+	byName := map[string]models.ValueReference{}
+	for _, ref := range refs {
+		if _, seen := byName[ref.Name]; !seen {
+			byName[ref.Name] = ref
+		}
+	}
+
+	expected := []struct {
+		name     string
+		line     int
+		optional bool
+		guards   string
+	}{
+		{"image", 2, true, ""},
+		{"image.repository", 3, false, ""},
+		{"image.tag", 3, true, ""},
+		{"resources", 4, true, ""},
+		{"resources.limits", 6, false, "resources"},
+		{"metrics.enabled", 8, true, ""},
+		{"metrics.port", 9, false, "metrics.enabled metrics.port"},
+		{"metrics.path", 10, false, "metrics.enabled metrics.port"},
+		{"port", 12, false, ""},
+		{"hosts", 14, true, ""},
+		{`podAnnotations["example.com/port"]`, 17, false, ""},
+		{"nameOverride", 19, false, ""},
+	}
+	for _, want := range expected {
+		ref, found := byName[want.name]
+		if !found {
+			t.Errorf("Expected a reference to %s, got %v", want.name, refs)
+			continue
+		}
+		var guards []string
+		for _, guard := range ref.Guards {
+			guards = append(guards, strings.Join(guard, "."))
+		}
+		if ref.Line != want.line || ref.Optional != want.optional || strings.Join(guards, " ") != want.guards {
+			t.Errorf("Expected %s at line %d, optional %v, guarded by %q, got line %d, optional %v, guarded by %q",
+				want.name, want.line, want.optional, want.guards, ref.Line, ref.Optional, strings.Join(guards, " "))
+		}
+	}
+	if _, found := byName["name"]; found {
+		t.Errorf("Expected fields of range elements not to be value references")
+	}
+	if ref := byName["image.repository"]; !strings.HasPrefix(ref.FullText, "{{ printf") {
+		t.Errorf("Expected the enclosing action as full text, got %q", ref.FullText)
+	}
+}
+
+func TestParseTemplateTreeWithElse(t *testing.T) {
+	content := `{{ with .Values.ingress }}{{ .host }}{{ else }}{{ .Values.host }}{{ end }}`
+	refs, ok := parseTemplateTree("ingress.yaml", content)
+	if !ok {
+		t.Fatalf("Expected the template to parse")
+	}
+	var names []string
+	for _, ref := range refs {
+		names = append(names, ref.Name)
+	}
+	if strings.Join(names, " ") != "ingress ingress.host host" {
+		t.Errorf("Expected dot to be restored in the else branch, got %v", names)
+	}
+}
+
+func TestParseTemplateTreeInvalid(t *testing.T) {
+	if _, ok := parseTemplateTree("broken.yaml", "{{ .Values.hosts[0].host }}"); ok {
+		t.Errorf("Expected list index syntax not to parse")
+	}
+}
+
+func TestWalkTemplateSourcesNamedTemplates(t *testing.T) {
+	defines := make(map[string]*namedTemplate)
+	var sources []*templateSource
+	for _, file := range []struct{ name, content string }{
+		{"templates/_helpers.tpl", `{{- define "app.image" -}}
+{{ .repository }}:{{ .tag }}
+{{- end }}
+{{- define "app.labels" -}}
+team: {{ .Values.team }}
+{{- end }}
+{{- define "app.unused" -}}
+{{ .Values.legacy }}
+{{- end }}
+{{- define "app.dict" -}}
+{{ .Values.ignored }}
+{{- end }}`},
+		{"templates/deployment.yaml", `labels:
+  {{- include "app.labels" . | nindent 2 }}
+image: {{ include "app.image" .Values.image }}
+other: {{ include "app.dict" (dict "Values" .Values.other) }}`},
+	} {
+		source, ok := parseTemplateSource(file.name, file.content, defines)
+		if !ok {
+			t.Fatalf("Expected %s to parse", file.name)
+		}
+		sources = append(sources, source)
+	}
+
+	var got []string
+	for _, ref := range walkTemplateSources(sources, defines) {
+		got = append(got, fmt.Sprintf("%s@%s:%d<%s", ref.Name, ref.File, ref.Line, ref.IncludedFrom))
+	}
+	expected := []string{
+		"team@templates/_helpers.tpl:5<templates/deployment.yaml:2",
+		"image@templates/deployment.yaml:3<",
+		"image.repository@templates/_helpers.tpl:2<templates/deployment.yaml:3",
+		"image.tag@templates/_helpers.tpl:2<templates/deployment.yaml:3",
+		"other@templates/deployment.yaml:4<",
+		"legacy@templates/_helpers.tpl:8<",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected references:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
@@ -0,0 +1,259 @@
+package renderer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+	"github.com/Jaydee94/chartscan/internal/rules"
+	"github.com/Jaydee94/chartscan/internal/valuessource"
+)
+
+var (
+	// keyedValueRegex matches index, get and hasKey calls on .Values or a
+	// value below it, capturing the dotted prefix and the literal keys.
+	keyedValueRegex = regexp.MustCompile(`\b(index|get|hasKey)\s+\$?\.Values((?:\.[a-zA-Z0-9_-]+)*)((?:\s+(?:"[^"]*"|[0-9]+))+)`)
+
+	// usedValueRegex matches any remaining access to .Values, including
+	// $.Values and $root.Values, within an action.
+	usedValueRegex = regexp.MustCompile(`\.Values\b((?:\.[a-zA-Z0-9_]+)*)`)
+)
+
+// UsedValuePaths returns the paths of the values accessed by a chart's
+// templates and helpers. Unlike ParseTemplates, it records every access,
+// including those in conditions, pipelines and helper arguments, since a value
+// that only feeds an if is used all the same. An empty path means .Values is
+// used as a whole.
+func UsedValuePaths(chartPath string) ([][]string, []string) {
+	var used [][]string
+	var errors []string
+
+	templatesDir := filepath.Join(chartPath, "templates")
+	if _, err := os.Stat(templatesDir); os.IsNotExist(err) {
+		return used, errors
+	}
+
+	err := filepath.Walk(templatesDir, func(path string, info os.FileInfo, walkErr error) error {
+		if walkErr != nil {
+			errors = append(errors, fmt.Sprintf("Error accessing file %s: %v", path, walkErr))
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("Error reading template file %s: %v", path, err))
+			return nil
+		}
+		for _, action := range findTemplateActions(string(content)) {
+			used = append(used, actionValuePaths(action)...)
+		}
+		return nil
+	})
+	if err != nil {
+		errors = append(errors, fmt.Sprintf("Error walking templates directory: %v", err))
+	}
+
+	return used, errors
+}
+
+// actionValuePaths returns the value paths accessed within a single action.
+// Accesses with literal keys are resolved first and removed, so that the
+// .Values they start from does not count as a use of all values.
+func actionValuePaths(action templateAction) [][]string {
+	var used [][]string
+
+	for _, ref := range parseFallbackReferences(action) {
+		used = append(used, ref.Path)
+	}
+	body := digRegex.ReplaceAllString(action.Body, "")
+	body = pluckRegex.ReplaceAllString(body, "")
+
+	for _, match := range keyedValueRegex.FindAllStringSubmatch(body, -1) {
+		path := dottedPath(match[2])
+		keys := indexArgRegex.FindAllStringSubmatch(match[3], -1)
+		if match[1] != "index" {
+			keys = keys[:1]
+		}
+		for _, key := range keys {
+			if key[1] == "" && key[2] != "" {
+				break
+			}
+			path = append(path, key[1])
+		}
+		used = append(used, path)
+	}
+	body = keyedValueRegex.ReplaceAllString(body, "")
+
+	for _, match := range usedValueRegex.FindAllStringSubmatch(body, -1) {
+		used = append(used, dottedPath(match[1]))
+	}
+
+	return used
+}
+
+// dottedPath splits a dotted suffix such as .image.tag into its keys.
+func dottedPath(suffix string) []string {
+	if suffix = strings.TrimPrefix(suffix, "."); suffix == "" {
+		return []string{}
+	}
+	return strings.Split(suffix, ".")
+}
+
+// UnusedValues returns the keys set in values that no template of the chart
+// accesses, as dotted paths. A key counts as used when it, one of its parents
+// or one of its children is accessed; only the topmost unused key of a
+// subtree is reported. Global values and values consumed by subcharts or by
+// dependency conditions and tags are never reported.
+func UnusedValues(chartPath string, values map[string]interface{}) ([]string, []string) {
+	used, skip, errors := valueAccesses(chartPath)
+
+	var unused []string
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		if !skip[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		unused = append(unused, unusedKeys([]string{key}, values[key], used)...)
+	}
+	return unused, errors
+}
+
+// valueAccesses returns the value paths accessed by the chart at chartPath,
+// including dependency conditions and tags, and the top-level keys passed on
+// to subcharts, which count as used without being accessed.
+func valueAccesses(chartPath string) ([][]string, map[string]bool, []string) {
+	used, errors := UsedValuePaths(chartPath)
+
+	skip := map[string]bool{"global": true}
+	deps, err := chartDependencies(filepath.Join(chartPath, "Chart.yaml"))
+	if err != nil && !os.IsNotExist(err) {
+		errors = append(errors, fmt.Sprintf("Error reading Chart.yaml: %v", err))
+	}
+	for _, dep := range deps {
+		skip[dep.Name] = true
+		if dep.Alias != "" {
+			skip[dep.Alias] = true
+		}
+		for _, condition := range strings.Split(dep.Condition, ",") {
+			if condition = strings.TrimSpace(condition); condition != "" {
+				used = append(used, strings.Split(condition, "."))
+			}
+		}
+		for _, tag := range dep.Tags {
+			used = append(used, []string{"tags", tag})
+		}
+	}
+	return used, skip, errors
+}
+
+// unusedKeys returns the unused keys at and below path, whose value is value.
+func unusedKeys(path []string, value interface{}, used [][]string) []string {
+	descendantUsed := false
+	for _, access := range used {
+		if len(access) <= len(path) && slices.Equal(access, path[:len(access)]) {
+			return nil
+		}
+		if len(access) > len(path) && slices.Equal(access[:len(path)], path) {
+			descendantUsed = true
+		}
+	}
+	if !descendantUsed {
+		return []string{strings.Join(path, ".")}
+	}
+
+	nested, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	keys := make([]string, 0, len(nested))
+	for key := range nested {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var unused []string
+	for _, key := range keys {
+		unused = append(unused, unusedKeys(append(slices.Clone(path), key), nested[key], used)...)
+	}
+	return unused
+}
+
+// unusedValuesFileID is the ID of the findings for values files that set no
+// value any scanned chart uses.
+const unusedValuesFileID = "unused-values-file"
+
+// CheckUnusedValuesFiles returns a warning for each of valuesFiles that sets
+// no value the templates of any of the results' charts access, which usually
+// means the file was written for other charts or its keys are nested wrongly.
+// The findings belong to the run, not to a chart, and are only meaningful when
+// results cover every chart the values files are meant for. Files that cannot
+// be read or set nothing are left to the scan, which reports them itself, and
+// values files resolved per chart are not checked. Rule overrides of config
+// apply.
+func CheckUnusedValuesFiles(results []models.Result, valuesFiles []string, config *models.Config) []models.Finding {
+	if len(results) == 0 || config.ValuesFilesRelativeTo == models.ValuesFilesRelativeToChart {
+		return nil
+	}
+
+	type chartAccesses struct {
+		used [][]string
+		skip map[string]bool
+	}
+	charts := make([]chartAccesses, 0, len(results))
+	for _, result := range results {
+		used, skip, _ := valueAccesses(result.ChartPath)
+		charts = append(charts, chartAccesses{used, skip})
+	}
+
+	var findings []models.Finding
+	for _, file := range valuesFiles {
+		local, err := valuessource.Resolve([]string{file})
+		if err != nil {
+			continue
+		}
+		values, err := ValuesLoader(local[0])
+		if err != nil || len(values) == 0 {
+			continue
+		}
+		usedByAny := false
+		for _, chart := range charts {
+			if valuesUsed(values, chart.used, chart.skip) {
+				usedByAny = true
+				break
+			}
+		}
+		if !usedByAny {
+			findings = append(findings, models.Finding{
+				RuleID:   unusedValuesFileID,
+				Severity: models.SeverityWarning,
+				Message:  "No scanned chart uses any value set in this values file",
+				File:     file,
+			})
+		}
+	}
+	return rules.ApplyOverrides(config, findings)
+}
+
+// valuesUsed reports whether any key set in values is accessed, or passed on
+// to a subchart, according to used and skip as returned by valueAccesses.
+func valuesUsed(values map[string]interface{}, used [][]string, skip map[string]bool) bool {
+	for key, value := range values {
+		if skip[key] {
+			return true
+		}
+		if unused := unusedKeys([]string{key}, value, used); len(unused) != 1 || unused[0] != key {
+			return true
+		}
+	}
+	return false
+}
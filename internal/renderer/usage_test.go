@@ -0,0 +1,141 @@
+package renderer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestUnusedValues(t *testing.T) {
+	chart := t.TempDir()
+	writeChart(t, chart, `
+apiVersion: v2
+name: app
+dependencies:
+  - name: redis
+    condition: redis.enabled
+    tags: [cache]
+`, "")
+	templates := map[string]string{
+		"deployment.yaml": `
+image: "{{ .Values.image.repository }}:{{ .Values.image.tag }}"
+{{- if .Values.metrics.enabled }}
+port: {{ index .Values.metrics "port" }}
+{{- end }}
+{{- with .Values.resources }}
+resources: {{ toYaml . | nindent 2 }}
+{{- end }}
+timeout: {{ dig "probes" "timeout" 5 .Values }}
+`,
+		"_helpers.tpl": `{{- define "app.labels" -}}team: {{ $.Values.team }}{{- end }}`,
+	}
+	for name, content := range templates {
+		if err := os.MkdirAll(filepath.Join(chart, "templates"), 0755); err != nil {
+			t.Fatalf("Failed to create templates dir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(chart, "templates", name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	values := map[string]interface{}{
+		"image": map[string]interface{}{"repository": "nginx", "tag": "1.27", "pullPolicy": "Always"},
+		"metrics": map[string]interface{}{
+			"enabled": true,
+			"port":    9090,
+			"path":    "/metrics",
+		},
+		"resources": map[string]interface{}{"limits": map[string]interface{}{"cpu": "1"}},
+		"probes":    map[string]interface{}{"timeout": 3, "period": 10},
+		"team":      "web",
+		"legacy":    map[string]interface{}{"enabled": false, "port": 80},
+		"global":    map[string]interface{}{"registry": "docker.io"},
+		"redis":     map[string]interface{}{"enabled": true, "port": 6379},
+		"tags":      map[string]interface{}{"cache": true, "queue": false},
+	}
+
+	unused, errors := UnusedValues(chart, values)
+	if len(errors) != 0 {
+		t.Fatalf("Unexpected errors: %v", errors)
+	}
+	expected := []string{"image.pullPolicy", "legacy", "metrics.path", "probes.period", "tags.queue"}
+	if !reflect.DeepEqual(unused, expected) {
+		t.Errorf("Expected unused values %v, got %v", expected, unused)
+	}
+}
+
+func TestUnusedValuesWholeValues(t *testing.T) {
+	chart := t.TempDir()
+	writeChart(t, chart, "apiVersion: v2\nname: app\n", "")
+	if err := os.MkdirAll(filepath.Join(chart, "templates"), 0755); err != nil {
+		t.Fatalf("Failed to create templates dir: %v", err)
+	}
+	config := "data:\n  config.yaml: {{ toYaml .Values | quote }}\n"
+	if err := os.WriteFile(filepath.Join(chart, "templates", "configmap.yaml"), []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	unused, _ := UnusedValues(chart, map[string]interface{}{"anything": 1})
+	if len(unused) != 0 {
+		t.Errorf("Expected no unused values when .Values is used as a whole, got %v", unused)
+	}
+}
+
+func TestCheckUnusedValuesFiles(t *testing.T) {
+	web, api := t.TempDir(), t.TempDir()
+	writeChart(t, web, "apiVersion: v2\nname: web\n", "")
+	writeChart(t, api, "apiVersion: v2\nname: api\ndependencies:\n  - name: redis\n", "")
+	for chart, template := range map[string]string{
+		web: "image: {{ .Values.image.tag }}\n",
+		api: "replicas: {{ .Values.replicas }}\n",
+	} {
+		if err := os.MkdirAll(filepath.Join(chart, "templates"), 0755); err != nil {
+			t.Fatalf("Failed to create templates dir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(chart, "templates", "deployment.yaml"), []byte(template), 0644); err != nil {
+			t.Fatalf("Failed to write template: %v", err)
+		}
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"used.yaml":     "replicas: 3\n",
+		"nested.yaml":   "image:\n  tag: 1.27\n  pullPolicy: Always\n",
+		"subchart.yaml": "redis:\n  enabled: true\n",
+		"unused.yaml":   "web:\n  image:\n    tag: 1.27\n",
+		"empty.yaml":    "",
+	}
+	var valuesFiles []string
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		valuesFiles = append(valuesFiles, path)
+	}
+	valuesFiles = append(valuesFiles, filepath.Join(dir, "missing.yaml"))
+
+	results := []models.Result{{ChartPath: web, Success: true}, {ChartPath: api, Success: true}}
+	findings := CheckUnusedValuesFiles(results, valuesFiles, &models.Config{})
+	if len(findings) != 1 {
+		t.Fatalf("Expected 1 finding for the run, got %v", findings)
+	}
+	if finding := findings[0]; finding.RuleID != unusedValuesFileID || finding.Severity != models.SeverityWarning || finding.File != filepath.Join(dir, "unused.yaml") {
+		t.Errorf("Expected a warning for unused.yaml, got %v", finding)
+	}
+	for _, result := range results {
+		if len(result.Findings) != 0 || !result.Success {
+			t.Errorf("Expected %s to be left alone, got %v", result.ChartPath, result)
+		}
+	}
+
+	errorConfig := models.RuleConfig{Severity: models.SeverityError}
+	results = []models.Result{{ChartPath: web, Success: true}}
+	findings = CheckUnusedValuesFiles(results, []string{filepath.Join(dir, "used.yaml")}, &models.Config{Rules: map[string]models.RuleConfig{unusedValuesFileID: errorConfig}})
+	if len(findings) != 1 || findings[0].Severity != models.SeverityError {
+		t.Errorf("Expected the overridden severity, got %v", findings)
+	}
+}
@@ -0,0 +1,127 @@
+package renderer
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// ValueUsages returns which values keys each template of a chart uses, and
+// through which named templates, together with any error messages. Every
+// distinct chain of named templates leading to a key is a usage of its own,
+// at the first line it occurs. Usages are ordered by key, template and
+// helpers.
+func ValueUsages(chartPath string) ([]models.ValueUsage, []string) {
+	sources, defines, textRefs, errors := loadTemplateSources(chartPath)
+	uses := walkTemplateReferences(sources, defines).uses
+	for _, ref := range textRefs {
+		uses = append(uses, valueUse{ref: ref, file: ref.File, line: ref.Line})
+	}
+
+	seen := make(map[string]int)
+	var usages []models.ValueUsage
+	for _, use := range uses {
+		template, err := filepath.Rel(chartPath, use.file)
+		if err != nil {
+			template = use.file
+		}
+		usage := models.ValueUsage{
+			Key:      use.ref.Name,
+			Template: filepath.ToSlash(template),
+			Line:     use.line,
+			Helpers:  slices.Clone(use.helpers),
+		}
+		key := usage.Key + "\x00" + usage.Template + "\x00" + strings.Join(usage.Helpers, "\x00")
+		if i, ok := seen[key]; ok {
+			usages[i].Line = min(usages[i].Line, usage.Line)
+			continue
+		}
+		seen[key] = len(usages)
+		usages = append(usages, usage)
+	}
+
+	sort.SliceStable(usages, func(i, j int) bool {
+		a, b := usages[i], usages[j]
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		if a.Template != b.Template {
+			return a.Template < b.Template
+		}
+		return strings.Join(a.Helpers, "\x00") < strings.Join(b.Helpers, "\x00")
+	})
+	return usages, errors
+}
+
+// WriteValueUsagesMarkdown writes the usages of a chart's values as a
+// GitHub-flavored markdown table, one row per usage.
+func WriteValueUsagesMarkdown(w io.Writer, chartName string, usages []models.ValueUsage) {
+	fmt.Fprintf(w, "## Values used by %s\n", chartName)
+	fmt.Fprintln(w)
+	if len(usages) == 0 {
+		fmt.Fprintln(w, "No template uses any values.")
+		return
+	}
+	fmt.Fprintln(w, "| Value | Template | Via |")
+	fmt.Fprintln(w, "|-------|----------|-----|")
+	for _, usage := range usages {
+		var helpers []string
+		for _, helper := range usage.Helpers {
+			helpers = append(helpers, "`"+escapeMarkdownCell(helper)+"`")
+		}
+		fmt.Fprintf(w, "| `%s` | `%s:%d` | %s |\n", escapeMarkdownCell(usage.Key), escapeMarkdownCell(usage.Template), usage.Line, strings.Join(helpers, " → "))
+	}
+}
+
+// WriteValueUsagesDOT writes the usages of a chart's values as a Graphviz
+// graph: templates (boxes) point at the named templates they include
+// (ellipses), which point at further named templates or the values keys
+// (notes) they use.
+func WriteValueUsagesDOT(w io.Writer, chartName string, usages []models.ValueUsage) {
+	nodes := make(map[string]string)
+	edges := make(map[string]bool)
+	node := func(kind, name, shape string) string {
+		id := dotQuote(kind + ":" + name)
+		nodes[id] = fmt.Sprintf("label=%s, shape=%s", dotQuote(name), shape)
+		return id
+	}
+	for _, usage := range usages {
+		from := node("template", usage.Template, "box")
+		for _, helper := range usage.Helpers {
+			to := node("helper", helper, "ellipse")
+			edges[from+" -> "+to] = true
+			from = to
+		}
+		edges[from+" -> "+node("value", usage.Key, "note")] = true
+	}
+
+	fmt.Fprintf(w, "digraph %s {\n", dotQuote(chartName+" values"))
+	fmt.Fprintln(w, "  rankdir=LR;")
+	for _, id := range sortedKeys(nodes) {
+		fmt.Fprintf(w, "  %s [%s];\n", id, nodes[id])
+	}
+	for _, edge := range sortedKeys(edges) {
+		fmt.Fprintf(w, "  %s;\n", edge)
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// dotQuote quotes an identifier for the DOT language.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,91 @@
+package renderer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestValueUsages(t *testing.T) {
+	chartDir := t.TempDir()
+	templates := map[string]string{
+		"_helpers.tpl": `{{- define "web.image" -}}
+{{ .Values.image.repository }}:{{ include "web.tag" . }}
+{{- end -}}
+{{- define "web.tag" -}}
+{{ .Values.image.tag }}
+{{- end -}}
+{{- define "web.unused" -}}
+{{ .Values.legacy }}
+{{- end -}}`,
+		"deployment.yaml": `spec:
+  replicas: {{ .Values.replicas }}
+  image: {{ include "web.image" . }}
+  tag: {{ include "web.tag" . }}
+  again: {{ .Values.replicas }}`,
+	}
+	os.MkdirAll(filepath.Join(chartDir, "templates"), 0755)
+	for name, content := range templates {
+		if err := os.WriteFile(filepath.Join(chartDir, "templates", name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	usages, errs := ValueUsages(chartDir)
+	if len(errs) != 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	expected := []models.ValueUsage{
+		{Key: "image.repository", Template: "templates/deployment.yaml", Line: 3, Helpers: []string{"web.image"}},
+		{Key: "image.tag", Template: "templates/deployment.yaml", Line: 3, Helpers: []string{"web.image", "web.tag"}},
+		{Key: "image.tag", Template: "templates/deployment.yaml", Line: 4, Helpers: []string{"web.tag"}},
+		{Key: "legacy", Template: "templates/_helpers.tpl", Line: 8, Helpers: []string{"web.unused"}},
+		{Key: "replicas", Template: "templates/deployment.yaml", Line: 2},
+	}
+	if !reflect.DeepEqual(usages, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, usages)
+	}
+}
+
+func TestWriteValueUsages(t *testing.T) {
+	usages := []models.ValueUsage{
+		{Key: "image.tag", Template: "templates/deployment.yaml", Line: 3, Helpers: []string{"web.image", "web.tag"}},
+		{Key: "replicas", Template: "templates/deployment.yaml", Line: 2},
+	}
+
+	var buf bytes.Buffer
+	WriteValueUsagesMarkdown(&buf, "web", usages)
+	for _, expected := range []string{
+		"## Values used by web",
+		"| `image.tag` | `templates/deployment.yaml:3` | `web.image` → `web.tag` |",
+		"| `replicas` | `templates/deployment.yaml:2` |  |",
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected markdown to contain '%s', got:\n%s", expected, buf.String())
+		}
+	}
+
+	buf.Reset()
+	WriteValueUsagesDOT(&buf, "web", usages)
+	expected := `digraph "web values" {
+  rankdir=LR;
+  "helper:web.image" [label="web.image", shape=ellipse];
+  "helper:web.tag" [label="web.tag", shape=ellipse];
+  "template:templates/deployment.yaml" [label="templates/deployment.yaml", shape=box];
+  "value:image.tag" [label="image.tag", shape=note];
+  "value:replicas" [label="replicas", shape=note];
+  "helper:web.image" -> "helper:web.tag";
+  "helper:web.tag" -> "value:image.tag";
+  "template:templates/deployment.yaml" -> "helper:web.image";
+  "template:templates/deployment.yaml" -> "value:replicas";
+}
+`
+	if buf.String() != expected {
+		t.Errorf("Expected DOT output:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
@@ -0,0 +1,104 @@
+package renderer
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+	"github.com/Jaydee94/chartscan/internal/validation"
+)
+
+// valuesSchemaViolationID is the ID of the findings of values that violate
+// the chart's values.schema.json.
+const valuesSchemaViolationID = "values-schema-violation"
+
+// helmSchemaError starts the error helm reports for values violating a
+// values.schema.json, which lists every violation in one message.
+const helmSchemaError = "values don't meet the specifications of the schema"
+
+// checkValuesSchema validates values, the merged values of the chart at
+// chartPath, against the chart's values.schema.json, if it ships one, and
+// returns one finding per violation, located by the JSON pointer of the
+// offending value.
+func checkValuesSchema(chartPath string, values map[string]interface{}) []models.Finding {
+	schema, err := loadValuesSchema(chartPath)
+	if err != nil {
+		return errorFindings(valuesSchemaViolationID, []string{"Error checking values: " + err.Error()})
+	}
+	if schema == nil {
+		return nil
+	}
+
+	var findings []models.Finding
+	for _, violation := range validation.ValidateValues(schema, values) {
+		findings = append(findings, models.Finding{
+			RuleID:   valuesSchemaViolationID,
+			Severity: models.SeverityError,
+			Message:  "Values violate values.schema.json at " + violation,
+			File:     filepath.Join(chartPath, "values.schema.json"),
+		})
+	}
+	return findings
+}
+
+// checkSubchartValuesSchemas validates the values the chart at chartPath
+// passes to each of its enabled subcharts, recursively, against the
+// subchart's values.schema.json. values are the chart's coalesced values and
+// pointer their JSON pointer below the values of the scanned chart, so
+// violations are located the way helm reports them, e.g. /redis/port.
+func checkSubchartValuesSchemas(chartPath string, values map[string]interface{}, pointer string) []models.Finding {
+	subcharts, cleanup, _ := findSubcharts(chartPath)
+	defer cleanup()
+
+	var findings []models.Finding
+	for _, sub := range subcharts {
+		if !subchartEnabled(sub, values) {
+			continue
+		}
+		scoped, _ := values[sub.Key].(map[string]interface{})
+		if scoped == nil {
+			scoped = make(map[string]interface{})
+		}
+		subPointer := pointer + "/" + sub.Key
+		schemaFile := filepath.Join(sub.Path, "values.schema.json")
+
+		schema, err := loadValuesSchema(sub.Dir)
+		if err != nil {
+			findings = append(findings, models.Finding{
+				RuleID:   valuesSchemaViolationID,
+				Severity: models.SeverityError,
+				Message:  "Error checking values: " + err.Error(),
+				File:     schemaFile,
+			})
+		}
+		if schema != nil {
+			for _, violation := range validation.ValidateValues(schema, scoped) {
+				if rest, ok := strings.CutPrefix(violation, "(root)"); ok {
+					violation = subPointer + rest
+				} else {
+					violation = subPointer + violation
+				}
+				findings = append(findings, models.Finding{
+					RuleID:   valuesSchemaViolationID,
+					Severity: models.SeverityError,
+					Message:  "Values violate values.schema.json at " + violation,
+					File:     schemaFile,
+				})
+			}
+		}
+		findings = append(findings, checkSubchartValuesSchemas(sub.Dir, scoped, subPointer)...)
+	}
+	return findings
+}
+
+// withoutHelmSchemaErrors drops the findings quoting helm's error for values
+// violating the schema, which checkValuesSchema reports in detail.
+func withoutHelmSchemaErrors(findings []models.Finding) []models.Finding {
+	var kept []models.Finding
+	for _, f := range findings {
+		if !strings.Contains(f.Message, helmSchemaError) {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
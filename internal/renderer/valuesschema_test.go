@@ -0,0 +1,131 @@
+package renderer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+	"github.com/Jaydee94/chartscan/internal/strvals"
+)
+
+func TestScanHelmChartValuesSchema(t *testing.T) {
+	bin := t.TempDir()
+	// The fake helm lint rejects the values the way helm does, in one message.
+	script := `#!/bin/sh
+if [ "$1" = lint ]; then
+  echo "==> Linting"
+  echo "[ERROR] values.yaml: values don't meet the specifications of the schema(s) in the following chart(s):"
+  echo "web:"
+  echo "- replicas: Invalid type. Expected: integer, given: string"
+  exit 1
+fi
+`
+	if err := os.WriteFile(filepath.Join(bin, "helm"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write helm: %v", err)
+	}
+	t.Setenv("PATH", bin)
+
+	chartDir := filepath.Join(t.TempDir(), "web")
+	writeChart(t, chartDir, "apiVersion: v2\nname: web\nversion: 0.1.0\n", "replicas: 1\nimage:\n  tag: \"1.0\"\n")
+	schema := `{
+  "type": "object",
+  "additionalProperties": false,
+  "properties": {
+    "replicas": {"type": "integer"},
+    "image": {"type": "object", "properties": {"tag": {"type": "string"}}}
+  }
+}`
+	if err := os.WriteFile(filepath.Join(chartDir, "values.schema.json"), []byte(schema), 0644); err != nil {
+		t.Fatalf("Failed to write values.schema.json: %v", err)
+	}
+
+	setValues := strvals.Overrides{Values: []string{"replicas=three", "image.tag=2"}, StringValues: []string{"debug=true"}}
+	result := ScanHelmChart(chartDir, ScanOptions{SetValues: setValues})
+	var got []string
+	for _, finding := range result.Findings {
+		if finding.RuleID == lintRuleID {
+			t.Errorf("Expected helm's schema error to be replaced, got %v", finding)
+		}
+		if finding.RuleID == valuesSchemaViolationID {
+			got = append(got, finding.Message)
+			if finding.File != filepath.Join(chartDir, "values.schema.json") || finding.Severity != models.SeverityError {
+				t.Errorf("Expected an error on values.schema.json, got %v", finding)
+			}
+		}
+	}
+	expected := []string{
+		`Values violate values.schema.json at (root): unknown field "debug"`,
+		"Values violate values.schema.json at /image/tag: expected string, got integer",
+		"Values violate values.schema.json at /replicas: expected integer, got string",
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Expected %q, got %q", expected[i], got[i])
+		}
+	}
+}
+
+func TestCheckValuesSchemaWithoutSchema(t *testing.T) {
+	chartDir := t.TempDir()
+	if findings := checkValuesSchema(chartDir, map[string]interface{}{"replicas": "three"}); len(findings) != 0 {
+		t.Errorf("Expected no findings without values.schema.json, got %v", findings)
+	}
+
+	if err := os.WriteFile(filepath.Join(chartDir, "values.schema.json"), []byte("{"), 0644); err != nil {
+		t.Fatalf("Failed to write values.schema.json: %v", err)
+	}
+	findings := checkValuesSchema(chartDir, nil)
+	if len(findings) != 1 || findings[0].Message != "Error checking values: values.schema.json is not valid JSON: unexpected end of JSON input" {
+		t.Errorf("Expected an invalid schema error, got %v", findings)
+	}
+}
+
+func TestCheckSubchartValuesSchemas(t *testing.T) {
+	parent := t.TempDir()
+	writeChart(t, parent, `apiVersion: v2
+name: app
+version: 0.1.0
+dependencies:
+  - name: redis
+    version: 1.0.0
+  - name: cache
+    version: 1.0.0
+    condition: cache.enabled
+`, "")
+	redisDir := filepath.Join(parent, "charts", "redis")
+	writeChart(t, redisDir, "apiVersion: v2\nname: redis\nversion: 1.0.0\n", "port: 6379\n")
+	schema := `{"type": "object", "properties": {"port": {"type": "integer"}}, "required": ["password"]}`
+	if err := os.WriteFile(filepath.Join(redisDir, "values.schema.json"), []byte(schema), 0644); err != nil {
+		t.Fatalf("Failed to write values.schema.json: %v", err)
+	}
+	cacheDir := filepath.Join(parent, "charts", "cache")
+	writeChart(t, cacheDir, "apiVersion: v2\nname: cache\nversion: 1.0.0\n", "")
+	if err := os.WriteFile(filepath.Join(cacheDir, "values.schema.json"), []byte(`{"required": ["size"]}`), 0644); err != nil {
+		t.Fatalf("Failed to write values.schema.json: %v", err)
+	}
+
+	values := map[string]interface{}{
+		"redis": map[string]interface{}{"port": "6379"},
+		"cache": map[string]interface{}{"enabled": false},
+	}
+	findings := checkSubchartValuesSchemas(parent, values, "")
+	var got []string
+	for _, finding := range findings {
+		got = append(got, finding.Message)
+		if finding.File != filepath.Join(redisDir, "values.schema.json") || finding.RuleID != valuesSchemaViolationID {
+			t.Errorf("Expected a violation of the redis schema, got %v", finding)
+		}
+	}
+	expected := []string{
+		`Values violate values.schema.json at /redis: missing required field "password"`,
+		"Values violate values.schema.json at /redis/port: expected integer, got string",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
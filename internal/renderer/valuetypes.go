@@ -0,0 +1,155 @@
+package renderer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// valueTypeMismatchID is the ID of the findings of values whose type does not
+// fit the way the templates use them.
+const valueTypeMismatchID = "value-type-mismatch"
+
+// falseStrings are the strings that read as false but, being non-empty, are
+// true in an if condition.
+var falseStrings = map[string]bool{"false": true, "no": true, "off": true}
+
+// checkValueTypes reports the references whose value in values has a type
+// the template cannot use: a value ranged over that is not a list or map, a
+// value with keys accessed below it that is not a map (or a list, for
+// indices), and a string such as "false" tested by an if, which is always
+// true. The first two fail rendering and are errors, the last is a warning.
+// References in blocks whose guards are empty are skipped, since if and with
+// do not render them. Undefined values are left to checkValueReferences.
+func checkValueTypes(valueReferences []models.ValueReference, values map[string]interface{}) []models.Finding {
+	var findings []models.Finding
+	seen := make(map[string]bool)
+	for _, ref := range valueReferences {
+		if len(ref.Path) == 0 || !guardsTruthy(ref.Guards, values) {
+			continue
+		}
+		finding, ok := valueTypeMismatch(ref, values)
+		if !ok {
+			continue
+		}
+		if ref.IncludedFrom != "" {
+			finding.Message += " (included from " + ref.IncludedFrom + ")"
+		}
+		key := fmt.Sprintf("%s:%d:%s", finding.File, finding.Line, finding.Message)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+// guardsTruthy reports whether every guard path holds a value that is true
+// in a template condition, i.e. whether the guarded block is rendered.
+func guardsTruthy(guards [][]string, values map[string]interface{}) bool {
+	for _, guard := range guards {
+		if !truthy(lookupValue(guard, values)) {
+			return false
+		}
+	}
+	return true
+}
+
+// truthy reports whether a template condition treats value as true: it is
+// not nil, false, zero or empty.
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case int:
+		return v != 0
+	case int64:
+		return v != 0
+	case uint64:
+		return v != 0
+	case float64:
+		return v != 0
+	case []interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return len(v) > 0
+	}
+	return true
+}
+
+// valueTypeMismatch returns the finding for ref if its value, or a value on
+// the way to it, has the wrong type.
+func valueTypeMismatch(ref models.ValueReference, values map[string]interface{}) (models.Finding, bool) {
+	finding := models.Finding{
+		RuleID:   valueTypeMismatchID,
+		Severity: models.SeverityError,
+		File:     ref.File,
+		Line:     ref.Line,
+	}
+
+	var current interface{} = values
+	for i, key := range ref.Path {
+		switch c := current.(type) {
+		case map[string]interface{}:
+			current = c[key]
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil {
+				finding.Message = fmt.Sprintf("Value '%s' is a list, but the template accesses the key %s of it, which needs a map", strings.Join(ref.Path[:i], "."), key)
+				return finding, true
+			}
+			if index < 0 || index >= len(c) {
+				return finding, false
+			}
+			current = c[index]
+		default:
+			finding.Message = fmt.Sprintf("Value '%s' is %s, but the template accesses %s below it, which needs a map", strings.Join(ref.Path[:i], "."), valueKind(current), ref.Name)
+			return finding, true
+		}
+		if current == nil {
+			return finding, false
+		}
+	}
+
+	switch ref.Expects {
+	case models.ExpectsList:
+		switch current.(type) {
+		case map[string]interface{}, []interface{}:
+			return finding, false
+		}
+		finding.Message = fmt.Sprintf("Value '%s' is %s, but the template ranges over it, which needs a list or map", ref.Name, valueKind(current))
+		return finding, true
+	case models.ExpectsBool:
+		if s, ok := current.(string); ok && falseStrings[strings.ToLower(s)] {
+			finding.Severity = models.SeverityWarning
+			finding.Message = fmt.Sprintf("Value '%s' is the string %q, which an if condition treats as true; use the boolean false", ref.Name, s)
+			return finding, true
+		}
+	}
+	return finding, false
+}
+
+// valueKind describes the type of a value loaded from YAML, with its value
+// for scalars, e.g. `the string "a"`.
+func valueKind(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("the string %q", v)
+	case bool:
+		return fmt.Sprintf("the boolean %v", v)
+	case int, int64, uint64, float64:
+		return fmt.Sprintf("the number %v", v)
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}:
+		return "a map"
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -0,0 +1,70 @@
+package renderer
+
+import (
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestCheckValueTypes(t *testing.T) {
+	template := `{{- range .Values.hosts }}
+host: {{ . }}
+{{- end }}
+{{- range $k, $v := .Values.labels }}{{ $k }}: {{ $v }}{{ end }}
+tag: {{ .Values.image.tag }}
+{{- if .Values.metrics.enabled }}
+metrics: true
+{{- end }}
+{{- with .Values.ingress }}
+{{- range .rules }}{{ . }}{{ end }}
+{{- end }}
+first: {{ index .Values.ports 0 }}
+name: {{ .Values.ports.name }}
+{{- if .Values.debug }}debug{{ end }}
+{{- if .Values.service }}{{ .Values.service.port }}{{ end }}
+`
+	refs, ok := parseTemplateTree("templates/app.yaml", template)
+	if !ok {
+		t.Fatalf("Expected the template to parse")
+	}
+
+	values := map[string]interface{}{
+		"hosts":   "example.com",
+		"labels":  map[string]interface{}{"app": "web"},
+		"image":   "nginx:1.27",
+		"metrics": map[string]interface{}{"enabled": "false"},
+		"ingress": map[string]interface{}{"rules": true},
+		"ports":   []interface{}{80},
+		"debug":   "true",
+		"service": "none",
+	}
+	findings := checkValueTypes(refs, values)
+	expected := []models.Finding{
+		{Severity: models.SeverityError, Line: 1, Message: `Value 'hosts' is the string "example.com", but the template ranges over it, which needs a list or map`},
+		{Severity: models.SeverityError, Line: 5, Message: `Value 'image' is the string "nginx:1.27", but the template accesses image.tag below it, which needs a map`},
+		{Severity: models.SeverityWarning, Line: 6, Message: `Value 'metrics.enabled' is the string "false", which an if condition treats as true; use the boolean false`},
+		{Severity: models.SeverityError, Line: 10, Message: "Value 'ingress.rules' is the boolean true, but the template ranges over it, which needs a list or map"},
+		{Severity: models.SeverityError, Line: 13, Message: "Value 'ports' is a list, but the template accesses the key name of it, which needs a map"},
+		{Severity: models.SeverityError, Line: 15, Message: `Value 'service' is the string "none", but the template accesses service.port below it, which needs a map`},
+	}
+	if len(findings) != len(expected) {
+		t.Fatalf("Expected %d findings, got %d: %v", len(expected), len(findings), findings)
+	}
+	for i, finding := range findings {
+		if finding.RuleID != valueTypeMismatchID || finding.File != "templates/app.yaml" {
+			t.Errorf("Expected a %s finding in templates/app.yaml, got %v", valueTypeMismatchID, finding)
+		}
+		if finding.Severity != expected[i].Severity || finding.Line != expected[i].Line || finding.Message != expected[i].Message {
+			t.Errorf("Expected finding %d to be %s at line %d: %s, got %v", i, expected[i].Severity, expected[i].Line, expected[i].Message, finding)
+		}
+	}
+
+	values["service"] = ""
+	if findings := checkValueTypes(refs, values); len(findings) != len(expected)-1 {
+		t.Errorf("Expected no finding in the if block of an empty value, got %v", findings)
+	}
+
+	if findings := checkValueTypes(refs, map[string]interface{}{}); len(findings) != 0 {
+		t.Errorf("Expected undefined values to be left to the undefined-value check, got %v", findings)
+	}
+}
@@ -0,0 +1,259 @@
+package rules
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func init() {
+	Register(argoInlineValuesRule{})
+}
+
+// argoInlineValuesRule validates Helm parameters and values carried inline by
+// Argo CD Applications against the values structure of the chart they point
+// to, catching typos in app-of-apps setups before they reach the cluster.
+type argoInlineValuesRule struct{}
+
+func (argoInlineValuesRule) ID() string { return "argocd-inline-values" }
+
+func (argoInlineValuesRule) Enabled(config *models.Config) bool {
+	return config.GitOps.Tool == "argocd"
+}
+
+func (r argoInlineValuesRule) Check(ctx *Context) []models.Finding {
+	var findings []models.Finding
+
+	for _, m := range ctx.Manifests {
+		if m.Kind != "Application" || !strings.HasPrefix(m.APIVersion, "argoproj.io/") {
+			continue
+		}
+
+		sources := NestedSlice(m.Object, "spec", "sources")
+		if source := NestedMap(m.Object, "spec", "source"); source != nil {
+			sources = append(sources, source)
+		}
+
+		for _, s := range sources {
+			source, _ := s.(map[string]interface{})
+			helm := NestedMap(source, "helm")
+			path := NestedString(source, "path")
+			if helm == nil || path == "" || ctx.RepoRoot == "" {
+				continue
+			}
+
+			chartDir := filepath.Join(ctx.RepoRoot, path)
+			chartValues, err := loadYAMLMap(filepath.Join(chartDir, "values.yaml"))
+			if os.IsNotExist(err) {
+				// Not a chart in this repository (e.g. another repoURL), or a
+				// chart without defaults to check the keys against.
+				continue
+			} else if err != nil {
+				findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+					"cannot load values of referenced chart %s: %v", path, err))
+				continue
+			}
+			if len(chartValues) == 0 {
+				continue
+			}
+
+			for _, problem := range checkInlineValues(helm, chartValues) {
+				findings = append(findings, newFinding(r.ID(), models.SeverityError, m, "chart %s: %s", path, problem))
+			}
+		}
+	}
+
+	return findings
+}
+
+// checkInlineValues validates the parameters, valuesObject and values of an
+// Application's helm source against chartValues and returns the problems.
+func checkInlineValues(helm map[string]interface{}, chartValues map[string]interface{}) []string {
+	var problems []string
+
+	for _, p := range NestedSlice(helm, "parameters") {
+		param, _ := p.(map[string]interface{})
+		name := NestedString(param, "name")
+		if name == "" {
+			continue
+		}
+		var value interface{} = NestedString(param, "value")
+		if forceString, _ := param["forceString"].(bool); !forceString {
+			value = parseParameterValue(NestedString(param, "value"))
+		}
+		problems = append(problems, checkInlineValue("parameter "+name, splitParameterName(name), value, chartValues)...)
+	}
+
+	if valuesObject := NestedMap(helm, "valuesObject"); valuesObject != nil {
+		problems = append(problems, compareValues("valuesObject", nil, valuesObject, chartValues)...)
+	}
+
+	if raw := NestedString(helm, "values"); raw != "" {
+		var values map[string]interface{}
+		if err := yaml.Unmarshal([]byte(raw), &values); err != nil {
+			problems = append(problems, fmt.Sprintf("values is not valid YAML: %v", err))
+		} else {
+			problems = append(problems, compareValues("values", nil, values, chartValues)...)
+		}
+	}
+
+	return problems
+}
+
+// compareValues walks inline values and reports keys unknown to the chart and
+// values whose type does not match the chart default.
+func compareValues(origin string, prefix []string, inline, chart map[string]interface{}) []string {
+	var problems []string
+
+	keys := make([]string, 0, len(inline))
+	for key := range inline {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		path := append(append([]string{}, prefix...), key)
+		problems = append(problems, checkInlineValue(origin, path, inline[key], chart)...)
+	}
+
+	return problems
+}
+
+// checkInlineValue checks a single inline value at path against chart values.
+func checkInlineValue(origin string, path []string, value interface{}, chart map[string]interface{}) []string {
+	var current interface{} = chart
+	for i, key := range path {
+		if list, ok := current.([]interface{}); ok {
+			index, err := strconv.Atoi(key)
+			if err != nil {
+				return []string{fmt.Sprintf("%s sets %s but %s is a list in the chart values",
+					origin, strings.Join(path, "."), strings.Join(path[:i], "."))}
+			}
+			if index < 0 || index >= len(list) {
+				// Appending list elements beyond the defaults is allowed.
+				return nil
+			}
+			current = list[index]
+			continue
+		}
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s sets %s but %s is not a map in the chart values",
+				origin, strings.Join(path, "."), strings.Join(path[:i], "."))}
+		}
+		if len(m) == 0 && i > 0 {
+			// Free-form maps such as podAnnotations accept any key.
+			return nil
+		}
+		next, exists := m[key]
+		if !exists {
+			return []string{fmt.Sprintf("%s sets unknown key %s", origin, strings.Join(path, "."))}
+		}
+		current = next
+	}
+
+	expected, actual := valueKind(current), valueKind(value)
+	if expected == "null" || actual == "null" {
+		return nil
+	}
+	if expected != actual {
+		return []string{fmt.Sprintf("%s sets %s to a %s but the chart default is a %s",
+			origin, strings.Join(path, "."), actual, expected)}
+	}
+
+	if inlineMap, ok := value.(map[string]interface{}); ok {
+		chartMap := current.(map[string]interface{})
+		if len(chartMap) == 0 {
+			return nil
+		}
+		return compareValues(origin, path, inlineMap, chart)
+	}
+
+	return nil
+}
+
+// valueKind classifies a decoded YAML value as map, list, string, number,
+// bool or null.
+func valueKind(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "map"
+	case []interface{}:
+		return "list"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case int, int64, uint64, float64:
+		return "number"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// parseParameterValue converts a helm --set style string to the type helm
+// would infer for it.
+func parseParameterValue(value string) interface{} {
+	if b, err := strconv.ParseBool(value); err == nil {
+		return b
+	}
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return i
+	}
+	if value == "null" {
+		return nil
+	}
+	return value
+}
+
+// splitParameterName splits a helm parameter name such as
+// ingress.hosts[0].host on dots and list indices, honouring backslash-escaped
+// dots as part of a key.
+func splitParameterName(name string) []string {
+	var parts []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			parts = append(parts, current.String())
+			current.Reset()
+		}
+	}
+	for i := 0; i < len(name); i++ {
+		switch {
+		case name[i] == '\\' && i+1 < len(name) && name[i+1] == '.':
+			current.WriteByte('.')
+			i++
+		case name[i] == '.' || name[i] == '[' || name[i] == ']':
+			flush()
+		default:
+			current.WriteByte(name[i])
+		}
+	}
+	flush()
+	return parts
+}
+
+// loadYAMLMap reads a YAML file into a map. An empty file yields an empty map.
+func loadYAMLMap(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	if values == nil {
+		values = make(map[string]interface{})
+	}
+	return values, nil
+}
@@ -0,0 +1,126 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestArgoInlineValuesRule(t *testing.T) {
+	repoRoot := t.TempDir()
+	chartDir := filepath.Join(repoRoot, "charts", "web")
+	if err := os.MkdirAll(chartDir, 0755); err != nil {
+		t.Fatalf("Failed to create chart dir: %v", err)
+	}
+	os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte("apiVersion: v2\nname: web\nversion: 0.1.0\n"), 0644)
+	os.WriteFile(filepath.Join(chartDir, "values.yaml"), []byte(`
+replicaCount: 1
+image:
+  repository: nginx
+  tag: "1.25"
+podAnnotations: {}
+ingress:
+  hosts:
+    - host: web.example.com
+`), 0644)
+
+	manifests, err := ParseManifests(`
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: web
+spec:
+  source:
+    repoURL: https://git.example.com/platform.git
+    path: charts/web
+    helm:
+      parameters:
+        - name: image.tag
+          value: "1.26"
+          forceString: true
+        - name: replicaCunt
+          value: "2"
+        - name: ingress.hosts[0].host
+          value: other.example.com
+      valuesObject:
+        image: nginx:latest
+        podAnnotations:
+          prometheus.io/scrape: "true"
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx := &Context{
+		RepoRoot:  repoRoot,
+		Manifests: manifests,
+		Config:    models.Config{GitOps: models.GitOpsConfig{Tool: "argocd"}},
+	}
+	findings := argoInlineValuesRule{}.Check(ctx)
+
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %d: %v", len(findings), findings)
+	}
+	if !strings.Contains(findings[0].Message, "unknown key replicaCunt") {
+		t.Errorf("Expected unknown key finding, got %s", findings[0].Message)
+	}
+	if !strings.Contains(findings[1].Message, "sets image to a string but the chart default is a map") {
+		t.Errorf("Expected type mismatch finding, got %s", findings[1].Message)
+	}
+}
+
+func TestArgoInlineValuesRuleWithoutValues(t *testing.T) {
+	repoRoot := t.TempDir()
+	for _, chart := range []string{"bare", "empty"} {
+		chartDir := filepath.Join(repoRoot, "charts", chart)
+		if err := os.MkdirAll(chartDir, 0755); err != nil {
+			t.Fatalf("Failed to create chart dir: %v", err)
+		}
+		os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte("apiVersion: v2\nname: "+chart+"\nversion: 0.1.0\n"), 0644)
+	}
+	os.WriteFile(filepath.Join(repoRoot, "charts", "empty", "values.yaml"), []byte("# No defaults\n"), 0644)
+
+	manifests, err := ParseManifests(`
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: apps
+spec:
+  sources:
+    - path: charts/bare
+      helm:
+        parameters:
+          - name: replicaCount
+            value: "2"
+    - path: charts/empty
+      helm:
+        valuesObject:
+          image: nginx
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx := &Context{
+		RepoRoot:  repoRoot,
+		Manifests: manifests,
+		Config:    models.Config{GitOps: models.GitOpsConfig{Tool: "argocd"}},
+	}
+	if findings := (argoInlineValuesRule{}).Check(ctx); len(findings) != 0 {
+		t.Errorf("Expected no findings for charts without default values, got %v", findings)
+	}
+}
+
+func TestSplitParameterName(t *testing.T) {
+	got := strings.Join(splitParameterName(`podAnnotations.prometheus\.io/port`), "|")
+	if got != "podAnnotations|prometheus.io/port" {
+		t.Errorf("Unexpected split with escaped dot: %s", got)
+	}
+	got = strings.Join(splitParameterName("ingress.hosts[0].host"), "|")
+	if got != "ingress|hosts|0|host" {
+		t.Errorf("Unexpected split with index: %s", got)
+	}
+}
@@ -0,0 +1,249 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+	"github.com/Jaydee94/chartscan/internal/registry"
+)
+
+func init() {
+	Register(resourceLimitsRule{})
+	Register(latestImageTagRule{})
+	Register(privilegedContainerRule{})
+	Register(livenessProbeRule{})
+	Register(deprecatedAPIRule{})
+}
+
+// bestPracticeEnabled reports whether the best-practice rule id runs: with the
+// whole set enabled, or on its own through the rules section.
+func bestPracticeEnabled(config *models.Config, id string) bool {
+	if override := config.Rules[id].Enabled; override != nil {
+		return *override
+	}
+	return config.BestPractices.Enabled
+}
+
+// resourceLimitsRule requires CPU and memory limits on every container, so a
+// single workload cannot starve its node. The resources option narrows or
+// extends the required limits, e.g. to memory only.
+type resourceLimitsRule struct{}
+
+func (resourceLimitsRule) ID() string { return "resource-limits" }
+
+func (r resourceLimitsRule) Enabled(config *models.Config) bool {
+	return bestPracticeEnabled(config, r.ID())
+}
+
+func (r resourceLimitsRule) Check(ctx *Context) []models.Finding {
+	resources := []string{"cpu", "memory"}
+	if option, ok := ctx.Options(r.ID())["resources"].([]interface{}); ok {
+		resources = nil
+		for _, resource := range option {
+			resources = append(resources, fmt.Sprint(resource))
+		}
+	}
+
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		podSpec := PodSpec(m)
+		if podSpec == nil {
+			continue
+		}
+		for _, container := range Containers(podSpec, true) {
+			var missing []string
+			for _, resource := range resources {
+				if NestedValue(container, "resources", "limits", resource) == nil {
+					missing = append(missing, resource)
+				}
+			}
+			if len(missing) > 0 {
+				findings = append(findings, newFinding(r.ID(), models.SeverityWarning, m,
+					"container %s sets no %s limit", NestedString(container, "name"), strings.Join(missing, " or ")))
+			}
+		}
+	}
+	return findings
+}
+
+// latestImageTagRule flags images that float with the latest tag, explicitly
+// or by omitting the tag, since pods of one workload may then run different
+// code.
+type latestImageTagRule struct{}
+
+func (latestImageTagRule) ID() string { return "latest-image-tag" }
+
+func (r latestImageTagRule) Enabled(config *models.Config) bool {
+	return bestPracticeEnabled(config, r.ID())
+}
+
+func (r latestImageTagRule) Check(ctx *Context) []models.Finding {
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		for _, image := range workloadImages(m) {
+			ref, err := registry.ParseReference(image)
+			if err != nil || strings.Contains(image, "@") || ref.Reference != "latest" {
+				continue
+			}
+			findings = append(findings, newFinding(r.ID(), models.SeverityWarning, m,
+				"image %s uses the latest tag; pin a version", image))
+		}
+	}
+	return findings
+}
+
+// privilegedContainerRule flags privileged containers, which have full access
+// to their node.
+type privilegedContainerRule struct{}
+
+func (privilegedContainerRule) ID() string { return "privileged-container" }
+
+func (r privilegedContainerRule) Enabled(config *models.Config) bool {
+	return bestPracticeEnabled(config, r.ID())
+}
+
+func (r privilegedContainerRule) Check(ctx *Context) []models.Finding {
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		podSpec := PodSpec(m)
+		if podSpec == nil {
+			continue
+		}
+		for _, container := range Containers(podSpec, true) {
+			if privileged, _ := NestedValue(container, "securityContext", "privileged").(bool); privileged {
+				findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+					"container %s is privileged", NestedString(container, "name")))
+			}
+		}
+	}
+	return findings
+}
+
+// livenessProbeRule requires a liveness probe on the containers of long-running
+// workloads, so the kubelet restarts them when they hang. Jobs and CronJobs run
+// to completion and are skipped.
+type livenessProbeRule struct{}
+
+func (livenessProbeRule) ID() string { return "liveness-probe" }
+
+func (r livenessProbeRule) Enabled(config *models.Config) bool {
+	return bestPracticeEnabled(config, r.ID())
+}
+
+func (r livenessProbeRule) Check(ctx *Context) []models.Finding {
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		podSpec := PodSpec(m)
+		if podSpec == nil || m.Kind == "Job" || m.Kind == "CronJob" {
+			continue
+		}
+		for _, container := range Containers(podSpec, false) {
+			if NestedMap(container, "livenessProbe") == nil {
+				findings = append(findings, newFinding(r.ID(), models.SeverityWarning, m,
+					"container %s has no livenessProbe", NestedString(container, "name")))
+			}
+		}
+	}
+	return findings
+}
+
+// deprecatedAPI is an API version that is deprecated and removed in the given
+// Kubernetes minor versions. An empty kinds list covers every kind of the
+// version.
+type deprecatedAPI struct {
+	apiVersion  string
+	kinds       []string
+	deprecated  int
+	removed     int
+	replacement string
+}
+
+var deprecatedAPIs = []deprecatedAPI{
+	{"extensions/v1beta1", []string{"Ingress"}, 14, 22, "networking.k8s.io/v1"},
+	{"extensions/v1beta1", []string{"Deployment", "DaemonSet", "ReplicaSet"}, 9, 16, "apps/v1"},
+	{"extensions/v1beta1", []string{"NetworkPolicy"}, 9, 16, "networking.k8s.io/v1"},
+	{"extensions/v1beta1", []string{"PodSecurityPolicy"}, 10, 16, "Pod Security Admission"},
+	{"apps/v1beta1", nil, 9, 16, "apps/v1"},
+	{"apps/v1beta2", nil, 9, 16, "apps/v1"},
+	{"networking.k8s.io/v1beta1", nil, 19, 22, "networking.k8s.io/v1"},
+	{"rbac.authorization.k8s.io/v1beta1", nil, 17, 22, "rbac.authorization.k8s.io/v1"},
+	{"apiregistration.k8s.io/v1beta1", nil, 19, 22, "apiregistration.k8s.io/v1"},
+	{"authentication.k8s.io/v1beta1", nil, 19, 22, "authentication.k8s.io/v1"},
+	{"authorization.k8s.io/v1beta1", nil, 19, 22, "authorization.k8s.io/v1"},
+	{"apiextensions.k8s.io/v1beta1", nil, 16, 22, "apiextensions.k8s.io/v1"},
+	{"admissionregistration.k8s.io/v1beta1", nil, 16, 22, "admissionregistration.k8s.io/v1"},
+	{"scheduling.k8s.io/v1beta1", nil, 14, 22, "scheduling.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", []string{"CSIStorageCapacity"}, 24, 27, "storage.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", nil, 19, 22, "storage.k8s.io/v1"},
+	{"coordination.k8s.io/v1beta1", nil, 19, 22, "coordination.k8s.io/v1"},
+	{"certificates.k8s.io/v1beta1", nil, 19, 22, "certificates.k8s.io/v1"},
+	{"batch/v1beta1", nil, 21, 25, "batch/v1"},
+	{"policy/v1beta1", []string{"PodSecurityPolicy"}, 21, 25, "Pod Security Admission"},
+	{"policy/v1beta1", nil, 21, 25, "policy/v1"},
+	{"autoscaling/v2beta1", nil, 22, 25, "autoscaling/v2"},
+	{"autoscaling/v2beta2", nil, 23, 26, "autoscaling/v2"},
+	{"discovery.k8s.io/v1beta1", nil, 21, 25, "discovery.k8s.io/v1"},
+	{"events.k8s.io/v1beta1", nil, 19, 25, "events.k8s.io/v1"},
+	{"node.k8s.io/v1beta1", nil, 20, 25, "node.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta1", nil, 23, 26, "flowcontrol.apiserver.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta2", nil, 26, 29, "flowcontrol.apiserver.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta3", nil, 29, 32, "flowcontrol.apiserver.k8s.io/v1"},
+}
+
+// deprecatedAPIRule flags manifests using deprecated API versions. Versions
+// removed in the target Kubernetes version (validation.kubeVersion) are
+// errors; without a target every deprecated version is a warning. Unlike the
+// other best practices it runs whenever a target is set, since a chart
+// rendering removed APIs cannot be installed there.
+type deprecatedAPIRule struct{}
+
+func (deprecatedAPIRule) ID() string { return "deprecated-api" }
+
+func (r deprecatedAPIRule) Enabled(config *models.Config) bool {
+	if override := config.Rules[r.ID()].Enabled; override != nil {
+		return *override
+	}
+	return config.BestPractices.Enabled || config.Validation.KubeVersion != ""
+}
+
+func (r deprecatedAPIRule) Check(ctx *Context) []models.Finding {
+	target, known := kubeMinorVersion(ctx.Config.Validation.KubeVersion)
+
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		api, ok := findDeprecatedAPI(m.APIVersion, m.Kind)
+		if !ok || (known && target < api.deprecated) {
+			continue
+		}
+		if known && target >= api.removed {
+			findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+				"%s %s was removed in Kubernetes 1.%d and is not served by the target 1.%d; use %s",
+				m.APIVersion, m.Kind, api.removed, target, api.replacement))
+			continue
+		}
+		findings = append(findings, newFinding(r.ID(), models.SeverityWarning, m,
+			"%s %s is deprecated since Kubernetes 1.%d and removed in 1.%d; use %s",
+			m.APIVersion, m.Kind, api.deprecated, api.removed, api.replacement))
+	}
+	return findings
+}
+
+// findDeprecatedAPI returns the first deprecation entry matching apiVersion
+// and kind.
+func findDeprecatedAPI(apiVersion, kind string) (deprecatedAPI, bool) {
+	for _, api := range deprecatedAPIs {
+		if api.apiVersion != apiVersion {
+			continue
+		}
+		if len(api.kinds) == 0 {
+			return api, true
+		}
+		for _, k := range api.kinds {
+			if k == kind {
+				return api, true
+			}
+		}
+	}
+	return deprecatedAPI{}, false
+}
@@ -0,0 +1,200 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+const bestPracticeManifests = `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      initContainers:
+        - name: setup
+          image: busybox:1.36
+          securityContext:
+            privileged: true
+          resources:
+            limits: {cpu: 100m, memory: 64Mi}
+      containers:
+        - name: web
+          image: nginx
+          resources:
+            limits: {memory: 128Mi}
+          livenessProbe:
+            httpGet: {path: /healthz, port: 8080}
+        - name: metrics
+          image: ghcr.io/example/exporter:latest
+          resources:
+            limits: {cpu: 100m, memory: 64Mi}
+---
+apiVersion: batch/v1beta1
+kind: CronJob
+metadata:
+  name: backup
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+            - name: backup
+              image: example/backup@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef
+              resources:
+                limits: {cpu: 100m, memory: 64Mi}
+---
+apiVersion: networking.k8s.io/v1beta1
+kind: Ingress
+metadata:
+  name: web
+`
+
+func TestBestPracticeRules(t *testing.T) {
+	manifests, err := ParseManifests(bestPracticeManifests)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx := &Context{Manifests: manifests, Config: models.Config{BestPractices: models.BestPracticesConfig{Enabled: true}}}
+
+	tests := []struct {
+		rule     Rule
+		expected []string
+	}{
+		{resourceLimitsRule{}, []string{"container web sets no cpu limit"}},
+		{latestImageTagRule{}, []string{"image ghcr.io/example/exporter:latest uses the latest tag; pin a version", "image nginx uses the latest tag; pin a version"}},
+		{privilegedContainerRule{}, []string{"container setup is privileged"}},
+		{livenessProbeRule{}, []string{"container metrics has no livenessProbe"}},
+		{deprecatedAPIRule{}, []string{
+			"batch/v1beta1 CronJob is deprecated since Kubernetes 1.21 and removed in 1.25; use batch/v1",
+			"networking.k8s.io/v1beta1 Ingress is deprecated since Kubernetes 1.19 and removed in 1.22; use networking.k8s.io/v1",
+		}},
+	}
+	for _, test := range tests {
+		if !test.rule.Enabled(&ctx.Config) {
+			t.Errorf("Expected %s to be enabled by bestPractices", test.rule.ID())
+		}
+		var messages []string
+		for _, finding := range test.rule.Check(ctx) {
+			messages = append(messages, finding.Message)
+		}
+		if strings.Join(messages, "\n") != strings.Join(test.expected, "\n") {
+			t.Errorf("Expected %s findings:\n%s\ngot:\n%s", test.rule.ID(), strings.Join(test.expected, "\n"), strings.Join(messages, "\n"))
+		}
+	}
+}
+
+func TestDeprecatedAPIRuleKubeVersion(t *testing.T) {
+	manifests, err := ParseManifests(bestPracticeManifests)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	config := models.Config{BestPractices: models.BestPracticesConfig{Enabled: true}, Validation: models.ValidationConfig{KubeVersion: "1.24"}}
+
+	findings := deprecatedAPIRule{}.Check(&Context{Manifests: manifests, Config: config})
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %v", findings)
+	}
+	if findings[0].Resource != "CronJob/backup" || findings[0].Severity != models.SeverityWarning {
+		t.Errorf("Expected a warning for the CronJob still served by 1.24, got %v", findings[0])
+	}
+	if findings[1].Resource != "Ingress/web" || findings[1].Severity != models.SeverityError || !strings.Contains(findings[1].Message, "was removed in Kubernetes 1.22") {
+		t.Errorf("Expected an error for the Ingress removed in 1.22, got %v", findings[1])
+	}
+
+	config.Validation.KubeVersion = "1.18"
+	if findings := (deprecatedAPIRule{}).Check(&Context{Manifests: manifests, Config: config}); len(findings) != 0 {
+		t.Errorf("Expected no findings for APIs not yet deprecated in 1.18, got %v", findings)
+	}
+}
+
+func TestDeprecatedAPIRuleEnabledByKubeVersion(t *testing.T) {
+	config := models.Config{Validation: models.ValidationConfig{KubeVersion: "1.29"}}
+	if !(deprecatedAPIRule{}).Enabled(&config) {
+		t.Errorf("Expected deprecated-api to run with a target Kubernetes version")
+	}
+	if (resourceLimitsRule{}).Enabled(&config) {
+		t.Errorf("Expected the other best practices to stay disabled")
+	}
+
+	disabled := false
+	config.Rules = map[string]models.RuleConfig{"deprecated-api": {Enabled: &disabled}}
+	if (deprecatedAPIRule{}).Enabled(&config) {
+		t.Errorf("Expected the rules section to disable deprecated-api")
+	}
+
+	manifests, err := ParseManifests(`# Source: web/templates/psp.yaml
+apiVersion: policy/v1beta1
+kind: PodSecurityPolicy
+metadata:
+  name: restricted
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	config.Rules = nil
+	findings := deprecatedAPIRule{}.Check(&Context{Manifests: manifests, Config: config})
+	expected := "[error] deprecated-api: PodSecurityPolicy/restricted (web/templates/psp.yaml): policy/v1beta1 PodSecurityPolicy was removed in Kubernetes 1.25 and is not served by the target 1.29; use Pod Security Admission"
+	if len(findings) != 1 || findings[0].String() != expected {
+		t.Errorf("Expected %s, got %v", expected, findings)
+	}
+}
+
+func TestRuleOverrides(t *testing.T) {
+	manifests, err := ParseManifests(bestPracticeManifests)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	disabled, enabled := false, true
+	config := models.Config{Rules: map[string]models.RuleConfig{
+		"privileged-container": {Enabled: &enabled, Severity: models.SeverityInfo},
+		"liveness-probe":       {Enabled: &disabled},
+		"no-such-rule":         {Severity: "fatal"},
+	}}
+
+	if !AnyEnabled(&config) {
+		t.Fatalf("Expected a rule enabled through the rules section")
+	}
+	var got []string
+	findings, _ := Run(&Context{Manifests: manifests, Config: config})
+	for _, finding := range findings {
+		got = append(got, finding.Severity+" "+finding.RuleID+": "+finding.Message)
+	}
+	expected := []string{
+		`error no-such-rule: rules: unknown rule "no-such-rule"`,
+		`error no-such-rule: rules: unknown severity "fatal" for no-such-rule (expected info, warning, error)`,
+		"info privileged-container: container setup is privileged",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected findings:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	config.BestPractices.Enabled = true
+	if (livenessProbeRule{}).Enabled(&config) {
+		t.Errorf("Expected enabled: false to take precedence over bestPractices")
+	}
+}
+
+func TestResourceLimitsRuleOption(t *testing.T) {
+	manifests, err := ParseManifests(bestPracticeManifests)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	config := models.Config{Rules: map[string]models.RuleConfig{
+		"resource-limits": {Options: map[string]interface{}{"resources": []interface{}{"memory"}}},
+	}}
+	if findings := (resourceLimitsRule{}).Check(&Context{Manifests: manifests, Config: config}); len(findings) != 0 {
+		t.Errorf("Expected no findings when only memory limits are required, got %v", findings)
+	}
+
+	config.Rules["resource-limits"] = models.RuleConfig{Options: map[string]interface{}{"resources": []interface{}{"memory", "ephemeral-storage"}}}
+	findings := (resourceLimitsRule{}).Check(&Context{Manifests: manifests, Config: config})
+	if len(findings) != 4 || findings[0].Message != "container web sets no ephemeral-storage limit" {
+		t.Errorf("Expected 4 missing ephemeral-storage limits, got %v", findings)
+	}
+}
@@ -0,0 +1,485 @@
+package rules
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// fetchIcon requests an icon URL and fails unless it responds with success.
+// It is a variable so tests can avoid network access.
+var fetchIcon = func(url string) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return nil
+}
+
+// chartVersionRegex matches a strict semantic version as Helm expects chart
+// versions to be, without a v prefix or leading zeros.
+var chartVersionRegex = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+func init() {
+	Register(chartMetadataRule{})
+	Register(chartVersionRule{})
+	Register(chartKubeVersionRule{})
+	Register(chartIconRule{})
+	Register(chartAPIVersionRule{})
+	Register(chartDependenciesTemplateRule{})
+}
+
+// chartFile is a parsed Chart.yaml with the lines of its top-level keys.
+type chartFile struct {
+	path   string
+	fields map[string]interface{}
+	lines  map[string]int
+}
+
+// loadChartFile parses the Chart.yaml of the chart at chartPath. Its path
+// is relative to the directory containing the chart, like the templates of
+// rendered manifests.
+func loadChartFile(chartPath string) (*chartFile, error) {
+	data, err := os.ReadFile(filepath.Join(chartPath, "Chart.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("error parsing Chart.yaml: %v", err)
+	}
+	chart := &chartFile{
+		path:   filepath.Join(filepath.Base(chartPath), "Chart.yaml"),
+		fields: make(map[string]interface{}),
+		lines:  make(map[string]int),
+	}
+	if len(document.Content) == 0 {
+		return chart, nil
+	}
+	if err := document.Content[0].Decode(&chart.fields); err != nil {
+		return nil, fmt.Errorf("error parsing Chart.yaml: %v", err)
+	}
+	mapping := document.Content[0]
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		chart.lines[mapping.Content[i].Value] = mapping.Content[i].Line
+	}
+	return chart, nil
+}
+
+// finding builds a finding for rule at the given key of the chart file, or
+// at the file itself when the key is missing.
+func (c *chartFile) finding(ruleID, severity, key, format string, args ...interface{}) models.Finding {
+	return models.Finding{
+		RuleID:   ruleID,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+		File:     c.path,
+		Line:     c.lines[key],
+	}
+}
+
+// str returns the string value of key, or "".
+func (c *chartFile) str(key string) string {
+	value, _ := c.fields[key].(string)
+	return strings.TrimSpace(value)
+}
+
+// chartMetadataRule flags Chart.yaml files that lack the description and
+// maintainers readers of a chart repository rely on, that use the Helm 2
+// apiVersion, or that mark the chart deprecated.
+type chartMetadataRule struct{}
+
+func (chartMetadataRule) ID() string { return "chart-metadata" }
+
+func (chartMetadataRule) Enabled(config *models.Config) bool {
+	return config.ChartMetadata.Enabled
+}
+
+func (r chartMetadataRule) Check(ctx *Context) []models.Finding {
+	chart, err := loadChartFile(ctx.ChartPath)
+	if err != nil {
+		return []models.Finding{{RuleID: r.ID(), Severity: models.SeverityError, Message: err.Error()}}
+	}
+
+	var findings []models.Finding
+	switch apiVersion := chart.str("apiVersion"); apiVersion {
+	case "v2":
+	case "v1":
+		findings = append(findings, chart.finding(r.ID(), models.SeverityWarning, "apiVersion",
+			"apiVersion v1 is the Helm 2 chart format; use v2 and move requirements.yaml into Chart.yaml"))
+	case "":
+		findings = append(findings, chart.finding(r.ID(), models.SeverityError, "apiVersion", "apiVersion is missing"))
+	default:
+		findings = append(findings, chart.finding(r.ID(), models.SeverityError, "apiVersion",
+			"unknown apiVersion %q (expected v2)", apiVersion))
+	}
+	if chart.str("description") == "" {
+		findings = append(findings, chart.finding(r.ID(), models.SeverityWarning, "description", "description is missing"))
+	}
+	if maintainers, _ := chart.fields["maintainers"].([]interface{}); len(maintainers) == 0 {
+		findings = append(findings, chart.finding(r.ID(), models.SeverityWarning, "maintainers", "no maintainers are listed"))
+	}
+	if deprecated, _ := chart.fields["deprecated"].(bool); deprecated {
+		findings = append(findings, chart.finding(r.ID(), models.SeverityWarning, "deprecated", "chart is marked deprecated"))
+	}
+	return findings
+}
+
+// chartVersionRule requires the chart version to be a strict semantic
+// version, which Helm and chart repositories order releases by.
+type chartVersionRule struct{}
+
+func (chartVersionRule) ID() string { return "chart-version" }
+
+func (chartVersionRule) Enabled(config *models.Config) bool {
+	return config.ChartMetadata.Enabled
+}
+
+func (r chartVersionRule) Check(ctx *Context) []models.Finding {
+	chart, err := loadChartFile(ctx.ChartPath)
+	if err != nil {
+		return nil
+	}
+	version := chart.str("version")
+	if version == "" {
+		if number, ok := chart.fields["version"]; ok && number != nil {
+			version = fmt.Sprint(number)
+		}
+	}
+	switch {
+	case version == "":
+		return []models.Finding{chart.finding(r.ID(), models.SeverityError, "version", "version is missing")}
+	case !chartVersionRegex.MatchString(version):
+		return []models.Finding{chart.finding(r.ID(), models.SeverityError, "version",
+			"version %q is not a semantic version (MAJOR.MINOR.PATCH)", version)}
+	}
+	return nil
+}
+
+// chartKubeVersionRule checks the kubeVersion constraint of a chart: that it
+// is valid, that it is present when required, and that it admits the
+// Kubernetes version manifests are validated for.
+type chartKubeVersionRule struct{}
+
+func (chartKubeVersionRule) ID() string { return "chart-kube-version" }
+
+func (chartKubeVersionRule) Enabled(config *models.Config) bool {
+	return config.ChartMetadata.Enabled
+}
+
+func (r chartKubeVersionRule) Check(ctx *Context) []models.Finding {
+	chart, err := loadChartFile(ctx.ChartPath)
+	if err != nil {
+		return nil
+	}
+	constraint := chart.str("kubeVersion")
+	if constraint == "" {
+		if ctx.Config.ChartMetadata.RequireKubeVersion {
+			return []models.Finding{chart.finding(r.ID(), models.SeverityWarning, "kubeVersion",
+				"kubeVersion is missing; declare the Kubernetes versions the chart supports")}
+		}
+		return nil
+	}
+
+	if op, replacement, ok := unsupportedConstraintOperator(constraint); ok {
+		return []models.Finding{chart.finding(r.ID(), models.SeverityError, "kubeVersion",
+			"kubeVersion %q uses the operator %s, which Helm does not support; use %s", constraint, op, replacement)}
+	}
+	satisfied, err := VersionSatisfies(constraint, ctx.Config.Validation.KubeVersion)
+	if err != nil {
+		return []models.Finding{chart.finding(r.ID(), models.SeverityError, "kubeVersion",
+			"invalid kubeVersion constraint %q: %v", constraint, err)}
+	}
+	if target := ctx.Config.Validation.KubeVersion; target != "" && !satisfied {
+		return []models.Finding{chart.finding(r.ID(), models.SeverityError, "kubeVersion",
+			"kubeVersion %q does not admit Kubernetes %s, the version manifests are validated for", constraint, target)}
+	}
+	return nil
+}
+
+// constraintOperator matches the operators of a version constraint.
+var constraintOperator = regexp.MustCompile(`[<>=!~^&|]+`)
+
+// unsupportedOperators maps operators of other version constraint syntaxes
+// that Helm rejects to what Helm expects instead.
+var unsupportedOperators = map[string]string{
+	"==":  "=",
+	"===": "=",
+	"&&":  "a space or comma between comparisons",
+	"~=":  "~",
+	"<>":  "!=",
+	"!":   "!=",
+	"|":   "||",
+	"=~":  "~",
+}
+
+// unsupportedConstraintOperator returns the first operator of constraint
+// that Helm does not support, such as == or &&, and its Helm equivalent.
+func unsupportedConstraintOperator(constraint string) (string, string, bool) {
+	for _, op := range constraintOperator.FindAllString(constraint, -1) {
+		if replacement, ok := unsupportedOperators[op]; ok {
+			return op, replacement, true
+		}
+	}
+	return "", "", false
+}
+
+// chartIconRule flags icon URLs that cannot be fetched, which leave a broken
+// image in chart repository UIs. It needs network access and is enabled
+// separately.
+type chartIconRule struct{}
+
+func (chartIconRule) ID() string { return "chart-icon" }
+
+func (chartIconRule) Enabled(config *models.Config) bool {
+	return config.ChartMetadata.Enabled && config.ChartMetadata.CheckIcon
+}
+
+func (r chartIconRule) Check(ctx *Context) []models.Finding {
+	chart, err := loadChartFile(ctx.ChartPath)
+	if err != nil {
+		return nil
+	}
+	icon := chart.str("icon")
+	switch {
+	case icon == "", strings.HasPrefix(icon, "data:"):
+		return nil
+	case !strings.HasPrefix(icon, "http://") && !strings.HasPrefix(icon, "https://"):
+		return []models.Finding{chart.finding(r.ID(), models.SeverityWarning, "icon", "icon %q is not an http(s) URL", icon)}
+	}
+	if err := fetchIcon(icon); err != nil {
+		return []models.Finding{chart.finding(r.ID(), models.SeverityWarning, "icon", "icon %s cannot be fetched: %v", icon, err)}
+	}
+	return nil
+}
+
+// partialVersion is a version of a constraint, such as 1.2.3, 1.2 or 1.x,
+// with the number of parts given.
+type partialVersion struct {
+	parts [3]int
+	given int
+}
+
+// parsePartialVersion parses a version of a constraint. Prerelease and build
+// suffixes are dropped, since Kubernetes versions are compared by their
+// release.
+func parsePartialVersion(s string) (partialVersion, error) {
+	var v partialVersion
+	trimmed := strings.TrimPrefix(s, "v")
+	if i := strings.IndexAny(trimmed, "-+"); i >= 0 {
+		trimmed = trimmed[:i]
+	}
+	fields := strings.Split(trimmed, ".")
+	if len(fields) > 3 {
+		return v, fmt.Errorf("invalid version %q", s)
+	}
+	wildcard := false
+	for i, field := range fields {
+		if field == "x" || field == "X" || field == "*" {
+			wildcard = true
+			continue
+		}
+		n, err := strconv.Atoi(field)
+		if err != nil || wildcard || n < 0 {
+			return v, fmt.Errorf("invalid version %q", s)
+		}
+		v.parts[i] = n
+		v.given = i + 1
+	}
+	return v, nil
+}
+
+// bump returns the lowest version above all versions matching the first
+// given parts of v.
+func (v partialVersion) bump(given int) [3]int {
+	next := [3]int{}
+	copy(next[:given], v.parts[:given])
+	next[given-1]++
+	return next
+}
+
+// compareVersions returns -1, 0 or 1 as a is lower, equal or higher than b.
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// constraintTerm matches one comparison of a version constraint.
+var constraintTerm = regexp.MustCompile(`^(>=|<=|!=|=>|=<|~>|[=<>~^])?\s*(v?[0-9xX*][0-9A-Za-z.*+-]*)`)
+
+// hyphenRange matches a range of versions such as "1.20 - 1.28".
+var hyphenRange = regexp.MustCompile(`^(\S+)\s+-\s+(\S+)$`)
+
+// VersionSatisfies reports whether version satisfies constraint, written in
+// the syntax Helm uses for kubeVersion and dependency versions: comparisons separated by spaces or
+// commas that must all hold, alternatives separated by ||, hyphen ranges, and
+// the ~ and ^ operators. An invalid constraint is an error; an empty version
+// only validates the constraint.
+func VersionSatisfies(constraint, version string) (bool, error) {
+	var target [3]int
+	if version != "" {
+		v, err := parsePartialVersion(version)
+		if err != nil {
+			return false, err
+		}
+		target = v.parts
+	}
+
+	satisfied := false
+	for _, alternative := range strings.Split(constraint, "||") {
+		alternative = strings.TrimSpace(alternative)
+		if alternative == "" {
+			return false, fmt.Errorf("empty alternative")
+		}
+		if match := hyphenRange.FindStringSubmatch(alternative); match != nil {
+			alternative = ">=" + match[1] + " <=" + match[2]
+		}
+
+		holds := true
+		for rest := alternative; rest != ""; {
+			match := constraintTerm.FindStringSubmatch(rest)
+			if match == nil {
+				return false, fmt.Errorf("unexpected %q", rest)
+			}
+			v, err := parsePartialVersion(match[2])
+			if err != nil {
+				return false, err
+			}
+			if !termHolds(match[1], v, target) {
+				holds = false
+			}
+			rest = strings.TrimLeft(rest[len(match[0]):], " ,")
+		}
+		if holds {
+			satisfied = true
+		}
+	}
+	return satisfied, nil
+}
+
+// termHolds reports whether target satisfies the comparison op v.
+func termHolds(op string, v partialVersion, target [3]int) bool {
+	if v.given == 0 {
+		return op != "!=" && op != "<" && op != ">"
+	}
+	lower, upper := v.parts, v.bump(v.given)
+	switch op {
+	case "", "=":
+		return compareVersions(target, lower) >= 0 && compareVersions(target, upper) < 0
+	case "!=":
+		return compareVersions(target, lower) < 0 || compareVersions(target, upper) >= 0
+	case ">":
+		return compareVersions(target, upper) >= 0
+	case ">=", "=>":
+		return compareVersions(target, lower) >= 0
+	case "<":
+		return compareVersions(target, lower) < 0
+	case "<=", "=<":
+		return compareVersions(target, upper) < 0
+	case "~", "~>":
+		return compareVersions(target, lower) >= 0 && compareVersions(target, v.bump(min(v.given, 2))) < 0
+	case "^":
+		given := 1
+		if v.parts[0] == 0 && v.given > 1 {
+			given = 2
+			if v.parts[1] == 0 && v.given > 2 {
+				given = 3
+			}
+		}
+		return compareVersions(target, lower) >= 0 && compareVersions(target, v.bump(given)) < 0
+	}
+	return false
+}
+
+// chartAPIVersionRule flags Chart.yaml fields that apiVersion v1 charts do
+// not support, which helm lint rejects: dependencies, declared in
+// requirements.yaml by v1 charts, and type, with which library charts were
+// introduced.
+type chartAPIVersionRule struct{}
+
+func (chartAPIVersionRule) ID() string { return "chart-api-version" }
+
+func (chartAPIVersionRule) Enabled(config *models.Config) bool {
+	return config.ChartMetadata.Enabled
+}
+
+func (r chartAPIVersionRule) Check(ctx *Context) []models.Finding {
+	chart, err := loadChartFile(ctx.ChartPath)
+	if err != nil || chart.str("apiVersion") != "v1" {
+		return nil
+	}
+	var findings []models.Finding
+	if _, ok := chart.fields["dependencies"]; ok {
+		findings = append(findings, chart.finding(r.ID(), models.SeverityError, "dependencies",
+			"dependencies in Chart.yaml need apiVersion v2; v1 charts declare them in requirements.yaml"))
+	}
+	if chartType := chart.str("type"); chartType != "" {
+		findings = append(findings, chart.finding(r.ID(), models.SeverityError, "type",
+			"type %s needs apiVersion v2; v1 charts cannot be library charts", chartType))
+	}
+	return findings
+}
+
+// chartDependenciesRegex matches references to .Chart.Dependencies.
+var chartDependenciesRegex = regexp.MustCompile(`\.Chart\.Dependencies\b`)
+
+// chartDependenciesTemplateRule flags templates reading .Chart.Dependencies,
+// which lists the dependencies declared in Chart.yaml whether or not their
+// condition or tags enable them. Templates should test the values that
+// enable a dependency instead.
+type chartDependenciesTemplateRule struct{}
+
+func (chartDependenciesTemplateRule) ID() string { return "chart-dependencies-template" }
+
+func (chartDependenciesTemplateRule) Enabled(config *models.Config) bool {
+	return config.ChartMetadata.Enabled
+}
+
+func (r chartDependenciesTemplateRule) Check(ctx *Context) []models.Finding {
+	var findings []models.Finding
+	chartName := filepath.Base(ctx.ChartPath)
+	filepath.Walk(filepath.Join(ctx.ChartPath, "templates"), func(path string, info os.FileInfo, err error) error { //nolint:errcheck
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(ctx.ChartPath, path)
+		for i, line := range strings.Split(string(data), "\n") {
+			if chartDependenciesRegex.MatchString(line) {
+				findings = append(findings, models.Finding{
+					RuleID:   r.ID(),
+					Severity: models.SeverityWarning,
+					Message:  ".Chart.Dependencies lists every dependency declared in Chart.yaml, including disabled ones; test the value enabling the dependency instead",
+					File:     chartName + "/" + filepath.ToSlash(rel),
+					Line:     i + 1,
+				})
+			}
+		}
+		return nil
+	})
+	return findings
+}
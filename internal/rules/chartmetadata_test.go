@@ -0,0 +1,181 @@
+package rules
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func writeChartFile(t *testing.T, content string) string {
+	t.Helper()
+	chartDir := filepath.Join(t.TempDir(), "web")
+	if err := os.MkdirAll(chartDir, 0755); err != nil {
+		t.Fatalf("Failed to create chart: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write Chart.yaml: %v", err)
+	}
+	return chartDir
+}
+
+func checkChartMetadata(ctx *Context) []string {
+	var got []string
+	for _, rule := range []Rule{chartMetadataRule{}, chartVersionRule{}, chartKubeVersionRule{}, chartIconRule{}} {
+		if !rule.Enabled(&ctx.Config) {
+			continue
+		}
+		for _, finding := range rule.Check(ctx) {
+			got = append(got, finding.String())
+		}
+	}
+	return got
+}
+
+func TestChartMetadataRules(t *testing.T) {
+	chartDir := writeChartFile(t, `apiVersion: v1
+name: web
+version: 1.2
+kubeVersion: ">= 1.20.0-0 < 1.28.0-0"
+deprecated: true
+icon: https://example.com/missing.png
+`)
+	original := fetchIcon
+	defer func() { fetchIcon = original }()
+	fetchIcon = func(url string) error { return errors.New("404 Not Found") }
+
+	config := models.Config{ChartMetadata: models.ChartMetadataConfig{Enabled: true, CheckIcon: true}}
+	config.Validation.KubeVersion = "1.29"
+	got := checkChartMetadata(&Context{ChartPath: chartDir, Config: config})
+	expected := []string{
+		"[warning] chart-metadata: web/Chart.yaml:1: apiVersion v1 is the Helm 2 chart format; use v2 and move requirements.yaml into Chart.yaml",
+		"[warning] chart-metadata: web/Chart.yaml: description is missing",
+		"[warning] chart-metadata: web/Chart.yaml: no maintainers are listed",
+		"[warning] chart-metadata: web/Chart.yaml:5: chart is marked deprecated",
+		`[error] chart-version: web/Chart.yaml:3: version "1.2" is not a semantic version (MAJOR.MINOR.PATCH)`,
+		`[error] chart-kube-version: web/Chart.yaml:4: kubeVersion ">= 1.20.0-0 < 1.28.0-0" does not admit Kubernetes 1.29, the version manifests are validated for`,
+		"[warning] chart-icon: web/Chart.yaml:6: icon https://example.com/missing.png cannot be fetched: 404 Not Found",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected findings:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestChartMetadataRules_Valid(t *testing.T) {
+	chartDir := writeChartFile(t, `apiVersion: v2
+name: web
+description: The web frontend
+version: 1.2.0
+kubeVersion: ">=1.25.0-0"
+maintainers:
+  - name: platform-team
+`)
+	config := models.Config{ChartMetadata: models.ChartMetadataConfig{Enabled: true, RequireKubeVersion: true}}
+	config.Validation.KubeVersion = "1.29"
+	if got := checkChartMetadata(&Context{ChartPath: chartDir, Config: config}); len(got) != 0 {
+		t.Errorf("Expected no findings, got %v", got)
+	}
+
+	chartDir = writeChartFile(t, `apiVersion: v2
+name: web
+description: The web frontend
+version: 1.2.0
+kubeVersion: "> 1.x.2"
+maintainers:
+  - name: platform-team
+`)
+	got := checkChartMetadata(&Context{ChartPath: chartDir, Config: config})
+	expected := `[error] chart-kube-version: web/Chart.yaml:5: invalid kubeVersion constraint "> 1.x.2": invalid version "1.x.2"`
+	if len(got) != 1 || got[0] != expected {
+		t.Errorf("Expected %s, got %v", expected, got)
+	}
+}
+
+func TestVersionSatisfies(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		expected   bool
+	}{
+		{">=1.20.0-0", "1.29", true},
+		{">=1.20.0-0", "1.19.9", false},
+		{">= 1.20, < 1.28", "1.28.0", false},
+		{">= 1.20, < 1.28", "1.27.3-eks-2", true},
+		{"<=1.27", "1.27.9", true},
+		{">1.27", "1.27.9", false},
+		{"~1.27.2", "1.27.5", true},
+		{"~1.27.2", "1.28.0", false},
+		{"^1.20", "1.30.1", true},
+		{"^0.2.3", "0.3.0", false},
+		{"1.x", "1.30", true},
+		{"!=1.25", "1.25.4", false},
+		{"1.20 - 1.24", "1.24.7", true},
+		{"<1.20 || >=1.25", "1.22", false},
+		{"<1.20 || >=1.25", "1.26", true},
+		{"*", "1.26", true},
+	}
+	for _, test := range tests {
+		satisfied, err := VersionSatisfies(test.constraint, test.version)
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", test.constraint, err)
+			continue
+		}
+		if satisfied != test.expected {
+			t.Errorf("Expected %q against %s to be %v, got %v", test.constraint, test.version, test.expected, satisfied)
+		}
+	}
+
+	for _, constraint := range []string{"latest", ">= 1.20 ||", "1.2.3.4"} {
+		if _, err := VersionSatisfies(constraint, "1.29"); err == nil {
+			t.Errorf("Expected an error for %q", constraint)
+		}
+	}
+}
+
+func TestChartAPIVersionRules(t *testing.T) {
+	chartDir := writeChartFile(t, `apiVersion: v1
+name: web
+version: 1.2.0
+type: library
+kubeVersion: ">= 1.25.0-0 && < 1.30.0-0"
+dependencies:
+  - name: redis
+    version: 17.x
+    repository: https://charts.example.com
+`)
+	templatesDir := filepath.Join(chartDir, "templates")
+	if err := os.MkdirAll(templatesDir, 0755); err != nil {
+		t.Fatalf("Failed to create templates: %v", err)
+	}
+	template := "{{- range .Chart.Dependencies }}\n# {{ .Name }}\n{{- end }}\n{{ .Chart.DependenciesX }}\n"
+	if err := os.WriteFile(filepath.Join(templatesDir, "notes.txt"), []byte(template), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	config := models.Config{ChartMetadata: models.ChartMetadataConfig{Enabled: true}}
+	var got []string
+	for _, rule := range []Rule{chartKubeVersionRule{}, chartAPIVersionRule{}, chartDependenciesTemplateRule{}} {
+		for _, finding := range rule.Check(&Context{ChartPath: chartDir, Config: config}) {
+			got = append(got, finding.String())
+		}
+	}
+	expected := []string{
+		`[error] chart-kube-version: web/Chart.yaml:5: kubeVersion ">= 1.25.0-0 && < 1.30.0-0" uses the operator &&, which Helm does not support; use a space or comma between comparisons`,
+		"[error] chart-api-version: web/Chart.yaml:6: dependencies in Chart.yaml need apiVersion v2; v1 charts declare them in requirements.yaml",
+		"[error] chart-api-version: web/Chart.yaml:4: type library needs apiVersion v2; v1 charts cannot be library charts",
+		"[warning] chart-dependencies-template: web/templates/notes.txt:1: .Chart.Dependencies lists every dependency declared in Chart.yaml, including disabled ones; test the value enabling the dependency instead",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected findings:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	chartDir = writeChartFile(t, "apiVersion: v2\nname: web\nversion: 1.2.0\ntype: library\nkubeVersion: \">=1.25.0-0 || =1.24.3\"\ndependencies: []\n")
+	for _, rule := range []Rule{chartKubeVersionRule{}, chartAPIVersionRule{}} {
+		if findings := rule.Check(&Context{ChartPath: chartDir, Config: config}); len(findings) != 0 {
+			t.Errorf("Expected no %s findings for a v2 chart, got %v", rule.ID(), findings)
+		}
+	}
+}
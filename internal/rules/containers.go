@@ -0,0 +1,266 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+	"github.com/Jaydee94/chartscan/internal/registry"
+)
+
+func init() {
+	Register(initContainerParityRule{})
+	Register(duplicateMeshProxyRule{})
+	Register(nativeSidecarRule{})
+}
+
+// containerRequirement is a setting that, when every main container of a pod
+// has it, init and sidecar containers must have as well.
+type containerRequirement struct {
+	description string
+	satisfied   func(container, podContext map[string]interface{}) bool
+}
+
+var containerRequirements = []containerRequirement{
+	{"resources.requests.cpu", func(c, _ map[string]interface{}) bool {
+		return NestedValue(c, "resources", "requests", "cpu") != nil || NestedValue(c, "resources", "limits", "cpu") != nil
+	}},
+	{"resources.requests.memory", func(c, _ map[string]interface{}) bool {
+		return NestedValue(c, "resources", "requests", "memory") != nil || NestedValue(c, "resources", "limits", "memory") != nil
+	}},
+	{"resources.limits.cpu", func(c, _ map[string]interface{}) bool {
+		return NestedValue(c, "resources", "limits", "cpu") != nil
+	}},
+	{"resources.limits.memory", func(c, _ map[string]interface{}) bool {
+		return NestedValue(c, "resources", "limits", "memory") != nil
+	}},
+	{"privileged: false", func(c, _ map[string]interface{}) bool {
+		privileged, _ := NestedValue(c, "securityContext", "privileged").(bool)
+		return !privileged
+	}},
+	{"runAsNonRoot: true", func(c, pod map[string]interface{}) bool {
+		if nonRoot, ok := NestedValue(c, "securityContext", "runAsNonRoot").(bool); ok {
+			return nonRoot
+		}
+		nonRoot, _ := pod["runAsNonRoot"].(bool)
+		return nonRoot
+	}},
+	{"allowPrivilegeEscalation: false", func(c, _ map[string]interface{}) bool {
+		escalation, ok := NestedValue(c, "securityContext", "allowPrivilegeEscalation").(bool)
+		return ok && !escalation
+	}},
+	{"readOnlyRootFilesystem: true", func(c, _ map[string]interface{}) bool {
+		readOnly, _ := NestedValue(c, "securityContext", "readOnlyRootFilesystem").(bool)
+		return readOnly
+	}},
+	{"capabilities.drop: [ALL]", func(c, _ map[string]interface{}) bool {
+		for _, capability := range NestedSlice(c, "securityContext", "capabilities", "drop") {
+			if capability == "ALL" {
+				return true
+			}
+		}
+		return false
+	}},
+}
+
+// initContainerParityRule holds init containers, including native sidecars,
+// to the security and resource settings all main containers of the pod share,
+// so that hardening the application does not leave its helpers behind.
+type initContainerParityRule struct{}
+
+func (initContainerParityRule) ID() string { return "init-container-parity" }
+
+func (initContainerParityRule) Enabled(config *models.Config) bool {
+	return config.Containers.Enabled
+}
+
+func (r initContainerParityRule) Check(ctx *Context) []models.Finding {
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		podSpec := PodSpec(m)
+		if podSpec == nil {
+			continue
+		}
+		podContext := NestedMap(podSpec, "securityContext")
+		main := Containers(podSpec, false)
+		if len(main) == 0 {
+			continue
+		}
+
+		var shared []containerRequirement
+		for _, requirement := range containerRequirements {
+			satisfied := true
+			for _, container := range main {
+				satisfied = satisfied && requirement.satisfied(container, podContext)
+			}
+			if satisfied {
+				shared = append(shared, requirement)
+			}
+		}
+
+		for _, i := range NestedSlice(podSpec, "initContainers") {
+			container, _ := i.(map[string]interface{})
+			var missing []string
+			for _, requirement := range shared {
+				if !requirement.satisfied(container, podContext) {
+					missing = append(missing, requirement.description)
+				}
+			}
+			if len(missing) > 0 {
+				findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+					"%s %s lacks settings all main containers have: %s",
+					initContainerKind(container), NestedString(container, "name"), strings.Join(missing, ", ")))
+			}
+		}
+	}
+	return findings
+}
+
+// initContainerKind describes an init container as a native sidecar or a
+// regular init container.
+func initContainerKind(container map[string]interface{}) string {
+	if NestedString(container, "restartPolicy") == "Always" {
+		return "sidecar container"
+	}
+	return "init container"
+}
+
+// meshProxy describes a service mesh that injects a proxy container into pods.
+type meshProxy struct {
+	mesh      string
+	container string
+	// repositories are image repository suffixes of the proxy.
+	repositories []string
+	// injectKey is the pod annotation or label controlling injection, and
+	// disabled and enabled its values that turn injection off and on.
+	injectKey string
+	disabled  string
+	enabled   []string
+}
+
+var meshProxies = []meshProxy{
+	{"Istio", "istio-proxy", []string{"/proxyv2"}, "sidecar.istio.io/inject", "false", []string{"true"}},
+	{"Linkerd", "linkerd-proxy", []string{"/linkerd/proxy", "/linkerd2-proxy"}, "linkerd.io/inject", "disabled", []string{"enabled", "ingress"}},
+}
+
+// duplicateMeshProxyRule flags proxy containers that charts declare
+// themselves although the mesh injects its own, leaving pods with two proxies
+// fighting over the same ports.
+type duplicateMeshProxyRule struct{}
+
+func (duplicateMeshProxyRule) ID() string { return "duplicate-mesh-proxy" }
+
+func (duplicateMeshProxyRule) Enabled(config *models.Config) bool {
+	return config.Containers.Enabled
+}
+
+func (r duplicateMeshProxyRule) Check(ctx *Context) []models.Finding {
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		podSpec := PodSpec(m)
+		if podSpec == nil {
+			continue
+		}
+		metadata := PodTemplateMetadata(m)
+		for _, container := range Containers(podSpec, true) {
+			proxy, ok := meshProxyOf(container)
+			if !ok {
+				continue
+			}
+			injection := fmt.Sprint(NestedMap(metadata, "annotations")[proxy.injectKey])
+			if label, ok := NestedMap(metadata, "labels")[proxy.injectKey]; ok {
+				injection = fmt.Sprint(label)
+			}
+			if injection == proxy.disabled {
+				continue
+			}
+
+			severity, reason := models.SeverityWarning, "unless injection is disabled for the namespace"
+			for _, value := range proxy.enabled {
+				if injection == value {
+					severity, reason = models.SeverityError, fmt.Sprintf("since the pod sets %s: %q", proxy.injectKey, injection)
+				}
+			}
+			findings = append(findings, newFinding(r.ID(), severity, m,
+				"container %s duplicates the %s proxy the mesh injects %s; remove it or set %s: %q on the pod template",
+				NestedString(container, "name"), proxy.mesh, reason, proxy.injectKey, proxy.disabled))
+		}
+	}
+	return findings
+}
+
+// meshProxyOf identifies a container as a service mesh proxy by its name or
+// image.
+func meshProxyOf(container map[string]interface{}) (meshProxy, bool) {
+	var repository string
+	if ref, err := registry.ParseReference(NestedString(container, "image")); err == nil {
+		repository = ref.Repository
+	}
+	for _, proxy := range meshProxies {
+		if NestedString(container, "name") == proxy.container {
+			return proxy, true
+		}
+		for _, suffix := range proxy.repositories {
+			if strings.HasSuffix("/"+repository, suffix) {
+				return proxy, true
+			}
+		}
+	}
+	return meshProxy{}, false
+}
+
+// nativeSidecarRule checks init containers' restartPolicy against the target
+// Kubernetes version. Native sidecars (restartPolicy: Always) are alpha behind
+// the SidecarContainers feature gate in 1.28 and enabled by default from 1.29;
+// older API servers drop the field and run the sidecar as a blocking init
+// container.
+type nativeSidecarRule struct{}
+
+func (nativeSidecarRule) ID() string { return "native-sidecar" }
+
+func (nativeSidecarRule) Enabled(config *models.Config) bool {
+	return config.Containers.Enabled
+}
+
+func (r nativeSidecarRule) Check(ctx *Context) []models.Finding {
+	kubeVersion := ctx.Config.Validation.KubeVersion
+	minor, known := kubeMinorVersion(kubeVersion)
+
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		podSpec := PodSpec(m)
+		if podSpec == nil {
+			continue
+		}
+		for _, i := range NestedSlice(podSpec, "initContainers") {
+			container, _ := i.(map[string]interface{})
+			name := NestedString(container, "name")
+			switch policy := NestedString(container, "restartPolicy"); {
+			case policy == "":
+			case policy != "Always":
+				findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+					"init container %s sets restartPolicy %s; only Always is allowed", name, policy))
+			case !known:
+			case minor < 28:
+				findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+					"init container %s is a native sidecar (restartPolicy: Always), which Kubernetes %s does not support; it requires 1.29", name, kubeVersion))
+			case minor == 28:
+				findings = append(findings, newFinding(r.ID(), models.SeverityWarning, m,
+					"init container %s is a native sidecar (restartPolicy: Always), which Kubernetes 1.28 only supports with the SidecarContainers feature gate", name))
+			}
+		}
+	}
+	return findings
+}
+
+// kubeMinorVersion returns the minor version of a Kubernetes 1.x version such
+// as 1.29 or v1.29.3. known is false for master and unparsable versions.
+func kubeMinorVersion(version string) (minor int, known bool) {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 || parts[0] != "1" {
+		return 0, false
+	}
+	minor, err := strconv.Atoi(parts[1])
+	return minor, err == nil
+}
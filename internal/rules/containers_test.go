@@ -0,0 +1,164 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestInitContainerParityRule(t *testing.T) {
+	manifests, err := ParseManifests(`---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      securityContext:
+        runAsNonRoot: true
+      initContainers:
+        - name: migrate
+          resources:
+            limits: {cpu: 500m, memory: 256Mi}
+          securityContext:
+            allowPrivilegeEscalation: false
+        - name: log-shipper
+          restartPolicy: Always
+          securityContext:
+            runAsNonRoot: false
+            allowPrivilegeEscalation: false
+        - name: wait
+          resources:
+            requests: {cpu: 10m, memory: 16Mi}
+          securityContext:
+            allowPrivilegeEscalation: false
+      containers:
+        - name: web
+          resources:
+            requests: {cpu: 100m, memory: 128Mi}
+          securityContext:
+            allowPrivilegeEscalation: false
+        - name: worker
+          resources:
+            requests: {cpu: 100m, memory: 128Mi}
+            limits: {memory: 128Mi}
+          securityContext:
+            allowPrivilegeEscalation: false
+            readOnlyRootFilesystem: true
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	findings := initContainerParityRule{}.Check(&Context{Manifests: manifests})
+	if len(findings) != 1 {
+		t.Fatalf("Expected 1 finding, got %d: %v", len(findings), findings)
+	}
+	expected := "sidecar container log-shipper lacks settings all main containers have: resources.requests.cpu, resources.requests.memory, runAsNonRoot: true"
+	if findings[0].Message != expected {
+		t.Errorf("Expected %q, got %q", expected, findings[0].Message)
+	}
+}
+
+func TestDuplicateMeshProxyRule(t *testing.T) {
+	manifests, err := ParseManifests(`---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: injected
+spec:
+  template:
+    metadata:
+      labels:
+        sidecar.istio.io/inject: "true"
+    spec:
+      containers:
+        - name: web
+          image: nginx
+        - name: proxy
+          image: docker.io/istio/proxyv2:1.22.0
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: namespace-injected
+spec:
+  template:
+    spec:
+      containers:
+        - name: linkerd-proxy
+          image: cr.l5d.io/linkerd/proxy:stable-2.14.0
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: opted-out
+spec:
+  template:
+    metadata:
+      annotations:
+        sidecar.istio.io/inject: "false"
+    spec:
+      containers:
+        - name: istio-proxy
+          image: istio/proxyv2:1.22.0
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	findings := duplicateMeshProxyRule{}.Check(&Context{Manifests: manifests})
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %d: %v", len(findings), findings)
+	}
+	if findings[0].Resource != "Deployment/injected" || findings[0].Severity != models.SeverityError || !strings.Contains(findings[0].Message, `since the pod sets sidecar.istio.io/inject: "true"`) {
+		t.Errorf("Expected an error for the explicitly injected pod, got %v", findings[0])
+	}
+	if findings[1].Resource != "Deployment/namespace-injected" || findings[1].Severity != models.SeverityWarning || !strings.Contains(findings[1].Message, "Linkerd proxy") {
+		t.Errorf("Expected a warning for the Linkerd proxy, got %v", findings[1])
+	}
+}
+
+func TestNativeSidecarRule(t *testing.T) {
+	manifests, err := ParseManifests(`---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+spec:
+  initContainers:
+    - name: proxy
+      restartPolicy: Always
+    - name: setup
+      restartPolicy: OnFailure
+  containers:
+    - name: web
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, test := range []struct {
+		kubeVersion string
+		expected    []string
+	}{
+		{"", []string{"restartPolicy OnFailure"}},
+		{"1.27", []string{"Kubernetes 1.27 does not support", "restartPolicy OnFailure"}},
+		{"v1.28.4", []string{"SidecarContainers feature gate", "restartPolicy OnFailure"}},
+		{"1.29", []string{"restartPolicy OnFailure"}},
+	} {
+		ctx := &Context{Manifests: manifests, Config: models.Config{Validation: models.ValidationConfig{KubeVersion: test.kubeVersion}}}
+		findings := nativeSidecarRule{}.Check(ctx)
+		if len(findings) != len(test.expected) {
+			t.Errorf("Expected %d findings for %q, got %v", len(test.expected), test.kubeVersion, findings)
+			continue
+		}
+		for i, want := range test.expected {
+			if !strings.Contains(findings[i].Message, want) {
+				t.Errorf("Expected finding %d for %q to contain %q, got %s", i, test.kubeVersion, want, findings[i].Message)
+			}
+		}
+	}
+}
@@ -0,0 +1,453 @@
+package rules
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func init() {
+	Register(hardcodedCredentialRule{})
+	Register(hardcodedSecretDataRule{})
+}
+
+// defaultMinEntropy is the Shannon entropy, in bits per character, from which
+// a long token-like value counts as random when secretScanning.minEntropy is
+// not set. Random base64 strings of 20 or more characters reach it; words,
+// hostnames and hex checksums stay below.
+const defaultMinEntropy = 4.5
+
+// credentialPattern is a credential format recognized by its shape alone.
+type credentialPattern struct {
+	name    string
+	pattern *regexp.Regexp
+	// secretGroup, if set, is the submatch holding the secret part, which
+	// must not be a placeholder.
+	secretGroup int
+}
+
+var credentialPatterns = []credentialPattern{
+	{name: "an AWS access key ID", pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{name: "a private key", pattern: regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )*PRIVATE KEY(?: BLOCK)?-----`)},
+	{name: "a GitHub token", pattern: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`)},
+	{name: "a GitLab token", pattern: regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}`)},
+	{name: "a Slack token", pattern: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{name: "a Google API key", pattern: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{name: "a Stripe live key", pattern: regexp.MustCompile(`\b[rs]k_live_[0-9A-Za-z]{24,}`)},
+	{name: "a JSON web token", pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+	{name: "a password in a URL", pattern: regexp.MustCompile(`\b[A-Za-z][A-Za-z0-9+.-]*://[^/\s:@]+:([^/\s:@]+)@`), secretGroup: 1},
+}
+
+// credentialWords are the last words of keys that hold a credential, and
+// credentialWordPairs the last two words.
+var (
+	credentialWords = map[string]bool{
+		"password": true, "passwd": true, "pwd": true, "passphrase": true, "secret": true,
+		"token": true, "apikey": true, "credential": true, "credentials": true,
+	}
+	credentialWordPairs = map[string]bool{
+		"api key": true, "access key": true, "secret key": true, "private key": true,
+		"signing key": true, "encryption key": true, "master key": true,
+	}
+	// referenceWords mark keys that name, locate or describe a credential
+	// rather than hold it, e.g. existingSecret or passwordSecretRef.
+	referenceWords = map[string]bool{
+		"existing": true, "name": true, "names": true, "ref": true, "file": true, "path": true,
+		"enabled": true, "length": true, "mount": true, "field": true, "annotation": true,
+		"annotations": true, "policy": true, "type": true, "ttl": true, "expiry": true,
+	}
+)
+
+// placeholderWords mark values that are obviously not real credentials.
+var placeholderWords = []string{"placeholder", "replace", "example", "dummy", "redacted", "your-", "your_", "todo", "fixme"}
+
+var tokenLike = regexp.MustCompile(`^[A-Za-z0-9+/=_-]+$`)
+
+// credentialDetector finds likely credentials in values.
+type credentialDetector struct {
+	minEntropy float64
+	allow      []*regexp.Regexp
+}
+
+// newCredentialDetector returns the detector configured by config, and
+// errors for invalid allow patterns.
+func newCredentialDetector(ruleID string, config models.SecretScanningConfig) (*credentialDetector, []models.Finding) {
+	d := &credentialDetector{minEntropy: config.MinEntropy}
+	if d.minEntropy <= 0 {
+		d.minEntropy = defaultMinEntropy
+	}
+	var findings []models.Finding
+	for _, pattern := range config.Allow {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			findings = append(findings, models.Finding{
+				RuleID:   ruleID,
+				Severity: models.SeverityError,
+				Message:  fmt.Sprintf("secretScanning: invalid allow pattern %q: %v", pattern, err),
+			})
+			continue
+		}
+		d.allow = append(d.allow, re)
+	}
+	return d, findings
+}
+
+// detect reports what kind of credential value appears to be, given the key
+// holding it, and the severity to report it with.
+func (d *credentialDetector) detect(key, value string) (what, severity string, found bool) {
+	if isPlaceholder(value) || d.allowed(key, value) {
+		return "", "", false
+	}
+	if name, ok := matchCredentialPattern(value); ok {
+		return name, models.SeverityError, true
+	}
+	if decoded, ok := decodeBase64(value); ok {
+		if name, ok := matchCredentialPattern(decoded); ok {
+			return "a base64-encoded " + strings.TrimPrefix(strings.TrimPrefix(name, "an "), "a "), models.SeverityError, true
+		}
+	}
+	if isCredentialKey(key) {
+		return "a hard-coded value of a credential key", models.SeverityWarning, true
+	}
+	if entropy := shannonEntropy(value); len(value) >= 20 && tokenLike.MatchString(value) && hasLetterAndDigit(value) && entropy >= d.minEntropy {
+		return fmt.Sprintf("a random-looking value (%.1f bits of entropy per character)", entropy), models.SeverityWarning, true
+	}
+	return "", "", false
+}
+
+func (d *credentialDetector) allowed(key, value string) bool {
+	for _, re := range d.allow {
+		if re.MatchString(key) || re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchCredentialPattern returns the name of the first credential format
+// found in value.
+func matchCredentialPattern(value string) (string, bool) {
+	for _, p := range credentialPatterns {
+		match := p.pattern.FindStringSubmatch(value)
+		if match == nil {
+			continue
+		}
+		if p.secretGroup > 0 && isPlaceholder(match[p.secretGroup]) {
+			continue
+		}
+		return p.name, true
+	}
+	return "", false
+}
+
+// decodeBase64 decodes a base64 value of at least 16 characters that decodes
+// to printable text.
+func decodeBase64(value string) (string, bool) {
+	if len(value) < 16 || len(value)%4 != 0 {
+		return "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || !utf8.Valid(decoded) {
+		return "", false
+	}
+	for _, r := range string(decoded) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return "", false
+		}
+	}
+	return string(decoded), true
+}
+
+// isCredentialKey reports whether the last segment of a dotted key names a
+// credential, like rootPassword, api_key or DB_PASSWORD.
+func isCredentialKey(key string) bool {
+	words := keyWords(key[strings.LastIndex(key, ".")+1:])
+	if len(words) == 0 {
+		return false
+	}
+	for _, word := range words {
+		if referenceWords[word] {
+			return false
+		}
+	}
+	last := words[len(words)-1]
+	if credentialWords[last] {
+		return true
+	}
+	return len(words) > 1 && credentialWordPairs[words[len(words)-2]+" "+last]
+}
+
+// keyWords splits a camelCase, snake_case or kebab-case key into lower-case
+// words.
+func keyWords(key string) []string {
+	var words []string
+	var current []rune
+	runes := []rune(key)
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-' || r == '[' || r == ']':
+			if len(current) > 0 {
+				words = append(words, strings.ToLower(string(current)))
+				current = nil
+			}
+			continue
+		case unicode.IsUpper(r) && len(current) > 0 && (unicode.IsLower(current[len(current)-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])):
+			words = append(words, strings.ToLower(string(current)))
+			current = nil
+		}
+		current = append(current, r)
+	}
+	if len(current) > 0 {
+		words = append(words, strings.ToLower(string(current)))
+	}
+	return words
+}
+
+// isPlaceholder reports whether value is empty, a template or variable
+// reference, or an obvious placeholder such as <password> or xxxx.
+func isPlaceholder(value string) bool {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.Contains(value, "{{") || strings.Contains(value, "${") || strings.Contains(value, "$(") {
+		return true
+	}
+	if strings.HasPrefix(value, "<") && strings.HasSuffix(value, ">") {
+		return true
+	}
+	if strings.Count(value, value[:1]) == len(value) {
+		return true
+	}
+	lower := strings.ToLower(value)
+	for _, word := range placeholderWords {
+		if strings.Contains(lower, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// shannonEntropy returns the entropy of value in bits per character.
+func shannonEntropy(value string) float64 {
+	if value == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	for _, r := range value {
+		counts[r]++
+	}
+	length := float64(utf8.RuneCountInString(value))
+	var entropy float64
+	for _, count := range counts {
+		p := float64(count) / length
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+func hasLetterAndDigit(value string) bool {
+	return strings.ContainsFunc(value, unicode.IsLetter) && strings.ContainsFunc(value, unicode.IsDigit)
+}
+
+// hardcodedCredentialRule flags likely credentials committed in the chart's
+// values.yaml and the configured values files, which should reference
+// existing Secrets instead.
+type hardcodedCredentialRule struct{}
+
+func (hardcodedCredentialRule) ID() string { return "hardcoded-credential" }
+
+func (hardcodedCredentialRule) Enabled(config *models.Config) bool {
+	return config.SecretScanning.Enabled
+}
+
+func (r hardcodedCredentialRule) Check(ctx *Context) []models.Finding {
+	detector, findings := newCredentialDetector(r.ID(), ctx.Config.SecretScanning)
+
+	var files []string
+	seen := make(map[string]bool)
+	addFiles := func(paths ...string) {
+		for _, path := range paths {
+			if !seen[path] {
+				seen[path] = true
+				files = append(files, path)
+			}
+		}
+	}
+	if _, err := os.Stat(filepath.Join(ctx.ChartPath, "values.yaml")); err == nil {
+		addFiles(filepath.Join(ctx.ChartPath, "values.yaml"))
+	}
+	names := make([]string, 0, len(ctx.Config.Environments))
+	for name := range ctx.Config.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		addFiles(ctx.Config.Environments[name].ValuesFiles...)
+	}
+	addFiles(ctx.Config.ValuesFiles...)
+
+	for _, file := range files {
+		leaves, err := loadLeafValues(file)
+		if err != nil {
+			findings = append(findings, models.Finding{RuleID: r.ID(), Severity: models.SeverityError, Message: err.Error(), File: file})
+			continue
+		}
+		for _, path := range sortedLeafPaths(leaves) {
+			leaf := leaves[path]
+			walkStrings(path, leaf.value, func(key, value string) {
+				if what, severity, found := detector.detect(key, value); found {
+					findings = append(findings, models.Finding{
+						RuleID:   r.ID(),
+						Severity: severity,
+						Message:  fmt.Sprintf("%s holds %s; reference an existing Secret instead of committing it", key, what),
+						File:     file,
+						Line:     leaf.line,
+					})
+				}
+			})
+		}
+	}
+	return findings
+}
+
+// walkStrings calls fn with the dotted key of every string in value. Items of
+// lists of name/value pairs, like container env, are keyed by their name.
+func walkStrings(key string, value interface{}, fn func(key, value string)) {
+	switch v := value.(type) {
+	case string:
+		fn(key, v)
+	case []interface{}:
+		for i, item := range v {
+			walkStrings(fmt.Sprintf("%s[%d]", key, i), item, fn)
+		}
+	case map[string]interface{}:
+		if name, ok := v["name"].(string); ok {
+			if s, ok := v["value"].(string); ok {
+				fn(key+"."+name, s)
+				return
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			walkStrings(key+"."+k, v[k], fn)
+		}
+	}
+}
+
+// hardcodedSecretDataRule flags Secret data that templates write literally,
+// which ends up in the chart's history and every release. Values coming from
+// values files are left to hardcoded-credential, and generated ones such as
+// randAlphaNum never appear in the templates.
+type hardcodedSecretDataRule struct{}
+
+func (hardcodedSecretDataRule) ID() string { return "hardcoded-secret-data" }
+
+func (hardcodedSecretDataRule) Enabled(config *models.Config) bool {
+	return config.SecretScanning.Enabled
+}
+
+func (r hardcodedSecretDataRule) Check(ctx *Context) []models.Finding {
+	detector, findings := newCredentialDetector(r.ID(), ctx.Config.SecretScanning)
+	templates := newTemplateSearch(ctx.ChartPath)
+	for _, m := range ctx.Manifests {
+		if m.Kind != "Secret" {
+			continue
+		}
+		for _, field := range []string{"data", "stringData"} {
+			data := NestedMap(m.Object, field)
+			keys := make([]string, 0, len(data))
+			for key := range data {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				raw, ok := data[key].(string)
+				if !ok {
+					continue
+				}
+				value, literals := raw, []string{raw}
+				if field == "data" {
+					decoded, err := base64.StdEncoding.DecodeString(raw)
+					if err != nil {
+						continue
+					}
+					value = string(decoded)
+					literals = append(literals, value)
+				}
+				what, severity, found := detector.detect(field+"."+key, value)
+				if !found {
+					continue
+				}
+				file, line, ok := templates.find(m.Source, literals)
+				if !ok {
+					continue
+				}
+				finding := newFinding(r.ID(), severity, m, "%s.%s holds %s written literally in the template; take it from values or an existing Secret", field, key, what)
+				finding.File, finding.Line = file, line
+				findings = append(findings, finding)
+			}
+		}
+	}
+	return findings
+}
+
+// templateSearch finds literal values in the templates of a chart.
+type templateSearch struct {
+	chartPath string
+	contents  map[string]string
+}
+
+func newTemplateSearch(chartPath string) *templateSearch {
+	return &templateSearch{chartPath: chartPath, contents: make(map[string]string)}
+}
+
+// find returns the template, named like the source, and line where one of
+// literals appears, looking at the source template first and then every
+// file of the chart's templates directory.
+func (s *templateSearch) find(source string, literals []string) (string, int, bool) {
+	chartName, sourcePath, found := strings.Cut(filepath.ToSlash(source), "/")
+	if !found {
+		chartName, sourcePath = filepath.Base(s.chartPath), ""
+	}
+	var candidates []string
+	if sourcePath != "" {
+		candidates = append(candidates, filepath.FromSlash(sourcePath))
+	}
+	filepath.Walk(filepath.Join(s.chartPath, "templates"), func(path string, info os.FileInfo, err error) error { //nolint:errcheck
+		if err == nil && !info.IsDir() {
+			if rel, err := filepath.Rel(s.chartPath, path); err == nil {
+				candidates = append(candidates, rel)
+			}
+		}
+		return nil
+	})
+
+	for _, rel := range candidates {
+		content, ok := s.contents[rel]
+		if !ok {
+			data, _ := os.ReadFile(filepath.Join(s.chartPath, rel))
+			content = string(data)
+			s.contents[rel] = content
+		}
+		for _, literal := range literals {
+			if len(literal) < 4 {
+				continue
+			}
+			if index := strings.Index(content, literal); index >= 0 {
+				return chartName + "/" + filepath.ToSlash(rel), strings.Count(content[:index], "\n") + 1, true
+			}
+		}
+	}
+	return "", 0, false
+}
@@ -0,0 +1,198 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronField describes one of the five fields of a standard cron schedule.
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var cronFields = [5]cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}},
+	{name: "day of week", min: 0, max: 6, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}},
+}
+
+// cronDescriptors are the predefined schedules accepted in place of the five
+// fields.
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// daysInMonth is the longest length of each month, counting leap years.
+var daysInMonth = [13]int{0, 31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+
+// cronSchedule is a parsed CronJob schedule, as accepted by the Kubernetes
+// CronJob controller: five fields or a descriptor such as @daily or
+// @every 1h.
+type cronSchedule struct {
+	// values holds the sorted values each field matches.
+	values [5][]int
+	// anyDay and anyWeekday are set when the day fields are * or ?. Like
+	// cron, a schedule restricting both fires when either matches.
+	anyDay, anyWeekday bool
+	// every is the interval of an @every schedule.
+	every time.Duration
+}
+
+// parseCron parses a CronJob schedule.
+func parseCron(spec string) (cronSchedule, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@every ") {
+		every, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil || every <= 0 {
+			return cronSchedule{}, fmt.Errorf("invalid @every interval in %q", spec)
+		}
+		return cronSchedule{every: every}, nil
+	}
+	if strings.HasPrefix(spec, "@") {
+		expanded, ok := cronDescriptors[strings.ToLower(spec)]
+		if !ok {
+			return cronSchedule{}, fmt.Errorf("unknown descriptor %q", spec)
+		}
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return cronSchedule{}, fmt.Errorf("expected 5 fields (minute hour day-of-month month day-of-week), got %d", len(fields))
+	}
+	var schedule cronSchedule
+	for i, field := range fields {
+		values, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return cronSchedule{}, err
+		}
+		schedule.values[i] = values
+	}
+	schedule.anyDay = strings.HasPrefix(fields[2], "*") || fields[2] == "?"
+	schedule.anyWeekday = strings.HasPrefix(fields[4], "*") || fields[4] == "?"
+	return schedule, nil
+}
+
+// parseCronField returns the sorted values a comma-separated list of values,
+// ranges and steps matches.
+func parseCronField(expression string, field cronField) ([]int, error) {
+	matched := make(map[int]bool)
+	for _, part := range strings.Split(expression, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			rangePart = part[:i]
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step in %s field %q", field.name, part)
+			}
+		}
+
+		var low, high int
+		switch {
+		case rangePart == "*" || rangePart == "?":
+			low, high = field.min, field.max
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if low, err = cronValue(bounds[0], field); err != nil {
+				return nil, err
+			}
+			if high, err = cronValue(bounds[1], field); err != nil {
+				return nil, err
+			}
+			if low > high {
+				return nil, fmt.Errorf("invalid range %q in %s field", rangePart, field.name)
+			}
+		default:
+			value, err := cronValue(rangePart, field)
+			if err != nil {
+				return nil, err
+			}
+			low, high = value, value
+			if strings.Contains(part, "/") {
+				// "5/15" steps from 5 to the end of the range.
+				high = field.max
+			}
+		}
+		for value := low; value <= high; value += step {
+			matched[value] = true
+		}
+	}
+
+	values := make([]int, 0, len(matched))
+	for value := range matched {
+		values = append(values, value)
+	}
+	sort.Ints(values)
+	return values, nil
+}
+
+// cronValue parses a single number or name of field.
+func cronValue(text string, field cronField) (int, error) {
+	value, ok := field.names[strings.ToLower(text)]
+	if !ok {
+		var err error
+		if value, err = strconv.Atoi(text); err != nil {
+			return 0, fmt.Errorf("invalid %s %q", field.name, text)
+		}
+	}
+	if value < field.min || value > field.max {
+		return 0, fmt.Errorf("%s %d is out of range %d-%d", field.name, value, field.min, field.max)
+	}
+	return value, nil
+}
+
+// fires reports whether the schedule matches any date at all. Only a day of
+// month restricted without a day of week can miss every month, as in
+// "0 0 30 2 *".
+func (s cronSchedule) fires() bool {
+	if s.every > 0 || s.anyDay || !s.anyWeekday {
+		return true
+	}
+	for _, month := range s.values[3] {
+		if s.values[2][0] <= daysInMonth[month] {
+			return true
+		}
+	}
+	return false
+}
+
+// minInterval returns the shortest time between two runs of the schedule.
+// Runs on consecutive days are assumed to be possible.
+func (s cronSchedule) minInterval() time.Duration {
+	if s.every > 0 {
+		return s.every
+	}
+	var times []int
+	for _, hour := range s.values[1] {
+		for _, minute := range s.values[0] {
+			times = append(times, hour*60+minute)
+		}
+	}
+	shortest := times[0] + 24*60 - times[len(times)-1]
+	for i := 1; i < len(times); i++ {
+		if gap := times[i] - times[i-1]; gap < shortest {
+			shortest = gap
+		}
+	}
+	return time.Duration(shortest) * time.Minute
+}
@@ -0,0 +1,55 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		schedule    string
+		fires       bool
+		minInterval time.Duration
+	}{
+		{"*/15 * * * *", true, 15 * time.Minute},
+		{"0 9-17 * * MON-FRI", true, time.Hour},
+		{"5/20 2 * * *", true, 20 * time.Minute},
+		{"0,59 0,23 * * *", true, time.Minute},
+		{"30 3 29 2 *", true, 24 * time.Hour},
+		{"0 0 30 2 *", false, 24 * time.Hour},
+		{"0 0 31 apr,jun,sep,nov *", false, 24 * time.Hour},
+		{"0 0 31 2 1", true, 24 * time.Hour},
+		{"@weekly", true, 24 * time.Hour},
+		{"@every 90s", true, 90 * time.Second},
+	}
+	for _, test := range tests {
+		schedule, err := parseCron(test.schedule)
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", test.schedule, err)
+			continue
+		}
+		if schedule.fires() != test.fires || schedule.minInterval() != test.minInterval {
+			t.Errorf("Expected %q to fire %v every %v, got %v every %v",
+				test.schedule, test.fires, test.minInterval, schedule.fires(), schedule.minInterval())
+		}
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	tests := map[string]string{
+		"* * * *":      "expected 5 fields",
+		"60 * * * *":   "minute 60 is out of range 0-59",
+		"0 0 * * 7":    "day of week 7 is out of range 0-6",
+		"*/0 * * * *":  "invalid step",
+		"0 17-9 * * *": "invalid range",
+		"0 0 * foo *":  `invalid month "foo"`,
+		"@fortnightly": "unknown descriptor",
+		"@every soon":  "invalid @every interval",
+	}
+	for schedule, expected := range tests {
+		if _, err := parseCron(schedule); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error containing %q for %q, got %v", expected, schedule, err)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package rules
+
+import (
+	"strings"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+const (
+	externalDNSHostnameAnnotation = "external-dns.alpha.kubernetes.io/hostname"
+	certManagerIssuerAnnotation   = "cert-manager.io/issuer"
+	certManagerClusterAnnotation  = "cert-manager.io/cluster-issuer"
+)
+
+func init() {
+	Register(externalDNSRule{})
+	Register(certManagerRule{})
+}
+
+// externalDNSRule checks that hostnames published by external-dns belong to
+// allowed domains and are actually served by the annotated resource.
+type externalDNSRule struct{}
+
+func (externalDNSRule) ID() string { return "external-dns-hostname" }
+
+func (externalDNSRule) Enabled(config *models.Config) bool {
+	return config.DNS.Enabled
+}
+
+func (r externalDNSRule) Check(ctx *Context) []models.Finding {
+	domains := ctx.Config.DNS.AllowedDomains
+
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		if m.Kind != "Ingress" && m.Kind != "Service" {
+			continue
+		}
+
+		annotated := splitHostnames(NestedString(m.Annotations(), externalDNSHostnameAnnotation))
+		published := annotated
+		if m.Kind == "Ingress" {
+			hosts := ingressRuleHosts(m)
+			for _, host := range annotated {
+				if !containsString(hosts, host) {
+					findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+						"%s lists %q, which is not a host of any Ingress rule", externalDNSHostnameAnnotation, host))
+				}
+			}
+			published = mergeHosts(annotated, hosts)
+		} else if len(annotated) > 0 {
+			if serviceType := NestedString(m.Object, "spec", "type"); serviceType != "LoadBalancer" {
+				findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+					"%s is only honoured on LoadBalancer Services, got type %q", externalDNSHostnameAnnotation, defaultString(serviceType, "ClusterIP")))
+			}
+		}
+
+		for _, host := range published {
+			if !domainAllowed(host, domains) {
+				findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+					"host %q is outside the allowed domains %v", host, domains))
+			}
+		}
+	}
+	return findings
+}
+
+// certManagerRule checks that cert-manager annotations and Certificates
+// reference allowed issuers and agree with the TLS sections they provision.
+type certManagerRule struct{}
+
+func (certManagerRule) ID() string { return "cert-manager-issuer" }
+
+func (certManagerRule) Enabled(config *models.Config) bool {
+	return config.DNS.Enabled
+}
+
+func (r certManagerRule) Check(ctx *Context) []models.Finding {
+	config := ctx.Config.DNS
+	issuers := renderedIssuers(ctx.Manifests)
+
+	var findings []models.Finding
+	checkIssuer := func(m Manifest, kind, name string) {
+		if len(config.AllowedIssuers) > 0 && !containsString(config.AllowedIssuers, name) && !issuers[kind+"/"+name] {
+			findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+				"%s %q is not in the allowed issuers %v", kind, name, config.AllowedIssuers))
+		}
+	}
+
+	for _, m := range ctx.Manifests {
+		switch m.Kind {
+		case "Ingress":
+			annotations := m.Annotations()
+			issuer := NestedString(annotations, certManagerIssuerAnnotation)
+			clusterIssuer := NestedString(annotations, certManagerClusterAnnotation)
+			if issuer == "" && clusterIssuer == "" {
+				continue
+			}
+			if issuer != "" {
+				checkIssuer(m, "Issuer", issuer)
+			}
+			if clusterIssuer != "" {
+				checkIssuer(m, "ClusterIssuer", clusterIssuer)
+			}
+
+			tls := NestedSlice(m.Object, "spec", "tls")
+			if len(tls) == 0 {
+				findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+					"has a cert-manager issuer annotation but no spec.tls, so no certificate is requested"))
+				continue
+			}
+
+			var tlsHosts []string
+			for i, t := range tls {
+				entry, _ := t.(map[string]interface{})
+				if NestedString(entry, "secretName") == "" {
+					findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+						"spec.tls[%d] has no secretName for cert-manager to store the certificate in", i))
+				}
+				for _, h := range NestedSlice(entry, "hosts") {
+					host, _ := h.(string)
+					tlsHosts = append(tlsHosts, host)
+					if !domainAllowed(host, config.AllowedDomains) {
+						findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+							"TLS host %q is outside the allowed domains %v", host, config.AllowedDomains))
+					}
+				}
+			}
+			for _, host := range ingressRuleHosts(m) {
+				if !containsString(tlsHosts, host) {
+					findings = append(findings, newFinding(r.ID(), models.SeverityWarning, m,
+						"rule host %q is not covered by spec.tls and will be served without the certificate", host))
+				}
+			}
+		case "Certificate":
+			if !strings.HasPrefix(m.APIVersion, "cert-manager.io/") {
+				continue
+			}
+			kind := defaultString(NestedString(m.Object, "spec", "issuerRef", "kind"), "Issuer")
+			checkIssuer(m, kind, NestedString(m.Object, "spec", "issuerRef", "name"))
+			for _, h := range NestedSlice(m.Object, "spec", "dnsNames") {
+				if host, _ := h.(string); !domainAllowed(host, config.AllowedDomains) {
+					findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+						"dnsName %q is outside the allowed domains %v", host, config.AllowedDomains))
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// renderedIssuers returns the Kind/name of every cert-manager Issuer and
+// ClusterIssuer rendered by the chart.
+func renderedIssuers(manifests []Manifest) map[string]bool {
+	issuers := make(map[string]bool)
+	for _, m := range manifests {
+		if (m.Kind == "Issuer" || m.Kind == "ClusterIssuer") && strings.HasPrefix(m.APIVersion, "cert-manager.io/") {
+			issuers[m.Resource()] = true
+		}
+	}
+	return issuers
+}
+
+// ingressRuleHosts returns the hosts of an Ingress's rules.
+func ingressRuleHosts(m Manifest) []string {
+	var hosts []string
+	for _, rl := range NestedSlice(m.Object, "spec", "rules") {
+		rule, _ := rl.(map[string]interface{})
+		if host := NestedString(rule, "host"); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// splitHostnames splits a comma-separated hostname annotation.
+func splitHostnames(value string) []string {
+	var hosts []string
+	for _, host := range strings.Split(value, ",") {
+		if host = strings.TrimSuffix(strings.TrimSpace(host), "."); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// mergeHosts returns the sorted union of two host lists.
+func mergeHosts(a, b []string) []string {
+	seen := make(map[string]string)
+	for _, host := range append(append([]string{}, a...), b...) {
+		seen[host] = host
+	}
+	return sortedKeys(seen)
+}
+
+// domainAllowed reports whether host equals or is a subdomain of one of
+// domains. Wildcard hosts are checked by their base domain. An empty domain
+// list allows every host.
+func domainAllowed(host string, domains []string) bool {
+	if len(domains) == 0 {
+		return true
+	}
+	host = strings.TrimPrefix(host, "*.")
+	for _, domain := range domains {
+		domain = strings.TrimPrefix(domain, "*.")
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsString reports whether list contains value.
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultString returns value, or fallback when value is empty.
+func defaultString(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
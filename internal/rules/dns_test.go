@@ -0,0 +1,120 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+const dnsTestManifests = `---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: web
+  annotations:
+    external-dns.alpha.kubernetes.io/hostname: web.example.com,old.example.com
+    cert-manager.io/cluster-issuer: letsencrypt-staging
+spec:
+  tls:
+    - hosts:
+        - web.example.com
+  rules:
+    - host: web.example.com
+    - host: web.example.org
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  annotations:
+    external-dns.alpha.kubernetes.io/hostname: lb.example.com
+---
+apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata:
+  name: internal
+spec:
+  issuerRef:
+    name: internal-ca
+  dnsNames:
+    - internal.example.com
+---
+apiVersion: cert-manager.io/v1
+kind: Issuer
+metadata:
+  name: internal-ca
+`
+
+func TestExternalDNSRule(t *testing.T) {
+	manifests, err := ParseManifests(dnsTestManifests)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx := &Context{
+		Manifests: manifests,
+		Config:    models.Config{DNS: models.DNSConfig{Enabled: true, AllowedDomains: []string{"example.com"}}},
+	}
+	findings := externalDNSRule{}.Check(ctx)
+
+	expected := []string{
+		`lists "old.example.com", which is not a host of any Ingress rule`,
+		`host "web.example.org" is outside the allowed domains`,
+		`only honoured on LoadBalancer Services, got type "ClusterIP"`,
+	}
+	if len(findings) != len(expected) {
+		t.Fatalf("Expected %d findings, got %d: %v", len(expected), len(findings), findings)
+	}
+	for i, message := range expected {
+		if !strings.Contains(findings[i].Message, message) {
+			t.Errorf("Expected finding %d to contain %q, got %s", i, message, findings[i].Message)
+		}
+	}
+}
+
+func TestCertManagerRule(t *testing.T) {
+	manifests, err := ParseManifests(dnsTestManifests)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx := &Context{
+		Manifests: manifests,
+		Config: models.Config{DNS: models.DNSConfig{
+			Enabled:        true,
+			AllowedIssuers: []string{"letsencrypt-prod"},
+		}},
+	}
+	findings := certManagerRule{}.Check(ctx)
+
+	expected := []string{
+		`ClusterIssuer "letsencrypt-staging" is not in the allowed issuers`,
+		"spec.tls[0] has no secretName",
+		`rule host "web.example.org" is not covered by spec.tls`,
+	}
+	if len(findings) != len(expected) {
+		t.Fatalf("Expected %d findings, got %d: %v", len(expected), len(findings), findings)
+	}
+	for i, message := range expected {
+		if !strings.Contains(findings[i].Message, message) {
+			t.Errorf("Expected finding %d to contain %q, got %s", i, message, findings[i].Message)
+		}
+	}
+	if findings[2].Severity != models.SeverityWarning {
+		t.Errorf("Expected uncovered host to be a warning, got %s", findings[2].Severity)
+	}
+}
+
+func TestDomainAllowed(t *testing.T) {
+	domains := []string{"example.com"}
+	if !domainAllowed("api.example.com", domains) || !domainAllowed("*.example.com", domains) || !domainAllowed("example.com", domains) {
+		t.Errorf("Expected subdomains of example.com to be allowed")
+	}
+	if domainAllowed("badexample.com", domains) {
+		t.Errorf("Expected badexample.com to be rejected")
+	}
+	if !domainAllowed("anything.org", nil) {
+		t.Errorf("Expected an empty allowlist to allow every host")
+	}
+}
@@ -0,0 +1,221 @@
+package rules
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func init() {
+	Register(duplicateValuesRule{})
+}
+
+// duplicateValuesRule keeps environment values files down to the values that
+// actually differ: keys repeating the value they would have anyway, from the
+// chart defaults or an earlier file of the environment, are noise, and keys
+// every environment sets to the same value belong in the chart's values.yaml.
+type duplicateValuesRule struct{}
+
+func (duplicateValuesRule) ID() string { return "duplicate-values" }
+
+func (duplicateValuesRule) Enabled(config *models.Config) bool {
+	return config.DuplicateValues.Enabled
+}
+
+// leafValue is a value set by a values file: a scalar, a sequence or an empty
+// mapping, at a line of the file.
+type leafValue struct {
+	value interface{}
+	file  string
+	line  int
+}
+
+func (r duplicateValuesRule) Check(ctx *Context) []models.Finding {
+	var findings []models.Finding
+	loaded := make(map[string]map[string]leafValue)
+	load := func(file string) map[string]leafValue {
+		if leaves, ok := loaded[file]; ok {
+			return leaves
+		}
+		leaves, err := loadLeafValues(file)
+		if err != nil {
+			findings = append(findings, models.Finding{RuleID: r.ID(), Severity: models.SeverityError, Message: err.Error(), File: file})
+		}
+		loaded[file] = leaves
+		return leaves
+	}
+
+	defaults := map[string]leafValue{}
+	if _, err := os.Stat(filepath.Join(ctx.ChartPath, "values.yaml")); err == nil {
+		defaults = load(filepath.Join(ctx.ChartPath, "values.yaml"))
+	}
+
+	environments := make(map[string][]string)
+	for name, environment := range ctx.Config.Environments {
+		if len(environment.ValuesFiles) > 0 {
+			environments[name] = environment.ValuesFiles
+		}
+	}
+	if len(environments) == 0 && len(ctx.Config.ValuesFiles) > 0 {
+		environments[""] = ctx.Config.ValuesFiles
+	}
+	names := make([]string, 0, len(environments))
+	for name := range environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Every file is checked once, against the values in effect before it in
+	// the first environment using it.
+	checked := make(map[string]bool)
+	// overrides holds the values each environment changes, attributed to the
+	// file that changes them.
+	overrides := make(map[string]map[string]leafValue, len(names))
+	for _, name := range names {
+		effective := make(map[string]leafValue, len(defaults))
+		for path, leaf := range defaults {
+			effective[path] = leaf
+		}
+		overrides[name] = make(map[string]leafValue)
+		for _, file := range environments[name] {
+			leaves := load(file)
+			for _, path := range sortedLeafPaths(leaves) {
+				leaf := leaves[path]
+				if previous, ok := effective[path]; ok && reflect.DeepEqual(previous.value, leaf.value) {
+					if !checked[file] {
+						findings = append(findings, models.Finding{
+							RuleID:   r.ID(),
+							Severity: models.SeverityWarning,
+							Message:  fmt.Sprintf("%s repeats the value %s already has from %s", path, formatLeaf(leaf.value), describeSource(previous.file, ctx.ChartPath)),
+							File:     file,
+							Line:     leaf.line,
+						})
+					}
+					continue
+				}
+				effective[path] = leaf
+				overrides[name][path] = leaf
+			}
+			checked[file] = true
+		}
+	}
+
+	if len(names) < 2 {
+		return findings
+	}
+	for _, path := range sortedLeafPaths(overrides[names[0]]) {
+		first := overrides[names[0]][path]
+		sameFile := true
+		identical := true
+		for _, name := range names[1:] {
+			leaf, ok := overrides[name][path]
+			if !ok || !reflect.DeepEqual(leaf.value, first.value) {
+				identical = false
+				break
+			}
+			sameFile = sameFile && leaf.file == first.file
+		}
+		if !identical || sameFile {
+			// Files shared by every environment are deliberate.
+			continue
+		}
+		findings = append(findings, models.Finding{
+			RuleID:   r.ID(),
+			Severity: models.SeverityInfo,
+			Message:  fmt.Sprintf("%s is set to %s in every environment (%s); consider moving it into values.yaml", path, formatLeaf(first.value), strings.Join(names, ", ")),
+			File:     first.file,
+			Line:     first.line,
+		})
+	}
+	return findings
+}
+
+// loadLeafValues returns the values set by a values file, keyed by their
+// dotted path.
+func loadLeafValues(file string) (map[string]leafValue, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read values file: %v", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("values file is not valid YAML: %v", err)
+	}
+	leaves := make(map[string]leafValue)
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return leaves, nil
+	}
+	if err := collectLeafValues(doc.Content[0], "", file, leaves); err != nil {
+		return nil, fmt.Errorf("values file is not valid YAML: %v", err)
+	}
+	return leaves, nil
+}
+
+// collectLeafValues adds the leaves of a non-empty mapping node at path.
+func collectLeafValues(node *yaml.Node, path, file string, leaves map[string]leafValue) error {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		keyPath := key.Value
+		if path != "" {
+			keyPath = path + "." + key.Value
+		}
+		if value.Kind == yaml.AliasNode {
+			value = value.Alias
+		}
+		if value.Kind == yaml.MappingNode && len(value.Content) > 0 {
+			if err := collectLeafValues(value, keyPath, file, leaves); err != nil {
+				return err
+			}
+			continue
+		}
+		var decoded interface{}
+		if err := value.Decode(&decoded); err != nil {
+			return err
+		}
+		leaves[keyPath] = leafValue{value: decoded, file: file, line: key.Line}
+	}
+	return nil
+}
+
+// sortedLeafPaths returns the paths of leaves in order.
+func sortedLeafPaths(leaves map[string]leafValue) []string {
+	paths := make([]string, 0, len(leaves))
+	for path := range leaves {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// formatLeaf renders a value compactly for messages, sequences and mappings
+// in flow style.
+func formatLeaf(value interface{}) string {
+	var node yaml.Node
+	if err := node.Encode(value); err != nil {
+		return fmt.Sprint(value)
+	}
+	if node.Kind == yaml.SequenceNode || node.Kind == yaml.MappingNode {
+		node.Style = yaml.FlowStyle
+	}
+	data, err := yaml.Marshal(&node)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// describeSource names where a value came from in messages: the chart
+// defaults or an earlier values file.
+func describeSource(file, chartPath string) string {
+	if file == filepath.Join(chartPath, "values.yaml") {
+		return "the chart defaults"
+	}
+	return file
+}
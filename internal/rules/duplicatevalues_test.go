@@ -0,0 +1,66 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestDuplicateValuesRule(t *testing.T) {
+	dir := t.TempDir()
+	chartDir := filepath.Join(dir, "web")
+	if err := os.MkdirAll(chartDir, 0755); err != nil {
+		t.Fatalf("Failed to create chart dir: %v", err)
+	}
+	files := map[string]string{
+		"web/values.yaml":    "replicas: 1\nimage:\n  repository: web\n  tag: latest\nports: [80]\n",
+		"common.yaml":        "logLevel: info\n",
+		"values-dev.yaml":    "replicas: 1\nimage:\n  tag: dev\nports: [80]\nregion: eu-west-1\n",
+		"values-prod.yaml":   "replicas: 3\nimage:\n  tag: dev\nregion: eu-west-1\nlogLevel: info\n",
+		"values-broken.yaml": "replicas: [\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	file := func(name string) string { return filepath.Join(dir, name) }
+
+	ctx := &Context{ChartPath: chartDir, Config: models.Config{
+		DuplicateValues: models.DuplicateValuesConfig{Enabled: true},
+		Environments: map[string]models.EnvironmentConfig{
+			"dev":  {ValuesFiles: []string{file("common.yaml"), file("values-dev.yaml")}},
+			"prod": {ValuesFiles: []string{file("common.yaml"), file("values-prod.yaml")}},
+		},
+	}}
+	expected := []models.Finding{
+		{RuleID: "duplicate-values", Severity: models.SeverityWarning, Message: "ports repeats the value [80] already has from the chart defaults", File: file("values-dev.yaml"), Line: 4},
+		{RuleID: "duplicate-values", Severity: models.SeverityWarning, Message: "replicas repeats the value 1 already has from the chart defaults", File: file("values-dev.yaml"), Line: 1},
+		{RuleID: "duplicate-values", Severity: models.SeverityWarning, Message: "logLevel repeats the value info already has from " + file("common.yaml"), File: file("values-prod.yaml"), Line: 5},
+		{RuleID: "duplicate-values", Severity: models.SeverityInfo, Message: "image.tag is set to dev in every environment (dev, prod); consider moving it into values.yaml", File: file("values-dev.yaml"), Line: 3},
+		{RuleID: "duplicate-values", Severity: models.SeverityInfo, Message: "region is set to eu-west-1 in every environment (dev, prod); consider moving it into values.yaml", File: file("values-dev.yaml"), Line: 5},
+	}
+	findings := duplicateValuesRule{}.Check(ctx)
+	if len(findings) != len(expected) {
+		t.Fatalf("Expected %d findings, got %v", len(expected), findings)
+	}
+	for i := range expected {
+		if findings[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected[i], findings[i])
+		}
+	}
+
+	ctx.Config.Environments = nil
+	ctx.Config.ValuesFiles = []string{file("values-prod.yaml")}
+	if findings := (duplicateValuesRule{}).Check(ctx); len(findings) != 0 {
+		t.Errorf("Expected no findings for a values file overriding every default, got %v", findings)
+	}
+
+	ctx.Config.ValuesFiles = []string{file("values-broken.yaml"), file("missing.yaml")}
+	findings = duplicateValuesRule{}.Check(ctx)
+	if len(findings) != 2 || findings[0].Severity != models.SeverityError || findings[1].Severity != models.SeverityError {
+		t.Errorf("Expected errors for unparseable and missing values files, got %v", findings)
+	}
+}
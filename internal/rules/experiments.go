@@ -0,0 +1,58 @@
+package rules
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// Experiment is a check that ships turned off until it is stable.
+// Repositories opt into it by name with the experimental section of the
+// configuration or --enable-experimental.
+type Experiment struct {
+	Name        string
+	Description string
+}
+
+var experiments []Experiment
+
+// RegisterExperiment declares an experiment, which rules and scan stages
+// implementing it check with ExperimentEnabled. It is meant to be called from
+// init functions.
+func RegisterExperiment(name, description string) {
+	experiments = append(experiments, Experiment{Name: name, Description: description})
+}
+
+// Experiments returns the registered experiments ordered by name.
+func Experiments() []Experiment {
+	sorted := slices.Clone(experiments)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
+
+// ExperimentEnabled reports whether config opts into the experiment name.
+func ExperimentEnabled(config *models.Config, name string) bool {
+	return slices.Contains(config.Experimental, name)
+}
+
+// ValidateExperiments returns an error naming the first of names that is not
+// a registered experiment.
+func ValidateExperiments(names []string) error {
+	var known []string
+	for _, experiment := range Experiments() {
+		known = append(known, experiment.Name)
+	}
+	for _, name := range names {
+		if !slices.Contains(known, name) {
+			available := "none"
+			if len(known) > 0 {
+				available = strings.Join(known, ", ")
+			}
+			return fmt.Errorf("unknown experiment %q (available: %s)", name, available)
+		}
+	}
+	return nil
+}
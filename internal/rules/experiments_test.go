@@ -0,0 +1,36 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestExperiments(t *testing.T) {
+	original := experiments
+	t.Cleanup(func() { experiments = original })
+	experiments = nil
+
+	if err := ValidateExperiments([]string{"schema-v2"}); err == nil || !strings.Contains(err.Error(), "available: none") {
+		t.Errorf("Expected an unknown experiment without any registered, got %v", err)
+	}
+
+	RegisterExperiment("schema-v2", "Validate manifests with the new schema loader")
+	RegisterExperiment("ast-parser", "Find value references with the new template parser")
+	if names := Experiments(); len(names) != 2 || names[0].Name != "ast-parser" || names[1].Name != "schema-v2" {
+		t.Errorf("Expected ast-parser and schema-v2, got %+v", names)
+	}
+
+	if err := ValidateExperiments([]string{"schema-v2", "ast-parser"}); err != nil {
+		t.Errorf("Expected registered experiments to be valid, got %v", err)
+	}
+	if err := ValidateExperiments([]string{"schema-v3"}); err == nil || !strings.Contains(err.Error(), "ast-parser, schema-v2") {
+		t.Errorf("Expected an error listing the available experiments, got %v", err)
+	}
+
+	config := &models.Config{Experimental: []string{"schema-v2"}}
+	if !ExperimentEnabled(config, "schema-v2") || ExperimentEnabled(config, "ast-parser") {
+		t.Errorf("Expected only schema-v2 to be enabled")
+	}
+}
@@ -0,0 +1,140 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// caInjectionAnnotations are the cert-manager cainjector annotations that make
+// the API server copy a CA bundle into the annotated resource.
+var caInjectionAnnotations = []string{
+	"cert-manager.io/inject-ca-from",
+	"cert-manager.io/inject-ca-from-secret",
+	"cert-manager.io/inject-apiserver-ca",
+}
+
+func init() {
+	Register(gitOpsDiffNoiseRule{})
+}
+
+// gitOpsDiffNoiseRule flags rendered fields that are mutated in-cluster after
+// apply and therefore show up as perpetual diffs in Argo CD or Flux.
+type gitOpsDiffNoiseRule struct{}
+
+func (gitOpsDiffNoiseRule) ID() string { return "gitops-diff-noise" }
+
+func (gitOpsDiffNoiseRule) Enabled(config *models.Config) bool {
+	return config.GitOps.Tool != ""
+}
+
+func (r gitOpsDiffNoiseRule) Check(ctx *Context) []models.Finding {
+	tool := ctx.Config.GitOps.Tool
+	if tool != "argocd" && tool != "flux" {
+		return []models.Finding{{
+			RuleID:   r.ID(),
+			Severity: models.SeverityError,
+			Message:  fmt.Sprintf("unknown gitops tool %q (expected argocd or flux)", tool),
+		}}
+	}
+
+	autoscaled := autoscaledTargets(ctx.Manifests)
+
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		for _, noisy := range noisyPaths(m, autoscaled) {
+			findings = append(findings, newFinding(r.ID(), models.SeverityWarning, m,
+				"%s will drift from the rendered value (%s); %s",
+				noisy.pointer, noisy.reason, ignoreSuggestion(tool, m, noisy.pointer)))
+		}
+	}
+	return findings
+}
+
+// noisyPath is a JSON pointer within a manifest that is known to be rewritten
+// in-cluster, together with the reason.
+type noisyPath struct {
+	pointer string
+	reason  string
+}
+
+// noisyPaths returns the fields of m that will not match the live object.
+func noisyPaths(m Manifest, autoscaled map[string]bool) []noisyPath {
+	var paths []noisyPath
+	injected := hasCAInjection(m)
+
+	switch m.Kind {
+	case "MutatingWebhookConfiguration", "ValidatingWebhookConfiguration":
+		if injected {
+			for i := range NestedSlice(m.Object, "webhooks") {
+				paths = append(paths, noisyPath{
+					pointer: fmt.Sprintf("/webhooks/%d/clientConfig/caBundle", i),
+					reason:  "injected by cert-manager cainjector",
+				})
+			}
+		}
+	case "CustomResourceDefinition":
+		if injected && NestedMap(m.Object, "spec", "conversion", "webhook") != nil {
+			paths = append(paths, noisyPath{
+				pointer: "/spec/conversion/webhook/clientConfig/caBundle",
+				reason:  "injected by cert-manager cainjector",
+			})
+		}
+	case "APIService":
+		if injected {
+			paths = append(paths, noisyPath{pointer: "/spec/caBundle", reason: "injected by cert-manager cainjector"})
+		}
+	case "Deployment", "StatefulSet", "ReplicaSet":
+		if _, set := NestedMap(m.Object, "spec")["replicas"]; set && autoscaled[m.Kind+"/"+m.Name] {
+			paths = append(paths, noisyPath{pointer: "/spec/replicas", reason: "managed by a HorizontalPodAutoscaler"})
+		}
+	case "Service":
+		if clusterIP, set := NestedMap(m.Object, "spec")["clusterIP"]; set && clusterIP == "" {
+			paths = append(paths, noisyPath{pointer: "/spec/clusterIP", reason: "empty value is defaulted by the API server"})
+		}
+	}
+
+	return paths
+}
+
+// hasCAInjection reports whether m carries a cert-manager CA injection annotation.
+func hasCAInjection(m Manifest) bool {
+	annotations := m.Annotations()
+	for _, annotation := range caInjectionAnnotations {
+		if _, ok := annotations[annotation]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// autoscaledTargets returns the Kind/name of every workload targeted by a
+// HorizontalPodAutoscaler in manifests.
+func autoscaledTargets(manifests []Manifest) map[string]bool {
+	targets := make(map[string]bool)
+	for _, m := range manifests {
+		if m.Kind != "HorizontalPodAutoscaler" {
+			continue
+		}
+		ref := NestedMap(m.Object, "spec", "scaleTargetRef")
+		targets[NestedString(ref, "kind")+"/"+NestedString(ref, "name")] = true
+	}
+	return targets
+}
+
+// ignoreSuggestion returns the tool-specific configuration that silences the
+// diff at pointer for m.
+func ignoreSuggestion(tool string, m Manifest, pointer string) string {
+	group := ""
+	if i := strings.Index(m.APIVersion, "/"); i >= 0 {
+		group = m.APIVersion[:i]
+	}
+
+	if tool == "flux" {
+		return fmt.Sprintf("add a HelmRelease driftDetection.ignore entry {paths: [%q], target: {kind: %s, name: %s}}",
+			pointer, m.Kind, m.Name)
+	}
+	return fmt.Sprintf("add an Application ignoreDifferences entry {group: %q, kind: %s, name: %s, jsonPointers: [%q]}",
+		group, m.Kind, m.Name, pointer)
+}
@@ -0,0 +1,76 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestGitOpsDiffNoiseRule(t *testing.T) {
+	manifests, err := ParseManifests(`---
+apiVersion: admissionregistration.k8s.io/v1
+kind: MutatingWebhookConfiguration
+metadata:
+  name: injector
+  annotations:
+    cert-manager.io/inject-ca-from: apps/injector-cert
+webhooks:
+  - name: inject.example.com
+    clientConfig:
+      caBundle: ""
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 2
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: worker
+spec:
+  replicas: 1
+---
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: web
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: web
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	rule := gitOpsDiffNoiseRule{}
+	if rule.Enabled(&models.Config{}) {
+		t.Fatal("Expected rule to be disabled without a gitops tool")
+	}
+
+	ctx := &Context{Manifests: manifests, Config: models.Config{GitOps: models.GitOpsConfig{Tool: "argocd"}}}
+	findings := rule.Check(ctx)
+
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %d: %v", len(findings), findings)
+	}
+	if findings[0].Resource != "MutatingWebhookConfiguration/injector" || !strings.Contains(findings[0].Message, "/webhooks/0/clientConfig/caBundle") {
+		t.Errorf("Unexpected webhook finding: %+v", findings[0])
+	}
+	if !strings.Contains(findings[0].Message, "ignoreDifferences") {
+		t.Errorf("Expected an Argo CD suggestion, got %s", findings[0].Message)
+	}
+	if findings[1].Resource != "Deployment/web" || findings[1].Severity != models.SeverityWarning {
+		t.Errorf("Unexpected replicas finding: %+v", findings[1])
+	}
+
+	ctx.Config.GitOps.Tool = "flux"
+	if findings := rule.Check(ctx); !strings.Contains(findings[0].Message, "driftDetection.ignore") {
+		t.Errorf("Expected a Flux suggestion, got %s", findings[0].Message)
+	}
+}
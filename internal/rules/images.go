@@ -0,0 +1,170 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+	"github.com/Jaydee94/chartscan/internal/registry"
+)
+
+// imagePlatforms looks up the platforms an image is published for. It is a
+// variable so tests can avoid registry access.
+var imagePlatforms = registry.NewClient().Platforms
+
+// semverTagRegex matches full semantic version tags, which are conventionally
+// never re-pushed, unlike floating tags such as 1.2 or stable.
+var semverTagRegex = regexp.MustCompile(`^v?\d+\.\d+\.\d+(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+func init() {
+	Register(imageArchitecturesRule{})
+	Register(imagePinningRule{})
+}
+
+// imageArchitecturesRule verifies that every image used by the chart is
+// published for the architectures of a mixed-architecture cluster.
+type imageArchitecturesRule struct{}
+
+func (imageArchitecturesRule) ID() string { return "image-architectures" }
+
+func (imageArchitecturesRule) Enabled(config *models.Config) bool {
+	return len(config.Images.Architectures) > 0
+}
+
+func (r imageArchitecturesRule) Check(ctx *Context) []models.Finding {
+	required := ctx.Config.Images.Architectures
+
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		for _, image := range workloadImages(m) {
+			platforms, err := imagePlatforms(image)
+			if err != nil {
+				findings = append(findings, newFinding(r.ID(), models.SeverityWarning, m,
+					"cannot verify architectures of image %s: %v", image, err))
+				continue
+			}
+
+			var missing []string
+			for _, architecture := range required {
+				if !platformsInclude(platforms, architecture) {
+					missing = append(missing, architecture)
+				}
+			}
+			if len(missing) > 0 {
+				findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+					"image %s is not published for %s (available: %s)",
+					image, strings.Join(missing, ", "), strings.Join(platforms, ", ")))
+			}
+		}
+	}
+	return findings
+}
+
+// imagePinningRule enforces the image pinning policy for supply-chain
+// integrity.
+type imagePinningRule struct{}
+
+func (imagePinningRule) ID() string { return "image-pinning" }
+
+func (imagePinningRule) Enabled(config *models.Config) bool {
+	return config.Images.Pinning != ""
+}
+
+func (r imagePinningRule) Check(ctx *Context) []models.Finding {
+	config := ctx.Config.Images
+	if config.Pinning != "digest" && config.Pinning != "semver" && config.Pinning != "no-latest" {
+		return []models.Finding{{
+			RuleID:   r.ID(),
+			Severity: models.SeverityError,
+			Message:  fmt.Sprintf("unknown image pinning mode %q (expected digest, semver or no-latest)", config.Pinning),
+		}}
+	}
+
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		for _, image := range workloadImages(m) {
+			ref, err := registry.ParseReference(image)
+			if err != nil {
+				findings = append(findings, newFinding(r.ID(), models.SeverityError, m, "%v", err))
+				continue
+			}
+			if registryMatchesAny(ref.Registry, config.PinningExceptions) {
+				continue
+			}
+			if problem := pinningProblem(image, ref, config.Pinning); problem != "" {
+				findings = append(findings, newFinding(r.ID(), models.SeverityError, m, "image %s %s", image, problem))
+			}
+		}
+	}
+	return findings
+}
+
+// pinningProblem describes how image violates the pinning mode, or returns "".
+func pinningProblem(image string, ref registry.Reference, mode string) string {
+	digest := strings.Contains(image, "@")
+	switch mode {
+	case "digest":
+		if !digest {
+			return "is not pinned by digest"
+		}
+	case "semver":
+		if !digest && !semverTagRegex.MatchString(ref.Reference) {
+			return fmt.Sprintf("uses tag %q, which is neither a full semantic version nor a digest", ref.Reference)
+		}
+	case "no-latest":
+		if !digest && ref.Reference == "latest" {
+			return "uses the latest tag"
+		}
+	}
+	return ""
+}
+
+// registryMatchesAny reports whether a registry host matches one of patterns.
+// docker.io is accepted as an alias for Docker Hub.
+func registryMatchesAny(host string, patterns []string) bool {
+	if matchesAny(host, patterns) {
+		return true
+	}
+	return host == "registry-1.docker.io" && matchesAny("docker.io", patterns)
+}
+
+// workloadImages returns the distinct images of a workload's containers and
+// init containers in lexical order.
+func workloadImages(m Manifest) []string {
+	podSpec := PodSpec(m)
+	if podSpec == nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var images []string
+	for _, container := range Containers(podSpec, true) {
+		if image := NestedString(container, "image"); image != "" && !seen[image] {
+			seen[image] = true
+			images = append(images, image)
+		}
+	}
+	sort.Strings(images)
+	return images
+}
+
+// platformsInclude reports whether one of platforms ("os/arch[/variant]")
+// satisfies the required architecture, which may be given as arch,
+// arch/variant, os/arch or os/arch/variant.
+func platformsInclude(platforms []string, required string) bool {
+	for _, platform := range platforms {
+		parts := strings.Split(platform, "/")
+		if len(parts) < 2 {
+			continue
+		}
+		candidates := []string{parts[1], parts[0] + "/" + parts[1]}
+		if len(parts) > 2 {
+			candidates = append(candidates, parts[1]+"/"+parts[2], platform)
+		}
+		if containsString(candidates, required) {
+			return true
+		}
+	}
+	return false
+}
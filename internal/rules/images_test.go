@@ -0,0 +1,117 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestImageArchitecturesRule(t *testing.T) {
+	manifests, err := ParseManifests(`---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      initContainers:
+        - name: migrate
+          image: example.com/migrate:1.0
+      containers:
+        - name: web
+          image: example.com/web:1.0
+        - name: proxy
+          image: example.com/proxy:1.0
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	original := imagePlatforms
+	defer func() { imagePlatforms = original }()
+	imagePlatforms = func(image string) ([]string, error) {
+		switch image {
+		case "example.com/web:1.0":
+			return []string{"linux/amd64", "linux/arm64/v8"}, nil
+		case "example.com/proxy:1.0":
+			return []string{"linux/amd64"}, nil
+		default:
+			return nil, fmt.Errorf("not found")
+		}
+	}
+
+	ctx := &Context{
+		Manifests: manifests,
+		Config:    models.Config{Images: models.ImagesConfig{Architectures: []string{"amd64", "linux/arm64"}}},
+	}
+	findings := imageArchitecturesRule{}.Check(ctx)
+
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %d: %v", len(findings), findings)
+	}
+	if findings[0].Severity != models.SeverityWarning || !strings.Contains(findings[0].Message, "cannot verify architectures of image example.com/migrate:1.0") {
+		t.Errorf("Expected lookup warning for migrate image, got %v", findings[0])
+	}
+	if findings[1].Severity != models.SeverityError || !strings.Contains(findings[1].Message, "example.com/proxy:1.0 is not published for linux/arm64") {
+		t.Errorf("Expected missing arm64 error for proxy image, got %v", findings[1])
+	}
+}
+
+func TestImagePinningRule(t *testing.T) {
+	manifests, err := ParseManifests(`---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+        - name: web
+          image: ghcr.io/org/web:1.4.2
+        - name: proxy
+          image: nginx
+        - name: agent
+          image: ghcr.io/org/agent:1.4
+        - name: sidecar
+          image: ghcr.io/org/sidecar@sha256:0123
+        - name: debug
+          image: internal.example.com/debug:latest
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx := &Context{
+		Manifests: manifests,
+		Config: models.Config{Images: models.ImagesConfig{
+			Pinning:           "semver",
+			PinningExceptions: []string{"*.example.com"},
+		}},
+	}
+	findings := imagePinningRule{}.Check(ctx)
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %d: %v", len(findings), findings)
+	}
+	if !strings.Contains(findings[0].Message, `ghcr.io/org/agent:1.4 uses tag "1.4"`) {
+		t.Errorf("Expected floating tag finding, got %s", findings[0].Message)
+	}
+	if !strings.Contains(findings[1].Message, `nginx uses tag "latest"`) {
+		t.Errorf("Expected implicit latest finding, got %s", findings[1].Message)
+	}
+
+	ctx.Config.Images.Pinning = "digest"
+	if findings := (imagePinningRule{}).Check(ctx); len(findings) != 3 {
+		t.Errorf("Expected 3 unpinned images in digest mode, got %v", findings)
+	}
+
+	ctx.Config.Images.Pinning = "no-latest"
+	ctx.Config.Images.PinningExceptions = []string{"docker.io"}
+	findings = imagePinningRule{}.Check(ctx)
+	if len(findings) != 1 || !strings.Contains(findings[0].Message, "internal.example.com/debug:latest uses the latest tag") {
+		t.Errorf("Expected only the debug image to use latest, got %v", findings)
+	}
+}
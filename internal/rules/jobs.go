@@ -0,0 +1,167 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// defaultMinScheduleInterval is the shortest interval between CronJob runs
+// accepted when jobs.minScheduleInterval is not set.
+const defaultMinScheduleInterval = 5 * time.Minute
+
+func init() {
+	Register(jobLimitsRule{})
+	Register(cronJobScheduleRule{})
+	Register(cronJobConcurrencyRule{})
+	Register(hookJobTTLRule{})
+}
+
+// jobSpec returns the Job spec of a Job or of the jobs a CronJob creates, or
+// nil for other kinds.
+func jobSpec(m Manifest) map[string]interface{} {
+	switch m.Kind {
+	case "Job":
+		return NestedMap(m.Object, "spec")
+	case "CronJob":
+		return NestedMap(m.Object, "spec", "jobTemplate", "spec")
+	default:
+		return nil
+	}
+}
+
+// jobLimitsRule requires Jobs to bound their retries and run time. Without
+// backoffLimit a failing Job is retried six times; without
+// activeDeadlineSeconds a hanging Job runs forever and, for a CronJob with
+// concurrencyPolicy Forbid, blocks every later run.
+type jobLimitsRule struct{}
+
+func (jobLimitsRule) ID() string { return "job-limits" }
+
+func (jobLimitsRule) Enabled(config *models.Config) bool {
+	return config.Jobs.Enabled
+}
+
+func (r jobLimitsRule) Check(ctx *Context) []models.Finding {
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		spec := jobSpec(m)
+		if spec == nil {
+			continue
+		}
+		var missing []string
+		for _, field := range []string{"backoffLimit", "activeDeadlineSeconds"} {
+			if spec[field] == nil {
+				missing = append(missing, field)
+			}
+		}
+		if len(missing) > 0 {
+			findings = append(findings, newFinding(r.ID(), models.SeverityWarning, m,
+				"job sets no %s", strings.Join(missing, " or ")))
+		}
+	}
+	return findings
+}
+
+// cronJobScheduleRule validates CronJob schedules and flags schedules that
+// can never fire or fire more often than jobs.minScheduleInterval.
+type cronJobScheduleRule struct{}
+
+func (cronJobScheduleRule) ID() string { return "cronjob-schedule" }
+
+func (cronJobScheduleRule) Enabled(config *models.Config) bool {
+	return config.Jobs.Enabled
+}
+
+func (r cronJobScheduleRule) Check(ctx *Context) []models.Finding {
+	minInterval := defaultMinScheduleInterval
+	if setting := ctx.Config.Jobs.MinScheduleInterval; setting != "" {
+		var err error
+		if minInterval, err = time.ParseDuration(setting); err != nil {
+			return []models.Finding{{
+				RuleID:   r.ID(),
+				Severity: models.SeverityError,
+				Message:  fmt.Sprintf("invalid jobs.minScheduleInterval %q: %v", setting, err),
+			}}
+		}
+	}
+
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		if m.Kind != "CronJob" {
+			continue
+		}
+		spec := NestedString(m.Object, "spec", "schedule")
+		if strings.HasPrefix(spec, "TZ=") || strings.HasPrefix(spec, "CRON_TZ=") {
+			findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+				"schedule %q sets a time zone, which Kubernetes rejects; use spec.timeZone", spec))
+			continue
+		}
+		schedule, err := parseCron(spec)
+		if err != nil {
+			findings = append(findings, newFinding(r.ID(), models.SeverityError, m, "invalid schedule %q: %v", spec, err))
+			continue
+		}
+		if !schedule.fires() {
+			findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+				"schedule %q never fires: no month has the requested day", spec))
+			continue
+		}
+		if interval := schedule.minInterval(); interval < minInterval {
+			findings = append(findings, newFinding(r.ID(), models.SeverityWarning, m,
+				"schedule %q runs every %v, more often than the minimum interval of %v", spec, interval, minInterval))
+		}
+	}
+	return findings
+}
+
+// cronJobConcurrencyRule requires CronJobs to choose a concurrencyPolicy. The
+// default, Allow, lets slow runs pile up on top of each other.
+type cronJobConcurrencyRule struct{}
+
+func (cronJobConcurrencyRule) ID() string { return "cronjob-concurrency" }
+
+func (cronJobConcurrencyRule) Enabled(config *models.Config) bool {
+	return config.Jobs.Enabled
+}
+
+func (r cronJobConcurrencyRule) Check(ctx *Context) []models.Finding {
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		if m.Kind == "CronJob" && NestedString(m.Object, "spec", "concurrencyPolicy") == "" {
+			findings = append(findings, newFinding(r.ID(), models.SeverityWarning, m,
+				"sets no concurrencyPolicy, so runs may overlap; choose Allow, Forbid or Replace explicitly"))
+		}
+	}
+	return findings
+}
+
+// hookJobTTLRule requires Helm hook Jobs to clean up after themselves. Helm
+// does not delete hook resources unless a hook-delete-policy says so, and
+// finished Jobs otherwise stay around until the next release fails to create
+// them again.
+type hookJobTTLRule struct{}
+
+func (hookJobTTLRule) ID() string { return "hook-job-ttl" }
+
+func (hookJobTTLRule) Enabled(config *models.Config) bool {
+	return config.Jobs.Enabled
+}
+
+func (r hookJobTTLRule) Check(ctx *Context) []models.Finding {
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		annotations := m.Annotations()
+		if m.Kind != "Job" || annotations["helm.sh/hook"] == nil {
+			continue
+		}
+		if NestedValue(m.Object, "spec", "ttlSecondsAfterFinished") != nil || annotations["helm.sh/hook-delete-policy"] != nil {
+			continue
+		}
+		findings = append(findings, newFinding(r.ID(), models.SeverityWarning, m,
+			"hook job sets neither spec.ttlSecondsAfterFinished nor a helm.sh/hook-delete-policy, so finished jobs are never cleaned up"))
+	}
+	return findings
+}
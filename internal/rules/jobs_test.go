@@ -0,0 +1,123 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestJobRules(t *testing.T) {
+	manifests, err := ParseManifests(`---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+  annotations:
+    helm.sh/hook: pre-upgrade
+spec:
+  backoffLimit: 2
+  template:
+    spec:
+      containers:
+        - name: migrate
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: seed
+  annotations:
+    helm.sh/hook: post-install
+    helm.sh/hook-delete-policy: hook-succeeded
+spec:
+  backoffLimit: 0
+  activeDeadlineSeconds: 600
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: report
+spec:
+  schedule: "*/2 * * * *"
+  concurrencyPolicy: Forbid
+  jobTemplate:
+    spec:
+      backoffLimit: 1
+      activeDeadlineSeconds: 60
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: leap
+spec:
+  schedule: "0 0 30 2 *"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+            - name: leap
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: zoned
+spec:
+  schedule: "TZ=Europe/Berlin 0 6 * * *"
+  concurrencyPolicy: Replace
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: typo
+spec:
+  schedule: "0 25 * * *"
+  concurrencyPolicy: Allow
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx := &Context{Manifests: manifests, Config: models.Config{Jobs: models.JobsConfig{Enabled: true}}}
+
+	tests := []struct {
+		rule     Rule
+		expected []string
+	}{
+		{jobLimitsRule{}, []string{
+			"Job/migrate: job sets no activeDeadlineSeconds",
+			"CronJob/leap: job sets no backoffLimit or activeDeadlineSeconds",
+		}},
+		{cronJobScheduleRule{}, []string{
+			`CronJob/report: schedule "*/2 * * * *" runs every 2m0s, more often than the minimum interval of 5m0s`,
+			`CronJob/leap: schedule "0 0 30 2 *" never fires: no month has the requested day`,
+			`CronJob/zoned: schedule "TZ=Europe/Berlin 0 6 * * *" sets a time zone, which Kubernetes rejects; use spec.timeZone`,
+			`CronJob/typo: invalid schedule "0 25 * * *": hour 25 is out of range 0-23`,
+		}},
+		{cronJobConcurrencyRule{}, []string{
+			"CronJob/leap: sets no concurrencyPolicy, so runs may overlap; choose Allow, Forbid or Replace explicitly",
+		}},
+		{hookJobTTLRule{}, []string{
+			"Job/migrate: hook job sets neither spec.ttlSecondsAfterFinished nor a helm.sh/hook-delete-policy, so finished jobs are never cleaned up",
+		}},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, finding := range test.rule.Check(ctx) {
+			got = append(got, finding.Resource+": "+finding.Message)
+		}
+		if strings.Join(got, "\n") != strings.Join(test.expected, "\n") {
+			t.Errorf("Expected %s findings:\n%s\ngot:\n%s", test.rule.ID(), strings.Join(test.expected, "\n"), strings.Join(got, "\n"))
+		}
+	}
+
+	ctx.Config.Jobs.MinScheduleInterval = "1m"
+	for _, finding := range (cronJobScheduleRule{}).Check(ctx) {
+		if finding.Resource == "CronJob/report" {
+			t.Errorf("Expected a 2m schedule to pass a 1m minimum interval, got %s", finding.Message)
+		}
+	}
+	ctx.Config.Jobs.MinScheduleInterval = "often"
+	if findings := (cronJobScheduleRule{}).Check(ctx); len(findings) != 1 || !strings.Contains(findings[0].Message, "invalid jobs.minScheduleInterval") {
+		t.Errorf("Expected an invalid minScheduleInterval error, got %v", findings)
+	}
+}
@@ -0,0 +1,397 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func init() {
+	Register(meshRouteTargetRule{})
+	Register(meshRoutePrecedenceRule{})
+	Register(meshTLSRule{})
+}
+
+// meshRouteTargetRule checks that Istio and Gateway API routes send traffic to
+// Services, ports and subsets that the chart renders.
+type meshRouteTargetRule struct{}
+
+func (meshRouteTargetRule) ID() string { return "mesh-route-target" }
+
+func (meshRouteTargetRule) Enabled(config *models.Config) bool {
+	return config.ServiceMesh.Enabled
+}
+
+func (r meshRouteTargetRule) Check(ctx *Context) []models.Finding {
+	external := ctx.Config.ServiceMesh.ExternalHosts
+	subsets := destinationRuleSubsets(ctx.Manifests)
+
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		switch {
+		case m.Kind == "VirtualService" && isIstio(m):
+			for _, section := range []string{"http", "tcp", "tls"} {
+				for i, rt := range NestedSlice(m.Object, "spec", section) {
+					route, _ := rt.(map[string]interface{})
+					where := fmt.Sprintf("%s[%d]", section, i)
+					for _, d := range NestedSlice(route, "route") {
+						weighted, _ := d.(map[string]interface{})
+						destination := NestedMap(weighted, "destination")
+						host := NestedString(destination, "host")
+						port := portString(NestedValue(destination, "port", "number"))
+						if problem := checkMeshTarget(ctx.Manifests, m.Namespace, host, port, external); problem != "" {
+							findings = append(findings, newFinding(r.ID(), models.SeverityError, m, "%s: %s", where, problem))
+							continue
+						}
+						if subset := NestedString(destination, "subset"); subset != "" && !subsets[serviceKey(host)+"/"+subset] {
+							findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+								"%s: subset %q of host %q is not defined by any DestinationRule in the chart", where, subset, host))
+						}
+					}
+					if mirror := NestedString(route, "mirror", "host"); mirror != "" {
+						if problem := checkMeshTarget(ctx.Manifests, m.Namespace, mirror, "", external); problem != "" {
+							findings = append(findings, newFinding(r.ID(), models.SeverityError, m, "%s mirror: %s", where, problem))
+						}
+					}
+				}
+			}
+		case m.Kind == "DestinationRule" && isIstio(m):
+			if problem := checkMeshTarget(ctx.Manifests, m.Namespace, NestedString(m.Object, "spec", "host"), "", external); problem != "" {
+				findings = append(findings, newFinding(r.ID(), models.SeverityError, m, "%s", problem))
+			}
+		case isGatewayAPIRoute(m):
+			for i, rl := range NestedSlice(m.Object, "spec", "rules") {
+				rule, _ := rl.(map[string]interface{})
+				for _, b := range NestedSlice(rule, "backendRefs") {
+					backend, _ := b.(map[string]interface{})
+					if kind := NestedString(backend, "kind"); kind != "" && kind != "Service" {
+						continue
+					}
+					host := NestedString(backend, "name")
+					if namespace := NestedString(backend, "namespace"); namespace != "" {
+						host += "." + namespace + ".svc"
+					}
+					if problem := checkMeshTarget(ctx.Manifests, m.Namespace, host, portString(backend["port"]), external); problem != "" {
+						findings = append(findings, newFinding(r.ID(), models.SeverityError, m, "rules[%d]: %s", i, problem))
+					}
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// meshRoutePrecedenceRule flags routes that can never match because an
+// earlier or identical route already captures their traffic.
+type meshRoutePrecedenceRule struct{}
+
+func (meshRoutePrecedenceRule) ID() string { return "mesh-route-precedence" }
+
+func (meshRoutePrecedenceRule) Enabled(config *models.Config) bool {
+	return config.ServiceMesh.Enabled
+}
+
+func (r meshRoutePrecedenceRule) Check(ctx *Context) []models.Finding {
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		switch {
+		case m.Kind == "VirtualService" && isIstio(m):
+			// Istio evaluates HTTP routes in order; the first match wins.
+			routes := NestedSlice(m.Object, "spec", "http")
+			seen := make(map[string]int)
+			for i, rt := range routes {
+				route, _ := rt.(map[string]interface{})
+				matches := NestedSlice(route, "match")
+				if isCatchAll(matches) && i < len(routes)-1 {
+					findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+						"http[%d] matches all requests, so the %d route(s) after it are unreachable", i, len(routes)-1-i))
+					break
+				}
+				for _, match := range matches {
+					key := fmt.Sprint(match)
+					if first, duplicate := seen[key]; duplicate {
+						findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+							"http[%d] repeats a match of http[%d] and is unreachable for it", i, first))
+					} else {
+						seen[key] = i
+					}
+				}
+			}
+		case m.Kind == "HTTPRoute" || m.Kind == "GRPCRoute":
+			// Gateway API orders by specificity, so identical matches in
+			// different rules leave the winner to implementation tie-breaking.
+			seen := make(map[string]int)
+			for i, rl := range NestedSlice(m.Object, "spec", "rules") {
+				rule, _ := rl.(map[string]interface{})
+				for _, match := range NestedSlice(rule, "matches") {
+					key := fmt.Sprint(match)
+					if first, duplicate := seen[key]; duplicate && first != i {
+						findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+							"rules[%d] repeats a match of rules[%d]; only one of them will receive the traffic", i, first))
+					} else if !duplicate {
+						seen[key] = i
+					}
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// meshTLSRule checks that TLS modes on gateways and destination rules are
+// consistent with the certificates they reference.
+type meshTLSRule struct{}
+
+func (meshTLSRule) ID() string { return "mesh-tls" }
+
+func (meshTLSRule) Enabled(config *models.Config) bool {
+	return config.ServiceMesh.Enabled
+}
+
+func (r meshTLSRule) Check(ctx *Context) []models.Finding {
+	secrets, _ := renderedConfigSources(ctx.Manifests)
+	external := ctx.Config.ServiceMesh.ExternalSecrets
+	secretExists := func(name string) bool {
+		return secrets[name] || matchesAny(name, external)
+	}
+
+	var findings []models.Finding
+	report := func(m Manifest, format string, args ...interface{}) {
+		findings = append(findings, newFinding(r.ID(), models.SeverityError, m, format, args...))
+	}
+
+	for _, m := range ctx.Manifests {
+		switch {
+		case m.Kind == "Gateway" && isIstio(m):
+			for i, s := range NestedSlice(m.Object, "spec", "servers") {
+				server, _ := s.(map[string]interface{})
+				protocol := strings.ToUpper(NestedString(server, "port", "protocol"))
+				tls := NestedMap(server, "tls")
+				mode := NestedString(tls, "mode")
+				credential := NestedString(tls, "credentialName")
+				switch {
+				case (protocol == "HTTPS" || protocol == "TLS") && tls == nil:
+					report(m, "servers[%d]: protocol %s requires a tls block", i, protocol)
+				case protocol == "HTTP" && mode != "":
+					report(m, "servers[%d]: protocol HTTP cannot use tls mode %s", i, mode)
+				case mode == "SIMPLE" || mode == "MUTUAL" || (mode == "" && tls != nil && protocol != "HTTP"):
+					if credential == "" && NestedString(tls, "serverCertificate") == "" {
+						report(m, "servers[%d]: tls mode %s requires credentialName or serverCertificate", i, defaultString(mode, "SIMPLE"))
+					}
+				case (mode == "PASSTHROUGH" || mode == "AUTO_PASSTHROUGH" || mode == "ISTIO_MUTUAL") && credential != "":
+					report(m, "servers[%d]: credentialName is ignored with tls mode %s", i, mode)
+					credential = ""
+				}
+				if credential != "" && !secretExists(credential) {
+					report(m, "servers[%d]: credentialName %q is not rendered by the chart nor listed in serviceMesh.externalSecrets", i, credential)
+				}
+			}
+		case m.Kind == "DestinationRule" && isIstio(m):
+			for _, policy := range destinationRuleTLSPolicies(m) {
+				mode := NestedString(policy.tls, "mode")
+				credential := NestedString(policy.tls, "credentialName")
+				switch {
+				case mode == "MUTUAL" && credential == "" && NestedString(policy.tls, "clientCertificate") == "":
+					report(m, "%s: tls mode MUTUAL requires credentialName or clientCertificate", policy.where)
+				case (mode == "DISABLE" || mode == "ISTIO_MUTUAL") && credential != "":
+					report(m, "%s: credentialName is ignored with tls mode %s", policy.where, mode)
+					credential = ""
+				}
+				if credential != "" && !secretExists(credential) {
+					report(m, "%s: credentialName %q is not rendered by the chart nor listed in serviceMesh.externalSecrets", policy.where, credential)
+				}
+			}
+		case m.Kind == "Gateway" && strings.HasPrefix(m.APIVersion, "gateway.networking.k8s.io/"):
+			for i, l := range NestedSlice(m.Object, "spec", "listeners") {
+				listener, _ := l.(map[string]interface{})
+				protocol := NestedString(listener, "protocol")
+				tls := NestedMap(listener, "tls")
+				mode := defaultString(NestedString(tls, "mode"), "Terminate")
+				refs := NestedSlice(tls, "certificateRefs")
+				switch {
+				case protocol == "HTTP" || protocol == "TCP" || protocol == "UDP":
+					if tls != nil {
+						report(m, "listeners[%d]: protocol %s cannot use tls", i, protocol)
+					}
+				case protocol == "HTTPS" && mode == "Passthrough":
+					report(m, "listeners[%d]: protocol HTTPS requires tls mode Terminate; use protocol TLS for Passthrough", i)
+				case (protocol == "HTTPS" || protocol == "TLS") && mode == "Terminate" && len(refs) == 0:
+					report(m, "listeners[%d]: tls mode Terminate requires certificateRefs", i)
+				case mode == "Passthrough" && len(refs) > 0:
+					report(m, "listeners[%d]: certificateRefs are ignored with tls mode Passthrough", i)
+				}
+				for _, c := range refs {
+					ref, _ := c.(map[string]interface{})
+					if kind := NestedString(ref, "kind"); kind != "" && kind != "Secret" {
+						continue
+					}
+					if name := NestedString(ref, "name"); name != "" && !secretExists(name) {
+						report(m, "listeners[%d]: certificateRef %q is not rendered by the chart nor listed in serviceMesh.externalSecrets", i, name)
+					}
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// isIstio reports whether m belongs to the Istio networking API.
+func isIstio(m Manifest) bool {
+	return strings.HasPrefix(m.APIVersion, "networking.istio.io/")
+}
+
+// isGatewayAPIRoute reports whether m is a Gateway API route with backendRefs.
+func isGatewayAPIRoute(m Manifest) bool {
+	if !strings.HasPrefix(m.APIVersion, "gateway.networking.k8s.io/") {
+		return false
+	}
+	switch m.Kind {
+	case "HTTPRoute", "GRPCRoute", "TCPRoute", "TLSRoute", "UDPRoute":
+		return true
+	}
+	return false
+}
+
+// parseServiceHost splits a cluster-local host (name, name.namespace.svc or
+// name.namespace.svc.cluster.local) into Service name and namespace. Other
+// hosts, such as external FQDNs, are reported as not cluster-local.
+func parseServiceHost(host string) (name, namespace string, local bool) {
+	if !strings.Contains(host, ".") {
+		return host, "", host != "" && host != "*"
+	}
+	trimmed := strings.TrimSuffix(host, ".cluster.local")
+	if !strings.HasSuffix(trimmed, ".svc") {
+		return "", "", false
+	}
+	parts := strings.Split(strings.TrimSuffix(trimmed, ".svc"), ".")
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// serviceKey normalizes host to the Service name it resolves to, so that short
+// and fully qualified spellings compare equal.
+func serviceKey(host string) string {
+	if name, _, local := parseServiceHost(host); local {
+		return name
+	}
+	return host
+}
+
+// checkMeshTarget returns a problem if host does not resolve to a Service
+// rendered by the chart or a host it declares via ServiceEntry, or if that
+// Service does not expose port. An empty result means the target is fine.
+func checkMeshTarget(manifests []Manifest, namespace, host, port string, external []string) string {
+	if host == "" || matchesAny(host, external) {
+		return ""
+	}
+	name, hostNamespace, local := parseServiceHost(host)
+	if !local {
+		return ""
+	}
+	if hostNamespace == "" {
+		hostNamespace = namespace
+	}
+
+	for _, m := range manifests {
+		if m.Kind == "ServiceEntry" && isIstio(m) {
+			for _, h := range NestedSlice(m.Object, "spec", "hosts") {
+				if h == host {
+					return ""
+				}
+			}
+		}
+		if m.Kind != "Service" || m.Name != name {
+			continue
+		}
+		if hostNamespace != "" && m.Namespace != "" && hostNamespace != m.Namespace {
+			continue
+		}
+		if port == "" {
+			return ""
+		}
+		for _, p := range NestedSlice(m.Object, "spec", "ports") {
+			servicePort, _ := p.(map[string]interface{})
+			if fmt.Sprint(servicePort["port"]) == port {
+				return ""
+			}
+		}
+		return fmt.Sprintf("Service %q does not expose port %s", name, port)
+	}
+	return fmt.Sprintf("host %q does not match any Service rendered by the chart nor serviceMesh.externalHosts", host)
+}
+
+// destinationRuleSubsets returns the "<service>/<subset>" pairs defined by the
+// DestinationRules in manifests.
+func destinationRuleSubsets(manifests []Manifest) map[string]bool {
+	subsets := make(map[string]bool)
+	for _, m := range manifests {
+		if m.Kind != "DestinationRule" || !isIstio(m) {
+			continue
+		}
+		host := serviceKey(NestedString(m.Object, "spec", "host"))
+		for _, s := range NestedSlice(m.Object, "spec", "subsets") {
+			subset, _ := s.(map[string]interface{})
+			subsets[host+"/"+NestedString(subset, "name")] = true
+		}
+	}
+	return subsets
+}
+
+// isCatchAll reports whether an Istio HTTP route's match list accepts every
+// request: no match at all, or a match consisting only of the "/" prefix.
+func isCatchAll(matches []interface{}) bool {
+	if len(matches) == 0 {
+		return true
+	}
+	for _, m := range matches {
+		match, _ := m.(map[string]interface{})
+		if len(match) == 0 {
+			return true
+		}
+		if len(match) == 1 && NestedString(match, "uri", "prefix") == "/" && len(NestedMap(match, "uri")) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// tlsPolicy is a tls settings block of a DestinationRule and its location.
+type tlsPolicy struct {
+	where string
+	tls   map[string]interface{}
+}
+
+// destinationRuleTLSPolicies returns every tls block of a DestinationRule,
+// including port-level and subset traffic policies.
+func destinationRuleTLSPolicies(m Manifest) []tlsPolicy {
+	var policies []tlsPolicy
+	collect := func(where string, trafficPolicy map[string]interface{}) {
+		if tls := NestedMap(trafficPolicy, "tls"); tls != nil {
+			policies = append(policies, tlsPolicy{where: where, tls: tls})
+		}
+		for i, p := range NestedSlice(trafficPolicy, "portLevelSettings") {
+			setting, _ := p.(map[string]interface{})
+			if tls := NestedMap(setting, "tls"); tls != nil {
+				policies = append(policies, tlsPolicy{where: fmt.Sprintf("%s.portLevelSettings[%d]", where, i), tls: tls})
+			}
+		}
+	}
+
+	collect("trafficPolicy", NestedMap(m.Object, "spec", "trafficPolicy"))
+	for _, s := range NestedSlice(m.Object, "spec", "subsets") {
+		subset, _ := s.(map[string]interface{})
+		collect(fmt.Sprintf("subset %s trafficPolicy", NestedString(subset, "name")), NestedMap(subset, "trafficPolicy"))
+	}
+	return policies
+}
+
+// portString formats a port number from a manifest, or "" when unset.
+func portString(port interface{}) string {
+	if port == nil {
+		return ""
+	}
+	return fmt.Sprint(port)
+}
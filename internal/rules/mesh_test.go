@@ -0,0 +1,229 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestMeshRouteTargetRule(t *testing.T) {
+	manifests, err := ParseManifests(`---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+    - name: http
+      port: 8080
+---
+apiVersion: networking.istio.io/v1beta1
+kind: DestinationRule
+metadata:
+  name: web
+spec:
+  host: web.default.svc.cluster.local
+  subsets:
+    - name: v1
+---
+apiVersion: networking.istio.io/v1beta1
+kind: VirtualService
+metadata:
+  name: web
+spec:
+  http:
+    - route:
+        - destination:
+            host: web
+            subset: v1
+            port:
+              number: 8080
+        - destination:
+            host: web
+            subset: v2
+        - destination:
+            host: api
+        - destination:
+            host: payments.example.com
+---
+apiVersion: gateway.networking.k8s.io/v1
+kind: HTTPRoute
+metadata:
+  name: web
+spec:
+  rules:
+    - backendRefs:
+        - name: web
+          port: 80
+        - name: legacy
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx := &Context{
+		Manifests: manifests,
+		Config: models.Config{ServiceMesh: models.ServiceMeshConfig{
+			Enabled:       true,
+			ExternalHosts: []string{"legacy"},
+		}},
+	}
+	findings := meshRouteTargetRule{}.Check(ctx)
+
+	expected := []string{
+		`subset "v2" of host "web"`,
+		`host "api" does not match any Service`,
+		`Service "web" does not expose port 80`,
+	}
+	if len(findings) != len(expected) {
+		t.Fatalf("Expected %d findings, got %d: %v", len(expected), len(findings), findings)
+	}
+	for i, message := range expected {
+		if !strings.Contains(findings[i].Message, message) {
+			t.Errorf("Expected finding %d to contain %q, got %s", i, message, findings[i].Message)
+		}
+	}
+}
+
+func TestMeshRoutePrecedenceRule(t *testing.T) {
+	manifests, err := ParseManifests(`---
+apiVersion: networking.istio.io/v1beta1
+kind: VirtualService
+metadata:
+  name: web
+spec:
+  http:
+    - match:
+        - uri:
+            prefix: /api
+    - match:
+        - uri:
+            prefix: /api
+    - route:
+        - destination:
+            host: web
+    - match:
+        - uri:
+            prefix: /admin
+---
+apiVersion: gateway.networking.k8s.io/v1
+kind: HTTPRoute
+metadata:
+  name: web
+spec:
+  rules:
+    - matches:
+        - path:
+            type: PathPrefix
+            value: /
+    - matches:
+        - path:
+            type: PathPrefix
+            value: /
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	findings := meshRoutePrecedenceRule{}.Check(&Context{Manifests: manifests})
+	if len(findings) != 3 {
+		t.Fatalf("Expected 3 findings, got %d: %v", len(findings), findings)
+	}
+	if !strings.Contains(findings[0].Message, "http[1] repeats a match of http[0]") {
+		t.Errorf("Expected duplicate match finding, got %s", findings[0].Message)
+	}
+	if !strings.Contains(findings[1].Message, "http[2] matches all requests, so the 1 route(s)") {
+		t.Errorf("Expected catch-all finding, got %s", findings[1].Message)
+	}
+	if findings[2].Resource != "HTTPRoute/web" {
+		t.Errorf("Expected HTTPRoute duplicate finding, got %v", findings[2])
+	}
+}
+
+func TestMeshTLSRule(t *testing.T) {
+	manifests, err := ParseManifests(`---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: web-tls
+---
+apiVersion: networking.istio.io/v1beta1
+kind: Gateway
+metadata:
+  name: web
+spec:
+  servers:
+    - port:
+        protocol: HTTPS
+      tls:
+        mode: SIMPLE
+        credentialName: web-tls
+    - port:
+        protocol: HTTPS
+      tls:
+        mode: SIMPLE
+    - port:
+        protocol: TLS
+      tls:
+        mode: PASSTHROUGH
+        credentialName: web-tls
+    - port:
+        protocol: HTTPS
+      tls:
+        credentialName: missing-tls
+---
+apiVersion: networking.istio.io/v1beta1
+kind: DestinationRule
+metadata:
+  name: db
+spec:
+  host: db
+  trafficPolicy:
+    tls:
+      mode: MUTUAL
+---
+apiVersion: gateway.networking.k8s.io/v1
+kind: Gateway
+metadata:
+  name: edge
+spec:
+  listeners:
+    - protocol: HTTPS
+    - protocol: TLS
+      tls:
+        mode: Passthrough
+    - protocol: HTTPS
+      tls:
+        certificateRefs:
+          - name: wildcard-tls
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx := &Context{
+		Manifests: manifests,
+		Config: models.Config{ServiceMesh: models.ServiceMeshConfig{
+			Enabled:         true,
+			ExternalSecrets: []string{"wildcard-*"},
+		}},
+	}
+	findings := meshTLSRule{}.Check(ctx)
+
+	expected := []string{
+		"servers[1]: tls mode SIMPLE requires credentialName",
+		"servers[2]: credentialName is ignored with tls mode PASSTHROUGH",
+		`servers[3]: credentialName "missing-tls" is not rendered`,
+		"trafficPolicy: tls mode MUTUAL requires credentialName",
+		"listeners[0]: tls mode Terminate requires certificateRefs",
+	}
+	if len(findings) != len(expected) {
+		t.Fatalf("Expected %d findings, got %d: %v", len(expected), len(findings), findings)
+	}
+	for i, message := range expected {
+		if !strings.Contains(findings[i].Message, message) {
+			t.Errorf("Expected finding %d to contain %q, got %s", i, message, findings[i].Message)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func init() {
+	Register(serviceMonitorTargetRule{})
+	Register(prometheusRuleExprRule{})
+	Register(monitoringLabelsRule{})
+}
+
+// serviceMonitorTargetRule checks that ServiceMonitors select a Service
+// rendered by the chart and scrape ports that Service exposes.
+type serviceMonitorTargetRule struct{}
+
+func (serviceMonitorTargetRule) ID() string { return "servicemonitor-target" }
+
+func (serviceMonitorTargetRule) Enabled(config *models.Config) bool {
+	return config.Monitoring.Enabled
+}
+
+func (r serviceMonitorTargetRule) Check(ctx *Context) []models.Finding {
+	var services []Manifest
+	for _, m := range ctx.Manifests {
+		if m.Kind == "Service" {
+			services = append(services, m)
+		}
+	}
+
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		if m.Kind != "ServiceMonitor" {
+			continue
+		}
+		// Monitors selecting other namespaces target Services outside the chart.
+		if nsSelector := NestedMap(m.Object, "spec", "namespaceSelector"); len(nsSelector) > 0 {
+			continue
+		}
+
+		selector := NestedMap(m.Object, "spec", "selector", "matchLabels")
+		if len(selector) == 0 {
+			findings = append(findings, newFinding(r.ID(), models.SeverityError, m, "spec.selector.matchLabels is empty"))
+			continue
+		}
+
+		var selected []Manifest
+		for _, service := range services {
+			if labelsMatch(NestedMap(service.Object, "metadata", "labels"), selector) {
+				selected = append(selected, service)
+			}
+		}
+		if len(selected) == 0 {
+			findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+				"selector %s matches no Service rendered by the chart", formatLabels(selector)))
+			continue
+		}
+
+		for _, e := range NestedSlice(m.Object, "spec", "endpoints") {
+			endpoint, _ := e.(map[string]interface{})
+			port := NestedString(endpoint, "port")
+			if port == "" {
+				continue
+			}
+			if !anyServiceExposesPort(selected, port) {
+				findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+					"endpoint port %q is not a named port of the selected Service(s)", port))
+			}
+		}
+	}
+	return findings
+}
+
+// prometheusRuleExprRule validates the PromQL expressions and structure of
+// PrometheusRule groups.
+type prometheusRuleExprRule struct{}
+
+func (prometheusRuleExprRule) ID() string { return "prometheusrule-expr" }
+
+func (prometheusRuleExprRule) Enabled(config *models.Config) bool {
+	return config.Monitoring.Enabled
+}
+
+func (r prometheusRuleExprRule) Check(ctx *Context) []models.Finding {
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		if m.Kind != "PrometheusRule" {
+			continue
+		}
+		for _, g := range NestedSlice(m.Object, "spec", "groups") {
+			group, _ := g.(map[string]interface{})
+			groupName := NestedString(group, "name")
+			for i, rl := range NestedSlice(group, "rules") {
+				rule, _ := rl.(map[string]interface{})
+				name := NestedString(rule, "alert")
+				if name == "" {
+					name = NestedString(rule, "record")
+				}
+				if name == "" {
+					findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+						"group %q rule %d has neither alert nor record", groupName, i))
+					name = fmt.Sprintf("#%d", i)
+				}
+				if rule["expr"] == nil {
+					findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+						"group %q rule %s has no expr", groupName, name))
+				} else if err := ValidatePromQL(fmt.Sprint(rule["expr"])); err != nil {
+					findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+						"group %q rule %s: invalid expr: %v", groupName, name, err))
+				}
+				if duration := NestedString(rule, "for"); duration != "" && !promQLDurationRegex.MatchString(duration) {
+					findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+						"group %q rule %s: invalid for duration %q", groupName, name, duration))
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// monitoringLabelsRule enforces the labels the Prometheus operator setup uses
+// to select monitoring resources.
+type monitoringLabelsRule struct{}
+
+func (monitoringLabelsRule) ID() string { return "monitoring-labels" }
+
+func (monitoringLabelsRule) Enabled(config *models.Config) bool {
+	return config.Monitoring.Enabled && len(config.Monitoring.RequiredLabels) > 0
+}
+
+func (r monitoringLabelsRule) Check(ctx *Context) []models.Finding {
+	required := ctx.Config.Monitoring.RequiredLabels
+	keys := make([]string, 0, len(required))
+	for key := range required {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		if m.Kind != "ServiceMonitor" && m.Kind != "PodMonitor" && m.Kind != "PrometheusRule" {
+			continue
+		}
+		labels := NestedMap(m.Object, "metadata", "labels")
+		for _, key := range keys {
+			value, exists := labels[key]
+			switch {
+			case !exists:
+				findings = append(findings, newFinding(r.ID(), models.SeverityError, m, "missing required label %q", key))
+			case required[key] != "" && fmt.Sprint(value) != required[key]:
+				findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+					"label %q is %q, expected %q", key, fmt.Sprint(value), required[key]))
+			}
+		}
+	}
+	return findings
+}
+
+// labelsMatch reports whether labels contains every key/value of selector.
+func labelsMatch(labels, selector map[string]interface{}) bool {
+	for key, value := range selector {
+		if actual, ok := labels[key]; !ok || fmt.Sprint(actual) != fmt.Sprint(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// formatLabels renders a label map as a sorted {k=v,...} string.
+func formatLabels(labels map[string]interface{}) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", key, value))
+	}
+	sort.Strings(pairs)
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// anyServiceExposesPort reports whether one of services has a port named port.
+func anyServiceExposesPort(services []Manifest, port string) bool {
+	for _, service := range services {
+		for _, p := range NestedSlice(service.Object, "spec", "ports") {
+			if servicePort, _ := p.(map[string]interface{}); NestedString(servicePort, "name") == port {
+				return true
+			}
+		}
+	}
+	return false
+}
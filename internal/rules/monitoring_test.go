@@ -0,0 +1,101 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestMonitoringRules(t *testing.T) {
+	manifests, err := ParseManifests(`---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  labels:
+    app: web
+spec:
+  ports:
+    - name: http
+      port: 80
+---
+apiVersion: monitoring.coreos.com/v1
+kind: ServiceMonitor
+metadata:
+  name: web
+  labels:
+    release: prometheus
+spec:
+  selector:
+    matchLabels:
+      app: web
+  endpoints:
+    - port: http
+    - port: metrics
+---
+apiVersion: monitoring.coreos.com/v1
+kind: ServiceMonitor
+metadata:
+  name: worker
+spec:
+  selector:
+    matchLabels:
+      app: worker
+---
+apiVersion: monitoring.coreos.com/v1
+kind: PrometheusRule
+metadata:
+  name: web
+  labels:
+    release: prometheus
+spec:
+  groups:
+    - name: web.rules
+      rules:
+        - alert: WebDown
+          expr: up{job="web"} == 0
+          for: 5m
+        - alert: WebErrors
+          expr: rate(http_requests_total{code=~"5.."}[5m] > 1
+          for: five minutes
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx := &Context{
+		Manifests: manifests,
+		Config: models.Config{Monitoring: models.MonitoringConfig{
+			Enabled:        true,
+			RequiredLabels: map[string]string{"release": "prometheus"},
+		}},
+	}
+
+	findings := serviceMonitorTargetRule{}.Check(ctx)
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 ServiceMonitor findings, got %d: %v", len(findings), findings)
+	}
+	if !strings.Contains(findings[0].Message, `endpoint port "metrics"`) {
+		t.Errorf("Expected unknown metrics port, got %s", findings[0].Message)
+	}
+	if findings[1].Resource != "ServiceMonitor/worker" || !strings.Contains(findings[1].Message, "selector {app=worker} matches no Service") {
+		t.Errorf("Expected unmatched worker selector, got %v", findings[1])
+	}
+
+	findings = prometheusRuleExprRule{}.Check(ctx)
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 PrometheusRule findings, got %d: %v", len(findings), findings)
+	}
+	if !strings.Contains(findings[0].Message, "rule WebErrors: invalid expr") {
+		t.Errorf("Expected invalid expr finding, got %s", findings[0].Message)
+	}
+	if !strings.Contains(findings[1].Message, `invalid for duration "five minutes"`) {
+		t.Errorf("Expected invalid duration finding, got %s", findings[1].Message)
+	}
+
+	findings = monitoringLabelsRule{}.Check(ctx)
+	if len(findings) != 1 || findings[0].Resource != "ServiceMonitor/worker" {
+		t.Fatalf("Expected missing label on ServiceMonitor/worker, got %v", findings)
+	}
+}
@@ -0,0 +1,233 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// Pod Security Standards profiles, from least to most restrictive.
+const (
+	pssPrivileged = "privileged"
+	pssBaseline   = "baseline"
+	pssRestricted = "restricted"
+)
+
+// baselineCapabilities are the capabilities the baseline profile allows to be
+// added on top of the container runtime defaults.
+var baselineCapabilities = map[string]bool{
+	"AUDIT_WRITE": true, "CHOWN": true, "DAC_OVERRIDE": true, "FOWNER": true, "FSETID": true,
+	"KILL": true, "MKNOD": true, "NET_BIND_SERVICE": true, "SETFCAP": true, "SETGID": true,
+	"SETPCAP": true, "SETUID": true, "SYS_CHROOT": true,
+}
+
+// safeSysctls are the sysctls the baseline profile allows.
+var safeSysctls = map[string]bool{
+	"kernel.shm_rmid_forced": true, "net.ipv4.ip_local_port_range": true, "net.ipv4.ip_unprivileged_port_start": true,
+	"net.ipv4.tcp_syncookies": true, "net.ipv4.ping_group_range": true, "net.ipv4.ip_local_reserved_ports": true,
+	"net.ipv4.tcp_keepalive_time": true, "net.ipv4.tcp_fin_timeout": true, "net.ipv4.tcp_keepalive_intvl": true,
+	"net.ipv4.tcp_keepalive_probes": true,
+}
+
+// allowedSELinuxTypes are the SELinux types the baseline profile allows.
+var allowedSELinuxTypes = map[string]bool{
+	"container_t": true, "container_init_t": true, "container_kvm_t": true, "container_engine_t": true,
+}
+
+// restrictedVolumeTypes are the volume sources the restricted profile allows.
+var restrictedVolumeTypes = map[string]bool{
+	"configMap": true, "csi": true, "downwardAPI": true, "emptyDir": true, "ephemeral": true,
+	"persistentVolumeClaim": true, "projected": true, "secret": true,
+}
+
+func init() {
+	Register(podSecurityRule{})
+}
+
+// podSecurityRule evaluates workloads against the Pod Security Standards and
+// reports those that would be rejected by a namespace enforcing the required
+// level.
+type podSecurityRule struct{}
+
+func (podSecurityRule) ID() string { return "pod-security" }
+
+func (podSecurityRule) Enabled(config *models.Config) bool {
+	return config.PodSecurity.Enabled
+}
+
+func (r podSecurityRule) Check(ctx *Context) []models.Finding {
+	required := defaultString(ctx.Config.PodSecurity.Level, pssBaseline)
+	if required != pssPrivileged && required != pssBaseline && required != pssRestricted {
+		return []models.Finding{{
+			RuleID:   r.ID(),
+			Severity: models.SeverityError,
+			Message:  fmt.Sprintf("unknown pod security level %q (expected privileged, baseline or restricted)", required),
+		}}
+	}
+	if required == pssPrivileged {
+		return nil
+	}
+
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		podSpec := PodSpec(m)
+		if podSpec == nil {
+			continue
+		}
+
+		baseline, restricted := podSecurityViolations(m, podSpec)
+		violations := baseline
+		if required == pssRestricted {
+			violations = append(violations, restricted...)
+		}
+		if len(violations) == 0 {
+			continue
+		}
+
+		satisfied := pssBaseline
+		if len(baseline) > 0 {
+			satisfied = pssPrivileged
+		}
+		findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+			"satisfies only the %s profile, %s is required: %s", satisfied, required, strings.Join(violations, "; ")))
+	}
+	return findings
+}
+
+// podSecurityViolations returns the checks of the baseline profile and the
+// additional checks of the restricted profile that podSpec fails.
+func podSecurityViolations(m Manifest, podSpec map[string]interface{}) (baseline, restricted []string) {
+	podContext := NestedMap(podSpec, "securityContext")
+
+	for _, field := range []string{"hostNetwork", "hostPID", "hostIPC"} {
+		if enabled, _ := podSpec[field].(bool); enabled {
+			baseline = append(baseline, field+" is true")
+		}
+	}
+	if hostProcess, _ := NestedValue(podContext, "windowsOptions", "hostProcess").(bool); hostProcess {
+		baseline = append(baseline, "windowsOptions.hostProcess is true")
+	}
+	baseline = append(baseline, securityContextViolations("pod", podContext)...)
+	for _, s := range NestedSlice(podContext, "sysctls") {
+		sysctl, _ := s.(map[string]interface{})
+		if name := NestedString(sysctl, "name"); !safeSysctls[name] {
+			baseline = append(baseline, fmt.Sprintf("sysctl %s is not allowed", name))
+		}
+	}
+	annotations := NestedMap(PodTemplateMetadata(m), "annotations")
+	for _, key := range sortedMapKeys(annotations) {
+		if value := annotations[key]; strings.HasPrefix(key, "container.apparmor.security.beta.kubernetes.io/") {
+			if profile := fmt.Sprint(value); profile != "runtime/default" && !strings.HasPrefix(profile, "localhost/") {
+				baseline = append(baseline, fmt.Sprintf("AppArmor profile %s is %s", strings.TrimPrefix(key, "container.apparmor.security.beta.kubernetes.io/"), profile))
+			}
+		}
+	}
+
+	for _, v := range NestedSlice(podSpec, "volumes") {
+		volume, _ := v.(map[string]interface{})
+		for _, source := range sortedMapKeys(volume) {
+			if source == "name" {
+				continue
+			}
+			if source == "hostPath" {
+				baseline = append(baseline, fmt.Sprintf("volume %s uses hostPath", NestedString(volume, "name")))
+			} else if !restrictedVolumeTypes[source] {
+				restricted = append(restricted, fmt.Sprintf("volume %s uses %s", NestedString(volume, "name"), source))
+			}
+		}
+	}
+
+	podSeccomp := NestedString(podContext, "seccompProfile", "type")
+	podNonRoot, _ := podContext["runAsNonRoot"].(bool)
+	if fmt.Sprint(podContext["runAsUser"]) == "0" {
+		restricted = append(restricted, "pod runAsUser is 0")
+	}
+
+	for _, container := range Containers(podSpec, true) {
+		name := "container " + NestedString(container, "name")
+		securityContext := NestedMap(container, "securityContext")
+
+		if privileged, _ := securityContext["privileged"].(bool); privileged {
+			baseline = append(baseline, name+" is privileged")
+		}
+		if hostProcess, _ := NestedValue(securityContext, "windowsOptions", "hostProcess").(bool); hostProcess {
+			baseline = append(baseline, name+" sets windowsOptions.hostProcess")
+		}
+		if procMount := NestedString(securityContext, "procMount"); procMount != "" && procMount != "Default" {
+			baseline = append(baseline, fmt.Sprintf("%s sets procMount %s", name, procMount))
+		}
+		baseline = append(baseline, securityContextViolations(name, securityContext)...)
+		for _, p := range NestedSlice(container, "ports") {
+			port, _ := p.(map[string]interface{})
+			if hostPort := port["hostPort"]; hostPort != nil && fmt.Sprint(hostPort) != "0" {
+				baseline = append(baseline, fmt.Sprintf("%s uses hostPort %v", name, hostPort))
+			}
+		}
+
+		var dropsAll bool
+		for _, c := range NestedSlice(securityContext, "capabilities", "drop") {
+			if c == "ALL" {
+				dropsAll = true
+			}
+		}
+		for _, c := range NestedSlice(securityContext, "capabilities", "add") {
+			capability := strings.TrimPrefix(fmt.Sprint(c), "CAP_")
+			if !baselineCapabilities[capability] {
+				baseline = append(baseline, fmt.Sprintf("%s adds capability %s", name, capability))
+			} else if capability != "NET_BIND_SERVICE" {
+				restricted = append(restricted, fmt.Sprintf("%s adds capability %s", name, capability))
+			}
+		}
+
+		if escalation, ok := securityContext["allowPrivilegeEscalation"].(bool); !ok || escalation {
+			restricted = append(restricted, name+" does not set allowPrivilegeEscalation: false")
+		}
+		if !dropsAll {
+			restricted = append(restricted, name+" does not drop ALL capabilities")
+		}
+		if nonRoot, ok := securityContext["runAsNonRoot"].(bool); (ok && !nonRoot) || (!ok && !podNonRoot) {
+			restricted = append(restricted, name+" does not set runAsNonRoot: true")
+		}
+		if fmt.Sprint(securityContext["runAsUser"]) == "0" {
+			restricted = append(restricted, name+" runAsUser is 0")
+		}
+		seccomp := defaultString(NestedString(securityContext, "seccompProfile", "type"), podSeccomp)
+		if seccomp != "RuntimeDefault" && seccomp != "Localhost" {
+			restricted = append(restricted, name+" does not use a RuntimeDefault or Localhost seccomp profile")
+		}
+	}
+
+	return baseline, restricted
+}
+
+// securityContextViolations checks the seccomp, AppArmor and SELinux settings
+// a pod and its containers share for the baseline profile.
+func securityContextViolations(name string, securityContext map[string]interface{}) []string {
+	var violations []string
+	if NestedString(securityContext, "seccompProfile", "type") == "Unconfined" {
+		violations = append(violations, name+" seccomp profile is Unconfined")
+	}
+	if NestedString(securityContext, "appArmorProfile", "type") == "Unconfined" {
+		violations = append(violations, name+" AppArmor profile is Unconfined")
+	}
+	seLinux := NestedMap(securityContext, "seLinuxOptions")
+	if seLinuxType := NestedString(seLinux, "type"); seLinuxType != "" && !allowedSELinuxTypes[seLinuxType] {
+		violations = append(violations, fmt.Sprintf("%s SELinux type %s is not allowed", name, seLinuxType))
+	}
+	if NestedString(seLinux, "user") != "" || NestedString(seLinux, "role") != "" {
+		violations = append(violations, name+" sets a custom SELinux user or role")
+	}
+	return violations
+}
+
+// sortedMapKeys returns the keys of m in lexical order.
+func sortedMapKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,103 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+const podSecurityTestManifests = `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: hardened
+spec:
+  template:
+    spec:
+      securityContext:
+        runAsNonRoot: true
+        seccompProfile:
+          type: RuntimeDefault
+      volumes:
+        - name: cache
+          emptyDir: {}
+      containers:
+        - name: app
+          securityContext:
+            allowPrivilegeEscalation: false
+            capabilities:
+              drop: [ALL]
+              add: [NET_BIND_SERVICE]
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: default
+spec:
+  template:
+    spec:
+      containers:
+        - name: app
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: agent
+spec:
+  template:
+    metadata:
+      annotations:
+        container.apparmor.security.beta.kubernetes.io/agent: unconfined
+    spec:
+      hostNetwork: true
+      volumes:
+        - name: root
+          hostPath:
+            path: /
+      containers:
+        - name: agent
+          securityContext:
+            privileged: true
+            capabilities:
+              add: [SYS_ADMIN]
+`
+
+func TestPodSecurityRule(t *testing.T) {
+	manifests, err := ParseManifests(podSecurityTestManifests)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx := &Context{
+		Manifests: manifests,
+		Config:    models.Config{PodSecurity: models.PodSecurityConfig{Enabled: true}},
+	}
+	findings := podSecurityRule{}.Check(ctx)
+	if len(findings) != 1 || findings[0].Resource != "DaemonSet/agent" {
+		t.Fatalf("Expected only DaemonSet/agent to fail baseline, got %v", findings)
+	}
+	for _, violation := range []string{"hostNetwork is true", "AppArmor profile agent is unconfined", "volume root uses hostPath", "container agent is privileged", "adds capability SYS_ADMIN"} {
+		if !strings.Contains(findings[0].Message, violation) {
+			t.Errorf("Expected %q in %s", violation, findings[0].Message)
+		}
+	}
+
+	ctx.Config.PodSecurity.Level = "restricted"
+	findings = podSecurityRule{}.Check(ctx)
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 findings at restricted, got %d: %v", len(findings), findings)
+	}
+	if findings[0].Resource != "Deployment/default" || !strings.HasPrefix(findings[0].Message, "satisfies only the baseline profile") {
+		t.Errorf("Expected Deployment/default to satisfy baseline only, got %v", findings[0])
+	}
+	if !strings.HasPrefix(findings[1].Message, "satisfies only the privileged profile") {
+		t.Errorf("Expected DaemonSet/agent to satisfy privileged only, got %s", findings[1].Message)
+	}
+
+	ctx.Config.PodSecurity.Level = "strict"
+	findings = podSecurityRule{}.Check(ctx)
+	if len(findings) != 1 || !strings.Contains(findings[0].Message, "unknown pod security level") {
+		t.Errorf("Expected an unknown level finding, got %v", findings)
+	}
+}
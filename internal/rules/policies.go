@@ -0,0 +1,161 @@
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+	"github.com/open-policy-agent/opa/v1/rego"
+)
+
+func init() {
+	Register(policyRule{})
+}
+
+// policyQuery evaluates the deny and warn rules of a package against each
+// manifest of the input array in turn, the way conftest does.
+const policyQuery = `[r | doc := input[i]; r := {"index": i, "deny": [msg | data.%[1]s.deny[msg] with input as doc], "warn": [msg | data.%[1]s.warn[msg] with input as doc]}]`
+
+// policyRule evaluates Rego policies against the rendered manifests with the
+// embedded OPA. Every message of a deny rule is an error and every message of
+// a warn rule a warning for the manifest that produced it.
+type policyRule struct{}
+
+func (policyRule) ID() string { return "policy" }
+
+func (policyRule) Enabled(config *models.Config) bool {
+	return len(config.Policies.Dirs) > 0
+}
+
+func (r policyRule) Check(ctx *Context) []models.Finding {
+	if len(ctx.Manifests) == 0 {
+		return nil
+	}
+	namespace := defaultString(ctx.Config.Policies.Namespace, "main")
+
+	results, err := evaluatePolicies(ctx.Config.Policies.Dirs, namespace, ctx.Manifests)
+	if err != nil {
+		return []models.Finding{{
+			RuleID:   r.ID(),
+			Severity: models.SeverityError,
+			Message:  fmt.Sprintf("error evaluating policies: %v", err),
+		}}
+	}
+
+	var findings []models.Finding
+	for _, result := range results {
+		if result.Index < 0 || result.Index >= len(ctx.Manifests) {
+			continue
+		}
+		m := ctx.Manifests[result.Index]
+		for _, msg := range result.Deny {
+			findings = append(findings, newFinding(r.ID(), models.SeverityError, m, "%s", policyMessage(msg)))
+		}
+		for _, msg := range result.Warn {
+			findings = append(findings, newFinding(r.ID(), models.SeverityWarning, m, "%s", policyMessage(msg)))
+		}
+	}
+	return findings
+}
+
+// policyResult holds the messages the policies produced for one manifest.
+type policyResult struct {
+	Index int           `json:"index"`
+	Deny  []interface{} `json:"deny"`
+	Warn  []interface{} `json:"warn"`
+}
+
+// policyCache holds the prepared queries by policy dirs and namespace, so the
+// policies are loaded and compiled once per scan rather than per chart.
+var policyCache = struct {
+	sync.Mutex
+	queries map[string]preparedPolicies
+}{queries: make(map[string]preparedPolicies)}
+
+type preparedPolicies struct {
+	query rego.PreparedEvalQuery
+	err   error
+}
+
+// preparePolicies loads and compiles the policies and data documents below
+// dirs, and the query for the deny and warn rules of namespace. The prepared
+// query is safe for concurrent evaluation.
+func preparePolicies(dirs []string, namespace string) (rego.PreparedEvalQuery, error) {
+	key := strings.Join(dirs, "\x00") + "\x00" + namespace
+	policyCache.Lock()
+	defer policyCache.Unlock()
+	if cached, ok := policyCache.queries[key]; ok {
+		return cached.query, cached.err
+	}
+
+	query, err := rego.New(
+		rego.Query(fmt.Sprintf(policyQuery, namespace)),
+		rego.Load(dirs, nil),
+	).PrepareForEval(context.Background())
+	policyCache.queries[key] = preparedPolicies{query: query, err: err}
+	return query, err
+}
+
+// ResetPolicies discards the prepared policies, so that the next scan loads
+// them again. Watch calls it before each scan to pick up edited policies.
+func ResetPolicies() {
+	policyCache.Lock()
+	defer policyCache.Unlock()
+	clear(policyCache.queries)
+}
+
+// CheckPolicies reports an error if the policies and data documents below
+// dirs do not load or compile, so chartscan doctor can tell before a scan.
+func CheckPolicies(dirs []string) error {
+	_, err := preparePolicies(dirs, "main")
+	return err
+}
+
+// evaluatePolicies evaluates the policies below dirs once for all manifests
+// of a chart, with OPA embedded as a library.
+func evaluatePolicies(dirs []string, namespace string, manifests []Manifest) ([]policyResult, error) {
+	query, err := preparePolicies(dirs, namespace)
+	if err != nil {
+		return nil, err
+	}
+	input := make([]interface{}, len(manifests))
+	for i, m := range manifests {
+		input[i] = m.Object
+	}
+	resultSet, err := query.Eval(context.Background(), rego.EvalInput(input))
+	if err != nil {
+		return nil, err
+	}
+	if len(resultSet) == 0 || len(resultSet[0].Expressions) == 0 {
+		return nil, nil
+	}
+
+	// The value holds OPA's generic JSON types; a round trip decodes it.
+	data, err := json.Marshal(resultSet[0].Expressions[0].Value)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding policy results: %v", err)
+	}
+	var results []policyResult
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("error decoding policy results: %v", err)
+	}
+	return results, nil
+}
+
+// policyMessage turns a deny or warn message into text. Besides strings,
+// policies may return objects with a msg field, as conftest accepts.
+func policyMessage(msg interface{}) string {
+	switch msg := msg.(type) {
+	case string:
+		return msg
+	case map[string]interface{}:
+		if text, ok := msg["msg"].(string); ok {
+			return text
+		}
+	}
+	data, _ := json.Marshal(msg)
+	return string(data)
+}
@@ -0,0 +1,126 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// writePolicies writes files, by name, to a new directory and returns it.
+func writePolicies(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestPolicyRule(t *testing.T) {
+	// The policy denies Deployments running as root and warns about
+	// Services without the label required by the data document.
+	policies := writePolicies(t, map[string]string{"kubernetes.rego": `package kubernetes
+
+deny contains msg if {
+	input.kind == "Deployment"
+	not input.spec.template.spec.securityContext.runAsNonRoot
+	msg := "containers must not run as root"
+}
+
+warn contains {"msg": msg, "details": {}} if {
+	input.kind == "Service"
+	not input.metadata.labels[data.labels.required]
+	msg := sprintf("Service %s has no %s label", [input.metadata.name, data.labels.required])
+}
+`})
+	data := writePolicies(t, map[string]string{"labels.yaml": "labels:\n  required: app\n"})
+
+	manifests, err := ParseManifests(`---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	config := models.Config{Policies: models.PoliciesConfig{Dirs: []string{policies, data}, Namespace: "kubernetes"}}
+	findings := policyRule{}.Check(&Context{Manifests: manifests, Config: config})
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %d: %v", len(findings), findings)
+	}
+	if findings[0].Resource != "Deployment/web" || findings[0].Severity != models.SeverityError || findings[0].Message != "containers must not run as root" {
+		t.Errorf("Expected the deny message for Deployment/web, got %v", findings[0])
+	}
+	if findings[1].Resource != "Service/web" || findings[1].Severity != models.SeverityWarning || findings[1].Message != "Service web has no app label" {
+		t.Errorf("Expected the warn message for Service/web, got %v", findings[1])
+	}
+
+	config.Policies.Namespace = "other"
+	if findings := (policyRule{}).Check(&Context{Manifests: manifests, Config: config}); len(findings) != 0 {
+		t.Errorf("Expected no findings for a package without rules, got %v", findings)
+	}
+}
+
+func TestPolicyRuleErrors(t *testing.T) {
+	policies := writePolicies(t, map[string]string{"broken.rego": "package main\n\ndeny contains msg if {\n"})
+
+	manifests, err := ParseManifests("apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx := &Context{Manifests: manifests, Config: models.Config{Policies: models.PoliciesConfig{Dirs: []string{policies}}}}
+
+	findings := policyRule{}.Check(ctx)
+	if len(findings) != 1 || !strings.Contains(findings[0].Message, "rego_parse_error") {
+		t.Fatalf("Expected the policy parse error, got %v", findings)
+	}
+	if err := CheckPolicies([]string{policies}); err == nil || !strings.Contains(err.Error(), "rego_parse_error") {
+		t.Errorf("Expected CheckPolicies to report the parse error, got %v", err)
+	}
+	if err := CheckPolicies([]string{filepath.Join(policies, "missing")}); err == nil {
+		t.Errorf("Expected CheckPolicies to report a missing directory")
+	}
+}
+
+func TestPreparePoliciesCache(t *testing.T) {
+	policies := writePolicies(t, map[string]string{"policy.rego": "package main\n\ndeny contains \"first\" if true\n"})
+	manifests, err := ParseManifests("apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	config := models.Config{Policies: models.PoliciesConfig{Dirs: []string{policies}}}
+	check := func() string {
+		findings := policyRule{}.Check(&Context{Manifests: manifests, Config: config})
+		if len(findings) != 1 {
+			t.Fatalf("Expected 1 finding, got %v", findings)
+		}
+		return findings[0].Message
+	}
+
+	if message := check(); message != "first" {
+		t.Fatalf("Expected 'first', got '%s'", message)
+	}
+	if err := os.WriteFile(filepath.Join(policies, "policy.rego"), []byte("package main\n\ndeny contains \"second\" if true\n"), 0644); err != nil {
+		t.Fatalf("Failed to write policy: %v", err)
+	}
+	// Later charts of the same scan reuse the prepared policies.
+	if message := check(); message != "first" {
+		t.Errorf("Expected the prepared policies to be reused, got '%s'", message)
+	}
+	ResetPolicies()
+	if message := check(); message != "second" {
+		t.Errorf("Expected the edited policies after a reset, got '%s'", message)
+	}
+}
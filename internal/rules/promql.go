@@ -0,0 +1,418 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// promQLFunctions lists the functions known to PromQL. Calls to other names
+// are reported, since a typo there only surfaces once Prometheus loads the rule.
+var promQLFunctions = map[string]bool{
+	"abs": true, "absent": true, "absent_over_time": true, "acos": true, "acosh": true, "asin": true,
+	"asinh": true, "atan": true, "atanh": true, "avg_over_time": true, "ceil": true, "changes": true,
+	"clamp": true, "clamp_max": true, "clamp_min": true, "cos": true, "cosh": true, "count_over_time": true,
+	"day_of_month": true, "day_of_week": true, "day_of_year": true, "days_in_month": true, "deg": true,
+	"delta": true, "deriv": true, "exp": true, "floor": true, "histogram_avg": true, "histogram_count": true,
+	"histogram_fraction": true, "histogram_quantile": true, "histogram_stddev": true, "histogram_stdvar": true,
+	"histogram_sum": true, "holt_winters": true, "double_exponential_smoothing": true, "hour": true,
+	"idelta": true, "increase": true, "irate": true, "label_join": true, "label_replace": true, "last_over_time": true,
+	"ln": true, "log10": true, "log2": true, "mad_over_time": true, "max_over_time": true, "min_over_time": true,
+	"minute": true, "month": true, "pi": true, "predict_linear": true, "present_over_time": true,
+	"quantile_over_time": true, "rad": true, "rate": true, "resets": true, "round": true, "scalar": true,
+	"sgn": true, "sin": true, "sinh": true, "sort": true, "sort_by_label": true, "sort_by_label_desc": true,
+	"sort_desc": true, "sqrt": true, "stddev_over_time": true, "stdvar_over_time": true, "sum_over_time": true,
+	"tan": true, "tanh": true, "time": true, "timestamp": true, "vector": true, "year": true,
+}
+
+// promQLAggregations lists the aggregation operators, which accept by/without
+// modifiers before or after their arguments.
+var promQLAggregations = map[string]bool{
+	"sum": true, "min": true, "max": true, "avg": true, "group": true, "stddev": true, "stdvar": true,
+	"count": true, "count_values": true, "bottomk": true, "topk": true, "quantile": true,
+	"limitk": true, "limit_ratio": true,
+}
+
+// promQLBinaryOperators lists the operators valid between two expressions.
+var promQLBinaryOperators = map[string]bool{
+	"+": true, "-": true, "*": true, "/": true, "%": true, "^": true,
+	"==": true, "!=": true, ">": true, "<": true, ">=": true, "<=": true,
+	"and": true, "or": true, "unless": true, "atan2": true,
+}
+
+var promQLDurationRegex = regexp.MustCompile(`^([0-9]+(ms|s|m|h|d|w|y))+$`)
+
+// promQLToken is a lexical token of a PromQL expression.
+type promQLToken struct {
+	kind  string // ident, number, string, duration, op, punct
+	value string
+	pos   int
+}
+
+// ValidatePromQL checks expr for PromQL syntax errors: unbalanced brackets,
+// unterminated strings, malformed selectors and ranges, unknown functions and
+// dangling operators. It is a syntax check only; metric names are not resolved.
+func ValidatePromQL(expr string) error {
+	if strings.TrimSpace(expr) == "" {
+		return fmt.Errorf("empty expression")
+	}
+	if strings.Contains(expr, "{{") {
+		return fmt.Errorf("unrendered template action in expression")
+	}
+
+	tokens, err := lexPromQL(expr)
+	if err != nil {
+		return err
+	}
+
+	p := &promQLParser{tokens: tokens}
+	if err := p.parseExpr(); err != nil {
+		return err
+	}
+	if tok := p.peek(); tok != nil {
+		return fmt.Errorf("unexpected %q at position %d", tok.value, tok.pos)
+	}
+	return nil
+}
+
+// lexPromQL splits expr into tokens.
+func lexPromQL(expr string) ([]promQLToken, error) {
+	var tokens []promQLToken
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '#':
+			for i < len(expr) && expr[i] != '\n' {
+				i++
+			}
+		case c == '"' || c == '\'' || c == '`':
+			end := i + 1
+			for end < len(expr) && rune(expr[end]) != c {
+				if expr[end] == '\\' && c != '`' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expr) {
+				return nil, fmt.Errorf("unterminated string starting at position %d", i)
+			}
+			tokens = append(tokens, promQLToken{kind: "string", value: expr[i : end+1], pos: i})
+			i = end + 1
+		case unicode.IsDigit(c) || (c == '.' && i+1 < len(expr) && unicode.IsDigit(rune(expr[i+1]))):
+			end := i
+			for end < len(expr) && (unicode.IsLetter(rune(expr[end])) || unicode.IsDigit(rune(expr[end])) || expr[end] == '.' ||
+				((expr[end] == '+' || expr[end] == '-') && end > i && (expr[end-1] == 'e' || expr[end-1] == 'E'))) {
+				end++
+			}
+			value := expr[i:end]
+			kind := "number"
+			if promQLDurationRegex.MatchString(value) {
+				kind = "duration"
+			} else if !isPromQLNumber(value) {
+				return nil, fmt.Errorf("invalid number or duration %q at position %d", value, i)
+			}
+			tokens = append(tokens, promQLToken{kind: kind, value: value, pos: i})
+			i = end
+		case unicode.IsLetter(c) || c == '_':
+			// Colons may appear inside but not start an identifier so that
+			// subqueries such as [1h:5m] lex correctly.
+			end := i
+			for end < len(expr) && (unicode.IsLetter(rune(expr[end])) || unicode.IsDigit(rune(expr[end])) || expr[end] == '_' || expr[end] == ':') {
+				end++
+			}
+			tokens = append(tokens, promQLToken{kind: "ident", value: expr[i:end], pos: i})
+			i = end
+		default:
+			if i+1 < len(expr) {
+				if two := expr[i : i+2]; two == "==" || two == "!=" || two == ">=" || two == "<=" || two == "=~" || two == "!~" {
+					tokens = append(tokens, promQLToken{kind: "op", value: two, pos: i})
+					i += 2
+					continue
+				}
+			}
+			if strings.ContainsRune("+-*/%^<>=", c) {
+				tokens = append(tokens, promQLToken{kind: "op", value: string(c), pos: i})
+			} else if strings.ContainsRune("(){}[],:@", c) {
+				tokens = append(tokens, promQLToken{kind: "punct", value: string(c), pos: i})
+			} else {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+// isPromQLNumber reports whether value is a valid PromQL number literal.
+func isPromQLNumber(value string) bool {
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return true
+	}
+	_, err := strconv.ParseInt(value, 0, 64)
+	return err == nil
+}
+
+// promQLParser is a recursive-descent parser over PromQL tokens that only
+// validates structure.
+type promQLParser struct {
+	tokens []promQLToken
+	pos    int
+}
+
+func (p *promQLParser) peek() *promQLToken {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+func (p *promQLParser) next() *promQLToken {
+	tok := p.peek()
+	if tok != nil {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *promQLParser) is(value string) bool {
+	tok := p.peek()
+	return tok != nil && tok.kind != "string" && tok.value == value
+}
+
+func (p *promQLParser) expect(value string) error {
+	tok := p.next()
+	if tok == nil {
+		return fmt.Errorf("expected %q but expression ended", value)
+	}
+	if tok.value != value || tok.kind == "string" {
+		return fmt.Errorf("expected %q but found %q at position %d", value, tok.value, tok.pos)
+	}
+	return nil
+}
+
+// parseExpr parses unary expressions joined by binary operators with their
+// optional bool, on/ignoring and group_left/group_right modifiers.
+func (p *promQLParser) parseExpr() error {
+	if err := p.parseUnary(); err != nil {
+		return err
+	}
+	for {
+		tok := p.peek()
+		if tok == nil || tok.kind == "string" || !promQLBinaryOperators[tok.value] {
+			return nil
+		}
+		p.next()
+		if p.is("bool") {
+			p.next()
+		}
+		if p.is("on") || p.is("ignoring") {
+			p.next()
+			if err := p.parseLabelList(); err != nil {
+				return err
+			}
+			if p.is("group_left") || p.is("group_right") {
+				p.next()
+				if p.is("(") {
+					if err := p.parseLabelList(); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		if p.peek() == nil {
+			return fmt.Errorf("binary operator %q at position %d has no right-hand side", tok.value, tok.pos)
+		}
+		if err := p.parseUnary(); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *promQLParser) parseUnary() error {
+	if p.is("-") || p.is("+") {
+		p.next()
+		return p.parseUnary()
+	}
+	if err := p.parsePrimary(); err != nil {
+		return err
+	}
+	return p.parsePostfix()
+}
+
+// parsePostfix parses range/subquery brackets and offset/@ modifiers.
+func (p *promQLParser) parsePostfix() error {
+	for {
+		switch {
+		case p.is("["):
+			p.next()
+			if tok := p.next(); tok == nil || tok.kind != "duration" {
+				return fmt.Errorf("range selector requires a duration like [5m]")
+			}
+			if p.is(":") {
+				p.next()
+				if tok := p.peek(); tok != nil && tok.kind == "duration" {
+					p.next()
+				}
+			}
+			if err := p.expect("]"); err != nil {
+				return err
+			}
+		case p.is("offset"):
+			p.next()
+			if p.is("-") {
+				p.next()
+			}
+			if tok := p.next(); tok == nil || tok.kind != "duration" {
+				return fmt.Errorf("offset requires a duration")
+			}
+		case p.is("@"):
+			p.next()
+			tok := p.next()
+			if tok == nil {
+				return fmt.Errorf("@ modifier requires a timestamp")
+			}
+			if tok.value == "start" || tok.value == "end" {
+				if err := p.expect("("); err != nil {
+					return err
+				}
+				if err := p.expect(")"); err != nil {
+					return err
+				}
+			} else if tok.kind != "number" {
+				return fmt.Errorf("@ modifier requires a timestamp, found %q", tok.value)
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+func (p *promQLParser) parsePrimary() error {
+	tok := p.peek()
+	if tok == nil {
+		return fmt.Errorf("unexpected end of expression")
+	}
+
+	switch {
+	case tok.kind == "number" || tok.kind == "string":
+		p.next()
+		return nil
+	case tok.value == "(" && tok.kind == "punct":
+		p.next()
+		if err := p.parseExpr(); err != nil {
+			return err
+		}
+		return p.expect(")")
+	case tok.value == "{" && tok.kind == "punct":
+		return p.parseMatchers()
+	case tok.kind == "ident":
+		p.next()
+		if promQLAggregations[tok.value] && (p.is("(") || p.is("by") || p.is("without")) {
+			return p.parseAggregation()
+		}
+		if p.is("(") {
+			if !promQLFunctions[tok.value] {
+				return fmt.Errorf("unknown function %q at position %d", tok.value, tok.pos)
+			}
+			return p.parseArgs()
+		}
+		if p.is("{") {
+			return p.parseMatchers()
+		}
+		return nil
+	default:
+		return fmt.Errorf("unexpected %q at position %d", tok.value, tok.pos)
+	}
+}
+
+func (p *promQLParser) parseAggregation() error {
+	if p.is("by") || p.is("without") {
+		p.next()
+		if err := p.parseLabelList(); err != nil {
+			return err
+		}
+	}
+	if err := p.parseArgs(); err != nil {
+		return err
+	}
+	if p.is("by") || p.is("without") {
+		p.next()
+		return p.parseLabelList()
+	}
+	return nil
+}
+
+// parseArgs parses a parenthesized, comma-separated list of expressions.
+func (p *promQLParser) parseArgs() error {
+	if err := p.expect("("); err != nil {
+		return err
+	}
+	if p.is(")") {
+		p.next()
+		return nil
+	}
+	for {
+		if err := p.parseExpr(); err != nil {
+			return err
+		}
+		if p.is(",") {
+			p.next()
+			continue
+		}
+		return p.expect(")")
+	}
+}
+
+// parseLabelList parses a parenthesized list of label names.
+func (p *promQLParser) parseLabelList() error {
+	if err := p.expect("("); err != nil {
+		return err
+	}
+	for !p.is(")") {
+		tok := p.next()
+		if tok == nil || tok.kind != "ident" {
+			return fmt.Errorf("expected label name in label list")
+		}
+		if !p.is(",") {
+			break
+		}
+		p.next()
+	}
+	return p.expect(")")
+}
+
+// parseMatchers parses a {label="value", ...} selector.
+func (p *promQLParser) parseMatchers() error {
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for !p.is("}") {
+		name := p.next()
+		if name == nil || (name.kind != "ident" && name.kind != "string") {
+			return fmt.Errorf("expected label name in selector")
+		}
+		if p.is(",") || p.is("}") {
+			// Bare metric name given as quoted string inside braces.
+			if p.is(",") {
+				p.next()
+			}
+			continue
+		}
+		op := p.next()
+		if op == nil || (op.value != "=" && op.value != "!=" && op.value != "=~" && op.value != "!~") {
+			return fmt.Errorf("expected label matcher operator after %q", name.value)
+		}
+		if value := p.next(); value == nil || value.kind != "string" {
+			return fmt.Errorf("label matcher for %q requires a quoted value", name.value)
+		}
+		if !p.is(",") {
+			break
+		}
+		p.next()
+	}
+	return p.expect("}")
+}
@@ -0,0 +1,40 @@
+package rules
+
+import "testing"
+
+func TestValidatePromQL(t *testing.T) {
+	valid := []string{
+		`up`,
+		`up == 0`,
+		`rate(http_requests_total{job="api", code=~"5.."}[5m]) > 0.1`,
+		`sum by (job) (rate(http_requests_total[5m])) / on(job) group_left sum without (code) (rate(http_requests_total[5m]))`,
+		`histogram_quantile(0.99, sum(rate(http_request_duration_seconds_bucket[5m])) by (le))`,
+		`absent(up{job="api"} offset 1h)`,
+		`max_over_time(up[1h:5m]) < bool 1`,
+		`topk(3, count_values("version", build_info))`,
+		`-1 * (node_memory_MemAvailable_bytes / node_memory_MemTotal_bytes) unless on() vector(0)`,
+		`{__name__="up"}`,
+	}
+	for _, expr := range valid {
+		if err := ValidatePromQL(expr); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", expr, err)
+		}
+	}
+
+	invalid := []string{
+		``,
+		`rate(http_requests_total[5m]`,
+		`sum(up) by job`,
+		`up{job="api"`,
+		`up{job=api}`,
+		`up >`,
+		`rate(up[5x])`,
+		`unknown_function(up)`,
+		`up up`,
+	}
+	for _, expr := range invalid {
+		if err := ValidatePromQL(expr); err == nil {
+			t.Errorf("Expected %q to be invalid", expr)
+		}
+	}
+}
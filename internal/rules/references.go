@@ -0,0 +1,160 @@
+package rules
+
+import (
+	"path"
+	"sort"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func init() {
+	Register(resourceReferencesRule{})
+}
+
+// resourceReferencesRule flags pod specs referencing Secrets or ConfigMaps that
+// are neither rendered by the chart nor declared as externally managed. Such
+// references leave pods stuck in CreateContainerConfigError.
+type resourceReferencesRule struct{}
+
+func (resourceReferencesRule) ID() string { return "missing-resource-reference" }
+
+func (resourceReferencesRule) Enabled(config *models.Config) bool {
+	return config.ResourceReferences.Enabled
+}
+
+func (r resourceReferencesRule) Check(ctx *Context) []models.Finding {
+	config := ctx.Config.ResourceReferences
+	secrets, configMaps := renderedConfigSources(ctx.Manifests)
+
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		podSpec := PodSpec(m)
+		if podSpec == nil {
+			continue
+		}
+
+		refs := podSpecReferences(podSpec)
+		for _, name := range sortedKeys(refs.secrets) {
+			if !secrets[name] && !matchesAny(name, config.ExternalSecrets) {
+				findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+					"references Secret %q via %s, which is not rendered by the chart nor listed in externalSecrets", name, refs.secrets[name]))
+			}
+		}
+		for _, name := range sortedKeys(refs.configMaps) {
+			if !configMaps[name] && !matchesAny(name, config.ExternalConfigMaps) {
+				findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+					"references ConfigMap %q via %s, which is not rendered by the chart nor listed in externalConfigMaps", name, refs.configMaps[name]))
+			}
+		}
+	}
+
+	return findings
+}
+
+// renderedConfigSources returns the names of Secrets and ConfigMaps produced by
+// the chart, including Secrets created by ExternalSecret and SealedSecret
+// resources.
+func renderedConfigSources(manifests []Manifest) (map[string]bool, map[string]bool) {
+	secrets := make(map[string]bool)
+	configMaps := make(map[string]bool)
+
+	for _, m := range manifests {
+		switch m.Kind {
+		case "Secret":
+			secrets[m.Name] = true
+		case "ConfigMap":
+			configMaps[m.Name] = true
+		case "ExternalSecret":
+			if target := NestedString(m.Object, "spec", "target", "name"); target != "" {
+				secrets[target] = true
+			} else {
+				secrets[m.Name] = true
+			}
+		case "SealedSecret":
+			if target := NestedString(m.Object, "spec", "template", "metadata", "name"); target != "" {
+				secrets[target] = true
+			} else {
+				secrets[m.Name] = true
+			}
+		}
+	}
+
+	return secrets, configMaps
+}
+
+// configReferences maps referenced Secret and ConfigMap names to the field
+// that references them first.
+type configReferences struct {
+	secrets    map[string]string
+	configMaps map[string]string
+}
+
+// podSpecReferences collects the mandatory Secret and ConfigMap references of
+// a pod spec. References marked optional are ignored.
+func podSpecReferences(podSpec map[string]interface{}) configReferences {
+	refs := configReferences{secrets: map[string]string{}, configMaps: map[string]string{}}
+	add := func(target map[string]string, source map[string]interface{}, field, via string) {
+		name := NestedString(source, field)
+		if name == "" {
+			return
+		}
+		if optional, _ := source["optional"].(bool); optional {
+			return
+		}
+		if _, seen := target[name]; !seen {
+			target[name] = via
+		}
+	}
+
+	for _, s := range NestedSlice(podSpec, "imagePullSecrets") {
+		secret, _ := s.(map[string]interface{})
+		add(refs.secrets, secret, "name", "imagePullSecrets")
+	}
+
+	for _, v := range NestedSlice(podSpec, "volumes") {
+		volume, _ := v.(map[string]interface{})
+		add(refs.secrets, NestedMap(volume, "secret"), "secretName", "volume "+NestedString(volume, "name"))
+		add(refs.configMaps, NestedMap(volume, "configMap"), "name", "volume "+NestedString(volume, "name"))
+		for _, p := range NestedSlice(volume, "projected", "sources") {
+			projection, _ := p.(map[string]interface{})
+			add(refs.secrets, NestedMap(projection, "secret"), "name", "volume "+NestedString(volume, "name"))
+			add(refs.configMaps, NestedMap(projection, "configMap"), "name", "volume "+NestedString(volume, "name"))
+		}
+	}
+
+	for _, container := range Containers(podSpec, true) {
+		via := "container " + NestedString(container, "name")
+		for _, e := range NestedSlice(container, "envFrom") {
+			envFrom, _ := e.(map[string]interface{})
+			add(refs.secrets, NestedMap(envFrom, "secretRef"), "name", via+" envFrom")
+			add(refs.configMaps, NestedMap(envFrom, "configMapRef"), "name", via+" envFrom")
+		}
+		for _, e := range NestedSlice(container, "env") {
+			env, _ := e.(map[string]interface{})
+			add(refs.secrets, NestedMap(env, "valueFrom", "secretKeyRef"), "name", via+" env "+NestedString(env, "name"))
+			add(refs.configMaps, NestedMap(env, "valueFrom", "configMapKeyRef"), "name", via+" env "+NestedString(env, "name"))
+		}
+	}
+
+	return refs
+}
+
+// matchesAny reports whether name matches one of the shell patterns.
+func matchesAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns the keys of m in lexical order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,81 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestResourceReferencesRule(t *testing.T) {
+	manifests, err := ParseManifests(`---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web-config
+---
+apiVersion: external-secrets.io/v1beta1
+kind: ExternalSecret
+metadata:
+  name: web-db
+spec:
+  target:
+    name: web-db-credentials
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      imagePullSecrets:
+        - name: regcred
+      volumes:
+        - name: config
+          configMap:
+            name: web-config
+        - name: tls
+          secret:
+            secretName: web-tls
+        - name: extra
+          secret:
+            secretName: maybe-there
+            optional: true
+      containers:
+        - name: web
+          envFrom:
+            - secretRef:
+                name: web-db-credentials
+            - configMapRef:
+                name: shared-settings
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx := &Context{
+		Manifests: manifests,
+		Config: models.Config{ResourceReferences: models.ResourceReferencesConfig{
+			Enabled:         true,
+			ExternalSecrets: []string{"regcred"},
+		}},
+	}
+	findings := resourceReferencesRule{}.Check(ctx)
+
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %d: %v", len(findings), findings)
+	}
+	if !strings.Contains(findings[0].Message, `Secret "web-tls" via volume tls`) {
+		t.Errorf("Expected missing web-tls secret, got %s", findings[0].Message)
+	}
+	if !strings.Contains(findings[1].Message, `ConfigMap "shared-settings" via container web envFrom`) {
+		t.Errorf("Expected missing shared-settings configmap, got %s", findings[1].Message)
+	}
+
+	ctx.Config.ResourceReferences.ExternalSecrets = []string{"regcred", "web-*"}
+	ctx.Config.ResourceReferences.ExternalConfigMaps = []string{"shared-*"}
+	if findings := (resourceReferencesRule{}).Check(ctx); len(findings) != 0 {
+		t.Errorf("Expected allowlisted references to pass, got %v", findings)
+	}
+}
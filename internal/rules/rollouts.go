@@ -0,0 +1,185 @@
+package rules
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// defaultOnDeleteAnnotation is the annotation justifying an OnDelete
+// StatefulSet when rollouts.onDeleteAnnotation is not set.
+const defaultOnDeleteAnnotation = "chartscan.io/on-delete-reason"
+
+func init() {
+	Register(rolloutDowntimeRule{})
+	Register(statefulSetOnDeleteRule{})
+	Register(productionRecreateRule{})
+}
+
+// rolloutDowntimeRule flags Deployments whose rolling update settings take
+// every pod down at once, or that the API server rejects because they allow
+// neither surge nor unavailability.
+type rolloutDowntimeRule struct{}
+
+func (rolloutDowntimeRule) ID() string { return "rollout-downtime" }
+
+func (rolloutDowntimeRule) Enabled(config *models.Config) bool {
+	return config.Rollouts.Enabled
+}
+
+func (r rolloutDowntimeRule) Check(ctx *Context) []models.Finding {
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		if m.Kind != "Deployment" || defaultString(NestedString(m.Object, "spec", "strategy", "type"), "RollingUpdate") != "RollingUpdate" {
+			continue
+		}
+		rollingUpdate := NestedMap(m.Object, "spec", "strategy", "rollingUpdate")
+		maxUnavailable := defaultIntOrPercent(rollingUpdate["maxUnavailable"], "25%")
+		maxSurge := defaultIntOrPercent(rollingUpdate["maxSurge"], "25%")
+
+		// Without replicas, e.g. when an autoscaler owns them, only settings
+		// that cause downtime at any scale are reported.
+		replicas, known := manifestReplicas(m, ctx.Manifests)
+		if !known {
+			replicas = 100
+		}
+		unavailable, err := scaledIntOrPercent(maxUnavailable, replicas, false)
+		if err != nil {
+			findings = append(findings, newFinding(r.ID(), models.SeverityError, m, "invalid maxUnavailable: %v", err))
+			continue
+		}
+		surge, err := scaledIntOrPercent(maxSurge, replicas, true)
+		if err != nil {
+			findings = append(findings, newFinding(r.ID(), models.SeverityError, m, "invalid maxSurge: %v", err))
+			continue
+		}
+
+		switch {
+		case unavailable == 0 && surge == 0:
+			findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+				"maxSurge %s and maxUnavailable %s are both zero, so the rollout can never progress", maxSurge, maxUnavailable))
+		case replicas > 0 && unavailable >= replicas && (known || strings.HasSuffix(maxUnavailable, "%")):
+			findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+				"maxUnavailable %s lets a rolling update stop all %s at once; lower it or raise maxSurge with maxUnavailable 0",
+				maxUnavailable, pluralReplicas(replicas, known)))
+		}
+	}
+	return findings
+}
+
+// manifestReplicas returns the replicas of a workload: spec.replicas, or the
+// default of 1. known is false when an autoscaler rendered by the chart owns
+// the replicas or the field is not a number.
+func manifestReplicas(m Manifest, manifests []Manifest) (replicas int, known bool) {
+	value := NestedValue(m.Object, "spec", "replicas")
+	if value == nil {
+		for _, hpa := range manifests {
+			if hpa.Kind == "HorizontalPodAutoscaler" &&
+				NestedString(hpa.Object, "spec", "scaleTargetRef", "kind") == m.Kind &&
+				NestedString(hpa.Object, "spec", "scaleTargetRef", "name") == m.Name {
+				return 0, false
+			}
+		}
+		return 1, true
+	}
+	replicas, err := strconv.Atoi(fmt.Sprint(value))
+	return replicas, err == nil
+}
+
+// pluralReplicas describes a replica count for messages.
+func pluralReplicas(replicas int, known bool) string {
+	switch {
+	case !known:
+		return "replicas"
+	case replicas == 1:
+		return "1 replica"
+	default:
+		return fmt.Sprintf("%d replicas", replicas)
+	}
+}
+
+// defaultIntOrPercent renders an int-or-string field, falling back to
+// fallback when it is unset.
+func defaultIntOrPercent(value interface{}, fallback string) string {
+	if value == nil {
+		return fallback
+	}
+	return fmt.Sprint(value)
+}
+
+// scaledIntOrPercent resolves an absolute number or a percentage of total the
+// way the Deployment controller does: percentages of maxSurge round up and of
+// maxUnavailable round down.
+func scaledIntOrPercent(value string, total int, roundUp bool) (int, error) {
+	if !strings.HasSuffix(value, "%") {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("%q is neither a non-negative number nor a percentage", value)
+		}
+		return n, nil
+	}
+	percent, err := strconv.Atoi(strings.TrimSuffix(value, "%"))
+	if err != nil || percent < 0 {
+		return 0, fmt.Errorf("%q is not a valid percentage", value)
+	}
+	scaled := float64(percent) * float64(total) / 100
+	if roundUp {
+		return int(math.Ceil(scaled)), nil
+	}
+	return int(math.Floor(scaled)), nil
+}
+
+// statefulSetOnDeleteRule requires StatefulSets using the OnDelete update
+// strategy, which never rolls out template changes on its own, to explain why
+// in an annotation.
+type statefulSetOnDeleteRule struct{}
+
+func (statefulSetOnDeleteRule) ID() string { return "statefulset-ondelete" }
+
+func (statefulSetOnDeleteRule) Enabled(config *models.Config) bool {
+	return config.Rollouts.Enabled
+}
+
+func (r statefulSetOnDeleteRule) Check(ctx *Context) []models.Finding {
+	annotation := defaultString(ctx.Config.Rollouts.OnDeleteAnnotation, defaultOnDeleteAnnotation)
+
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		if m.Kind != "StatefulSet" || NestedString(m.Object, "spec", "updateStrategy", "type") != "OnDelete" {
+			continue
+		}
+		if reason := m.Annotations()[annotation]; reason != nil && fmt.Sprint(reason) != "" {
+			continue
+		}
+		findings = append(findings, newFinding(r.ID(), models.SeverityWarning, m,
+			"uses the OnDelete update strategy, so pods only pick up changes when deleted by hand; explain why in the %s annotation", annotation))
+	}
+	return findings
+}
+
+// productionRecreateRule flags single-replica Deployments with the Recreate
+// strategy in production, where every rollout is an outage.
+type productionRecreateRule struct{}
+
+func (productionRecreateRule) ID() string { return "production-recreate" }
+
+func (productionRecreateRule) Enabled(config *models.Config) bool {
+	return config.Rollouts.Enabled && config.Rollouts.Production
+}
+
+func (r productionRecreateRule) Check(ctx *Context) []models.Finding {
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		if m.Kind != "Deployment" || NestedString(m.Object, "spec", "strategy", "type") != "Recreate" {
+			continue
+		}
+		if replicas, known := manifestReplicas(m, ctx.Manifests); known && replicas <= 1 {
+			findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+				"runs %s with the Recreate strategy, so every rollout in production is an outage", pluralReplicas(replicas, true)))
+		}
+	}
+	return findings
+}
@@ -0,0 +1,117 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestRolloutRules(t *testing.T) {
+	manifests, err := ParseManifests(`---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3
+  strategy:
+    rollingUpdate:
+      maxUnavailable: 3
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  strategy:
+    rollingUpdate:
+      maxSurge: 0
+      maxUnavailable: 100%
+---
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: api
+spec:
+  scaleTargetRef: {apiVersion: apps/v1, kind: Deployment, name: api}
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: stuck
+spec:
+  replicas: 2
+  strategy:
+    rollingUpdate:
+      maxSurge: 0
+      maxUnavailable: 10%
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: defaults
+spec:
+  replicas: 4
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: singleton
+spec:
+  strategy:
+    type: Recreate
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  updateStrategy:
+    type: OnDelete
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: cache
+  annotations:
+    chartscan.io/on-delete-reason: nodes are drained manually
+spec:
+  updateStrategy:
+    type: OnDelete
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx := &Context{Manifests: manifests, Config: models.Config{Rollouts: models.RolloutsConfig{Enabled: true}}}
+
+	tests := []struct {
+		rule     Rule
+		expected []string
+	}{
+		{rolloutDowntimeRule{}, []string{
+			"Deployment/web: maxUnavailable 3 lets a rolling update stop all 3 replicas at once; lower it or raise maxSurge with maxUnavailable 0",
+			"Deployment/api: maxUnavailable 100% lets a rolling update stop all replicas at once; lower it or raise maxSurge with maxUnavailable 0",
+			"Deployment/stuck: maxSurge 0 and maxUnavailable 10% are both zero, so the rollout can never progress",
+		}},
+		{statefulSetOnDeleteRule{}, []string{
+			"StatefulSet/db: uses the OnDelete update strategy, so pods only pick up changes when deleted by hand; explain why in the chartscan.io/on-delete-reason annotation",
+		}},
+		{productionRecreateRule{}, []string{
+			"Deployment/singleton: runs 1 replica with the Recreate strategy, so every rollout in production is an outage",
+		}},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, finding := range test.rule.Check(ctx) {
+			got = append(got, finding.Resource+": "+finding.Message)
+		}
+		if strings.Join(got, "\n") != strings.Join(test.expected, "\n") {
+			t.Errorf("Expected %s findings:\n%s\ngot:\n%s", test.rule.ID(), strings.Join(test.expected, "\n"), strings.Join(got, "\n"))
+		}
+	}
+
+	if (productionRecreateRule{}).Enabled(&ctx.Config) {
+		t.Errorf("Expected production-recreate to only run in production")
+	}
+}
@@ -0,0 +1,394 @@
+package rules
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// Manifest is a single Kubernetes object from a chart's rendered output.
+type Manifest struct {
+	APIVersion string
+	Kind       string
+	Name       string
+	Namespace  string
+	// Source is the template that produced the object, taken from the
+	// "# Source:" comment emitted by helm template.
+	Source string
+	Object map[string]interface{}
+}
+
+// Resource returns the Kind/name identifier of the manifest.
+func (m Manifest) Resource() string {
+	return m.Kind + "/" + m.Name
+}
+
+// Annotations returns the metadata.annotations of the manifest.
+func (m Manifest) Annotations() map[string]interface{} {
+	return NestedMap(m.Object, "metadata", "annotations")
+}
+
+// Context is the input of a rule evaluation for a single chart.
+type Context struct {
+	ChartPath string
+	// RepoRoot is the root of the Git repository containing the chart, or ""
+	// when the chart is not inside a repository.
+	RepoRoot  string
+	Manifests []Manifest
+	// ValueReferences are the .Values references found in the chart's
+	// templates.
+	ValueReferences []models.ValueReference
+	Config          models.Config
+}
+
+// Options returns the options of rule id from the rules section of the
+// configuration, or nil when none are set.
+func (ctx *Context) Options(id string) map[string]interface{} {
+	return ctx.Config.Rules[id].Options
+}
+
+// Rule checks the rendered manifests of a chart.
+type Rule interface {
+	// ID uniquely identifies the rule in findings and configuration.
+	ID() string
+	// Enabled reports whether the rule applies under the given configuration.
+	Enabled(config *models.Config) bool
+	// Check evaluates the rule and returns its findings.
+	Check(ctx *Context) []models.Finding
+}
+
+var registered []Rule
+
+// Register adds a rule to the set evaluated by Run. It is meant to be called
+// from init functions of the files defining rules.
+func Register(rule Rule) {
+	registered = append(registered, rule)
+}
+
+// IDs returns the IDs of all registered rules in registration order.
+func IDs() []string {
+	ids := make([]string, 0, len(registered))
+	for _, rule := range registered {
+		ids = append(ids, rule.ID())
+	}
+	return ids
+}
+
+var stages []string
+
+// RegisterStage declares the IDs of findings reported by scan stages rather
+// than by registered rules, such as helm lint problems, so that the rules
+// section of the configuration accepts them.
+func RegisterStage(ids ...string) {
+	stages = append(stages, ids...)
+}
+
+// Known reports whether id names a registered rule or a scan stage.
+func Known(id string) bool {
+	return slices.Contains(IDs(), id) || slices.Contains(stages, id)
+}
+
+// AnyEnabled reports whether at least one rule applies under config, i.e.
+// whether the chart needs to be rendered for manifest checks at all.
+func AnyEnabled(config *models.Config) bool {
+	for _, rule := range registered {
+		if enabled(rule, config) {
+			return true
+		}
+	}
+	return false
+}
+
+// EnabledIDs returns the IDs of the rules that apply under config, in
+// registration order.
+func EnabledIDs(config *models.Config) []string {
+	var ids []string
+	for _, rule := range registered {
+		if enabled(rule, config) {
+			ids = append(ids, rule.ID())
+		}
+	}
+	return ids
+}
+
+// SkipAnnotation exempts the annotated resource from the manifest rules it
+// lists, separated by commas, or from all of them with "*".
+const SkipAnnotation = "chartscan.io/skip"
+
+// Run evaluates all enabled rules against ctx and returns their findings,
+// with severities overridden by the rules section of the configuration.
+// Findings of resources that skip their rule through SkipAnnotation are
+// returned separately.
+func Run(ctx *Context) ([]models.Finding, []models.Finding) {
+	findings := validateRuleOverrides(&ctx.Config)
+	skips, skipFindings := resourceSkips(ctx.Manifests)
+	findings = append(findings, skipFindings...)
+
+	var skipped []models.Finding
+	for _, rule := range registered {
+		if !enabled(rule, &ctx.Config) {
+			continue
+		}
+		for _, finding := range ApplyOverrides(&ctx.Config, rule.Check(ctx)) {
+			if ruleIDs := skips[finding.File+"\x00"+finding.Resource]; ruleIDs["*"] || ruleIDs[finding.RuleID] {
+				skipped = append(skipped, finding)
+				continue
+			}
+			findings = append(findings, finding)
+		}
+	}
+	return findings, skipped
+}
+
+// resourceSkips returns the rule IDs listed in the SkipAnnotation of each
+// manifest, keyed by template and resource like the findings of the manifest,
+// and a warning for every listed rule that does not exist.
+func resourceSkips(manifests []Manifest) (map[string]map[string]bool, []models.Finding) {
+	skips := make(map[string]map[string]bool)
+	var findings []models.Finding
+	for _, m := range manifests {
+		annotation, ok := m.Annotations()[SkipAnnotation].(string)
+		if !ok {
+			continue
+		}
+		key := m.Source + "\x00" + m.Resource()
+		if skips[key] == nil {
+			skips[key] = make(map[string]bool)
+		}
+		for _, id := range strings.Split(annotation, ",") {
+			id = strings.TrimSpace(id)
+			if id == "" {
+				continue
+			}
+			if id != "*" && !slices.Contains(IDs(), id) {
+				findings = append(findings, newFinding(id, models.SeverityWarning, m, "%s: unknown rule %q", SkipAnnotation, id))
+				continue
+			}
+			skips[key][id] = true
+		}
+	}
+	return skips, findings
+}
+
+// enabled reports whether rule runs under config. Setting enabled: false in
+// the rules section turns off any rule.
+func enabled(rule Rule, config *models.Config) bool {
+	if override := config.Rules[rule.ID()].Enabled; override != nil && !*override {
+		return false
+	}
+	return rule.Enabled(config)
+}
+
+// ApplyOverrides applies the rules section of config to findings of any rule
+// or registered scan stage: findings of rules turned off with enabled: false are dropped
+// and the severity of the others is replaced where one is configured.
+func ApplyOverrides(config *models.Config, findings []models.Finding) []models.Finding {
+	if len(config.Rules) == 0 {
+		return findings
+	}
+	var applied []models.Finding
+	for _, finding := range findings {
+		override, ok := config.Rules[finding.RuleID]
+		if !ok || !Known(finding.RuleID) {
+			applied = append(applied, finding)
+			continue
+		}
+		if override.Enabled != nil && !*override.Enabled {
+			continue
+		}
+		if models.SeverityRank(override.Severity) >= 0 {
+			finding.Severity = override.Severity
+		}
+		applied = append(applied, finding)
+	}
+	return applied
+}
+
+// validateRuleOverrides reports entries of the rules section that name
+// neither a registered rule nor a scan stage, or set an unknown severity.
+func validateRuleOverrides(config *models.Config) []models.Finding {
+	ids := make([]string, 0, len(config.Rules))
+	for id := range config.Rules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var findings []models.Finding
+	for _, id := range ids {
+		if !Known(id) {
+			findings = append(findings, models.Finding{
+				RuleID:   id,
+				Severity: models.SeverityError,
+				Message:  fmt.Sprintf("rules: unknown rule %q", id),
+			})
+		}
+		if severity := config.Rules[id].Severity; severity != "" && models.SeverityRank(severity) < 0 {
+			findings = append(findings, models.Finding{
+				RuleID:   id,
+				Severity: models.SeverityError,
+				Message:  fmt.Sprintf("rules: unknown severity %q for %s (expected %s)", severity, id, strings.Join(models.Severities, ", ")),
+			})
+		}
+	}
+	return findings
+}
+
+// ParseManifests splits rendered helm output into manifests. Empty documents
+// are skipped.
+func ParseManifests(rendered string) ([]Manifest, error) {
+	var manifests []Manifest
+
+	decoder := yaml.NewDecoder(bytes.NewBufferString(rendered))
+	for {
+		var node yaml.Node
+		err := decoder.Decode(&node)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return manifests, fmt.Errorf("error parsing rendered manifests: %v", err)
+		}
+
+		var object map[string]interface{}
+		if err := node.Decode(&object); err != nil {
+			return manifests, fmt.Errorf("error decoding rendered manifest%s: %v", sourceSuffix(&node), err)
+		}
+		if object == nil {
+			continue
+		}
+
+		manifests = append(manifests, Manifest{
+			APIVersion: NestedString(object, "apiVersion"),
+			Kind:       NestedString(object, "kind"),
+			Name:       NestedString(object, "metadata", "name"),
+			Namespace:  NestedString(object, "metadata", "namespace"),
+			Source:     manifestSource(&node),
+			Object:     object,
+		})
+	}
+
+	return manifests, nil
+}
+
+// manifestSource extracts the template path from the "# Source:" head comment
+// of a rendered document.
+func manifestSource(node *yaml.Node) string {
+	comments := node.HeadComment
+	for current := node; len(current.Content) > 0; current = current.Content[0] {
+		comments += "\n" + current.Content[0].HeadComment
+	}
+	for _, line := range strings.Split(comments, "\n") {
+		if source, found := strings.CutPrefix(strings.TrimSpace(line), "# Source:"); found {
+			return strings.TrimSpace(source)
+		}
+	}
+	return ""
+}
+
+// sourceSuffix returns " from <template>" for error messages, or "".
+func sourceSuffix(node *yaml.Node) string {
+	if source := manifestSource(node); source != "" {
+		return " from " + source
+	}
+	return ""
+}
+
+// NestedValue returns the value at the given map keys within object, or nil if
+// any key is missing.
+func NestedValue(object map[string]interface{}, keys ...string) interface{} {
+	var current interface{} = object
+	for _, key := range keys {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[key]
+	}
+	return current
+}
+
+// NestedMap returns the map at the given keys within object, or nil.
+func NestedMap(object map[string]interface{}, keys ...string) map[string]interface{} {
+	m, _ := NestedValue(object, keys...).(map[string]interface{})
+	return m
+}
+
+// NestedSlice returns the list at the given keys within object, or nil.
+func NestedSlice(object map[string]interface{}, keys ...string) []interface{} {
+	s, _ := NestedValue(object, keys...).([]interface{})
+	return s
+}
+
+// NestedString returns the string at the given keys within object, or "".
+func NestedString(object map[string]interface{}, keys ...string) string {
+	s, _ := NestedValue(object, keys...).(string)
+	return s
+}
+
+// PodSpec returns the pod spec embedded in a workload manifest (Pod,
+// Deployment, StatefulSet, DaemonSet, ReplicaSet, Job or CronJob), or nil.
+func PodSpec(m Manifest) map[string]interface{} {
+	switch m.Kind {
+	case "Pod":
+		return NestedMap(m.Object, "spec")
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "Job":
+		return NestedMap(m.Object, "spec", "template", "spec")
+	case "CronJob":
+		return NestedMap(m.Object, "spec", "jobTemplate", "spec", "template", "spec")
+	default:
+		return nil
+	}
+}
+
+// PodTemplateMetadata returns the metadata of the pods created by a workload
+// manifest, or nil if m is not a workload.
+func PodTemplateMetadata(m Manifest) map[string]interface{} {
+	switch m.Kind {
+	case "Pod":
+		return NestedMap(m.Object, "metadata")
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "Job":
+		return NestedMap(m.Object, "spec", "template", "metadata")
+	case "CronJob":
+		return NestedMap(m.Object, "spec", "jobTemplate", "spec", "template", "metadata")
+	default:
+		return nil
+	}
+}
+
+// Containers returns the containers of a pod spec, followed by its init
+// containers when includeInit is set.
+func Containers(podSpec map[string]interface{}, includeInit bool) []map[string]interface{} {
+	fields := []string{"containers"}
+	if includeInit {
+		fields = append(fields, "initContainers")
+	}
+
+	var containers []map[string]interface{}
+	for _, field := range fields {
+		for _, c := range NestedSlice(podSpec, field) {
+			if container, ok := c.(map[string]interface{}); ok {
+				containers = append(containers, container)
+			}
+		}
+	}
+	return containers
+}
+
+// newFinding builds a finding for a rule located at manifest m.
+func newFinding(ruleID, severity string, m Manifest, format string, args ...interface{}) models.Finding {
+	return models.Finding{
+		RuleID:   ruleID,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+		Resource: m.Resource(),
+		File:     m.Source,
+	}
+}
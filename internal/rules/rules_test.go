@@ -0,0 +1,171 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestParseManifests(t *testing.T) {
+	rendered := `---
+# Source: app/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: apps
+spec:
+  ports:
+    - port: 80
+---
+# Source: app/templates/empty.yaml
+---
+# Source: app/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+`
+
+	manifests, err := ParseManifests(rendered)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(manifests) != 2 {
+		t.Fatalf("Expected 2 manifests, got %d", len(manifests))
+	}
+
+	service := manifests[0]
+	if service.Resource() != "Service/web" || service.Namespace != "apps" || service.APIVersion != "v1" {
+		t.Errorf("Unexpected service manifest: %+v", service)
+	}
+	if service.Source != "app/templates/service.yaml" {
+		t.Errorf("Expected source app/templates/service.yaml, got '%s'", service.Source)
+	}
+	if manifests[1].Source != "app/templates/deployment.yaml" {
+		t.Errorf("Expected source app/templates/deployment.yaml, got '%s'", manifests[1].Source)
+	}
+
+	if ports := NestedSlice(service.Object, "spec", "ports"); len(ports) != 1 {
+		t.Errorf("Expected 1 service port, got %v", ports)
+	}
+}
+
+func TestParseManifests_InvalidYAML(t *testing.T) {
+	if _, err := ParseManifests("kind: [unclosed"); err == nil {
+		t.Fatal("Expected error for invalid YAML, got nil")
+	}
+}
+
+func TestIDs(t *testing.T) {
+	ids := IDs()
+	if len(ids) != len(registered) {
+		t.Fatalf("Expected %d IDs, got %d", len(registered), len(ids))
+	}
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if id == "" || seen[id] {
+			t.Errorf("Expected unique non-empty rule IDs, got %v", ids)
+		}
+		seen[id] = true
+	}
+}
+
+func TestApplyOverrides(t *testing.T) {
+	RegisterStage("test-stage")
+	disabled := false
+	config := models.Config{Rules: map[string]models.RuleConfig{
+		"test-stage":     {Severity: models.SeverityWarning},
+		"liveness-probe": {Enabled: &disabled},
+		"unknown-stage":  {Severity: models.SeverityInfo},
+	}}
+	findings := ApplyOverrides(&config, []models.Finding{
+		{RuleID: "test-stage", Severity: models.SeverityError, Message: "stage failed"},
+		{RuleID: "liveness-probe", Severity: models.SeverityWarning, Message: "no probe"},
+		{RuleID: "unknown-stage", Severity: models.SeverityError, Message: "not overridable"},
+	})
+
+	var got []string
+	for _, finding := range findings {
+		got = append(got, finding.String())
+	}
+	expected := []string{
+		"[warning] test-stage: stage failed",
+		"[error] unknown-stage: not overridable",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected findings:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+	if !Known("test-stage") || !Known("liveness-probe") || Known("unknown-stage") {
+		t.Errorf("Expected registered rules and stages to be known, and nothing else")
+	}
+}
+
+func TestRunSkipAnnotation(t *testing.T) {
+	rendered := `---
+# Source: app/templates/agent.yaml
+apiVersion: v1
+kind: Pod
+metadata:
+  name: agent
+  annotations:
+    chartscan.io/skip: "privileged-container, no-such-rule"
+spec:
+  containers:
+    - name: agent
+      securityContext:
+        privileged: true
+---
+# Source: app/templates/debug.yaml
+apiVersion: v1
+kind: Pod
+metadata:
+  name: debug
+  annotations:
+    chartscan.io/skip: "*"
+spec:
+  containers:
+    - name: debug
+      securityContext:
+        privileged: true
+---
+# Source: app/templates/web.yaml
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  annotations:
+    chartscan.io/skip: liveness-probe
+spec:
+  containers:
+    - name: web
+      securityContext:
+        privileged: true
+`
+	manifests, err := ParseManifests(rendered)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	enabled := true
+	config := models.Config{Rules: map[string]models.RuleConfig{
+		"privileged-container": {Enabled: &enabled},
+	}}
+
+	findings, skipped := Run(&Context{Manifests: manifests, Config: config})
+	var got []string
+	for _, finding := range findings {
+		got = append(got, finding.String())
+	}
+	expected := []string{
+		`[warning] no-such-rule: Pod/agent (app/templates/agent.yaml): chartscan.io/skip: unknown rule "no-such-rule"`,
+		"[error] privileged-container: Pod/web (app/templates/web.yaml): container web is privileged",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected findings:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+	if len(skipped) != 2 || skipped[0].Resource != "Pod/agent" || skipped[1].Resource != "Pod/debug" {
+		t.Errorf("Expected the findings of Pod/agent and Pod/debug to be skipped, got %v", skipped)
+	}
+}
@@ -0,0 +1,184 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// QoS classes as assigned by the kubelet.
+const (
+	qosGuaranteed = "Guaranteed"
+	qosBurstable  = "Burstable"
+	qosBestEffort = "BestEffort"
+)
+
+func init() {
+	Register(priorityClassRule{})
+	Register(qosClassRule{})
+}
+
+// priorityClassRule requires workloads to use one of the allowed priority
+// classes.
+type priorityClassRule struct{}
+
+func (priorityClassRule) ID() string { return "priority-class" }
+
+func (priorityClassRule) Enabled(config *models.Config) bool {
+	return config.Scheduling.Enabled && len(config.Scheduling.PriorityClasses) > 0
+}
+
+func (r priorityClassRule) Check(ctx *Context) []models.Finding {
+	allowed := ctx.Config.Scheduling.PriorityClasses
+
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		podSpec := PodSpec(m)
+		if podSpec == nil {
+			continue
+		}
+		name := NestedString(podSpec, "priorityClassName")
+		switch {
+		case name == "":
+			findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+				"priorityClassName is not set (allowed: %s)", strings.Join(allowed, ", ")))
+		case !containsString(allowed, name):
+			findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+				"priorityClassName %q is not allowed (allowed: %s)", name, strings.Join(allowed, ", ")))
+		}
+	}
+	return findings
+}
+
+// qosClassRule compares the QoS class a workload's pods will get with the
+// class required for its tier.
+type qosClassRule struct{}
+
+func (qosClassRule) ID() string { return "qos-class" }
+
+func (qosClassRule) Enabled(config *models.Config) bool {
+	return config.Scheduling.Enabled && len(config.Scheduling.QoS) > 0
+}
+
+func (r qosClassRule) Check(ctx *Context) []models.Finding {
+	config := ctx.Config.Scheduling
+	tierLabel := defaultString(config.TierLabel, "tier")
+
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		podSpec := PodSpec(m)
+		if podSpec == nil {
+			continue
+		}
+
+		tier := podTemplateLabel(m, tierLabel)
+		required, ok := config.QoS[tier]
+		if !ok {
+			required, ok = config.QoS["*"]
+		}
+		if !ok {
+			continue
+		}
+
+		actual, err := qosClass(podSpec)
+		if err != nil {
+			findings = append(findings, newFinding(r.ID(), models.SeverityError, m, "%v", err))
+			continue
+		}
+		if !strings.EqualFold(actual, required) {
+			findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+				"pods get QoS class %s but tier %q requires %s", actual, defaultString(tier, "*"), required))
+		}
+	}
+	return findings
+}
+
+// podTemplateLabel returns a label of the workload's pod template, falling
+// back to the workload's own labels.
+func podTemplateLabel(m Manifest, label string) string {
+	if value, ok := NestedMap(PodTemplateMetadata(m), "labels")[label]; ok {
+		return fmt.Sprint(value)
+	}
+	if value, ok := NestedMap(m.Object, "metadata", "labels")[label]; ok {
+		return fmt.Sprint(value)
+	}
+	return ""
+}
+
+// qosClass computes the QoS class the kubelet assigns to pods of podSpec:
+// Guaranteed when every container sets equal CPU and memory requests and
+// limits, BestEffort when none sets any, Burstable otherwise.
+func qosClass(podSpec map[string]interface{}) (string, error) {
+	guaranteed, anySet := true, false
+	for _, container := range Containers(podSpec, true) {
+		for _, resource := range []string{"cpu", "memory"} {
+			limit := NestedValue(container, "resources", "limits", resource)
+			request := NestedValue(container, "resources", "requests", resource)
+			if request == nil {
+				// Requests default to limits.
+				request = limit
+			}
+			if limit == nil && request == nil {
+				guaranteed = false
+				continue
+			}
+			anySet = true
+			if limit == nil {
+				guaranteed = false
+				continue
+			}
+			requestValue, err := parseQuantity(fmt.Sprint(request))
+			if err != nil {
+				return "", fmt.Errorf("container %s: invalid %s request: %v", NestedString(container, "name"), resource, err)
+			}
+			limitValue, err := parseQuantity(fmt.Sprint(limit))
+			if err != nil {
+				return "", fmt.Errorf("container %s: invalid %s limit: %v", NestedString(container, "name"), resource, err)
+			}
+			if requestValue != limitValue {
+				guaranteed = false
+			}
+		}
+	}
+
+	switch {
+	case !anySet:
+		return qosBestEffort, nil
+	case guaranteed:
+		return qosGuaranteed, nil
+	default:
+		return qosBurstable, nil
+	}
+}
+
+// quantitySuffixes are the Kubernetes resource quantity suffixes and their
+// multipliers.
+var quantitySuffixes = []struct {
+	suffix     string
+	multiplier float64
+}{
+	{"Ki", 1 << 10}, {"Mi", 1 << 20}, {"Gi", 1 << 30}, {"Ti", 1 << 40}, {"Pi", 1 << 50}, {"Ei", 1 << 60},
+	{"n", 1e-9}, {"u", 1e-6}, {"m", 1e-3}, {"k", 1e3}, {"M", 1e6}, {"G", 1e9}, {"T", 1e12}, {"P", 1e15}, {"E", 1e18},
+}
+
+// parseQuantity converts a Kubernetes resource quantity such as 500m, 1.5 or
+// 256Mi to a number so that equivalent spellings compare equal.
+func parseQuantity(quantity string) (float64, error) {
+	quantity = strings.TrimSpace(quantity)
+	for _, s := range quantitySuffixes {
+		if number, found := strings.CutSuffix(quantity, s.suffix); found {
+			value, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid quantity %q", quantity)
+			}
+			return value * s.multiplier, nil
+		}
+	}
+	value, err := strconv.ParseFloat(quantity, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity %q", quantity)
+	}
+	return value, nil
+}
@@ -0,0 +1,126 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+const schedulingTestManifests = `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  template:
+    metadata:
+      labels:
+        tier: critical
+    spec:
+      priorityClassName: critical
+      containers:
+        - name: api
+          resources:
+            requests:
+              cpu: 500m
+              memory: 1Gi
+            limits:
+              cpu: "0.5"
+              memory: 1024Mi
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: worker
+  labels:
+    tier: critical
+spec:
+  template:
+    spec:
+      priorityClassName: system-node-critical
+      containers:
+        - name: worker
+          resources:
+            requests:
+              cpu: 100m
+            limits:
+              cpu: 1
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: cleanup
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+            - name: cleanup
+`
+
+func TestPriorityClassRule(t *testing.T) {
+	manifests, err := ParseManifests(schedulingTestManifests)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx := &Context{
+		Manifests: manifests,
+		Config: models.Config{Scheduling: models.SchedulingConfig{
+			Enabled:         true,
+			PriorityClasses: []string{"critical", "standard"},
+		}},
+	}
+	findings := priorityClassRule{}.Check(ctx)
+
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %d: %v", len(findings), findings)
+	}
+	if !strings.Contains(findings[0].Message, `"system-node-critical" is not allowed`) {
+		t.Errorf("Expected disallowed class finding, got %s", findings[0].Message)
+	}
+	if findings[1].Resource != "CronJob/cleanup" || !strings.Contains(findings[1].Message, "not set") {
+		t.Errorf("Expected missing class on CronJob/cleanup, got %v", findings[1])
+	}
+}
+
+func TestQoSClassRule(t *testing.T) {
+	manifests, err := ParseManifests(schedulingTestManifests)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx := &Context{
+		Manifests: manifests,
+		Config: models.Config{Scheduling: models.SchedulingConfig{
+			Enabled: true,
+			QoS:     map[string]string{"critical": "Guaranteed", "*": "Burstable"},
+		}},
+	}
+	findings := qosClassRule{}.Check(ctx)
+
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %d: %v", len(findings), findings)
+	}
+	if findings[0].Resource != "Deployment/worker" || !strings.Contains(findings[0].Message, `QoS class Burstable but tier "critical" requires Guaranteed`) {
+		t.Errorf("Expected Burstable worker finding, got %v", findings[0])
+	}
+	if findings[1].Resource != "CronJob/cleanup" || !strings.Contains(findings[1].Message, "BestEffort") {
+		t.Errorf("Expected BestEffort cleanup finding, got %v", findings[1])
+	}
+}
+
+func TestParseQuantity(t *testing.T) {
+	tests := map[string]float64{"500m": 0.5, "0.5": 0.5, "1Gi": 1 << 30, "1024Mi": 1 << 30, "2k": 2000}
+	for quantity, expected := range tests {
+		value, err := parseQuantity(quantity)
+		if err != nil || value != expected {
+			t.Errorf("Expected %s to parse as %v, got %v (%v)", quantity, expected, value, err)
+		}
+	}
+	if _, err := parseQuantity("lots"); err == nil {
+		t.Errorf("Expected an error for an invalid quantity")
+	}
+}
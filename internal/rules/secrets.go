@@ -0,0 +1,246 @@
+package rules
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func init() {
+	Register(externalSecretKeysRule{})
+	Register(sealedSecretScopeRule{})
+	Register(rawSecretRule{})
+}
+
+const (
+	sealedSecretsClusterWide   = "sealedsecrets.bitnami.com/cluster-wide"
+	sealedSecretsNamespaceWide = "sealedsecrets.bitnami.com/namespace-wide"
+)
+
+// externalSecretKeysRule checks that the keys pods read from a Secret produced
+// by an ExternalSecret are among the keys the ExternalSecret writes. A missing
+// key leaves pods stuck in CreateContainerConfigError once the Secret exists.
+type externalSecretKeysRule struct{}
+
+func (externalSecretKeysRule) ID() string { return "externalsecret-keys" }
+
+func (externalSecretKeysRule) Enabled(config *models.Config) bool {
+	return config.Secrets.Enabled
+}
+
+func (r externalSecretKeysRule) Check(ctx *Context) []models.Finding {
+	produced := make(map[string]map[string]bool)
+	for _, m := range ctx.Manifests {
+		if m.Kind != "ExternalSecret" {
+			continue
+		}
+		target := NestedString(m.Object, "spec", "target", "name")
+		if target == "" {
+			target = m.Name
+		}
+		// Keys are only known without dataFrom and templateFrom, which
+		// fetch them from the provider at runtime.
+		if keys, known := externalSecretKeys(m.Object); known {
+			produced[target] = keys
+		}
+	}
+	if len(produced) == 0 {
+		return nil
+	}
+
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		podSpec := PodSpec(m)
+		if podSpec == nil {
+			continue
+		}
+		for _, ref := range secretKeyReferences(podSpec) {
+			keys, ok := produced[ref.secret]
+			if !ok || keys[ref.key] {
+				continue
+			}
+			known := make([]string, 0, len(keys))
+			for key := range keys {
+				known = append(known, key)
+			}
+			sort.Strings(known)
+			findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+				"%s reads key %q of Secret %q, which its ExternalSecret does not produce (keys: %s)",
+				ref.via, ref.key, ref.secret, strings.Join(known, ", ")))
+		}
+	}
+	return findings
+}
+
+// externalSecretKeys returns the keys of the Secret an ExternalSecret writes.
+// A target template replaces the fetched keys unless its mergePolicy is
+// Merge. known is false if the keys depend on the provider.
+func externalSecretKeys(object map[string]interface{}) (map[string]bool, bool) {
+	keys := make(map[string]bool)
+	dataKeys := func() bool {
+		if len(NestedSlice(object, "spec", "dataFrom")) > 0 {
+			return false
+		}
+		for _, d := range NestedSlice(object, "spec", "data") {
+			data, _ := d.(map[string]interface{})
+			if key := NestedString(data, "secretKey"); key != "" {
+				keys[key] = true
+			}
+		}
+		return true
+	}
+
+	template := NestedMap(object, "spec", "target", "template")
+	if template == nil {
+		return keys, dataKeys()
+	}
+	if len(NestedSlice(template, "templateFrom")) > 0 {
+		return nil, false
+	}
+	for key := range NestedMap(template, "data") {
+		keys[key] = true
+	}
+	if NestedString(template, "mergePolicy") == "Merge" && !dataKeys() {
+		return nil, false
+	}
+	return keys, true
+}
+
+// secretKeyReference is a mandatory read of a single key of a Secret.
+type secretKeyReference struct {
+	secret, key, via string
+}
+
+// secretKeyReferences returns the Secret keys a pod spec reads through env
+// secretKeyRefs and the items of secret volumes. Optional references are
+// ignored.
+func secretKeyReferences(podSpec map[string]interface{}) []secretKeyReference {
+	var refs []secretKeyReference
+	optional := func(source map[string]interface{}) bool {
+		value, _ := source["optional"].(bool)
+		return value
+	}
+	addItems := func(source map[string]interface{}, name, via string) {
+		if source == nil || optional(source) {
+			return
+		}
+		for _, i := range NestedSlice(source, "items") {
+			item, _ := i.(map[string]interface{})
+			if key := NestedString(item, "key"); key != "" {
+				refs = append(refs, secretKeyReference{secret: name, key: key, via: via})
+			}
+		}
+	}
+
+	for _, v := range NestedSlice(podSpec, "volumes") {
+		volume, _ := v.(map[string]interface{})
+		via := "volume " + NestedString(volume, "name")
+		secret := NestedMap(volume, "secret")
+		addItems(secret, NestedString(secret, "secretName"), via)
+		for _, p := range NestedSlice(volume, "projected", "sources") {
+			source, _ := p.(map[string]interface{})
+			projected := NestedMap(source, "secret")
+			addItems(projected, NestedString(projected, "name"), via)
+		}
+	}
+
+	for _, container := range Containers(podSpec, true) {
+		for _, e := range NestedSlice(container, "env") {
+			env, _ := e.(map[string]interface{})
+			ref := NestedMap(env, "valueFrom", "secretKeyRef")
+			if ref == nil || optional(ref) {
+				continue
+			}
+			refs = append(refs, secretKeyReference{
+				secret: NestedString(ref, "name"),
+				key:    NestedString(ref, "key"),
+				via:    "container " + NestedString(container, "name") + " env " + NestedString(env, "name"),
+			})
+		}
+	}
+	return refs
+}
+
+// sealedSecretScopeRule checks that SealedSecrets are rendered where their
+// sealing scope lets the controller decrypt them. Strict scope binds the
+// ciphertext to the Secret's name and namespace, namespace-wide scope to its
+// namespace.
+type sealedSecretScopeRule struct{}
+
+func (sealedSecretScopeRule) ID() string { return "sealedsecret-scope" }
+
+func (sealedSecretScopeRule) Enabled(config *models.Config) bool {
+	return config.Secrets.Enabled
+}
+
+func (r sealedSecretScopeRule) Check(ctx *Context) []models.Finding {
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		if m.Kind != "SealedSecret" {
+			continue
+		}
+		annotations := m.Annotations()
+		clusterWide := annotations[sealedSecretsClusterWide] == "true"
+		namespaceWide := annotations[sealedSecretsNamespaceWide] == "true"
+		if clusterWide && namespaceWide {
+			findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+				"sets both %s and %s; seal it with a single scope", sealedSecretsClusterWide, sealedSecretsNamespaceWide))
+			continue
+		}
+		if clusterWide {
+			continue
+		}
+
+		scope := "strict"
+		if namespaceWide {
+			scope = "namespace-wide"
+		}
+		template := NestedMap(m.Object, "spec", "template", "metadata")
+		if name := NestedString(template, "name"); !namespaceWide && name != "" && name != m.Name {
+			findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+				"is sealed with strict scope for its own name but its template names the Secret %q", name))
+		}
+		if namespace := NestedString(template, "namespace"); namespace != "" && namespace != m.Namespace {
+			findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+				"is sealed with %s scope for namespace %q but its template targets namespace %q", scope, m.Namespace, namespace))
+		}
+		if m.Namespace == "" {
+			findings = append(findings, newFinding(r.ID(), models.SeverityWarning, m,
+				"is sealed with %s scope but sets no namespace; it only decrypts when the chart is installed into the namespace it was sealed for", scope))
+		}
+	}
+	return findings
+}
+
+// rawSecretRule forbids Secrets with inline data where secrets must be
+// managed externally, e.g. through ExternalSecrets or SealedSecrets.
+type rawSecretRule struct{}
+
+func (rawSecretRule) ID() string { return "raw-secret" }
+
+func (rawSecretRule) Enabled(config *models.Config) bool {
+	return config.Secrets.Enabled && config.Secrets.RequireExternal
+}
+
+func (r rawSecretRule) Check(ctx *Context) []models.Finding {
+	var findings []models.Finding
+	for _, m := range ctx.Manifests {
+		if m.Kind != "Secret" || matchesAny(m.Name, ctx.Config.Secrets.AllowedSecrets) {
+			continue
+		}
+		var fields []string
+		for _, field := range []string{"data", "stringData"} {
+			if len(NestedMap(m.Object, field)) > 0 {
+				fields = append(fields, field)
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		findings = append(findings, newFinding(r.ID(), models.SeverityError, m,
+			"renders secret material in %s; manage it with an ExternalSecret or SealedSecret, or list the Secret in allowedSecrets",
+			strings.Join(fields, " and ")))
+	}
+	return findings
+}
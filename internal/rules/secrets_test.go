@@ -0,0 +1,198 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestExternalSecretKeysRule(t *testing.T) {
+	manifests, err := ParseManifests(`---
+apiVersion: external-secrets.io/v1beta1
+kind: ExternalSecret
+metadata:
+  name: db
+spec:
+  target:
+    name: db-credentials
+  data:
+    - secretKey: username
+      remoteRef: {key: db/username}
+    - secretKey: password
+      remoteRef: {key: db/password}
+---
+apiVersion: external-secrets.io/v1beta1
+kind: ExternalSecret
+metadata:
+  name: api
+spec:
+  target:
+    template:
+      data:
+        token: "{{ .token }}"
+  data:
+    - secretKey: raw
+      remoteRef: {key: api/token}
+---
+apiVersion: external-secrets.io/v1beta1
+kind: ExternalSecret
+metadata:
+  name: everything
+spec:
+  dataFrom:
+    - extract: {key: app}
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+        - name: web
+          env:
+            - name: DB_USER
+              valueFrom:
+                secretKeyRef: {name: db-credentials, key: username}
+            - name: DB_PASS
+              valueFrom:
+                secretKeyRef: {name: db-credentials, key: pass}
+            - name: DB_HOST
+              valueFrom:
+                secretKeyRef: {name: db-credentials, key: host, optional: true}
+            - name: APP
+              valueFrom:
+                secretKeyRef: {name: everything, key: anything}
+      volumes:
+        - name: token
+          secret:
+            secretName: api
+            items:
+              - key: raw
+                path: token
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx := &Context{Manifests: manifests, Config: models.Config{Secrets: models.SecretsConfig{Enabled: true}}}
+	findings := externalSecretKeysRule{}.Check(ctx)
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %d: %v", len(findings), findings)
+	}
+	if !strings.Contains(findings[0].Message, `volume token reads key "raw" of Secret "api"`) || !strings.Contains(findings[0].Message, "(keys: token)") {
+		t.Errorf("Expected the templated-away raw key, got %s", findings[0].Message)
+	}
+	if !strings.Contains(findings[1].Message, `env DB_PASS reads key "pass" of Secret "db-credentials"`) || !strings.Contains(findings[1].Message, "(keys: password, username)") {
+		t.Errorf("Expected the misspelled pass key, got %s", findings[1].Message)
+	}
+}
+
+func TestSealedSecretScopeRule(t *testing.T) {
+	manifests, err := ParseManifests(`---
+apiVersion: bitnami.com/v1alpha1
+kind: SealedSecret
+metadata:
+  name: db
+  namespace: prod
+spec:
+  template:
+    metadata:
+      name: db
+      namespace: prod
+---
+apiVersion: bitnami.com/v1alpha1
+kind: SealedSecret
+metadata:
+  name: api
+  namespace: prod
+spec:
+  template:
+    metadata:
+      name: api-token
+---
+apiVersion: bitnami.com/v1alpha1
+kind: SealedSecret
+metadata:
+  name: shared
+  namespace: prod
+  annotations:
+    sealedsecrets.bitnami.com/namespace-wide: "true"
+spec:
+  template:
+    metadata:
+      name: shared-renamed
+      namespace: staging
+---
+apiVersion: bitnami.com/v1alpha1
+kind: SealedSecret
+metadata:
+  name: unscoped
+---
+apiVersion: bitnami.com/v1alpha1
+kind: SealedSecret
+metadata:
+  name: global
+  annotations:
+    sealedsecrets.bitnami.com/cluster-wide: "true"
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	findings := sealedSecretScopeRule{}.Check(&Context{Manifests: manifests})
+	expected := []struct{ resource, severity, message string }{
+		{"SealedSecret/api", models.SeverityError, `template names the Secret "api-token"`},
+		{"SealedSecret/shared", models.SeverityError, `namespace-wide scope for namespace "prod" but its template targets namespace "staging"`},
+		{"SealedSecret/unscoped", models.SeverityWarning, "sets no namespace"},
+	}
+	if len(findings) != len(expected) {
+		t.Fatalf("Expected %d findings, got %d: %v", len(expected), len(findings), findings)
+	}
+	for i, want := range expected {
+		if findings[i].Resource != want.resource || findings[i].Severity != want.severity || !strings.Contains(findings[i].Message, want.message) {
+			t.Errorf("Expected %s %s finding containing %q, got %v", want.severity, want.resource, want.message, findings[i])
+		}
+	}
+}
+
+func TestRawSecretRule(t *testing.T) {
+	manifests, err := ParseManifests(`---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: web
+stringData:
+  password: hunter2
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: web-tls
+data:
+  tls.crt: Y2VydA==
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: empty
+type: kubernetes.io/service-account-token
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	config := models.Config{Secrets: models.SecretsConfig{Enabled: true}}
+	if (rawSecretRule{}).Enabled(&config) {
+		t.Errorf("Expected raw-secret to require requireExternal")
+	}
+	config.Secrets.RequireExternal = true
+	config.Secrets.AllowedSecrets = []string{"*-tls"}
+
+	findings := rawSecretRule{}.Check(&Context{Manifests: manifests, Config: config})
+	if len(findings) != 1 || findings[0].Resource != "Secret/web" || !strings.Contains(findings[0].Message, "in stringData") {
+		t.Fatalf("Expected only Secret/web to be flagged, got %v", findings)
+	}
+}
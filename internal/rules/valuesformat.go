@@ -0,0 +1,54 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+	"github.com/Jaydee94/chartscan/internal/valuesfmt"
+)
+
+func init() {
+	Register(valuesFormatRule{})
+}
+
+// valuesFormatRule keeps the chart's own values.yaml reviewable: keys in
+// alphabetical or schema order and consistent indentation. Values files passed
+// on the command line are user overrides and not checked. `chartscan fix`
+// rewrites the file accordingly.
+type valuesFormatRule struct{}
+
+func (valuesFormatRule) ID() string { return "values-format" }
+
+func (valuesFormatRule) Enabled(config *models.Config) bool {
+	return config.ValuesFormat.Enabled
+}
+
+func (r valuesFormatRule) Check(ctx *Context) []models.Finding {
+	file := filepath.Join(filepath.Base(ctx.ChartPath), "values.yaml")
+	finding := func(severity, message string, line int) models.Finding {
+		return models.Finding{RuleID: r.ID(), Severity: severity, Message: message, File: file, Line: line}
+	}
+
+	data, err := os.ReadFile(filepath.Join(ctx.ChartPath, "values.yaml"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return []models.Finding{finding(models.SeverityError, "cannot read values.yaml: "+err.Error(), 0)}
+	}
+	opts, err := valuesfmt.ChartOptions(ctx.ChartPath, ctx.Config.ValuesFormat)
+	if err != nil {
+		return []models.Finding{finding(models.SeverityError, err.Error(), 0)}
+	}
+	problems, err := valuesfmt.Check(data, opts)
+	if err != nil {
+		return []models.Finding{finding(models.SeverityError, "values.yaml is not valid YAML: "+err.Error(), 0)}
+	}
+
+	var findings []models.Finding
+	for _, problem := range problems {
+		findings = append(findings, finding(models.SeverityWarning, problem.Message, problem.Line))
+	}
+	return findings
+}
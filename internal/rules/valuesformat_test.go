@@ -0,0 +1,54 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestValuesFormatRule(t *testing.T) {
+	chartDir := filepath.Join(t.TempDir(), "web")
+	if err := os.MkdirAll(chartDir, 0755); err != nil {
+		t.Fatalf("Failed to create chart dir: %v", err)
+	}
+	ctx := &Context{ChartPath: chartDir, Config: models.Config{ValuesFormat: models.ValuesFormatConfig{Enabled: true}}}
+
+	if findings := (valuesFormatRule{}).Check(ctx); len(findings) != 0 {
+		t.Fatalf("Expected no findings without values.yaml, got %v", findings)
+	}
+
+	values := "replicaCount: 1\nimage:\n  tag: latest\n"
+	if err := os.WriteFile(filepath.Join(chartDir, "values.yaml"), []byte(values), 0644); err != nil {
+		t.Fatalf("Failed to write values: %v", err)
+	}
+	findings := valuesFormatRule{}.Check(ctx)
+	if len(findings) != 1 {
+		t.Fatalf("Expected 1 finding, got %v", findings)
+	}
+	expected := models.Finding{
+		RuleID:   "values-format",
+		Severity: models.SeverityWarning,
+		Message:  "the top level is not in alphabetical order: image should come before replicaCount",
+		File:     "web/values.yaml",
+		Line:     2,
+	}
+	if findings[0] != expected {
+		t.Errorf("Expected %v, got %v", expected, findings[0])
+	}
+
+	ctx.Config.ValuesFormat.Order = "schema"
+	findings = valuesFormatRule{}.Check(ctx)
+	if len(findings) != 1 || findings[0].Severity != models.SeverityError {
+		t.Fatalf("Expected an error for schema order without values.schema.json, got %v", findings)
+	}
+
+	schema := `{"properties": {"replicaCount": {}, "image": {}}}`
+	if err := os.WriteFile(filepath.Join(chartDir, "values.schema.json"), []byte(schema), 0644); err != nil {
+		t.Fatalf("Failed to write schema: %v", err)
+	}
+	if findings := (valuesFormatRule{}).Check(ctx); len(findings) != 0 {
+		t.Errorf("Expected values in schema order to pass, got %v", findings)
+	}
+}
@@ -0,0 +1,59 @@
+package rules
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+	"github.com/Jaydee94/chartscan/internal/schema"
+)
+
+func init() {
+	Register(valuesSchemaRule{})
+}
+
+// valuesSchemaRule audits a chart's values.schema.json so that it keeps
+// covering the values its templates use as the chart evolves.
+type valuesSchemaRule struct{}
+
+func (valuesSchemaRule) ID() string { return "values-schema" }
+
+func (valuesSchemaRule) Enabled(config *models.Config) bool {
+	return config.ValuesSchema.Enabled
+}
+
+func (r valuesSchemaRule) Check(ctx *Context) []models.Finding {
+	config := ctx.Config.ValuesSchema
+	schemaFile := filepath.Join(ctx.ChartPath, "values.schema.json")
+	finding := func(message string) models.Finding {
+		return models.Finding{
+			RuleID:   r.ID(),
+			Severity: models.SeverityError,
+			Message:  message,
+			File:     filepath.Join(filepath.Base(ctx.ChartPath), "values.schema.json"),
+		}
+	}
+
+	data, err := os.ReadFile(schemaFile)
+	if os.IsNotExist(err) {
+		return []models.Finding{finding("chart has no values.schema.json")}
+	}
+	if err != nil {
+		return []models.Finding{finding("cannot read values.schema.json: " + err.Error())}
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return []models.Finding{finding("values.schema.json is not valid JSON: " + err.Error())}
+	}
+
+	var findings []models.Finding
+	for _, problem := range schema.Audit(doc, ctx.ValueReferences, schema.AuditOptions{
+		ForbidAdditionalProperties: config.ForbidAdditionalProperties,
+		RequireTypes:               config.RequireTypes,
+	}) {
+		findings = append(findings, finding(problem))
+	}
+	return findings
+}
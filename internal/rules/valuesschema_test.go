@@ -0,0 +1,49 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestValuesSchemaRule(t *testing.T) {
+	chartDir := filepath.Join(t.TempDir(), "web")
+	if err := os.MkdirAll(chartDir, 0755); err != nil {
+		t.Fatalf("Failed to create chart dir: %v", err)
+	}
+
+	ctx := &Context{
+		ChartPath: chartDir,
+		ValueReferences: []models.ValueReference{
+			{Name: "replicaCount", Path: []string{"replicaCount"}},
+			{Name: "image.tag", Path: []string{"image", "tag"}},
+		},
+		Config: models.Config{ValuesSchema: models.ValuesSchemaConfig{Enabled: true}},
+	}
+
+	findings := valuesSchemaRule{}.Check(ctx)
+	if len(findings) != 1 || findings[0].Message != "chart has no values.schema.json" {
+		t.Fatalf("Expected a missing schema finding, got %v", findings)
+	}
+
+	schema := `{"type": "object", "properties": {"replicaCount": {"type": "integer"}}}`
+	if err := os.WriteFile(filepath.Join(chartDir, "values.schema.json"), []byte(schema), 0644); err != nil {
+		t.Fatalf("Failed to write schema: %v", err)
+	}
+
+	findings = valuesSchemaRule{}.Check(ctx)
+	if len(findings) != 1 || !strings.HasPrefix(findings[0].Message, "image.tag is used by templates") {
+		t.Fatalf("Expected an undeclared image.tag finding, got %v", findings)
+	}
+	if findings[0].File != "web/values.schema.json" {
+		t.Errorf("Expected finding in web/values.schema.json, got %s", findings[0].File)
+	}
+
+	ctx.Config.ValuesSchema.ForbidAdditionalProperties = true
+	if findings := (valuesSchemaRule{}).Check(ctx); len(findings) != 2 {
+		t.Errorf("Expected the open root object to be reported, got %v", findings)
+	}
+}
@@ -0,0 +1,164 @@
+// Package ruletest compares the findings of rules on fixture charts with the
+// findings recorded next to them, for `chartscan rules test`.
+package ruletest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// ExpectedFile holds the expected findings of a fixture chart. A fixture
+// without it is expected to produce no findings.
+const ExpectedFile = "expected-findings.yaml"
+
+// ConfigFile is the optional chartscan.yaml a fixture is scanned with,
+// typically enabling the rules under test.
+const ConfigFile = "chartscan.yaml"
+
+// Expectation is an expected finding. Empty fields match any value.
+type Expectation struct {
+	RuleID   string `yaml:"rule"`
+	Severity string `yaml:"severity,omitempty"`
+	Resource string `yaml:"resource,omitempty"`
+	File     string `yaml:"file,omitempty"`
+	Message  string `yaml:"message,omitempty"`
+}
+
+// Matches reports whether finding satisfies the expectation.
+func (e Expectation) Matches(finding models.Finding) bool {
+	return matchField(e.RuleID, finding.RuleID) &&
+		matchField(e.Severity, finding.Severity) &&
+		matchField(e.Resource, finding.Resource) &&
+		matchField(e.File, finding.File) &&
+		matchField(e.Message, finding.Message)
+}
+
+func matchField(expected, actual string) bool {
+	return expected == "" || expected == actual
+}
+
+// String formats the expectation like a finding.
+func (e Expectation) String() string {
+	return models.Finding{RuleID: e.RuleID, Severity: e.Severity, Resource: e.Resource, File: e.File, Message: e.Message}.String()
+}
+
+// FindFixtures returns the fixture charts in dir: its immediate
+// subdirectories containing a Chart.yaml, sorted by name.
+func FindFixtures(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixtures []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		fixture := filepath.Join(dir, entry.Name())
+		if _, err := os.Stat(filepath.Join(fixture, "Chart.yaml")); err == nil {
+			fixtures = append(fixtures, fixture)
+		}
+	}
+	sort.Strings(fixtures)
+	return fixtures, nil
+}
+
+// LoadExpectations reads the expected findings of a fixture.
+func LoadExpectations(fixture string) ([]Expectation, error) {
+	data, err := os.ReadFile(filepath.Join(fixture, ExpectedFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var expected []Expectation
+	if err := yaml.Unmarshal(data, &expected); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", filepath.Join(fixture, ExpectedFile), err)
+	}
+	for i, expectation := range expected {
+		if expectation.RuleID == "" {
+			return nil, fmt.Errorf("expectation %d in %s has no rule", i+1, filepath.Join(fixture, ExpectedFile))
+		}
+	}
+	return expected, nil
+}
+
+// WriteExpectations records findings as the expected findings of a fixture.
+// The file is removed when there are no findings.
+func WriteExpectations(fixture string, findings []models.Finding) error {
+	path := filepath.Join(fixture, ExpectedFile)
+	if len(findings) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	expected := make([]Expectation, 0, len(findings))
+	for _, finding := range findings {
+		expected = append(expected, Expectation{
+			RuleID:   finding.RuleID,
+			Severity: finding.Severity,
+			Resource: finding.Resource,
+			File:     finding.File,
+			Message:  finding.Message,
+		})
+	}
+	data, err := yaml.Marshal(expected)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// FilterRules returns the findings of the given rules, or all findings if
+// ruleIDs is empty.
+func FilterRules(findings []models.Finding, ruleIDs []string) []models.Finding {
+	if len(ruleIDs) == 0 {
+		return findings
+	}
+	var filtered []models.Finding
+	for _, finding := range findings {
+		if slices.Contains(ruleIDs, finding.RuleID) {
+			filtered = append(filtered, finding)
+		}
+	}
+	return filtered
+}
+
+// Compare matches every expectation to a distinct finding. It returns the
+// expectations no finding satisfied and the findings no expectation claimed.
+func Compare(expected []Expectation, findings []models.Finding) ([]Expectation, []models.Finding) {
+	claimed := make([]bool, len(findings))
+	var missing []Expectation
+	for _, expectation := range expected {
+		found := false
+		for i, finding := range findings {
+			if !claimed[i] && expectation.Matches(finding) {
+				claimed[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, expectation)
+		}
+	}
+
+	var unexpected []models.Finding
+	for i, finding := range findings {
+		if !claimed[i] {
+			unexpected = append(unexpected, finding)
+		}
+	}
+	return missing, unexpected
+}
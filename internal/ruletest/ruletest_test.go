@@ -0,0 +1,97 @@
+package ruletest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+var findings = []models.Finding{
+	{RuleID: "image-pinning", Severity: models.SeverityError, Resource: "Deployment/web", File: "app/templates/deployment.yaml", Message: "image nginx is not pinned"},
+	{RuleID: "image-pinning", Severity: models.SeverityError, Resource: "Deployment/worker", File: "app/templates/worker.yaml", Message: "image busybox is not pinned"},
+	{RuleID: "pod-security", Severity: models.SeverityWarning, Resource: "Deployment/web", Message: "runs as root"},
+}
+
+func TestCompare(t *testing.T) {
+	expected := []Expectation{
+		{RuleID: "image-pinning", Resource: "Deployment/web"},
+		{RuleID: "image-pinning"},
+		{RuleID: "image-pinning", Resource: "Deployment/api"},
+	}
+
+	missing, unexpected := Compare(expected, findings)
+	if len(missing) != 1 || missing[0].Resource != "Deployment/api" {
+		t.Errorf("Expected the Deployment/api expectation to be missing, got %v", missing)
+	}
+	if len(unexpected) != 1 || unexpected[0].RuleID != "pod-security" {
+		t.Errorf("Expected the pod-security finding to be unexpected, got %v", unexpected)
+	}
+}
+
+func TestFilterRules(t *testing.T) {
+	if filtered := FilterRules(findings, []string{"pod-security"}); len(filtered) != 1 {
+		t.Errorf("Expected 1 pod-security finding, got %v", filtered)
+	}
+	if filtered := FilterRules(findings, nil); len(filtered) != len(findings) {
+		t.Errorf("Expected all findings without a rule filter, got %v", filtered)
+	}
+}
+
+func TestFindFixtures(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"pinned", "unpinned"} {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatalf("Failed to create fixture: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name, "Chart.yaml"), []byte("name: "+name+"\n"), 0644); err != nil {
+			t.Fatalf("Failed to write Chart.yaml: %v", err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "notes"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	fixtures, err := FindFixtures(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(fixtures) != 2 || filepath.Base(fixtures[0]) != "pinned" || filepath.Base(fixtures[1]) != "unpinned" {
+		t.Errorf("Expected the pinned and unpinned fixtures, got %v", fixtures)
+	}
+}
+
+func TestWriteAndLoadExpectations(t *testing.T) {
+	fixture := t.TempDir()
+
+	expected, err := LoadExpectations(fixture)
+	if err != nil || expected != nil {
+		t.Fatalf("Expected no expectations without a file, got %v, %v", expected, err)
+	}
+
+	if err := WriteExpectations(fixture, findings); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected, err = LoadExpectations(fixture)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if missing, unexpected := Compare(expected, findings); len(missing) != 0 || len(unexpected) != 0 {
+		t.Errorf("Expected recorded findings to match, got missing %v and unexpected %v", missing, unexpected)
+	}
+
+	if err := WriteExpectations(fixture, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(fixture, ExpectedFile)); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be removed", ExpectedFile)
+	}
+
+	if err := os.WriteFile(filepath.Join(fixture, ExpectedFile), []byte("- severity: error\n"), 0644); err != nil {
+		t.Fatalf("Failed to write expectations: %v", err)
+	}
+	if _, err := LoadExpectations(fixture); err == nil {
+		t.Errorf("Expected an error for an expectation without a rule")
+	}
+}
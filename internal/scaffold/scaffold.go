@@ -0,0 +1,91 @@
+package scaffold
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Placeholder is replaced with the chart name in the paths and contents of
+// every starter file, following Helm's starter convention.
+const Placeholder = "<CHARTNAME>"
+
+// defaultStarter is the built-in starter. Its chart passes chartscan's rules:
+// pinned image, Guaranteed resources, probes, the restricted Pod Security
+// Standard and the common label helpers.
+//
+//go:embed all:starter
+var defaultStarter embed.FS
+
+// chartNameRegex matches valid chart names.
+var chartNameRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// Create writes a new chart called name into parentDir/name and returns its
+// directory. The chart is copied from the starter directory, or from the
+// built-in starter if starterDir is empty. The target directory must not
+// exist yet.
+func Create(name, parentDir, starterDir string) (string, error) {
+	if !chartNameRegex.MatchString(name) {
+		return "", fmt.Errorf("invalid chart name %q: use lowercase letters, digits and dashes", name)
+	}
+
+	chartDir := filepath.Join(parentDir, name)
+	if _, err := os.Stat(chartDir); err == nil {
+		return "", fmt.Errorf("%s already exists", chartDir)
+	}
+
+	var starter fs.FS
+	if starterDir == "" {
+		sub, err := fs.Sub(defaultStarter, "starter")
+		if err != nil {
+			return "", err
+		}
+		starter = sub
+	} else {
+		if _, err := os.Stat(filepath.Join(starterDir, "Chart.yaml")); err != nil {
+			return "", fmt.Errorf("starter %s has no Chart.yaml", starterDir)
+		}
+		starter = os.DirFS(starterDir)
+	}
+
+	if err := copyStarter(starter, chartDir, name); err != nil {
+		os.RemoveAll(chartDir)
+		return "", err
+	}
+	return chartDir, nil
+}
+
+// copyStarter copies the files of starter into chartDir, replacing the
+// placeholder with name. Version control metadata is skipped.
+func copyStarter(starter fs.FS, chartDir, name string) error {
+	return fs.WalkDir(starter, ".", func(path string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if entry.IsDir() && entry.Name() == ".git" {
+			return fs.SkipDir
+		}
+
+		target := filepath.Join(chartDir, filepath.FromSlash(strings.ReplaceAll(path, Placeholder, name)))
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		content, err := fs.ReadFile(starter, path)
+		if err != nil {
+			return fmt.Errorf("error reading starter file %s: %v", path, err)
+		}
+		content = []byte(strings.ReplaceAll(string(content), Placeholder, name))
+		if err := os.WriteFile(target, content, 0644); err != nil {
+			return fmt.Errorf("error writing %s: %v", target, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,82 @@
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/renderer"
+	"github.com/Jaydee94/chartscan/internal/schema"
+)
+
+func TestCreate(t *testing.T) {
+	parentDir := t.TempDir()
+
+	chartDir, err := Create("my-app", parentDir, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if chartDir != filepath.Join(parentDir, "my-app") {
+		t.Errorf("Expected chart dir %s, got %s", filepath.Join(parentDir, "my-app"), chartDir)
+	}
+
+	for _, file := range []string{"Chart.yaml", "values.yaml", ".helmignore", "templates/_helpers.tpl", "templates/deployment.yaml"} {
+		content, err := os.ReadFile(filepath.Join(chartDir, file))
+		if err != nil {
+			t.Fatalf("Expected %s to be created: %v", file, err)
+		}
+		if strings.Contains(string(content), Placeholder) {
+			t.Errorf("Expected placeholder to be replaced in %s", file)
+		}
+	}
+
+	refs, errs := renderer.ParseTemplates(chartDir)
+	if len(errs) > 0 {
+		t.Fatalf("Unexpected template errors: %v", errs)
+	}
+	values, err := renderer.ValuesLoader(filepath.Join(chartDir, "values.yaml"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if undefined := renderer.CheckValueReferences(refs, values); len(undefined) > 0 {
+		t.Errorf("Expected no undefined values, got %v", undefined)
+	}
+	if issues := schema.Audit(schema.Generate(refs, values), refs, schema.AuditOptions{RequireTypes: true}); len(issues) > 0 {
+		t.Errorf("Expected generated schema to pass the audit, got %v", issues)
+	}
+
+	if _, err := Create("my-app", parentDir, ""); err == nil {
+		t.Errorf("Expected error when the chart already exists, got nil")
+	}
+}
+
+func TestCreate_Starter(t *testing.T) {
+	starter := t.TempDir()
+	os.MkdirAll(filepath.Join(starter, "templates"), 0755)
+	os.MkdirAll(filepath.Join(starter, ".git"), 0755)
+	os.WriteFile(filepath.Join(starter, "Chart.yaml"), []byte("apiVersion: v2\nname: <CHARTNAME>\nversion: 0.1.0\n"), 0644)
+	os.WriteFile(filepath.Join(starter, "templates", "<CHARTNAME>-config.yaml"), []byte("name: <CHARTNAME>-config\n"), 0644)
+	os.WriteFile(filepath.Join(starter, ".git", "HEAD"), []byte("ref: refs/heads/main\n"), 0644)
+
+	chartDir, err := Create("billing", t.TempDir(), starter)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(chartDir, "templates", "billing-config.yaml"))
+	if err != nil || string(content) != "name: billing-config\n" {
+		t.Errorf("Expected renamed template with replaced placeholder, got %q (%v)", content, err)
+	}
+	if _, err := os.Stat(filepath.Join(chartDir, ".git")); err == nil {
+		t.Errorf("Expected .git to be skipped")
+	}
+}
+
+func TestCreate_InvalidName(t *testing.T) {
+	for _, name := range []string{"MyApp", "-app", "app_1", ""} {
+		if _, err := Create(name, t.TempDir(), ""); err == nil {
+			t.Errorf("Expected error for chart name %q, got nil", name)
+		}
+	}
+}
@@ -0,0 +1,394 @@
+// Package schema derives JSON Schemas for chart values from values.yaml
+// defaults and the value references found in templates.
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// Draft is the JSON Schema dialect of generated schemas.
+const Draft = "https://json-schema.org/draft-07/schema#"
+
+// Generate builds a values.schema.json skeleton. Types are inferred from the
+// chart's default values; keys referenced by templates without a default are
+// added untyped so that chart authors can fill in their constraints.
+func Generate(refs []models.ValueReference, values map[string]interface{}) map[string]interface{} {
+	root := Infer(values)
+	root["$schema"] = Draft
+	for _, ref := range refs {
+		addPath(root, ref.Path)
+	}
+	return root
+}
+
+// Update adds typed stubs to doc for keys referenced by templates that the
+// schema does not declare yet and returns their names. A stub's type comes
+// from the key's default in values when there is one, otherwise from how the
+// template uses the value; a key whose children are referenced as well is an
+// object. Existing definitions are left untouched.
+func Update(doc map[string]interface{}, refs []models.ValueReference, values map[string]interface{}) []string {
+	parents := make(map[string]bool)
+	for _, ref := range refs {
+		for i := 1; i < len(ref.Path); i++ {
+			parents[strings.Join(ref.Path[:i], "\x00")] = true
+		}
+	}
+
+	var added []string
+	seen := make(map[string]bool)
+	for _, ref := range refs {
+		if seen[ref.Name] || declares(doc, doc, ref.Path) {
+			continue
+		}
+		seen[ref.Name] = true
+
+		leaf := addPath(doc, ref.Path)
+		if leaf == nil {
+			continue
+		}
+		if len(leaf) == 0 {
+			stub := inferUsage(ref.FullText)
+			if parents[strings.Join(ref.Path, "\x00")] {
+				stub = map[string]interface{}{"type": "object"}
+			}
+			if value, ok := valueAt(values, ref.Path); ok && value != nil {
+				stub = Infer(value)
+			}
+			for key, value := range stub {
+				leaf[key] = value
+			}
+		}
+		added = append(added, ref.Name)
+	}
+	return added
+}
+
+// Infer returns the schema describing a decoded YAML value: objects with their
+// properties, arrays with the schema of their first item, and scalar types.
+// A nil value yields an empty (unconstrained) schema.
+func Infer(value interface{}) map[string]interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			properties[key] = Infer(child)
+		}
+		return map[string]interface{}{"type": "object", "properties": properties}
+	case []interface{}:
+		schema := map[string]interface{}{"type": "array"}
+		if len(v) > 0 {
+			schema["items"] = Infer(v[0])
+		}
+		return schema
+	case string:
+		return map[string]interface{}{"type": "string"}
+	case bool:
+		return map[string]interface{}{"type": "boolean"}
+	case int, int64, uint64:
+		return map[string]interface{}{"type": "integer"}
+	case float64:
+		return map[string]interface{}{"type": "number"}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// addPath makes sure schema describes path, creating object properties for
+// keys and array items for numeric segments. Existing definitions are kept.
+// It returns the schema of the last segment, or nil if an existing scalar
+// definition conflicts with path.
+func addPath(schema map[string]interface{}, path []string) map[string]interface{} {
+	current := schema
+	for _, segment := range path {
+		if _, err := strconv.Atoi(segment); err == nil {
+			if typ, _ := current["type"].(string); typ != "" && typ != "array" {
+				return nil
+			}
+			current["type"] = "array"
+			items, ok := current["items"].(map[string]interface{})
+			if !ok {
+				items = map[string]interface{}{}
+				current["items"] = items
+			}
+			current = items
+			continue
+		}
+
+		if typ, _ := current["type"].(string); typ != "" && typ != "object" {
+			// The default is a scalar or list; the template disagrees with it,
+			// which is reported elsewhere.
+			return nil
+		}
+		current["type"] = "object"
+		properties, ok := current["properties"].(map[string]interface{})
+		if !ok {
+			properties = map[string]interface{}{}
+			current["properties"] = properties
+		}
+		child, ok := properties[segment].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			properties[segment] = child
+		}
+		current = child
+	}
+	return current
+}
+
+// usageTypes maps template functions to the value type their use implies.
+var usageTypes = []struct {
+	pattern string
+	schema  map[string]interface{}
+}{
+	{"range ", map[string]interface{}{"type": []interface{}{"array", "object"}}},
+	{"toYaml", map[string]interface{}{"type": []interface{}{"object", "array"}}},
+	{"toJson", map[string]interface{}{"type": []interface{}{"object", "array"}}},
+	{"quote", map[string]interface{}{"type": "string"}},
+	{"printf", map[string]interface{}{"type": "string"}},
+	{"b64enc", map[string]interface{}{"type": "string"}},
+	{"| int", map[string]interface{}{"type": "integer"}},
+	{"int64", map[string]interface{}{"type": "integer"}},
+}
+
+// inferUsage returns a schema stub for a value from the template action that
+// uses it. Plain interpolation implies a scalar.
+func inferUsage(action string) map[string]interface{} {
+	for _, usage := range usageTypes {
+		if strings.Contains(action, usage.pattern) {
+			stub := make(map[string]interface{}, len(usage.schema))
+			for key, value := range usage.schema {
+				stub[key] = value
+			}
+			return stub
+		}
+	}
+	return map[string]interface{}{"type": []interface{}{"string", "number", "boolean"}}
+}
+
+// valueAt returns the value at path within values, where numeric segments
+// index lists.
+func valueAt(values map[string]interface{}, path []string) (interface{}, bool) {
+	var current interface{} = values
+	for _, segment := range path {
+		switch c := current.(type) {
+		case map[string]interface{}:
+			next, ok := c[segment]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(c) {
+				return nil, false
+			}
+			current = c[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// AuditOptions selects the strictness checks of Audit beyond coverage.
+type AuditOptions struct {
+	// ForbidAdditionalProperties requires every object schema to set
+	// additionalProperties to false or to a schema.
+	ForbidAdditionalProperties bool
+	// RequireTypes requires every property schema to constrain its type.
+	RequireTypes bool
+}
+
+// Audit checks a values schema against the value references of the chart's
+// templates and returns the problems: referenced keys the schema does not
+// declare, and, depending on opts, open objects and untyped properties.
+func Audit(doc map[string]interface{}, refs []models.ValueReference, opts AuditOptions) []string {
+	var problems []string
+
+	seen := make(map[string]bool)
+	for _, ref := range refs {
+		if seen[ref.Name] {
+			continue
+		}
+		seen[ref.Name] = true
+		if !declares(doc, doc, ref.Path) {
+			problems = append(problems, fmt.Sprintf("%s is used by templates but not declared in the schema", ref.Name))
+		}
+	}
+
+	walkProperties(doc, doc, "", func(path string, schema map[string]interface{}) {
+		if opts.RequireTypes && path != "" && !ConstrainsType(schema) {
+			problems = append(problems, fmt.Sprintf("%s has no type constraint", path))
+		}
+		if opts.ForbidAdditionalProperties && isObjectSchema(schema) {
+			if additional, set := schema["additionalProperties"]; !set || additional == true {
+				problems = append(problems, fmt.Sprintf("%s allows additional properties", displayPath(path)))
+			}
+		}
+	})
+
+	return problems
+}
+
+// declares reports whether schema explicitly declares path, following local
+// $refs, properties, schema-valued additionalProperties and array items.
+func declares(doc, schema map[string]interface{}, path []string) bool {
+	schema = resolveRef(doc, schema)
+	if len(path) == 0 {
+		return true
+	}
+	if schema == nil {
+		return false
+	}
+
+	segment := path[0]
+	if _, err := strconv.Atoi(segment); err == nil {
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			return declares(doc, items, path[1:])
+		}
+	}
+	if properties, ok := schema["properties"].(map[string]interface{}); ok {
+		if child, ok := properties[segment].(map[string]interface{}); ok {
+			return declares(doc, child, path[1:])
+		}
+	}
+	if additional, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+		return declares(doc, additional, path[1:])
+	}
+	for _, keyword := range []string{"allOf", "anyOf", "oneOf"} {
+		subschemas, _ := schema[keyword].([]interface{})
+		for _, s := range subschemas {
+			if sub, ok := s.(map[string]interface{}); ok && declares(doc, sub, path) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Property returns the schema of the value at path within doc, following local
+// $refs, properties and schema-valued additionalProperties, and whether the
+// enclosing object requires the value. The schema is nil when doc does not
+// declare path.
+func Property(doc map[string]interface{}, path []string) (map[string]interface{}, bool) {
+	current := resolveRef(doc, doc)
+	required := false
+	for _, segment := range path {
+		if current == nil {
+			return nil, false
+		}
+		var child map[string]interface{}
+		if properties, ok := current["properties"].(map[string]interface{}); ok {
+			child, _ = properties[segment].(map[string]interface{})
+		}
+		if child == nil {
+			child, _ = current["additionalProperties"].(map[string]interface{})
+		}
+		names, _ := current["required"].([]interface{})
+		required = false
+		for _, name := range names {
+			if name == segment {
+				required = true
+			}
+		}
+		current = resolveRef(doc, child)
+	}
+	return current, required
+}
+
+// walkProperties calls visit for schema and every nested property, additional
+// properties and items schema, with the dotted values path of each.
+func walkProperties(doc, schema map[string]interface{}, path string, visit func(string, map[string]interface{})) {
+	visit(path, schema)
+	schema = resolveRef(doc, schema)
+	if schema == nil {
+		return
+	}
+
+	if properties, ok := schema["properties"].(map[string]interface{}); ok {
+		keys := make([]string, 0, len(properties))
+		for key := range properties {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if child, ok := properties[key].(map[string]interface{}); ok {
+				walkProperties(doc, child, joinPath(path, key), visit)
+			}
+		}
+	}
+	if additional, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+		walkProperties(doc, additional, joinPath(path, "*"), visit)
+	}
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		walkProperties(doc, items, path+"[]", visit)
+	}
+}
+
+// resolveRef follows a local "#/..." $ref of schema within doc.
+func resolveRef(doc, schema map[string]interface{}) map[string]interface{} {
+	for i := 0; i < 32 && schema != nil; i++ {
+		ref, ok := schema["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#") {
+			return schema
+		}
+		var current interface{} = doc
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#"), "/") {
+			if part == "" {
+				continue
+			}
+			m, _ := current.(map[string]interface{})
+			current = m[strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")]
+		}
+		schema, _ = current.(map[string]interface{})
+	}
+	return schema
+}
+
+// ConstrainsType reports whether a property schema restricts the type of its
+// value.
+func ConstrainsType(schema map[string]interface{}) bool {
+	for _, keyword := range []string{"type", "$ref", "enum", "const", "allOf", "anyOf", "oneOf"} {
+		if _, ok := schema[keyword]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isObjectSchema reports whether schema describes an object.
+func isObjectSchema(schema map[string]interface{}) bool {
+	if _, ok := schema["properties"]; ok {
+		return true
+	}
+	switch t := schema["type"].(type) {
+	case string:
+		return t == "object"
+	case []interface{}:
+		for _, item := range t {
+			if item == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+	return path
+}
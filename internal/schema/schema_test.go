@@ -0,0 +1,160 @@
+package schema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestGenerate(t *testing.T) {
+	values := map[string]interface{}{
+		"replicaCount": 1,
+		"image": map[string]interface{}{
+			"repository": "nginx",
+			"pullPolicy": nil,
+		},
+		"ports":   []interface{}{map[string]interface{}{"name": "http", "port": 80}},
+		"enabled": true,
+		"ratio":   0.5,
+	}
+	refs := []models.ValueReference{
+		{Name: "image.tag", Path: []string{"image", "tag"}},
+		{Name: "ingress.hosts[0].host", Path: []string{"ingress", "hosts", "0", "host"}},
+		{Name: "replicaCount.max", Path: []string{"replicaCount", "max"}},
+	}
+
+	generated := Generate(refs, values)
+	data, err := json.Marshal(generated)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := `{"$schema":"https://json-schema.org/draft-07/schema#","properties":{` +
+		`"enabled":{"type":"boolean"},` +
+		`"image":{"properties":{"pullPolicy":{},"repository":{"type":"string"},"tag":{}},"type":"object"},` +
+		`"ingress":{"properties":{"hosts":{"items":{"properties":{"host":{}},"type":"object"},"type":"array"}},"type":"object"},` +
+		`"ports":{"items":{"properties":{"name":{"type":"string"},"port":{"type":"integer"}},"type":"object"},"type":"array"},` +
+		`"ratio":{"type":"number"},` +
+		`"replicaCount":{"type":"integer"}},"type":"object"}`
+	if string(data) != expected {
+		t.Errorf("Unexpected schema:\n%s\nexpected:\n%s", data, expected)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(`{
+  "type": "object",
+  "additionalProperties": false,
+  "definitions": {"port": {"type": "integer"}},
+  "properties": {
+    "image": {
+      "type": "object",
+      "properties": {"repository": {"type": "string"}, "tag": {}}
+    },
+    "service": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {"port": {"$ref": "#/definitions/port"}}
+    },
+    "podAnnotations": {"type": "object", "additionalProperties": {"type": "string"}},
+    "hosts": {"type": "array", "items": {"type": "object", "additionalProperties": false, "properties": {"host": {"type": "string"}}}}
+  }
+}`), &doc); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	refs := []models.ValueReference{
+		{Name: "image.repository", Path: []string{"image", "repository"}},
+		{Name: "image.digest", Path: []string{"image", "digest"}},
+		{Name: "service.port", Path: []string{"service", "port"}},
+		{Name: "podAnnotations.team", Path: []string{"podAnnotations", "team"}},
+		{Name: "hosts[0].host", Path: []string{"hosts", "0", "host"}},
+		{Name: "hosts[0].path", Path: []string{"hosts", "0", "path"}},
+		{Name: "replicaCount", Path: []string{"replicaCount"}},
+	}
+
+	problems := Audit(doc, refs, AuditOptions{})
+	expected := []string{
+		"image.digest is used by templates but not declared in the schema",
+		"hosts[0].path is used by templates but not declared in the schema",
+		"replicaCount is used by templates but not declared in the schema",
+	}
+	if strings.Join(problems, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected coverage problems:\n%s", strings.Join(problems, "\n"))
+	}
+
+	problems = Audit(doc, nil, AuditOptions{ForbidAdditionalProperties: true, RequireTypes: true})
+	expected = []string{
+		"image allows additional properties",
+		"image.tag has no type constraint",
+	}
+	if strings.Join(problems, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected strictness problems:\n%s", strings.Join(problems, "\n"))
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	doc := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"image": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"repository": map[string]interface{}{"type": "string"}}},
+		},
+	}
+	values := map[string]interface{}{"image": map[string]interface{}{"pullPolicy": "IfNotPresent"}}
+	refs := []models.ValueReference{
+		{Name: "image.repository", Path: []string{"image", "repository"}, FullText: "{{ .Values.image.repository }}"},
+		{Name: "image.pullPolicy", Path: []string{"image", "pullPolicy"}, FullText: "{{ .Values.image.pullPolicy }}"},
+		{Name: "podLabels", Path: []string{"podLabels"}, FullText: "{{- toYaml .Values.podLabels | nindent 8 }}"},
+		{Name: "port", Path: []string{"port"}, FullText: "{{ .Values.port }}"},
+		{Name: "probes", Path: []string{"probes"}, FullText: "{{- with .Values.probes }}"},
+		{Name: "probes.path", Path: []string{"probes", "path"}, FullText: "{{ .path | quote }}"},
+	}
+
+	added := Update(doc, refs, values)
+	if strings.Join(added, ",") != "image.pullPolicy,podLabels,port,probes,probes.path" {
+		t.Fatalf("Unexpected added keys: %v", added)
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `{"properties":{"image":{"properties":{"pullPolicy":{"type":"string"},"repository":{"type":"string"}},"type":"object"},` +
+		`"podLabels":{"type":["object","array"]},"port":{"type":["string","number","boolean"]},` +
+		`"probes":{"properties":{"path":{"type":"string"}},"type":"object"}},"type":"object"}`
+	if string(data) != expected {
+		t.Errorf("Unexpected schema:\n%s\nexpected:\n%s", data, expected)
+	}
+
+	if added := Update(doc, refs, values); len(added) != 0 {
+		t.Errorf("Expected a second update to add nothing, got %v", added)
+	}
+}
+
+func TestProperty(t *testing.T) {
+	var doc map[string]interface{}
+	err := json.Unmarshal([]byte(`{
+  "definitions": {"image": {"type": "object", "required": ["repository"], "properties": {"repository": {"type": "string", "minLength": 1}}}},
+  "properties": {
+    "image": {"$ref": "#/definitions/image"},
+    "podLabels": {"type": "object", "additionalProperties": {"type": "string"}}
+  }
+}`), &doc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	property, required := Property(doc, []string{"image", "repository"})
+	if property["minLength"] != 1.0 || !required {
+		t.Errorf("Expected the required repository schema, got %v (required %v)", property, required)
+	}
+	if property, required := Property(doc, []string{"podLabels", "team"}); property["type"] != "string" || required {
+		t.Errorf("Expected the optional additional properties schema, got %v (required %v)", property, required)
+	}
+	if property, _ := Property(doc, []string{"image", "tag", "suffix"}); property != nil {
+		t.Errorf("Expected no schema for an undeclared path, got %v", property)
+	}
+}
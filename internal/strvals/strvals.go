@@ -0,0 +1,320 @@
+// Package strvals parses the --set, --set-string and --set-file values of the
+// helm command line, so ChartScan sees the same values helm renders with.
+package strvals
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// maxIndex bounds list indexes such as "hosts[3]", like helm does, so a typo
+// cannot allocate a huge list.
+const maxIndex = 65536
+
+// Overrides are the values set on the command line, in the order helm applies
+// them: --set, then --set-string, then --set-file.
+type Overrides struct {
+	// Values are --set expressions, e.g. "image.tag=1.27,replicas=2". Values
+	// are typed like helm types them: true, false, null and integers. A null
+	// value deletes its key, as helm's coalescing of the values does.
+	Values []string
+	// StringValues are --set-string expressions, whose values are strings.
+	StringValues []string
+	// FileValues are --set-file expressions, whose values are the contents of
+	// the named files.
+	FileValues []string
+}
+
+// Empty reports whether no values are set.
+func (o Overrides) Empty() bool {
+	return len(o.Values) == 0 && len(o.StringValues) == 0 && len(o.FileValues) == 0
+}
+
+// Args returns the helm flags setting the values.
+func (o Overrides) Args() []string {
+	var args []string
+	for _, v := range o.Values {
+		args = append(args, "--set", v)
+	}
+	for _, v := range o.StringValues {
+		args = append(args, "--set-string", v)
+	}
+	for _, v := range o.FileValues {
+		args = append(args, "--set-file", v)
+	}
+	return args
+}
+
+// MergeInto sets the values in values, creating the maps and lists their
+// keys name.
+func (o Overrides) MergeInto(values map[string]interface{}) error {
+	for _, v := range o.Values {
+		if err := parse(v, values, typedValue); err != nil {
+			return fmt.Errorf("invalid --set %q: %v", v, err)
+		}
+	}
+	for _, v := range o.StringValues {
+		if err := parse(v, values, stringValue); err != nil {
+			return fmt.Errorf("invalid --set-string %q: %v", v, err)
+		}
+	}
+	for _, v := range o.FileValues {
+		if err := parse(v, values, fileValue); err != nil {
+			return fmt.Errorf("invalid --set-file %q: %v", v, err)
+		}
+	}
+	return nil
+}
+
+// Validate checks that the expressions parse and that the files of
+// --set-file can be read.
+func (o Overrides) Validate() error {
+	return o.MergeInto(make(map[string]interface{}))
+}
+
+// valueFunc converts the text of a value. list is set for the elements of a
+// {a,b} list.
+type valueFunc func(text string, list bool) (interface{}, error)
+
+// typedValue types a --set value the way helm does: true, false and null are
+// parsed case-insensitively and integers without a leading zero become
+// int64; everything else stays a string.
+func typedValue(text string, _ bool) (interface{}, error) {
+	switch strings.ToLower(text) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	if text == "0" {
+		return int64(0), nil
+	}
+	if text != "" && text[0] != '0' {
+		if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return i, nil
+		}
+	}
+	return text, nil
+}
+
+// stringValue keeps a --set-string value as it is.
+func stringValue(text string, _ bool) (interface{}, error) {
+	return text, nil
+}
+
+// fileValue reads the file a --set-file value names.
+func fileValue(text string, list bool) (interface{}, error) {
+	if list {
+		return nil, fmt.Errorf("lists are not supported")
+	}
+	data, err := os.ReadFile(text)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", text, err)
+	}
+	return string(data), nil
+}
+
+// step is one segment of a key: a map key, or a list index when index is 0
+// or more.
+type step struct {
+	key   string
+	index int
+}
+
+// parse sets each key=value pair of the comma-separated expression s in
+// values. A backslash escapes the next character, so keys may contain dots
+// and values commas.
+func parse(s string, values map[string]interface{}, convert valueFunc) error {
+	p := &parser{runes: []rune(s)}
+	for !p.done() {
+		steps, err := p.key()
+		if err != nil {
+			return err
+		}
+		value, err := p.value(convert)
+		if err != nil {
+			return err
+		}
+		set(values, steps, value)
+	}
+	return nil
+}
+
+// parser reads an expression rune by rune.
+type parser struct {
+	runes []rune
+	pos   int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.runes)
+}
+
+// key reads the key of a pair up to its "=" and splits it into steps, e.g.
+// "hosts[0].name" into hosts, 0 and name.
+func (p *parser) key() ([]step, error) {
+	var steps []step
+	var key strings.Builder
+	start := p.pos
+	for {
+		if p.done() {
+			return nil, fmt.Errorf("key %q has no value", string(p.runes[start:]))
+		}
+		r := p.runes[p.pos]
+		p.pos++
+		switch r {
+		case '\\':
+			if p.done() {
+				return nil, fmt.Errorf("key %q ends with a backslash", string(p.runes[start:]))
+			}
+			key.WriteRune(p.runes[p.pos])
+			p.pos++
+		case ',':
+			return nil, fmt.Errorf("key %q has no value", string(p.runes[start:p.pos-1]))
+		case '.', '=':
+			if key.Len() == 0 && (len(steps) == 0 || steps[len(steps)-1].index < 0) {
+				return nil, fmt.Errorf("key %q has an empty segment", string(p.runes[start:p.pos-1]))
+			}
+			if key.Len() > 0 {
+				steps = append(steps, step{key: key.String(), index: -1})
+				key.Reset()
+			}
+			if r == '=' {
+				return steps, nil
+			}
+		case '[':
+			if key.Len() > 0 {
+				steps = append(steps, step{key: key.String(), index: -1})
+				key.Reset()
+			}
+			if len(steps) == 0 {
+				return nil, fmt.Errorf("key %q starts with a list index", string(p.runes[start:]))
+			}
+			index, err := p.index()
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, step{index: index})
+		default:
+			key.WriteRune(r)
+		}
+	}
+}
+
+// index reads a list index up to its "]".
+func (p *parser) index() (int, error) {
+	start := p.pos
+	for !p.done() && p.runes[p.pos] != ']' {
+		p.pos++
+	}
+	if p.done() {
+		return 0, fmt.Errorf("list index %q is not closed", string(p.runes[start:]))
+	}
+	text := string(p.runes[start:p.pos])
+	p.pos++
+	index, err := strconv.Atoi(text)
+	if err != nil || index < 0 {
+		return 0, fmt.Errorf("invalid list index %q", text)
+	}
+	if index > maxIndex {
+		return 0, fmt.Errorf("list index %d is greater than %d", index, maxIndex)
+	}
+	return index, nil
+}
+
+// value reads the value of a pair up to the next unescaped comma. A value in
+// braces, e.g. "{a,b}", is a list.
+func (p *parser) value(convert valueFunc) (interface{}, error) {
+	if !p.done() && p.runes[p.pos] == '{' {
+		p.pos++
+		list := []interface{}{}
+		for {
+			text, end, err := p.text(",}")
+			if err != nil {
+				return nil, err
+			}
+			if end != '}' && end != ',' {
+				return nil, fmt.Errorf("list is not closed")
+			}
+			if text != "" || end == ',' || len(list) > 0 {
+				element, err := convert(text, true)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, element)
+			}
+			if end == '}' {
+				break
+			}
+		}
+		if !p.done() {
+			if p.runes[p.pos] != ',' {
+				return nil, fmt.Errorf("unexpected %q after list", string(p.runes[p.pos:]))
+			}
+			p.pos++
+		}
+		return list, nil
+	}
+
+	text, _, err := p.text(",")
+	if err != nil {
+		return nil, err
+	}
+	return convert(text, false)
+}
+
+// text reads unescaped text up to one of the stop runes, which it consumes
+// and returns, or to the end of the expression, in which case it returns 0.
+func (p *parser) text(stop string) (string, rune, error) {
+	var text strings.Builder
+	for !p.done() {
+		r := p.runes[p.pos]
+		p.pos++
+		if r == '\\' {
+			if p.done() {
+				return "", 0, fmt.Errorf("value ends with a backslash")
+			}
+			text.WriteRune(p.runes[p.pos])
+			p.pos++
+			continue
+		}
+		if strings.ContainsRune(stop, r) {
+			return text.String(), r, nil
+		}
+		text.WriteRune(r)
+	}
+	return text.String(), 0, nil
+}
+
+// set stores value at steps below node and returns the updated node, creating
+// maps and lists as needed and replacing values of another kind. A nil value
+// deletes a map key; list elements keep it.
+func set(node interface{}, steps []step, value interface{}) interface{} {
+	if len(steps) == 0 {
+		return value
+	}
+	s := steps[0]
+	if s.index >= 0 {
+		list, _ := node.([]interface{})
+		for len(list) <= s.index {
+			list = append(list, nil)
+		}
+		list[s.index] = set(list[s.index], steps[1:], value)
+		return list
+	}
+
+	m, ok := node.(map[string]interface{})
+	if !ok {
+		m = make(map[string]interface{})
+	}
+	if len(steps) == 1 && value == nil {
+		delete(m, s.key)
+		return m
+	}
+	m[s.key] = set(m[s.key], steps[1:], value)
+	return m
+}
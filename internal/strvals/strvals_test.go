@@ -0,0 +1,93 @@
+package strvals
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMergeInto(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.ini")
+	if err := os.WriteFile(file, []byte("[main]\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	values := map[string]interface{}{
+		"existing": "value",
+		"image":    map[string]interface{}{"repository": "nginx"},
+	}
+	overrides := Overrides{
+		Values: []string{
+			"newStr=strvalue,newInt=123,newFloat=1.23,newBool=true,zip=0123",
+			"image.tag=1.27",
+			"hosts={a.example.org,b.example.org}",
+			`annotations.example\.org/team=web,args[1]=--debug`,
+			"ports[0].name=http,ports[0].port=80",
+			// null deletes a key, like helm's coalescing of the values.
+			"empty=,nothing=null,image.repository=null",
+		},
+		StringValues: []string{"replicas=3"},
+		FileValues:   []string{"config=" + file},
+	}
+	if err := overrides.MergeInto(values); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"existing": "value",
+		"newStr":   "strvalue",
+		"newInt":   int64(123),
+		"newFloat": "1.23",
+		"newBool":  true,
+		"zip":      "0123",
+		"image":    map[string]interface{}{"tag": "1.27"},
+		"hosts":    []interface{}{"a.example.org", "b.example.org"},
+		"annotations": map[string]interface{}{
+			"example.org/team": "web",
+		},
+		"args":     []interface{}{nil, "--debug"},
+		"ports":    []interface{}{map[string]interface{}{"name": "http", "port": int64(80)}},
+		"empty":    "",
+		"replicas": "3",
+		"config":   "[main]\n",
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("Expected values %v, got %v", expected, values)
+	}
+}
+
+func TestMergeIntoErrors(t *testing.T) {
+	tests := []Overrides{
+		{Values: []string{"name"}},
+		{Values: []string{"name=a,b"}},
+		{Values: []string{"a..b=c"}},
+		{Values: []string{"[0]=a"}},
+		{Values: []string{"list[x]=a"}},
+		{Values: []string{"list[70000]=a"}},
+		{Values: []string{"hosts={a,b"}},
+		{StringValues: []string{`name=a\`}},
+		{FileValues: []string{"config=/does/not/exist"}},
+	}
+	for _, overrides := range tests {
+		if err := overrides.Validate(); err == nil {
+			t.Errorf("Expected an error for %v", overrides)
+		}
+	}
+}
+
+func TestArgs(t *testing.T) {
+	overrides := Overrides{
+		Values:       []string{"a=1"},
+		StringValues: []string{"b=2"},
+		FileValues:   []string{"c=file.txt"},
+	}
+	expected := []string{"--set", "a=1", "--set-string", "b=2", "--set-file", "c=file.txt"}
+	if got := overrides.Args(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected args %v, got %v", expected, got)
+	}
+	if !(Overrides{}).Empty() || overrides.Empty() {
+		t.Errorf("Expected only the zero Overrides to be empty")
+	}
+}
@@ -0,0 +1,175 @@
+// Package tracing records the spans of a scan with the OpenTelemetry SDK and
+// exports them to a collector with OTLP over http/protobuf or grpc. It is
+// configured by the standard OTEL_* environment variables, which the
+// exporters read themselves. A nil Tracer or Span records nothing, so code can
+// trace unconditionally.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attribute is a key and value attached to a span.
+type Attribute = attribute.KeyValue
+
+// String returns a string attribute.
+func String(key, value string) Attribute { return attribute.String(key, value) }
+
+// Int returns an int attribute.
+func Int(key string, value int) Attribute { return attribute.Int(key, value) }
+
+// Bool returns a bool attribute.
+func Bool(key string, value bool) Attribute { return attribute.Bool(key, value) }
+
+// Tracer records the spans of a scan until they are exported by Shutdown.
+type Tracer struct {
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer
+	// parent continues the trace of a TRACEPARENT passed in by the CI system,
+	// if any.
+	parent context.Context
+}
+
+// FromEnv returns a tracer configured by the OTEL_* environment variables, or
+// nil when no OTLP endpoint is set or OTEL_TRACES_EXPORTER is "none". The
+// protocol is http/protobuf unless grpc is set. version is reported as the
+// service version and the version of the instrumentation scope.
+func FromEnv(version string) (*Tracer, error) {
+	if exporter := os.Getenv("OTEL_TRACES_EXPORTER"); exporter == "none" {
+		return nil, nil
+	} else if exporter != "" && exporter != "otlp" {
+		return nil, fmt.Errorf("unsupported OTEL_TRACES_EXPORTER %q (expected otlp or none)", exporter)
+	}
+	if firstEnv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "OTEL_EXPORTER_OTLP_ENDPOINT") == "" {
+		return nil, nil
+	}
+
+	ctx := context.Background()
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch protocol := firstEnv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "OTEL_EXPORTER_OTLP_PROTOCOL"); protocol {
+	case "", "http/protobuf":
+		exporter, err = otlptracehttp.New(ctx)
+	case "grpc":
+		exporter, err = otlptracegrpc.New(ctx)
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol %q (expected http/protobuf or grpc)", protocol)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot create the OTLP exporter: %v", err)
+	}
+
+	// Later sources take precedence: OTEL_SERVICE_NAME and
+	// OTEL_RESOURCE_ATTRIBUTES override the default service name.
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", "chartscan")),
+		resource.WithFromEnv(),
+		resource.WithAttributes(attribute.String("service.version", version)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTEL_RESOURCE_ATTRIBUTES: %v", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	parent := propagation.TraceContext{}.Extract(ctx, propagation.MapCarrier{"traceparent": os.Getenv("TRACEPARENT")})
+	return &Tracer{
+		provider: provider,
+		tracer:   provider.Tracer("chartscan", trace.WithInstrumentationVersion(version)),
+		parent:   parent,
+	}, nil
+}
+
+// firstEnv returns the value of the first of the environment variables that
+// is set.
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// Start begins a root span of the trace at start. The span is the child of
+// the TRACEPARENT span, if any.
+func (t *Tracer) Start(name string, start time.Time, attributes ...Attribute) *Span {
+	if t == nil {
+		return nil
+	}
+	return t.newSpan(t.parent, name, start, attributes)
+}
+
+func (t *Tracer) newSpan(parent context.Context, name string, start time.Time, attributes []Attribute) *Span {
+	ctx, span := t.tracer.Start(parent, name, trace.WithTimestamp(start), trace.WithAttributes(attributes...))
+	return &Span{tracer: t, ctx: ctx, span: span}
+}
+
+// Shutdown exports the ended spans. It is called once, when the scan is
+// done; the OTLP timeout bounds each export.
+func (t *Tracer) Shutdown() error {
+	if t == nil {
+		return nil
+	}
+	if err := t.provider.Shutdown(context.Background()); err != nil {
+		return fmt.Errorf("error exporting traces: %v", err)
+	}
+	return nil
+}
+
+// Span is a timed operation of the scan.
+type Span struct {
+	tracer *Tracer
+	// ctx carries span as the parent of child spans.
+	ctx  context.Context
+	span trace.Span
+}
+
+// Start begins a child span now.
+func (s *Span) Start(name string, attributes ...Attribute) *Span {
+	return s.StartAt(name, time.Now(), attributes...)
+}
+
+// StartAt begins a child span that started at start, for operations timed
+// before they are traced.
+func (s *Span) StartAt(name string, start time.Time, attributes ...Attribute) *Span {
+	if s == nil {
+		return nil
+	}
+	return s.tracer.newSpan(s.ctx, name, start, attributes)
+}
+
+// SetAttributes adds attributes to the span.
+func (s *Span) SetAttributes(attributes ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.span.SetAttributes(attributes...)
+}
+
+// SetError marks the span as failed with message.
+func (s *Span) SetError(message string) {
+	if s == nil {
+		return
+	}
+	s.span.SetStatus(codes.Error, message)
+}
+
+// End finishes the span. Spans that are not ended are not exported.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.span.End()
+}
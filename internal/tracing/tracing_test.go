@@ -0,0 +1,173 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestFromEnv_Disabled(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	tracer, err := FromEnv("dev")
+	if err != nil || tracer != nil {
+		t.Fatalf("Expected no tracer without an endpoint, got %v, %v", tracer, err)
+	}
+
+	// A nil tracer and its spans record nothing.
+	span := tracer.Start("scan", time.Now())
+	span.Start("chart").End()
+	span.End()
+	if err := tracer.Shutdown(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4317")
+	t.Setenv("OTEL_TRACES_EXPORTER", "none")
+	if tracer, err := FromEnv("dev"); err != nil || tracer != nil {
+		t.Errorf("Expected no tracer with OTEL_TRACES_EXPORTER=none, got %v, %v", tracer, err)
+	}
+	t.Setenv("OTEL_TRACES_EXPORTER", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "grpc")
+	if tracer, err := FromEnv("dev"); err != nil || tracer == nil {
+		t.Errorf("Expected a tracer exporting over grpc, got %v, %v", tracer, err)
+	} else if err := tracer.Shutdown(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "http/json")
+	if _, err := FromEnv("dev"); err == nil {
+		t.Errorf("Expected an error for an unsupported protocol")
+	}
+}
+
+func TestTracerExport(t *testing.T) {
+	var request coltracepb.ExportTraceServiceRequest
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("Expected /v1/traces, got %s", r.URL.Path)
+		}
+		headers = r.Header
+		body, _ := io.ReadAll(r.Body)
+		if err := proto.Unmarshal(body, &request); err != nil {
+			t.Errorf("Failed to decode export request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer server.Close()
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", server.URL)
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "authorization=Bearer%20secret")
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "ci.pipeline=nightly")
+	t.Setenv("OTEL_SERVICE_NAME", "")
+	t.Setenv("TRACEPARENT", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+
+	tracer, err := FromEnv("1.2.3")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	root := tracer.Start("chartscan scan", time.Now(), Int("chartscan.charts", 1))
+	chart := root.Start("scan chart", String("chartscan.chart.path", "charts/web"))
+	command := chart.StartAt("helm lint", time.Now())
+	command.SetError("exit status 1")
+	command.End()
+	chart.Start("unfinished")
+	chart.End()
+	root.End()
+	if err := tracer.Shutdown(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if headers.Get("Content-Type") != "application/x-protobuf" {
+		t.Errorf("Expected a protobuf request by default, got '%s'", headers.Get("Content-Type"))
+	}
+	if headers.Get("Authorization") != "Bearer secret" {
+		t.Errorf("Expected the configured authorization header, got '%s'", headers.Get("Authorization"))
+	}
+	if len(request.ResourceSpans) != 1 || len(request.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("Expected one resource and scope, got %v", &request)
+	}
+	resourceAttributes := map[string]string{}
+	for _, attribute := range request.ResourceSpans[0].Resource.Attributes {
+		resourceAttributes[attribute.Key] = attribute.Value.GetStringValue()
+	}
+	if resourceAttributes["service.name"] != "chartscan" || resourceAttributes["service.version"] != "1.2.3" || resourceAttributes["ci.pipeline"] != "nightly" {
+		t.Errorf("Unexpected resource attributes %v", resourceAttributes)
+	}
+	if scope := request.ResourceSpans[0].ScopeSpans[0].Scope; scope.GetName() != "chartscan" || scope.GetVersion() != "1.2.3" {
+		t.Errorf("Expected the chartscan 1.2.3 scope, got %v", scope)
+	}
+
+	spans := request.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 3 {
+		t.Fatalf("Expected the 3 ended spans, got %d", len(spans))
+	}
+	byName := map[string]*tracepb.Span{}
+	for _, span := range spans {
+		if hex.EncodeToString(span.TraceId) != "0af7651916cd43dd8448eb211c80319c" {
+			t.Errorf("Expected the trace of TRACEPARENT, got %x", span.TraceId)
+		}
+		byName[span.Name] = span
+	}
+	if hex.EncodeToString(byName["chartscan scan"].ParentSpanId) != "b7ad6b7169203331" {
+		t.Errorf("Expected the root span to continue TRACEPARENT, got parent '%x'", byName["chartscan scan"].ParentSpanId)
+	}
+	if !bytes.Equal(byName["scan chart"].ParentSpanId, byName["chartscan scan"].SpanId) || !bytes.Equal(byName["helm lint"].ParentSpanId, byName["scan chart"].SpanId) {
+		t.Errorf("Expected spans to be nested, got %v", spans)
+	}
+	if status := byName["helm lint"].Status; status.GetCode() != tracepb.Status_STATUS_CODE_ERROR || status.GetMessage() != "exit status 1" {
+		t.Errorf("Expected the failed command to have an error status, got %v", status)
+	}
+	if attributes := byName["chartscan scan"].Attributes; len(attributes) != 1 || attributes[0].Value.GetIntValue() != 1 {
+		t.Errorf("Expected the chartscan.charts attribute, got %v", attributes)
+	}
+}
+
+func TestTracerServiceName(t *testing.T) {
+	var request coltracepb.ExportTraceServiceRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		proto.Unmarshal(body, &request) //nolint:errcheck
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer server.Close()
+
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", server.URL+"/v1/traces")
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "http/protobuf")
+	t.Setenv("OTEL_SERVICE_NAME", "charts-ci")
+	t.Setenv("TRACEPARENT", "")
+
+	tracer, err := FromEnv("1.2.3")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tracer.Start("chartscan scan", time.Now()).End()
+	if err := tracer.Shutdown(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(request.ResourceSpans) != 1 {
+		t.Fatalf("Expected one resource, got %v", &request)
+	}
+	var serviceName string
+	for _, attribute := range request.ResourceSpans[0].Resource.Attributes {
+		if attribute.Key == "service.name" {
+			serviceName = attribute.Value.GetStringValue()
+		}
+	}
+	if serviceName != "charts-ci" {
+		t.Errorf("Expected the service name charts-ci, got '%s'", serviceName)
+	}
+	if spans := request.ResourceSpans[0].ScopeSpans[0].Spans; len(spans) != 1 || len(spans[0].ParentSpanId) != 0 {
+		t.Errorf("Expected a single root span without TRACEPARENT, got %v", spans)
+	}
+}
@@ -0,0 +1,85 @@
+package validation
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+)
+
+//go:generate go run ./genschemas -versions 1.32,1.33,1.34,1.35 -out schemas
+
+// embeddedLocation is the location of the schemas bundled into the binary.
+// It is selected with the "embedded" location keyword.
+const embeddedLocation = embeddedPrefix + "{{ .NormalizedKubernetesVersion }}/{{ .ResourceKind }}{{ .KindSuffix }}.json"
+
+const embeddedPrefix = "embedded:"
+
+// bundledSchemas is the file system bundles are read from.
+var bundledSchemas fs.FS = embeddedSchemas
+
+// bundles caches decoded bundles by minor version. A nil entry records a
+// version without a bundle.
+var bundles = struct {
+	sync.Mutex
+	schemas map[string]map[string]json.RawMessage
+}{schemas: make(map[string]map[string]json.RawMessage)}
+
+// BundledVersions returns the Kubernetes minor versions with embedded
+// schemas, e.g. 1.32.
+func BundledVersions() []string {
+	entries, _ := fs.ReadDir(bundledSchemas, "schemas")
+	var versions []string
+	for _, entry := range entries {
+		if version, ok := strings.CutSuffix(entry.Name(), ".json.gz"); ok {
+			versions = append(versions, version)
+		}
+	}
+	return versions
+}
+
+// readEmbedded returns the bundled schema at an expanded embedded location,
+// embedded:<kube version>/<file>, or nil if it is not bundled.
+func readEmbedded(location string) ([]byte, error) {
+	version, file, _ := strings.Cut(strings.TrimPrefix(location, embeddedPrefix), "/")
+	schemas, err := loadBundle(minorVersion(version))
+	if err != nil {
+		return nil, err
+	}
+	return schemas[file], nil
+}
+
+// loadBundle returns the schemas bundled for a minor version.
+func loadBundle(minor string) (map[string]json.RawMessage, error) {
+	bundles.Lock()
+	defer bundles.Unlock()
+	if schemas, cached := bundles.schemas[minor]; cached {
+		return schemas, nil
+	}
+
+	var schemas map[string]json.RawMessage
+	if file, err := bundledSchemas.Open(path.Join("schemas", minor+".json.gz")); err == nil {
+		defer file.Close()
+		reader, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading embedded schemas for %s: %v", minor, err)
+		}
+		if err := json.NewDecoder(reader).Decode(&schemas); err != nil {
+			return nil, fmt.Errorf("error reading embedded schemas for %s: %v", minor, err)
+		}
+	}
+	bundles.schemas[minor] = schemas
+	return schemas, nil
+}
+
+// minorVersion turns a normalized version such as v1.29.3 into 1.29.
+func minorVersion(version string) string {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 {
+		return parts[0]
+	}
+	return parts[0] + "." + parts[1]
+}
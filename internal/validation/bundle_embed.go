@@ -0,0 +1,15 @@
+//go:build !release
+
+package validation
+
+import "embed"
+
+// embeddedSchemas holds one bundle per Kubernetes minor version,
+// schemas/<major.minor>.json.gz, generated by genschemas: a gzipped JSON
+// object of the strict standalone schemas of common built-in kinds, keyed by
+// file name as in the default schema location. Builds from source without
+// generated bundles embed only the README and validate against the other
+// schema locations.
+//
+//go:embed schemas
+var embeddedSchemas embed.FS
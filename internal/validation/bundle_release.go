@@ -0,0 +1,11 @@
+//go:build release
+
+package validation
+
+import "embed"
+
+// embeddedSchemas holds the generated bundles, as in bundle_embed.go. Release
+// builds fail to compile without them, so no release ships without schemas.
+//
+//go:embed schemas/*.json.gz
+var embeddedSchemas embed.FS
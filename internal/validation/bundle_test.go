@@ -0,0 +1,75 @@
+package validation
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+func TestEmbeddedSchemas(t *testing.T) {
+	var bundle bytes.Buffer
+	writer := gzip.NewWriter(&bundle)
+	writer.Write([]byte(`{"deployment-apps-v1.json": ` + deploymentSchema + `}`))
+	writer.Close()
+
+	defer func() { bundledSchemas = embeddedSchemas }()
+	bundledSchemas = fstest.MapFS{
+		"schemas/9.98.json.gz": {Data: bundle.Bytes()},
+		"schemas/README.md":    {Data: []byte("# Embedded Kubernetes schemas\n")},
+	}
+
+	if versions := BundledVersions(); len(versions) != 1 || versions[0] != "9.98" {
+		t.Errorf("Expected the bundled version 9.98, got %v", versions)
+	}
+
+	// No network is needed: the embedded location is tried first.
+	validator, err := New(models.ValidationConfig{KubeVersion: "9.98.3", SchemaLocations: []string{"embedded"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	problems, err := validator.Validate(map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "web"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(problems) != 1 || !strings.Contains(problems[0], "spec") {
+		t.Errorf("Expected the missing spec to be reported, got %v", problems)
+	}
+
+	if _, err := validator.Validate(map[string]interface{}{"apiVersion": "v1", "kind": "Service"}); err == nil {
+		t.Errorf("Expected no schema for a kind missing from the bundle")
+	}
+
+	other, _ := New(models.ValidationConfig{KubeVersion: "9.97", SchemaLocations: []string{"embedded"}})
+	if _, err := other.Validate(map[string]interface{}{"apiVersion": "apps/v1", "kind": "Deployment"}); err == nil {
+		t.Errorf("Expected no schema for a version without a bundle")
+	}
+}
+
+func TestDefaultLocations(t *testing.T) {
+	validator, _ := New(models.ValidationConfig{KubeVersion: "1.29"})
+	if len(validator.locations) != 2 || !strings.HasPrefix(validator.locations[0].Root.String(), embeddedPrefix) {
+		t.Errorf("Expected the embedded schemas before the default location")
+	}
+
+	pinned, _ := New(models.ValidationConfig{KubeVersion: "1.29", SchemaVersion: "0123abcd"})
+	if len(pinned.locations) != 1 || !strings.Contains(pinned.locations[0].Root.String(), "/0123abcd/") {
+		t.Errorf("Expected only the pinned default location")
+	}
+}
+
+func TestMinorVersion(t *testing.T) {
+	tests := map[string]string{"v1.29.3": "1.29", "v1.30.0": "1.30", "master": "master"}
+	for input, expected := range tests {
+		if actual := minorVersion(input); actual != expected {
+			t.Errorf("Expected %s to be minor version %s, got %s", input, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package validation
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// crdCache holds the CRD schemas loaded from each source for the whole
+// process, so that a cluster is queried once per scan rather than per chart.
+var crdCache = struct {
+	sync.Mutex
+	sources map[string]crdSource
+}{sources: make(map[string]crdSource)}
+
+type crdSource struct {
+	schemas map[string]map[string]interface{}
+	err     error
+}
+
+// loadCRDSchemas returns the schemas of the custom resources defined by the
+// CRDs at source, keyed by apiVersion/kind, e.g.
+// cert-manager.io/v1/Certificate. source is a file or directory of CRD
+// manifests or, if no such path exists, a kubeconfig context whose CRDs are
+// read with kubectl.
+func loadCRDSchemas(source string) (map[string]map[string]interface{}, error) {
+	crdCache.Lock()
+	defer crdCache.Unlock()
+	if cached, ok := crdCache.sources[source]; ok {
+		return cached.schemas, cached.err
+	}
+
+	schemas := make(map[string]map[string]interface{})
+	var err error
+	if _, statErr := os.Stat(source); statErr == nil {
+		err = filepath.WalkDir(source, func(path string, entry fs.DirEntry, walkErr error) error {
+			if walkErr != nil {
+				return walkErr
+			}
+			switch filepath.Ext(path) {
+			case ".yaml", ".yml", ".json":
+			default:
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if err := parseCRDs(data, schemas); err != nil {
+				return fmt.Errorf("error reading CRDs from %s: %v", path, err)
+			}
+			return nil
+		})
+	} else {
+		var stderr bytes.Buffer
+		cmd := exec.Command("kubectl", "--context", source, "get", "customresourcedefinitions", "--output", "json")
+		cmd.Stderr = &stderr
+		output, cmdErr := cmd.Output()
+		if cmdErr != nil {
+			err = fmt.Errorf("%s is not a CRD file or directory, and reading CRDs from it as a kubeconfig context failed: %v: %s", source, cmdErr, strings.TrimSpace(stderr.String()))
+		} else if parseErr := parseCRDs(output, schemas); parseErr != nil {
+			err = fmt.Errorf("error reading CRDs from context %s: %v", source, parseErr)
+		}
+	}
+	if err == nil && len(schemas) == 0 {
+		err = fmt.Errorf("no CustomResourceDefinitions found in %s", source)
+	}
+	if err != nil {
+		schemas = nil
+	}
+
+	crdCache.sources[source] = crdSource{schemas: schemas, err: err}
+	return schemas, err
+}
+
+// parseCRDs adds the schemas of the CRDs in a YAML or JSON stream to schemas.
+// Lists, as printed by kubectl, are unpacked; other kinds are ignored.
+func parseCRDs(data []byte, schemas map[string]map[string]interface{}) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc map[string]interface{}
+		if err := decoder.Decode(&doc); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		docs := []interface{}{doc}
+		if doc["kind"] == "List" {
+			docs = interfaceSlice(doc["items"])
+		}
+		for _, item := range docs {
+			if crd, ok := item.(map[string]interface{}); ok && crd["kind"] == "CustomResourceDefinition" {
+				addCRDSchemas(crd, schemas)
+			}
+		}
+	}
+}
+
+// addCRDSchemas adds the schema of every version of a CRD. The v1beta1
+// top-level spec.validation schema applies to versions without their own.
+func addCRDSchemas(crd map[string]interface{}, schemas map[string]map[string]interface{}) {
+	spec, _ := crd["spec"].(map[string]interface{})
+	group, _ := spec["group"].(string)
+	names, _ := spec["names"].(map[string]interface{})
+	kind, _ := names["kind"].(string)
+	if group == "" || kind == "" {
+		return
+	}
+	shared := openAPISchema(spec["validation"])
+
+	versions := interfaceSlice(spec["versions"])
+	if version, _ := spec["version"].(string); version != "" && len(versions) == 0 {
+		versions = []interface{}{map[string]interface{}{"name": version}}
+	}
+	for _, v := range versions {
+		version, _ := v.(map[string]interface{})
+		name, _ := version["name"].(string)
+		schema := openAPISchema(version["schema"])
+		if schema == nil {
+			schema = shared
+		}
+		if name == "" || schema == nil {
+			continue
+		}
+		schemas[group+"/"+name+"/"+kind] = strictCRDSchema(schema)
+	}
+}
+
+// openAPISchema returns the openAPIV3Schema of a CRD validation stanza.
+func openAPISchema(validation interface{}) map[string]interface{} {
+	stanza, _ := validation.(map[string]interface{})
+	schema, _ := stanza["openAPIV3Schema"].(map[string]interface{})
+	return schema
+}
+
+// strictCRDSchema rejects unknown fields the way the API server prunes them:
+// objects with properties disallow other keys unless they preserve unknown
+// fields. The type meta and metadata of the root object are always allowed;
+// CRDs may only restrict the name of the latter.
+func strictCRDSchema(schema map[string]interface{}) map[string]interface{} {
+	properties, _ := schema["properties"].(map[string]interface{})
+	if properties == nil {
+		properties = make(map[string]interface{})
+		schema["properties"] = properties
+	}
+	for _, key := range []string{"apiVersion", "kind"} {
+		if _, ok := properties[key]; !ok {
+			properties[key] = map[string]interface{}{"type": "string"}
+		}
+	}
+	forbidUnknownFields(schema)
+	properties["metadata"] = map[string]interface{}{"type": "object"}
+	return schema
+}
+
+// forbidUnknownFields disallows additional properties below schema. The
+// subschemas of allOf, anyOf and oneOf are left alone, since structural
+// schemas only use them to constrain properties declared by their parent.
+func forbidUnknownFields(schema map[string]interface{}) {
+	properties, _ := schema["properties"].(map[string]interface{})
+	preserveUnknown, _ := schema["x-kubernetes-preserve-unknown-fields"].(bool)
+	if _, set := schema["additionalProperties"]; properties != nil && !set && !preserveUnknown {
+		schema["additionalProperties"] = false
+	}
+
+	for _, property := range properties {
+		if sub, ok := property.(map[string]interface{}); ok {
+			forbidUnknownFields(sub)
+		}
+	}
+	for _, keyword := range []string{"items", "additionalProperties"} {
+		if sub, ok := schema[keyword].(map[string]interface{}); ok {
+			forbidUnknownFields(sub)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package validation
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+const certificateCRD = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: certificates.cert-manager.io
+spec:
+  group: cert-manager.io
+  names:
+    kind: Certificate
+  versions:
+    - name: v1
+      schema:
+        openAPIV3Schema:
+          type: object
+          required: [spec]
+          properties:
+            apiVersion: {type: string}
+            kind: {type: string}
+            metadata: {type: object}
+            spec:
+              type: object
+              required: [secretName]
+              properties:
+                secretName: {type: string}
+                dnsNames:
+                  type: array
+                  items: {type: string}
+                additionalOutputFormats:
+                  type: object
+                  x-kubernetes-preserve-unknown-fields: true
+                  properties:
+                    type: {type: string}
+`
+
+func TestCRDSchemas(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "certificates.yaml"), "# cert-manager\n---\n"+certificateCRD)
+	writeFile(t, filepath.Join(dir, "README.md"), "not a manifest: [")
+
+	validator, err := New(models.ValidationConfig{KubeVersion: "1.29", SchemaLocations: []string{filepath.Join(dir, "missing")}, CRDSchemas: dir})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	problems, err := validator.Validate(map[string]interface{}{
+		"apiVersion": "cert-manager.io/v1",
+		"kind":       "Certificate",
+		"metadata":   map[string]interface{}{"name": "web", "labels": map[string]interface{}{"app": "web"}},
+		"spec": map[string]interface{}{
+			"dnsName":                 "example.com",
+			"additionalOutputFormats": map[string]interface{}{"type": "DER", "extra": true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(problems) != 2 {
+		t.Fatalf("Expected 2 problems, got %v", problems)
+	}
+	if !strings.Contains(problems[0], `"secretName"`) || !strings.Contains(problems[1], `unknown field "dnsName"`) {
+		t.Errorf("Expected the missing secretName and the unknown dnsName, got %v", problems)
+	}
+
+	if _, err := validator.Validate(map[string]interface{}{"apiVersion": "cert-manager.io/v1alpha2", "kind": "Certificate"}); err == nil {
+		t.Errorf("Expected no schema for a version the CRD does not define")
+	}
+}
+
+func TestCRDSchemasFromContext(t *testing.T) {
+	bin := t.TempDir()
+	list := `{"apiVersion": "v1", "kind": "List", "items": [` + toJSON(t, certificateCRD) + `]}`
+	writeFile(t, filepath.Join(bin, "list.json"), list)
+	script := "#!/bin/sh\n[ \"$2\" = staging ] || { echo \"context $2 not found\" >&2; exit 1; }\ncat " + filepath.Join(bin, "list.json") + "\n"
+	if err := os.WriteFile(filepath.Join(bin, "kubectl"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write kubectl: %v", err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	schemas, err := loadCRDSchemas("staging")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := schemas["cert-manager.io/v1/Certificate"]; !ok {
+		t.Errorf("Expected the Certificate schema from the cluster, got %v", schemas)
+	}
+
+	if _, err := loadCRDSchemas("production"); err == nil || !strings.Contains(err.Error(), "context production not found") {
+		t.Errorf("Expected kubectl's error for an unknown context, got %v", err)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+// toJSON converts a YAML document to JSON, as kubectl prints it.
+func toJSON(t *testing.T, document string) string {
+	t.Helper()
+	var object map[string]interface{}
+	if err := yaml.Unmarshal([]byte(document), &object); err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+	data, err := json.Marshal(object)
+	if err != nil {
+		t.Fatalf("Failed to encode JSON: %v", err)
+	}
+	return string(data)
+}
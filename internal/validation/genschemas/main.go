@@ -0,0 +1,121 @@
+// Command genschemas downloads the Kubernetes JSON Schemas of common built-in
+// kinds and writes the bundles embedded by the validation package, one
+// gzipped JSON object per Kubernetes minor version keyed by schema file name.
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Jaydee94/chartscan/internal/validation"
+)
+
+// kinds are the built-in kinds bundled, by apiVersion. CRDs and rarely
+// templated kinds are left to the remote schema locations.
+var kinds = map[string][]string{
+	"v1": {
+		"ConfigMap", "Endpoints", "LimitRange", "Namespace", "PersistentVolume",
+		"PersistentVolumeClaim", "Pod", "ResourceQuota", "Secret", "Service",
+		"ServiceAccount",
+	},
+	"apps/v1":                         {"DaemonSet", "Deployment", "ReplicaSet", "StatefulSet"},
+	"autoscaling/v2":                  {"HorizontalPodAutoscaler"},
+	"batch/v1":                        {"CronJob", "Job"},
+	"networking.k8s.io/v1":            {"Ingress", "IngressClass", "NetworkPolicy"},
+	"policy/v1":                       {"PodDisruptionBudget"},
+	"rbac.authorization.k8s.io/v1":    {"ClusterRole", "ClusterRoleBinding", "Role", "RoleBinding"},
+	"scheduling.k8s.io/v1":            {"PriorityClass"},
+	"storage.k8s.io/v1":               {"StorageClass"},
+	"admissionregistration.k8s.io/v1": {"MutatingWebhookConfiguration", "ValidatingWebhookConfiguration"},
+	"apiextensions.k8s.io/v1":         {"CustomResourceDefinition"},
+	"coordination.k8s.io/v1":          {"Lease"},
+}
+
+func main() {
+	versions := flag.String("versions", "", "Comma-separated Kubernetes minor versions to bundle, e.g. 1.32,1.33")
+	ref := flag.String("ref", "master", "Commit, branch or tag of the schema repository")
+	out := flag.String("out", "schemas", "Directory to write the bundles to")
+	flag.Parse()
+
+	if *versions == "" {
+		fmt.Fprintln(os.Stderr, "Error: -versions is required")
+		os.Exit(1)
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	for _, version := range strings.Split(*versions, ",") {
+		if err := writeBundle(client, *ref, strings.TrimSpace(version), *out); err != nil {
+			fmt.Fprintf(os.Stderr, "Error bundling schemas for %s: %v\n", version, err)
+			os.Exit(1)
+		}
+	}
+}
+
+// writeBundle downloads the schemas of version and writes its bundle to out.
+func writeBundle(client *http.Client, ref, version, out string) error {
+	dir := fmt.Sprintf("https://raw.githubusercontent.com/yannh/kubernetes-json-schema/%s/v%s.0-standalone-strict/", ref, version)
+
+	schemas := make(map[string]json.RawMessage)
+	for apiVersion, names := range kinds {
+		for _, kind := range names {
+			file := validation.SchemaFile(apiVersion, kind)
+			data, err := download(client, dir+file)
+			if err != nil {
+				return err
+			}
+			if data == nil {
+				fmt.Fprintf(os.Stderr, "Skipping %s %s: no schema for %s\n", apiVersion, kind, version)
+				continue
+			}
+			if !json.Valid(data) {
+				return fmt.Errorf("invalid schema %s", dir+file)
+			}
+			schemas[file] = data
+		}
+	}
+	if len(schemas) == 0 {
+		return fmt.Errorf("no schemas found in %s", dir)
+	}
+
+	path := filepath.Join(out, version+".json.gz")
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer, err := gzip.NewWriterLevel(file, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(writer).Encode(schemas); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d schemas to %s\n", len(schemas), path)
+	return nil
+}
+
+// download fetches url, returning nil data if it does not exist.
+func download(client *http.Client, url string) ([]byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,314 @@
+package validation
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// validate checks value against a JSON Schema and returns the violations
+// prefixed with their location below path. It implements the subset of JSON
+// Schema used by Kubernetes OpenAPI and CRD schemas and chart values schemas:
+// type, enum, const, properties, additionalProperties, required, items,
+// allOf/anyOf/oneOf, numeric and length bounds and patterns, plus the
+// x-kubernetes-int-or-string and x-kubernetes-preserve-unknown-fields
+// extensions.
+func validate(schema map[string]interface{}, value interface{}, path location) []string {
+	if schema == nil {
+		return nil
+	}
+	var problems []string
+
+	if value == nil {
+		if nullable, _ := schema["nullable"].(bool); nullable || typeAllows(schema, "null") || schema["type"] == nil {
+			return nil
+		}
+	}
+
+	if intOrString, _ := schema["x-kubernetes-int-or-string"].(bool); intOrString {
+		if _, ok := value.(string); !ok && !isInteger(value) {
+			return []string{fmt.Sprintf("%s: expected integer or string, got %s", path, jsonType(value))}
+		}
+	} else if types := schemaTypes(schema); len(types) > 0 && !matchesAnyType(types, value) {
+		return []string{fmt.Sprintf("%s: expected %s, got %s", path, strings.Join(types, " or "), jsonType(value))}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		found := false
+		for _, allowed := range enum {
+			if fmt.Sprint(allowed) == fmt.Sprint(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("%s: value %v is not one of %v", path, value, enum))
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		problems = append(problems, validateObject(schema, v, path)...)
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				problems = append(problems, validate(items, item, path.index(i))...)
+			}
+		}
+		if max, ok := number(schema["maxItems"]); ok && float64(len(v)) > max {
+			problems = append(problems, fmt.Sprintf("%s: has %d items, at most %v allowed", path, len(v), max))
+		}
+		if min, ok := number(schema["minItems"]); ok && float64(len(v)) < min {
+			problems = append(problems, fmt.Sprintf("%s: has %d items, at least %v required", path, len(v), min))
+		}
+	case string:
+		if max, ok := number(schema["maxLength"]); ok && float64(len(v)) > max {
+			problems = append(problems, fmt.Sprintf("%s: longer than %v characters", path, max))
+		}
+		if min, ok := number(schema["minLength"]); ok && float64(utf8.RuneCountInString(v)) < min {
+			problems = append(problems, fmt.Sprintf("%s: shorter than %v characters", path, min))
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(v) {
+				problems = append(problems, fmt.Sprintf("%s: %q does not match the pattern %s", path, v, pattern))
+			}
+		}
+	}
+
+	if constant, ok := schema["const"]; ok && fmt.Sprint(constant) != fmt.Sprint(value) {
+		problems = append(problems, fmt.Sprintf("%s: value %v is not %v", path, value, constant))
+	}
+
+	if n, ok := number(value); ok {
+		if max, ok := number(schema["maximum"]); ok && n > max {
+			problems = append(problems, fmt.Sprintf("%s: %v is greater than the maximum %v", path, value, max))
+		}
+		if min, ok := number(schema["minimum"]); ok && n < min {
+			problems = append(problems, fmt.Sprintf("%s: %v is less than the minimum %v", path, value, min))
+		}
+	}
+
+	for _, sub := range subschemas(schema, "allOf") {
+		problems = append(problems, validate(sub, value, path)...)
+	}
+	if anyOf := subschemas(schema, "anyOf"); len(anyOf) > 0 && countMatching(anyOf, value, path) == 0 {
+		problems = append(problems, fmt.Sprintf("%s: does not match any of the allowed schemas", path))
+	}
+	if oneOf := subschemas(schema, "oneOf"); len(oneOf) > 0 && countMatching(oneOf, value, path) != 1 {
+		problems = append(problems, fmt.Sprintf("%s: must match exactly one of the allowed schemas", path))
+	}
+
+	return problems
+}
+
+// validateObject checks required and unknown properties of an object value
+// and validates each property against its schema.
+func validateObject(schema map[string]interface{}, object map[string]interface{}, path location) []string {
+	var problems []string
+
+	for _, r := range interfaceSlice(schema["required"]) {
+		if key, _ := r.(string); key != "" {
+			if _, ok := object[key]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: missing required field %q", path, key))
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	preserveUnknown, _ := schema["x-kubernetes-preserve-unknown-fields"].(bool)
+
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		childPath := path.key(key)
+		if propertySchema, ok := properties[key].(map[string]interface{}); ok {
+			problems = append(problems, validate(propertySchema, object[key], childPath)...)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional && !preserveUnknown {
+				problems = append(problems, fmt.Sprintf("%s: unknown field %q", path, key))
+			}
+		case map[string]interface{}:
+			problems = append(problems, validate(additional, object[key], childPath)...)
+		}
+	}
+
+	return problems
+}
+
+func countMatching(schemas []map[string]interface{}, value interface{}, path location) int {
+	matching := 0
+	for _, sub := range schemas {
+		if len(validate(sub, value, path)) == 0 {
+			matching++
+		}
+	}
+	return matching
+}
+
+func subschemas(schema map[string]interface{}, keyword string) []map[string]interface{} {
+	var result []map[string]interface{}
+	for _, s := range interfaceSlice(schema[keyword]) {
+		if sub, ok := s.(map[string]interface{}); ok {
+			result = append(result, sub)
+		}
+	}
+	return result
+}
+
+func schemaTypes(schema map[string]interface{}) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var types []string
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func typeAllows(schema map[string]interface{}, typ string) bool {
+	for _, t := range schemaTypes(schema) {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAnyType(types []string, value interface{}) bool {
+	for _, t := range types {
+		switch t {
+		case "object":
+			if _, ok := value.(map[string]interface{}); ok {
+				return true
+			}
+		case "array":
+			if _, ok := value.([]interface{}); ok {
+				return true
+			}
+		case "string":
+			if _, ok := value.(string); ok {
+				return true
+			}
+		case "boolean":
+			if _, ok := value.(bool); ok {
+				return true
+			}
+		case "integer":
+			if isInteger(value) {
+				return true
+			}
+		case "number":
+			if _, ok := number(value); ok {
+				return true
+			}
+		case "null":
+			if value == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jsonType names the JSON type of a decoded YAML or JSON value.
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	}
+	if isInteger(value) {
+		return "integer"
+	}
+	if _, ok := number(value); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func isInteger(value interface{}) bool {
+	switch v := value.(type) {
+	case int, int64, uint64:
+		return true
+	case float64:
+		return v == math.Trunc(v)
+	}
+	return false
+}
+
+func number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func interfaceSlice(value interface{}) []interface{} {
+	s, _ := value.([]interface{})
+	return s
+}
+
+// location is the path of a value below the validated document. Violations
+// name it with dots and brackets, e.g. spec.containers[0].image, or, for
+// values, as a JSON pointer such as /containers/0/image.
+type location struct {
+	pointer bool
+	path    string
+}
+
+// key returns the location of the property key of the object at l.
+func (l location) key(key string) location {
+	if l.pointer {
+		key = strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+		return location{pointer: true, path: l.path + "/" + key}
+	}
+	if l.path == "" {
+		return location{path: key}
+	}
+	return location{path: l.path + "." + key}
+}
+
+// index returns the location of item i of the array at l.
+func (l location) index(i int) location {
+	if l.pointer {
+		return location{pointer: true, path: fmt.Sprintf("%s/%d", l.path, i)}
+	}
+	return location{path: fmt.Sprintf("%s[%d]", l.path, i)}
+}
+
+func (l location) String() string {
+	if l.path == "" {
+		return "(root)"
+	}
+	return l.path
+}
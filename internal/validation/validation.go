@@ -0,0 +1,261 @@
+// Package validation checks rendered Kubernetes manifests against the JSON
+// Schemas of their API versions, in the style of kubeconform.
+package validation
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// DefaultSchemaRepository is the git repository behind the default schema
+// location.
+const DefaultSchemaRepository = "https://github.com/yannh/kubernetes-json-schema.git"
+
+// DefaultSchemaLocation returns the schema location used when no location is
+// configured or when a location is given as "default", at version, a commit,
+// branch or tag of DefaultSchemaRepository. An empty version is master.
+func DefaultSchemaLocation(version string) string {
+	if version == "" {
+		version = "master"
+	}
+	return "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/" + version + "/" +
+		"{{ .NormalizedKubernetesVersion }}-standalone{{ .StrictSuffix }}/{{ .ResourceKind }}{{ .KindSuffix }}.json"
+}
+
+// ErrSchemaNotFound is returned by Validate when no location has a schema for
+// the object's kind.
+var ErrSchemaNotFound = errors.New("schema not found")
+
+// schemaCache holds schemas by resolved location for the whole process, so
+// charts scanned concurrently share downloads. A nil entry records a miss.
+var schemaCache = struct {
+	sync.Mutex
+	schemas map[string]map[string]interface{}
+}{schemas: make(map[string]map[string]interface{})}
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// Validator validates objects for one Kubernetes version.
+type Validator struct {
+	kubeVersion string
+	locations   []*template.Template
+	crds        map[string]map[string]interface{}
+	cacheDir    string
+}
+
+// templateData are the fields available in schema location templates. They
+// match kubeconform's so that its location templates can be reused.
+type templateData struct {
+	NormalizedKubernetesVersion string
+	StrictSuffix                string
+	ResourceKind                string
+	ResourceAPIVersion          string
+	Group                       string
+	KindSuffix                  string
+}
+
+// newTemplateData returns the template fields for apiVersion and kind.
+func newTemplateData(kubeVersion, apiVersion, kind string) templateData {
+	data := templateData{
+		NormalizedKubernetesVersion: kubeVersion,
+		StrictSuffix:                "-strict",
+		ResourceKind:                strings.ToLower(kind),
+	}
+	group, version, found := strings.Cut(apiVersion, "/")
+	if !found {
+		group, version = "", apiVersion
+	}
+	data.ResourceAPIVersion = version
+	data.Group = group
+	data.KindSuffix = "-" + version
+	if group != "" {
+		data.KindSuffix = "-" + strings.Split(group, ".")[0] + "-" + version
+	}
+	return data
+}
+
+// SchemaFile returns the file name of the schema of apiVersion and kind in
+// the default schema location, e.g. deployment-apps-v1.json.
+func SchemaFile(apiVersion, kind string) string {
+	data := newTemplateData("", apiVersion, kind)
+	return data.ResourceKind + data.KindSuffix + ".json"
+}
+
+// New returns a Validator for config. Without configured locations, the
+// schemas embedded in the binary are tried before the default location, unless
+// the default location is pinned to a SchemaVersion. The CRDs of CRDSchemas,
+// if set, take precedence over all locations. Schemas downloaded over HTTP are
+// cached on disk below the user cache directory.
+func New(config models.ValidationConfig) (*Validator, error) {
+	locations := config.SchemaLocations
+	if len(locations) == 0 {
+		locations = []string{"embedded", "default"}
+		if config.SchemaVersion != "" {
+			locations = []string{"default"}
+		}
+	}
+
+	v := &Validator{kubeVersion: normalizeKubeVersion(config.KubeVersion)}
+	for _, location := range locations {
+		switch location {
+		case "default":
+			location = DefaultSchemaLocation(config.SchemaVersion)
+		case "embedded":
+			location = embeddedLocation
+		}
+		tmpl, err := template.New("schemaLocation").Parse(location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema location %q: %v", location, err)
+		}
+		v.locations = append(v.locations, tmpl)
+	}
+	if config.CRDSchemas != "" {
+		crds, err := loadCRDSchemas(config.CRDSchemas)
+		if err != nil {
+			return nil, err
+		}
+		v.crds = crds
+	}
+	if cacheDir, err := os.UserCacheDir(); err == nil {
+		v.cacheDir = filepath.Join(cacheDir, "chartscan", "schemas")
+	}
+	return v, nil
+}
+
+// Validate checks object against the schema of its apiVersion and kind and
+// returns the violations. ErrSchemaNotFound is returned when no configured
+// location provides a schema.
+func (v *Validator) Validate(object map[string]interface{}) ([]string, error) {
+	apiVersion, _ := object["apiVersion"].(string)
+	kind, _ := object["kind"].(string)
+	if apiVersion == "" || kind == "" {
+		return []string{"missing apiVersion or kind"}, nil
+	}
+
+	schema, err := v.schemaFor(apiVersion, kind)
+	if err != nil {
+		return nil, err
+	}
+	return validate(schema, object, location{}), nil
+}
+
+// schemaFor returns the schema of a loaded CRD for apiVersion and kind or
+// else the first schema found across the configured locations.
+func (v *Validator) schemaFor(apiVersion, kind string) (map[string]interface{}, error) {
+	if schema, ok := v.crds[apiVersion+"/"+kind]; ok {
+		return schema, nil
+	}
+	data := newTemplateData(v.kubeVersion, apiVersion, kind)
+	for _, location := range v.locations {
+		var buf bytes.Buffer
+		if err := location.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("error expanding schema location: %v", err)
+		}
+		schema, err := v.load(buf.String())
+		if err != nil {
+			return nil, err
+		}
+		if schema != nil {
+			return schema, nil
+		}
+	}
+	return nil, fmt.Errorf("%w for %s %s (kubernetes %s)", ErrSchemaNotFound, apiVersion, kind, v.kubeVersion)
+}
+
+// load returns the schema at location, or nil if it does not exist.
+func (v *Validator) load(location string) (map[string]interface{}, error) {
+	schemaCache.Lock()
+	schema, cached := schemaCache.schemas[location]
+	schemaCache.Unlock()
+	if cached {
+		return schema, nil
+	}
+
+	data, err := v.read(location)
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		if err := json.Unmarshal(data, &schema); err != nil {
+			return nil, fmt.Errorf("error parsing schema %s: %v", location, err)
+		}
+	}
+
+	schemaCache.Lock()
+	schemaCache.schemas[location] = schema
+	schemaCache.Unlock()
+	return schema, nil
+}
+
+// read fetches a schema from the embedded bundles, a URL (through the disk
+// cache) or a local file. A missing schema yields nil data and no error.
+func (v *Validator) read(location string) ([]byte, error) {
+	if strings.HasPrefix(location, embeddedPrefix) {
+		return readEmbedded(location)
+	}
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		data, err := os.ReadFile(location)
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return data, err
+	}
+
+	var cacheFile string
+	if v.cacheDir != "" {
+		sum := sha256.Sum256([]byte(location))
+		cacheFile = filepath.Join(v.cacheDir, hex.EncodeToString(sum[:])+".json")
+		if data, err := os.ReadFile(cacheFile); err == nil {
+			return data, nil
+		}
+	}
+
+	resp, err := httpClient.Get(location)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching schema %s: %v", location, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching schema %s: %s", location, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching schema %s: %v", location, err)
+	}
+
+	if cacheFile != "" {
+		if err := os.MkdirAll(v.cacheDir, 0755); err == nil {
+			os.WriteFile(cacheFile, data, 0644)
+		}
+	}
+	return data, nil
+}
+
+// normalizeKubeVersion turns 1.29 or v1.29 into v1.29.0; "master" is kept.
+func normalizeKubeVersion(version string) string {
+	if version == "" || version == "master" {
+		return "master"
+	}
+	version = "v" + strings.TrimPrefix(version, "v")
+	if strings.Count(version, ".") == 1 {
+		version += ".0"
+	}
+	return version
+}
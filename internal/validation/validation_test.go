@@ -0,0 +1,123 @@
+package validation
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+const deploymentSchema = `{
+  "type": "object",
+  "required": ["spec"],
+  "properties": {
+    "apiVersion": {"type": ["string", "null"]},
+    "kind": {"type": ["string", "null"]},
+    "metadata": {"type": "object", "properties": {"name": {"type": "string"}}, "additionalProperties": false},
+    "spec": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "replicas": {"type": "integer", "minimum": 0},
+        "strategy": {"type": "object", "properties": {"type": {"type": "string", "enum": ["Recreate", "RollingUpdate"]}}},
+        "template": {
+          "type": "object",
+          "properties": {
+            "spec": {
+              "type": "object",
+              "properties": {
+                "containers": {
+                  "type": "array",
+                  "items": {
+                    "type": "object",
+                    "required": ["name"],
+                    "properties": {
+                      "name": {"type": "string"},
+                      "ports": {"type": "array", "items": {"type": "object", "properties": {"containerPort": {"x-kubernetes-int-or-string": true}}}}
+                    }
+                  }
+                }
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "deployment-apps-v1.json"), []byte(deploymentSchema), 0644); err != nil {
+		t.Fatalf("Failed to write schema: %v", err)
+	}
+
+	validator, err := New(models.ValidationConfig{
+		KubeVersion:     "1.29",
+		SchemaLocations: []string{filepath.Join(dir, "{{ .ResourceKind }}{{ .KindSuffix }}.json")},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	problems, err := validator.Validate(map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "web", "lables": map[string]interface{}{}},
+		"spec": map[string]interface{}{
+			"replicas": "3",
+			"strategy": map[string]interface{}{"type": "Rolling"},
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"ports": []interface{}{map[string]interface{}{"containerPort": 8080}}},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		`metadata: unknown field "lables"`,
+		"spec.replicas: expected integer, got string",
+		"spec.strategy.type: value Rolling is not one of [Recreate RollingUpdate]",
+		`spec.template.spec.containers[0]: missing required field "name"`,
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected %d problems, got %d: %v", len(expected), len(problems), problems)
+	}
+	for i, problem := range expected {
+		if problems[i] != problem {
+			t.Errorf("Expected problem %d to be %q, got %q", i, problem, problems[i])
+		}
+	}
+
+	_, err = validator.Validate(map[string]interface{}{"apiVersion": "example.com/v1", "kind": "Widget"})
+	if !errors.Is(err, ErrSchemaNotFound) || !strings.Contains(err.Error(), "example.com/v1 Widget (kubernetes v1.29.0)") {
+		t.Errorf("Expected a schema not found error, got %v", err)
+	}
+}
+
+func TestNormalizeKubeVersion(t *testing.T) {
+	tests := map[string]string{"": "master", "1.29": "v1.29.0", "v1.30.2": "v1.30.2", "master": "master"}
+	for input, expected := range tests {
+		if actual := normalizeKubeVersion(input); actual != expected {
+			t.Errorf("Expected %q to normalize to %s, got %s", input, expected, actual)
+		}
+	}
+}
+
+func TestDefaultSchemaLocation(t *testing.T) {
+	if location := DefaultSchemaLocation(""); !strings.HasPrefix(location, "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/") {
+		t.Errorf("Expected master by default, got %s", location)
+	}
+	if location := DefaultSchemaLocation("0123abcd"); !strings.HasPrefix(location, "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/0123abcd/") {
+		t.Errorf("Expected the pinned commit, got %s", location)
+	}
+}
@@ -0,0 +1,81 @@
+package validation
+
+import "strings"
+
+// ValidateValues checks the values of a chart against its values.schema.json,
+// as helm does before rendering, and returns the violations, each prefixed
+// with the JSON pointer of the offending value, e.g. "/image/tag: expected
+// string, got integer". References to other parts of the schema, such as
+// {"$ref": "#/definitions/port"}, are resolved first.
+func ValidateValues(schema map[string]interface{}, values map[string]interface{}) []string {
+	resolved, _ := resolveRefs(schema, schema, nil).(map[string]interface{})
+	return validate(resolved, values, location{pointer: true})
+}
+
+// maxRefDepth bounds how often a recursive definition is expanded into
+// itself; values nested deeper are not checked.
+const maxRefDepth = 5
+
+// resolveRefs returns node with every local $ref replaced by the part of
+// root it points at. References that cannot be resolved, and those nested
+// more than maxRefDepth times into their own definition, are replaced by an
+// empty schema accepting everything.
+func resolveRefs(node interface{}, root map[string]interface{}, resolving []string) interface{} {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if ref, ok := n["$ref"].(string); ok {
+			target, found := lookupRef(root, ref)
+			if !found || countRef(resolving, ref) >= maxRefDepth {
+				return map[string]interface{}{}
+			}
+			return resolveRefs(target, root, append(resolving, ref))
+		}
+		resolved := make(map[string]interface{}, len(n))
+		for key, value := range n {
+			resolved[key] = resolveRefs(value, root, resolving)
+		}
+		return resolved
+	case []interface{}:
+		resolved := make([]interface{}, len(n))
+		for i, value := range n {
+			resolved[i] = resolveRefs(value, root, resolving)
+		}
+		return resolved
+	}
+	return node
+}
+
+// countRef returns how often ref is being resolved.
+func countRef(resolving []string, ref string) int {
+	count := 0
+	for _, r := range resolving {
+		if r == ref {
+			count++
+		}
+	}
+	return count
+}
+
+// lookupRef returns the part of root that the local reference ref, a JSON
+// pointer such as #/definitions/port, points at.
+func lookupRef(root map[string]interface{}, ref string) (interface{}, bool) {
+	pointer, ok := strings.CutPrefix(ref, "#")
+	if !ok {
+		return nil, false
+	}
+	var node interface{} = root
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if token == "" {
+			continue
+		}
+		object, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		if node, ok = object[token]; !ok {
+			return nil, false
+		}
+	}
+	return node, true
+}
@@ -0,0 +1,76 @@
+package validation
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const valuesSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "required": ["image"],
+  "additionalProperties": false,
+  "definitions": {
+    "port": {"type": "integer", "minimum": 1, "maximum": 65535},
+    "node": {"type": "object", "properties": {"children": {"type": "array", "items": {"$ref": "#/definitions/node"}}}}
+  },
+  "properties": {
+    "image": {
+      "type": "object",
+      "properties": {
+        "repository": {"type": "string", "minLength": 1},
+        "tag": {"type": "string", "pattern": "^v?[0-9]"},
+        "pullPolicy": {"enum": ["Always", "IfNotPresent", "Never"]}
+      }
+    },
+    "service": {"type": "object", "properties": {"port": {"$ref": "#/definitions/port"}}},
+    "tree": {"$ref": "#/definitions/node"},
+    "apiVersion": {"const": "v2"},
+    "annotations": {"type": "object", "additionalProperties": {"type": "string"}},
+    "extra": {"$ref": "#/definitions/missing"}
+  }
+}`
+
+func TestValidateValues(t *testing.T) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(valuesSchema), &schema); err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	values := map[string]interface{}{
+		"image": map[string]interface{}{
+			"repository": "",
+			"tag":        "latest",
+			"pullPolicy": "Sometimes",
+		},
+		"service":     map[string]interface{}{"port": 70000},
+		"tree":        map[string]interface{}{"children": []interface{}{map[string]interface{}{"children": "none"}}},
+		"apiVersion":  "v1",
+		"annotations": map[string]interface{}{"example.com/ttl": 60},
+		"extra":       true,
+		"replicas":    2,
+	}
+	problems := ValidateValues(schema, values)
+	expected := []string{
+		"/annotations/example.com~1ttl: expected string, got integer",
+		"/apiVersion: value v1 is not v2",
+		"/image/pullPolicy: value Sometimes is not one of [Always IfNotPresent Never]",
+		"/image/repository: shorter than 1 characters",
+		`/image/tag: "latest" does not match the pattern ^v?[0-9]`,
+		`(root): unknown field "replicas"`,
+		"/service/port: 70000 is greater than the maximum 65535",
+		"/tree/children/0/children: expected array, got string",
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected %d problems, got %d: %v", len(expected), len(problems), problems)
+	}
+	for i, problem := range expected {
+		if problems[i] != problem {
+			t.Errorf("Expected problem %d to be %q, got %q", i, problem, problems[i])
+		}
+	}
+
+	if problems := ValidateValues(schema, map[string]interface{}{}); len(problems) != 1 || problems[0] != `(root): missing required field "image"` {
+		t.Errorf("Expected the missing required image, got %v", problems)
+	}
+}
@@ -0,0 +1,346 @@
+// Package valuesfmt checks and fixes the key order and indentation of chart
+// values files, so large values files stay reviewable.
+package valuesfmt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Jaydee94/chartscan/internal/models"
+)
+
+// Key orders accepted by Options.Order.
+const (
+	OrderAlphabetical = "alphabetical"
+	OrderSchema       = "schema"
+)
+
+// defaultIndent is the number of spaces per level when Options.Indent is 0.
+const defaultIndent = 2
+
+// Options controls the expected layout of a values file.
+type Options struct {
+	// Order is OrderAlphabetical (the default) or OrderSchema.
+	Order string
+	// Indent is the number of spaces per nesting level, 2 by default.
+	Indent int
+	// SchemaOrder maps the path of each mapping to its keys in the order
+	// the schema declares them, as returned by SchemaOrder. Mappings the
+	// schema does not describe, and keys it does not declare, are ordered
+	// alphabetically after the declared ones.
+	SchemaOrder map[string][]string
+}
+
+// Problem is a key order or indentation problem at a line of a values file.
+type Problem struct {
+	Line    int
+	Message string
+}
+
+// ChartOptions returns the options for the values.yaml of the chart in
+// chartDir under config, loading the chart's values.schema.json for the
+// schema order.
+func ChartOptions(chartDir string, config models.ValuesFormatConfig) (Options, error) {
+	opts := Options{Order: config.Order, Indent: config.Indent}
+	switch opts.Order {
+	case "":
+		opts.Order = OrderAlphabetical
+	case OrderAlphabetical:
+	case OrderSchema:
+		data, err := os.ReadFile(filepath.Join(chartDir, "values.schema.json"))
+		if err != nil {
+			return opts, fmt.Errorf("valuesFormat.order is schema but the chart has no readable values.schema.json: %v", err)
+		}
+		if opts.SchemaOrder, err = SchemaOrder(data); err != nil {
+			return opts, err
+		}
+	default:
+		return opts, fmt.Errorf("invalid valuesFormat.order %q (expected %s or %s)", opts.Order, OrderAlphabetical, OrderSchema)
+	}
+	if opts.Indent == 0 {
+		opts.Indent = defaultIndent
+	}
+	if opts.Indent < 0 {
+		return opts, fmt.Errorf("invalid valuesFormat.indent %d", opts.Indent)
+	}
+	return opts, nil
+}
+
+// SchemaOrder returns the declared order of the properties of every object
+// in a JSON schema, keyed by their path. Paths join keys with dots; the items
+// of an array add "[]".
+func SchemaOrder(data []byte) (map[string][]string, error) {
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("values.schema.json is not valid JSON")
+	}
+	// JSON is YAML, and yaml.v3 keeps the order of the properties.
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing values.schema.json: %v", err)
+	}
+	order := make(map[string][]string)
+	if len(doc.Content) > 0 {
+		collectSchemaOrder(doc.Content[0], "", order)
+	}
+	return order, nil
+}
+
+// collectSchemaOrder records the property order of the schema node at path
+// and of its nested schemas.
+func collectSchemaOrder(node *yaml.Node, path string, order map[string][]string) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	if properties := mappingValue(node, "properties"); properties != nil && properties.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(properties.Content); i += 2 {
+			key := properties.Content[i].Value
+			order[path] = append(order[path], key)
+			collectSchemaOrder(properties.Content[i+1], joinPath(path, key), order)
+		}
+	}
+	if items := mappingValue(node, "items"); items != nil {
+		collectSchemaOrder(items, path+"[]", order)
+	}
+}
+
+// mappingValue returns the value of key in a mapping node, or nil.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// joinPath appends key to the dotted path.
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// describe names the mapping at path in messages.
+func describe(path string) string {
+	if path == "" {
+		return "the top level"
+	}
+	return path
+}
+
+// Check returns the key order and indentation problems of a values file,
+// ordered by line.
+func Check(data []byte, opts Options) ([]Problem, error) {
+	root, err := parse(data)
+	if err != nil || root == nil {
+		return nil, err
+	}
+	c := checker{opts: opts}
+	if root.Kind == yaml.MappingNode {
+		c.mapping(root, "", 1)
+	}
+	sort.SliceStable(c.problems, func(i, j int) bool { return c.problems[i].Line < c.problems[j].Line })
+	return c.problems, nil
+}
+
+// checker collects the problems of a values file.
+type checker struct {
+	opts     Options
+	problems []Problem
+}
+
+func (c *checker) add(line int, format string, args ...interface{}) {
+	c.problems = append(c.problems, Problem{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+// mapping checks a block mapping whose keys are expected at column.
+func (c *checker) mapping(node *yaml.Node, path string, column int) {
+	if node.Style&yaml.FlowStyle != 0 {
+		return
+	}
+	less := c.opts.less(path)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if i == 0 && key.Column != column {
+			c.add(key.Line, "%s is indented by %d spaces; expected %d", joinPath(path, key.Value), key.Column-1, column-1)
+		}
+		if i > 0 {
+			if previous := node.Content[i-2]; less(key.Value, previous.Value) {
+				c.add(key.Line, "%s is not in %s order: %s should come before %s", describe(path), c.opts.Order, key.Value, previous.Value)
+			}
+		}
+		c.value(value, joinPath(path, key.Value), key.Column)
+	}
+}
+
+// value checks the value of a key at keyColumn, whose nested content is
+// expected one indentation level deeper.
+func (c *checker) value(node *yaml.Node, path string, keyColumn int) {
+	if node.Style&yaml.FlowStyle != 0 || len(node.Content) == 0 {
+		return
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		c.mapping(node, path, keyColumn+c.opts.Indent)
+	case yaml.SequenceNode:
+		// Items start after their "- " marker.
+		expected := keyColumn + c.opts.Indent + 2
+		if first := node.Content[0]; first.Column != expected {
+			c.add(first.Line, "items of %s are indented by %d spaces; expected %d", path, first.Column-3, expected-3)
+		}
+		for _, item := range node.Content {
+			if item.Kind == yaml.MappingNode {
+				c.mapping(item, path+"[]", item.Column)
+			}
+		}
+	}
+}
+
+// less returns the ordering of the keys of the mapping at path.
+func (opts Options) less(path string) func(a, b string) bool {
+	declared := opts.SchemaOrder[path]
+	if opts.Order != OrderSchema || len(declared) == 0 {
+		return func(a, b string) bool { return a < b }
+	}
+	rank := make(map[string]int, len(declared))
+	for i, key := range declared {
+		rank[key] = i
+	}
+	return func(a, b string) bool {
+		rankA, knownA := rank[a]
+		rankB, knownB := rank[b]
+		switch {
+		case knownA && knownB:
+			return rankA < rankB
+		case knownA != knownB:
+			return knownA
+		default:
+			return a < b
+		}
+	}
+}
+
+// Format returns the values file with its keys in order and indented by
+// opts.Indent spaces. Comments and the blank lines between top-level keys are
+// kept.
+func Format(data []byte, opts Options) ([]byte, error) {
+	root, err := parse(data)
+	if err != nil || root == nil {
+		return data, err
+	}
+	sortMapping(root, "", opts)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(opts.Indent)
+	if err := encoder.Encode(root); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return restoreBlankLines(buf.Bytes(), blankLinesBefore(data, root)), nil
+}
+
+// parse returns the root node of a values file, or nil for an empty file.
+func parse(data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	return doc.Content[0], nil
+}
+
+// sortMapping orders the keys of the mappings in node and its children.
+func sortMapping(node *yaml.Node, path string, opts Options) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		type pair struct{ key, value *yaml.Node }
+		pairs := make([]pair, 0, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			pairs = append(pairs, pair{node.Content[i], node.Content[i+1]})
+		}
+		less := opts.less(path)
+		sort.SliceStable(pairs, func(i, j int) bool { return less(pairs[i].key.Value, pairs[j].key.Value) })
+		node.Content = node.Content[:0]
+		for _, p := range pairs {
+			node.Content = append(node.Content, p.key, p.value)
+			sortMapping(p.value, joinPath(path, p.key.Value), opts)
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			sortMapping(item, path+"[]", opts)
+		}
+	}
+}
+
+// blankLinesBefore returns the top-level keys of root preceded by a blank line
+// in data, above their comments. In files separating sections by blank lines,
+// keys with a comment start a section wherever they move to.
+func blankLinesBefore(data []byte, root *yaml.Node) map[string]bool {
+	lines := strings.Split(string(data), "\n")
+	blank := make(map[string]bool)
+	if root.Kind != yaml.MappingNode {
+		return blank
+	}
+	sections := false
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key := root.Content[i]
+		// Line numbers are 1-based; walk up past the key's comments.
+		line := key.Line - 2
+		for line >= 0 && strings.HasPrefix(strings.TrimSpace(lines[line]), "#") {
+			line--
+		}
+		blank[key.Value] = line >= 0 && strings.TrimSpace(lines[line]) == ""
+		sections = sections || blank[key.Value]
+	}
+	if sections {
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if key := root.Content[i]; key.HeadComment != "" {
+				blank[key.Value] = true
+			}
+		}
+	}
+	return blank
+}
+
+// restoreBlankLines inserts a blank line before the top-level keys of the
+// formatted output, and their comments, that had one in the original.
+func restoreBlankLines(formatted []byte, blank map[string]bool) []byte {
+	lines := strings.Split(string(formatted), "\n")
+	var out []string
+	commentStart := -1
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "#"):
+			if commentStart < 0 {
+				commentStart = len(out)
+			}
+		case line != "" && line[0] != ' ' && line[0] != '-':
+			key := strings.Trim(strings.SplitN(line, ":", 2)[0], `"'`)
+			insertAt := len(out)
+			if commentStart >= 0 {
+				insertAt = commentStart
+			}
+			if blank[key] && insertAt > 0 {
+				out = append(out[:insertAt], append([]string{""}, out[insertAt:]...)...)
+			}
+			commentStart = -1
+		default:
+			commentStart = -1
+		}
+		out = append(out, line)
+	}
+	return []byte(strings.Join(out, "\n"))
+}
@@ -0,0 +1,117 @@
+package valuesfmt
+
+import (
+	"strings"
+	"testing"
+)
+
+const unformatted = `# Image settings
+image:
+    tag: "1.0"
+    repository: nginx
+
+# Number of pods
+replicaCount: 1
+ingress:
+  hosts:
+  - host: example.com
+    paths: []
+  enabled: false
+`
+
+func TestCheck(t *testing.T) {
+	problems, err := Check([]byte(unformatted), Options{Order: OrderAlphabetical, Indent: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var got []string
+	for _, problem := range problems {
+		got = append(got, problem.Message)
+	}
+	expected := []string{
+		"image.tag is indented by 4 spaces; expected 2",
+		"image is not in alphabetical order: repository should come before tag",
+		"the top level is not in alphabetical order: ingress should come before replicaCount",
+		"items of ingress.hosts are indented by 2 spaces; expected 4",
+		"ingress is not in alphabetical order: enabled should come before hosts",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected problems:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+	if problems[0].Line != 3 || problems[2].Line != 8 {
+		t.Errorf("Expected problems on lines 3 and 8, got %v", problems)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	opts := Options{Order: OrderAlphabetical, Indent: 2}
+	formatted, err := Format([]byte(unformatted), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `# Image settings
+image:
+  repository: nginx
+  tag: "1.0"
+ingress:
+  enabled: false
+  hosts:
+    - host: example.com
+      paths: []
+
+# Number of pods
+replicaCount: 1
+`
+	if string(formatted) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, formatted)
+	}
+	if problems, _ := Check(formatted, opts); len(problems) != 0 {
+		t.Errorf("Expected formatted values to have no problems, got %v", problems)
+	}
+}
+
+func TestSchemaOrder(t *testing.T) {
+	schema := `{
+  "properties": {
+    "replicaCount": {"type": "integer"},
+    "image": {
+      "properties": {"repository": {}, "tag": {}, "pullPolicy": {}}
+    },
+    "hosts": {"type": "array", "items": {"properties": {"name": {}, "aliases": {}}}}
+  }
+}`
+	order, err := SchemaOrder([]byte(schema))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Join(order[""], ",") != "replicaCount,image,hosts" || strings.Join(order["image"], ",") != "repository,tag,pullPolicy" || strings.Join(order["hosts[]"], ",") != "name,aliases" {
+		t.Fatalf("Unexpected schema order: %v", order)
+	}
+
+	values := `replicaCount: 1
+image:
+  repository: nginx
+  tag: "1.0"
+hosts:
+  - name: a
+    aliases: []
+extra: true
+annotations:
+  b: "2"
+  a: "1"
+`
+	opts := Options{Order: OrderSchema, Indent: 2, SchemaOrder: order}
+	problems, err := Check([]byte(values), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(problems) != 2 ||
+		problems[0].Message != "the top level is not in schema order: annotations should come before extra" ||
+		problems[1].Message != "annotations is not in schema order: a should come before b" {
+		t.Errorf("Expected undeclared keys to sort alphabetically after declared ones, got %v", problems)
+	}
+
+	if _, err := SchemaOrder([]byte("properties: {}")); err == nil {
+		t.Errorf("Expected an error for a schema that is not JSON")
+	}
+}
@@ -0,0 +1,40 @@
+package valuessource
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestHTTPSSourceFetch(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "ci" || password != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/values.yaml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("replicas: 2\n"))
+	}))
+	defer server.Close()
+	defer func(client *http.Client) { httpClient = client }(httpClient)
+	httpClient = server.Client()
+
+	uri, _ := url.Parse(server.URL + "/values.yaml")
+	uri.User = url.UserPassword("ci", "token")
+	data, err := httpsSource{}.Fetch(uri)
+	if err != nil || string(data) != "replicas: 2\n" {
+		t.Errorf("Expected the values document, got %q (%v)", data, err)
+	}
+
+	uri.User = nil
+	if _, err := (httpsSource{}).Fetch(uri); err == nil {
+		t.Errorf("Expected an error for an unauthorized request")
+	}
+	if (httpsSource{}).Sensitive() {
+		t.Errorf("Expected https values not to be treated as secrets")
+	}
+}
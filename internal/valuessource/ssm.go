@@ -0,0 +1,38 @@
+package valuessource
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+)
+
+func init() {
+	Register("aws-ssm", ssmSource{})
+}
+
+// ssmSource reads parameters of AWS Systems Manager Parameter Store holding
+// a YAML document at aws-ssm://<name>, e.g. aws-ssm:///shop/prod/values for
+// the parameter /shop/prod/values, with the aws CLI and its usual
+// credentials. SecureString parameters are decrypted; ?region=<region>
+// selects the region.
+type ssmSource struct{}
+
+func (ssmSource) Fetch(uri *url.URL) ([]byte, error) {
+	name := uri.Host + uri.Path
+	args := []string{"ssm", "get-parameter", "--name", name, "--with-decryption", "--query", "Parameter.Value", "--output", "text"}
+	if region := uri.Query().Get("region"); region != "" {
+		args = append(args, "--region", region)
+	}
+	cmd := exec.Command("aws", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error reading the parameter %s: %v: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
+
+func (ssmSource) Sensitive() bool { return true }
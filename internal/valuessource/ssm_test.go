@@ -0,0 +1,35 @@
+package valuessource
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSSMSourceFetch(t *testing.T) {
+	bin := t.TempDir()
+	argsFile := filepath.Join(t.TempDir(), "args")
+	script := "#!/bin/sh\necho \"$@\" > " + argsFile + "\nprintf 'replicas: 2\\n'\n"
+	if err := os.WriteFile(filepath.Join(bin, "aws"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write aws: %v", err)
+	}
+	t.Setenv("PATH", bin)
+
+	uri, _ := url.Parse("aws-ssm:///shop/prod/values?region=eu-west-1")
+	data, err := ssmSource{}.Fetch(uri)
+	if err != nil || string(data) != "replicas: 2\n" {
+		t.Errorf("Expected the parameter value, got %q (%v)", data, err)
+	}
+	args, _ := os.ReadFile(argsFile)
+	expected := "ssm get-parameter --name /shop/prod/values --with-decryption --query Parameter.Value --output text --region eu-west-1"
+	if got := strings.TrimSpace(string(args)); got != expected {
+		t.Errorf("Expected aws %s, got aws %s", expected, got)
+	}
+
+	os.WriteFile(filepath.Join(bin, "aws"), []byte("#!/bin/sh\necho 'ParameterNotFound' >&2\nexit 254\n"), 0755)
+	if _, err := (ssmSource{}).Fetch(uri); err == nil || !strings.Contains(err.Error(), "ParameterNotFound") {
+		t.Errorf("Expected the aws error, got %v", err)
+	}
+}
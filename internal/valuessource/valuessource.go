@@ -0,0 +1,213 @@
+// Package valuessource resolves values files given as URIs, such as
+// https://, vault:// or aws-ssm://, into local files helm can read, for
+// pipelines whose final values live in remote parameter stores. Each URI is
+// fetched once per run, and values from sources holding secrets are redacted
+// from what ChartScan reports.
+package valuessource
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Source fetches values documents from the URIs of one scheme.
+type Source interface {
+	// Fetch returns the YAML values document at uri.
+	Fetch(uri *url.URL) ([]byte, error)
+	// Sensitive reports whether the values of the source are secrets, which
+	// are redacted from reports.
+	Sensitive() bool
+}
+
+var sources = make(map[string]Source)
+
+// Register makes source fetch the values files whose URI has scheme. It is
+// meant to be called from init functions.
+func Register(scheme string, source Source) {
+	sources[scheme] = source
+}
+
+// Schemes returns the schemes of the registered sources, sorted.
+func Schemes() []string {
+	var schemes []string
+	for scheme := range sources {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// IsURI reports whether path is a URI of a registered source rather than a
+// local file.
+func IsURI(path string) bool {
+	scheme, _, ok := strings.Cut(path, "://")
+	return ok && sources[scheme] != nil
+}
+
+// minSecretLength is the length below which values are not redacted, since
+// replacing short values such as true or 3 would garble every message.
+const minSecretLength = 4
+
+// redacted replaces secret values in reports.
+const redacted = "[redacted]"
+
+// fetched is a values file fetched for a URI.
+type fetched struct {
+	path string
+	err  error
+}
+
+// cache holds the values files fetched during the run.
+var cache struct {
+	sync.Mutex
+	dir     string
+	files   map[string]fetched
+	uris    map[string]string
+	secrets []string
+}
+
+// Resolve returns valuesFiles with each URI replaced by a local file holding
+// the values fetched from it. Every URI is fetched once per run; a failed
+// fetch fails every later Resolve of the URI too. $VAR and ${VAR} in URIs
+// are expanded from the environment, so credentials stay out of the
+// configuration.
+func Resolve(valuesFiles []string) ([]string, error) {
+	if !slices.ContainsFunc(valuesFiles, IsURI) {
+		return valuesFiles, nil
+	}
+	cache.Lock()
+	defer cache.Unlock()
+
+	resolved := make([]string, len(valuesFiles))
+	for i, file := range valuesFiles {
+		resolved[i] = file
+		if !IsURI(file) {
+			continue
+		}
+		f, ok := cache.files[file]
+		if !ok {
+			f.path, f.err = fetch(file)
+			if cache.files == nil {
+				cache.files, cache.uris = make(map[string]fetched), make(map[string]string)
+			}
+			cache.files[file] = f
+			if f.err == nil {
+				cache.uris[f.path] = file
+			}
+		}
+		if f.err != nil {
+			return nil, f.err
+		}
+		resolved[i] = f.path
+	}
+	return resolved, nil
+}
+
+// fetch fetches the values at uri into a new file of the cache directory and
+// records the secrets of sensitive sources. The cache lock must be held.
+func fetch(uri string) (string, error) {
+	parsed, err := url.Parse(os.ExpandEnv(uri))
+	if err != nil {
+		// The error quotes the expanded URI, which may hold credentials.
+		return "", fmt.Errorf("invalid values URI %s: %v", uri, errors.Unwrap(err))
+	}
+	source := sources[parsed.Scheme]
+	data, err := source.Fetch(parsed)
+	if err != nil {
+		return "", fmt.Errorf("error fetching values from %s: %v", uri, err)
+	}
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return "", fmt.Errorf("values from %s are not a YAML map: %v", uri, err)
+	}
+	if source.Sensitive() {
+		collectSecrets(values)
+	}
+
+	if cache.dir == "" {
+		// MkdirTemp creates the directory accessible only by its owner,
+		// which keeps fetched secrets private.
+		if cache.dir, err = os.MkdirTemp("", "chartscan-values"); err != nil {
+			return "", fmt.Errorf("error creating temp dir: %v", err)
+		}
+	}
+	path := filepath.Join(cache.dir, fmt.Sprintf("%d.yaml", len(cache.files)))
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("error writing values from %s: %v", uri, err)
+	}
+	return path, nil
+}
+
+// collectSecrets records the strings in value for redaction, longest first
+// so that a secret containing another is redacted as a whole.
+func collectSecrets(value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, item := range v {
+			collectSecrets(item)
+		}
+	case []interface{}:
+		for _, item := range v {
+			collectSecrets(item)
+		}
+	case string:
+		if len(v) >= minSecretLength && !slices.Contains(cache.secrets, v) {
+			cache.secrets = append(cache.secrets, v)
+			sort.Slice(cache.secrets, func(i, j int) bool { return len(cache.secrets[i]) > len(cache.secrets[j]) })
+		}
+	}
+}
+
+// Redact replaces the local files of fetched URIs in text with their URI,
+// and the values of sensitive sources with [redacted].
+func Redact(text string) string {
+	cache.Lock()
+	defer cache.Unlock()
+	for path, uri := range cache.uris {
+		text = strings.ReplaceAll(text, path, uri)
+	}
+	for _, secret := range cache.secrets {
+		text = strings.ReplaceAll(text, secret, redacted)
+	}
+	return text
+}
+
+// RedactValues applies Redact to the strings in values, in place.
+func RedactValues(values map[string]interface{}) {
+	for key, value := range values {
+		values[key] = redactValue(value)
+	}
+}
+
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		RedactValues(v)
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+	case string:
+		return Redact(v)
+	}
+	return value
+}
+
+// Cleanup removes the fetched values files. URIs are fetched again after it.
+func Cleanup() {
+	cache.Lock()
+	defer cache.Unlock()
+	if cache.dir != "" {
+		os.RemoveAll(cache.dir)
+	}
+	cache.dir, cache.files, cache.uris = "", nil, nil
+}
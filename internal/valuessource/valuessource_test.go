@@ -0,0 +1,104 @@
+package valuessource
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+// fakeSource serves documents by URI host and counts the fetches.
+type fakeSource struct {
+	documents map[string]string
+	sensitive bool
+	fetches   int
+}
+
+func (s *fakeSource) Fetch(uri *url.URL) ([]byte, error) {
+	s.fetches++
+	document, ok := s.documents[uri.Host]
+	if !ok {
+		return nil, fmt.Errorf("no document %s", uri.Host)
+	}
+	return []byte(document), nil
+}
+
+func (s *fakeSource) Sensitive() bool { return s.sensitive }
+
+func TestResolve(t *testing.T) {
+	source := &fakeSource{documents: map[string]string{"app": "replicas: 2\n", "list": "- a\n"}}
+	Register("fake", source)
+	defer delete(sources, "fake")
+	defer Cleanup()
+
+	if !IsURI("fake://app") || IsURI("values.yaml") || IsURI("unknown://app") {
+		t.Errorf("Expected only URIs of registered sources to be URIs")
+	}
+
+	for range 2 {
+		resolved, err := Resolve([]string{"values.yaml", "fake://app"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if resolved[0] != "values.yaml" {
+			t.Errorf("Expected local files to be kept, got %s", resolved[0])
+		}
+		data, err := os.ReadFile(resolved[1])
+		if err != nil || string(data) != "replicas: 2\n" {
+			t.Errorf("Expected the fetched values in %s, got %q (%v)", resolved[1], data, err)
+		}
+	}
+	if source.fetches != 1 {
+		t.Errorf("Expected the URI to be fetched once, got %d fetches", source.fetches)
+	}
+
+	for _, uri := range []string{"fake://missing", "fake://list"} {
+		if _, err := Resolve([]string{uri}); err == nil || !strings.Contains(err.Error(), uri) {
+			t.Errorf("Expected an error naming %s, got %v", uri, err)
+		}
+	}
+	Resolve([]string{"fake://missing"})
+	if source.fetches != 3 {
+		t.Errorf("Expected failed fetches not to be retried, got %d fetches", source.fetches)
+	}
+
+	resolved, _ := Resolve([]string{"fake://app"})
+	Cleanup()
+	if _, err := os.Stat(resolved[0]); !os.IsNotExist(err) {
+		t.Errorf("Expected Cleanup to remove the fetched values, got %v", err)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	source := &fakeSource{documents: map[string]string{"db": "db:\n  password: hunter22\n  user: app\nhosts: [db.internal]\n"}, sensitive: true}
+	Register("secret", source)
+	defer delete(sources, "secret")
+	defer Cleanup()
+
+	resolved, err := Resolve([]string{"secret://db"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	message := "Error loading " + resolved[0] + ": password hunter22 for app at db.internal"
+	expected := "Error loading secret://db: password [redacted] for app at [redacted]"
+	if got := Redact(message); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	values := map[string]interface{}{
+		"db":    map[string]interface{}{"password": "hunter22", "port": 5432},
+		"hosts": []interface{}{"db.internal", "cache"},
+	}
+	RedactValues(values)
+	if password := values["db"].(map[string]interface{})["password"]; password != "[redacted]" {
+		t.Errorf("Expected the password to be redacted, got %v", password)
+	}
+	if port := values["db"].(map[string]interface{})["port"]; port != 5432 {
+		t.Errorf("Expected other values to be kept, got %v", port)
+	}
+	if hosts := values["hosts"].([]interface{}); hosts[0] != "[redacted]" || hosts[1] != "cache" {
+		t.Errorf("Expected only secrets in lists to be redacted, got %v", hosts)
+	}
+}
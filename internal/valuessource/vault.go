@@ -0,0 +1,77 @@
+package valuessource
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	Register("vault", vaultSource{})
+}
+
+// vaultSource reads secrets from HashiCorp Vault at vault://<path>, e.g.
+// vault://secret/data/shop/values for the KV version 2 secret shop/values of
+// the secret mount. The secret's keys are the values, or, with ?field=<key>,
+// the YAML document held by one of them. The server and token are taken from
+// VAULT_ADDR and VAULT_TOKEN, and the namespace from VAULT_NAMESPACE, as the
+// vault CLI does.
+type vaultSource struct{}
+
+func (vaultSource) Fetch(uri *url.URL) ([]byte, error) {
+	addr, token := os.Getenv("VAULT_ADDR"), os.Getenv("VAULT_TOKEN")
+	if addr == "" || token == "" {
+		return nil, fmt.Errorf("VAULT_ADDR and VAULT_TOKEN must be set")
+	}
+	path := strings.Trim(uri.Host+uri.Path, "/")
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(addr, "/")+"/v1/"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s reading %s", resp.Status, path)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var secret struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return nil, fmt.Errorf("error parsing the secret %s: %v", path, err)
+	}
+	data := secret.Data
+	// KV version 2 wraps the secret's keys with its metadata.
+	if nested, ok := data["data"].(map[string]interface{}); ok && data["metadata"] != nil {
+		data = nested
+	}
+
+	field := uri.Query().Get("field")
+	if field == "" {
+		return yaml.Marshal(data)
+	}
+	document, ok := data[field].(string)
+	if !ok {
+		return nil, fmt.Errorf("the secret %s has no field %s holding a string", path, field)
+	}
+	return []byte(document), nil
+}
+
+func (vaultSource) Sensitive() bool { return true }
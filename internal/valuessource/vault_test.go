@@ -0,0 +1,71 @@
+package valuessource
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestVaultSourceFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.token" || r.Header.Get("X-Vault-Namespace") != "team" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/shop":
+			w.Write([]byte(`{"data": {"data": {"password": "hunter22", "values": "replicas: 2\n"}, "metadata": {"version": 3}}}`))
+		case "/v1/kv/shop":
+			w.Write([]byte(`{"data": {"password": "hunter22"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("VAULT_ADDR", server.URL+"/")
+	t.Setenv("VAULT_TOKEN", "s.token")
+	t.Setenv("VAULT_NAMESPACE", "team")
+
+	tests := []struct {
+		uri      string
+		expected map[string]interface{}
+	}{
+		{"vault://secret/data/shop", map[string]interface{}{"password": "hunter22", "values": "replicas: 2\n"}},
+		{"vault://secret/data/shop?field=values", map[string]interface{}{"replicas": 2}},
+		{"vault://kv/shop", map[string]interface{}{"password": "hunter22"}},
+	}
+	for _, tt := range tests {
+		uri, _ := url.Parse(tt.uri)
+		data, err := vaultSource{}.Fetch(uri)
+		if err != nil {
+			t.Errorf("Expected no error for %s, got %v", tt.uri, err)
+			continue
+		}
+		var values map[string]interface{}
+		yaml.Unmarshal(data, &values)
+		if len(values) != len(tt.expected) {
+			t.Errorf("Expected %v for %s, got %v", tt.expected, tt.uri, values)
+		}
+		for key, value := range tt.expected {
+			if values[key] != value {
+				t.Errorf("Expected %s=%v for %s, got %v", key, value, tt.uri, values[key])
+			}
+		}
+	}
+
+	for _, uri := range []string{"vault://secret/data/shop?field=missing", "vault://secret/data/other"} {
+		parsed, _ := url.Parse(uri)
+		if _, err := (vaultSource{}).Fetch(parsed); err == nil {
+			t.Errorf("Expected an error for %s", uri)
+		}
+	}
+
+	t.Setenv("VAULT_TOKEN", "")
+	uri, _ := url.Parse("vault://secret/data/shop")
+	if _, err := (vaultSource{}).Fetch(uri); err == nil {
+		t.Errorf("Expected an error without VAULT_TOKEN")
+	}
+}
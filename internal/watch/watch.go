@@ -0,0 +1,287 @@
+// Package watch detects changes to chart files. By default the operating
+// system reports them (inotify, kqueue or ReadDirectoryChangesW); polling
+// modification times is an explicit fallback for filesystems that do not
+// deliver those events, such as network filesystems.
+package watch
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// fileState is what a change is detected from when polling.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watcher tracks the regular files under a set of files and directories.
+// Version control metadata is ignored.
+type Watcher struct {
+	paths []string
+	// events delivers the changes reported by the operating system. It is
+	// nil when polling.
+	events *fsnotify.Watcher
+	// files is the state of every file when polling.
+	files map[string]fileState
+}
+
+// New returns a Watcher for paths that is notified of changes by the
+// operating system. Directories are watched recursively, including those
+// created later; files are watched through their directory, so missing files
+// are watched for their creation if their directory exists.
+func New(paths ...string) (*Watcher, error) {
+	events, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{paths: paths, events: events}
+	for _, root := range paths {
+		if info, err := os.Stat(root); err == nil && info.IsDir() {
+			_, err = w.addDirs(root)
+		} else if err = events.Add(filepath.Dir(root)); errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+		if err != nil {
+			events.Close()
+			return nil, fmt.Errorf("failed to watch %s: %w", root, err)
+		}
+	}
+	return w, nil
+}
+
+// NewPolling returns a Watcher for paths that polls their modification times,
+// taking their current state as the baseline. Missing paths are watched for
+// their creation.
+func NewPolling(paths ...string) *Watcher {
+	w := &Watcher{paths: paths}
+	w.files = w.snapshot()
+	return w
+}
+
+// Changes returns the files created, modified or removed since the last call
+// of a polling Watcher, sorted, and makes the current state the new baseline.
+func (w *Watcher) Changes() []string {
+	current := w.snapshot()
+
+	var changed []string
+	for path, state := range current {
+		if previous, ok := w.files[path]; !ok || previous != state {
+			changed = append(changed, path)
+		}
+	}
+	for path := range w.files {
+		if _, ok := current[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	w.files = current
+
+	sort.Strings(changed)
+	return changed
+}
+
+// Run calls onChange with the changed files until stop is closed. Changes are
+// collected until none arrive for interval, so an editor saving several files
+// triggers a single call; when polling, interval is also how often the files
+// are checked. Changes made by onChange itself, such as `helm dependency
+// update` writing charts/, are discarded.
+func (w *Watcher) Run(interval time.Duration, stop <-chan struct{}, onChange func(changed []string)) {
+	if w.events == nil {
+		w.poll(interval, stop, onChange)
+		return
+	}
+	defer w.events.Close()
+
+	quiet := time.NewTimer(interval)
+	quiet.Stop()
+	var pending []string
+	for {
+		select {
+		case <-stop:
+			return
+		case event, ok := <-w.events.Events:
+			if !ok {
+				return
+			}
+			if changed := w.handle(event); len(changed) > 0 {
+				pending = append(pending, changed...)
+				quiet.Reset(interval)
+			}
+		case <-w.events.Errors:
+			// Events dropped by the operating system cannot be recovered;
+			// the next change of the same files is reported as usual.
+		case <-quiet.C:
+			onChange(unique(pending))
+			pending = nil
+			w.discard(interval)
+		}
+	}
+}
+
+// poll is Run for a polling Watcher.
+func (w *Watcher) poll(interval time.Duration, stop <-chan struct{}, onChange func(changed []string)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var pending []string
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		if changed := w.Changes(); len(changed) > 0 {
+			pending = append(pending, changed...)
+			continue
+		}
+		if len(pending) == 0 {
+			continue
+		}
+
+		onChange(unique(pending))
+		pending = nil
+		w.Changes()
+	}
+}
+
+// discard drops events until none arrive for quiet, still watching the
+// directories they create.
+func (w *Watcher) discard(quiet time.Duration) {
+	for {
+		select {
+		case event, ok := <-w.events.Events:
+			if !ok {
+				return
+			}
+			w.handle(event)
+		case <-w.events.Errors:
+		case <-time.After(quiet):
+			return
+		}
+	}
+}
+
+// handle returns the watched files that event changed. A created directory
+// is watched and the files already in it are returned, since they may have
+// been written before its watch was added.
+func (w *Watcher) handle(event fsnotify.Event) []string {
+	if event.Op == fsnotify.Chmod {
+		return nil
+	}
+	name := w.watchedName(event.Name)
+	if name == "" {
+		return nil
+	}
+	if event.Has(fsnotify.Create) {
+		if info, err := os.Stat(name); err == nil && info.IsDir() {
+			files, _ := w.addDirs(name) //nolint:errcheck
+			return files
+		}
+	}
+	return []string{name}
+}
+
+// watchedName returns name in the form its watched path was given in, or ""
+// if it is not below a watched path or is version control metadata.
+func (w *Watcher) watchedName(name string) string {
+	name = filepath.Clean(name)
+	for _, root := range w.paths {
+		if !Under(name, root) {
+			continue
+		}
+		rel, _ := filepath.Rel(root, name) //nolint:errcheck
+		if slices.Contains(strings.Split(rel, string(filepath.Separator)), ".git") {
+			return ""
+		}
+		if rel == "." {
+			return root
+		}
+		return name
+	}
+	return ""
+}
+
+// addDirs watches dir and the directories below it, and returns the regular
+// files in them.
+func (w *Watcher) addDirs(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// Missing or unreadable directories are not watched.
+			return nil
+		}
+		if !entry.IsDir() {
+			if entry.Type().IsRegular() {
+				files = append(files, path)
+			}
+			return nil
+		}
+		if entry.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		return w.events.Add(path)
+	})
+	return files, err
+}
+
+// snapshot returns the state of every regular file under the watched paths.
+func (w *Watcher) snapshot() map[string]fileState {
+	files := make(map[string]fileState)
+	for _, root := range w.paths {
+		filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error { //nolint:errcheck
+			if err != nil {
+				// Missing or unreadable files are treated as absent.
+				return nil
+			}
+			if entry.IsDir() {
+				if entry.Name() == ".git" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !entry.Type().IsRegular() {
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return nil
+			}
+			files[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+			return nil
+		})
+	}
+	return files
+}
+
+// unique returns the sorted distinct entries of paths.
+func unique(paths []string) []string {
+	sort.Strings(paths)
+	var result []string
+	for i, path := range paths {
+		if i == 0 || path != paths[i-1] {
+			result = append(result, path)
+		}
+	}
+	return result
+}
+
+// Under reports whether path is dir or inside it. Both must be absolute or
+// both relative.
+func Under(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,164 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestChanges(t *testing.T) {
+	dir := t.TempDir()
+	template := filepath.Join(dir, "templates", "deployment.yaml")
+	values := filepath.Join(dir, "values.yaml")
+	writeFile(t, template, "kind: Deployment\n")
+	writeFile(t, values, "replicas: 1\n")
+	writeFile(t, filepath.Join(dir, ".git", "HEAD"), "ref: refs/heads/main\n")
+	extraValues := filepath.Join(t.TempDir(), "values-prod.yaml")
+
+	w := NewPolling(dir, extraValues)
+	if changed := w.Changes(); len(changed) != 0 {
+		t.Fatalf("Expected no changes, got %v", changed)
+	}
+
+	writeFile(t, values, "replicas: 3\n")
+	os.Remove(template)
+	writeFile(t, extraValues, "replicas: 5\n")
+	writeFile(t, filepath.Join(dir, ".git", "HEAD"), "ref: refs/heads/feature\n")
+
+	changed := w.Changes()
+	expected := []string{template, values, extraValues}
+	for _, path := range expected {
+		found := false
+		for _, c := range changed {
+			found = found || c == path
+		}
+		if !found {
+			t.Errorf("Expected %s to be reported, got %v", path, changed)
+		}
+	}
+	if len(changed) != len(expected) {
+		t.Errorf("Expected %d changes, got %v", len(expected), changed)
+	}
+
+	if changed := w.Changes(); len(changed) != 0 {
+		t.Errorf("Expected the baseline to be updated, got %v", changed)
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests := map[string]func(t *testing.T, dir string) *Watcher{
+		"events": func(t *testing.T, dir string) *Watcher {
+			w, err := New(dir)
+			if err != nil {
+				t.Fatalf("Failed to watch %s: %v", dir, err)
+			}
+			return w
+		},
+		"polling": func(t *testing.T, dir string) *Watcher {
+			return NewPolling(dir)
+		},
+	}
+	for name, newWatcher := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, "values.yaml"), "replicas: 1\n")
+
+			w := newWatcher(t, dir)
+			stop := make(chan struct{})
+			calls := make(chan []string, 10)
+			done := make(chan struct{})
+			go func() {
+				w.Run(10*time.Millisecond, stop, func(changed []string) {
+					// Files written while handling a change are not reported.
+					writeFile(t, filepath.Join(dir, "charts", "dep.tgz"), "archive")
+					calls <- changed
+				})
+				close(done)
+			}()
+
+			writeFile(t, filepath.Join(dir, "values.yaml"), "replicas: 2\n")
+			writeFile(t, filepath.Join(dir, "templates", "service.yaml"), "kind: Service\n")
+
+			select {
+			case changed := <-calls:
+				expected := []string{filepath.Join(dir, "templates", "service.yaml"), filepath.Join(dir, "values.yaml")}
+				if !slices.Equal(changed, expected) {
+					t.Errorf("Expected %v in a single call, got %v", expected, changed)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("Expected a call after files changed")
+			}
+
+			time.Sleep(100 * time.Millisecond)
+			close(stop)
+			<-done
+			if len(calls) != 0 {
+				t.Errorf("Expected no call for files written by the handler, got %v", <-calls)
+			}
+		})
+	}
+}
+
+func TestRunWatchesFiles(t *testing.T) {
+	dir := t.TempDir()
+	values := filepath.Join(dir, "values-prod.yaml")
+	writeFile(t, filepath.Join(dir, ".git", "HEAD"), "ref: refs/heads/main\n")
+
+	w, err := New(values)
+	if err != nil {
+		t.Fatalf("Failed to watch %s: %v", values, err)
+	}
+	stop := make(chan struct{})
+	calls := make(chan []string, 10)
+	done := make(chan struct{})
+	go func() {
+		w.Run(10*time.Millisecond, stop, func(changed []string) { calls <- changed })
+		close(done)
+	}()
+	defer func() {
+		close(stop)
+		<-done
+	}()
+
+	// Other files in the directory of a watched file are not reported.
+	writeFile(t, filepath.Join(dir, "values-dev.yaml"), "replicas: 1\n")
+	writeFile(t, filepath.Join(dir, ".git", "HEAD"), "ref: refs/heads/feature\n")
+	writeFile(t, values, "replicas: 5\n")
+
+	select {
+	case changed := <-calls:
+		if !slices.Equal(changed, []string{values}) {
+			t.Errorf("Expected only %s to be reported, got %v", values, changed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected a call after the missing file was created")
+	}
+}
+
+func TestUnder(t *testing.T) {
+	tests := map[string]bool{
+		"/charts/app/values.yaml":      true,
+		"/charts/app":                  true,
+		"/charts/app-two/values.yaml":  false,
+		"/charts/values.yaml":          false,
+		"/charts/app/../other/x.yaml":  false,
+		"/charts/app/templates/a.yaml": true,
+	}
+	for path, expected := range tests {
+		if got := Under(path, "/charts/app"); got != expected {
+			t.Errorf("Expected Under(%s) to be %v, got %v", path, expected, got)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Package chartscan is the public API for extending the chartscan command
+// line with output formats of its own.
+//
+// A wrapper implements Renderer and registers it under a name from an init
+// function:
+//
+//	func init() {
+//		chartscan.RegisterRenderer("sarif-lite", sarifRenderer{})
+//	}
+//
+// A wrapper builds a binary of its own that runs the chartscan command line
+// with its renderers registered, without forking cmd/chartscan:
+//
+//	func main() {
+//		chartscan.Main()
+//	}
+//
+// The format is then selected with `--output-format sarif-lite` like the
+// built-in ones.
+package chartscan
+
+import (
+	"io"
+	"time"
+
+	"github.com/Jaydee94/chartscan/internal/cli"
+	"github.com/Jaydee94/chartscan/internal/models"
+	"github.com/Jaydee94/chartscan/internal/renderer"
+)
+
+type (
+	// Result is the outcome of scanning a single chart.
+	Result = models.Result
+	// Finding is a single issue reported for a chart.
+	Finding = models.Finding
+)
+
+// Severity levels of a Finding. Only error findings mark a chart as failed.
+const (
+	SeverityError   = models.SeverityError
+	SeverityWarning = models.SeverityWarning
+	SeverityInfo    = models.SeverityInfo
+)
+
+// Renderer writes the results of a scan in an output format.
+type Renderer interface {
+	// Render writes results, scanned in duration, to w. Results of charts
+	// scanned with --include-dependencies carry their subcharts in
+	// Dependencies.
+	Render(w io.Writer, results []Result, duration time.Duration) error
+}
+
+// RendererFunc adapts a function to a Renderer.
+type RendererFunc func(w io.Writer, results []Result, duration time.Duration) error
+
+// Render calls f.
+func (f RendererFunc) Render(w io.Writer, results []Result, duration time.Duration) error {
+	return f(w, results, duration)
+}
+
+// RegisterRenderer makes r available as the output format name of scan and
+// watch. It panics if name is empty, names a built-in format or is already
+// registered, or if r is nil.
+func RegisterRenderer(name string, r Renderer) {
+	if f, ok := r.(RendererFunc); r == nil || ok && f == nil {
+		panic("chartscan: RegisterRenderer called with a nil renderer for " + name)
+	}
+	if err := renderer.RegisterFormat(name, r.Render); err != nil {
+		panic("chartscan: " + err.Error())
+	}
+}
+
+// OutputFormats returns the names of the built-in output formats followed by
+// the registered ones.
+func OutputFormats() []string {
+	return renderer.FormatNames()
+}
+
+// Main runs the chartscan command line with os.Args, including the renderers
+// and rules registered so far, and exits the process when it is done. Call it
+// from the main function of a wrapper. `chartscan version` reports the build
+// as "dev", with the commit recorded by the Go toolchain.
+func Main() {
+	cli.Main("", "", "")
+}
@@ -0,0 +1,59 @@
+package chartscan
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Jaydee94/chartscan/internal/renderer"
+)
+
+func TestRegisterRenderer(t *testing.T) {
+	RegisterRenderer("count", RendererFunc(func(w io.Writer, results []Result, duration time.Duration) error {
+		_, err := fmt.Fprintf(w, "%d charts\n", len(results))
+		return err
+	}))
+
+	if formats := OutputFormats(); !slices.Contains(formats, "count") || formats[0] != "pretty" {
+		t.Errorf("Expected the built-in formats followed by count, got %v", formats)
+	}
+
+	format, ok := renderer.LookupFormat("count")
+	if !ok {
+		t.Fatalf("Expected count to be registered")
+	}
+	var buf bytes.Buffer
+	if err := format(&buf, []Result{{ChartPath: "charts/web"}}, time.Second); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if buf.String() != "1 charts\n" {
+		t.Errorf("Expected '1 charts', got '%s'", buf.String())
+	}
+
+	noop := RendererFunc(func(io.Writer, []Result, time.Duration) error { return nil })
+	for _, tc := range []struct {
+		name string
+		r    Renderer
+	}{
+		{"count", noop},
+		{"junit", noop},
+		{"", noop},
+		{"nil", nil},
+		{"nil-func", RendererFunc(nil)},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected registering %q to panic", tc.name)
+				}
+			}()
+			RegisterRenderer(tc.name, tc.r)
+		}()
+	}
+	if _, ok := renderer.LookupFormat("nil-func"); ok {
+		t.Errorf("Expected a nil RendererFunc not to be registered")
+	}
+}
@@ -0,0 +1,107 @@
+// Package rulesdk is the public API for writing chartscan rules outside of
+// this repository.
+//
+// A rule implements Rule and registers itself from an init function:
+//
+//	func init() {
+//		rulesdk.Register(requireTeamLabel{})
+//	}
+//
+// A rule reads its settings from the options of its entry in the rules section
+// of chartscan.yaml with Context.Options.
+//
+// Rules are compiled into chartscan: import the package defining them from a
+// wrapper whose main function calls chartscan.Main, or add a blank import of
+// it to cmd/chartscan, and build the binary. Evaluate runs a rule
+// against rendered manifests for unit tests; `chartscan rules test` runs the
+// registered rules against fixture charts.
+package rulesdk
+
+import (
+	"github.com/Jaydee94/chartscan/internal/models"
+	"github.com/Jaydee94/chartscan/internal/rules"
+)
+
+type (
+	// Rule checks the rendered manifests of a chart.
+	Rule = rules.Rule
+	// Context is the input of a rule evaluation for a single chart.
+	Context = rules.Context
+	// Manifest is a single Kubernetes object from a chart's rendered output.
+	Manifest = rules.Manifest
+	// Finding is a single issue reported by a rule.
+	Finding = models.Finding
+	// Config is the chartscan.yaml configuration rules are evaluated under.
+	Config = models.Config
+	// ValueReference is a .Values reference found in a chart's templates.
+	ValueReference = models.ValueReference
+)
+
+// Severity levels of a Finding. Only error findings mark a chart as failed.
+const (
+	SeverityError   = models.SeverityError
+	SeverityWarning = models.SeverityWarning
+	SeverityInfo    = models.SeverityInfo
+)
+
+// Register adds a rule to the set chartscan evaluates. Rule IDs must be
+// unique.
+func Register(rule Rule) {
+	rules.Register(rule)
+}
+
+// Evaluate parses rendered, the output of helm template, and returns the
+// findings of rule under config. A rule that is not enabled by config reports
+// nothing.
+func Evaluate(rule Rule, rendered string, config Config) ([]Finding, error) {
+	if !rule.Enabled(&config) {
+		return nil, nil
+	}
+	manifests, err := rules.ParseManifests(rendered)
+	if err != nil {
+		return nil, err
+	}
+	return rule.Check(&Context{Manifests: manifests, Config: config}), nil
+}
+
+// ParseManifests splits rendered helm output into manifests.
+func ParseManifests(rendered string) ([]Manifest, error) {
+	return rules.ParseManifests(rendered)
+}
+
+// NestedValue returns the value at the given map keys within object, or nil.
+func NestedValue(object map[string]interface{}, keys ...string) interface{} {
+	return rules.NestedValue(object, keys...)
+}
+
+// NestedMap returns the map at the given keys within object, or nil.
+func NestedMap(object map[string]interface{}, keys ...string) map[string]interface{} {
+	return rules.NestedMap(object, keys...)
+}
+
+// NestedSlice returns the list at the given keys within object, or nil.
+func NestedSlice(object map[string]interface{}, keys ...string) []interface{} {
+	return rules.NestedSlice(object, keys...)
+}
+
+// NestedString returns the string at the given keys within object, or "".
+func NestedString(object map[string]interface{}, keys ...string) string {
+	return rules.NestedString(object, keys...)
+}
+
+// PodSpec returns the pod spec embedded in a workload manifest, or nil.
+func PodSpec(m Manifest) map[string]interface{} {
+	return rules.PodSpec(m)
+}
+
+// PodTemplateMetadata returns the metadata of the pods created by a workload
+// manifest, or nil.
+func PodTemplateMetadata(m Manifest) map[string]interface{} {
+	return rules.PodTemplateMetadata(m)
+}
+
+// Containers returns the containers of a pod spec, optionally including init
+// containers.
+func Containers(podSpec map[string]interface{}, includeInit bool) []map[string]interface{} {
+	return rules.Containers(podSpec, includeInit)
+}
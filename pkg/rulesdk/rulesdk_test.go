@@ -0,0 +1,66 @@
+package rulesdk
+
+import (
+	"testing"
+)
+
+// teamLabelRule requires a team label on every Deployment.
+type teamLabelRule struct{}
+
+func (teamLabelRule) ID() string { return "team-label" }
+
+func (teamLabelRule) Enabled(config *Config) bool { return config.Format != "disabled" }
+
+func (teamLabelRule) Check(ctx *Context) []Finding {
+	var findings []Finding
+	for _, m := range ctx.Manifests {
+		if m.Kind == "Deployment" && NestedString(m.Object, "metadata", "labels", "team") == "" {
+			findings = append(findings, Finding{
+				RuleID:   "team-label",
+				Severity: SeverityWarning,
+				Message:  "missing team label",
+				Resource: m.Resource(),
+				File:     m.Source,
+			})
+		}
+	}
+	return findings
+}
+
+const rendered = `---
+# Source: app/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+---
+# Source: app/templates/worker.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: worker
+  labels:
+    team: payments
+`
+
+func TestEvaluate(t *testing.T) {
+	findings, err := Evaluate(teamLabelRule{}, rendered, Config{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(findings) != 1 {
+		t.Fatalf("Expected 1 finding, got %v", findings)
+	}
+	if findings[0].Resource != "Deployment/web" || findings[0].File != "app/templates/deployment.yaml" {
+		t.Errorf("Expected a finding for Deployment/web, got %+v", findings[0])
+	}
+
+	findings, err = Evaluate(teamLabelRule{}, rendered, Config{Format: "disabled"})
+	if err != nil || len(findings) != 0 {
+		t.Errorf("Expected no findings from a disabled rule, got %v, %v", findings, err)
+	}
+
+	if _, err := Evaluate(teamLabelRule{}, "kind: [unclosed", Config{}); err == nil {
+		t.Errorf("Expected an error for invalid YAML")
+	}
+}